@@ -0,0 +1,137 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/AlecAivazis/survey/v2"
+)
+
+// KeyAccessible is the configuration key for enabling screen-reader friendly output
+const KeyAccessible = "ui.accessible"
+
+// KeyASCIIOnly is the configuration key for limiting output to ASCII on terminals without UTF-8 support
+const KeyASCIIOnly = "ui.ascii_only"
+
+// Glyphs used to decorate status messages with plain text equivalents for accessible mode
+const (
+	glyphInfo    = "ℹ"
+	glyphSuccess = "✓"
+	glyphFailure = "✗"
+	glyphWarning = "⚠"
+	glyphPrompt  = "❯"
+)
+
+// Box drawing glyphs framing streamed output and error panels
+const (
+	glyphGutter       = "│"
+	glyphCornerTop    = "╭"
+	glyphCornerBottom = "╰"
+)
+
+var accessibleGlyphs = map[string]string{
+	glyphInfo:         "[info]",
+	glyphSuccess:      "[ok]",
+	glyphFailure:      "[failed]",
+	glyphWarning:      "[warning]",
+	glyphPrompt:       ">",
+	glyphGutter:       "|",
+	glyphCornerTop:    "+",
+	glyphCornerBottom: "+",
+	"💥":               "",
+	"🔥":               "",
+}
+
+var asciiGlyphs = map[string]string{
+	glyphInfo:         "i",
+	glyphSuccess:      "+",
+	glyphFailure:      "x",
+	glyphWarning:      "!",
+	glyphPrompt:       ">",
+	glyphGutter:       "|",
+	glyphCornerTop:    "+",
+	glyphCornerBottom: "+",
+	"💥":               "",
+	"🔥":               "",
+}
+
+// activeGlyphs replaces unicode glyphs in output rendered outside of commands, or is nil when unicode is supported
+// It is configured alongside the color settings of output libraries
+var activeGlyphs map[string]string
+
+// outputGlyph returns the given unicode glyph or its replacement for the detected output capabilities
+func outputGlyph(glyph string) string {
+	if activeGlyphs == nil {
+		return glyph
+	}
+	return activeGlyphs[glyph]
+}
+
+// Accessible indicates if accessible mode is enabled
+// Accessible mode disables spinners, screen redraws, and unicode glyphs for compatibility with screen readers
+func (baseCmd *BaseCommand) Accessible() bool {
+	if baseCmd.viperCfg == nil {
+		return false
+	}
+	return baseCmd.viperCfg.GetBool(KeyAccessible)
+}
+
+// ASCIIOnly indicates if output is limited to ASCII, as configured or detected from a locale without UTF-8
+func (baseCmd *BaseCommand) ASCIIOnly() bool {
+	if baseCmd.capabilities.ASCII {
+		return true
+	}
+	return baseCmd.viperCfg != nil && baseCmd.viperCfg.GetBool(KeyASCIIOnly)
+}
+
+// Glyph returns the given unicode glyph, its plain text equivalent in accessible mode, or its ASCII equivalent
+func (baseCmd *BaseCommand) Glyph(glyph string) string {
+	if baseCmd.Accessible() {
+		return accessibleGlyphs[glyph]
+	} else if baseCmd.ASCIIOnly() {
+		return asciiGlyphs[glyph]
+	}
+	return glyph
+}
+
+// Emoji returns the given emoji followed by a space, or an empty string in accessible and ASCII modes
+func (baseCmd *BaseCommand) Emoji(emoji string) string {
+	if glyph := baseCmd.Glyph(emoji); glyph != "" {
+		return glyph + " "
+	}
+	return ""
+}
+
+// surveyOpts returns the options applied to all survey prompts
+// Icons are colored with the palette and replaced by plain text equivalents in accessible mode
+func (baseCmd *BaseCommand) surveyOpts() []survey.AskOpt {
+	accessible := baseCmd.Accessible()
+	return []survey.AskOpt{
+		survey.WithIcons(func(icons *survey.IconSet) {
+			icons.Question.Format = activePalette.Success.surveyStyle()
+			icons.Help.Format = activePalette.Info.Foreground().surveyStyle()
+			icons.Error.Format = activePalette.Failure.Foreground().surveyStyle()
+			icons.MarkedOption.Format = activePalette.Success.Foreground().surveyStyle()
+			icons.SelectFocus.Format = activePalette.Info.surveyStyle()
+			if accessible {
+				icons.Question.Text = "[question]"
+				icons.Help.Text = "[help]"
+				icons.Error.Text = "[error]"
+				icons.MarkedOption.Text = "[x]"
+				icons.UnmarkedOption.Text = "[ ]"
+				icons.SelectFocus.Text = ">"
+			}
+		}),
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessibleDisabledByDefault(t *testing.T) {
+	baseCmd := command.NewRootCommand()
+	require.False(t, baseCmd.Accessible())
+	require.Equal(t, "✓", baseCmd.Glyph("✓"))
+	require.Equal(t, "🔥 ", baseCmd.Emoji("🔥"))
+}
+
+func TestAccessibleReplacesGlyphs(t *testing.T) {
+	baseCmd := command.NewRootCommand()
+	baseCmd.Viper().Set(command.KeyAccessible, true)
+	require.True(t, baseCmd.Accessible())
+	require.Equal(t, "[info]", baseCmd.Glyph("ℹ"))
+	require.Equal(t, "[ok]", baseCmd.Glyph("✓"))
+	require.Equal(t, "[failed]", baseCmd.Glyph("✗"))
+	require.Equal(t, "", baseCmd.Emoji("🔥"))
+}
+
+func TestPalettesCoverStatusRoles(t *testing.T) {
+	require.Equal(t, []string{command.PaletteColorBlind, command.PaletteDefault}, command.PaletteNames())
+	for name, palette := range command.Palettes {
+		for _, c := range []command.PaletteColor{palette.Success, palette.Failure, palette.Info, palette.Warning} {
+			require.NotEmpty(t, c.Foreground(), name)
+		}
+	}
+	colorBlind := command.Palettes[command.PaletteColorBlind]
+	require.NotEqual(t, colorBlind.Success.Foreground(), colorBlind.Failure.Foreground())
+}
+
+func TestASCIIOnlyReplacesGlyphs(t *testing.T) {
+	baseCmd := command.NewRootCommand()
+	baseCmd.Viper().Set(command.KeyASCIIOnly, true)
+	require.True(t, baseCmd.ASCIIOnly())
+	require.Equal(t, "i", baseCmd.Glyph("ℹ"))
+	require.Equal(t, "+", baseCmd.Glyph("✓"))
+	require.Equal(t, "x", baseCmd.Glyph("✗"))
+	require.Equal(t, ">", baseCmd.Glyph("❯"))
+	require.Equal(t, "", baseCmd.Emoji("💥"))
+}
@@ -0,0 +1,221 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// KeyAliases is the config key for user-defined command aliases
+const KeyAliases = "aliases"
+
+// Alias is a user-defined shortcut expanding to a command line (e.g. `cfg: optimizer config get`)
+type Alias struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+
+	// Shadowed is set when a built-in command of the same name takes precedence over the alias
+	Shadowed bool `json:"shadowed"`
+}
+
+// Aliases returns the aliases defined in the loaded config, sorted by name
+func (baseCmd *BaseCommand) Aliases() []Alias {
+	return baseCmd.aliasesFromConfig(baseCmd.viperCfg)
+}
+
+func (baseCmd *BaseCommand) aliasesFromConfig(cfg *viper.Viper) []Alias {
+	aliases := []Alias{}
+	for name, command := range cfg.GetStringMapString(KeyAliases) {
+		aliases = append(aliases, Alias{Name: name, Command: command, Shadowed: baseCmd.isBuiltinCommand(name)})
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Name < aliases[j].Name })
+	return aliases
+}
+
+// isBuiltinCommand returns true if the name or alias of a top-level command matches the name
+func (baseCmd *BaseCommand) isBuiltinCommand(name string) bool {
+	if name == "help" {
+		return true
+	}
+	for _, cmd := range baseCmd.rootCobraCommand.Commands() {
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandAliases replaces a user-defined alias in the command position of the arguments with its expansion
+// Global flags preceding the alias are preserved and arguments following it are appended to the expansion.
+// Built-in commands always take precedence and expansions are not themselves expanded.
+func (baseCmd *BaseCommand) ExpandAliases(args []string) ([]string, error) {
+	configFile := ""
+	i := 0
+	for ; i < len(args) && strings.HasPrefix(args[i], "-"); i++ {
+		if args[i] == "--" {
+			return args, nil
+		}
+		name, value := strings.TrimLeft(args[i], "-"), ""
+		hasValue := false
+		if n := strings.Index(name, "="); n != -1 {
+			name, value, hasValue = name[:n], name[n+1:], true
+		}
+		var flag *pflag.Flag
+		if strings.HasPrefix(args[i], "--") {
+			flag = baseCmd.rootCobraCommand.PersistentFlags().Lookup(name)
+		} else if len(name) == 1 {
+			flag = baseCmd.rootCobraCommand.PersistentFlags().ShorthandLookup(name)
+		}
+		if flag == nil {
+			// Leave unknown flags for Cobra to report
+			return args, nil
+		}
+		if !hasValue && flag.Value.Type() != "bool" {
+			if i+1 >= len(args) {
+				return args, nil
+			}
+			i++
+			value = args[i]
+		}
+		if flag.Name == "config" {
+			configFile = value
+		}
+	}
+	if i >= len(args) || baseCmd.isBuiltinCommand(args[i]) {
+		return args, nil
+	}
+
+	cfg := viper.New()
+	if configFile != "" {
+		cfg.SetConfigFile(configFile)
+	} else {
+		cfg.SetConfigFile(baseCmd.DefaultConfigFile())
+	}
+	if err := cfg.ReadInConfig(); err != nil {
+		// Let command execution report missing or invalid config files
+		return args, nil
+	}
+	for _, alias := range baseCmd.aliasesFromConfig(cfg) {
+		if alias.Name != args[i] {
+			continue
+		}
+		expansion, err := splitCommandLine(alias.Command)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alias %q: %w", alias.Name, err)
+		}
+		if len(expansion) == 0 {
+			return nil, fmt.Errorf("invalid alias %q: expansion is empty", alias.Name)
+		}
+		expanded := append([]string{}, args[:i]...)
+		expanded = append(expanded, expansion...)
+		return append(expanded, args[i+1:]...), nil
+	}
+	return args, nil
+}
+
+// splitCommandLine splits a command line into arguments at whitespace, honoring single and double quotes
+func splitCommandLine(line string) ([]string, error) {
+	args := []string{}
+	var current strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// NewAliasCommand returns a new `opsani alias` command instance
+func NewAliasCommand(baseCmd *BaseCommand) *cobra.Command {
+	aliasCmd := &cobra.Command{
+		Use:   "alias",
+		Short: "Manage command aliases",
+		Long: `Aliases are shortcuts for frequently used command lines, defined in the
+aliases section of the config file:
+
+  aliases:
+    cfg: optimizer config get
+    logs: servo logs -f
+
+Arguments following an alias are appended to its expansion, so that
+` + "`opsani logs -l 50`" + ` runs ` + "`opsani servo logs -f -l 50`" + `. Built-in commands take
+precedence over aliases of the same name.`,
+		Annotations:       map[string]string{"other": "true"},
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: baseCmd.InitConfigRunE,
+	}
+	aliasCmd.AddCommand(NewAliasListCommand(baseCmd))
+	return aliasCmd
+}
+
+// NewAliasListCommand returns a new `opsani alias list` command instance
+func NewAliasListCommand(baseCmd *BaseCommand) *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List command aliases",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := OutputFormat(cmd, TabularOutputFormats...)
+			if err != nil {
+				return err
+			}
+			aliases := baseCmd.Aliases()
+			if output == OutputJSON {
+				return baseCmd.PrettyPrintJSONObject(aliases)
+			}
+			table := Table{Headers: []string{"NAME", "COMMAND", "NOTE"}}
+			for _, alias := range aliases {
+				note := ""
+				if alias.Shadowed {
+					note = "shadowed by built-in command"
+				}
+				table.Rows = append(table.Rows, []string{alias.Name, alias.Command, note})
+			}
+			return baseCmd.RenderTable(output, table)
+		},
+	}
+	AddOutputFlag(listCmd, TabularOutputFormats...)
+	return listCmd
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+)
+
+type AliasTestSuite struct {
+	test.Suite
+}
+
+func TestAliasTestSuite(t *testing.T) {
+	suite.Run(t, new(AliasTestSuite))
+}
+
+func (s *AliasTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+}
+
+func (s *AliasTestSuite) aliasConfigFile() string {
+	return test.TempConfigFileWithObj(map[string]interface{}{
+		"aliases": map[string]string{
+			"cfg":     "optimizer config get",
+			"logs":    "servo logs -f",
+			"deploy":  `optimizer config patch --data '{"adjustment": {"mode": "fast"}}'`,
+			"servo":   "servo list",
+			"broken":  `servo "logs`,
+			"nothing": "",
+		},
+	}).Name()
+}
+
+func (s *AliasTestSuite) TestExpandAlias() {
+	configFile := s.aliasConfigFile()
+	args, err := command.NewRootCommand().ExpandAliases([]string{"--config", configFile, "logs", "-l", "50"})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"--config", configFile, "servo", "logs", "-f", "-l", "50"}, args)
+}
+
+func (s *AliasTestSuite) TestExpandAliasAfterGlobalFlags() {
+	configFile := s.aliasConfigFile()
+	args, err := command.NewRootCommand().ExpandAliases([]string{"--config=" + configFile, "-p", "staging", "--debug", "cfg", "adjustment"})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"--config=" + configFile, "-p", "staging", "--debug", "optimizer", "config", "get", "adjustment"}, args)
+}
+
+func (s *AliasTestSuite) TestExpandAliasHonorsQuotes() {
+	configFile := s.aliasConfigFile()
+	args, err := command.NewRootCommand().ExpandAliases([]string{"--config", configFile, "deploy"})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"--config", configFile, "optimizer", "config", "patch", "--data", `{"adjustment": {"mode": "fast"}}`}, args)
+}
+
+func (s *AliasTestSuite) TestBuiltinCommandTakesPrecedence() {
+	configFile := s.aliasConfigFile()
+	args, err := command.NewRootCommand().ExpandAliases([]string{"--config", configFile, "servo", "status"})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"--config", configFile, "servo", "status"}, args)
+}
+
+func (s *AliasTestSuite) TestUnknownCommandIsNotExpanded() {
+	configFile := s.aliasConfigFile()
+	args, err := command.NewRootCommand().ExpandAliases([]string{"--config", configFile, "unknown"})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"--config", configFile, "unknown"}, args)
+}
+
+func (s *AliasTestSuite) TestInvalidAlias() {
+	configFile := s.aliasConfigFile()
+	_, err := command.NewRootCommand().ExpandAliases([]string{"--config", configFile, "broken"})
+	s.Require().EqualError(err, `invalid alias "broken": unterminated quote`)
+	_, err = command.NewRootCommand().ExpandAliases([]string{"--config", configFile, "nothing"})
+	s.Require().EqualError(err, `invalid alias "nothing": expansion is empty`)
+}
+
+func (s *AliasTestSuite) TestListAliases() {
+	output, err := s.Execute("--config", s.aliasConfigFile(), "alias", "list", "--output", "csv")
+	s.Require().NoError(err)
+	s.Require().Equal(`NAME,COMMAND,NOTE
+broken,"servo ""logs",
+cfg,optimizer config get,
+deploy,"optimizer config patch --data '{""adjustment"": {""mode"": ""fast""}}'",
+logs,servo logs -f,
+nothing,,
+servo,servo list,shadowed by built-in command
+`, output)
+}
@@ -0,0 +1,331 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opsani/cli/opsani"
+	"github.com/spf13/cobra"
+)
+
+// Configuration keys for the observability systems annotated with adjustments made by the optimizer
+const (
+	KeyAnnotationsGrafanaURL         = "annotations.grafana.url"
+	KeyAnnotationsGrafanaAPIKey      = "annotations.grafana.api_key"
+	KeyAnnotationsDatadogAPIKey      = "annotations.datadog.api_key"
+	KeyAnnotationsDatadogSite        = "annotations.datadog.site"
+	KeyAnnotationsNewRelicAPIKey     = "annotations.newrelic.api_key"
+	KeyAnnotationsNewRelicAppID      = "annotations.newrelic.application_id"
+	KeyAnnotationsNewRelicAPIBaseURL = "annotations.newrelic.api_url"
+)
+
+// AdjustmentAnnotation describes an adjustment applied by the optimizer
+type AdjustmentAnnotation struct {
+	Optimizer  string
+	Adjustment opsani.Adjustment
+}
+
+// Title returns a one line summary of the annotation
+func (a AdjustmentAnnotation) Title() string {
+	return fmt.Sprintf("Opsani adjusted %s", a.Optimizer)
+}
+
+// Text returns a description of the settings applied by the adjustment
+func (a AdjustmentAnnotation) Text() string {
+	return fmt.Sprintf("%s: %s", a.Title(), summarizeAdjustmentSettings(a.Adjustment.Components))
+}
+
+// Tags returns the tags attached to the annotation
+func (a AdjustmentAnnotation) Tags() []string {
+	return []string{"opsani", "optimizer:" + a.Optimizer, "adjustment:" + a.Adjustment.ID}
+}
+
+// EndedAt returns the time the adjustment completed, or the time it started if it has not completed
+func (a AdjustmentAnnotation) EndedAt() time.Time {
+	if a.Adjustment.CompletedAt != nil {
+		return *a.Adjustment.CompletedAt
+	}
+	return a.Adjustment.StartedAt
+}
+
+// Annotator records adjustments in an external observability system
+type Annotator interface {
+	Name() string
+	Annotate(annotation AdjustmentAnnotation) error
+}
+
+// postAnnotationJSON posts the body as JSON with the given headers, failing on non-2xx responses
+func postAnnotationJSON(client *http.Client, name string, url string, headers map[string]string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", name, resp.Status)
+	}
+	return nil
+}
+
+// GrafanaAnnotator creates region annotations via the Grafana HTTP API
+type GrafanaAnnotator struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+// Name returns the name of the observability system
+func (g *GrafanaAnnotator) Name() string {
+	return "grafana"
+}
+
+// Annotate creates an annotation spanning the adjustment
+func (g *GrafanaAnnotator) Annotate(annotation AdjustmentAnnotation) error {
+	headers := map[string]string{}
+	if g.APIKey != "" {
+		headers["Authorization"] = "Bearer " + g.APIKey
+	}
+	return postAnnotationJSON(g.Client, g.Name(), strings.TrimSuffix(g.URL, "/")+"/api/annotations", headers, map[string]interface{}{
+		"time":    annotation.Adjustment.StartedAt.UnixNano() / int64(time.Millisecond),
+		"timeEnd": annotation.EndedAt().UnixNano() / int64(time.Millisecond),
+		"tags":    annotation.Tags(),
+		"text":    annotation.Text(),
+	})
+}
+
+// DatadogAnnotator posts events to the Datadog events API
+type DatadogAnnotator struct {
+	// URL is the base URL of the Datadog API for the site (e.g. https://api.datadoghq.com)
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+// Name returns the name of the observability system
+func (d *DatadogAnnotator) Name() string {
+	return "datadog"
+}
+
+// Annotate posts an event at the completion of the adjustment
+func (d *DatadogAnnotator) Annotate(annotation AdjustmentAnnotation) error {
+	return postAnnotationJSON(d.Client, d.Name(), strings.TrimSuffix(d.URL, "/")+"/api/v1/events", map[string]string{"DD-API-KEY": d.APIKey}, map[string]interface{}{
+		"title":           annotation.Title(),
+		"text":            annotation.Text(),
+		"tags":            annotation.Tags(),
+		"date_happened":   annotation.EndedAt().Unix(),
+		"aggregation_key": annotation.Adjustment.ID,
+	})
+}
+
+// NewRelicAnnotator records deployment markers via the New Relic REST API
+type NewRelicAnnotator struct {
+	// URL is the base URL of the New Relic REST API (e.g. https://api.newrelic.com)
+	URL           string
+	APIKey        string
+	ApplicationID string
+	Client        *http.Client
+}
+
+// Name returns the name of the observability system
+func (n *NewRelicAnnotator) Name() string {
+	return "newrelic"
+}
+
+// Annotate records a deployment marker at the completion of the adjustment
+func (n *NewRelicAnnotator) Annotate(annotation AdjustmentAnnotation) error {
+	url := fmt.Sprintf("%s/v2/applications/%s/deployments.json", strings.TrimSuffix(n.URL, "/"), n.ApplicationID)
+	return postAnnotationJSON(n.Client, n.Name(), url, map[string]string{"X-Api-Key": n.APIKey}, map[string]interface{}{
+		"deployment": map[string]string{
+			"revision":    annotation.Adjustment.ID,
+			"description": annotation.Text(),
+			"user":        "opsani",
+			"timestamp":   annotation.EndedAt().UTC().Format(time.RFC3339),
+		},
+	})
+}
+
+// Annotators returns the annotators configured in the config file
+func (baseCmd *BaseCommand) Annotators() []Annotator {
+	if baseCmd.viperCfg == nil {
+		return nil
+	}
+	cfg := baseCmd.viperCfg
+	client := &http.Client{Timeout: 10 * time.Second}
+	annotators := []Annotator{}
+	if url := cfg.GetString(KeyAnnotationsGrafanaURL); url != "" {
+		annotators = append(annotators, &GrafanaAnnotator{URL: url, APIKey: cfg.GetString(KeyAnnotationsGrafanaAPIKey), Client: client})
+	}
+	if apiKey := cfg.GetString(KeyAnnotationsDatadogAPIKey); apiKey != "" {
+		site := cfg.GetString(KeyAnnotationsDatadogSite)
+		if site == "" {
+			site = "datadoghq.com"
+		}
+		annotators = append(annotators, &DatadogAnnotator{URL: "https://api." + site, APIKey: apiKey, Client: client})
+	}
+	if apiKey := cfg.GetString(KeyAnnotationsNewRelicAPIKey); apiKey != "" {
+		url := cfg.GetString(KeyAnnotationsNewRelicAPIBaseURL)
+		if url == "" {
+			url = "https://api.newrelic.com"
+		}
+		annotators = append(annotators, &NewRelicAnnotator{URL: url, APIKey: apiKey, ApplicationID: cfg.GetString(KeyAnnotationsNewRelicAppID), Client: client})
+	}
+	return annotators
+}
+
+// adjustmentForwarder annotates each completed adjustment once
+type adjustmentForwarder struct {
+	*BaseCommand
+	annotators []Annotator
+	forwarded  map[string]bool
+}
+
+// forward annotates the completed adjustments that have not been forwarded yet, oldest first
+// Adjustments that fail to be annotated are retried by later calls. The start time for the next
+// poll is returned so that adjustments in progress are seen again once they complete.
+func (f *adjustmentForwarder) forward(adjustments []opsani.Adjustment, polledAt time.Time) (next time.Time, failures int) {
+	sort.Slice(adjustments, func(i, j int) bool { return adjustments[i].StartedAt.Before(adjustments[j].StartedAt) })
+	next = polledAt
+	for _, adjustment := range adjustments {
+		if adjustment.CompletedAt == nil || f.forwarded[adjustment.ID] {
+			if adjustment.CompletedAt == nil && adjustment.StartedAt.Before(next) {
+				next = adjustment.StartedAt
+			}
+			continue
+		}
+		annotation := AdjustmentAnnotation{Optimizer: f.Optimizer(), Adjustment: adjustment}
+		names := []string{}
+		failed := false
+		for _, annotator := range f.annotators {
+			if err := annotator.Annotate(annotation); err != nil {
+				f.PrintErrf("warning: failed forwarding adjustment %s to %s: %s\n", adjustment.ID, annotator.Name(), err)
+				failed = true
+				continue
+			}
+			names = append(names, annotator.Name())
+		}
+		if failed {
+			failures++
+			if adjustment.StartedAt.Before(next) {
+				next = adjustment.StartedAt
+			}
+		}
+		if len(names) > 0 {
+			f.Printf("Forwarded adjustment %s to %s\n", adjustment.ID, strings.Join(names, ", "))
+		}
+		f.forwarded[adjustment.ID] = !failed
+	}
+	return next, failures
+}
+
+// NewOptimizerAdjustmentsForwardCommand returns a new Opsani CLI `optimizer adjustments forward` command
+func NewOptimizerAdjustmentsForwardCommand(baseCmd *BaseCommand) *cobra.Command {
+	forwardCmd := &cobra.Command{
+		Use:   "forward",
+		Short: "Annotate dashboards with adjustments made by the optimizer",
+		Long: `Posts each completed adjustment to the observability systems configured in the
+annotations section of the config file so that changes in application behavior can
+be correlated with optimization:
+
+  annotations:
+    grafana:
+      url: https://grafana.example.com
+      api_key: ...
+    datadog:
+      api_key: ...
+      site: datadoghq.eu
+    newrelic:
+      api_key: ...
+      application_id: "12345"
+
+Grafana receives region annotations, Datadog receives events, and New Relic receives
+deployment markers. With --follow the optimizer is polled for new adjustments until
+interrupted.`,
+		Example: `  opsani optimizer adjustments forward --since 24h
+  opsani optimizer adjustments forward --follow`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			annotators := baseCmd.Annotators()
+			if len(annotators) == 0 {
+				return fmt.Errorf("no annotation targets configured: set %s, %s, or %s in the config file",
+					KeyAnnotationsGrafanaURL, KeyAnnotationsDatadogAPIKey, KeyAnnotationsNewRelicAPIKey)
+			}
+			since, _ := cmd.Flags().GetDuration("since")
+			follow, _ := cmd.Flags().GetBool("follow")
+			interval, _ := cmd.Flags().GetDuration(KeyWatchInterval)
+			if follow && interval <= 0 {
+				return fmt.Errorf("invalid interval %q: must be greater than zero", interval)
+			}
+
+			forwarder := &adjustmentForwarder{BaseCommand: baseCmd, annotators: annotators, forwarded: map[string]bool{}}
+			client := baseCmd.NewAPIClient()
+			start := time.Now().Add(-since)
+			if !follow {
+				adjustments, err := client.GetAdjustments(opsani.TimeRange{Start: start})
+				if err != nil {
+					return err
+				}
+				if _, failures := forwarder.forward(adjustments, time.Now()); failures > 0 {
+					return fmt.Errorf("failed forwarding %d adjustments", failures)
+				}
+				return nil
+			}
+
+			interrupt := make(chan os.Signal, 1)
+			signal.Notify(interrupt, os.Interrupt)
+			defer signal.Stop(interrupt)
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				polledAt := time.Now()
+				if adjustments, err := client.GetAdjustments(opsani.TimeRange{Start: start}); err != nil {
+					baseCmd.PrintErrf("warning: failed retrieving adjustments: %s\n", err)
+				} else {
+					start, _ = forwarder.forward(adjustments, polledAt)
+				}
+
+				select {
+				case <-interrupt:
+					return nil
+				case <-ticker.C:
+				}
+			}
+		},
+	}
+	forwardCmd.Flags().Duration("since", time.Hour, "Forward adjustments newer than a relative duration (e.g. 30m, 6h)")
+	forwardCmd.Flags().Bool("follow", false, "Keep forwarding new adjustments until interrupted")
+	forwardCmd.Flags().Duration(KeyWatchInterval, time.Minute, "Polling interval when following")
+	return forwardCmd
+}
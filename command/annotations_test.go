@@ -0,0 +1,225 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/opsani"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v2"
+)
+
+func testAdjustmentAnnotation() command.AdjustmentAnnotation {
+	completedAt := time.Date(2020, 6, 1, 10, 2, 30, 0, time.UTC)
+	return command.AdjustmentAnnotation{
+		Optimizer: "example.com/app",
+		Adjustment: opsani.Adjustment{
+			ID:          "adj-1",
+			Status:      "completed",
+			StartedAt:   time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC),
+			CompletedAt: &completedAt,
+			Components:  map[string]map[string]interface{}{"web": {"cpu": 0.5, "replicas": 2}},
+		},
+	}
+}
+
+// annotationServer returns a test server recording the path, headers, and JSON body of the last request
+func annotationServer(status int, path *string, header *http.Header, body *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*path = r.URL.Path
+		*header = r.Header
+		data, _ := ioutil.ReadAll(r.Body)
+		*body = string(data)
+		w.WriteHeader(status)
+	}))
+}
+
+func TestAdjustmentAnnotationText(t *testing.T) {
+	annotation := testAdjustmentAnnotation()
+	require.Equal(t, "Opsani adjusted example.com/app: web.cpu=0.5 web.replicas=2", annotation.Text())
+	require.Equal(t, []string{"opsani", "optimizer:example.com/app", "adjustment:adj-1"}, annotation.Tags())
+}
+
+func TestGrafanaAnnotator(t *testing.T) {
+	var path, body string
+	var header http.Header
+	server := annotationServer(http.StatusOK, &path, &header, &body)
+	defer server.Close()
+
+	annotator := &command.GrafanaAnnotator{URL: server.URL + "/", APIKey: "secret", Client: http.DefaultClient}
+	require.NoError(t, annotator.Annotate(testAdjustmentAnnotation()))
+	require.Equal(t, "/api/annotations", path)
+	require.Equal(t, "Bearer secret", header.Get("Authorization"))
+	require.JSONEq(t, `{"time": 1591005600000, "timeEnd": 1591005750000,
+		"tags": ["opsani", "optimizer:example.com/app", "adjustment:adj-1"],
+		"text": "Opsani adjusted example.com/app: web.cpu=0.5 web.replicas=2"}`, body)
+}
+
+func TestDatadogAnnotator(t *testing.T) {
+	var path, body string
+	var header http.Header
+	server := annotationServer(http.StatusAccepted, &path, &header, &body)
+	defer server.Close()
+
+	annotator := &command.DatadogAnnotator{URL: server.URL, APIKey: "secret", Client: http.DefaultClient}
+	require.NoError(t, annotator.Annotate(testAdjustmentAnnotation()))
+	require.Equal(t, "/api/v1/events", path)
+	require.Equal(t, "secret", header.Get("DD-API-KEY"))
+	require.JSONEq(t, `{"title": "Opsani adjusted example.com/app",
+		"text": "Opsani adjusted example.com/app: web.cpu=0.5 web.replicas=2",
+		"tags": ["opsani", "optimizer:example.com/app", "adjustment:adj-1"],
+		"date_happened": 1591005750, "aggregation_key": "adj-1"}`, body)
+}
+
+func TestNewRelicAnnotator(t *testing.T) {
+	var path, body string
+	var header http.Header
+	server := annotationServer(http.StatusCreated, &path, &header, &body)
+	defer server.Close()
+
+	annotator := &command.NewRelicAnnotator{URL: server.URL, APIKey: "secret", ApplicationID: "12345", Client: http.DefaultClient}
+	require.NoError(t, annotator.Annotate(testAdjustmentAnnotation()))
+	require.Equal(t, "/v2/applications/12345/deployments.json", path)
+	require.Equal(t, "secret", header.Get("X-Api-Key"))
+	require.JSONEq(t, `{"deployment": {"revision": "adj-1", "user": "opsani", "timestamp": "2020-06-01T10:02:30Z",
+		"description": "Opsani adjusted example.com/app: web.cpu=0.5 web.replicas=2"}}`, body)
+}
+
+func TestAnnotatorError(t *testing.T) {
+	var path, body string
+	var header http.Header
+	server := annotationServer(http.StatusForbidden, &path, &header, &body)
+	defer server.Close()
+
+	annotator := &command.DatadogAnnotator{URL: server.URL, Client: http.DefaultClient}
+	require.EqualError(t, annotator.Annotate(testAdjustmentAnnotation()), "datadog returned 403 Forbidden")
+}
+
+func TestAnnotatorsFromConfig(t *testing.T) {
+	baseCmd := command.NewRootCommand()
+	require.Empty(t, baseCmd.Annotators())
+
+	baseCmd.Viper().Set(command.KeyAnnotationsGrafanaURL, "https://grafana.example.com")
+	baseCmd.Viper().Set(command.KeyAnnotationsDatadogAPIKey, "dd-key")
+	baseCmd.Viper().Set(command.KeyAnnotationsDatadogSite, "datadoghq.eu")
+	baseCmd.Viper().Set(command.KeyAnnotationsNewRelicAPIKey, "nr-key")
+	annotators := baseCmd.Annotators()
+	require.Len(t, annotators, 3)
+	require.Equal(t, "https://grafana.example.com", annotators[0].(*command.GrafanaAnnotator).URL)
+	require.Equal(t, "https://api.datadoghq.eu", annotators[1].(*command.DatadogAnnotator).URL)
+	require.Equal(t, "https://api.newrelic.com", annotators[2].(*command.NewRelicAnnotator).URL)
+}
+
+type AnnotationsTestSuite struct {
+	test.Suite
+	dir string
+}
+
+func TestAnnotationsTestSuite(t *testing.T) {
+	suite.Run(t, new(AnnotationsTestSuite))
+}
+
+func (s *AnnotationsTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+	dir, err := ioutil.TempDir("", "opsani-annotations")
+	s.Require().NoError(err)
+	s.dir = dir
+}
+
+func (s *AnnotationsTestSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+// writeConfig writes a config file with the given annotations section and returns its path
+func (s *AnnotationsTestSuite) writeConfig(annotations map[string]interface{}) string {
+	configFile := filepath.Join(s.dir, "config.yaml")
+	data, err := yaml.Marshal(map[string]interface{}{
+		"profiles": []map[string]string{
+			{"name": "dev", "optimizer": "example.com/app", "token": "123456"},
+		},
+		"annotations": annotations,
+	})
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(configFile, data, 0600))
+	return configFile
+}
+
+func (s *AnnotationsTestSuite) TestForwardRequiresAnnotationTargets() {
+	configFile := s.writeConfig(nil)
+	_, err := s.Execute("--config", configFile, "optimizer", "adjustments", "forward")
+	s.Require().EqualError(err, "no annotation targets configured: set annotations.grafana.url, annotations.datadog.api_key, or annotations.newrelic.api_key in the config file")
+}
+
+func (s *AnnotationsTestSuite) TestForwardCompletedAdjustments() {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"adjustments": [
+			{"id": "adj-2", "status": "completed", "started_at": "2020-06-01T11:00:00Z", "completed_at": "2020-06-01T11:01:00Z", "components": {"web": {"cpu": 1}}},
+			{"id": "adj-3", "status": "adjusting", "started_at": "2020-06-01T12:00:00Z", "components": {"web": {"cpu": 2}}},
+			{"id": "adj-1", "status": "completed", "started_at": "2020-06-01T10:00:00Z", "completed_at": "2020-06-01T10:01:00Z", "components": {"web": {"cpu": 0.5}}}
+		]}`))
+	}))
+	defer api.Close()
+	s.SetEnv("OPSANI_BASE_URL", api.URL)
+
+	texts := []string{}
+	grafana := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		annotation := map[string]interface{}{}
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&annotation))
+		texts = append(texts, annotation["text"].(string))
+	}))
+	defer grafana.Close()
+
+	configFile := s.writeConfig(map[string]interface{}{"grafana": map[string]string{"url": grafana.URL}})
+	output, err := s.Execute("--config", configFile, "optimizer", "adjustments", "forward", "--since", "24h")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{
+		"Opsani adjusted example.com/app: web.cpu=0.5",
+		"Opsani adjusted example.com/app: web.cpu=1",
+	}, texts)
+	s.Require().Contains(output, "Forwarded adjustment adj-1 to grafana\nForwarded adjustment adj-2 to grafana\n")
+	s.Require().NotContains(output, "adj-3")
+}
+
+func (s *AnnotationsTestSuite) TestForwardReportsFailures() {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"adjustments": [
+			{"id": "adj-1", "status": "completed", "started_at": "2020-06-01T10:00:00Z", "completed_at": "2020-06-01T10:01:00Z", "components": {"web": {"cpu": 0.5}}}
+		]}`))
+	}))
+	defer api.Close()
+	s.SetEnv("OPSANI_BASE_URL", api.URL)
+	grafana := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer grafana.Close()
+
+	configFile := s.writeConfig(map[string]interface{}{"grafana": map[string]string{"url": grafana.URL}})
+	output, err := s.Execute("--config", configFile, "optimizer", "adjustments", "forward")
+	s.Require().EqualError(err, "failed forwarding 1 adjustments")
+	s.Require().Contains(output, "warning: failed forwarding adjustment adj-1 to grafana: grafana returned 401 Unauthorized")
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+)
+
+type BaseURLTestSuite struct {
+	test.Suite
+	rootCmd *command.BaseCommand
+}
+
+func TestBaseURLTestSuite(t *testing.T) {
+	suite.Run(t, new(BaseURLTestSuite))
+}
+
+func (s *BaseURLTestSuite) SetupTest() {
+	os.Unsetenv("OPSANI_BASE_URL")
+	s.rootCmd = command.NewRootCommand()
+	s.SetCommand(s.rootCmd)
+}
+
+func (s *BaseURLTestSuite) configFile(baseURL string) *os.File {
+	return test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+				"base_url":  baseURL,
+			},
+			{
+				"name":      "shared",
+				"optimizer": "example.com/other",
+				"token":     "123456",
+			},
+		},
+	})
+}
+
+func (s *BaseURLTestSuite) TestBaseURLDefault() {
+	s.Require().Equal(command.DefaultBaseURL, s.rootCmd.BaseURL())
+	s.Require().Equal("https://console.opsani.com", s.rootCmd.ConsoleURL())
+}
+
+func (s *BaseURLTestSuite) TestBaseURLFromProfile() {
+	_, err := s.Execute("--config", s.configFile("https://api.cell-1.opsani.com/").Name(), "config")
+	s.Require().NoError(err)
+	s.Require().Equal("https://api.cell-1.opsani.com/", s.rootCmd.BaseURL())
+	s.Require().Equal("https://console.cell-1.opsani.com", s.rootCmd.ConsoleURL())
+}
+
+func (s *BaseURLTestSuite) TestBaseURLDefaultsForProfileWithoutBaseURL() {
+	_, err := s.Execute("--config", s.configFile("https://api.cell-1.opsani.com/").Name(), "--profile", "shared", "config")
+	s.Require().NoError(err)
+	s.Require().Equal(command.DefaultBaseURL, s.rootCmd.BaseURL())
+}
+
+func (s *BaseURLTestSuite) TestBaseURLFromRegistryDefaults() {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"defaults": map[string]string{
+			"base_url": "https://opsani.internal.example.com/api/",
+		},
+		"profiles": []map[string]string{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+			},
+		},
+	})
+	_, err := s.Execute("--config", configFile.Name(), "config")
+	s.Require().NoError(err)
+	s.Require().Equal("https://opsani.internal.example.com/api/", s.rootCmd.BaseURL())
+	s.Require().Equal("https://opsani.internal.example.com/api", s.rootCmd.ConsoleURL())
+}
+
+func (s *BaseURLTestSuite) TestBaseURLFromEnvOverridesProfile() {
+	os.Setenv("OPSANI_BASE_URL", "https://api.staging.opsani.com/")
+	defer os.Unsetenv("OPSANI_BASE_URL")
+	_, err := s.Execute("--config", s.configFile("https://api.cell-1.opsani.com/").Name(), "config")
+	s.Require().NoError(err)
+	s.Require().Equal("https://api.staging.opsani.com/", s.rootCmd.BaseURL())
+}
+
+func (s *BaseURLTestSuite) TestBaseURLFromFlagOverridesEnv() {
+	os.Setenv("OPSANI_BASE_URL", "https://api.staging.opsani.com/")
+	defer os.Unsetenv("OPSANI_BASE_URL")
+	_, err := s.Execute("--config", s.configFile("https://api.cell-1.opsani.com/").Name(), "--base-url", "http://localhost:8080/", "config")
+	s.Require().NoError(err)
+	s.Require().Equal("http://localhost:8080/", s.rootCmd.BaseURL())
+}
+
+func (s *BaseURLTestSuite) TestAPIRequestsUseProfileBaseURL() {
+	var requestPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	_, err := s.Execute("--config", s.configFile(server.URL+"/cell-1/").Name(), "optimizer", "config", "get")
+	s.Require().NoError(err)
+	s.Require().Equal("/cell-1/accounts/example.com/applications/app/config", requestPath)
+}
@@ -0,0 +1,153 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2/core"
+	"github.com/fatih/color"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// KeyWidth is the flag that overrides the detected output width
+const KeyWidth = "width"
+
+// DefaultTerminalWidth is the width assumed for rendering Markdown when the output width is unknown
+const DefaultTerminalWidth = 80
+
+// TerminalCapabilities describes the output features supported where the CLI writes its output
+type TerminalCapabilities struct {
+	// Color indicates that ANSI colors and styles can be used
+	Color bool
+
+	// Animation indicates that spinners, cursor movement, and screen redraws can be used
+	Animation bool
+
+	// Width is the number of columns available for output or zero when unknown
+	Width int
+
+	// ASCII indicates that the locale does not use UTF-8, so unicode glyphs may be garbled
+	ASCII bool
+}
+
+// LookupEnvFunc looks up an environment variable, reporting whether it is set
+type LookupEnvFunc func(key string) (string, bool)
+
+// DetectTerminalCapabilities determines the capabilities of the output from the environment
+// NO_COLOR disables colors, CLICOLOR_FORCE enables them even when output is redirected, and CLICOLOR=0 disables them.
+// Dumb terminals and output that is not a terminal otherwise support neither colors nor animation.
+// The width is taken from the terminal size, falling back to the COLUMNS variable set by many shells and CI systems.
+// Output is limited to ASCII when the locale selected by LC_ALL, LC_CTYPE, or LANG does not use UTF-8
+func DetectTerminalCapabilities(out io.Writer, lookupEnv LookupEnvFunc) TerminalCapabilities {
+	tty, width := false, 0
+	if f, ok := out.(*os.File); ok && IsTerminal(f) {
+		tty = true
+		if w, _, err := terminal.GetSize(int(f.Fd())); err == nil && w > 0 {
+			width = w
+		}
+	}
+	if columns, ok := lookupEnv("COLUMNS"); ok && width == 0 {
+		if w, err := strconv.Atoi(columns); err == nil && w > 0 {
+			width = w
+		}
+	}
+	if term, _ := lookupEnv("TERM"); term == "dumb" {
+		tty = false
+	}
+
+	capabilities := TerminalCapabilities{Color: tty, Animation: tty, Width: width, ASCII: !utf8Locale(lookupEnv)}
+	if value, ok := lookupEnv("CLICOLOR"); ok && value == "0" {
+		capabilities.Color = false
+	}
+	if value, ok := lookupEnv("CLICOLOR_FORCE"); ok && value != "" && value != "0" {
+		capabilities.Color = true
+	}
+	// https://no-color.org/
+	if _, ok := lookupEnv("NO_COLOR"); ok {
+		capabilities.Color = false
+	}
+	return capabilities
+}
+
+// utf8Locale reports whether the locale in effect uses UTF-8
+// The first non-empty of LC_ALL, LC_CTYPE, and LANG takes precedence as in POSIX; no locale is assumed to support UTF-8
+func utf8Locale(lookupEnv LookupEnvFunc) bool {
+	for _, key := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale, _ := lookupEnv(key); locale != "" {
+			locale = strings.ToLower(locale)
+			return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+		}
+	}
+	return true
+}
+
+// Capabilities returns the output capabilities detected for the current invocation
+func (baseCmd *BaseCommand) Capabilities() TerminalCapabilities {
+	return baseCmd.capabilities
+}
+
+// TerminalWidth returns the number of columns available for output or DefaultTerminalWidth when unknown
+func (baseCmd *BaseCommand) TerminalWidth() int {
+	if baseCmd.capabilities.Width > 0 {
+		return baseCmd.capabilities.Width
+	}
+	return DefaultTerminalWidth
+}
+
+// detectCapabilities detects the output capabilities and configures the color settings and palette of output libraries to match
+// Colors are disabled by --no-colors and both colors and animation by accessible mode
+func (baseCmd *BaseCommand) detectCapabilities() error {
+	capabilities := DetectTerminalCapabilities(baseCmd.rootCobraCommand.OutOrStdout(), os.LookupEnv)
+	if width, _ := baseCmd.rootCobraCommand.PersistentFlags().GetInt(KeyWidth); width < 0 {
+		return fmt.Errorf("invalid width %d: must be a positive number of columns", width)
+	} else if width > 0 {
+		capabilities.Width = width
+	}
+	if baseCmd.disableColors {
+		capabilities.Color = false
+	}
+	if baseCmd.Accessible() {
+		capabilities.Color = false
+		capabilities.Animation = false
+	}
+	if baseCmd.viperCfg != nil && baseCmd.viperCfg.GetBool(KeyASCIIOnly) {
+		capabilities.ASCII = true
+	}
+	baseCmd.capabilities = capabilities
+
+	activeGlyphs = nil
+	if baseCmd.Accessible() {
+		activeGlyphs = accessibleGlyphs
+	} else if capabilities.ASCII {
+		activeGlyphs = asciiGlyphs
+	}
+
+	palette, err := baseCmd.Palette()
+	if err != nil {
+		baseCmd.PrintErrf("warning: %s, using the %s palette\n", err, PaletteDefault)
+		palette = Palettes[PaletteDefault]
+	}
+	activePalette = palette
+
+	baseCmd.disableColors = !capabilities.Color
+	color.NoColor = !capabilities.Color
+	core.DisableColor = !capabilities.Color
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/opsani"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func lookupEnv(env map[string]string) command.LookupEnvFunc {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestDetectTerminalCapabilities(t *testing.T) {
+	out := new(bytes.Buffer)
+	require.Equal(t, command.TerminalCapabilities{}, command.DetectTerminalCapabilities(out, lookupEnv(nil)))
+	require.Equal(t, command.TerminalCapabilities{Color: true}, command.DetectTerminalCapabilities(out, lookupEnv(map[string]string{"CLICOLOR_FORCE": "1"})))
+	require.Equal(t, command.TerminalCapabilities{Color: true}, command.DetectTerminalCapabilities(out, lookupEnv(map[string]string{"CLICOLOR_FORCE": "1", "TERM": "dumb"})))
+	require.Equal(t, command.TerminalCapabilities{}, command.DetectTerminalCapabilities(out, lookupEnv(map[string]string{"CLICOLOR_FORCE": "0"})))
+	require.Equal(t, command.TerminalCapabilities{}, command.DetectTerminalCapabilities(out, lookupEnv(map[string]string{"CLICOLOR_FORCE": "1", "NO_COLOR": ""})))
+	require.Equal(t, command.TerminalCapabilities{Width: 120}, command.DetectTerminalCapabilities(out, lookupEnv(map[string]string{"COLUMNS": "120"})))
+	require.Equal(t, command.TerminalCapabilities{}, command.DetectTerminalCapabilities(out, lookupEnv(map[string]string{"COLUMNS": "wide"})))
+}
+
+func TestDetectTerminalCapabilitiesLocale(t *testing.T) {
+	out := new(bytes.Buffer)
+	require.False(t, command.DetectTerminalCapabilities(out, lookupEnv(map[string]string{"LANG": "en_US.UTF-8"})).ASCII)
+	require.False(t, command.DetectTerminalCapabilities(out, lookupEnv(map[string]string{"LANG": "de_DE.utf8"})).ASCII)
+	require.True(t, command.DetectTerminalCapabilities(out, lookupEnv(map[string]string{"LANG": "C"})).ASCII)
+	require.True(t, command.DetectTerminalCapabilities(out, lookupEnv(map[string]string{"LANG": "en_US.UTF-8", "LC_ALL": "POSIX"})).ASCII)
+	require.False(t, command.DetectTerminalCapabilities(out, lookupEnv(map[string]string{"LANG": "C", "LC_ALL": "", "LC_CTYPE": "C.UTF-8"})).ASCII)
+}
+
+type CapabilitiesTestSuite struct {
+	test.Suite
+	noColor bool
+}
+
+func TestCapabilitiesTestSuite(t *testing.T) {
+	suite.Run(t, new(CapabilitiesTestSuite))
+}
+
+func (s *CapabilitiesTestSuite) SetupTest() {
+	s.noColor = color.NoColor
+	s.SetCommand(command.NewRootCommand())
+	s.UnsetEnv("NO_COLOR")
+	s.UnsetEnv("CLICOLOR_FORCE")
+}
+
+func (s *CapabilitiesTestSuite) TearDownTest() {
+	color.NoColor = s.noColor
+}
+
+func (s *CapabilitiesTestSuite) TestRedirectedOutputIsPlain() {
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "config")
+	s.Require().NoError(err)
+	s.Require().NotContains(output, "\x1b[")
+	s.Require().True(color.NoColor)
+}
+
+func (s *CapabilitiesTestSuite) TestForcedColors() {
+	s.SetEnv("CLICOLOR_FORCE", "1")
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "config")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "\x1b[")
+	s.Require().False(color.NoColor)
+}
+
+func (s *CapabilitiesTestSuite) TestNoColorsFlagOverridesForcedColors() {
+	s.SetEnv("CLICOLOR_FORCE", "1")
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "--no-colors", "config")
+	s.Require().NoError(err)
+	s.Require().NotContains(output, "\x1b[")
+}
+
+func (s *CapabilitiesTestSuite) TestAccessibleModeDisablesForcedColors() {
+	s.SetEnv("CLICOLOR_FORCE", "1")
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"ui":       map[string]interface{}{"accessible": true},
+		"profiles": []map[string]interface{}{{"name": "default", "optimizer": "example.com/app", "token": "123456"}},
+	})
+	output, err := s.Execute("--config", configFile.Name(), "config")
+	s.Require().NoError(err)
+	s.Require().NotContains(output, "\x1b[")
+}
+
+func (s *CapabilitiesTestSuite) TestColorBlindPalette() {
+	s.SetEnv("CLICOLOR_FORCE", "1")
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"ui":       map[string]interface{}{"palette": "colorblind"},
+		"profiles": []map[string]interface{}{{"name": "default", "optimizer": "example.com/app", "token": "123456"}},
+	})
+	output, err := s.Execute("--config", configFile.Name(), "config")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "\x1b[94mprofiles")
+	s.Require().NotContains(output, "\x1b[96mprofiles")
+
+	output, err = s.Execute("--config", kubernetesServoConfigFile(), "config")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "\x1b[96mprofiles")
+}
+
+func (s *CapabilitiesTestSuite) TestInvalidPaletteFallsBackToDefault() {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"ui":       map[string]interface{}{"palette": "neon"},
+		"profiles": []map[string]interface{}{{"name": "default", "optimizer": "example.com/app", "token": "123456"}},
+	})
+	output, err := s.Execute("--config", configFile.Name(), "config")
+	s.Require().NoError(err)
+	s.Require().Contains(output, `warning: invalid palette "neon": must be one of colorblind, default, using the default palette`)
+}
+
+func (s *CapabilitiesTestSuite) TestASCIILocaleReplacesGlyphs() {
+	apiErr := &opsani.APIError{Status: "400 Bad Request", Message: "invalid"}
+	s.SetEnv("LANG", "C")
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "config")
+	s.Require().NoError(err)
+	s.Require().Equal("+ request failed (400 Bad Request)\n| invalid\n+\n", command.RenderAPIError(apiErr, 80, false))
+
+	s.UnsetEnv("LANG")
+	_, err = s.Execute("--config", kubernetesServoConfigFile(), "config")
+	s.Require().NoError(err)
+	s.Require().Equal("╭ request failed (400 Bad Request)\n│ invalid\n╰\n", command.RenderAPIError(apiErr, 80, false))
+}
@@ -0,0 +1,313 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+)
+
+// Autoscaler kinds that conflict with optimization
+const (
+	AutoscalerHorizontal = "HorizontalPodAutoscaler"
+	AutoscalerVertical   = "VerticalPodAutoscaler"
+)
+
+// Annotations recording the autoscaler settings replaced while paused for optimization
+const (
+	annotationPausedBy          = "opsani.com/paused-by"
+	annotationPausedMinReplicas = "opsani.com/paused-min-replicas"
+	annotationPausedMaxReplicas = "opsani.com/paused-max-replicas"
+	annotationPausedUpdateMode  = "opsani.com/paused-update-mode"
+)
+
+// Autoscaler is a horizontal or vertical pod autoscaler targeting an optimized deployment
+type Autoscaler struct {
+	Kind      string
+	Namespace string
+	Name      string
+
+	// Conflict explains how the autoscaler interferes with adjustments and is empty if it does not
+	Conflict string
+
+	// Paused is true when the autoscaler has been paused for optimization
+	Paused bool
+
+	minReplicas     int64
+	maxReplicas     int64
+	currentReplicas int64
+	updateMode      string
+	annotations     map[string]gjson.Result
+}
+
+// resource returns the kubectl resource name of the autoscaler kind
+func (a Autoscaler) resource() string {
+	if a.Kind == AutoscalerVertical {
+		return "verticalpodautoscalers.autoscaling.k8s.io"
+	}
+	return "horizontalpodautoscalers"
+}
+
+// DetectAutoscalers returns the autoscalers in the namespace targeting the deployment
+// Vertical pod autoscalers are only detected when their CRD is installed.
+func DetectAutoscalers(ctx context.Context, kubectl KubectlRunner, namespace string, deployment string) ([]Autoscaler, error) {
+	autoscalers := []Autoscaler{}
+	output, err := kubectl(ctx, "get", "horizontalpodautoscalers", "-n", namespace, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed listing HorizontalPodAutoscalers: %w", err)
+	}
+	for _, hpa := range gjson.GetBytes(output, "items").Array() {
+		if hpa.Get("spec.scaleTargetRef.kind").String() != WorkloadDeployment || hpa.Get("spec.scaleTargetRef.name").String() != deployment {
+			continue
+		}
+		autoscaler := newAutoscaler(AutoscalerHorizontal, namespace, hpa)
+		autoscaler.minReplicas = hpa.Get("spec.minReplicas").Int()
+		if autoscaler.minReplicas == 0 {
+			// Kubernetes defaults to a minimum of one replica
+			autoscaler.minReplicas = 1
+		}
+		autoscaler.maxReplicas = hpa.Get("spec.maxReplicas").Int()
+		autoscaler.currentReplicas = hpa.Get("status.currentReplicas").Int()
+		if resources := hpaResourceMetrics(hpa); len(resources) > 0 {
+			autoscaler.Conflict = fmt.Sprintf("scales on %s utilization, which is relative to the requests the optimizer adjusts", strings.Join(resources, " and "))
+		} else {
+			autoscaler.Conflict = "sets the replica count, overriding the replicas chosen by the optimizer"
+		}
+		autoscalers = append(autoscalers, autoscaler)
+	}
+
+	// The VPA CRD is optional
+	if output, err := kubectl(ctx, "get", "verticalpodautoscalers.autoscaling.k8s.io", "-n", namespace, "-o", "json"); err == nil {
+		for _, vpa := range gjson.GetBytes(output, "items").Array() {
+			if vpa.Get("spec.targetRef.kind").String() != WorkloadDeployment || vpa.Get("spec.targetRef.name").String() != deployment {
+				continue
+			}
+			autoscaler := newAutoscaler(AutoscalerVertical, namespace, vpa)
+			autoscaler.updateMode = vpa.Get("spec.updatePolicy.updateMode").String()
+			if autoscaler.updateMode == "" {
+				autoscaler.updateMode = "Auto"
+			}
+			if autoscaler.updateMode != "Off" {
+				autoscaler.Conflict = fmt.Sprintf("rewrites the cpu and memory requests set by the optimizer in %s mode", autoscaler.updateMode)
+			}
+			autoscalers = append(autoscalers, autoscaler)
+		}
+	}
+	return autoscalers, nil
+}
+
+func newAutoscaler(kind string, namespace string, item gjson.Result) Autoscaler {
+	annotations := item.Get("metadata.annotations").Map()
+	_, paused := annotations[annotationPausedBy]
+	return Autoscaler{
+		Kind:        kind,
+		Namespace:   namespace,
+		Name:        item.Get("metadata.name").String(),
+		Paused:      paused,
+		annotations: annotations,
+	}
+}
+
+// PauseAutoscaler stops the autoscaler from interfering with adjustments, recording its settings in annotations
+// Horizontal autoscalers are pinned to their current replica count and vertical autoscalers are switched to recommendation only.
+func PauseAutoscaler(ctx context.Context, kubectl KubectlRunner, autoscaler Autoscaler, pausedBy string) (string, error) {
+	annotations := map[string]interface{}{annotationPausedBy: pausedBy}
+	spec := map[string]interface{}{}
+	var description string
+	if autoscaler.Kind == AutoscalerVertical {
+		annotations[annotationPausedUpdateMode] = autoscaler.updateMode
+		spec["updatePolicy"] = map[string]interface{}{"updateMode": "Off"}
+		description = fmt.Sprintf("update mode set to Off (was %s)", autoscaler.updateMode)
+	} else {
+		replicas := autoscaler.currentReplicas
+		if replicas < autoscaler.minReplicas {
+			replicas = autoscaler.minReplicas
+		}
+		if replicas < 1 {
+			replicas = 1
+		}
+		annotations[annotationPausedMinReplicas] = strconv.FormatInt(autoscaler.minReplicas, 10)
+		annotations[annotationPausedMaxReplicas] = strconv.FormatInt(autoscaler.maxReplicas, 10)
+		spec["minReplicas"] = replicas
+		spec["maxReplicas"] = replicas
+		description = fmt.Sprintf("replicas pinned to %d (was %d-%d)", replicas, autoscaler.minReplicas, autoscaler.maxReplicas)
+	}
+	return description, patchAutoscaler(ctx, kubectl, autoscaler, annotations, spec)
+}
+
+// ResumeAutoscaler restores the settings recorded when the autoscaler was paused
+func ResumeAutoscaler(ctx context.Context, kubectl KubectlRunner, autoscaler Autoscaler) (string, error) {
+	annotations := map[string]interface{}{annotationPausedBy: nil}
+	spec := map[string]interface{}{}
+	var description string
+	if autoscaler.Kind == AutoscalerVertical {
+		updateMode := autoscaler.annotations[annotationPausedUpdateMode].String()
+		if updateMode == "" {
+			return "", fmt.Errorf("cannot resume %s %s: annotation %s is missing", autoscaler.Kind, autoscaler.Name, annotationPausedUpdateMode)
+		}
+		annotations[annotationPausedUpdateMode] = nil
+		spec["updatePolicy"] = map[string]interface{}{"updateMode": updateMode}
+		description = fmt.Sprintf("update mode restored to %s", updateMode)
+	} else {
+		minReplicas, err := strconv.Atoi(autoscaler.annotations[annotationPausedMinReplicas].String())
+		if err != nil {
+			return "", fmt.Errorf("cannot resume %s %s: annotation %s is invalid", autoscaler.Kind, autoscaler.Name, annotationPausedMinReplicas)
+		}
+		maxReplicas, err := strconv.Atoi(autoscaler.annotations[annotationPausedMaxReplicas].String())
+		if err != nil {
+			return "", fmt.Errorf("cannot resume %s %s: annotation %s is invalid", autoscaler.Kind, autoscaler.Name, annotationPausedMaxReplicas)
+		}
+		annotations[annotationPausedMinReplicas] = nil
+		annotations[annotationPausedMaxReplicas] = nil
+		spec["minReplicas"] = minReplicas
+		spec["maxReplicas"] = maxReplicas
+		description = fmt.Sprintf("replicas restored to %d-%d", minReplicas, maxReplicas)
+	}
+	return description, patchAutoscaler(ctx, kubectl, autoscaler, annotations, spec)
+}
+
+// patchAutoscaler applies a merge patch, where nil annotations are removed
+func patchAutoscaler(ctx context.Context, kubectl KubectlRunner, autoscaler Autoscaler, annotations map[string]interface{}, spec map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+		"spec":     spec,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = kubectl(ctx, "patch", autoscaler.resource(), autoscaler.Name, "-n", autoscaler.Namespace, "--type", "merge", "-p", string(patch))
+	return err
+}
+
+// NewCheckCommand returns a new `opsani check` command instance
+func NewCheckCommand(baseCmd *BaseCommand) *cobra.Command {
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check applications for conflicts with optimization",
+		Args:  cobra.NoArgs,
+	}
+	checkCmd.AddCommand(NewCheckAutoscalersCommand(baseCmd))
+	checkCmd.AddCommand(NewCheckLinkCommand(baseCmd))
+	return checkCmd
+}
+
+// NewCheckAutoscalersCommand returns a new `opsani check autoscalers` command instance
+func NewCheckAutoscalersCommand(baseCmd *BaseCommand) *cobra.Command {
+	autoscalersCmd := &cobra.Command{
+		Use:   "autoscalers",
+		Short: "Detect autoscalers that conflict with optimization",
+		Long: `Detects the HorizontalPodAutoscalers and VerticalPodAutoscalers targeting the optimized
+deployment and explains how they interfere with adjustments made by the optimizer.
+
+With --pause, conflicting autoscalers are paused for the optimization window:
+horizontal autoscalers are pinned to their current replica count and vertical
+autoscalers are switched to recommendation only. The original settings are
+recorded in opsani.com annotations on each autoscaler and restored with --resume.`,
+		Example: `  opsani check autoscalers -n apps --deployment web
+  opsani check autoscalers -n apps --deployment web --pause
+  opsani check autoscalers -n apps --deployment web --resume`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: ReduceRunEFuncs(baseCmd.InitConfigRunE, baseCmd.RequireConfigFileFlagToExistRunE),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := OutputFormat(cmd, TabularOutputFormats...)
+			if err != nil {
+				return err
+			}
+			namespace, _ := cmd.Flags().GetString("namespace")
+			deployment, _ := cmd.Flags().GetString("deployment")
+			pause, _ := cmd.Flags().GetBool("pause")
+			resume, _ := cmd.Flags().GetBool("resume")
+			if pause && resume {
+				return fmt.Errorf("--pause and --resume cannot be used together")
+			}
+
+			kubeconfig, pausedBy := "", "opsani"
+			if baseCmd.profile != nil {
+				kubeconfig = baseCmd.profile.Servo.Kubeconfig
+				if baseCmd.profile.Optimizer != "" {
+					pausedBy = baseCmd.profile.Optimizer
+				}
+			}
+			kubectl := kubectlRunner(kubeconfig)
+			ctx, cancel := baseCmd.ContextWithTimeout()
+			defer cancel()
+			autoscalers, err := DetectAutoscalers(ctx, kubectl, namespace, deployment)
+			if err != nil {
+				return err
+			}
+			if len(autoscalers) == 0 {
+				baseCmd.Printf("No autoscalers target deployment %s/%s\n", namespace, deployment)
+				return nil
+			}
+
+			if pause || resume {
+				pending := []Autoscaler{}
+				for _, autoscaler := range autoscalers {
+					if (pause && autoscaler.Conflict != "" && !autoscaler.Paused) || (resume && autoscaler.Paused) {
+						pending = append(pending, autoscaler)
+					}
+				}
+				if len(pending) == 0 {
+					baseCmd.Println("No autoscalers to update")
+					return nil
+				}
+				action := "Pause"
+				if resume {
+					action = "Resume"
+				}
+				confirmed, err := baseCmd.Confirm(fmt.Sprintf("%s %d autoscalers targeting deployment %s/%s?", action, len(pending), namespace, deployment), true)
+				if err != nil || !confirmed {
+					return err
+				}
+				for _, autoscaler := range pending {
+					var description string
+					if pause {
+						description, err = PauseAutoscaler(ctx, kubectl, autoscaler, pausedBy)
+					} else {
+						description, err = ResumeAutoscaler(ctx, kubectl, autoscaler)
+					}
+					if err != nil {
+						return err
+					}
+					baseCmd.Printf("%sd %s %s: %s\n", action, autoscaler.Kind, autoscaler.Name, description)
+				}
+				return nil
+			}
+
+			table := Table{Headers: []string{"KIND", "NAME", "PAUSED", "CONFLICT"}}
+			for _, autoscaler := range autoscalers {
+				conflict := autoscaler.Conflict
+				if conflict == "" {
+					conflict = "none: recommendations only"
+				}
+				table.Rows = append(table.Rows, []string{autoscaler.Kind, autoscaler.Name, strconv.FormatBool(autoscaler.Paused), conflict})
+			}
+			return baseCmd.RenderTable(output, table)
+		},
+	}
+	autoscalersCmd.Flags().StringP("namespace", "n", "default", "Namespace of the optimized deployment")
+	autoscalersCmd.Flags().String("deployment", "", "Name of the optimized deployment")
+	autoscalersCmd.MarkFlagRequired("deployment")
+	autoscalersCmd.Flags().Bool("pause", false, "Pause conflicting autoscalers for the optimization window")
+	autoscalersCmd.Flags().Bool("resume", false, "Restore autoscalers paused with --pause")
+	AddOutputFlag(autoscalersCmd, TabularOutputFormats...)
+	return autoscalersCmd
+}
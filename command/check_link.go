@@ -0,0 +1,250 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+)
+
+// ServoLink is the optimizer and token that a servo deployment runs with
+type ServoLink struct {
+	Optimizer string
+	Token     string
+}
+
+// LinkCheck compares a setting of the servo against the active profile
+type LinkCheck struct {
+	Name    string
+	Servo   string
+	Profile string
+	Err     error
+}
+
+// Status returns the outcome of the check
+func (check LinkCheck) Status() string {
+	if check.Err != nil {
+		return "unknown"
+	} else if check.Servo != check.Profile {
+		return "mismatch"
+	}
+	return "ok"
+}
+
+// servoKubectl returns a runner executing kubectl against the cluster of the servo
+func servoKubectl(servo Servo) KubectlRunner {
+	kubectl := kubectlRunner(servo.Kubeconfig)
+	if servo.Context == "" {
+		return kubectl
+	}
+	return func(ctx context.Context, args ...string) ([]byte, error) {
+		return kubectl(ctx, append([]string{"--context", servo.Context}, args...)...)
+	}
+}
+
+// gjsonKey escapes a key containing dots for use in a gjson path
+func gjsonKey(key string) string {
+	return strings.ReplaceAll(key, ".", `\.`)
+}
+
+// resourceValue returns a key of a ConfigMap or Secret, decoding Secret data
+func resourceValue(ctx context.Context, kubectl KubectlRunner, namespace, kind, name, key string) (string, error) {
+	output, err := kubectl(ctx, "-n", namespace, "get", kind, name, "-o", "json")
+	if err != nil {
+		return "", err
+	}
+	value := gjson.GetBytes(output, "data."+gjsonKey(key))
+	if !value.Exists() {
+		return "", fmt.Errorf("%s %s/%s has no key %q", kind, namespace, name, key)
+	}
+	if kind != "secret" {
+		return value.String(), nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(value.String())
+	if err != nil {
+		return "", fmt.Errorf("secret %s/%s key %q: %w", namespace, name, key, err)
+	}
+	return string(decoded), nil
+}
+
+// containerEnv resolves an environment variable of a container, reading values referenced from ConfigMaps and Secrets
+func containerEnv(ctx context.Context, kubectl KubectlRunner, namespace string, container gjson.Result, name string) (string, bool, error) {
+	env := container.Get(fmt.Sprintf(`env.#(name==%q)`, name))
+	if !env.Exists() {
+		return "", false, nil
+	}
+	if ref := env.Get("valueFrom.secretKeyRef"); ref.Exists() {
+		value, err := resourceValue(ctx, kubectl, namespace, "secret", ref.Get("name").String(), ref.Get("key").String())
+		return value, true, err
+	}
+	if ref := env.Get("valueFrom.configMapKeyRef"); ref.Exists() {
+		value, err := resourceValue(ctx, kubectl, namespace, "configmap", ref.Get("name").String(), ref.Get("key").String())
+		return value, true, err
+	}
+	return env.Get("value").String(), true, nil
+}
+
+// mountedSecretValue reads the Secret key mounted at a file path of a container
+func mountedSecretValue(ctx context.Context, kubectl KubectlRunner, namespace string, pod, container gjson.Result, file string) (string, error) {
+	for _, mount := range container.Get("volumeMounts").Array() {
+		mountPath, subPath := mount.Get("mountPath").String(), mount.Get("subPath").String()
+		relative := ""
+		if subPath != "" && mountPath == file {
+			relative = subPath
+		} else if subPath == "" && strings.HasPrefix(file, strings.TrimSuffix(mountPath, "/")+"/") {
+			relative = strings.TrimPrefix(file, strings.TrimSuffix(mountPath, "/")+"/")
+		} else {
+			continue
+		}
+
+		volume := pod.Get(fmt.Sprintf(`volumes.#(name==%q)`, mount.Get("name").String()))
+		secretName := volume.Get("secret.secretName").String()
+		if secretName == "" {
+			return "", fmt.Errorf("volume %q mounted at %s is not a secret", mount.Get("name").String(), file)
+		}
+		key := relative
+		if item := volume.Get(fmt.Sprintf(`secret.items.#(path==%q)`, path.Clean(relative))); item.Exists() {
+			key = item.Get("key").String()
+		}
+		return resourceValue(ctx, kubectl, namespace, "secret", secretName, key)
+	}
+	return "", fmt.Errorf("no volume is mounted at %s", file)
+}
+
+// ReadServoLink reads the optimizer and token of a Kubernetes servo from its deployment and the ConfigMaps and Secrets it references
+// The optimizer falls back to the servo.opsani.com/optimizer annotation when the servo has no OPSANI_OPTIMIZER variable
+func ReadServoLink(ctx context.Context, kubectl KubectlRunner, servo Servo) (link ServoLink, optimizerErr error, tokenErr error) {
+	output, err := kubectl(ctx, "-n", servo.Namespace, "get", "deployment", servo.Deployment, "-o", "json")
+	if err != nil {
+		return link, err, err
+	}
+	pod := gjson.GetBytes(output, "spec.template.spec")
+	containers := pod.Get("containers").Array()
+	if len(containers) == 0 {
+		err := fmt.Errorf("deployment %s/%s has no containers", servo.Namespace, servo.Deployment)
+		return link, err, err
+	}
+	container := containers[0]
+	for _, candidate := range containers {
+		if candidate.Get(`env.#(name=="OPSANI_OPTIMIZER")`).Exists() || candidate.Get("name").String() == "servo" {
+			container = candidate
+			break
+		}
+	}
+
+	optimizer, found, optimizerErr := containerEnv(ctx, kubectl, servo.Namespace, container, "OPSANI_OPTIMIZER")
+	if !found {
+		optimizer = gjson.GetBytes(output, "metadata.annotations."+gjsonKey(annotationServoOptimizer)).String()
+	}
+	if optimizerErr == nil && optimizer == "" {
+		optimizerErr = fmt.Errorf("servo does not set OPSANI_OPTIMIZER")
+	}
+	if normalized, err := NormalizeOptimizer(optimizer); err == nil {
+		optimizer = normalized
+	}
+	link.Optimizer = optimizer
+
+	token, found, tokenErr := containerEnv(ctx, kubectl, servo.Namespace, container, "OPSANI_TOKEN")
+	if !found {
+		file, found, err := containerEnv(ctx, kubectl, servo.Namespace, container, "OPSANI_TOKEN_FILE")
+		if err != nil {
+			tokenErr = err
+		} else if !found {
+			tokenErr = fmt.Errorf("servo sets neither OPSANI_TOKEN nor OPSANI_TOKEN_FILE")
+		} else {
+			token, tokenErr = mountedSecretValue(ctx, kubectl, servo.Namespace, pod, container, file)
+		}
+	}
+	link.Token = strings.TrimSpace(token)
+	return link, optimizerErr, tokenErr
+}
+
+// NewCheckLinkCommand returns a new `opsani check link` command instance
+func NewCheckLinkCommand(baseCmd *BaseCommand) *cobra.Command {
+	linkCmd := &cobra.Command{
+		Use:   "link",
+		Short: "Check that the servo reports to the optimizer of the active profile",
+		Long: `Compares the optimizer and token that the attached Kubernetes servo runs with against the
+active profile. The optimizer is read from the OPSANI_OPTIMIZER variable of the servo and the
+token from the OPSANI_TOKEN variable or the Secret mounted at OPSANI_TOKEN_FILE, following
+references to ConfigMaps and Secrets.
+
+A servo running with another optimizer or a stale token reports nowhere the console shows,
+which otherwise only surfaces as an optimizer without data. Tokens are redacted in the output
+unless --show-secrets is given.`,
+		Example: `  opsani check link
+  opsani --profile staging check link`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: ReduceRunEFuncs(baseCmd.InitConfigRunE, baseCmd.RequireConfigFileFlagToExistRunE),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := OutputFormat(cmd, TabularOutputFormats...)
+			if err != nil {
+				return err
+			}
+			profile := baseCmd.profile
+			if profile == nil || profile.Servo.Type != "kubernetes" {
+				return fmt.Errorf("no Kubernetes servo attached to the active profile: attach one with `opsani servo attach`")
+			}
+
+			ctx, cancel := baseCmd.ContextWithTimeout()
+			defer cancel()
+			link, optimizerErr, tokenErr := ReadServoLink(ctx, servoKubectl(profile.Servo), profile.Servo)
+			checks := []LinkCheck{
+				{Name: "Optimizer", Servo: link.Optimizer, Profile: profile.Optimizer, Err: optimizerErr},
+				{Name: "Token", Servo: link.Token, Profile: profile.Token, Err: tokenErr},
+			}
+
+			table := Table{Headers: []string{"SETTING", "SERVO", "PROFILE", "STATUS"}}
+			mismatches := 0
+			for _, check := range checks {
+				servoValue, profileValue := check.Servo, check.Profile
+				if check.Name == "Token" {
+					servoValue, profileValue = baseCmd.Redact(servoValue), baseCmd.Redact(profileValue)
+				}
+				if check.Err != nil {
+					servoValue = "-"
+				}
+				status := check.Status()
+				if status == "mismatch" {
+					mismatches++
+				}
+				if output == OutputTable {
+					status = baseCmd.ColorGlyph(map[string]string{"ok": glyphSuccess, "mismatch": glyphFailure, "unknown": glyphWarning}[status]) + " " + status
+				}
+				table.Rows = append(table.Rows, []string{check.Name, servoValue, profileValue, status})
+			}
+			if err := baseCmd.RenderTable(output, table); err != nil {
+				return err
+			}
+			for _, check := range checks {
+				if check.Err != nil {
+					baseCmd.PrintErrf("warning: unable to read the %s of servo %s: %s\n", strings.ToLower(check.Name), profile.Servo.Description(), check.Err)
+				}
+			}
+			if mismatches > 0 {
+				return fmt.Errorf("servo %s is not linked to the optimizer of profile %q: its reports will not appear in the console", profile.Servo.Description(), profile.Name)
+			}
+			return nil
+		},
+	}
+	AddOutputFlag(linkCmd, TabularOutputFormats...)
+	return linkCmd
+}
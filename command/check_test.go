@@ -0,0 +1,187 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const autoscalersFixture = `{"items": [
+	{"metadata": {"name": "web"}, "spec": {"scaleTargetRef": {"kind": "Deployment", "name": "web"}, "minReplicas": 2, "maxReplicas": 10, "targetCPUUtilizationPercentage": 75}, "status": {"currentReplicas": 4}},
+	{"metadata": {"name": "web-queue"}, "spec": {"scaleTargetRef": {"kind": "Deployment", "name": "web"}, "maxReplicas": 5, "metrics": [{"type": "External"}]}},
+	{"metadata": {"name": "api"}, "spec": {"scaleTargetRef": {"kind": "Deployment", "name": "api"}, "maxReplicas": 3}}
+]}`
+
+const verticalAutoscalersFixture = `{"items": [
+	{"metadata": {"name": "web-vpa"}, "spec": {"targetRef": {"kind": "Deployment", "name": "web"}}},
+	{"metadata": {"name": "web-recommender"}, "spec": {"targetRef": {"kind": "Deployment", "name": "web"}, "updatePolicy": {"updateMode": "Off"}}}
+]}`
+
+func TestDetectAutoscalers(t *testing.T) {
+	kubectl := cannedKubectl(map[string]string{
+		"get horizontalpodautoscalers -n apps -o json":                  autoscalersFixture,
+		"get verticalpodautoscalers.autoscaling.k8s.io -n apps -o json": verticalAutoscalersFixture,
+	})
+	autoscalers, err := command.DetectAutoscalers(context.Background(), kubectl, "apps", "web")
+	require.NoError(t, err)
+	require.Len(t, autoscalers, 4)
+	require.Equal(t, "scales on cpu utilization, which is relative to the requests the optimizer adjusts", autoscalers[0].Conflict)
+	require.Equal(t, "sets the replica count, overriding the replicas chosen by the optimizer", autoscalers[1].Conflict)
+	require.Equal(t, command.AutoscalerVertical, autoscalers[2].Kind)
+	require.Equal(t, "rewrites the cpu and memory requests set by the optimizer in Auto mode", autoscalers[2].Conflict)
+	require.Equal(t, "", autoscalers[3].Conflict)
+}
+
+func TestDetectAutoscalersWithoutVPA(t *testing.T) {
+	kubectl := cannedKubectl(map[string]string{
+		"get horizontalpodautoscalers -n apps -o json": autoscalersFixture,
+	})
+	autoscalers, err := command.DetectAutoscalers(context.Background(), kubectl, "apps", "api")
+	require.NoError(t, err)
+	require.Len(t, autoscalers, 1)
+	require.Equal(t, "api", autoscalers[0].Name)
+}
+
+type CheckTestSuite struct {
+	test.Suite
+	recorder *test.ExecRecorder
+}
+
+func TestCheckTestSuite(t *testing.T) {
+	suite.Run(t, new(CheckTestSuite))
+}
+
+func (s *CheckTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+	s.recorder = test.NewExecRecorder()
+	s.recorder.Respond("kubectl get horizontalpodautoscalers", test.ExecResponse{Stdout: autoscalersFixture})
+	s.recorder.Respond("kubectl get verticalpodautoscalers", test.ExecResponse{ExitCode: 1})
+	command.SetCommandContextFunc(s.recorder.CommandContext)
+}
+
+func (s *CheckTestSuite) TearDownTest() {
+	command.SetCommandContextFunc(nil)
+}
+
+func (s *CheckTestSuite) configFile() string {
+	return test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{"name": "default", "optimizer": "example.com/app", "token": "123456"},
+		},
+	}).Name()
+}
+
+func (s *CheckTestSuite) TestAutoscalers() {
+	output, err := s.Execute("--config", s.configFile(), "check", "autoscalers", "-n", "apps", "--deployment", "web")
+	s.Require().NoError(err)
+	s.Require().Regexp(`HorizontalPodAutoscaler\s+web\s+false\s+scales on cpu utilization`, output)
+	s.Require().Regexp(`HorizontalPodAutoscaler\s+web-queue\s+false\s+sets the replica count`, output)
+	s.Require().NotContains(output, "api")
+}
+
+func (s *CheckTestSuite) TestAutoscalersNoneFound() {
+	output, err := s.Execute("--config", s.configFile(), "check", "autoscalers", "-n", "apps", "--deployment", "worker")
+	s.Require().NoError(err)
+	s.Require().Equal("No autoscalers target deployment apps/worker\n", output)
+}
+
+func (s *CheckTestSuite) TestAutoscalersPause() {
+	output, err := s.Execute("--config", s.configFile(), "--yes", "check", "autoscalers", "-n", "apps", "--deployment", "web", "--pause")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Paused HorizontalPodAutoscaler web: replicas pinned to 4 (was 2-10)\n")
+	s.Require().Contains(output, "Paused HorizontalPodAutoscaler web-queue: replicas pinned to 1 (was 1-5)\n")
+	s.Require().Equal([]string{"kubectl", "patch", "horizontalpodautoscalers", "web-queue", "-n", "apps", "--type", "merge", "-p",
+		`{"metadata":{"annotations":{"opsani.com/paused-by":"example.com/app","opsani.com/paused-max-replicas":"5","opsani.com/paused-min-replicas":"1"}},"spec":{"maxReplicas":1,"minReplicas":1}}`,
+	}, s.recorder.LastInvocation())
+}
+
+func (s *CheckTestSuite) TestAutoscalersResume() {
+	s.recorder.Respond("kubectl get horizontalpodautoscalers", test.ExecResponse{Stdout: `{"items": [
+		{"metadata": {"name": "web", "annotations": {"opsani.com/paused-by": "example.com/app", "opsani.com/paused-min-replicas": "2", "opsani.com/paused-max-replicas": "10"}},
+		 "spec": {"scaleTargetRef": {"kind": "Deployment", "name": "web"}, "minReplicas": 4, "maxReplicas": 4}}
+	]}`})
+	output, err := s.Execute("--config", s.configFile(), "--yes", "check", "autoscalers", "-n", "apps", "--deployment", "web", "--resume")
+	s.Require().NoError(err)
+	s.Require().Equal("Resumed HorizontalPodAutoscaler web: replicas restored to 2-10\n", output)
+	s.Require().Equal([]string{"kubectl", "patch", "horizontalpodautoscalers", "web", "-n", "apps", "--type", "merge", "-p",
+		`{"metadata":{"annotations":{"opsani.com/paused-by":null,"opsani.com/paused-max-replicas":null,"opsani.com/paused-min-replicas":null}},"spec":{"maxReplicas":10,"minReplicas":2}}`,
+	}, s.recorder.LastInvocation())
+}
+
+func (s *CheckTestSuite) TestAutoscalersPauseAndResume() {
+	_, err := s.Execute("--config", s.configFile(), "check", "autoscalers", "--deployment", "web", "--pause", "--resume")
+	s.Require().EqualError(err, "--pause and --resume cannot be used together")
+}
+
+const servoDeploymentFixture = `{
+	"metadata": {"name": "servo", "annotations": {"servo.opsani.com/optimizer": "example.com/app"}},
+	"spec": {"template": {"spec": {
+		"containers": [{
+			"name": "servo",
+			"env": [{"name": "OPSANI_OPTIMIZER", "value": "example.com/app"}, {"name": "OPSANI_TOKEN_FILE", "value": "/servo/opsani.token"}],
+			"volumeMounts": [{"name": "servo-token-volume", "mountPath": "/servo/opsani.token", "subPath": "opsani.token"}]
+		}],
+		"volumes": [{"name": "servo-token-volume", "secret": {"secretName": "servo-token", "items": [{"key": "token", "path": "opsani.token"}]}}]
+	}}}
+}`
+
+func (s *CheckTestSuite) linkConfigFile() string {
+	return test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]interface{}{
+			{
+				"name": "default", "optimizer": "example.com/app", "token": "123456",
+				"servo": map[string]string{"type": "kubernetes", "namespace": "opsani", "deployment": "servo", "context": "production"},
+			},
+		},
+	}).Name()
+}
+
+func (s *CheckTestSuite) TestLink() {
+	s.recorder.Respond("kubectl --context production -n opsani get deployment servo", test.ExecResponse{Stdout: servoDeploymentFixture})
+	s.recorder.Respond("kubectl --context production -n opsani get secret servo-token", test.ExecResponse{Stdout: `{"data": {"token": "MTIzNDU2Cg=="}}`})
+	output, err := s.Execute("--config", s.linkConfigFile(), "check", "link")
+	s.Require().NoError(err)
+	s.Require().Regexp(`Optimizer\s+example.com/app\s+example.com/app\s+✓ ok`, output)
+	s.Require().Regexp(`Token\s+\*+\s+\*+\s+✓ ok`, output)
+	s.Require().NotContains(output, "123456")
+}
+
+func (s *CheckTestSuite) TestLinkMismatch() {
+	s.recorder.Respond("kubectl --context production -n opsani get deployment servo", test.ExecResponse{Stdout: servoDeploymentFixture})
+	s.recorder.Respond("kubectl --context production -n opsani get secret servo-token", test.ExecResponse{Stdout: `{"data": {"token": "NjU0MzIx"}}`})
+	output, err := s.Execute("--config", s.linkConfigFile(), "check", "link", "-o", "json")
+	s.Require().EqualError(err, `servo namespaces/opsani/deployments/servo is not linked to the optimizer of profile "default": its reports will not appear in the console`)
+	s.Require().Contains(output, `"status": "mismatch"`)
+}
+
+func (s *CheckTestSuite) TestLinkUnreadableToken() {
+	s.recorder.Respond("kubectl --context production -n opsani get deployment servo", test.ExecResponse{Stdout: servoDeploymentFixture})
+	s.recorder.Respond("kubectl --context production -n opsani get secret servo-token", test.ExecResponse{ExitCode: 1})
+	output, err := s.Execute("--config", s.linkConfigFile(), "check", "link")
+	s.Require().NoError(err)
+	s.Require().Regexp(`Token\s+-\s+\*+\s+⚠ unknown`, output)
+	s.Require().Contains(output, "warning: unable to read the token of servo namespaces/opsani/deployments/servo")
+}
+
+func (s *CheckTestSuite) TestLinkWithoutServo() {
+	_, err := s.Execute("--config", s.configFile(), "check", "link")
+	s.Require().EqualError(err, "no Kubernetes servo attached to the active profile: attach one with `opsani servo attach`")
+}
@@ -15,20 +15,20 @@
 package command
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/AlecAivazis/survey/v2/terminal"
-	"github.com/fatih/color"
 	"github.com/go-resty/resty/v2"
-	"github.com/goccy/go-yaml/lexer"
-	"github.com/goccy/go-yaml/printer"
-	"github.com/hokaccha/go-prettyjson"
 	"github.com/mattn/go-colorable"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -47,6 +47,21 @@ func SetStdio(stdio terminal.Stdio) {
 	globalStdio = stdio
 }
 
+// CommandContextFunc creates an external command to be executed
+type CommandContextFunc func(ctx context.Context, name string, args ...string) *exec.Cmd
+
+var commandContext CommandContextFunc = exec.CommandContext
+
+// SetCommandContextFunc is a package helper for testing that intercepts the creation of
+// external commands such as kubectl so that invocations can be recorded and simulated
+// Passing nil restores the default of exec.CommandContext
+func SetCommandContextFunc(f CommandContextFunc) {
+	if f == nil {
+		f = exec.CommandContext
+	}
+	commandContext = f
+}
+
 // BaseCommand is the foundational command structure for the Opsani CLI
 // It contains the root command for Cobra and is designed for embedding
 // into other command structures to add subcommand functionality
@@ -59,18 +74,28 @@ type BaseCommand struct {
 	requestTracingEnabled bool
 	debugModeEnabled      bool
 	disableColors         bool
+	capabilities          TerminalCapabilities
+	showSecrets           bool
+	assumeYes             bool
+	progressFormat        string
+
+	recorder      *SessionRecorder
+	recordingFile *os.File
+
+	profiling  bool
+	cpuProfile *os.File
 }
 
 // stdio is a test helper for returning terminal file descriptors usable by Survey
 func (cmd *BaseCommand) stdio() terminal.Stdio {
 	if globalStdio != (terminal.Stdio{}) {
-		return globalStdio
+		return cmd.recordingStdio(globalStdio)
 	} else {
-		return terminal.Stdio{
+		return cmd.recordingStdio(terminal.Stdio{
 			In:  os.Stdin,
 			Out: os.Stdout,
 			Err: os.Stderr,
-		}
+		})
 	}
 }
 
@@ -79,6 +104,18 @@ func (cmd *BaseCommand) RootCobraCommand() *cobra.Command {
 	return cmd.rootCobraCommand
 }
 
+// executeNewRootCommand runs the CLI with the given arguments on a fresh root command sharing this command's I/O
+// Flags are parsed from scratch so that settings of the current invocation do not leak into the new one
+func (cmd *BaseCommand) executeNewRootCommand(args []string) error {
+	rootCmd := NewRootCommand()
+	rootCmd.rootCobraCommand.SetIn(cmd.rootCobraCommand.InOrStdin())
+	rootCmd.rootCobraCommand.SetOut(cmd.rootCobraCommand.OutOrStdout())
+	rootCmd.rootCobraCommand.SetErr(cmd.rootCobraCommand.ErrOrStderr())
+	rootCmd.rootCobraCommand.SetArgs(args)
+	_, err := rootCmd.rootCobraCommand.ExecuteC()
+	return err
+}
+
 // Viper returns the Viper configuration object underlying the Opsani CLI command
 func (cmd *BaseCommand) Viper() *viper.Viper {
 	return cmd.viperCfg
@@ -90,9 +127,9 @@ func (cmd *BaseCommand) Viper() *viper.Viper {
 func (cmd *BaseCommand) OutOrStdout() io.Writer {
 	stdout := cmd.rootCobraCommand.OutOrStdout()
 	if stdout == os.Stdout {
-		return colorable.NewColorableStdout()
+		return cmd.recordingOutput(colorable.NewColorableStdout())
 	} else {
-		return cmd.rootCobraCommand.OutOrStdout()
+		return cmd.recordingOutput(cmd.rootCobraCommand.OutOrStdout())
 	}
 }
 
@@ -100,9 +137,9 @@ func (cmd *BaseCommand) OutOrStdout() io.Writer {
 func (cmd *BaseCommand) ErrOrStderr() io.Writer {
 	stderr := cmd.rootCobraCommand.ErrOrStderr()
 	if stderr == os.Stderr {
-		return colorable.NewColorableStderr()
+		return cmd.recordingOutput(colorable.NewColorableStderr())
 	} else {
-		return cmd.rootCobraCommand.ErrOrStderr()
+		return cmd.recordingOutput(cmd.rootCobraCommand.ErrOrStderr())
 	}
 }
 
@@ -141,32 +178,34 @@ func (cmd *BaseCommand) PrintErrf(format string, i ...interface{}) {
 // Ask is a wrapper for survey.AskOne that executes with the command's stdio
 func (cmd *BaseCommand) Ask(qs []*survey.Question, response interface{}, opts ...survey.AskOpt) error {
 	stdio := cmd.stdio()
+	opts = append(cmd.surveyOpts(), opts...)
 	return survey.Ask(qs, response, append(opts, survey.WithStdio(stdio.In, stdio.Out, stdio.Err))...)
 }
 
 // AskOne is a wrapper for survey.AskOne that executes with the command's stdio
 func (cmd *BaseCommand) AskOne(p survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
 	stdio := cmd.stdio()
+	opts = append(cmd.surveyOpts(), opts...)
 	return survey.AskOne(p, response, append(opts, survey.WithStdio(stdio.In, stdio.Out, stdio.Err))...)
 }
 
 // PrettyPrintJSONObject prints the given object as pretty printed JSON
 func (cmd *BaseCommand) PrettyPrintJSONObject(obj interface{}) error {
-	s, err := prettyjson.Marshal(obj)
+	s, err := prettyJSONFormatter().Marshal(obj)
 	if err != nil {
 		return err
 	}
-	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(s))
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), cmd.RedactString(string(s)))
 	return err
 }
 
 // PrettyPrintJSONBytes prints the given byte array as pretty printed JSON
 func (cmd *BaseCommand) PrettyPrintJSONBytes(bytes []byte) error {
-	s, err := prettyjson.Format(bytes)
+	s, err := prettyJSONFormatter().Format(bytes)
 	if err != nil {
 		return err
 	}
-	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(s))
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), cmd.RedactString(string(s)))
 	return err
 }
 
@@ -194,12 +233,6 @@ func (cmd *BaseCommand) PrettyPrintJSONResponse(resp *resty.Response) error {
 	return PrettyPrintJSONObject(result)
 }
 
-const escape = "\x1b"
-
-func format(attr color.Attribute) string {
-	return fmt.Sprintf("%s[%dm", escape, attr)
-}
-
 // PrettyPrintYAMLObject pretty prints the given object marshalled into YAML
 func (cmd *BaseCommand) PrettyPrintYAMLObject(obj interface{}) error {
 	yaml, err := yaml.Marshal(obj)
@@ -211,58 +244,15 @@ func (cmd *BaseCommand) PrettyPrintYAMLObject(obj interface{}) error {
 }
 
 // PrettyPrintYAMLToString pretty formats the given YAML byte array, optionally including line numbers
+// Multi-document streams are formatted document by document with the separators between them
 func PrettyPrintYAMLToString(bytes []byte, colorize bool, lineNumbers bool) (string, error) {
-	tokens := lexer.Tokenize(string(bytes))
-	var p printer.Printer
-	p.LineNumber = lineNumbers
-	if colorize {
-		p.LineNumberFormat = func(num int) string {
-			fn := color.New(color.Bold, color.FgHiWhite).SprintFunc()
-			return fn(fmt.Sprintf("%2d | ", num))
-		}
-		p.Bool = func() *printer.Property {
-			return &printer.Property{
-				Prefix: format(color.FgHiMagenta),
-				Suffix: format(color.Reset),
-			}
-		}
-		p.Number = func() *printer.Property {
-			return &printer.Property{
-				Prefix: format(color.FgHiMagenta),
-				Suffix: format(color.Reset),
-			}
-		}
-		p.MapKey = func() *printer.Property {
-			return &printer.Property{
-				Prefix: format(color.FgHiCyan),
-				Suffix: format(color.Reset),
-			}
-		}
-		p.Anchor = func() *printer.Property {
-			return &printer.Property{
-				Prefix: format(color.FgHiYellow),
-				Suffix: format(color.Reset),
-			}
-		}
-		p.Alias = func() *printer.Property {
-			return &printer.Property{
-				Prefix: format(color.FgHiYellow),
-				Suffix: format(color.Reset),
-			}
-		}
-		p.String = func() *printer.Property {
-			return &printer.Property{
-				Prefix: format(color.FgHiGreen),
-				Suffix: format(color.Reset),
-			}
-		}
-	}
-	return p.PrintTokens(tokens), nil
+	return prettyPrintYAMLDocuments(bytes, colorize, lineNumbers), nil
 }
 
 // PrettyPrintYAML pretty prints the given YAML byte array, optionally including line numbers
+// Known secrets are redacted unless explicitly requested
 func (cmd *BaseCommand) PrettyPrintYAML(bytes []byte, lineNumbers bool) error {
-	prettyYAML, _ := PrettyPrintYAMLToString(bytes, cmd.ColorOutput(), lineNumbers)
+	prettyYAML, _ := PrettyPrintYAMLToString(cmd.RedactBytes(bytes), cmd.ColorOutput(), lineNumbers)
 	_, err := cmd.OutOrStdout().Write([]byte(prettyYAML + "\n"))
 	return err
 }
@@ -279,17 +269,51 @@ func (cmd *BaseCommand) Flags() *pflag.FlagSet {
 }
 
 // BaseURL returns the Opsani API base URL
-// The BaseURL is determined by config (args/env), active profile, or default
+// The BaseURL is determined by flag, env, active profile (including registry defaults), or default
+// Single-tenant and dedicated-cell deployments set base_url on the profile (e.g. https://api.cell-1.opsani.com/)
 func (cmd *BaseCommand) BaseURL() string {
 	if baseURL := cmd.valueFromFlagOrEnv(KeyBaseURL, "OPSANI_BASE_URL"); baseURL != "" {
 		return baseURL
 	}
-	if cmd.profile != nil {
+	if cmd.profile != nil && cmd.profile.BaseURL != "" {
 		return cmd.profile.BaseURL
 	}
 	return DefaultBaseURL
 }
 
+// CustomBaseURL returns the base URL if it differs from the default Opsani API and an empty string otherwise
+// Generated manifests and pipelines only configure the base URL for single-tenant and dedicated-cell deployments
+func CustomBaseURL(baseURL string) string {
+	if strings.TrimRight(baseURL, "/") == strings.TrimRight(DefaultBaseURL, "/") {
+		return ""
+	}
+	return baseURL
+}
+
+// ValidateBaseURL returns an error if the base URL is not an absolute HTTP or HTTPS URL
+func ValidateBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid base URL %q: must be an http or https URL such as %s", baseURL, DefaultBaseURL)
+	}
+	return nil
+}
+
+// ConsoleURL returns the Opsani console URL paired with the API base URL
+// The console of an "api." host is served from the "console." host of the same domain
+func (cmd *BaseCommand) ConsoleURL() string {
+	u, err := url.Parse(cmd.BaseURL())
+	if err != nil || u.Host == "" {
+		return defaultConsoleURL
+	}
+	if strings.HasPrefix(u.Host, "api.") {
+		u.Host = "console." + strings.TrimPrefix(u.Host, "api.")
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawQuery, u.Fragment = "", ""
+	return u.String()
+}
+
 func (cmd *BaseCommand) valueFromFlagOrEnv(flagKey string, envKey string) string {
 	if value, _ := cmd.PersistentFlags().GetString(flagKey); value != "" {
 		return value
@@ -304,7 +328,7 @@ func (cmd *BaseCommand) valueFromFlagOrEnv(flagKey string, envKey string) string
 func (cmd *BaseCommand) BaseURLHostnameAndPort() string {
 	u, err := url.Parse(cmd.BaseURL())
 	if err != nil {
-		return cmd.GetBaseURL()
+		return cmd.BaseURL()
 	}
 	baseURLDescription := u.Hostname()
 	if port := u.Port(); port != "" && port != "80" && port != "443" {
@@ -318,7 +342,10 @@ func (cmd *BaseCommand) baseURLFromFlagsOrEnv() string {
 }
 
 func (cmd *BaseCommand) appFromFlagsOrEnv() string {
-	return cmd.valueFromFlagOrEnv(KeyOptimizer, "OPSANI_OPTIMIZER")
+	if app := cmd.valueFromFlagOrEnv(KeyOptimizer, "OPSANI_OPTIMIZER"); app != "" {
+		return app
+	}
+	return cmd.legacyAppFromFlagsOrEnv()
 }
 
 func (cmd *BaseCommand) tokenFromFlagsOrEnv() string {
@@ -346,14 +373,25 @@ func (cmd *BaseCommand) LoadProfile() (*Profile, error) {
 
 	// Apply any config overrides
 	if profile != nil {
+		profile.ApplyDefaults(registry.Defaults())
 		if baseURL := cmd.baseURLFromFlagsOrEnv(); baseURL != "" {
 			profile.BaseURL = baseURL
 		}
 		if app := cmd.appFromFlagsOrEnv(); app != "" {
 			profile.Optimizer = app
 		}
-		if token := cmd.tokenFromFlagsOrEnv(); token != "" {
+		if token := cmd.tokenFromFlagsOrEnv(); token != "" && token != profile.Token {
+			// The recorded scopes belong to the stored token
 			profile.Token = token
+			profile.Scopes = nil
+		}
+		if err := profile.ValidateOrg(); err != nil {
+			return nil, err
+		}
+		if profile.Timeout != "" {
+			if _, err := time.ParseDuration(profile.Timeout); err != nil {
+				return nil, fmt.Errorf("invalid timeout %q for profile %q: %w", profile.Timeout, profile.Name, err)
+			}
 		}
 
 		cmd.profile = profile
@@ -375,7 +413,7 @@ func (cmd *BaseCommand) AccessToken() string {
 
 // Optimizer returns the target Opsani app
 func (cmd *BaseCommand) Optimizer() string {
-	if app := cmd.valueFromFlagOrEnv(KeyOptimizer, "OPSANI_OPTIMIZER"); app != "" {
+	if app := cmd.appFromFlagsOrEnv(); app != "" {
 		return app
 	}
 	if cmd.profile != nil {
@@ -0,0 +1,72 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/opsani/cli/test"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/suite"
+)
+
+type CommandExecutorTestSuite struct {
+	test.Suite
+}
+
+func TestCommandExecutorTestSuite(t *testing.T) {
+	suite.Run(t, new(CommandExecutorTestSuite))
+}
+
+func (s *CommandExecutorTestSuite) SetupTest() {
+	s.SetCobraCommand(&cobra.Command{
+		Use: "echo",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			input, err := ioutil.ReadAll(cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			cmd.Printf("stdin=%s env=%s", input, os.Getenv("OPSANI_EXECUTOR_TEST"))
+			return nil
+		},
+	})
+}
+
+func (s *CommandExecutorTestSuite) TestStdinIsReadByCommand() {
+	s.SetStdin(strings.NewReader("hello"))
+	output, err := s.Execute()
+	s.Require().NoError(err)
+	s.Require().Equal("stdin=hello env=", output)
+}
+
+func (s *CommandExecutorTestSuite) TestEnvIsRestoredAfterExecution() {
+	os.Setenv("OPSANI_EXECUTOR_TEST", "original")
+	defer os.Unsetenv("OPSANI_EXECUTOR_TEST")
+	s.SetStdin(strings.NewReader(""))
+	s.SetEnv("OPSANI_EXECUTOR_TEST", "injected")
+	output, err := s.Execute()
+	s.Require().NoError(err)
+	s.Require().Equal("stdin= env=injected", output)
+	s.Require().Equal("original", os.Getenv("OPSANI_EXECUTOR_TEST"))
+
+	s.UnsetEnv("OPSANI_EXECUTOR_TEST")
+	s.SetStdin(strings.NewReader(""))
+	output, err = s.Execute()
+	s.Require().NoError(err)
+	s.Require().Equal("stdin= env=original", output)
+}
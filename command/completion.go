@@ -17,9 +17,14 @@ package command
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"github.com/mattn/go-isatty"
+	"github.com/mitchellh/go-homedir"
 	"github.com/opsani/cli/internal/cobrafish"
 	"github.com/spf13/cobra"
 )
@@ -63,26 +68,133 @@ func NewCompletionCommand(baseCmd *BaseCommand) *cobra.Command {
 				shellType = "bash"
 			}
 
-			switch shellType {
-			case "bash":
-				return cmd.Root().GenBashCompletion(cmd.OutOrStdout())
-			case "zsh":
-				return cmd.Root().GenZshCompletion(cmd.OutOrStdout())
-			case "powershell":
-				return cmd.Root().GenPowerShellCompletion(cmd.OutOrStdout())
-			case "fish":
-				return cobrafish.GenCompletion(cmd.Root(), cmd.OutOrStdout())
-			default:
-				return fmt.Errorf("unsupported shell type %q", shellType)
-			}
+			return generateCompletion(cmd.Root(), shellType, cmd.OutOrStdout())
 		},
 	}
 
 	completionCmd.Flags().StringP("shell", "s", "", "Shell type: {bash|zsh|fish|powershell}")
+	completionCmd.AddCommand(NewCompletionInstallCommand(baseCmd))
 
 	return completionCmd
 }
 
+func generateCompletion(rootCmd *cobra.Command, shellType string, w io.Writer) error {
+	switch shellType {
+	case "bash":
+		return rootCmd.GenBashCompletion(w)
+	case "zsh":
+		return rootCmd.GenZshCompletion(w)
+	case "powershell":
+		return rootCmd.GenPowerShellCompletion(w)
+	case "fish":
+		return cobrafish.GenCompletion(rootCmd, w)
+	default:
+		return fmt.Errorf("unsupported shell type %q", shellType)
+	}
+}
+
+// NewCompletionInstallCommand returns a new Opsani CLI completion install command instance
+func NewCompletionInstallCommand(baseCmd *BaseCommand) *cobra.Command {
+	return &cobra.Command{
+		Use:   "install [bash|zsh|fish|powershell]",
+		Short: "Install shell completion scripts",
+		Long: `Install writes the shell completion script for Opsani CLI to the standard
+location for the given shell (or the shell in $SHELL if none is given).
+
+Oh My Zsh, Homebrew site-functions, and XDG data and config directories are
+detected automatically.`,
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shellType := filepath.Base(os.Getenv("SHELL"))
+			if len(args) > 0 {
+				shellType = args[0]
+			}
+			if shellType == "" || shellType == "." {
+				return errors.New("unable to detect shell: please specify one of bash, zsh, fish, or powershell")
+			}
+
+			path, hint, err := completionInstallPath(shellType)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			file, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			if err := generateCompletion(cmd.Root(), shellType, file); err != nil {
+				return err
+			}
+
+			baseCmd.Printf("Installed %s completion to %s\n", shellType, path)
+			if hint != "" {
+				baseCmd.Println(hint)
+			}
+			return nil
+		},
+	}
+}
+
+// completionInstallPath returns the path to install the completion script for the shell at
+// along with any instructions for the user to activate it
+func completionInstallPath(shellType string) (string, string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", "", err
+	}
+	brewPrefix := homebrewPrefix()
+
+	switch shellType {
+	case "bash":
+		if brewPrefix != "" {
+			return filepath.Join(brewPrefix, "etc", "bash_completion.d", "opsani"), "", nil
+		}
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+		return filepath.Join(dataHome, "bash-completion", "completions", "opsani"),
+			"Completions are loaded on demand by the bash-completion package. Start a new shell to activate them.", nil
+	case "zsh":
+		if zsh := os.Getenv("ZSH"); zsh != "" {
+			return filepath.Join(zsh, "completions", "_opsani"), "Run `exec zsh` to activate completions.", nil
+		}
+		if brewPrefix != "" {
+			return filepath.Join(brewPrefix, "share", "zsh", "site-functions", "_opsani"), "Run `exec zsh` to activate completions.", nil
+		}
+		return filepath.Join(home, ".zsh", "completions", "_opsani"),
+			"Add `fpath=(~/.zsh/completions $fpath)` before `compinit` in your ~/.zshrc to activate completions.", nil
+	case "fish":
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			configHome = filepath.Join(home, ".config")
+		}
+		return filepath.Join(configHome, "fish", "completions", "opsani.fish"), "", nil
+	case "powershell":
+		path := filepath.Join(home, ".config", "powershell", "opsani-completion.ps1")
+		return path, fmt.Sprintf("Add `. %s` to your PowerShell $PROFILE to activate completions.", path), nil
+	default:
+		return "", "", fmt.Errorf("unsupported shell type %q", shellType)
+	}
+}
+
+// homebrewPrefix returns the Homebrew installation prefix or an empty string if unavailable
+func homebrewPrefix() string {
+	path, err := exec.LookPath("brew")
+	if err != nil {
+		return ""
+	}
+	output, err := exec.Command(path, "--prefix").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
 // IsTerminal reports whether the file descriptor is connected to a terminal
 func IsTerminal(f *os.File) bool {
 	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
@@ -15,6 +15,9 @@
 package command_test
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/opsani/cli/command"
@@ -63,3 +66,30 @@ func (s *CompletionTestSuite) TestRunningCompletionPowershell() {
 	s.Require().NoError(err)
 	s.Require().Contains(output, "Register-ArgumentCompleter -Native -CommandName 'opsani'")
 }
+
+func (s *CompletionTestSuite) TestRunningCompletionInstallHelp() {
+	output, err := s.Execute("completion", "install", "--help")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Install writes the shell completion script")
+}
+
+func (s *CompletionTestSuite) TestRunningCompletionInstallFish() {
+	configHome, err := ioutil.TempDir("", "opsani-completion")
+	s.Require().NoError(err)
+	defer os.RemoveAll(configHome)
+	os.Setenv("XDG_CONFIG_HOME", configHome)
+	defer os.Unsetenv("XDG_CONFIG_HOME")
+
+	output, err := s.Execute("completion", "install", "fish")
+	s.Require().NoError(err)
+	path := filepath.Join(configHome, "fish", "completions", "opsani.fish")
+	s.Require().Contains(output, "Installed fish completion to "+path)
+	body, err := ioutil.ReadFile(path)
+	s.Require().NoError(err)
+	s.Require().Contains(string(body), "__fish_opsani_no_subcommand")
+}
+
+func (s *CompletionTestSuite) TestRunningCompletionInstallUnsupportedShell() {
+	_, err := s.Execute("completion", "install", "tcsh")
+	s.Require().EqualError(err, `unsupported shell type "tcsh"`)
+}
@@ -46,6 +46,8 @@ func NewConfigCommand(baseCmd *BaseCommand) *cobra.Command {
 		},
 	}
 	cobraCmd.AddCommand(cobraEditCmd)
+	cobraCmd.AddCommand(NewConfigUndoCommand(baseCmd))
+	cobraCmd.AddCommand(NewConfigSchemaCommand(baseCmd))
 
 	return cobraCmd
 }
@@ -54,7 +56,7 @@ func NewConfigCommand(baseCmd *BaseCommand) *cobra.Command {
 func (configCmd *configCommand) Run(_ *cobra.Command, args []string) error {
 	configCmd.Println("Using config from:", configCmd.viperCfg.ConfigFileUsed())
 
-	yaml, err := yaml.Marshal(configCmd.GetAllSettings())
+	yaml, err := yaml.Marshal(configCmd.RedactSecretKeys(configCmd.GetAllSettings()))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,208 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// KeyConfigHistoryLimit is the config key for the number of config revisions retained for undo
+const KeyConfigHistoryLimit = "history.limit"
+
+// DefaultConfigHistoryLimit is the number of config revisions retained when no limit is configured
+const DefaultConfigHistoryLimit = 10
+
+// ConfigRevision records the config file as it was before a destructive change
+type ConfigRevision struct {
+	File        string    `json:"file"`
+	Description string    `json:"description"`
+	Time        time.Time `json:"time"`
+}
+
+// ConfigHistoryDir returns the directory holding revisions of the config file in use
+// Revisions are stored alongside the config file, keyed by its name
+func (baseCmd *BaseCommand) ConfigHistoryDir() string {
+	configFile := baseCmd.viperCfg.ConfigFileUsed()
+	if configFile == "" {
+		configFile = baseCmd.DefaultConfigFile()
+	}
+	name := strings.TrimSuffix(filepath.Base(configFile), filepath.Ext(configFile))
+	return filepath.Join(filepath.Dir(configFile), "history", name)
+}
+
+// ConfigHistory returns the recorded config revisions, oldest first
+func (baseCmd *BaseCommand) ConfigHistory() ([]ConfigRevision, error) {
+	data, err := ioutil.ReadFile(filepath.Join(baseCmd.ConfigHistoryDir(), "index.yaml"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	revisions := []ConfigRevision{}
+	if err := yaml.Unmarshal(data, &revisions); err != nil {
+		return nil, fmt.Errorf("invalid config history: %w", err)
+	}
+	return revisions, nil
+}
+
+func (baseCmd *BaseCommand) saveConfigHistory(revisions []ConfigRevision) error {
+	data, err := yaml.Marshal(revisions)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(baseCmd.ConfigHistoryDir(), "index.yaml"), data, 0600)
+}
+
+// configHistoryLimit returns the number of config revisions to retain
+func (baseCmd *BaseCommand) configHistoryLimit() int {
+	if baseCmd.viperCfg.IsSet(KeyConfigHistoryLimit) {
+		return baseCmd.viperCfg.GetInt(KeyConfigHistoryLimit)
+	}
+	return DefaultConfigHistoryLimit
+}
+
+// BackupConfig records the current config file before a destructive change so that it can be undone
+// The oldest revisions are discarded once the history limit is reached
+func (baseCmd *BaseCommand) BackupConfig(description string) error {
+	configFile := baseCmd.viperCfg.ConfigFileUsed()
+	if configFile == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	limit := baseCmd.configHistoryLimit()
+	if limit <= 0 {
+		return nil
+	}
+
+	dir := baseCmd.ConfigHistoryDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	revisions, err := baseCmd.ConfigHistory()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	revision := ConfigRevision{
+		File:        fmt.Sprintf("%s.yaml", now.Format("20060102T150405.000000000Z")),
+		Description: description,
+		Time:        now,
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, revision.File), data, 0600); err != nil {
+		return err
+	}
+	revisions = append(revisions, revision)
+	for len(revisions) > limit {
+		os.Remove(filepath.Join(dir, revisions[0].File))
+		revisions = revisions[1:]
+	}
+	return baseCmd.saveConfigHistory(revisions)
+}
+
+// UndoConfig restores the config file to the most recent revision and removes it from the history
+func (baseCmd *BaseCommand) UndoConfig() (*ConfigRevision, error) {
+	revisions, err := baseCmd.ConfigHistory()
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("nothing to undo")
+	}
+	revision := revisions[len(revisions)-1]
+	path := filepath.Join(baseCmd.ConfigHistoryDir(), revision.File)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	configFile := baseCmd.viperCfg.ConfigFileUsed()
+	if configFile == "" {
+		configFile = baseCmd.DefaultConfigFile()
+	}
+	if err := ioutil.WriteFile(configFile, data, 0600); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, err
+	}
+	return &revision, baseCmd.saveConfigHistory(revisions[:len(revisions)-1])
+}
+
+// NewConfigUndoCommand returns a new `opsani config undo` command instance
+func NewConfigUndoCommand(baseCmd *BaseCommand) *cobra.Command {
+	undoCmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Undo the last destructive config change",
+		Long: `Restores the config file as it was before the last destructive change, such as
+"opsani profile remove" or "opsani servo detach".
+
+Up to 10 revisions are retained by default. Set history.limit in the config file to change the limit.`,
+		Args: cobra.NoArgs,
+		// Undo must remain available after removing the last profile
+		PersistentPreRunE: ReduceRunEFuncs(baseCmd.InitConfigRunE, baseCmd.RequireConfigFileFlagToExistRunE),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if list, _ := cmd.Flags().GetBool("list"); list {
+				revisions, err := baseCmd.ConfigHistory()
+				if err != nil {
+					return err
+				}
+				table := baseCmd.newPlainTable()
+				table.SetHeader([]string{"TIME", "CHANGE"})
+				for i := len(revisions) - 1; i >= 0; i-- {
+					table.Append([]string{revisions[i].Time.Local().Format(time.RFC1123), revisions[i].Description})
+				}
+				table.Render()
+				return nil
+			}
+
+			revisions, err := baseCmd.ConfigHistory()
+			if err != nil {
+				return err
+			}
+			if len(revisions) == 0 {
+				return fmt.Errorf("nothing to undo")
+			}
+			last := revisions[len(revisions)-1]
+			confirmed, err := baseCmd.Confirm(fmt.Sprintf("Undo %q from %s?", last.Description, last.Time.Local().Format(time.RFC1123)), false)
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				return nil
+			}
+			revision, err := baseCmd.UndoConfig()
+			if err != nil {
+				return err
+			}
+			baseCmd.Printf("Undid %q\n", revision.Description)
+			return nil
+		},
+	}
+	baseCmd.AddDeprecatedForceFlag(undoCmd)
+	undoCmd.Flags().Bool("list", false, "List the changes that can be undone, most recent first")
+	return undoCmd
+}
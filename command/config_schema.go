@@ -0,0 +1,209 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// JSONSchemaDialect is the JSON Schema draft that the config schema is written against
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema describing a value of the config file
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Minimum              *int                   `json:"minimum,omitempty"`
+	Deprecated           bool                   `json:"deprecated,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+}
+
+func stringSchema(description string) *JSONSchema {
+	return &JSONSchema{Type: "string", Description: description}
+}
+
+func enumSchema(description string, values ...string) *JSONSchema {
+	return &JSONSchema{Type: "string", Description: description, Enum: values}
+}
+
+func integerSchema(description string, minimum int) *JSONSchema {
+	return &JSONSchema{Type: "integer", Description: description, Minimum: &minimum}
+}
+
+func booleanSchema(description string) *JSONSchema {
+	return &JSONSchema{Type: "boolean", Description: description}
+}
+
+func urlSchema(description string) *JSONSchema {
+	return &JSONSchema{Type: "string", Format: "uri", Description: description}
+}
+
+// durationSchema describes a Go duration such as 30s or 5m
+func durationSchema(description string) *JSONSchema {
+	return &JSONSchema{Type: "string", Pattern: `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`, Description: description}
+}
+
+// objectSchema describes an object that only accepts the given properties
+func objectSchema(description string, properties map[string]*JSONSchema, required ...string) *JSONSchema {
+	return &JSONSchema{
+		Type:                 "object",
+		Description:          description,
+		Properties:           properties,
+		Required:             required,
+		AdditionalProperties: false,
+	}
+}
+
+// ConfigSchema returns the JSON Schema of the config file
+func ConfigSchema() *JSONSchema {
+	locales := []string{}
+	for locale := range messageCatalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	servo := objectSchema("Servo attached to the profile", map[string]*JSONSchema{
+		"type":             enumSchema("How the servo is deployed", "kubernetes", "docker-compose"),
+		"user":             stringSchema("SSH user of the Docker Compose host"),
+		"host":             stringSchema("Docker Compose host the servo runs on"),
+		"port":             stringSchema("SSH port of the Docker Compose host (default is 22)"),
+		"path":             stringSchema("Directory of the servo on the Docker Compose host"),
+		"bastion":          stringSchema("SSH bastion host for reaching the Docker Compose host (e.g. user@bastion.example.com)"),
+		"namespace":        stringSchema("Kubernetes namespace of the servo deployment"),
+		"deployment":       stringSchema("Kubernetes deployment of the servo"),
+		"kubeconfig":       stringSchema("Path to the kubeconfig for reaching the cluster"),
+		"context":          stringSchema("Kubeconfig context of the cluster"),
+		"servo_deployment": stringSchema("ServoDeployment resource of servos managed by the servo operator"),
+		"prometheus_url":   urlSchema("Prometheus endpoint the servo gathers metrics from"),
+	}, "type")
+
+	profile := objectSchema("Optimizer, token, and servo used by CLI commands", map[string]*JSONSchema{
+		"name":      stringSchema("Name used to select the profile with --profile"),
+		"optimizer": &JSONSchema{Type: "string", Pattern: "^[^/]+/[^/]+$", Description: "Optimizer to interact with (e.g. example.com/app)"},
+		"app":       &JSONSchema{Type: "string", Deprecated: true, Description: "Deprecated alias of optimizer"},
+		"token":     stringSchema("API authentication token"),
+		"base_url":  urlSchema("Opsani API base URL for single-tenant and dedicated-cell deployments"),
+		"timeout":   durationSchema("Maximum duration of API requests and external commands (e.g. 30s)"),
+		"org":       stringSchema("Organization selected with `opsani org switch`"),
+		"scopes":    &JSONSchema{Type: "array", Items: stringSchema("Permission scope"), Description: "Permission scopes of the token"},
+		"servo":     servo,
+	}, "name", "token")
+
+	return &JSONSchema{
+		Schema:      JSONSchemaDialect,
+		Title:       "Opsani CLI configuration",
+		Description: "Configuration file of the Opsani CLI (~/.opsani/config.yaml)",
+		Type:        "object",
+		Properties: map[string]*JSONSchema{
+			"profiles": {Type: "array", Items: profile, Description: "Profiles of the optimizers managed by the CLI"},
+			"defaults": objectSchema("Settings inherited by all profiles unless overridden", map[string]*JSONSchema{
+				"base_url":   urlSchema("Opsani API base URL"),
+				"bastion":    stringSchema("SSH bastion host of Docker Compose servos"),
+				"kubeconfig": stringSchema("Path to the kubeconfig of Kubernetes servos"),
+				"namespace":  stringSchema("Kubernetes namespace of Kubernetes servos"),
+				"timeout":    durationSchema("Maximum duration of API requests and external commands (e.g. 30s)"),
+			}),
+			"ui": objectSchema("Terminal user interface settings", map[string]*JSONSchema{
+				configSchemaLeaf(KeyAccessible): booleanSchema("Screen reader friendly mode without spinners, colors, or unicode glyphs"),
+				configSchemaLeaf(KeyASCIIOnly):  booleanSchema("Replace unicode glyphs, emoji, and spinners with ASCII for terminals without UTF-8 (detected from the locale by default)"),
+				configSchemaLeaf(KeyLocale):     enumSchema("Language of onboarding messages", locales...),
+				configSchemaLeaf(KeyPalette):    enumSchema("Color palette of status glyphs, tables, prompts, and YAML and JSON output", PaletteNames()...),
+			}),
+			"learn": objectSchema("Topics displayed by `opsani learn`", map[string]*JSONSchema{
+				configSchemaLeaf(KeyLearnURL): urlSchema("Docs endpoint that `opsani learn refresh` downloads topics from"),
+			}),
+			"history": objectSchema("History retention settings", map[string]*JSONSchema{
+				configSchemaLeaf(KeyConfigHistoryLimit):  integerSchema("Number of optimizer config snapshots kept for undo", 0),
+				configSchemaLeaf(KeyCommandHistoryLimit): integerSchema("Number of commands kept in the command history", 0),
+				configSchemaLeaf(KeyTaskLogLimit):        integerSchema("Number of task output logs kept in the logs directory", 0),
+			}),
+			"notifications": objectSchema("Notifications of long running tasks", map[string]*JSONSchema{
+				configSchemaLeaf(KeySlackWebhook): urlSchema("Slack incoming webhook URL"),
+			}),
+			"annotations": objectSchema("Targets of `opsani optimizer adjustments forward`", map[string]*JSONSchema{
+				"grafana": objectSchema("Grafana annotations", map[string]*JSONSchema{
+					configSchemaLeaf(KeyAnnotationsGrafanaURL):    urlSchema("Grafana URL"),
+					configSchemaLeaf(KeyAnnotationsGrafanaAPIKey): stringSchema("Grafana API key"),
+				}),
+				"datadog": objectSchema("Datadog events", map[string]*JSONSchema{
+					configSchemaLeaf(KeyAnnotationsDatadogAPIKey): stringSchema("Datadog API key"),
+					configSchemaLeaf(KeyAnnotationsDatadogSite):   stringSchema("Datadog site (e.g. datadoghq.eu)"),
+				}),
+				"newrelic": objectSchema("New Relic deployment markers", map[string]*JSONSchema{
+					configSchemaLeaf(KeyAnnotationsNewRelicAPIKey):     stringSchema("New Relic API key"),
+					configSchemaLeaf(KeyAnnotationsNewRelicAppID):      stringSchema("New Relic application ID"),
+					configSchemaLeaf(KeyAnnotationsNewRelicAPIBaseURL): urlSchema("New Relic API URL"),
+				}),
+			}),
+			"ignite": objectSchema("Ignite demo cluster settings", map[string]*JSONSchema{
+				configSchemaLeaf(KeyIgniteMemoryConfig): {
+					Type:        []string{"string", "integer"},
+					Description: "Memory allocated to the cluster in MB or with a unit (e.g. 8192, 8g)",
+				},
+				configSchemaLeaf(KeyIgniteCPUsConfig):              integerSchema("CPUs allocated to the cluster", minIgniteCPUs),
+				configSchemaLeaf(KeyIgniteKubernetesVersionConfig): &JSONSchema{Type: "string", Pattern: kubernetesVersionPattern.String(), Description: "Kubernetes version of the cluster (e.g. v1.18.3)"},
+				configSchemaLeaf(KeyIgniteDriverConfig):            enumSchema("minikube driver", igniteDrivers...),
+				configSchemaLeaf(KeyContainerRuntimeConfig):        enumSchema("Container runtime used to pull images", ContainerRuntimeDocker, ContainerRuntimePodman, ContainerRuntimeNerdctl),
+			}),
+			KeyAliases: {
+				Type:                 "object",
+				Description:          "Command aliases expanded by `opsani NAME`",
+				AdditionalProperties: stringSchema("Command line the alias expands to"),
+			},
+		},
+	}
+}
+
+// configSchemaLeaf returns the last component of a dotted config key
+func configSchemaLeaf(key string) string {
+	return key[strings.LastIndex(key, ".")+1:]
+}
+
+// NewConfigSchemaCommand returns a new `opsani config schema` command instance
+func NewConfigSchemaCommand(baseCmd *BaseCommand) *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the config file",
+		Long: `Prints a JSON Schema (draft 2020-12) describing the config file for editors that validate and
+complete YAML against a schema.
+
+With the VS Code YAML extension, save the schema and associate it with the config file in settings.json:
+
+  "yaml.schemas": { "~/.opsani/config.schema.json": "**/.opsani/config.yaml" }`,
+		Example:           `  opsani config schema > ~/.opsani/config.schema.json`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: baseCmd.InitConfigRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := json.MarshalIndent(ConfigSchema(), "", "  ")
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(append(data, '\n'))
+			return err
+		},
+	}
+}
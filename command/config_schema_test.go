@@ -0,0 +1,74 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+)
+
+// requireSchemaCoversFields checks that every field of a config struct is described by the schema
+func requireSchemaCoversFields(t *testing.T, schema *command.JSONSchema, value interface{}) {
+	typ := reflect.TypeOf(value)
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		require.Contains(t, schema.Properties, name, "%s.%s is missing from the config schema", typ.Name(), typ.Field(i).Name)
+	}
+}
+
+func TestConfigSchemaCoversProfiles(t *testing.T) {
+	schema := command.ConfigSchema()
+	profile := schema.Properties["profiles"].Items
+	requireSchemaCoversFields(t, profile, command.Profile{})
+	requireSchemaCoversFields(t, profile.Properties["servo"], command.Servo{})
+	requireSchemaCoversFields(t, schema.Properties["defaults"], command.ProfileDefaults{})
+}
+
+func TestConfigSchemaCoversSettings(t *testing.T) {
+	schema := command.ConfigSchema()
+	for _, key := range []string{
+		command.KeyAccessible, command.KeyLocale, command.KeySlackWebhook,
+		command.KeyConfigHistoryLimit, command.KeyCommandHistoryLimit,
+		command.KeyAnnotationsGrafanaURL, command.KeyAnnotationsNewRelicAppID,
+		command.KeyIgniteMemoryConfig, command.KeyContainerRuntimeConfig,
+	} {
+		properties := schema.Properties
+		components := strings.Split(key, ".")
+		for _, component := range components[:len(components)-1] {
+			require.Contains(t, properties, component, key)
+			properties = properties[component].Properties
+		}
+		require.Contains(t, properties, components[len(components)-1], key)
+	}
+}
+
+func (s *ConfigTestSuite) TestRunningConfigSchema() {
+	output, err := s.Execute("config", "schema")
+	s.Require().NoError(err)
+
+	var schema map[string]interface{}
+	s.Require().NoError(json.Unmarshal([]byte(output), &schema))
+	s.Require().Equal("https://json-schema.org/draft/2020-12/schema", schema["$schema"])
+	profiles := schema["properties"].(map[string]interface{})["profiles"].(map[string]interface{})
+	s.Require().Equal([]interface{}{"name", "token"}, profiles["items"].(map[string]interface{})["required"])
+}
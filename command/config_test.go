@@ -18,12 +18,15 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"regexp"
 	"testing"
 
 	"github.com/opsani/cli/command"
 	"github.com/opsani/cli/test"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v2"
 )
 
 func ConfigFileArgs(file *os.File, args ...string) []string {
@@ -90,8 +93,129 @@ func (s *ConfigTestSuite) TestRunningWithInitializedConfig() {
 	s.Require().NoError(err)
 	yaml := Strip(output)
 	s.Require().Contains(yaml, `optimizer: example.com/app1`)
-	s.Require().Contains(yaml, `token: "123456`)
+	s.Require().Regexp(`token: ['"]\*{8}['"]`, yaml)
+	s.Require().NotContains(yaml, `123456`)
 	s.Require().Contains(yaml, fmt.Sprintln("Using config from:", configFile.Name()))
 }
 
+func (s *ConfigTestSuite) TestRunningWithInitializedConfigShowSecrets() {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{"profiles": []map[string]string{{"optimizer": "example.com/app1", "token": "123456"}}})
+	output, err := s.ExecuteArgs(ConfigFileArgs(configFile, "--show-secrets", "config"))
+	s.Require().NoError(err)
+	s.Require().Contains(Strip(output), `token: "123456`)
+}
+
 // TODO: Edit command
+
+// historyConfigFile writes a config with two profiles to its own directory so that its history is isolated
+func (s *ConfigTestSuite) historyConfigFile(extra map[string]interface{}) string {
+	dir, err := ioutil.TempDir("", "opsani-config-history")
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { os.RemoveAll(dir) })
+	config := map[string]interface{}{
+		"profiles": []map[string]interface{}{
+			{"name": "default", "optimizer": "example.com/app", "token": "123456", "servo": map[string]string{"type": "kubernetes", "deployment": "servo"}},
+			{"name": "staging", "optimizer": "example.com/staging", "token": "123456"},
+		},
+	}
+	for key, value := range extra {
+		config[key] = value
+	}
+	data, err := yaml.Marshal(config)
+	s.Require().NoError(err)
+	path := filepath.Join(dir, "config.yaml")
+	s.Require().NoError(ioutil.WriteFile(path, data, 0600))
+	return path
+}
+
+func (s *ConfigTestSuite) profileNames(configFile string) []string {
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	s.Require().NoError(v.ReadInConfig())
+	registry, err := command.NewProfileRegistry(v)
+	s.Require().NoError(err)
+	names := []string{}
+	for _, profile := range registry.Profiles() {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+func (s *ConfigTestSuite) TestConfigUndoProfileRemove() {
+	configFile := s.historyConfigFile(nil)
+	original, err := ioutil.ReadFile(configFile)
+	s.Require().NoError(err)
+
+	_, err = s.Execute("--config", configFile, "profile", "remove", "staging", "-f")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"default"}, s.profileNames(configFile))
+
+	s.SetCommand(command.NewRootCommand())
+	output, err := s.Execute("--config", configFile, "config", "undo", "-f")
+	s.Require().NoError(err)
+	s.Require().Contains(output, `Undid "profile remove staging"`)
+	restored, err := ioutil.ReadFile(configFile)
+	s.Require().NoError(err)
+	s.Require().Equal(string(original), string(restored))
+
+	s.SetCommand(command.NewRootCommand())
+	_, err = s.Execute("--config", configFile, "config", "undo", "-f")
+	s.Require().EqualError(err, "nothing to undo")
+}
+
+func (s *ConfigTestSuite) TestConfigUndoAfterRemovingLastProfile() {
+	configFile := s.historyConfigFile(nil)
+	for _, name := range []string{"staging", "default"} {
+		s.SetCommand(command.NewRootCommand())
+		_, err := s.Execute("--config", configFile, "profile", "remove", name, "-f")
+		s.Require().NoError(err)
+	}
+	s.Require().Empty(s.profileNames(configFile))
+
+	s.SetCommand(command.NewRootCommand())
+	_, err := s.Execute("--config", configFile, "config", "undo", "-f")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"default"}, s.profileNames(configFile))
+}
+
+func (s *ConfigTestSuite) TestConfigUndoServoDetach() {
+	configFile := s.historyConfigFile(nil)
+	_, err := s.Execute("--config", configFile, "servo", "detach", "-f")
+	s.Require().NoError(err)
+
+	s.SetCommand(command.NewRootCommand())
+	output, err := s.ExecuteTestInteractively(test.Args("--config", configFile, "config", "undo"), func(t *test.InteractiveTestContext) error {
+		t.RequireString(`Undo "servo detach from profile default"`)
+		t.SendLine("Y")
+		t.ExpectEOF()
+		return nil
+	})
+	s.Require().NoError(err, output.OutputBuffer().String())
+	data, err := ioutil.ReadFile(configFile)
+	s.Require().NoError(err)
+	s.Require().Contains(string(data), "deployment: servo")
+}
+
+func (s *ConfigTestSuite) TestConfigHistoryIsBounded() {
+	configFile := s.historyConfigFile(map[string]interface{}{"history": map[string]int{"limit": 2}})
+	for _, args := range [][]string{
+		{"profile", "rename", "staging", "qa"},
+		{"profile", "rename", "qa", "test"},
+		{"profile", "remove", "test", "-f"},
+	} {
+		s.SetCommand(command.NewRootCommand())
+		_, err := s.Execute(append([]string{"--config", configFile}, args...)...)
+		s.Require().NoError(err)
+	}
+
+	s.SetCommand(command.NewRootCommand())
+	output, err := s.Execute("--config", configFile, "config", "undo", "--list")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "profile remove test")
+	s.Require().Contains(output, "profile rename qa test")
+	s.Require().NotContains(output, "profile rename staging qa")
+
+	entries, err := ioutil.ReadDir(filepath.Join(filepath.Dir(configFile), "history", "config"))
+	s.Require().NoError(err)
+	s.Require().Len(entries, 3) // two revisions and the index
+}
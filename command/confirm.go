@@ -0,0 +1,60 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+// KeyYes is the global flag that answers yes to all confirmation prompts
+const KeyYes = "yes"
+
+// AssumeYes returns true when confirmation prompts are answered by --yes
+func (baseCmd *BaseCommand) AssumeYes() bool {
+	return baseCmd.assumeYes
+}
+
+// Interactive returns true when the command can prompt the user for input
+func (baseCmd *BaseCommand) Interactive() bool {
+	in, ok := baseCmd.rootCobraCommand.InOrStdin().(interface{ Fd() uintptr })
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(in.Fd()) || isatty.IsCygwinTerminal(in.Fd())
+}
+
+// Confirm asks the user to confirm an action, answering yes without prompting when --yes is given
+// Sessions that cannot prompt fail rather than assume an answer to avoid destructive surprises
+func (baseCmd *BaseCommand) Confirm(message string, defaultAnswer bool) (bool, error) {
+	if baseCmd.assumeYes {
+		return true, nil
+	}
+	if !baseCmd.Interactive() {
+		return false, fmt.Errorf("cannot confirm %q in a non-interactive session: rerun with --%s to proceed", message, KeyYes)
+	}
+	confirmed := defaultAnswer
+	err := baseCmd.AskOne(&survey.Confirm{Message: message, Default: defaultAnswer}, &confirmed)
+	return confirmed, err
+}
+
+// AddDeprecatedForceFlag registers --force as a deprecated alias of the global --yes flag
+func (baseCmd *BaseCommand) AddDeprecatedForceFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&baseCmd.assumeYes, "force", "f", false, "Don't prompt for confirmation")
+	cmd.Flags().MarkDeprecated("force", fmt.Sprintf("use --%s instead", KeyYes))
+}
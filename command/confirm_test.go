@@ -0,0 +1,93 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v2"
+)
+
+type ConfirmTestSuite struct {
+	test.Suite
+}
+
+func TestConfirmTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfirmTestSuite))
+}
+
+func (s *ConfirmTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+}
+
+func (s *ConfirmTestSuite) configFile() string {
+	return test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+				"base_url":  "https://api.opsani.com/",
+			},
+		},
+	}).Name()
+}
+
+func (s *ConfirmTestSuite) profileNames(configFile string) []string {
+	var config struct {
+		Profiles []command.Profile `yaml:"profiles"`
+	}
+	body, err := ioutil.ReadFile(configFile)
+	s.Require().NoError(err)
+	s.Require().NoError(yaml.Unmarshal(body, &config))
+	names := []string{}
+	for _, profile := range config.Profiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+func (s *ConfirmTestSuite) TestNonInteractiveConfirmationFails() {
+	configFile := s.configFile()
+	_, err := s.Execute("--config", configFile, "profile", "remove", "default")
+	s.Require().EqualError(err, `cannot confirm "Remove profile \"default\"?" in a non-interactive session: rerun with --yes to proceed`)
+	s.Require().Equal([]string{"default"}, s.profileNames(configFile))
+}
+
+func (s *ConfirmTestSuite) TestYesSkipsConfirmation() {
+	configFile := s.configFile()
+	_, err := s.Execute("--config", configFile, "--yes", "profile", "remove", "default")
+	s.Require().NoError(err)
+	s.Require().Empty(s.profileNames(configFile))
+}
+
+func (s *ConfirmTestSuite) TestShorthandYesSkipsConfirmation() {
+	configFile := s.configFile()
+	_, err := s.Execute("--config", configFile, "profile", "remove", "default", "-y")
+	s.Require().NoError(err)
+	s.Require().Empty(s.profileNames(configFile))
+}
+
+func (s *ConfirmTestSuite) TestDeprecatedForceSkipsConfirmation() {
+	configFile := s.configFile()
+	output, err := s.Execute("--config", configFile, "profile", "remove", "default", "--force")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Flag --force has been deprecated, use --yes instead")
+	s.Require().Empty(s.profileNames(configFile))
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+// Container runtimes supported for pulling and loading images
+// Each provides a Docker compatible command line
+const (
+	ContainerRuntimeDocker  = "docker"
+	ContainerRuntimePodman  = "podman"
+	ContainerRuntimeNerdctl = "nerdctl"
+)
+
+// Flag and config keys for selecting the container runtime
+const (
+	KeyContainerRuntime       = "container-runtime"
+	KeyContainerRuntimeConfig = "ignite.container_runtime"
+)
+
+// ContainerRuntimes returns the names of the supported container runtimes
+func ContainerRuntimes() []string {
+	return []string{ContainerRuntimeDocker, ContainerRuntimePodman, ContainerRuntimeNerdctl}
+}
+
+// ValidateContainerRuntime returns an error if the runtime is not supported
+func ValidateContainerRuntime(runtime string) error {
+	for _, r := range ContainerRuntimes() {
+		if r == runtime {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid container runtime %q: must be one of %s", runtime, strings.Join(ContainerRuntimes(), ", "))
+}
+
+// ContainerRuntimeEnv describes the host environment inspected when detecting the container runtime
+type ContainerRuntimeEnv struct {
+	Getenv   func(key string) string
+	Exists   func(path string) bool
+	LookPath func(file string) (string, error)
+}
+
+// DetectContainerRuntime returns the container runtime whose socket is available
+// Docker is preferred, then Podman, then containerd via nerdctl. When no socket is found the first
+// runtime with a CLI on the path is returned, defaulting to Docker.
+func DetectContainerRuntime(env ContainerRuntimeEnv) string {
+	home := env.Getenv("HOME")
+	runtimeDir := env.Getenv("XDG_RUNTIME_DIR")
+	sockets := []struct {
+		runtime string
+		paths   []string
+	}{
+		{ContainerRuntimeDocker, []string{"/var/run/docker.sock", filepath.Join(home, ".docker/run/docker.sock")}},
+		{ContainerRuntimePodman, []string{filepath.Join(runtimeDir, "podman/podman.sock"), "/run/podman/podman.sock",
+			filepath.Join(home, ".local/share/containers/podman/machine/podman.sock")}},
+		{ContainerRuntimeNerdctl, []string{"/run/containerd/containerd.sock", filepath.Join(home, ".rd/containerd.sock")}},
+	}
+
+	if env.Getenv("DOCKER_HOST") != "" {
+		return ContainerRuntimeDocker
+	}
+	for _, socket := range sockets {
+		if _, err := env.LookPath(socket.runtime); err != nil {
+			continue
+		}
+		for _, path := range socket.paths {
+			if path != "" && env.Exists(path) {
+				return socket.runtime
+			}
+		}
+	}
+	for _, runtime := range ContainerRuntimes() {
+		if _, err := env.LookPath(runtime); err == nil {
+			return runtime
+		}
+	}
+	return ContainerRuntimeDocker
+}
+
+// ContainerRuntimeDetector returns the container runtime of the host
+type ContainerRuntimeDetector func() string
+
+var detectContainerRuntime ContainerRuntimeDetector = detectHostContainerRuntime
+
+// SetContainerRuntimeDetector sets the function used to detect the container runtime of the host
+// A nil function restores the default detection
+func SetContainerRuntimeDetector(f ContainerRuntimeDetector) {
+	if f == nil {
+		f = detectHostContainerRuntime
+	}
+	detectContainerRuntime = f
+}
+
+func detectHostContainerRuntime() string {
+	return DetectContainerRuntime(ContainerRuntimeEnv{
+		Getenv: func(key string) string {
+			if key == "HOME" {
+				home, _ := homedir.Dir()
+				return home
+			}
+			return os.Getenv(key)
+		},
+		Exists: func(path string) bool {
+			_, err := os.Stat(path)
+			return err == nil
+		},
+		LookPath: exec.LookPath,
+	})
+}
+
+// AddContainerRuntimeFlag adds the container runtime flag to the command
+func AddContainerRuntimeFlag(cmd *cobra.Command) {
+	cmd.Flags().String(KeyContainerRuntime, "", fmt.Sprintf("Container runtime for pulling images: {%s} (default is detected)", strings.Join(ContainerRuntimes(), "|")))
+}
+
+// ContainerRuntime returns the container runtime from the flag, falling back to config and then detection
+func (baseCmd *BaseCommand) ContainerRuntime(cmd *cobra.Command) (string, error) {
+	runtime := ""
+	if flag := cmd.Flags().Lookup(KeyContainerRuntime); flag != nil && flag.Changed {
+		runtime = flag.Value.String()
+	} else if baseCmd.viperCfg != nil {
+		runtime = baseCmd.viperCfg.GetString(KeyContainerRuntimeConfig)
+	}
+	if runtime == "" {
+		return detectContainerRuntime(), nil
+	}
+	return runtime, ValidateContainerRuntime(runtime)
+}
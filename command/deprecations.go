@@ -0,0 +1,54 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Settings renamed when "app" became "optimizer", accepted for compatibility with older scripts and configs
+const (
+	KeyLegacyApp    = "app"
+	legacyAppEnvKey = "OPSANI_APP"
+)
+
+// addDeprecatedAppFlag registers --app as a deprecated alias of the --optimizer flag
+func addDeprecatedAppFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().String(KeyLegacyApp, "", "Optimizer to manage")
+	cmd.PersistentFlags().MarkDeprecated(KeyLegacyApp, fmt.Sprintf("use --%s instead", KeyOptimizer))
+}
+
+// legacyAppFromFlagsOrEnv returns the optimizer given by the deprecated --app flag or OPSANI_APP environment variable
+func (cmd *BaseCommand) legacyAppFromFlagsOrEnv() string {
+	return cmd.valueFromFlagOrEnv(KeyLegacyApp, legacyAppEnvKey)
+}
+
+// warnDeprecatedSettings reports use of renamed environment variables and profile keys on stderr
+func (cmd *BaseCommand) warnDeprecatedSettings(registry *ProfileRegistry) {
+	if _, set := os.LookupEnv(legacyAppEnvKey); set {
+		cmd.PrintErrf("Environment variable %s has been deprecated, use OPSANI_OPTIMIZER instead\n", legacyAppEnvKey)
+	}
+	if registry == nil {
+		return
+	}
+	for _, profile := range registry.Profiles() {
+		if profile.LegacyApp != "" {
+			cmd.PrintErrf("Key %q of profile %q has been deprecated, use %q instead\n", KeyLegacyApp, profile.Name, KeyOptimizer)
+		}
+	}
+}
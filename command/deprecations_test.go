@@ -0,0 +1,107 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v2"
+)
+
+type DeprecationsTestSuite struct {
+	test.Suite
+	rootCmd *command.BaseCommand
+}
+
+func TestDeprecationsTestSuite(t *testing.T) {
+	suite.Run(t, new(DeprecationsTestSuite))
+}
+
+func (s *DeprecationsTestSuite) SetupTest() {
+	os.Unsetenv("OPSANI_APP")
+	os.Unsetenv("OPSANI_OPTIMIZER")
+	s.rootCmd = command.NewRootCommand()
+	s.SetCommand(s.rootCmd)
+}
+
+func (s *DeprecationsTestSuite) TearDownTest() {
+	os.Unsetenv("OPSANI_APP")
+}
+
+func (s *DeprecationsTestSuite) configFile() string {
+	return test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+			},
+		},
+	}).Name()
+}
+
+func (s *DeprecationsTestSuite) TestAppFlag() {
+	output, err := s.Execute("--config", s.configFile(), "--app", "example.com/legacy", "config")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Flag --app has been deprecated, use --optimizer instead")
+	s.Require().Equal("example.com/legacy", s.rootCmd.Optimizer())
+}
+
+func (s *DeprecationsTestSuite) TestOptimizerFlagTakesPrecedenceOverAppFlag() {
+	_, err := s.Execute("--config", s.configFile(), "--app", "example.com/legacy", "--optimizer", "example.com/current", "config")
+	s.Require().NoError(err)
+	s.Require().Equal("example.com/current", s.rootCmd.Optimizer())
+}
+
+func (s *DeprecationsTestSuite) TestAppEnv() {
+	os.Setenv("OPSANI_APP", "example.com/legacy")
+	output, err := s.Execute("--config", s.configFile(), "config")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Environment variable OPSANI_APP has been deprecated, use OPSANI_OPTIMIZER instead")
+	s.Require().Equal("example.com/legacy", s.rootCmd.Optimizer())
+}
+
+func (s *DeprecationsTestSuite) TestProfileAppKey() {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{
+				"name":  "legacy",
+				"app":   "example.com/legacy",
+				"token": "123456",
+			},
+		},
+	}).Name()
+	output, err := s.Execute("--config", configFile, "config")
+	s.Require().NoError(err)
+	s.Require().Contains(output, `Key "app" of profile "legacy" has been deprecated, use "optimizer" instead`)
+	s.Require().Equal("example.com/legacy", s.rootCmd.Optimizer())
+
+	// Saving the config migrates the profile to the current key
+	registry, err := command.NewProfileRegistry(s.rootCmd.Viper())
+	s.Require().NoError(err)
+	s.Require().NoError(registry.Save())
+
+	var config map[string][]map[string]string
+	body, err := ioutil.ReadFile(configFile)
+	s.Require().NoError(err)
+	s.Require().NoError(yaml.Unmarshal(body, &config))
+	s.Require().Equal("example.com/legacy", config["profiles"][0]["optimizer"])
+	s.Require().NotContains(config["profiles"][0], "app")
+}
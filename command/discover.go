@@ -0,0 +1,195 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// TargetSelector narrows the discovered optimization targets
+// Empty fields match any value
+type TargetSelector struct {
+	Namespace string
+	Workload  string
+	Container string
+	Service   string
+}
+
+// SelectTarget returns the highest ranked target matching the selector
+// The container and service of the target are overridden when given and validated against the discovered resources
+func SelectTarget(resources *KubernetesResources, selector TargetSelector) (*VitalTarget, error) {
+	// Report why the top-ranked candidate was rejected once every candidate is exhausted
+	var mismatch error
+	for _, candidate := range DetectTargets(resources) {
+		target := candidate.Target
+		if selector.Namespace != "" && target.Namespace != selector.Namespace {
+			continue
+		}
+		if selector.Workload != "" && target.Workload != selector.Workload {
+			continue
+		}
+		if selector.Container != "" {
+			if !workloadHasContainer(resources, target, selector.Container) {
+				if mismatch == nil {
+					mismatch = fmt.Errorf("container %q not found in %s", selector.Container, target)
+				}
+				continue
+			}
+			target.Container = selector.Container
+		}
+		if selector.Service != "" {
+			if !namespaceHasService(resources, target.Namespace, selector.Service) {
+				if mismatch == nil {
+					mismatch = fmt.Errorf("service %q not found in namespace %q", selector.Service, target.Namespace)
+				}
+				continue
+			}
+			target.Service = selector.Service
+		}
+		return &target, nil
+	}
+	if mismatch != nil {
+		return nil, mismatch
+	}
+	if selector.Workload != "" {
+		return nil, fmt.Errorf("no workload named %q found", selector.Workload)
+	}
+	return nil, fmt.Errorf("no optimization targets found")
+}
+
+func workloadHasContainer(resources *KubernetesResources, target VitalTarget, container string) bool {
+	for _, workload := range resources.WorkloadsInNamespace(target.Namespace) {
+		if workload.Kind != target.Kind || workload.Name != target.Workload {
+			continue
+		}
+		for _, name := range workload.Containers {
+			if name == container {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func namespaceHasService(resources *KubernetesResources, namespace, service string) bool {
+	for _, s := range resources.ServicesInNamespace(namespace) {
+		if s.Name == service {
+			return true
+		}
+	}
+	return false
+}
+
+// NewDiscoverCommand returns a new `opsani discover` command instance
+func NewDiscoverCommand(baseCmd *BaseCommand) *cobra.Command {
+	discoverCmd := &cobra.Command{
+		Use:   "discover",
+		Short: "Discover optimization targets and build servo manifests",
+		Long: `Discovers the workloads of the cluster in the current kubeconfig context and ranks
+them as optimization targets. Workloads selected by a service, running a single
+container, and carrying conventional app labels rank highest.
+
+With --manifests, the servo manifests for optimizing the highest ranked target
+(narrowed with --namespace, --workload, --container, and --service) with the
+optimizer of the active profile are written to the given directory. Metrics are
+gathered from Prometheus; use ` + "`opsani vital`" + ` to configure other providers interactively.`,
+		Example: `  opsani discover
+  opsani discover --namespace apps --workload web --manifests ./manifests`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: ReduceRunEFuncs(baseCmd.InitConfigRunE, baseCmd.RequireConfigFileFlagToExistRunE),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := OutputFormat(cmd, TabularOutputFormats...)
+			if err != nil {
+				return err
+			}
+			manifestsDir, _ := cmd.Flags().GetString("manifests")
+			scope, _ := cmd.Flags().GetString("scope")
+			if manifestsDir != "" {
+				if err := validateRBACScope(scope); err != nil {
+					return err
+				}
+				if err := baseCmd.RequireInitRunE(cmd, args); err != nil {
+					return err
+				}
+			}
+
+			kubeconfig := ""
+			if baseCmd.profile != nil {
+				kubeconfig = baseCmd.profile.Servo.Kubeconfig
+			}
+			kubernetes, err := kubernetesClientFactory(kubeconfig, "")
+			if err != nil {
+				return err
+			}
+			resources, err := NewKubernetesDiscovery(kubernetes, baseCmd.Timeout()).Discover()
+			if err != nil {
+				return err
+			}
+
+			if manifestsDir == "" {
+				candidates := DetectTargets(resources)
+				table := Table{Headers: []string{"NAMESPACE", "KIND", "WORKLOAD", "CONTAINER", "SERVICE", "SCORE"}}
+				for _, candidate := range candidates {
+					table.Rows = append(table.Rows, []string{
+						candidate.Target.Namespace,
+						strings.ToLower(candidate.Target.Kind),
+						candidate.Target.Workload,
+						candidate.Target.Container,
+						candidate.Target.Service,
+						strconv.Itoa(candidate.Score),
+					})
+				}
+				return baseCmd.RenderTable(output, table)
+			}
+
+			selector := TargetSelector{}
+			selector.Namespace, _ = cmd.Flags().GetString("namespace")
+			selector.Workload, _ = cmd.Flags().GetString("workload")
+			selector.Container, _ = cmd.Flags().GetString("container")
+			selector.Service, _ = cmd.Flags().GetString("service")
+			target, err := SelectTarget(resources, selector)
+			if err != nil {
+				return err
+			}
+			servoNamespace, _ := cmd.Flags().GetString("servo-namespace")
+			manifests, err := GenerateServoManifests(*target, *baseCmd.profile, ServoManifestOptions{
+				Scope:          scope,
+				ServoNamespace: servoNamespace,
+			})
+			if err != nil {
+				return err
+			}
+			if err := writeManifests(manifestsDir, manifests); err != nil {
+				return err
+			}
+			baseCmd.Printf("Wrote servo manifests for %s (container %q, service %q) to %s\n", target, target.Container, target.Service, manifestsDir)
+			baseCmd.Printf("Apply them with `kubectl apply -f %s`\n", manifestsDir)
+			return nil
+		},
+	}
+	discoverCmd.Flags().String("manifests", "", "Write servo manifests for the selected target into the directory")
+	discoverCmd.Flags().StringP("namespace", "n", "", "Namespace of the target workload")
+	discoverCmd.Flags().String("workload", "", "Name of the target workload")
+	discoverCmd.Flags().String("container", "", "Container of the target workload to optimize")
+	discoverCmd.Flags().String("service", "", "Service routing traffic to the target workload")
+	discoverCmd.Flags().String("scope", RBACScopeCluster, "Scope of servo permissions: {cluster|namespace}")
+	discoverCmd.Flags().String("servo-namespace", "", "Namespace to deploy the servo into (default is the target namespace)")
+	AddOutputFlag(discoverCmd, TabularOutputFormats...)
+	return discoverCmd
+}
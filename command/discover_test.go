@@ -0,0 +1,132 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+)
+
+type DiscoverTestSuite struct {
+	test.Suite
+	kubernetes *test.FakeKubernetes
+}
+
+func TestDiscoverTestSuite(t *testing.T) {
+	suite.Run(t, new(DiscoverTestSuite))
+}
+
+func (s *DiscoverTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+	s.kubernetes = newDiscoveryKubernetes()
+	command.SetKubernetesClientFactory(s.kubernetes.Factory())
+}
+
+func (s *DiscoverTestSuite) TearDownTest() {
+	command.SetKubernetesClientFactory(nil)
+}
+
+func (s *DiscoverTestSuite) configFile() string {
+	return test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{"name": "default", "optimizer": "example.com/app", "token": "123456"},
+		},
+	}).Name()
+}
+
+func (s *DiscoverTestSuite) TestListTargets() {
+	output, err := s.Execute("--config", s.configFile(), "discover", "--output", "csv")
+	s.Require().NoError(err)
+	s.Require().Equal(`NAMESPACE,KIND,WORKLOAD,CONTAINER,SERVICE,SCORE
+apps,deployment,web,main,web,12
+apps,statefulset,db,postgres,,5
+`, output)
+}
+
+func (s *DiscoverTestSuite) TestWriteManifests() {
+	dir, err := ioutil.TempDir("", "opsani-discover")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+
+	output, err := s.Execute("--config", s.configFile(), "discover", "--workload", "db", "--manifests", dir)
+	s.Require().NoError(err)
+	s.Require().Contains(output, `Wrote servo manifests for statefulset apps/db (container "postgres", service "")`)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "servo-configmap.yaml"))
+	s.Require().NoError(err)
+	s.Require().Contains(string(data), "namespace: apps")
+}
+
+func (s *DiscoverTestSuite) TestWriteManifestsRequiresInit() {
+	_, err := s.Execute("discover", "--manifests", "manifests")
+	s.Require().EqualError(err, `command failed because client is not initialized. Run "opsani init" and try again`)
+}
+
+func (s *DiscoverTestSuite) TestWriteManifestsUnknownWorkload() {
+	_, err := s.Execute("--config", s.configFile(), "discover", "--workload", "api", "--manifests", "manifests")
+	s.Require().EqualError(err, `no workload named "api" found`)
+}
+
+func (s *DiscoverTestSuite) TestSelectTargetOverrides() {
+	resources := &command.KubernetesResources{
+		Namespaces: []string{"apps"},
+		Workloads: []command.KubernetesWorkload{
+			{Kind: command.WorkloadDeployment, Namespace: "apps", Name: "web", Selector: map[string]string{"app": "web"}, PodLabels: map[string]string{"app": "web"}, Containers: []string{"main", "envoy"}},
+		},
+		Services: []command.KubernetesService{
+			{Namespace: "apps", Name: "web", Selector: map[string]string{"app": "web"}},
+			{Namespace: "apps", Name: "web-admin", Selector: map[string]string{"app": "web"}},
+		},
+	}
+	target, err := command.SelectTarget(resources, command.TargetSelector{Container: "envoy", Service: "web-admin"})
+	s.Require().NoError(err)
+	s.Require().Equal(command.VitalTarget{Namespace: "apps", Kind: command.WorkloadDeployment, Workload: "web", Container: "envoy", Service: "web-admin"}, *target)
+
+	_, err = command.SelectTarget(resources, command.TargetSelector{Container: "sidecar"})
+	s.Require().EqualError(err, `container "sidecar" not found in deployment apps/web`)
+	_, err = command.SelectTarget(resources, command.TargetSelector{Service: "api"})
+	s.Require().EqualError(err, `service "api" not found in namespace "apps"`)
+	_, err = command.SelectTarget(resources, command.TargetSelector{Namespace: "other"})
+	s.Require().EqualError(err, "no optimization targets found")
+}
+
+func (s *DiscoverTestSuite) TestSelectTargetSkipsCandidatesWithoutContainer() {
+	resources := &command.KubernetesResources{
+		Namespaces: []string{"apps"},
+		Workloads: []command.KubernetesWorkload{
+			{Kind: command.WorkloadDeployment, Namespace: "apps", Name: "web", Selector: map[string]string{"app": "web"}, PodLabels: map[string]string{"app": "web"}, Containers: []string{"main"}},
+			{Kind: command.WorkloadDeployment, Namespace: "apps", Name: "api", Selector: map[string]string{"app": "api"}, PodLabels: map[string]string{"app": "api"}, Containers: []string{"main", "envoy"}},
+		},
+		Services: []command.KubernetesService{
+			{Namespace: "apps", Name: "web", Selector: map[string]string{"app": "web"}},
+		},
+	}
+	target, err := command.SelectTarget(resources, command.TargetSelector{})
+	s.Require().NoError(err)
+	s.Require().Equal("web", target.Workload)
+
+	target, err = command.SelectTarget(resources, command.TargetSelector{Container: "envoy"})
+	s.Require().NoError(err)
+	s.Require().Equal(command.VitalTarget{Namespace: "apps", Kind: command.WorkloadDeployment, Workload: "api", Container: "envoy"}, *target)
+
+	_, err = command.SelectTarget(resources, command.TargetSelector{Container: "sidecar"})
+	s.Require().EqualError(err, `container "sidecar" not found in deployment apps/web`)
+}
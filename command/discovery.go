@@ -0,0 +1,277 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// SystemNamespaces are excluded from discovery as they do not host optimizable applications
+var SystemNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// Workload kinds supported as optimization targets
+const (
+	WorkloadDeployment  = "Deployment"
+	WorkloadStatefulSet = "StatefulSet"
+	WorkloadDaemonSet   = "DaemonSet"
+	WorkloadRollout     = "Rollout"
+)
+
+// KubernetesWorkload describes a deployment, stateful set, daemon set, or Argo rollout discovered in the cluster
+type KubernetesWorkload struct {
+	Kind       string
+	Namespace  string
+	Name       string
+	Labels     map[string]string
+	Selector   map[string]string
+	Containers []string
+
+	// PodLabels are the labels of the pod template, which Services and PodDisruptionBudgets select on
+	PodLabels map[string]string
+
+	// Replicas is the desired number of pods, which is zero for daemon sets
+	Replicas int
+
+	// ReadinessProbes are the names of the containers that define a readiness probe
+	ReadinessProbes []string
+
+	// Resources are the resource requests and limits of the containers, keyed by container name
+	Resources map[string]ContainerResources
+}
+
+// KubernetesService describes a service discovered in the cluster
+type KubernetesService struct {
+	Namespace string
+	Name      string
+	Selector  map[string]string
+}
+
+// KubernetesResources is a snapshot of the cluster resources relevant to optimization
+type KubernetesResources struct {
+	Namespaces []string
+	Workloads  []KubernetesWorkload
+	Services   []KubernetesService
+}
+
+// WorkloadsInNamespace returns the workloads in the given namespace
+func (r *KubernetesResources) WorkloadsInNamespace(namespace string) []KubernetesWorkload {
+	workloads := []KubernetesWorkload{}
+	for _, workload := range r.Workloads {
+		if workload.Namespace == namespace {
+			workloads = append(workloads, workload)
+		}
+	}
+	return workloads
+}
+
+// ServicesInNamespace returns the services in the given namespace
+func (r *KubernetesResources) ServicesInNamespace(namespace string) []KubernetesService {
+	services := []KubernetesService{}
+	for _, service := range r.Services {
+		if service.Namespace == namespace {
+			services = append(services, service)
+		}
+	}
+	return services
+}
+
+// KubernetesDiscovery fetches cluster resources concurrently and caches them for the session
+type KubernetesDiscovery struct {
+	kubernetes *KubernetesClient
+	timeout    time.Duration
+	once       sync.Once
+	resources  *KubernetesResources
+	err        error
+}
+
+// NewKubernetesDiscovery returns a discovery service that queries the cluster via the given client
+func NewKubernetesDiscovery(kubernetes *KubernetesClient, timeout time.Duration) *KubernetesDiscovery {
+	return &KubernetesDiscovery{kubernetes: kubernetes, timeout: timeout}
+}
+
+// Discover returns the namespaces, workloads, and services in the cluster, excluding system namespaces
+// Resources are fetched in parallel on first use and cached for subsequent calls
+func (d *KubernetesDiscovery) Discover() (*KubernetesResources, error) {
+	d.once.Do(func() {
+		d.resources, d.err = d.fetch()
+	})
+	return d.resources, d.err
+}
+
+func (d *KubernetesDiscovery) fetch() (*KubernetesResources, error) {
+	ctx, cancel := contextWithTimeout(d.timeout)
+	defer cancel()
+
+	var namespaces, deployments, statefulSets, daemonSets, rollouts, services kubernetesList
+	fetches := []struct {
+		resource schema.GroupVersionResource
+		list     *kubernetesList
+		optional bool
+	}{
+		{namespaceResource, &namespaces, false},
+		{deploymentResource, &deployments, false},
+		{statefulSetResource, &statefulSets, false},
+		{daemonSetResource, &daemonSets, false},
+		{rolloutResource, &rollouts, true}, // Only available when Argo Rollouts is installed
+		{serviceResource, &services, false},
+	}
+	// The first failure cancels the remaining fetches and is reported rather than their cancellation
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	for _, fetch := range fetches {
+		wg.Add(1)
+		go func(resource schema.GroupVersionResource, list *kubernetesList, optional bool) {
+			defer wg.Done()
+			output, err := d.kubernetes.List(ctx, resource, "", nil)
+			if err == nil {
+				err = json.Unmarshal(output, list)
+			}
+			if err != nil && !optional {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed listing %s: %w", resource.GroupResource(), err)
+				}
+				mu.Unlock()
+				cancel()
+			}
+		}(fetch.resource, fetch.list, fetch.optional)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	resources := &KubernetesResources{}
+	for _, item := range namespaces.Items {
+		if !isSystemNamespace(item.Metadata.Name) {
+			resources.Namespaces = append(resources.Namespaces, item.Metadata.Name)
+		}
+	}
+	sort.Strings(resources.Namespaces)
+	workloads := []struct {
+		kind string
+		list kubernetesList
+	}{
+		{WorkloadDeployment, deployments},
+		{WorkloadStatefulSet, statefulSets},
+		{WorkloadDaemonSet, daemonSets},
+		{WorkloadRollout, rollouts},
+	}
+	for _, workload := range workloads {
+		for _, item := range workload.list.Items {
+			if isSystemNamespace(item.Metadata.Namespace) {
+				continue
+			}
+			var selector struct {
+				MatchLabels map[string]string `json:"matchLabels"`
+			}
+			json.Unmarshal(item.Spec.Selector, &selector)
+			containers, readinessProbes := []string{}, []string{}
+			containerResources := map[string]ContainerResources{}
+			for _, container := range item.Spec.Template.Spec.Containers {
+				containers = append(containers, container.Name)
+				if len(container.ReadinessProbe) > 0 && string(container.ReadinessProbe) != "null" {
+					readinessProbes = append(readinessProbes, container.Name)
+				}
+				containerResources[container.Name] = ContainerResources{
+					CPURequest:    container.Resources.Requests["cpu"],
+					CPULimit:      container.Resources.Limits["cpu"],
+					MemoryRequest: container.Resources.Requests["memory"],
+					MemoryLimit:   container.Resources.Limits["memory"],
+				}
+			}
+			replicas := 0
+			if workload.kind != WorkloadDaemonSet {
+				// Kubernetes defaults to a single replica when unspecified
+				replicas = 1
+				if item.Spec.Replicas != nil {
+					replicas = *item.Spec.Replicas
+				}
+			}
+			resources.Workloads = append(resources.Workloads, KubernetesWorkload{
+				Kind:            workload.kind,
+				Namespace:       item.Metadata.Namespace,
+				Name:            item.Metadata.Name,
+				Labels:          item.Metadata.Labels,
+				Selector:        selector.MatchLabels,
+				Containers:      containers,
+				PodLabels:       item.Spec.Template.Metadata.Labels,
+				Replicas:        replicas,
+				ReadinessProbes: readinessProbes,
+				Resources:       containerResources,
+			})
+		}
+	}
+	for _, item := range services.Items {
+		if isSystemNamespace(item.Metadata.Namespace) {
+			continue
+		}
+		selector := map[string]string{}
+		json.Unmarshal(item.Spec.Selector, &selector)
+		resources.Services = append(resources.Services, KubernetesService{
+			Namespace: item.Metadata.Namespace,
+			Name:      item.Metadata.Name,
+			Selector:  selector,
+		})
+	}
+	return resources, nil
+}
+
+func isSystemNamespace(namespace string) bool {
+	for _, systemNamespace := range SystemNamespaces {
+		if namespace == systemNamespace {
+			return true
+		}
+	}
+	return false
+}
+
+// kubernetesList models the subset of the JSON object lists used during discovery
+type kubernetesList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string            `json:"name"`
+			Namespace string            `json:"namespace"`
+			Labels    map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Spec struct {
+			Replicas *int `json:"replicas"`
+			// Workloads select pods via match labels while services use a plain label map
+			Selector json.RawMessage `json:"selector"`
+			Template struct {
+				Metadata struct {
+					Labels map[string]string `json:"labels"`
+				} `json:"metadata"`
+				Spec struct {
+					Containers []struct {
+						Name      string `json:"name"`
+						Resources struct {
+							Requests map[string]string `json:"requests"`
+							Limits   map[string]string `json:"limits"`
+						} `json:"resources"`
+						ReadinessProbe json.RawMessage `json:"readinessProbe"`
+					} `json:"containers"`
+				} `json:"spec"`
+			} `json:"template"`
+		} `json:"spec"`
+	} `json:"items"`
+}
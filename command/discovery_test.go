@@ -0,0 +1,172 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var discoveryFixtures = map[string]string{
+	"namespaces": `{"items": [
+		{"metadata": {"name": "default"}},
+		{"metadata": {"name": "kube-system"}},
+		{"metadata": {"name": "apps"}}
+	]}`,
+	"deployments.apps": `{"items": [
+		{
+			"metadata": {"name": "web", "namespace": "apps", "labels": {"app": "web"}},
+			"spec": {
+				"replicas": 2,
+				"selector": {"matchLabels": {"app": "web"}},
+				"template": {"metadata": {"labels": {"app": "web", "tier": "frontend"}}, "spec": {"containers": [
+					{"name": "main", "resources": {"requests": {"cpu": "500m", "memory": "512Mi"}, "limits": {"memory": "1Gi"}}, "readinessProbe": {"httpGet": {"path": "/", "port": 8080}}},
+					{"name": "envoy"}
+				]}}
+			}
+		},
+		{
+			"metadata": {"name": "coredns", "namespace": "kube-system"},
+			"spec": {"template": {"spec": {"containers": [{"name": "coredns"}]}}}
+		}
+	]}`,
+	"statefulsets.apps": `{"items": [
+		{
+			"metadata": {"name": "db", "namespace": "apps"},
+			"spec": {
+				"selector": {"matchLabels": {"app": "db"}},
+				"template": {"metadata": {"labels": {"app": "db"}}, "spec": {"containers": [{"name": "postgres"}]}}
+			}
+		}
+	]}`,
+	"daemonsets.apps": `{"items": []}`,
+	"services": `{"items": [
+		{"metadata": {"name": "web", "namespace": "apps"}, "spec": {"selector": {"app": "web"}}},
+		{"metadata": {"name": "kube-dns", "namespace": "kube-system"}, "spec": {"selector": {"k8s-app": "kube-dns"}}}
+	]}`,
+}
+
+// newDiscoveryKubernetes returns a fake cluster serving the discovery fixtures without Argo Rollouts
+func newDiscoveryKubernetes() *test.FakeKubernetes {
+	kubernetes := test.NewFakeKubernetes()
+	for resource, list := range discoveryFixtures {
+		kubernetes.Lists[resource] = list
+	}
+	return kubernetes
+}
+
+// listCounts returns the number of times each resource was listed
+func listCounts(kubernetes *test.FakeKubernetes) map[string]int {
+	counts := map[string]int{}
+	for _, action := range kubernetes.Actions() {
+		if action.GetVerb() == "list" {
+			counts[action.GetResource().GroupResource().String()]++
+		}
+	}
+	return counts
+}
+
+func TestKubernetesDiscoveryFiltersSystemNamespaces(t *testing.T) {
+	resources, err := command.NewKubernetesDiscovery(newDiscoveryKubernetes().Client(), 0).Discover()
+	require.NoError(t, err)
+	require.Equal(t, []string{"apps", "default"}, resources.Namespaces)
+	require.Equal(t, []command.KubernetesWorkload{
+		{
+			Kind:            command.WorkloadDeployment,
+			Namespace:       "apps",
+			Name:            "web",
+			Labels:          map[string]string{"app": "web"},
+			Selector:        map[string]string{"app": "web"},
+			Containers:      []string{"main", "envoy"},
+			PodLabels:       map[string]string{"app": "web", "tier": "frontend"},
+			Replicas:        2,
+			ReadinessProbes: []string{"main"},
+			Resources: map[string]command.ContainerResources{
+				"main":  {CPURequest: "500m", MemoryRequest: "512Mi", MemoryLimit: "1Gi"},
+				"envoy": {},
+			},
+		},
+		{
+			Kind:            command.WorkloadStatefulSet,
+			Namespace:       "apps",
+			Name:            "db",
+			Selector:        map[string]string{"app": "db"},
+			Containers:      []string{"postgres"},
+			PodLabels:       map[string]string{"app": "db"},
+			Replicas:        1,
+			ReadinessProbes: []string{},
+			Resources:       map[string]command.ContainerResources{"postgres": {}},
+		},
+	}, resources.Workloads)
+	require.Equal(t, []command.KubernetesService{
+		{Namespace: "apps", Name: "web", Selector: map[string]string{"app": "web"}},
+	}, resources.Services)
+}
+
+func TestKubernetesDiscoveryCachesResources(t *testing.T) {
+	kubernetes := newDiscoveryKubernetes()
+	discovery := command.NewKubernetesDiscovery(kubernetes.Client(), 0)
+	first, err := discovery.Discover()
+	require.NoError(t, err)
+	second, err := discovery.Discover()
+	require.NoError(t, err)
+	require.Same(t, first, second)
+	require.Equal(t, map[string]int{
+		"namespaces":        1,
+		"deployments.apps":  1,
+		"statefulsets.apps": 1,
+		"daemonsets.apps":   1,
+		"services":          1,
+	}, listCounts(kubernetes))
+}
+
+func TestKubernetesDiscoveryReturnsErrors(t *testing.T) {
+	kubernetes := newDiscoveryKubernetes()
+	kubernetes.Errors["list services"] = errors.New("connection refused")
+	_, err := command.NewKubernetesDiscovery(kubernetes.Client(), 0).Discover()
+	require.EqualError(t, err, "failed listing services: connection refused")
+}
+
+func TestKubernetesDiscoveryReturnsCauseOfCancellation(t *testing.T) {
+	// Sibling fetches fail with the cancellation after the services fetch has failed
+	kubernetes := newDiscoveryKubernetes()
+	kubernetes.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetResource().Resource == "services" {
+			return true, nil, errors.New("connection refused")
+		}
+		time.Sleep(20 * time.Millisecond)
+		return true, nil, context.Canceled
+	})
+	for i := 0; i < 10; i++ {
+		_, err := command.NewKubernetesDiscovery(kubernetes.Client(), 0).Discover()
+		require.EqualError(t, err, "failed listing services: connection refused")
+	}
+}
+
+func TestKubernetesResourcesByNamespace(t *testing.T) {
+	resources, err := command.NewKubernetesDiscovery(newDiscoveryKubernetes().Client(), 0).Discover()
+	require.NoError(t, err)
+	require.Len(t, resources.WorkloadsInNamespace("apps"), 2)
+	require.Len(t, resources.ServicesInNamespace("apps"), 1)
+	require.Empty(t, resources.WorkloadsInNamespace("default"))
+}
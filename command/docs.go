@@ -0,0 +1,122 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+// NewDocsCommand returns a new Opsani CLI docs command instance
+// Docs are used by packagers and the website and are not intended for end users
+func NewDocsCommand(baseCmd *BaseCommand) *cobra.Command {
+	docsCmd := &cobra.Command{
+		Use:    "docs",
+		Short:  "Generate reference documentation",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+	}
+
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate man pages or Markdown reference docs",
+		Long: `Generate man pages or Markdown reference docs for every command in the
+Opsani CLI command tree, including the grouped command sections shown in help output.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			dir, _ := cmd.Flags().GetString("output")
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+
+			root := cmd.Root()
+			root.DisableAutoGenTag = true
+			switch format {
+			case "man":
+				header := &doc.GenManHeader{
+					Title:   "OPSANI",
+					Section: "1",
+					Source:  fmt.Sprintf("Opsani CLI %s", Version),
+					Manual:  "Opsani CLI Manual",
+				}
+				if err := doc.GenManTree(root, header, dir); err != nil {
+					return err
+				}
+			case "markdown":
+				if err := doc.GenMarkdownTreeCustom(root, dir, func(string) string { return "" }, markdownLinkHandler); err != nil {
+					return err
+				}
+				if err := writeMarkdownCommandIndex(root, filepath.Join(dir, "README.md")); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unsupported docs format %q", format)
+			}
+			baseCmd.Printf("Generated %s docs in %s\n", format, dir)
+			return nil
+		},
+	}
+	generateCmd.Flags().StringP("format", "f", "markdown", "Output format: {man|markdown}")
+	generateCmd.Flags().StringP("output", "o", "docs/reference", "Directory to write generated docs to")
+	generateCmd.MarkFlagDirname("output")
+	docsCmd.AddCommand(generateCmd)
+
+	return docsCmd
+}
+
+func markdownLinkHandler(name string) string {
+	return strings.ToLower(name)
+}
+
+// writeMarkdownCommandIndex writes an index of top-level commands grouped into the
+// same sections rendered by the custom usage template
+func writeMarkdownCommandIndex(root *cobra.Command, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	basename := func(cmd *cobra.Command) string {
+		return strings.Replace(cmd.CommandPath(), " ", "_", -1) + ".md"
+	}
+	fmt.Fprintf(file, "# %s\n\n%s\n", root.Name(), root.Short)
+	sections := []struct {
+		title    string
+		commands []*cobra.Command
+	}{
+		{"Core Commands", managementSubCommands(root)},
+		{"Registry Commands", registrySubCommands(root)},
+		{"Commands", operationSubCommands(root)},
+		{"Learning Commands", educationalSubCommands(root)},
+		{"Other Commands", otherSubCommands(root)},
+	}
+	for _, section := range sections {
+		if len(section.commands) == 0 {
+			continue
+		}
+		fmt.Fprintf(file, "\n## %s\n\n", section.title)
+		for _, cmd := range section.commands {
+			fmt.Fprintf(file, "* [%s](%s) - %s\n", cmd.Name(), basename(cmd), cmd.Short)
+		}
+	}
+	return nil
+}
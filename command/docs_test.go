@@ -0,0 +1,74 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+)
+
+type DocsTestSuite struct {
+	test.Suite
+}
+
+func TestDocsTestSuite(t *testing.T) {
+	suite.Run(t, new(DocsTestSuite))
+}
+
+func (s *DocsTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+}
+
+func (s *DocsTestSuite) TestDocsCommandIsHidden() {
+	output, err := s.Execute("--help")
+	s.Require().NoError(err)
+	s.Require().NotContains(output, "Generate reference documentation")
+}
+
+func (s *DocsTestSuite) TestRunningDocsGenerateMarkdown() {
+	dir, err := ioutil.TempDir("", "opsani-docs")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+
+	_, err = s.Execute("docs", "generate", "--format", "markdown", "--output", dir)
+	s.Require().NoError(err)
+	body, err := ioutil.ReadFile(filepath.Join(dir, "opsani_servo_logs.md"))
+	s.Require().NoError(err)
+	s.Require().Contains(string(body), "View servo logs")
+	index, err := ioutil.ReadFile(filepath.Join(dir, "README.md"))
+	s.Require().NoError(err)
+	s.Require().Contains(string(index), "## Core Commands")
+}
+
+func (s *DocsTestSuite) TestRunningDocsGenerateMan() {
+	dir, err := ioutil.TempDir("", "opsani-docs")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+
+	_, err = s.Execute("docs", "generate", "--format", "man", "--output", dir)
+	s.Require().NoError(err)
+	s.Require().FileExists(filepath.Join(dir, "opsani-servo-logs.1"))
+}
+
+func (s *DocsTestSuite) TestRunningDocsGenerateInvalidFormat() {
+	_, err := s.Execute("docs", "generate", "--format", "pdf", "--output", os.TempDir())
+	s.Require().EqualError(err, `unsupported docs format "pdf"`)
+}
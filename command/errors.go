@@ -0,0 +1,243 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/opsani/cli/opsani"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// maxSuggestionDistance is the maximum edit distance for a "did you mean" suggestion
+const maxSuggestionDistance = 2
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	s, t := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	prev := make([]int, len(t)+1)
+	curr := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		curr[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(minInt(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(t)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// suggestionsFor returns the candidates that are within a small edit distance of the input
+// or which the input is a prefix of
+func suggestionsFor(input string, candidates []string) []string {
+	suggestions := []string{}
+	for _, candidate := range candidates {
+		if candidate == input {
+			continue
+		}
+		if levenshtein(input, candidate) <= maxSuggestionDistance ||
+			strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(input)) {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	return suggestions
+}
+
+func formatSuggestions(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n\nDid you mean this?\n\t%s\n", strings.Join(suggestions, "\n\t"))
+}
+
+// subCommandSuggestions returns the names of available subcommands whose name or aliases resemble the input
+func subCommandSuggestions(cmd *cobra.Command, input string) []string {
+	suggestions := []string{}
+	for _, sub := range cmd.Commands() {
+		if !sub.IsAvailableCommand() {
+			continue
+		}
+		if len(suggestionsFor(input, append([]string{sub.Name()}, sub.Aliases...))) > 0 {
+			suggestions = append(suggestions, sub.Name())
+		}
+	}
+	return suggestions
+}
+
+// subCommandArgs rejects arguments to a command group with suggestions for mistyped subcommands
+// Invocations without arguments display help without running any pre-run hooks
+func subCommandArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return pflag.ErrHelp
+	}
+	return fmt.Errorf("unknown command %q for %q%s",
+		args[0], cmd.CommandPath(), formatSuggestions(subCommandSuggestions(cmd, args[0])))
+}
+
+// enableSubCommandSuggestions walks the command tree and configures command groups to
+// suggest subcommands on typos rather than silently displaying help
+// Cobra only suggests subcommands of the root command
+func enableSubCommandSuggestions(cmd *cobra.Command) {
+	for _, sub := range cmd.Commands() {
+		if sub.HasSubCommands() && !sub.Runnable() {
+			sub.Args = subCommandArgs
+			sub.RunE = func(cmd *cobra.Command, args []string) error {
+				return cmd.Help()
+			}
+		}
+		enableSubCommandSuggestions(sub)
+	}
+}
+
+var unknownFlagPattern = regexp.MustCompile(`^unknown flag: --(\S+)`)
+
+// flagSuggestionsError decorates unknown flag errors with suggestions for similarly named flags
+func flagSuggestionsError(cmd *cobra.Command, err error) error {
+	matches := unknownFlagPattern.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return err
+	}
+	names := []string{}
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if !flag.Hidden {
+			names = append(names, flag.Name)
+		}
+	})
+	suggestions := suggestionsFor(matches[1], names)
+	if len(suggestions) == 0 {
+		return err
+	}
+	for i := range suggestions {
+		suggestions[i] = "--" + suggestions[i]
+	}
+	return fmt.Errorf("%w%s", err, formatSuggestions(suggestions))
+}
+
+// RenderAPIError formats an API error as a readable panel with the message wrapped to the given width
+// The server traceback is only included when requested as it is rarely useful to end users
+func RenderAPIError(apiErr *opsani.APIError, width int, includeTraceback bool) string {
+	border := activePalette.Failure.Foreground().Sprint
+	heading := activePalette.Failure.Sprint
+	dim := color.New(color.Faint).SprintFunc()
+
+	var sb strings.Builder
+	title := "request failed"
+	if apiErr.Status != "" {
+		title = fmt.Sprintf("request failed (%s)", apiErr.Status)
+	}
+	fmt.Fprintf(&sb, "%s %s\n", border(outputGlyph(glyphCornerTop)), heading(title))
+	for _, line := range wrapText(apiErrorDetails(apiErr), width-2) {
+		fmt.Fprintf(&sb, "%s %s\n", border(outputGlyph(glyphGutter)), line)
+	}
+	if traceback := strings.TrimSpace(apiErr.Traceback); traceback != "" {
+		fmt.Fprintf(&sb, "%s\n", border(outputGlyph(glyphGutter)))
+		if includeTraceback {
+			fmt.Fprintf(&sb, "%s %s\n", border(outputGlyph(glyphGutter)), heading("Traceback:"))
+			for _, line := range strings.Split(traceback, "\n") {
+				fmt.Fprintf(&sb, "%s %s\n", border(outputGlyph(glyphGutter)), dim(line))
+			}
+		} else {
+			fmt.Fprintf(&sb, "%s %s\n", border(outputGlyph(glyphGutter)), dim("Run with --debug to display the server traceback"))
+		}
+	}
+	fmt.Fprintf(&sb, "%s\n", border(outputGlyph(glyphCornerBottom)))
+	return sb.String()
+}
+
+// apiErrorDetails returns the message of an API error followed by a line for each invalid field
+func apiErrorDetails(apiErr *opsani.APIError) string {
+	lines := []string{strings.TrimSpace(apiErr.Message)}
+	for _, field := range apiErr.Errors {
+		lines = append(lines, "- "+field.String())
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// APIErrorRemediation suggests how to resolve an API error based on its type
+// An empty string is returned for errors without a targeted remediation
+func APIErrorRemediation(err error, profile *Profile) string {
+	name, optimizer := "NAME", "the optimizer"
+	if profile != nil {
+		name, optimizer = profile.Name, fmt.Sprintf("optimizer %q", profile.Optimizer)
+	}
+	var notFound *opsani.NotFoundError
+	var unauthorized *opsani.UnauthorizedError
+	var validation *opsani.ValidationError
+	switch {
+	case errors.As(err, &unauthorized) && unauthorized.Forbidden:
+		return fmt.Sprintf("The API token is not permitted to perform this request on %s. Use a token with the required access by running `opsani profile update %s --token TOKEN`", optimizer, name)
+	case errors.As(err, &unauthorized):
+		return fmt.Sprintf("The API token was rejected. Update it by running `opsani profile update %s --token TOKEN`", name)
+	case errors.As(err, &notFound):
+		return fmt.Sprintf("Check that %s exists or select another by running `opsani profile update %s --optimizer OPTIMIZER`", optimizer, name)
+	case errors.As(err, &validation) && len(validation.Fields()) > 0:
+		return "Correct the fields listed above. The current config is displayed by `opsani optimizer config get`"
+	}
+	return ""
+}
+
+// wrapText splits text into lines no longer than width, breaking on whitespace
+// Existing line breaks are preserved
+func wrapText(text string, width int) []string {
+	if width < 20 {
+		width = 20
+	}
+	lines := []string{}
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line != "" && len(line)+1+len(word) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line == "" {
+				line = word
+			} else {
+				line += " " + word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// terminalWidth returns the width of the terminal attached to stdout or DefaultTerminalWidth when unknown
+func terminalWidth() int {
+	if w, _, err := terminal.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		return w
+	}
+	return DefaultTerminalWidth
+}
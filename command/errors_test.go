@@ -0,0 +1,119 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/opsani"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ErrorsTestSuite struct {
+	test.Suite
+}
+
+func TestErrorsTestSuite(t *testing.T) {
+	suite.Run(t, new(ErrorsTestSuite))
+}
+
+func (s *ErrorsTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+}
+
+func (s *ErrorsTestSuite) TestUnknownSubCommandSuggestsMatches() {
+	_, err := s.Execute("profile", "lst")
+	s.Require().EqualError(err, "unknown command \"lst\" for \"opsani profile\"\n\nDid you mean this?\n\tlist\n\ttest\n")
+}
+
+func (s *ErrorsTestSuite) TestUnknownSubCommandWithoutMatches() {
+	_, err := s.Execute("servo", "zzzzzz")
+	s.Require().EqualError(err, "unknown command \"zzzzzz\" for \"opsani servo\"")
+}
+
+func (s *ErrorsTestSuite) TestCommandGroupWithoutArgsDisplaysHelp() {
+	output, err := s.Execute("profile")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Usage:	opsani profile [OPTIONS] COMMAND")
+}
+
+func (s *ErrorsTestSuite) TestUnknownFlagSuggestsMatches() {
+	_, err := s.Execute("config", "--tokn", "123")
+	s.Require().EqualError(err, "unknown flag: --tokn\n\nDid you mean this?\n\t--token\n")
+}
+
+func TestRenderAPIErrorWrapsMessage(t *testing.T) {
+	color.NoColor = true
+	apiErr := &opsani.APIError{
+		Status:    "400 Bad Request",
+		Message:   "The optimizer configuration is invalid because the measurement duration must be greater than zero",
+		Traceback: "Traceback (most recent call last):\n  File \"app.py\", line 1",
+	}
+	output := command.RenderAPIError(apiErr, 40, false)
+	require.Equal(t, `╭ request failed (400 Bad Request)
+│ The optimizer configuration is invalid
+│ because the measurement duration must
+│ be greater than zero
+│
+│ Run with --debug to display the server traceback
+╰
+`, output)
+}
+
+func TestRenderAPIErrorIncludesTraceback(t *testing.T) {
+	color.NoColor = true
+	apiErr := &opsani.APIError{
+		Status:    "500 Internal Server Error",
+		Message:   "boom",
+		Traceback: "Traceback (most recent call last):\n  File \"app.py\", line 1",
+	}
+	output := command.RenderAPIError(apiErr, 80, true)
+	require.Contains(t, output, "│ Traceback:\n│ Traceback (most recent call last):\n│   File \"app.py\", line 1\n")
+}
+
+func TestRenderAPIErrorListsInvalidFields(t *testing.T) {
+	color.NoColor = true
+	apiErr := &opsani.APIError{
+		Status:  "422 Unprocessable Entity",
+		Message: "invalid config",
+		Errors: []opsani.FieldError{
+			{Location: []interface{}{"adjustment", "cpu", "max"}, Message: "must be a number"},
+		},
+	}
+	output := command.RenderAPIError(apiErr, 80, false)
+	require.Equal(t, `╭ request failed (422 Unprocessable Entity)
+│ invalid config
+│ - adjustment.cpu.max: must be a number
+╰
+`, output)
+}
+
+func TestAPIErrorRemediation(t *testing.T) {
+	profile := &command.Profile{Name: "staging", Optimizer: "example.com/app"}
+	apiErr := &opsani.APIError{Message: "denied"}
+	require.Equal(t, "The API token was rejected. Update it by running `opsani profile update staging --token TOKEN`",
+		command.APIErrorRemediation(&opsani.UnauthorizedError{APIError: apiErr}, profile))
+	require.Contains(t, command.APIErrorRemediation(&opsani.UnauthorizedError{APIError: apiErr, Forbidden: true}, profile),
+		`not permitted to perform this request on optimizer "example.com/app"`)
+	require.Equal(t, "Check that optimizer \"example.com/app\" exists or select another by running `opsani profile update staging --optimizer OPTIMIZER`",
+		command.APIErrorRemediation(fmt.Errorf("status: %w", &opsani.NotFoundError{APIError: apiErr}), profile))
+	require.Empty(t, command.APIErrorRemediation(&opsani.ValidationError{APIError: apiErr}, profile))
+	require.Empty(t, command.APIErrorRemediation(apiErr, nil))
+}
@@ -0,0 +1,204 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+// CI providers supported by `opsani generate ci`
+const (
+	CIProviderGitHubActions = "github-actions"
+	CIProviderGitLab        = "gitlab"
+)
+
+// CIPipelineOptions describes the pipeline emitted by `opsani generate ci`
+type CIPipelineOptions struct {
+	Provider   string
+	CLIVersion string
+	ConfigFile string
+	ServoCheck bool
+	// BaseURL is the API base URL of single-tenant deployments and empty for the Opsani API
+	BaseURL string
+}
+
+// Pipeline templates use [[ ]] delimiters so that provider expressions such as ${{ secrets.TOKEN }} pass through
+var ciPipelineTemplates = map[string]string{
+	CIProviderGitHubActions: `# Generated by "opsani generate ci --provider github-actions"
+# Requires the OPSANI_OPTIMIZER and OPSANI_TOKEN repository secrets[[ if .ServoCheck ]]
+# and an OPSANI_CONFIG secret containing an Opsani CLI config with a servo attached[[ end ]]
+name: Opsani
+on: [push, pull_request]
+jobs:
+  opsani:
+    runs-on: ubuntu-latest
+    env:
+      OPSANI_OPTIMIZER: ${{ secrets.OPSANI_OPTIMIZER }}
+      OPSANI_TOKEN: ${{ secrets.OPSANI_TOKEN }}
+[[- with .BaseURL ]]
+      OPSANI_BASE_URL: [[ . ]]
+[[- end ]]
+    steps:
+    - name: Checkout code
+      uses: actions/checkout@v2
+
+    - name: Install Opsani CLI
+      run: |
+        curl -sSL [[ .ReleaseURL ]] | tar -xz
+        sudo install [[ .ReleaseDir ]]/bin/opsani /usr/local/bin/opsani
+        opsani --version
+
+    - name: Check optimizer config drift
+      run: |
+        opsani optimizer config --output "$RUNNER_TEMP/optimizer.json"
+        diff -u [[ .ConfigFile ]] "$RUNNER_TEMP/optimizer.json"
+[[- if .ServoCheck ]]
+
+    - name: Check servo
+      env:
+        OPSANI_CONFIG: ${{ secrets.OPSANI_CONFIG }}
+      run: |
+        echo "$OPSANI_CONFIG" > "$RUNNER_TEMP/opsani.yaml"
+        opsani --config "$RUNNER_TEMP/opsani.yaml" servo check --wait
+[[- end ]]
+`,
+	CIProviderGitLab: `# Generated by "opsani generate ci --provider gitlab"
+# Requires the OPSANI_OPTIMIZER and OPSANI_TOKEN CI/CD variables[[ if .ServoCheck ]]
+# and an OPSANI_CONFIG file variable containing an Opsani CLI config with a servo attached[[ end ]]
+opsani:
+  image: alpine:3.12
+[[- with .BaseURL ]]
+  variables:
+    OPSANI_BASE_URL: [[ . ]]
+[[- end ]]
+  before_script:
+    - apk add --no-cache curl
+    - curl -sSL [[ .ReleaseURL ]] | tar -xz
+    - install [[ .ReleaseDir ]]/bin/opsani /usr/local/bin/opsani
+    - opsani --version
+  script:
+    - opsani optimizer config --output /tmp/optimizer.json
+    - diff -u [[ .ConfigFile ]] /tmp/optimizer.json
+[[- if .ServoCheck ]]
+    - opsani --config "$OPSANI_CONFIG" servo check --wait
+[[- end ]]
+`,
+}
+
+// CIProviders returns the names of the supported CI providers
+func CIProviders() []string {
+	return []string{CIProviderGitHubActions, CIProviderGitLab}
+}
+
+// ReleaseDir returns the name of the directory in the Linux release archive of the CLI
+func (o CIPipelineOptions) ReleaseDir() string {
+	return fmt.Sprintf("opsani-cli_%s_linux_amd64", o.CLIVersion)
+}
+
+// ReleaseURL returns the download URL of the Linux release archive of the CLI
+func (o CIPipelineOptions) ReleaseURL() string {
+	return fmt.Sprintf("https://github.com/opsani/cli/releases/download/v%s/%s.tar.gz", o.CLIVersion, o.ReleaseDir())
+}
+
+// GenerateCIPipeline renders a pipeline snippet for the provider
+func GenerateCIPipeline(options CIPipelineOptions) ([]byte, error) {
+	text, ok := ciPipelineTemplates[options.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported CI provider %q: must be one of %s", options.Provider, strings.Join(CIProviders(), ", "))
+	}
+	tmpl, err := template.New(options.Provider).Delims("[[", "]]").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, options); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NewGenerateCommand returns a new Opsani CLI generate command instance
+func NewGenerateCommand(baseCmd *BaseCommand) *cobra.Command {
+	generateCmd := &cobra.Command{
+		Use:         "generate",
+		Short:       "Generate integration scaffolding",
+		Annotations: map[string]string{"other": "true"},
+		Args:        cobra.NoArgs,
+	}
+	generateCmd.AddCommand(NewGenerateCICommand(baseCmd))
+	generateCmd.AddCommand(NewGenerateGrafanaDashboardCommand(baseCmd))
+	generateCmd.AddCommand(NewGenerateSecretCommand(baseCmd))
+	generateCmd.AddCommand(NewGenerateOperatorCommand(baseCmd))
+	return generateCmd
+}
+
+// NewGenerateCICommand returns a new `opsani generate ci` command instance
+func NewGenerateCICommand(baseCmd *BaseCommand) *cobra.Command {
+	ciCmd := &cobra.Command{
+		Use:   "ci",
+		Short: "Generate a CI pipeline for managing optimizer config",
+		Long: `Generates a CI pipeline snippet that installs the Opsani CLI, authenticates with
+the optimizer via CI secrets, and fails when the live optimizer config drifts from the
+config committed to the repository. The committed config is produced by:
+
+    opsani optimizer config --output opsani.json
+
+With --servo-check the pipeline also runs "opsani servo check" against the servo
+attached to a config file supplied as a CI secret.`,
+		Example: `  opsani generate ci --provider github-actions --output .github/workflows/opsani.yml
+  opsani generate ci --provider gitlab --servo-check`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: nil,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options := CIPipelineOptions{}
+			options.Provider, _ = cmd.Flags().GetString("provider")
+			options.CLIVersion, _ = cmd.Flags().GetString("cli-version")
+			options.ConfigFile, _ = cmd.Flags().GetString("config-file")
+			options.ServoCheck, _ = cmd.Flags().GetBool("servo-check")
+			options.BaseURL = CustomBaseURL(baseCmd.BaseURL())
+			options.CLIVersion = strings.TrimPrefix(options.CLIVersion, "v")
+			if options.CLIVersion == "" || options.CLIVersion == "dev" {
+				return fmt.Errorf("cannot determine the CLI release to install: specify one with --cli-version")
+			}
+
+			pipeline, err := GenerateCIPipeline(options)
+			if err != nil {
+				return err
+			}
+			if output, _ := cmd.Flags().GetString("output"); output != "" {
+				if err := ioutil.WriteFile(output, pipeline, 0644); err != nil {
+					return err
+				}
+				baseCmd.Printf("Generated %s pipeline in %s\n", options.Provider, output)
+				return nil
+			}
+			_, err = baseCmd.OutOrStdout().Write(pipeline)
+			return err
+		},
+	}
+	ciCmd.Flags().String("provider", CIProviderGitHubActions, fmt.Sprintf("CI provider: {%s}", strings.Join(CIProviders(), "|")))
+	ciCmd.Flags().String("cli-version", Version, "Opsani CLI release installed by the pipeline")
+	ciCmd.Flags().String("config-file", "opsani.json", "Path of the committed optimizer config checked for drift")
+	ciCmd.Flags().Bool("servo-check", false, "Run servo checks in the pipeline")
+	ciCmd.Flags().StringP("output", "o", "", "Write the pipeline to a file instead of stdout")
+	ciCmd.MarkFlagFilename("output", "yml", "yaml")
+	return ciCmd
+}
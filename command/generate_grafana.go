@@ -0,0 +1,224 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// grafanaAPIKeyEnvKey is the environment variable holding the API key used to push dashboards
+const grafanaAPIKeyEnvKey = "GRAFANA_API_KEY"
+
+// GrafanaDashboardOptions describes the dashboard emitted by `opsani generate grafana-dashboard`
+type GrafanaDashboardOptions struct {
+	// Namespace is the namespace of the optimized application
+	Namespace string
+
+	// Datasource is the name of the Grafana datasource for the sidecar Prometheus
+	Datasource string
+
+	// Title defaults to "Opsani: <namespace>"
+	Title string
+}
+
+// grafanaLatencyPercentiles are the latency percentiles charted on the dashboard
+var grafanaLatencyPercentiles = []int{50, 90, 99}
+
+// GenerateGrafanaDashboard renders a Grafana dashboard charting the Envoy metrics scraped by the servo sidecar Prometheus
+// Main and tuning pods are charted as separate series and servo adjustments, which restart the tuning pod, are annotated.
+func GenerateGrafanaDashboard(options GrafanaDashboardOptions) ([]byte, error) {
+	if options.Namespace == "" {
+		return nil, fmt.Errorf("a namespace is required")
+	}
+	if options.Datasource == "" {
+		options.Datasource = "Prometheus"
+	}
+	if options.Title == "" {
+		options.Title = "Opsani: " + options.Namespace
+	}
+	uid := "opsani-" + options.Namespace
+	if len(uid) > 40 {
+		uid = uid[:40]
+	}
+
+	selector := fmt.Sprintf(`kubernetes_namespace="%s"`, options.Namespace)
+	latencyTargets := []map[string]interface{}{}
+	for i, p := range grafanaLatencyPercentiles {
+		latencyTargets = append(latencyTargets, map[string]interface{}{
+			"expr":         fmt.Sprintf(`histogram_quantile(0.%d, sum(rate(envoy_cluster_upstream_rq_time_bucket{%s}[1m])) by (opsani_role, le))`, p, selector),
+			"legendFormat": fmt.Sprintf("p%d {{opsani_role}}", p),
+			"refId":        string(rune('A' + i)),
+		})
+	}
+	panels := []map[string]interface{}{
+		grafanaGraphPanel(1, "Request rate", "reqps", 0, []map[string]interface{}{{
+			"expr":         fmt.Sprintf(`sum(rate(envoy_cluster_upstream_rq_total{%s}[1m])) by (opsani_role)`, selector),
+			"legendFormat": "{{opsani_role}}",
+			"refId":        "A",
+		}}),
+		grafanaGraphPanel(2, "Latency", "ms", 12, latencyTargets),
+	}
+	for _, panel := range panels {
+		panel["datasource"] = options.Datasource
+	}
+
+	dashboard := map[string]interface{}{
+		"uid":           uid,
+		"title":         options.Title,
+		"tags":          []string{"opsani"},
+		"timezone":      "browser",
+		"refresh":       "30s",
+		"schemaVersion": 22,
+		"time":          map[string]string{"from": "now-6h", "to": "now"},
+		"panels":        panels,
+		"annotations": map[string]interface{}{
+			"list": []map[string]interface{}{{
+				"name":        "Adjustments",
+				"datasource":  options.Datasource,
+				"enable":      true,
+				"iconColor":   "rgba(255, 96, 96, 1)",
+				"expr":        fmt.Sprintf(`resets(envoy_server_uptime{%s,opsani_role="tuning"}[1m]) > 0`, selector),
+				"step":        "1m",
+				"titleFormat": "Adjustment applied to tuning pod",
+			}},
+		},
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(dashboard); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// grafanaGraphPanel returns a half-width graph panel at the given horizontal position
+func grafanaGraphPanel(id int, title string, unit string, x int, targets []map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"id":      id,
+		"type":    "graph",
+		"title":   title,
+		"gridPos": map[string]int{"h": 9, "w": 12, "x": x, "y": 0},
+		"targets": targets,
+		"yaxes": []map[string]interface{}{
+			{"format": unit, "min": 0, "show": true},
+			{"format": "short", "show": false},
+		},
+		"lines":     true,
+		"linewidth": 1,
+		"legend":    map[string]bool{"show": true},
+	}
+}
+
+// PushGrafanaDashboard creates or replaces the dashboard via the Grafana HTTP API and returns its URL
+func PushGrafanaDashboard(client *http.Client, grafanaURL string, apiKey string, dashboard []byte) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"dashboard": json.RawMessage(dashboard),
+		"overwrite": true,
+		"message":   "Generated by opsani generate grafana-dashboard",
+	})
+	if err != nil {
+		return "", err
+	}
+	grafanaURL = strings.TrimSuffix(grafanaURL, "/")
+	req, err := http.NewRequest(http.MethodPost, grafanaURL+"/api/dashboards/db", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("grafana returned %s", resp.Status)
+	}
+	result := struct {
+		URL string `json:"url"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid grafana response: %w", err)
+	}
+	return grafanaURL + result.URL, nil
+}
+
+// NewGenerateGrafanaDashboardCommand returns a new `opsani generate grafana-dashboard` command instance
+func NewGenerateGrafanaDashboardCommand(baseCmd *BaseCommand) *cobra.Command {
+	dashboardCmd := &cobra.Command{
+		Use:   "grafana-dashboard",
+		Short: "Generate a Grafana dashboard for an optimized application",
+		Long: `Generates a Grafana dashboard charting the request rate and latency percentiles
+of an optimized application from the Envoy metrics scraped by the servo sidecar
+Prometheus. Main and tuning pods are charted as separate series and adjustments
+made by the servo are annotated.
+
+With --grafana-url the dashboard is pushed to Grafana, authenticating with the API
+key in the ` + grafanaAPIKeyEnvKey + ` environment variable.`,
+		Example: `  opsani generate grafana-dashboard --namespace apps --output dashboard.json
+  GRAFANA_API_KEY=... opsani generate grafana-dashboard --namespace apps --grafana-url https://grafana.example.com`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options := GrafanaDashboardOptions{}
+			options.Namespace, _ = cmd.Flags().GetString("namespace")
+			options.Datasource, _ = cmd.Flags().GetString("datasource")
+			options.Title, _ = cmd.Flags().GetString("title")
+			dashboard, err := GenerateGrafanaDashboard(options)
+			if err != nil {
+				return err
+			}
+
+			if grafanaURL, _ := cmd.Flags().GetString("grafana-url"); grafanaURL != "" {
+				client := &http.Client{Timeout: 10 * time.Second}
+				url, err := PushGrafanaDashboard(client, grafanaURL, os.Getenv(grafanaAPIKeyEnvKey), dashboard)
+				if err != nil {
+					return err
+				}
+				baseCmd.Printf("Pushed dashboard to %s\n", url)
+				return nil
+			}
+			if output, _ := cmd.Flags().GetString("output"); output != "" {
+				if err := ioutil.WriteFile(output, dashboard, 0644); err != nil {
+					return err
+				}
+				baseCmd.Printf("Generated Grafana dashboard in %s\n", output)
+				return nil
+			}
+			_, err = baseCmd.OutOrStdout().Write(dashboard)
+			return err
+		},
+	}
+	dashboardCmd.Flags().StringP("namespace", "n", "", "Namespace of the optimized application")
+	dashboardCmd.Flags().String("datasource", "Prometheus", "Grafana datasource of the servo sidecar Prometheus")
+	dashboardCmd.Flags().String("title", "", "Dashboard title (default is \"Opsani: <namespace>\")")
+	dashboardCmd.Flags().String("grafana-url", "", "Push the dashboard to the Grafana instance at the URL")
+	dashboardCmd.Flags().StringP("output", "o", "", "Write the dashboard to a file instead of stdout")
+	dashboardCmd.MarkFlagRequired("namespace")
+	dashboardCmd.MarkFlagFilename("output", "json")
+	return dashboardCmd
+}
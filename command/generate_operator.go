@@ -0,0 +1,306 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+// ServoDeployment custom resource managed by the servo operator
+const (
+	ServoDeploymentKind     = "ServoDeployment"
+	ServoDeploymentResource = "servodeployments.servo.opsani.com"
+)
+
+// ServoOperatorOptions describes the manifests emitted by `opsani generate operator`
+type ServoOperatorOptions struct {
+	// OperatorNamespace is the namespace the operator is deployed into
+	OperatorNamespace string
+	OperatorImage     string
+
+	// ServoOnly omits the operator manifests for clusters that already run the operator
+	ServoOnly bool
+
+	// Name and Namespace identify the ServoDeployment, which names the servo deployment created by the operator
+	Name      string
+	Namespace string
+	Optimizer string
+	// BaseURL is the API base URL of single-tenant deployments and empty for the Opsani API
+	BaseURL       string
+	ServoImage    string
+	PrometheusURL string
+
+	// TokenSecret and TokenKey identify the Secret generated by `opsani generate secret`
+	TokenSecret string
+	TokenKey    string
+}
+
+var servoOperatorTemplate = `# Generated by "opsani generate operator"
+# The servo reads the API token from Secret "{{ .TokenSecret }}" in namespace "{{ .Namespace }}":
+#   opsani generate secret -n {{ .Namespace }} --name {{ .TokenSecret }} | kubectl apply -f -
+{{- if not .ServoOnly }}
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: {{ .OperatorNamespace }}
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: servodeployments.servo.opsani.com
+spec:
+  group: servo.opsani.com
+  scope: Namespaced
+  names:
+    kind: ServoDeployment
+    listKind: ServoDeploymentList
+    plural: servodeployments
+    singular: servodeployment
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+    subresources:
+      status: {}
+    additionalPrinterColumns:
+    - name: Optimizer
+      type: string
+      jsonPath: .spec.optimizer
+    - name: Replicas
+      type: integer
+      jsonPath: .spec.replicas
+    - name: Ready
+      type: string
+      jsonPath: .status.conditions[?(@.type=="Ready")].status
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            required:
+            - optimizer
+            - tokenSecretRef
+            properties:
+              optimizer:
+                type: string
+              baseURL:
+                type: string
+              image:
+                type: string
+              replicas:
+                type: integer
+                minimum: 0
+                maximum: 1
+              prometheusURL:
+                type: string
+              tokenSecretRef:
+                type: object
+                required:
+                - name
+                properties:
+                  name:
+                    type: string
+                  key:
+                    type: string
+          status:
+            type: object
+            x-kubernetes-preserve-unknown-fields: true
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: servo-operator
+  namespace: {{ .OperatorNamespace }}
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: servo-operator
+rules:
+- apiGroups: ["servo.opsani.com"]
+  resources: ["servodeployments", "servodeployments/status"]
+  verbs: ["get", "list", "watch", "update", "patch"]
+- apiGroups: ["apps"]
+  resources: ["deployments"]
+  verbs: ["get", "list", "watch", "create", "update", "patch", "delete"]
+- apiGroups: [""]
+  resources: ["serviceaccounts", "configmaps"]
+  verbs: ["get", "list", "watch", "create", "update", "patch", "delete"]
+- apiGroups: ["rbac.authorization.k8s.io"]
+  resources: ["roles", "rolebindings"]
+  verbs: ["get", "list", "watch", "create", "update", "patch", "delete", "bind", "escalate"]
+- apiGroups: [""]
+  resources: ["events"]
+  verbs: ["create", "patch"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: servo-operator
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: servo-operator
+subjects:
+- kind: ServiceAccount
+  name: servo-operator
+  namespace: {{ .OperatorNamespace }}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: servo-operator
+  namespace: {{ .OperatorNamespace }}
+  labels:
+    app.kubernetes.io/name: servo-operator
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: servo-operator
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: servo-operator
+    spec:
+      serviceAccountName: servo-operator
+      containers:
+      - name: operator
+        image: {{ .OperatorImage }}
+        args:
+        - --leader-elect
+        resources:
+          limits:
+            cpu: 100m
+            memory: 128Mi
+---
+{{- end }}
+apiVersion: servo.opsani.com/v1alpha1
+kind: ServoDeployment
+metadata:
+  name: {{ .Name }}
+  namespace: {{ .Namespace }}
+  labels:
+    app.kubernetes.io/name: servo
+spec:
+  optimizer: {{ .Optimizer }}
+{{- with .BaseURL }}
+  baseURL: {{ . }}
+{{- end }}
+  image: {{ .ServoImage }}
+  replicas: 1
+{{- with .PrometheusURL }}
+  prometheusURL: {{ . }}
+{{- end }}
+  tokenSecretRef:
+    name: {{ .TokenSecret }}
+    key: {{ .TokenKey }}
+`
+
+// GenerateServoOperatorManifests renders the servo operator manifests and a ServoDeployment
+func GenerateServoOperatorManifests(options ServoOperatorOptions) ([]byte, error) {
+	if options.Optimizer == "" {
+		return nil, fmt.Errorf("no optimizer: run \"opsani init\" or set OPSANI_OPTIMIZER")
+	}
+	tmpl, err := template.New("operator").Parse(servoOperatorTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, options); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NewGenerateOperatorCommand returns a new `opsani generate operator` command instance
+func NewGenerateOperatorCommand(baseCmd *BaseCommand) *cobra.Command {
+	operatorCmd := &cobra.Command{
+		Use:   "operator",
+		Short: "Generate servo operator manifests",
+		Long: `Generates manifests for deploying the Opsani servo operator and a ServoDeployment
+custom resource describing the servo of the active profile. The operator creates and
+reconciles the servo deployment, so the servo lifecycle can be managed by GitOps controllers
+instead of manifests applied by the CLI.
+
+The ServoDeployment reads the API token from the Secret generated by "opsani generate secret".
+Once the operator has created the servo, "opsani servo attach --from-context" recognizes it as
+operator managed.`,
+		Example: `  opsani generate operator | kubectl apply -f -
+  opsani generate operator --servo-only -n apps --output servo.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options := ServoOperatorOptions{
+				Optimizer: baseCmd.Optimizer(),
+				BaseURL:   CustomBaseURL(baseCmd.BaseURL()),
+			}
+			options.OperatorNamespace, _ = cmd.Flags().GetString("operator-namespace")
+			options.OperatorImage, _ = cmd.Flags().GetString("operator-image")
+			options.ServoOnly, _ = cmd.Flags().GetBool("servo-only")
+			options.Name, _ = cmd.Flags().GetString("name")
+			options.Namespace, _ = cmd.Flags().GetString("namespace")
+			options.ServoImage, _ = cmd.Flags().GetString("image")
+			options.TokenSecret, _ = cmd.Flags().GetString("token-secret")
+			options.TokenKey, _ = cmd.Flags().GetString("token-key")
+			if baseCmd.profile != nil {
+				servo := baseCmd.profile.Servo
+				if options.Namespace == "" {
+					options.Namespace = servo.Namespace
+				}
+				if options.Name == "" {
+					options.Name = servo.Deployment
+				}
+				options.PrometheusURL = servo.PrometheusURL
+			}
+			if options.Namespace == "" {
+				options.Namespace = "default"
+			}
+			if options.Name == "" {
+				options.Name = "servo"
+			}
+
+			manifests, err := GenerateServoOperatorManifests(options)
+			if err != nil {
+				return err
+			}
+			if output, _ := cmd.Flags().GetString("output"); output != "" {
+				if err := ioutil.WriteFile(output, manifests, 0644); err != nil {
+					return err
+				}
+				baseCmd.Printf("Generated servo operator manifests in %s\n", output)
+				return nil
+			}
+			_, err = baseCmd.OutOrStdout().Write(manifests)
+			return err
+		},
+	}
+	operatorCmd.Flags().String("operator-namespace", "opsani-system", "Namespace the operator is deployed into")
+	operatorCmd.Flags().String("operator-image", "opsani/servo-operator:latest", "Container image of the operator")
+	operatorCmd.Flags().Bool("servo-only", false, "Emit only the ServoDeployment for clusters already running the operator")
+	operatorCmd.Flags().String("name", "", "Name of the ServoDeployment and servo (defaults to the deployment of the attached servo)")
+	operatorCmd.Flags().StringP("namespace", "n", "", "Namespace of the servo (defaults to the namespace of the attached servo)")
+	operatorCmd.Flags().String("image", "opsani/servox:latest", "Container image of the servo")
+	operatorCmd.Flags().String("token-secret", "servo-token", "Secret containing the API token")
+	operatorCmd.Flags().String("token-key", "token", "Key of the token within the Secret")
+	operatorCmd.Flags().StringP("output", "o", "", "Write the manifests to a file instead of stdout")
+	operatorCmd.MarkFlagFilename("output", "yml", "yaml")
+	return operatorCmd
+}
@@ -0,0 +1,189 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+// ServoTokenSecretOptions describes the Secret emitted by `opsani generate secret`
+type ServoTokenSecretOptions struct {
+	Name      string
+	Namespace string
+	// Key is the key of the token within the Secret
+	Key       string
+	Token     string
+	Optimizer string
+
+	// ExternalSecrets emits an ExternalSecret that reads the token from a secret store instead of a Secret
+	ExternalSecrets bool
+	SecretStore     string
+	RemoteKey       string
+}
+
+var servoTokenSecretTemplate = `apiVersion: v1
+kind: Secret
+metadata:
+  name: {{ .Name }}
+  namespace: {{ .Namespace }}
+  labels:
+    app.kubernetes.io/name: servo
+type: Opaque
+data:
+  {{ .Key }}: {{ base64encode .Token }}
+`
+
+var servoTokenExternalSecretTemplate = `# Store the API token of {{ .Optimizer }} in the secret store at key "{{ .RemoteKey }}"
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: {{ .Name }}
+  namespace: {{ .Namespace }}
+  labels:
+    app.kubernetes.io/name: servo
+spec:
+  refreshInterval: 1h
+  secretStoreRef:
+    kind: SecretStore
+    name: {{ .SecretStore }}
+  target:
+    name: {{ .Name }}
+  data:
+  - secretKey: {{ .Key }}
+    remoteRef:
+      key: {{ .RemoteKey }}
+`
+
+// GenerateServoTokenSecret renders the manifest supplying the API token to the servo
+func GenerateServoTokenSecret(options ServoTokenSecretOptions) ([]byte, error) {
+	text := servoTokenSecretTemplate
+	if options.ExternalSecrets {
+		text = servoTokenExternalSecretTemplate
+	} else if options.Token == "" {
+		return nil, fmt.Errorf("no API token: run \"opsani init\" or set OPSANI_TOKEN")
+	}
+	tmpl, err := template.New("secret").Funcs(template.FuncMap{
+		"base64encode": func(v string) string {
+			return base64.StdEncoding.EncodeToString([]byte(v))
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, options); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sealSecret encrypts a Secret manifest into a SealedSecret with kubeseal
+func sealSecret(ctx context.Context, secret []byte) ([]byte, error) {
+	output := new(bytes.Buffer)
+	cmd := commandContext(ctx, "kubeseal", "--format", "yaml")
+	cmd.Stdin = bytes.NewReader(secret)
+	cmd.Stdout = output
+	cmd.Stderr = os.Stderr
+	if err := runCommand(cmd); err != nil {
+		return nil, fmt.Errorf("failed sealing secret with kubeseal: %w", err)
+	}
+	return output.Bytes(), nil
+}
+
+// NewGenerateSecretCommand returns a new `opsani generate secret` command instance
+func NewGenerateSecretCommand(baseCmd *BaseCommand) *cobra.Command {
+	secretCmd := &cobra.Command{
+		Use:   "secret",
+		Short: "Generate the servo token Secret",
+		Long: `Generates a Kubernetes Secret containing the API token of the active profile
+for use by the servo.
+
+The plain Secret contains the token and must not be committed to source control. For GitOps
+workflows, --sealed-secrets encrypts the Secret with kubeseal into a SealedSecret and
+--external-secrets emits an ExternalSecret that reads the token from a secret store.`,
+		Example: `  opsani generate secret --profile production | kubectl apply -f -
+  opsani generate secret --sealed-secrets --output servo-token.yaml
+  opsani generate secret --external-secrets --secret-store vault`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options := ServoTokenSecretOptions{
+				Token:     baseCmd.AccessToken(),
+				Optimizer: baseCmd.Optimizer(),
+			}
+			options.Name, _ = cmd.Flags().GetString("name")
+			options.Key, _ = cmd.Flags().GetString("key")
+			options.ExternalSecrets, _ = cmd.Flags().GetBool("external-secrets")
+			options.SecretStore, _ = cmd.Flags().GetString("secret-store")
+			options.RemoteKey, _ = cmd.Flags().GetString("remote-key")
+			if options.RemoteKey == "" {
+				options.RemoteKey = "opsani/" + options.Optimizer
+			}
+			options.Namespace, _ = cmd.Flags().GetString("namespace")
+			if options.Namespace == "" && baseCmd.profile != nil {
+				options.Namespace = baseCmd.profile.Servo.Namespace
+			}
+			if options.Namespace == "" {
+				options.Namespace = "default"
+			}
+			sealed, _ := cmd.Flags().GetBool("sealed-secrets")
+			if sealed && options.ExternalSecrets {
+				return fmt.Errorf("--sealed-secrets and --external-secrets cannot be used together")
+			}
+			if options.ExternalSecrets && options.Optimizer == "" && !cmd.Flags().Changed("remote-key") {
+				return fmt.Errorf("no optimizer: run \"opsani init\" or specify the secret store key with --remote-key")
+			}
+
+			manifest, err := GenerateServoTokenSecret(options)
+			if err != nil {
+				return err
+			}
+			if sealed {
+				ctx, cancel := contextWithTimeout(baseCmd.Timeout())
+				defer cancel()
+				if manifest, err = sealSecret(ctx, manifest); err != nil {
+					return err
+				}
+			}
+
+			if output, _ := cmd.Flags().GetString("output"); output != "" {
+				if err := ioutil.WriteFile(output, manifest, 0600); err != nil {
+					return err
+				}
+				baseCmd.Printf("Generated servo token secret in %s\n", output)
+				return nil
+			}
+			_, err = baseCmd.OutOrStdout().Write(manifest)
+			return err
+		},
+	}
+	secretCmd.Flags().String("name", "servo-token", "Name of the Secret")
+	secretCmd.Flags().StringP("namespace", "n", "", "Namespace of the Secret (defaults to the namespace of the attached servo)")
+	secretCmd.Flags().String("key", "token", "Key of the token within the Secret")
+	secretCmd.Flags().Bool("sealed-secrets", false, "Encrypt the Secret into a SealedSecret with kubeseal")
+	secretCmd.Flags().Bool("external-secrets", false, "Emit an ExternalSecret reading the token from a secret store")
+	secretCmd.Flags().String("secret-store", "opsani", "SecretStore read by the ExternalSecret")
+	secretCmd.Flags().String("remote-key", "", "Key of the token in the secret store (defaults to opsani/<optimizer>)")
+	secretCmd.Flags().StringP("output", "o", "", "Write the manifest to a file instead of stdout")
+	secretCmd.MarkFlagFilename("output", "yml", "yaml")
+	return secretCmd
+}
@@ -0,0 +1,238 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+	"sigs.k8s.io/yaml"
+)
+
+type GenerateTestSuite struct {
+	test.Suite
+}
+
+func TestGenerateTestSuite(t *testing.T) {
+	suite.Run(t, new(GenerateTestSuite))
+}
+
+func (s *GenerateTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+}
+
+func (s *GenerateTestSuite) TestGenerateCIGitHubActions() {
+	output, err := s.Execute("generate", "ci", "--provider", "github-actions", "--cli-version", "v0.2.0", "--servo-check")
+	s.Require().NoError(err)
+	s.Require().NoError(yaml.Unmarshal([]byte(output), &map[string]interface{}{}))
+	test.RequireMatchesGolden(s.T(), "ci/github-actions.yml", output)
+}
+
+func (s *GenerateTestSuite) TestGenerateCIGitLab() {
+	output, err := s.Execute("generate", "ci", "--provider", "gitlab", "--cli-version", "0.2.0", "--config-file", "optimizer/opsani.json")
+	s.Require().NoError(err)
+	s.Require().NoError(yaml.Unmarshal([]byte(output), &map[string]interface{}{}))
+	test.RequireMatchesGolden(s.T(), "ci/gitlab.yml", output)
+}
+
+func (s *GenerateTestSuite) TestGenerateCIBaseURL() {
+	output, err := s.Execute("--base-url", "https://api.cell-1.opsani.com/", "generate", "ci", "--provider", "github-actions", "--cli-version", "0.2.0")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "      OPSANI_BASE_URL: https://api.cell-1.opsani.com/\n")
+
+	output, err = s.Execute("--base-url", "https://api.cell-1.opsani.com/", "generate", "ci", "--provider", "gitlab", "--cli-version", "0.2.0")
+	s.Require().NoError(err)
+	var pipeline struct {
+		Opsani struct {
+			Variables map[string]string `yaml:"variables"`
+		} `yaml:"opsani"`
+	}
+	s.Require().NoError(yaml.Unmarshal([]byte(output), &pipeline))
+	s.Require().Equal(map[string]string{"OPSANI_BASE_URL": "https://api.cell-1.opsani.com/"}, pipeline.Opsani.Variables)
+}
+
+func (s *GenerateTestSuite) TestGenerateCIToFile() {
+	dir, err := ioutil.TempDir("", "opsani-ci")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "opsani.yml")
+	output, err := s.Execute("generate", "ci", "--cli-version", "0.2.0", "--output", path)
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Generated github-actions pipeline in "+path)
+	body, err := ioutil.ReadFile(path)
+	s.Require().NoError(err)
+	s.Require().Contains(string(body), "opsani-cli_0.2.0_linux_amd64.tar.gz")
+	s.Require().NotContains(string(body), "servo check")
+}
+
+func (s *GenerateTestSuite) TestGenerateCIUnsupportedProvider() {
+	_, err := s.Execute("generate", "ci", "--provider", "jenkins", "--cli-version", "0.2.0")
+	s.Require().EqualError(err, `unsupported CI provider "jenkins": must be one of github-actions, gitlab`)
+}
+
+func (s *GenerateTestSuite) TestGenerateCIRequiresReleaseVersion() {
+	_, err := s.Execute("generate", "ci", "--cli-version", "dev")
+	s.Require().EqualError(err, "cannot determine the CLI release to install: specify one with --cli-version")
+}
+
+func (s *GenerateTestSuite) TestGenerateGrafanaDashboard() {
+	output, err := s.Execute("generate", "grafana-dashboard", "--namespace", "apps")
+	s.Require().NoError(err)
+	s.Require().NoError(json.Unmarshal([]byte(output), &map[string]interface{}{}))
+	test.RequireMatchesGolden(s.T(), "grafana/dashboard.json", output)
+}
+
+func (s *GenerateTestSuite) TestGenerateGrafanaDashboardRequiresNamespace() {
+	_, err := s.Execute("generate", "grafana-dashboard")
+	s.Require().EqualError(err, `required flag(s) "namespace" not set`)
+}
+
+func (s *GenerateTestSuite) TestGenerateGrafanaDashboardPush() {
+	var received map[string]interface{}
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().Equal("/api/dashboards/db", r.URL.Path)
+		authorization = r.Header.Get("Authorization")
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&received))
+		w.Write([]byte(`{"status": "success", "url": "/d/opsani-apps/opsani-apps"}`))
+	}))
+	defer server.Close()
+	os.Setenv("GRAFANA_API_KEY", "secret")
+	defer os.Unsetenv("GRAFANA_API_KEY")
+
+	output, err := s.Execute("generate", "grafana-dashboard", "--namespace", "apps", "--title", "Web", "--grafana-url", server.URL+"/")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Pushed dashboard to "+server.URL+"/d/opsani-apps/opsani-apps")
+	s.Require().Equal("Bearer secret", authorization)
+	s.Require().Equal(true, received["overwrite"])
+	s.Require().Equal("Web", received["dashboard"].(map[string]interface{})["title"])
+}
+
+func (s *GenerateTestSuite) TestGenerateGrafanaDashboardPushError() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := s.Execute("generate", "grafana-dashboard", "--namespace", "apps", "--grafana-url", server.URL)
+	s.Require().EqualError(err, "grafana returned 401 Unauthorized")
+}
+
+func (s *GenerateTestSuite) TestGenerateSecret() {
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "generate", "secret")
+	s.Require().NoError(err)
+	s.Require().Equal(`apiVersion: v1
+kind: Secret
+metadata:
+  name: servo-token
+  namespace: opsani
+  labels:
+    app.kubernetes.io/name: servo
+type: Opaque
+data:
+  token: MTIzNDU2
+`, output)
+}
+
+func (s *GenerateTestSuite) TestGenerateSecretForProfile() {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]interface{}{
+			{"name": "default", "optimizer": "example.com/app", "token": "123456"},
+			{"name": "production", "optimizer": "example.com/prod", "token": "s3cr3t:with/slashes+"},
+		},
+	})
+	output, err := s.Execute("--config", configFile.Name(), "--profile", "production", "generate", "secret", "-n", "apps", "--name", "prod-token")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "  name: prod-token\n  namespace: apps\n")
+	s.Require().Contains(output, "  token: czNjcjN0OndpdGgvc2xhc2hlcys=\n")
+}
+
+func (s *GenerateTestSuite) TestGenerateSecretSealed() {
+	recorder := test.NewExecRecorder()
+	recorder.Respond("kubeseal", test.ExecResponse{Stdout: "kind: SealedSecret\n"})
+	command.SetCommandContextFunc(recorder.CommandContext)
+	defer command.SetCommandContextFunc(nil)
+
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "generate", "secret", "--sealed-secrets")
+	s.Require().NoError(err)
+	s.Require().Equal("kind: SealedSecret\n", output)
+	s.Require().Equal([][]string{{"kubeseal", "--format", "yaml"}}, recorder.Invocations())
+}
+
+func (s *GenerateTestSuite) TestGenerateSecretExternal() {
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "generate", "secret", "--external-secrets", "--secret-store", "vault")
+	s.Require().NoError(err)
+	s.Require().NotContains(output, "123456")
+	s.Require().NoError(yaml.Unmarshal([]byte(output), &map[string]interface{}{}))
+	s.Require().Equal(`# Store the API token of example.com/app in the secret store at key "opsani/example.com/app"
+apiVersion: external-secrets.io/v1beta1
+kind: ExternalSecret
+metadata:
+  name: servo-token
+  namespace: opsani
+  labels:
+    app.kubernetes.io/name: servo
+spec:
+  refreshInterval: 1h
+  secretStoreRef:
+    kind: SecretStore
+    name: vault
+  target:
+    name: servo-token
+  data:
+  - secretKey: token
+    remoteRef:
+      key: opsani/example.com/app
+`, output)
+}
+
+func (s *GenerateTestSuite) TestGenerateSecretRequiresToken() {
+	_, err := s.Execute("generate", "secret")
+	s.Require().EqualError(err, `no API token: run "opsani init" or set OPSANI_TOKEN`)
+}
+
+func (s *GenerateTestSuite) TestGenerateSecretSealedAndExternal() {
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "generate", "secret", "--sealed-secrets", "--external-secrets")
+	s.Require().EqualError(err, "--sealed-secrets and --external-secrets cannot be used together")
+}
+
+func (s *GenerateTestSuite) TestGenerateOperator() {
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "generate", "operator")
+	s.Require().NoError(err)
+	s.Require().NoError(command.ValidateYAMLDocuments([]byte(output)))
+	test.RequireMatchesGolden(s.T(), "operator/servo-operator.yaml", output)
+}
+
+func (s *GenerateTestSuite) TestGenerateOperatorServoOnly() {
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "--base-url", "https://opsani.example.com", "generate", "operator", "--servo-only", "-n", "apps", "--name", "web")
+	s.Require().NoError(err)
+	s.Require().Len(command.SplitYAMLDocuments([]byte(output)), 1)
+	s.Require().Contains(output, "kind: ServoDeployment\nmetadata:\n  name: web\n  namespace: apps\n")
+	s.Require().Contains(output, "  baseURL: https://opsani.example.com\n")
+}
+
+func (s *GenerateTestSuite) TestGenerateOperatorRequiresOptimizer() {
+	_, err := s.Execute("--config", test.TempConfigFileWithObj(map[string]interface{}{}).Name(), "generate", "operator")
+	s.Require().EqualError(err, `no optimizer: run "opsani init" or set OPSANI_OPTIMIZER`)
+}
@@ -0,0 +1,281 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/spf13/cobra"
+)
+
+// KeyCommandHistoryLimit is the config key for the number of CLI invocations retained in the command history
+const KeyCommandHistoryLimit = "history.commands"
+
+// DefaultCommandHistoryLimit is the number of CLI invocations retained when no limit is configured
+const DefaultCommandHistoryLimit = 500
+
+// CommandHistoryEntry records an invocation of the CLI
+type CommandHistoryEntry struct {
+	Args       []string  `json:"args"`
+	Command    string    `json:"command"`
+	Profile    string    `json:"profile,omitempty"`
+	Time       time.Time `json:"time"`
+	ExitStatus int       `json:"exit_status"`
+}
+
+// CommandLine returns the invocation as a shell command line
+func (e CommandHistoryEntry) CommandLine() string {
+	return "opsani " + shellQuoteArgs(e.Args)
+}
+
+// CommandHistoryFile returns the path of the file recording CLI invocations
+// The history is stored alongside the config file revisions
+func (baseCmd *BaseCommand) CommandHistoryFile() string {
+	configFile := baseCmd.viperCfg.ConfigFileUsed()
+	if configFile == "" {
+		configFile = baseCmd.configFile
+	}
+	if configFile == "" {
+		configFile = baseCmd.DefaultConfigFile()
+	}
+	return filepath.Join(filepath.Dir(configFile), "history", "commands.jsonl")
+}
+
+// CommandHistory returns the recorded CLI invocations, oldest first
+func (baseCmd *BaseCommand) CommandHistory() ([]CommandHistoryEntry, error) {
+	data, err := ioutil.ReadFile(baseCmd.CommandHistoryFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	entries := []CommandHistoryEntry{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		entry := CommandHistoryEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid command history: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// commandHistoryLimit returns the number of CLI invocations to retain
+func (baseCmd *BaseCommand) commandHistoryLimit() int {
+	if baseCmd.viperCfg.IsSet(KeyCommandHistoryLimit) {
+		return baseCmd.viperCfg.GetInt(KeyCommandHistoryLimit)
+	}
+	return DefaultCommandHistoryLimit
+}
+
+// RecordCommand appends an invocation of the CLI to the command history
+// Invocations are only recorded once the config directory exists. Tokens given on the command line are
+// dropped and init and history commands are not recorded so that secrets are never written to the history.
+func (baseCmd *BaseCommand) RecordCommand(executedCmd *cobra.Command, args []string, startedAt time.Time, err error) error {
+	if executedCmd == nil {
+		return nil
+	}
+	path := subCommandPath(baseCmd.rootCobraCommand, executedCmd)
+	if path == "" || strings.HasPrefix(path, "init") || strings.HasPrefix(path, "history") {
+		return nil
+	}
+	limit := baseCmd.commandHistoryLimit()
+	if limit <= 0 {
+		return nil
+	}
+	historyFile := baseCmd.CommandHistoryFile()
+	if _, err := os.Stat(filepath.Dir(filepath.Dir(historyFile))); err != nil {
+		return nil
+	}
+
+	entry := CommandHistoryEntry{
+		Args:    withoutSecretFlags(args),
+		Command: path,
+		Time:    startedAt.UTC(),
+	}
+	if baseCmd.profile != nil {
+		entry.Profile = baseCmd.profile.Name
+	}
+	if errors.Is(err, terminal.InterruptErr) {
+		entry.ExitStatus = 130
+	} else if err != nil {
+		entry.ExitStatus = 1
+	}
+
+	entries, historyErr := baseCmd.CommandHistory()
+	if historyErr != nil {
+		// Start over rather than fail every command on a corrupt history
+		entries = nil
+	}
+	entries = append(entries, entry)
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	var buffer bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buffer.Write(append(line, '\n'))
+	}
+	if err := os.MkdirAll(filepath.Dir(historyFile), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(historyFile, buffer.Bytes(), 0600)
+}
+
+// historySecretFlags are the flags whose values are credentials and are never recorded
+var historySecretFlags = []string{KeyToken, KeyMonitorWebhook}
+
+// withoutSecretFlags returns the arguments with any secret flags and their values removed
+func withoutSecretFlags(args []string) []string {
+	filtered := []string{}
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			return append(filtered, args[i:]...)
+		}
+		if secret, separateValue := secretFlag(args[i]); secret {
+			if separateValue {
+				i++
+			}
+			continue
+		}
+		filtered = append(filtered, args[i])
+	}
+	return filtered
+}
+
+// secretFlag reports whether the argument is a secret flag and whether its value is the next argument
+func secretFlag(arg string) (secret bool, separateValue bool) {
+	for _, key := range historySecretFlags {
+		if arg == "--"+key {
+			return true, true
+		} else if strings.HasPrefix(arg, "--"+key+"=") {
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// shellQuoteArgs joins the arguments into a command line, quoting arguments as necessary for a POSIX shell
+func shellQuoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_=./:,@%+", r))
+		}) == -1 {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// NewHistoryCommand returns a new `opsani history` command instance
+func NewHistoryCommand(baseCmd *BaseCommand) *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "List recent CLI invocations",
+		Long: `Lists recent invocations of the CLI along with the profile they ran against,
+when they ran, and their exit status. Commands can be repeated by number with
+` + "`opsani history rerun N`" + `.
+
+Tokens given on the command line are never recorded. Set history.commands in the
+config file to change the number of invocations retained (0 disables the history).`,
+		Annotations:       map[string]string{"other": "true"},
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: baseCmd.InitConfigRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := OutputFormat(cmd, TabularOutputFormats...)
+			if err != nil {
+				return err
+			}
+			entries, err := baseCmd.CommandHistory()
+			if err != nil {
+				return err
+			}
+			first := 0
+			if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 && len(entries) > limit {
+				first = len(entries) - limit
+			}
+			if output == OutputJSON {
+				return baseCmd.PrettyPrintJSONObject(entries[first:])
+			}
+			table := Table{Headers: []string{"#", "TIME", "PROFILE", "STATUS", "COMMAND"}}
+			for i, entry := range entries[first:] {
+				table.Rows = append(table.Rows, []string{
+					strconv.Itoa(first + i + 1),
+					entry.Time.Local().Format("2006-01-02 15:04:05"),
+					entry.Profile,
+					strconv.Itoa(entry.ExitStatus),
+					entry.CommandLine(),
+				})
+			}
+			return baseCmd.RenderTable(output, table)
+		},
+	}
+	historyCmd.Flags().IntP("limit", "n", 20, "Number of recent invocations to list (0 lists all)")
+	AddOutputFlag(historyCmd, TabularOutputFormats...)
+	historyCmd.AddCommand(NewHistoryRerunCommand(baseCmd))
+	return historyCmd
+}
+
+// NewHistoryRerunCommand returns a new `opsani history rerun` command instance
+func NewHistoryRerunCommand(baseCmd *BaseCommand) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rerun N",
+		Short: "Run a command from the history again",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid history number %q", args[0])
+			}
+			entries, err := baseCmd.CommandHistory()
+			if err != nil {
+				return err
+			}
+			if n < 1 || n > len(entries) {
+				return fmt.Errorf("no command numbered %d in history", n)
+			}
+			entry := entries[n-1]
+			fmt.Fprintln(baseCmd.ErrOrStderr(), entry.CommandLine())
+
+			rerunArgs, err := baseCmd.ExpandAliases(entry.Args)
+			if err != nil {
+				return err
+			}
+			return baseCmd.executeNewRootCommand(rerunArgs)
+		},
+	}
+}
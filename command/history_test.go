@@ -0,0 +1,135 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v2"
+)
+
+type HistoryTestSuite struct {
+	test.Suite
+	dir        string
+	configFile string
+}
+
+func TestHistoryTestSuite(t *testing.T) {
+	suite.Run(t, new(HistoryTestSuite))
+}
+
+func (s *HistoryTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+
+	dir, err := ioutil.TempDir("", "opsani-history")
+	s.Require().NoError(err)
+	s.dir = dir
+	s.configFile = filepath.Join(dir, "config.yaml")
+	data, _ := yaml.Marshal(map[string]interface{}{
+		"profiles": []map[string]string{
+			{"name": "dev", "optimizer": "example.com/app", "token": "123456"},
+		},
+	})
+	s.Require().NoError(ioutil.WriteFile(s.configFile, data, 0600))
+}
+
+func (s *HistoryTestSuite) TearDownTest() {
+	os.RemoveAll(s.dir)
+}
+
+// record executes the command and records it in the history as the CLI entry point would
+func (s *HistoryTestSuite) record(args ...string) {
+	rootCmd := command.NewRootCommand()
+	s.SetCommand(rootCmd)
+	executedCmd, _, err := s.ExecuteC(args...)
+	s.Require().NoError(rootCmd.RecordCommand(executedCmd, args, time.Now(), err))
+	s.SetCommand(command.NewRootCommand())
+}
+
+func (s *HistoryTestSuite) TestRecordCommand() {
+	s.record("--config", s.configFile, "--token", "secret-token", "profile", "list")
+	s.record("--config", s.configFile, "servo", "list", "--no-such-flag")
+	s.record("--config", s.configFile, "history")
+	s.record("--config", s.configFile, "monitor", "status", "--webhook", "https://hooks.example.com/a", "--webhook=https://hooks.example.com/b")
+
+	rootCmd := command.NewRootCommand()
+	s.SetCommand(rootCmd)
+	_, err := s.Execute("--config", s.configFile, "history", "--help")
+	s.Require().NoError(err)
+	entries, err := rootCmd.CommandHistory()
+	s.Require().NoError(err)
+	s.Require().Len(entries, 3)
+	s.Require().Equal([]string{"--config", s.configFile, "profile", "list"}, entries[0].Args)
+	s.Require().Equal("profile list", entries[0].Command)
+	s.Require().Equal("dev", entries[0].Profile)
+	s.Require().Equal(0, entries[0].ExitStatus)
+	s.Require().Equal("servo list", entries[1].Command)
+	s.Require().Equal(1, entries[1].ExitStatus)
+	s.Require().Equal([]string{"--config", s.configFile, "monitor", "status"}, entries[2].Args)
+}
+
+func (s *HistoryTestSuite) TestRecordCommandRespectsLimit() {
+	data, err := ioutil.ReadFile(s.configFile)
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(s.configFile, append(data, []byte("history:\n  commands: 2\n")...), 0600))
+	for i := 0; i < 3; i++ {
+		s.record("--config", s.configFile, "profile", "list")
+	}
+	s.record("--config", s.configFile, "alias", "list")
+
+	output, err := s.Execute("--config", s.configFile, "history", "--output", "json")
+	s.Require().NoError(err)
+	s.Require().Contains(output, `"command": "profile list"`)
+	s.Require().Contains(output, `"command": "alias list"`)
+
+	rootCmd := command.NewRootCommand()
+	s.SetCommand(rootCmd)
+	_, err = s.Execute("--config", s.configFile, "history", "--help")
+	s.Require().NoError(err)
+	entries, err := rootCmd.CommandHistory()
+	s.Require().NoError(err)
+	s.Require().Len(entries, 2)
+}
+
+func (s *HistoryTestSuite) TestListHistory() {
+	s.record("--config", s.configFile, "profile", "list")
+	s.record("--config", s.configFile, "optimizer", "config", "patch", "--data", `{"adjustment": {"mode": "fast"}}`)
+
+	output, err := s.Execute("--config", s.configFile, "history", "--limit", "1", "--output", "csv")
+	s.Require().NoError(err)
+	s.Require().Regexp(`^#,TIME,PROFILE,STATUS,COMMAND\n2,[0-9-]+ [0-9:]+,[a-z]*,1,`, output)
+	s.Require().Contains(output, `opsani --config `+s.configFile+` optimizer config patch --data '{""adjustment"": {""mode"": ""fast""}}'`)
+}
+
+func (s *HistoryTestSuite) TestRerun() {
+	s.record("--config", s.configFile, "profile", "list")
+
+	output, err := s.Execute("--config", s.configFile, "history", "rerun", "1")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "opsani --config "+s.configFile+" profile list\n")
+	s.Require().Contains(output, "dev\texample.com/app")
+}
+
+func (s *HistoryTestSuite) TestRerunUnknownNumber() {
+	_, err := s.Execute("--config", s.configFile, "history", "rerun", "3")
+	s.Require().EqualError(err, "no command numbered 3 in history")
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyLocale is the configuration key for selecting the locale of user-facing messages
+const KeyLocale = "ui.locale"
+
+// DefaultLocale is the locale used when no other locale is configured or available
+const DefaultLocale = "en"
+
+// messageCatalogs maps language codes to catalogs of user-facing messages
+var messageCatalogs = map[string]map[string]string{
+	"en": messagesEn,
+	"es": messagesEs,
+}
+
+// Locale returns the language code for user-facing messages
+// The `ui.locale` setting takes precedence over the LC_ALL, LC_MESSAGES, and LANG environment variables
+func (baseCmd *BaseCommand) Locale() string {
+	candidates := []string{}
+	if baseCmd.viperCfg != nil {
+		candidates = append(candidates, baseCmd.viperCfg.GetString(KeyLocale))
+	}
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		candidates = append(candidates, os.Getenv(env))
+	}
+	for _, candidate := range candidates {
+		if locale := parseLocale(candidate); locale != "" {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// parseLocale extracts the language code from a POSIX locale string such as "es_ES.UTF-8"
+func parseLocale(value string) string {
+	value = strings.TrimSpace(value)
+	if i := strings.IndexAny(value, ".@"); i != -1 {
+		value = value[:i]
+	}
+	if i := strings.IndexAny(value, "_-"); i != -1 {
+		value = value[:i]
+	}
+	value = strings.ToLower(value)
+	if value == "c" || value == "posix" {
+		return DefaultLocale
+	}
+	if _, ok := messageCatalogs[value]; !ok {
+		return ""
+	}
+	return value
+}
+
+// T returns the localized message for the key, formatted with any args
+// Messages missing from the active locale fall back to English and then to the key itself
+func (baseCmd *BaseCommand) T(key string, args ...interface{}) string {
+	message, ok := messageCatalogs[baseCmd.Locale()][key]
+	if !ok {
+		if message, ok = messagesEn[key]; !ok {
+			message = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
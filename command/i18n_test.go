@@ -0,0 +1,105 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+)
+
+func setLocaleEnv(t *testing.T, lang string) {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		env := env
+		value, ok := os.LookupEnv(env)
+		os.Unsetenv(env)
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(env, value)
+			} else {
+				os.Unsetenv(env)
+			}
+		})
+	}
+	os.Setenv("LANG", lang)
+}
+
+func TestSetLocaleEnvRestoresEnvironment(t *testing.T) {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value, ok := os.LookupEnv(env); ok {
+			defer os.Setenv(env, value)
+		} else {
+			defer os.Unsetenv(env)
+		}
+	}
+	os.Setenv("LC_ALL", "C")
+	os.Unsetenv("LC_MESSAGES")
+	os.Setenv("LANG", "en_US.UTF-8")
+
+	t.Run("es", func(t *testing.T) {
+		setLocaleEnv(t, "es_ES.UTF-8")
+	})
+	require.Equal(t, "C", os.Getenv("LC_ALL"))
+	_, ok := os.LookupEnv("LC_MESSAGES")
+	require.False(t, ok)
+	require.Equal(t, "en_US.UTF-8", os.Getenv("LANG"))
+}
+
+func TestLocaleDefaultsToEnglish(t *testing.T) {
+	setLocaleEnv(t, "")
+	baseCmd := command.NewRootCommand()
+	require.Equal(t, "en", baseCmd.Locale())
+	require.Equal(t, "Ready to get started?", baseCmd.T("prompt.ready"))
+}
+
+func TestLocaleFromLangEnvironment(t *testing.T) {
+	setLocaleEnv(t, "es_ES.UTF-8")
+	baseCmd := command.NewRootCommand()
+	require.Equal(t, "es", baseCmd.Locale())
+	require.Equal(t, "¿Listo para comenzar?", baseCmd.T("prompt.ready"))
+}
+
+func TestLocaleUnsupportedFallsBackToEnglish(t *testing.T) {
+	setLocaleEnv(t, "xx_XX.UTF-8")
+	baseCmd := command.NewRootCommand()
+	require.Equal(t, "en", baseCmd.Locale())
+}
+
+func TestLocaleFromConfigOverridesEnvironment(t *testing.T) {
+	setLocaleEnv(t, "en_US.UTF-8")
+	baseCmd := command.NewRootCommand()
+	baseCmd.Viper().Set(command.KeyLocale, "es")
+	require.Equal(t, "es", baseCmd.Locale())
+}
+
+func TestTranslateFallsBackToEnglishForMissingKeys(t *testing.T) {
+	setLocaleEnv(t, "es")
+	baseCmd := command.NewRootCommand()
+	require.Equal(t, "minikube profile opsani-ignite created.", baseCmd.T("ignite.task.create.success", "opsani-ignite"))
+}
+
+func TestTranslateFormatsArgs(t *testing.T) {
+	setLocaleEnv(t, "es")
+	baseCmd := command.NewRootCommand()
+	require.Equal(t, "Docker 19.03 encontrado.", baseCmd.T("ignite.task.docker.success", "19.03"))
+}
+
+func TestTranslateUnknownKey(t *testing.T) {
+	setLocaleEnv(t, "")
+	baseCmd := command.NewRootCommand()
+	require.Equal(t, "no.such.key", baseCmd.T("no.such.key"))
+}
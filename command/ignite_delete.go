@@ -0,0 +1,149 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// KeyResetOptimizer is the flag for resetting the demo optimizer when deleting an Ignite cluster
+const KeyResetOptimizer = "reset-optimizer"
+
+// KeyKeepManifests is the flag for keeping the manifests written by Ignite when deleting an Ignite cluster
+const KeyKeepManifests = "keep-manifests"
+
+// igniteServo is the servo attached to the profile by Ignite
+var igniteServo = Servo{
+	Type:       "kubernetes",
+	Namespace:  "default",
+	Deployment: "servo",
+}
+
+// NewIgniteDeleteCommand returns a new `opsani ignite delete` command instance
+func NewIgniteDeleteCommand(vitalCommand *vitalCommand) *cobra.Command {
+	cobraCmd := &cobra.Command{
+		Use:   "delete",
+		Short: "Delete an Ignite cluster",
+		Long: `Deletes the Ignite cluster and returns the environment to a clean slate.
+
+The minikube profile is deleted, the servo attached by Ignite is detached from the profile, and the
+manifests written by Ignite are removed. Pass --reset-optimizer to also reset the demo optimizer.`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: nil,
+		RunE:              vitalCommand.RunIgniteDelete,
+	}
+	cobraCmd.Flags().Bool(KeyResetOptimizer, false, "Reset the demo optimizer so that the next demo starts from scratch")
+	cobraCmd.Flags().Bool(KeyKeepManifests, false, "Keep the manifests written by Ignite")
+	return cobraCmd
+}
+
+// RunIgniteDelete deletes the Ignite cluster along with the artifacts of the demo
+func (vitalCommand *vitalCommand) RunIgniteDelete(cobraCmd *cobra.Command, args []string) error {
+	bold := color.New(color.Bold).SprintFunc()
+	// The manifests directory is recorded in the state cleared with the cluster
+	manifestsDir := vitalCommand.igniteManifestsDir()
+	err := vitalCommand.RunTask(Task{
+		Description: vitalCommand.T("ignite.task.delete.description"),
+		Success:     vitalCommand.T("ignite.task.delete.success", bold(igniteProfile)),
+		Failure:     vitalCommand.T("ignite.task.delete.failure"),
+		RunW: func(w io.Writer) error {
+			ctx, cancel := vitalCommand.ProvisionContext()
+			defer cancel()
+			cmd := commandContext(ctx, "minikube", "delete", "-p", igniteProfile)
+			cmd.Stdout = w
+			cmd.Stderr = w
+			cmd.Stdin = os.Stdin
+			if err := runCommand(cmd); err != nil {
+				return err
+			}
+			return vitalCommand.SaveIgniteState(nil)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if detached, err := vitalCommand.detachIgniteServo(); err != nil {
+		return err
+	} else if detached {
+		vitalCommand.Println(vitalCommand.T("ignite.delete.detached", bold(vitalCommand.profile.Name)))
+	}
+
+	if keep, _ := cobraCmd.Flags().GetBool(KeyKeepManifests); !keep {
+		removed, err := removeIgniteManifests(manifestsDir)
+		if err != nil {
+			return err
+		}
+		if removed > 0 {
+			vitalCommand.Println(vitalCommand.T("ignite.delete.manifests", removed, bold(manifestsDir)))
+		}
+	}
+
+	if reset, _ := cobraCmd.Flags().GetBool(KeyResetOptimizer); reset {
+		return vitalCommand.RunTaskWithSpinner(Task{
+			Description: vitalCommand.T("ignite.task.reset.description"),
+			Success:     vitalCommand.T("ignite.task.reset.success"),
+			Failure:     vitalCommand.T("ignite.task.reset.failure"),
+			Run: func() error {
+				_, err := vitalCommand.NewAPIClient().RestartApp()
+				return err
+			},
+		})
+	}
+	return nil
+}
+
+// detachIgniteServo removes the servo attached by Ignite from the selected profile
+// Servos attached by other means are left in place
+func (vitalCommand *vitalCommand) detachIgniteServo() (bool, error) {
+	if vitalCommand.profile == nil {
+		return false, nil
+	}
+	registry, err := NewProfileRegistry(vitalCommand.viperCfg)
+	if err != nil {
+		return false, err
+	}
+	profile := registry.ProfileNamed(vitalCommand.profile.Name)
+	if profile == nil || profile.Servo != igniteServo {
+		return false, nil
+	}
+	profile.Servo = Servo{}
+	vitalCommand.profile.Servo = Servo{}
+	return true, registry.Save()
+}
+
+// removeIgniteManifests deletes the manifests written by Ignite from dir and returns the number removed
+// The directory is removed once empty so that files written by other tools are preserved
+func removeIgniteManifests(dir string) (int, error) {
+	removed := 0
+	for path := range igniteManifestChecksums {
+		err := os.Remove(filepath.Join(dir, filepath.Base(path)))
+		if err == nil {
+			removed++
+		} else if !os.IsNotExist(err) {
+			return removed, err
+		}
+	}
+	if entries, err := ioutil.ReadDir(dir); err == nil && len(entries) == 0 {
+		os.Remove(dir)
+	}
+	return removed, nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/fatih/color"
+	"github.com/markbates/pkger"
+	"github.com/spf13/cobra"
+)
+
+// KeyServoVersion is the flag for pinning the servo image tag installed into the Ignite cluster
+const KeyServoVersion = "servo-version"
+
+// Servo image installed by the embedded Ignite manifests
+const (
+	IgniteServoImage     = "opsani/servo-k8s-prom-vegeta"
+	DefaultServoVersion  = "latest"
+	igniteServoContainer = "main"
+)
+
+// servoVersionPlaceholder is substituted with the pinned servo version when rendering manifests
+const servoVersionPlaceholder = "{{ .ServoVersion }}"
+
+var servoVersionPattern = regexp.MustCompile(`^(latest|v?\d+\.\d+\.\d+([-+.][0-9A-Za-z.-]+)?)$`)
+
+// igniteManifestChecksums pins the SHA-256 checksum of each embedded Ignite manifest template
+// Update the checksums when changing the manifests
+var igniteManifestChecksums = map[string]string{
+	"/demo/manifests/prometheus-operator_bundle.yaml": "63844f35fda96468010e015fe3f4915b9cf5934ee83625c2c3c49b692b3f32ba",
+	"/demo/manifests/prometheus.yaml":                 "4e3ec60dd89d842ac1167c8b60954d7135e1fbeaa55723ed7218545798082021",
+	"/demo/manifests/servo/servo-configmap.yaml":      "bc72ebb9f20ae9dbc3bf51609925be8a24e5ade51c06ef89a0c4e9baf76b3ba2",
+	"/demo/manifests/servo/servo-deployment.yaml":     "364364506c903944c3329c977f9dd67e2d8665b5d06c695c6aa37730b1c77505",
+	"/demo/manifests/servo/servo-rbac.yaml":           "098a03735bf41adaee8bad089f71567326e3c39e817b7fac9dbb7456b00fbad5",
+	"/demo/manifests/servo/servo-secret.yaml":         "281d2489dd5933ecb4ad92ae142c2c668f14bbd1c8b43ec2a679ca68d94c6d39",
+	"/demo/manifests/web/web-deployment.yaml":         "c54bbe5db463ab0394303e96edf55dae2c0d4aad748ad0ef2687332937c80fbc",
+	"/demo/manifests/web/web-service.yaml":            "5ed5d0cb16b494a305e9644f5b3d26cb2f96e8e6a9fe3049efa27053c1383d14",
+}
+
+// DefaultIgnitePrometheusURL is the endpoint of the Prometheus deployed into the Ignite cluster
+const DefaultIgnitePrometheusURL = "http://prometheus-operated.default.svc.cluster.local:9090"
+
+// igniteManifestData is the template context for rendering the embedded manifests
+type igniteManifestData struct {
+	Profile
+	ServoVersion string
+}
+
+// PrometheusURL returns the Prometheus endpoint of the profile servo, defaulting to the Prometheus deployed by Ignite
+func (d igniteManifestData) PrometheusURL() string {
+	if d.Servo.PrometheusURL != "" {
+		return d.Servo.PrometheusURL
+	}
+	return DefaultIgnitePrometheusURL
+}
+
+// ServoURL returns the servo endpoint of the optimizer for profiles of single-tenant deployments
+// An empty string is returned for the Opsani API, which the servo connects to by default
+func (d igniteManifestData) ServoURL() string {
+	baseURL := CustomBaseURL(d.BaseURL)
+	if baseURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/accounts/%s/applications/%s/servo", strings.TrimRight(baseURL, "/"), d.Organization(), d.AppName())
+}
+
+// VerifyIgniteManifest checks the manifest template at the embedded path against its pinned checksum
+func VerifyIgniteManifest(path string, data []byte) error {
+	pinned, ok := igniteManifestChecksums[path]
+	if !ok {
+		return fmt.Errorf("embedded manifest %q has no pinned checksum", path)
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != pinned {
+		return fmt.Errorf("embedded manifest %q failed checksum verification: expected sha256 %s, got %s", path, pinned, actual)
+	}
+	return nil
+}
+
+// RenderIgniteManifest verifies the manifest template at the embedded path and renders it for the profile
+// Optimizer, token, and API endpoints are all taken from the profile so that Ignite can target any backend
+func RenderIgniteManifest(path string, profile Profile, servoVersion string) ([]byte, error) {
+	manifestTemplate, err := readEmbeddedFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyIgniteManifest(embeddedPath(path), manifestTemplate); err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(template.FuncMap{
+		"base64encode": func(v string) string {
+			return base64.StdEncoding.EncodeToString([]byte(v))
+		},
+	}).Parse(string(manifestTemplate))
+	if err != nil {
+		return nil, err
+	}
+	rendered := new(bytes.Buffer)
+	if err := tmpl.Execute(rendered, igniteManifestData{Profile: profile, ServoVersion: servoVersion}); err != nil {
+		return nil, fmt.Errorf("failed rendering manifest %q: %w", embeddedPath(path), err)
+	}
+	return rendered.Bytes(), nil
+}
+
+// VerifyIgniteManifests checks all embedded manifest templates against their pinned checksums
+func VerifyIgniteManifests() error {
+	return pkger.Walk("/demo/manifests", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		data, err := readEmbeddedFile(path)
+		if err != nil {
+			return err
+		}
+		return VerifyIgniteManifest(embeddedPath(path), data)
+	})
+}
+
+// ValidateServoVersion checks that the version is a valid servo image tag
+func ValidateServoVersion(version string) error {
+	if !servoVersionPattern.MatchString(version) {
+		return fmt.Errorf("invalid servo version %q: must be %q or a release version such as 0.9.1", version, DefaultServoVersion)
+	}
+	return nil
+}
+
+// AddServoVersionFlag registers the --servo-version flag on the given command
+func AddServoVersionFlag(cmd *cobra.Command) {
+	cmd.Flags().String(KeyServoVersion, "", fmt.Sprintf("Servo image tag to install (default is the pinned version or %q)", DefaultServoVersion))
+}
+
+// servoVersion returns the servo version from the flag, falling back to the version pinned in the Ignite state
+func (vitalCommand *vitalCommand) servoVersion(cmd *cobra.Command) (string, error) {
+	if flag := cmd.Flags().Lookup(KeyServoVersion); flag != nil && flag.Changed {
+		version := flag.Value.String()
+		return version, ValidateServoVersion(version)
+	}
+	if state, err := vitalCommand.LoadIgniteState(); err != nil {
+		return "", err
+	} else if state != nil && state.ServoVersion != "" {
+		return state.ServoVersion, nil
+	}
+	return DefaultServoVersion, nil
+}
+
+// rollServo updates the servo deployment in the Ignite cluster to the version and records the pin
+func (vitalCommand *vitalCommand) rollServo(w io.Writer, version string) error {
+	kubernetes, err := kubernetesClientFactory("", igniteProfile)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := vitalCommand.ProvisionContext()
+	defer cancel()
+	image := fmt.Sprintf("%s:%s", IgniteServoImage, version)
+	if err := kubernetes.SetDeploymentImage(ctx, "default", "servo", igniteServoContainer, image); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "deployment.apps/servo image updated to %s\n", image)
+	err = kubernetes.WaitForDeploymentAvailable(ctx, "default", "servo", func(status string) {
+		fmt.Fprintf(w, "Waiting for deployment \"servo\" rollout to finish: %s\n", status)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, `deployment "servo" successfully rolled out`)
+
+	state, err := vitalCommand.LoadIgniteState()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = &IgniteState{Profile: igniteProfile}
+	}
+	state.ServoVersion = version
+	return vitalCommand.SaveIgniteState(state)
+}
+
+// NewIgniteUpgradeCommand returns a new `opsani ignite upgrade` command instance
+func NewIgniteUpgradeCommand(vitalCommand *vitalCommand) *cobra.Command {
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade the servo in an Ignite cluster",
+		Long: `Rolls the servo deployed in the Ignite cluster forward to a new image version and pins it
+for subsequent Ignite commands.
+
+The servo is upgraded to "latest" unless a version is given with --servo-version.`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: nil,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version := DefaultServoVersion
+			if flag := cmd.Flags().Lookup(KeyServoVersion); flag.Changed {
+				version = flag.Value.String()
+			}
+			if err := ValidateServoVersion(version); err != nil {
+				return err
+			}
+			bold := color.New(color.Bold).SprintFunc()
+			return vitalCommand.RunTask(Task{
+				Description: vitalCommand.T("ignite.task.upgrade.description", bold(version)),
+				Success:     vitalCommand.T("ignite.task.upgrade.success", bold(IgniteServoImage+":"+version)),
+				Failure:     vitalCommand.T("ignite.task.upgrade.failure"),
+				RunW: func(w io.Writer) error {
+					return vitalCommand.rollServo(w, version)
+				},
+			})
+		},
+	}
+	AddServoVersionFlag(upgradeCmd)
+	return upgradeCmd
+}
+
+// readEmbeddedFile reads a file packaged with pkger
+func readEmbeddedFile(path string) ([]byte, error) {
+	f, err := pkger.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// embeddedPath returns the module relative path of a file packaged with pkger
+// Paths walked by pkger are qualified with the module (e.g. github.com/opsani/cli:/demo/manifests)
+func embeddedPath(path string) string {
+	if i := strings.Index(path, ":"); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
@@ -0,0 +1,304 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/markbates/pkger"
+	"github.com/spf13/cobra"
+)
+
+// Image loaders for side-loading images into the cluster
+const (
+	ImageLoaderMinikube = "minikube"
+	ImageLoaderKind     = "kind"
+)
+
+// manifestImagePattern matches container images and the images passed to the Prometheus operator by argument
+var manifestImagePattern = regexp.MustCompile(`(?m)(?:image:\s*|-image=|-reloader=)["']?([^\s"']+)`)
+
+// NewIgnitePreloadCommand returns a new `opsani ignite preload` command instance
+func NewIgnitePreloadCommand(vitalCommand *vitalCommand) *cobra.Command {
+	preloadCmd := &cobra.Command{
+		Use:   "preload",
+		Short: "Preload images into an Ignite cluster",
+		Long: `Pulls the servo, Prometheus, and demo application images and side-loads them into the
+Ignite cluster so that the demo can run on restricted networks.
+
+Additional images can be listed one per line in a file given by --images-file. When
+--registry-mirror is set images are pulled from the mirror and tagged with their original names.
+
+Images are pulled for the platform of the host (e.g. linux/arm64 on Apple Silicon) unless
+--platform is given. Images that are only published for linux/amd64 are pulled for that
+platform instead and will run emulated.
+
+Images are pulled with Docker, Podman, or nerdctl (containerd) as selected by --container-runtime
+or the ignite.container_runtime setting, or detected from the available runtime sockets.`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: nil,
+		RunE:              vitalCommand.RunIgnitePreload,
+	}
+	preloadCmd.Flags().String("images-file", "", "File listing additional images to preload, one per line")
+	preloadCmd.Flags().String("registry-mirror", "", "Registry host (and optional path) to pull images through (e.g. registry.example.com/mirror)")
+	preloadCmd.Flags().String("loader", ImageLoaderMinikube, "Tool for loading images into the cluster: {minikube|kind}")
+	preloadCmd.Flags().Bool("list", false, "List the images without preloading them")
+	preloadCmd.Flags().String(KeyPlatform, "", "Platform of the pulled images (default is the host platform)")
+	AddContainerRuntimeFlag(preloadCmd)
+	AddServoVersionFlag(preloadCmd)
+	return preloadCmd
+}
+
+// RunIgnitePreload pulls and loads the images needed by the Ignite demo
+func (vitalCommand *vitalCommand) RunIgnitePreload(cmd *cobra.Command, args []string) error {
+	loader, _ := cmd.Flags().GetString("loader")
+	if loader != ImageLoaderMinikube && loader != ImageLoaderKind {
+		return fmt.Errorf("invalid loader %q: must be %q or %q", loader, ImageLoaderMinikube, ImageLoaderKind)
+	}
+	servoVersion, err := vitalCommand.servoVersion(cmd)
+	if err != nil {
+		return err
+	}
+	images, err := IgniteManifestImages(servoVersion)
+	if err != nil {
+		return err
+	}
+	if imagesFile, _ := cmd.Flags().GetString("images-file"); imagesFile != "" {
+		f, err := os.Open(imagesFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		listed, err := ReadImageList(f)
+		if err != nil {
+			return fmt.Errorf("failed reading images file %s: %w", imagesFile, err)
+		}
+		images = uniqueSortedStrings(append(images, listed...))
+	}
+	if list, _ := cmd.Flags().GetBool("list"); list {
+		for _, image := range images {
+			vitalCommand.Println(image)
+		}
+		return nil
+	}
+
+	containerRuntime, err := vitalCommand.ContainerRuntime(cmd)
+	if err != nil {
+		return err
+	}
+	platform, _ := cmd.Flags().GetString(KeyPlatform)
+	if platform == "" {
+		platform = hostPlatform()
+	} else if err := ValidatePlatform(platform); err != nil {
+		return err
+	}
+
+	mirror, _ := cmd.Flags().GetString("registry-mirror")
+	bold := color.New(color.Bold).SprintFunc()
+	emulated := []string{}
+	for _, image := range images {
+		image := image
+		err := vitalCommand.RunTask(Task{
+			Description: vitalCommand.T("ignite.task.preload.description", bold(image)),
+			Success:     vitalCommand.T("ignite.task.preload.success", bold(image)),
+			Failure:     vitalCommand.T("ignite.task.preload.failure", image),
+			RunW: func(w io.Writer) error {
+				pulled, err := vitalCommand.preloadImage(w, containerRuntime, image, mirror, loader, platform)
+				if err == nil && !SamePlatform(pulled, platform) {
+					emulated = append(emulated, fmt.Sprintf("%s (%s)", image, pulled))
+				}
+				return err
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, image := range emulated {
+		fmt.Fprint(vitalCommand.OutOrStdout(), vitalCommand.warningMessage(vitalCommand.T("ignite.preload.emulated", bold(image), platform)))
+	}
+	return nil
+}
+
+// preloadImage pulls an image for the platform, through the mirror if given, and loads it into the cluster
+// The platform of the pulled image is returned, which is linux/amd64 when the image is not published for the platform
+// Images pulled by runtimes other than Docker are loaded into the cluster from an image archive
+func (vitalCommand *vitalCommand) preloadImage(w io.Writer, runtime, image, mirror, loader, platform string) (string, error) {
+	run := func(name string, args ...string) error {
+		ctx, cancel := vitalCommand.ProvisionContext()
+		defer cancel()
+		cmd := commandContext(ctx, name, args...)
+		cmd.Stdout = w
+		cmd.Stderr = w
+		return runCommand(cmd)
+	}
+
+	source := MirroredImage(image, mirror)
+	pulled, err := vitalCommand.pullImage(w, runtime, source, platform)
+	if err != nil {
+		return "", err
+	}
+	if source != image {
+		if err := run(runtime, "tag", source, image); err != nil {
+			return "", err
+		}
+	}
+	if runtime != ContainerRuntimeDocker {
+		archive, err := ioutil.TempFile("", "opsani-image-*.tar")
+		if err != nil {
+			return "", err
+		}
+		archive.Close()
+		defer os.Remove(archive.Name())
+		if err := run(runtime, "save", "-o", archive.Name(), image); err != nil {
+			return "", err
+		}
+		if loader == ImageLoaderKind {
+			return pulled, run("kind", "load", "image-archive", archive.Name(), "--name", igniteProfile)
+		}
+		return pulled, run("minikube", "image", "load", archive.Name(), "-p", igniteProfile)
+	}
+	if loader == ImageLoaderKind {
+		return pulled, run("kind", "load", "docker-image", image, "--name", igniteProfile)
+	}
+	return pulled, run("minikube", "image", "load", image, "-p", igniteProfile)
+}
+
+// pullImage pulls the image for the platform and returns the platform of the pulled image
+// Images without a manifest for the platform are pulled for linux/amd64 to run emulated
+func (vitalCommand *vitalCommand) pullImage(w io.Writer, runtime, image, platform string) (string, error) {
+	pull := func(platform string) (string, error) {
+		ctx, cancel := vitalCommand.ProvisionContext()
+		defer cancel()
+		args := []string{"pull", image}
+		if platform != "" {
+			args = []string{"pull", "--platform", platform, image}
+		}
+		output := new(bytes.Buffer)
+		cmd := commandContext(ctx, runtime, args...)
+		cmd.Stdout = w
+		cmd.Stderr = io.MultiWriter(w, output)
+		err := runCommand(cmd)
+		return output.String(), err
+	}
+
+	// Docker selects the host platform when none is given
+	if SamePlatform(platform, DefaultImagePlatform) {
+		if SamePlatform(hostPlatform(), DefaultImagePlatform) {
+			platform = ""
+		}
+		_, err := pull(platform)
+		return DefaultImagePlatform, err
+	}
+	stderr, err := pull(platform)
+	if err != nil {
+		if !strings.Contains(stderr, "no matching manifest") {
+			return "", err
+		}
+		if _, err := pull(DefaultImagePlatform); err != nil {
+			return "", err
+		}
+		return DefaultImagePlatform, nil
+	}
+
+	// Single platform images are pulled regardless of the requested platform
+	ctx, cancel := vitalCommand.ContextWithTimeout()
+	defer cancel()
+	cmd := commandContext(ctx, runtime, "image", "inspect", "--format", "{{.Os}}/{{.Architecture}}", image)
+	cmd.Stderr = w
+	output, err := commandOutput(cmd)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// IgniteManifestImages returns the images referenced by the Ignite demo manifests with the servo at the given version
+func IgniteManifestImages(servoVersion string) ([]string, error) {
+	images := []string{}
+	err := pkger.Walk("/demo/manifests", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		data, err := readEmbeddedFile(path)
+		if err != nil {
+			return err
+		}
+		manifest := strings.ReplaceAll(string(data), servoVersionPlaceholder, servoVersion)
+		for _, match := range manifestImagePattern.FindAllStringSubmatch(manifest, -1) {
+			images = append(images, match[1])
+		}
+		return nil
+	})
+	return uniqueSortedStrings(images), err
+}
+
+// ReadImageList reads image references one per line, ignoring blank lines and # comments
+func ReadImageList(r io.Reader) ([]string, error) {
+	images := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			images = append(images, line)
+		}
+	}
+	return images, scanner.Err()
+}
+
+// MirroredImage returns the reference of the image in the registry mirror
+// The registry host of the image is replaced by the mirror and Docker Hub images are qualified
+func MirroredImage(image string, mirror string) string {
+	mirror = strings.TrimRight(mirror, "/")
+	if mirror == "" {
+		return image
+	}
+	path := image
+	components := strings.SplitN(image, "/", 2)
+	if len(components) == 2 && (strings.ContainsAny(components[0], ".:") || components[0] == "localhost") {
+		path = components[1]
+	} else if len(components) == 1 {
+		path = "library/" + image
+	}
+	return mirror + "/" + path
+}
+
+func uniqueSortedStrings(values []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
@@ -0,0 +1,216 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Ignite cluster sizing flags and the config keys providing their defaults
+const (
+	KeyIgniteMemory            = "memory"
+	KeyIgniteCPUs              = "cpus"
+	KeyIgniteKubernetesVersion = "kubernetes-version"
+	KeyIgniteDriver            = "driver"
+
+	KeyIgniteMemoryConfig            = "ignite.memory"
+	KeyIgniteCPUsConfig              = "ignite.cpus"
+	KeyIgniteKubernetesVersionConfig = "ignite.kubernetes_version"
+	KeyIgniteDriverConfig            = "ignite.driver"
+)
+
+// Defaults and lower bounds for the size of the Ignite cluster
+const (
+	DefaultIgniteMemoryMB = 4096
+	DefaultIgniteCPUs     = 4
+
+	minIgniteMemoryMB = 2048
+	minIgniteCPUs     = 2
+)
+
+// igniteDrivers are the minikube drivers supported for Ignite clusters
+var igniteDrivers = []string{"docker", "podman", "virtualbox", "vmware", "parallels", "hyperkit", "hyperv", "kvm2", "none"}
+
+var kubernetesVersionPattern = regexp.MustCompile(`^v?(\d+\.\d+\.\d+)$`)
+
+// HostCapacity describes the compute resources of the host machine
+// A zero value indicates that the capacity could not be determined
+type HostCapacity struct {
+	CPUs     int
+	MemoryMB int
+}
+
+// HostCapacityFunc returns the capacity of the host machine
+type HostCapacityFunc func() HostCapacity
+
+var hostCapacity HostCapacityFunc = detectHostCapacity
+
+// SetHostCapacityFunc sets the function used to determine the capacity of the host machine
+// A nil function restores the default detection
+func SetHostCapacityFunc(f HostCapacityFunc) {
+	if f == nil {
+		f = detectHostCapacity
+	}
+	hostCapacity = f
+}
+
+// IgniteClusterOptions describes the minikube cluster created for Ignite
+type IgniteClusterOptions struct {
+	MemoryMB          int
+	CPUs              int
+	KubernetesVersion string
+	Driver            string
+}
+
+// AddIgniteClusterFlags registers the cluster sizing flags on the given command
+func AddIgniteClusterFlags(cmd *cobra.Command) {
+	cmd.Flags().String(KeyIgniteMemory, "", fmt.Sprintf("Memory allocated to the cluster (e.g. 8192, 8g) (default %dMB)", DefaultIgniteMemoryMB))
+	cmd.Flags().Int(KeyIgniteCPUs, 0, fmt.Sprintf("CPUs allocated to the cluster (default %d)", DefaultIgniteCPUs))
+	cmd.Flags().String(KeyIgniteKubernetesVersion, "", "Kubernetes version of the cluster (e.g. v1.18.3) (default is the minikube default)")
+	cmd.Flags().String(KeyIgniteDriver, "", fmt.Sprintf("minikube driver: {%s} (default is the minikube default)", strings.Join(igniteDrivers, "|")))
+}
+
+// IgniteClusterOptions returns the cluster options from flags, falling back to config and then defaults
+func (baseCmd *BaseCommand) IgniteClusterOptions(cmd *cobra.Command) (IgniteClusterOptions, error) {
+	stringOption := func(flag string, configKey string) string {
+		if f := cmd.Flags().Lookup(flag); f != nil && f.Changed {
+			return f.Value.String()
+		}
+		return baseCmd.viperCfg.GetString(configKey)
+	}
+
+	options := IgniteClusterOptions{MemoryMB: DefaultIgniteMemoryMB, CPUs: DefaultIgniteCPUs}
+	if memory := stringOption(KeyIgniteMemory, KeyIgniteMemoryConfig); memory != "" {
+		memoryMB, err := parseMemoryMB(memory)
+		if err != nil {
+			return options, err
+		}
+		options.MemoryMB = memoryMB
+	}
+	if cpus := stringOption(KeyIgniteCPUs, KeyIgniteCPUsConfig); cpus != "" && cpus != "0" {
+		n, err := strconv.Atoi(cpus)
+		if err != nil {
+			return options, fmt.Errorf("invalid cpus %q: must be a whole number", cpus)
+		}
+		options.CPUs = n
+	}
+	if version := stringOption(KeyIgniteKubernetesVersion, KeyIgniteKubernetesVersionConfig); version != "" {
+		matches := kubernetesVersionPattern.FindStringSubmatch(version)
+		if matches == nil {
+			return options, fmt.Errorf("invalid Kubernetes version %q: must be of the form v1.18.3", version)
+		}
+		options.KubernetesVersion = "v" + matches[1]
+	}
+	if driver := stringOption(KeyIgniteDriver, KeyIgniteDriverConfig); driver != "" {
+		if !stringSliceContains(igniteDrivers, driver) {
+			return options, fmt.Errorf("invalid driver %q: must be one of %s", driver, strings.Join(igniteDrivers, ", "))
+		}
+		options.Driver = driver
+	}
+	return options, nil
+}
+
+// Validate checks that the options are within minikube minimums and the capacity of the host
+func (o IgniteClusterOptions) Validate(host HostCapacity) error {
+	if o.CPUs < minIgniteCPUs {
+		return fmt.Errorf("requested %d CPUs but the cluster requires at least %d", o.CPUs, minIgniteCPUs)
+	}
+	if o.MemoryMB < minIgniteMemoryMB {
+		return fmt.Errorf("requested %dMB of memory but the cluster requires at least %dMB", o.MemoryMB, minIgniteMemoryMB)
+	}
+	if host.CPUs > 0 && o.CPUs > host.CPUs {
+		return fmt.Errorf("requested %d CPUs but the host only has %d: run with --cpus=%d or less", o.CPUs, host.CPUs, host.CPUs)
+	}
+	if host.MemoryMB > 0 && o.MemoryMB > host.MemoryMB {
+		return fmt.Errorf("requested %dMB of memory but the host only has %dMB: run with a smaller --memory", o.MemoryMB, host.MemoryMB)
+	}
+	return nil
+}
+
+// MinikubeStartArgs returns the arguments for creating the cluster with minikube
+func (o IgniteClusterOptions) MinikubeStartArgs(profile string) []string {
+	args := []string{"start", fmt.Sprintf("--memory=%d", o.MemoryMB), fmt.Sprintf("--cpus=%d", o.CPUs), "--wait=all"}
+	if o.KubernetesVersion != "" {
+		args = append(args, "--kubernetes-version="+o.KubernetesVersion)
+	}
+	if o.Driver != "" {
+		args = append(args, "--driver="+o.Driver)
+	}
+	return append(args, "-p", profile)
+}
+
+// parseMemoryMB parses a memory size in megabytes with an optional m/mb or g/gb suffix
+func parseMemoryMB(memory string) (int, error) {
+	value := strings.ToLower(strings.TrimSpace(memory))
+	multiplier := 1
+	switch {
+	case strings.HasSuffix(value, "gb"), strings.HasSuffix(value, "g"):
+		value = strings.TrimRight(value, "gb")
+		multiplier = 1024
+	case strings.HasSuffix(value, "mb"), strings.HasSuffix(value, "m"):
+		value = strings.TrimRight(value, "mb")
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid memory %q: must be a size in megabytes (e.g. 4096) or gigabytes (e.g. 4g)", memory)
+	}
+	return n * multiplier, nil
+}
+
+func stringSliceContains(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// detectHostCapacity determines the CPUs and total memory of the host
+// Memory is only detected on Linux and macOS
+func detectHostCapacity() HostCapacity {
+	capacity := HostCapacity{CPUs: runtime.NumCPU()}
+	switch runtime.GOOS {
+	case "linux":
+		if data, err := ioutil.ReadFile("/proc/meminfo"); err == nil {
+			scanner := bufio.NewScanner(bytes.NewReader(data))
+			for scanner.Scan() {
+				fields := strings.Fields(scanner.Text())
+				if len(fields) >= 2 && fields[0] == "MemTotal:" {
+					if kb, err := strconv.Atoi(fields[1]); err == nil {
+						capacity.MemoryMB = kb / 1024
+					}
+				}
+			}
+		}
+	case "darwin":
+		if output, err := exec.Command("sysctl", "-n", "hw.memsize").Output(); err == nil {
+			if b, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64); err == nil {
+				capacity.MemoryMB = int(b / 1024 / 1024)
+			}
+		}
+	}
+	return capacity
+}
@@ -0,0 +1,218 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+)
+
+// igniteProfile is the name of the minikube profile and kubectl context hosting the Ignite demo
+const igniteProfile = "opsani-ignite"
+
+// IgniteStatus summarizes the health of the Ignite cluster and the workloads running in it
+type IgniteStatus struct {
+	Cluster       IgniteClusterStatus    `json:"cluster"`
+	Servo         IgnitePodStatus        `json:"servo"`
+	Prometheus    IgnitePodStatus        `json:"prometheus"`
+	App           IgniteDeploymentStatus `json:"app"`
+	LastReport    *time.Time             `json:"last_report,omitempty"`
+	LastReportAge string                 `json:"last_report_age,omitempty"`
+}
+
+// IgniteClusterStatus describes the state of the minikube cluster
+type IgniteClusterStatus struct {
+	Profile   string `json:"profile"`
+	Host      string `json:"host"`
+	APIServer string `json:"apiserver"`
+}
+
+// Running reports whether the cluster is able to serve Kubernetes API requests
+func (s IgniteClusterStatus) Running() bool {
+	return s.Host == "Running" && s.APIServer == "Running"
+}
+
+// IgnitePodStatus describes the state of a pod
+type IgnitePodStatus struct {
+	Phase    string `json:"phase"`
+	Ready    bool   `json:"ready"`
+	Restarts int64  `json:"restarts"`
+}
+
+// IgniteDeploymentStatus describes the replicas of a deployment
+type IgniteDeploymentStatus struct {
+	Name          string `json:"name"`
+	Replicas      int64  `json:"replicas"`
+	ReadyReplicas int64  `json:"ready_replicas"`
+}
+
+// Ready reports whether all desired replicas are ready
+func (s IgniteDeploymentStatus) Ready() bool {
+	return s.Replicas > 0 && s.ReadyReplicas == s.Replicas
+}
+
+const igniteStatusUnknown = "Unknown"
+
+// NewIgniteStatusCommand returns a new `opsani ignite status` command instance
+func NewIgniteStatusCommand(vitalCommand *vitalCommand) *cobra.Command {
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Get the status of an Ignite cluster",
+		Long: `Inspects the Ignite cluster and summarizes the state of the servo, Prometheus,
+the demo web application, and the time since the servo last reported to the optimizer.`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: nil,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			status := vitalCommand.IgniteStatus()
+			if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+				data, err := json.MarshalIndent(status, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintln(vitalCommand.OutOrStdout(), string(data))
+				return err
+			}
+			vitalCommand.printIgniteStatus(status)
+			return nil
+		},
+	}
+	statusCmd.Flags().Bool("json", false, "Output status as JSON")
+	return statusCmd
+}
+
+// IgniteStatus introspects the Ignite cluster
+// Components that cannot be inspected are reported with an unknown phase rather than failing
+func (vitalCommand *vitalCommand) IgniteStatus() IgniteStatus {
+	status := IgniteStatus{
+		Cluster:    IgniteClusterStatus{Profile: igniteProfile, Host: igniteStatusUnknown, APIServer: igniteStatusUnknown},
+		Servo:      IgnitePodStatus{Phase: igniteStatusUnknown},
+		Prometheus: IgnitePodStatus{Phase: igniteStatusUnknown},
+		App:        IgniteDeploymentStatus{Name: "web"},
+	}
+
+	// minikube exits non-zero when the cluster is stopped but still reports its state
+	output, _ := vitalCommand.igniteOutput("minikube", "status", "-p", igniteProfile, "-o", "json")
+	if gjson.ValidBytes(output) {
+		results := gjson.GetManyBytes(output, "Host", "APIServer")
+		if results[0].Exists() {
+			status.Cluster.Host = results[0].String()
+		}
+		if results[1].Exists() {
+			status.Cluster.APIServer = results[1].String()
+		}
+	}
+
+	if status.Cluster.Running() {
+		vitalCommand.igniteClusterStatus(&status)
+	}
+
+	if lastReport := vitalCommand.lastOptimizerReport(); lastReport != nil {
+		status.LastReport = lastReport
+		status.LastReportAge = time.Since(*lastReport).Round(time.Second).String()
+	}
+	return status
+}
+
+// igniteClusterStatus inspects the servo, Prometheus, and app in the Ignite cluster
+func (vitalCommand *vitalCommand) igniteClusterStatus(status *IgniteStatus) {
+	kubernetes, err := kubernetesClientFactory("", igniteProfile)
+	if err != nil {
+		return
+	}
+	ctx, cancel := vitalCommand.ContextWithTimeout()
+	defer cancel()
+	if output, err := kubernetes.List(ctx, podResource, "default", map[string]string{"comp": "servo"}); err == nil {
+		status.Servo = igniteDecodePodStatus(gjson.GetBytes(output, "items.0"))
+	}
+	if output, err := kubernetes.Get(ctx, podResource, "default", "prometheus-prometheus-0"); err == nil {
+		status.Prometheus = igniteDecodePodStatus(gjson.ParseBytes(output))
+	}
+	if output, err := kubernetes.Get(ctx, deploymentResource, "default", status.App.Name); err == nil {
+		results := gjson.GetManyBytes(output, "spec.replicas", "status.readyReplicas")
+		status.App.Replicas, status.App.ReadyReplicas = results[0].Int(), results[1].Int()
+	}
+}
+
+// igniteOutput runs an external command and returns its standard output
+func (vitalCommand *vitalCommand) igniteOutput(name string, args ...string) ([]byte, error) {
+	ctx, cancel := vitalCommand.ContextWithTimeout()
+	defer cancel()
+	stdout := new(bytes.Buffer)
+	cmd := commandContext(ctx, name, args...)
+	cmd.Stdout = stdout
+	err := runCommand(cmd)
+	return stdout.Bytes(), err
+}
+
+// igniteDecodePodStatus decodes the status of a Kubernetes pod object
+func igniteDecodePodStatus(pod gjson.Result) IgnitePodStatus {
+	if !pod.Exists() {
+		return IgnitePodStatus{Phase: "NotFound"}
+	}
+	status := IgnitePodStatus{Phase: pod.Get("status.phase").String(), Ready: true}
+	containers := pod.Get("status.containerStatuses").Array()
+	if len(containers) == 0 {
+		status.Ready = false
+	}
+	for _, container := range containers {
+		status.Ready = status.Ready && container.Get("ready").Bool()
+		status.Restarts += container.Get("restartCount").Int()
+	}
+	return status
+}
+
+// lastOptimizerReport returns the time that the servo last reported an adjustment or measurement
+func (vitalCommand *vitalCommand) lastOptimizerReport() *time.Time {
+	if vitalCommand.Optimizer() == "" || vitalCommand.AccessToken() == "" {
+		return nil
+	}
+	lastReport, _ := lastServoReport(vitalCommand.NewAPIClient())
+	return lastReport
+}
+
+// printIgniteStatus renders a human readable summary of the status
+func (vitalCommand *vitalCommand) printIgniteStatus(status IgniteStatus) {
+	bold := color.New(color.Bold).SprintFunc()
+	line := func(ok bool, label string, format string, args ...interface{}) {
+		glyph := vitalCommand.ColorGlyph(glyphSuccess)
+		if !ok {
+			glyph = vitalCommand.ColorGlyph(glyphFailure)
+		}
+		fmt.Fprintf(vitalCommand.OutOrStdout(), "%s  %-12s %s\n", glyph, bold(label), fmt.Sprintf(format, args...))
+	}
+	podSummary := func(pod IgnitePodStatus) string {
+		readiness := "not ready"
+		if pod.Ready {
+			readiness = "ready"
+		}
+		return fmt.Sprintf("%s, %s (%d restarts)", pod.Phase, readiness, pod.Restarts)
+	}
+
+	line(status.Cluster.Running(), "Cluster:", "%s (host %s, apiserver %s)", status.Cluster.Profile, status.Cluster.Host, status.Cluster.APIServer)
+	line(status.Servo.Ready, "Servo:", podSummary(status.Servo))
+	line(status.Prometheus.Ready, "Prometheus:", podSummary(status.Prometheus))
+	line(status.App.Ready(), "App:", "%s %d/%d replicas ready", status.App.Name, status.App.ReadyReplicas, status.App.Replicas)
+	if status.LastReport != nil {
+		line(true, "Last report:", "%s ago", status.LastReportAge)
+	} else {
+		line(false, "Last report:", "none")
+	}
+}
@@ -15,20 +15,44 @@
 package command_test
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/opsani/cli/command"
 	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"sigs.k8s.io/yaml"
 )
 
 type IgniteTestSuite struct {
 	test.Suite
+	kubernetes *test.FakeKubernetes
 }
 
 func (s *IgniteTestSuite) SetupTest() {
 	s.SetCommand(command.NewRootCommand())
+	s.kubernetes = igniteKubernetes()
+	command.SetKubernetesClientFactory(s.kubernetes.Factory())
+	command.SetHostPlatformFunc(func() string { return "linux/amd64" })
+	command.SetContainerRuntimeDetector(func() string { return command.ContainerRuntimeDocker })
+}
+
+func (s *IgniteTestSuite) TearDownTest() {
+	command.SetCommandContextFunc(nil)
+	command.SetKubernetesClientFactory(nil)
+	command.SetHostCapacityFunc(nil)
+	command.SetHostPlatformFunc(nil)
+	command.SetContainerRuntimeDetector(nil)
 }
 
 func TestIgniteTestSuite(t *testing.T) {
@@ -58,3 +82,700 @@ func (s *IgniteTestSuite) TestRunningIgniteBadConfigExt() {
 	fmt.Println(output)
 	s.Require().EqualError(err, "config file does not exist. Run \"opsani init\" and try again (stat foo.ini: no such file or directory)")
 }
+
+func (s *IgniteTestSuite) TestRunningIgniteHelpRecord() {
+	output, err := s.Execute("ignite", "--help")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "--record string")
+	s.Require().Contains(output, "asciinema cast file")
+}
+
+// igniteKubernetes returns a fake Ignite cluster with an available servo deployment
+func igniteKubernetes() *test.FakeKubernetes {
+	kubernetes := test.NewFakeKubernetes()
+	kubernetes.Lists["pods"] = `{"items": [
+		{"metadata": {"name": "servo-7d9f", "namespace": "default", "labels": {"comp": "servo"}}, "status": {"phase": "Running", "containerStatuses": [{"ready": true, "restartCount": 2}]}},
+		{"metadata": {"name": "web-5c8b", "namespace": "default", "labels": {"comp": "web"}}, "status": {"phase": "Running"}}
+	]}`
+	kubernetes.Objects["pods/prometheus-prometheus-0"] = `{"metadata": {"name": "prometheus-prometheus-0"}, "status": {"phase": "Pending", "containerStatuses": [{"ready": false, "restartCount": 0}]}}`
+	kubernetes.Objects["deployments.apps/web"] = `{"metadata": {"name": "web"}, "spec": {"replicas": 2}, "status": {"readyReplicas": 1}}`
+	kubernetes.Objects["deployments.apps/servo"] = `{"metadata": {"name": "servo", "generation": 2}, "spec": {"replicas": 1}, "status": {
+		"observedGeneration": 2, "updatedReplicas": 1, "availableReplicas": 1, "conditions": [{"type": "Available", "status": "True"}]
+	}}`
+	return kubernetes
+}
+
+// igniteStatusRecorder returns an exec recorder simulating a running Ignite cluster
+func igniteStatusRecorder() *test.ExecRecorder {
+	recorder := test.NewExecRecorder()
+	recorder.Respond("minikube status", test.ExecResponse{Stdout: `{"Name":"opsani-ignite","Host":"Running","Kubelet":"Running","APIServer":"Running"}`})
+	return recorder
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteStatusJSON() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/adjustments") {
+			w.Write([]byte(`{"adjustments": [{"id": "adj-1", "started_at": "2020-06-01T10:00:00Z", "completed_at": "2020-06-01T10:05:00Z"}]}`))
+		} else {
+			w.Write([]byte(`{"measurements": [{"id": "msr-1", "started_at": "2020-06-01T10:06:00Z"}]}`))
+		}
+	}))
+	defer server.Close()
+	s.SetEnv("OPSANI_BASE_URL", server.URL)
+	command.SetCommandContextFunc(igniteStatusRecorder().CommandContext)
+
+	configFile := test.TempConfigFileWithObj(map[string][]map[string]string{
+		"profiles": {{"name": "default", "optimizer": "example.com/app", "token": "123456"}},
+	})
+	output, err := s.Execute("--config", configFile.Name(), "ignite", "status", "--json")
+	s.Require().NoError(err)
+
+	var status command.IgniteStatus
+	s.Require().NoError(json.Unmarshal([]byte(output), &status))
+	s.Require().True(status.Cluster.Running())
+	s.Require().Equal(command.IgnitePodStatus{Phase: "Running", Ready: true, Restarts: 2}, status.Servo)
+	s.Require().Equal(command.IgnitePodStatus{Phase: "Pending", Ready: false}, status.Prometheus)
+	s.Require().Equal(command.IgniteDeploymentStatus{Name: "web", Replicas: 2, ReadyReplicas: 1}, status.App)
+	s.Require().Equal("opsani-ignite", s.kubernetes.Context)
+	s.Require().NotNil(status.LastReport)
+	s.Require().Equal(time.Date(2020, 6, 1, 10, 6, 0, 0, time.UTC), status.LastReport.UTC())
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteStatusStoppedCluster() {
+	recorder := test.NewExecRecorder()
+	recorder.Respond("minikube status", test.ExecResponse{Stdout: `{"Name":"opsani-ignite","Host":"Stopped","APIServer":"Stopped"}`, ExitCode: 7})
+	command.SetCommandContextFunc(recorder.CommandContext)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	s.SetEnv("OPSANI_BASE_URL", server.URL)
+
+	configFile := test.TempConfigFileWithObj(map[string][]map[string]string{
+		"profiles": {{"name": "default", "optimizer": "example.com/app", "token": "123456"}},
+	})
+	output, err := s.Execute("--config", configFile.Name(), "ignite", "status")
+	s.Require().NoError(err)
+	s.Require().Len(recorder.Invocations(), 1)
+	s.Require().Contains(output, "opsani-ignite (host Stopped, apiserver Stopped)")
+	s.Require().Contains(output, "Unknown, not ready (0 restarts)")
+	s.Require().Contains(output, "web 0/0 replicas ready")
+	s.Require().Contains(output, "none")
+}
+
+// igniteConfigDir returns a temporary directory containing a config file for the Ignite state to be recorded alongside
+func (s *IgniteTestSuite) igniteConfigDir() (dir string, configFile string) {
+	dir, err := ioutil.TempDir("", "ignite")
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { os.RemoveAll(dir) })
+	configFile = filepath.Join(dir, "config.yaml")
+	data, err := yaml.Marshal(map[string][]map[string]string{
+		"profiles": {{"name": "default", "optimizer": "example.com/app", "token": "123456"}},
+	})
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(configFile, data, 0644))
+	return dir, configFile
+}
+
+func (s *IgniteTestSuite) writeIgniteState(dir string, expiresAt time.Time) {
+	data, err := yaml.Marshal(command.IgniteState{Profile: "opsani-ignite", ExpiresAt: &expiresAt})
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "ignite-state.yaml"), data, 0644))
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteStartWithTTL() {
+	dir, configFile := s.igniteConfigDir()
+	recorder := test.NewExecRecorder()
+	recorder.Respond("minikube profile list", test.ExecResponse{Stdout: `{"valid": [{"Name": "opsani-ignite"}]}`})
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	before := time.Now()
+	output, err := s.Execute("--config", configFile, "ignite", "start", "--ttl", "4h")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "opsani ignite gc")
+	s.Require().Equal([]string{"minikube", "start", "-p", "opsani-ignite"}, recorder.LastInvocation())
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "ignite-state.yaml"))
+	s.Require().NoError(err)
+	var state command.IgniteState
+	s.Require().NoError(yaml.Unmarshal(data, &state))
+	s.Require().Equal("opsani-ignite", state.Profile)
+	s.Require().WithinDuration(before.Add(4*time.Hour), *state.ExpiresAt, time.Minute)
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteStartExemptFromTimeout() {
+	_, configFile := s.igniteConfigDir()
+	recorder := test.NewExecRecorder()
+	recorder.Respond("minikube profile list", test.ExecResponse{Stdout: `{"valid": [{"Name": "opsani-ignite"}]}`})
+	recorder.Respond("minikube start", test.ExecResponse{Delay: 6 * time.Second})
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	_, err := s.Execute("--config", configFile, "--timeout", "4s", "ignite", "start")
+	s.Require().NoError(err)
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteStartWithProvisionTimeout() {
+	_, configFile := s.igniteConfigDir()
+	recorder := test.NewExecRecorder()
+	recorder.Respond("minikube profile list", test.ExecResponse{Stdout: `{"valid": [{"Name": "opsani-ignite"}]}`})
+	recorder.Respond("minikube start", test.ExecResponse{Delay: time.Minute})
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	start := time.Now()
+	_, err := s.Execute("--config", configFile, "ignite", "start", "--provision-timeout", "2s")
+	s.Require().Error(err)
+	s.Require().True(time.Since(start) < 30*time.Second, "minikube start was not cancelled")
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteStopIsRecorded() {
+	_, configFile := s.igniteConfigDir()
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	_, err := s.Execute("--config", configFile, "ignite", "stop")
+	s.Require().NoError(err)
+	s.Require().Equal([][]string{{"minikube", "stop", "-p", "opsani-ignite"}}, recorder.Invocations())
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteGCExpired() {
+	dir, configFile := s.igniteConfigDir()
+	s.writeIgniteState(dir, time.Now().Add(-time.Hour))
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	_, err := s.Execute("--config", configFile, "ignite", "gc")
+	s.Require().NoError(err)
+	s.Require().Equal([][]string{{"minikube", "delete", "-p", "opsani-ignite"}}, recorder.Invocations())
+	s.Require().NoFileExists(filepath.Join(dir, "ignite-state.yaml"))
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteGCNotExpired() {
+	dir, configFile := s.igniteConfigDir()
+	s.writeIgniteState(dir, time.Now().Add(time.Hour))
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	_, err := s.Execute("--config", configFile, "ignite", "gc")
+	s.Require().NoError(err)
+	s.Require().Empty(recorder.Invocations())
+	s.Require().FileExists(filepath.Join(dir, "ignite-state.yaml"))
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteDeleteRemovesArtifacts() {
+	var resetQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resetQuery = r.Method + " " + r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	s.SetEnv("OPSANI_BASE_URL", server.URL)
+
+	dir, err := ioutil.TempDir("", "ignite")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "config.yaml")
+	data, err := yaml.Marshal(map[string]interface{}{
+		"profiles": []map[string]interface{}{{
+			"name": "default", "optimizer": "example.com/app", "token": "123456",
+			"servo": map[string]string{"type": "kubernetes", "namespace": "default", "deployment": "servo"},
+		}},
+	})
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(configFile, data, 0644))
+
+	// Manifests are written to the workspace of the profile by default
+	manifestsDir := filepath.Join(dir, "workspaces", "default", "manifests")
+	s.Require().NoError(os.MkdirAll(manifestsDir, 0755))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(manifestsDir, "prometheus.yaml"), []byte("kind: Prometheus"), 0644))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(manifestsDir, "servo-deployment.yaml"), []byte("kind: Deployment"), 0644))
+
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+	output, err := s.Execute("--config", configFile, "ignite", "delete", "--reset-optimizer")
+	s.Require().NoError(err)
+	s.Require().Equal([][]string{{"minikube", "delete", "-p", "opsani-ignite"}}, recorder.Invocations())
+	s.Require().Contains(output, "servo detached from profile default.")
+	s.Require().Contains(output, fmt.Sprintf("2 manifests removed from %s.", manifestsDir))
+	s.Require().NoDirExists(manifestsDir)
+	s.Require().Equal("PUT patch=true&reset=true", resetQuery)
+
+	data, err = ioutil.ReadFile(configFile)
+	s.Require().NoError(err)
+	s.Require().NotContains(string(data), "deployment: servo")
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteDeleteKeepsOtherServos() {
+	dir, err := ioutil.TempDir("", "ignite")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "config.yaml")
+	data, err := yaml.Marshal(map[string]interface{}{
+		"profiles": []map[string]interface{}{{
+			"name": "default", "optimizer": "example.com/app", "token": "123456",
+			"servo": map[string]string{"type": "kubernetes", "namespace": "production", "deployment": "servo"},
+		}},
+	})
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(configFile, data, 0644))
+
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+	output, err := s.Execute("--config", configFile, "ignite", "delete", "--keep-manifests")
+	s.Require().NoError(err)
+	s.Require().NotContains(output, "servo detached")
+
+	data, err = ioutil.ReadFile(configFile)
+	s.Require().NoError(err)
+	s.Require().Contains(string(data), "namespace: production")
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteLearnReferencesManifestsDir() {
+	dir, configFile := s.igniteConfigDir()
+	data, err := yaml.Marshal(command.IgniteState{Profile: "opsani-ignite", ManifestsDir: "/srv/ignite"})
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "ignite-state.yaml"), data, 0644))
+
+	output, err := s.Execute("--config", configFile, "ignite", "loadgen")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "kubectl apply -f "+filepath.Join("/srv/ignite", "servo-configmap.yaml"))
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteDeleteRemovesRecordedManifestsDir() {
+	dir, configFile := s.igniteConfigDir()
+	manifestsDir := filepath.Join(dir, "custom")
+	s.Require().NoError(os.Mkdir(manifestsDir, 0755))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(manifestsDir, "web-service.yaml"), []byte("kind: Service"), 0644))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(manifestsDir, "notes.txt"), []byte("notes"), 0644))
+	data, err := yaml.Marshal(command.IgniteState{Profile: "opsani-ignite", ManifestsDir: manifestsDir})
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "ignite-state.yaml"), data, 0644))
+
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+	_, err = s.Execute("--config", configFile, "ignite", "delete")
+	s.Require().NoError(err)
+	s.Require().NoFileExists(filepath.Join(manifestsDir, "web-service.yaml"))
+	// Files not written by Ignite are preserved
+	s.Require().FileExists(filepath.Join(manifestsDir, "notes.txt"))
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteExpiredPromptsForTeardown() {
+	dir, configFile := s.igniteConfigDir()
+	s.writeIgniteState(dir, time.Now().Add(-2*time.Hour))
+	recorder := test.NewExecRecorder()
+	recorder.Respond("minikube status", test.ExecResponse{ExitCode: 85})
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	_, err := s.ExecuteTestInteractively(test.Args("--config", configFile, "ignite", "status"), func(t *test.InteractiveTestContext) error {
+		t.RequireString(`Ignite cluster "opsani-ignite" expired 2h0m0s ago. Delete it now?`)
+		t.SendLine("Y")
+		t.ExpectEOF()
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"minikube", "delete", "-p", "opsani-ignite"}, recorder.Invocations()[0])
+	s.Require().NoFileExists(filepath.Join(dir, "ignite-state.yaml"))
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteCPUsExceedHostCapacity() {
+	_, configFile := s.igniteConfigDir()
+	command.SetHostCapacityFunc(func() command.HostCapacity { return command.HostCapacity{CPUs: 4, MemoryMB: 8192} })
+	_, err := s.Execute("--config", configFile, "ignite", "--cpus", "8")
+	s.Require().EqualError(err, "requested 8 CPUs but the host only has 4: run with --cpus=4 or less")
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteMemoryFromConfigExceedsHostCapacity() {
+	dir, _ := s.igniteConfigDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	data, err := yaml.Marshal(map[string]interface{}{
+		"ignite":   map[string]string{"memory": "16g"},
+		"profiles": []map[string]string{{"name": "default", "optimizer": "example.com/app", "token": "123456"}},
+	})
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(configFile, data, 0644))
+	command.SetHostCapacityFunc(func() command.HostCapacity { return command.HostCapacity{CPUs: 8, MemoryMB: 8192} })
+
+	_, err = s.Execute("--config", configFile, "ignite")
+	s.Require().EqualError(err, "requested 16384MB of memory but the host only has 8192MB: run with a smaller --memory")
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteInvalidClusterFlags() {
+	_, configFile := s.igniteConfigDir()
+	_, err := s.Execute("--config", configFile, "ignite", "--memory", "lots")
+	s.Require().EqualError(err, `invalid memory "lots": must be a size in megabytes (e.g. 4096) or gigabytes (e.g. 4g)`)
+
+	s.SetCommand(command.NewRootCommand())
+	_, err = s.Execute("--config", configFile, "ignite", "--driver", "qemu")
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), `invalid driver "qemu"`)
+
+	s.SetCommand(command.NewRootCommand())
+	_, err = s.Execute("--config", configFile, "ignite", "--kubernetes-version", "latest")
+	s.Require().EqualError(err, `invalid Kubernetes version "latest": must be of the form v1.18.3`)
+}
+
+func TestIgniteClusterOptionsMinikubeStartArgs(t *testing.T) {
+	options := command.IgniteClusterOptions{MemoryMB: 8192, CPUs: 6, KubernetesVersion: "v1.18.3", Driver: "docker"}
+	require.Equal(t, []string{
+		"start", "--memory=8192", "--cpus=6", "--wait=all", "--kubernetes-version=v1.18.3", "--driver=docker", "-p", "opsani-ignite",
+	}, options.MinikubeStartArgs("opsani-ignite"))
+}
+
+func TestIgniteClusterOptionsValidateMinimums(t *testing.T) {
+	err := command.IgniteClusterOptions{MemoryMB: 4096, CPUs: 1}.Validate(command.HostCapacity{})
+	require.EqualError(t, err, "requested 1 CPUs but the cluster requires at least 2")
+	err = command.IgniteClusterOptions{MemoryMB: 1024, CPUs: 2}.Validate(command.HostCapacity{})
+	require.EqualError(t, err, "requested 1024MB of memory but the cluster requires at least 2048MB")
+	err = command.IgniteClusterOptions{MemoryMB: 65536, CPUs: 64}.Validate(command.HostCapacity{})
+	require.NoError(t, err, "unknown host capacity is not validated")
+}
+
+func (s *IgniteTestSuite) TestRunningIgnitePreloadList() {
+	dir, configFile := s.igniteConfigDir()
+	imagesFile := filepath.Join(dir, "images.txt")
+	s.Require().NoError(ioutil.WriteFile(imagesFile, []byte("# Prometheus server\nquay.io/prometheus/prometheus:v2.18.1\n\nopsani/co-http:latest\n"), 0644))
+
+	output, err := s.Execute("--config", configFile, "ignite", "preload", "--list", "--images-file", imagesFile)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{
+		"jimmidyson/configmap-reload:v0.3.0",
+		"opsani/co-http:latest",
+		"opsani/servo-k8s-prom-vegeta:latest",
+		"quay.io/coreos/prometheus-config-reloader:v0.38.1",
+		"quay.io/coreos/prometheus-operator:v0.38.1",
+		"quay.io/prometheus/prometheus:v2.18.1",
+	}, strings.Split(strings.TrimSpace(output), "\n"))
+}
+
+func (s *IgniteTestSuite) TestRunningIgnitePreloadThroughMirror() {
+	dir, configFile := s.igniteConfigDir()
+	imagesFile := filepath.Join(dir, "images.txt")
+	s.Require().NoError(ioutil.WriteFile(imagesFile, []byte("quay.io/prometheus/prometheus:v2.18.1\n"), 0644))
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	_, err := s.Execute("--config", configFile, "ignite", "preload", "--images-file", imagesFile, "--registry-mirror", "registry.example.com/mirror/", "--loader", "kind")
+	s.Require().NoError(err)
+	invocations := recorder.Invocations()
+	s.Require().Len(invocations, 18)
+	s.Require().Equal([][]string{
+		{"docker", "pull", "registry.example.com/mirror/jimmidyson/configmap-reload:v0.3.0"},
+		{"docker", "tag", "registry.example.com/mirror/jimmidyson/configmap-reload:v0.3.0", "jimmidyson/configmap-reload:v0.3.0"},
+		{"kind", "load", "docker-image", "jimmidyson/configmap-reload:v0.3.0", "--name", "opsani-ignite"},
+	}, invocations[:3])
+	s.Require().Equal([]string{"docker", "pull", "registry.example.com/mirror/prometheus/prometheus:v2.18.1"}, invocations[15])
+}
+
+func (s *IgniteTestSuite) TestRunningIgnitePreloadFailure() {
+	_, configFile := s.igniteConfigDir()
+	recorder := test.NewExecRecorder()
+	recorder.Respond("docker pull", test.ExecResponse{Stderr: "connection refused", ExitCode: 1})
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	output, err := s.Execute("--config", configFile, "ignite", "preload")
+	s.Require().EqualError(err, "exit status 1")
+	s.Require().Contains(output, "failed preloading image jimmidyson/configmap-reload:v0.3.0")
+	s.Require().Len(recorder.Invocations(), 1)
+}
+
+func TestMirroredImage(t *testing.T) {
+	require.Equal(t, "opsani/servo:latest", command.MirroredImage("opsani/servo:latest", ""))
+	require.Equal(t, "mirror.local/opsani/servo:latest", command.MirroredImage("opsani/servo:latest", "mirror.local"))
+	require.Equal(t, "mirror.local/library/nginx:1.19", command.MirroredImage("nginx:1.19", "mirror.local/"))
+	require.Equal(t, "mirror.local/coreos/prometheus-operator:v0.38.1", command.MirroredImage("quay.io/coreos/prometheus-operator:v0.38.1", "mirror.local"))
+	require.Equal(t, "mirror.local/team/app", command.MirroredImage("localhost:5000/team/app", "mirror.local"))
+}
+
+func TestIgniteManifestsMatchPinnedChecksums(t *testing.T) {
+	require.NoError(t, command.VerifyIgniteManifests())
+}
+
+func TestIgniteManifestChecksumMismatch(t *testing.T) {
+	err := command.VerifyIgniteManifest("/demo/manifests/prometheus.yaml", []byte("kind: Prometheus\n"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `embedded manifest "/demo/manifests/prometheus.yaml" failed checksum verification`)
+	err = command.VerifyIgniteManifest("/demo/manifests/extra.yaml", []byte{})
+	require.EqualError(t, err, `embedded manifest "/demo/manifests/extra.yaml" has no pinned checksum`)
+}
+
+func TestRenderIgniteManifestFromProfile(t *testing.T) {
+	profile := command.Profile{Optimizer: "dev.example.com/demo", Token: "123456"}
+	deployment, err := command.RenderIgniteManifest("/demo/manifests/servo/servo-deployment.yaml", profile, "0.9.1")
+	require.NoError(t, err)
+	require.Contains(t, string(deployment), "image: opsani/servo-k8s-prom-vegeta:0.9.1\n")
+	require.Contains(t, string(deployment), "        - demo\n        - '--auth-token=/etc/opsani/token'\n        env:\n")
+	require.Contains(t, string(deployment), "- name: OPTUNE_ACCOUNT\n          value: dev.example.com\n")
+	require.NotContains(t, string(deployment), "--url")
+
+	secret, err := command.RenderIgniteManifest("/demo/manifests/servo/servo-secret.yaml", profile, "latest")
+	require.NoError(t, err)
+	require.Contains(t, string(secret), "token: MTIzNDU2")
+
+	configMap, err := command.RenderIgniteManifest("/demo/manifests/servo/servo-configmap.yaml", profile, "latest")
+	require.NoError(t, err)
+	require.Contains(t, string(configMap), "prometheus_endpoint: "+command.DefaultIgnitePrometheusURL+"\n")
+	var config map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(configMap, &config))
+}
+
+func TestRenderIgniteManifestForDedicatedBackend(t *testing.T) {
+	profile := command.Profile{
+		Optimizer: "dev.example.com/demo",
+		Token:     "123456",
+		BaseURL:   "https://api.staging.opsani.com/",
+		Servo:     command.Servo{PrometheusURL: "http://prometheus.monitoring:9090"},
+	}
+	deployment, err := command.RenderIgniteManifest("/demo/manifests/servo/servo-deployment.yaml", profile, "latest")
+	require.NoError(t, err)
+	require.Contains(t, string(deployment), "- '--url=https://api.staging.opsani.com/accounts/dev.example.com/applications/demo/servo'\n")
+
+	configMap, err := command.RenderIgniteManifest("/demo/manifests/servo/servo-configmap.yaml", profile, "latest")
+	require.NoError(t, err)
+	require.Contains(t, string(configMap), "prometheus_endpoint: http://prometheus.monitoring:9090\n")
+	require.NotContains(t, string(configMap), "prometheus-operated")
+}
+
+func TestValidateServoVersion(t *testing.T) {
+	for _, version := range []string{"latest", "0.9.1", "v0.9.1", "1.0.0-rc.1"} {
+		require.NoError(t, command.ValidateServoVersion(version), version)
+	}
+	require.EqualError(t, command.ValidateServoVersion("newest"), `invalid servo version "newest": must be "latest" or a release version such as 0.9.1`)
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteUpgrade() {
+	dir, configFile := s.igniteConfigDir()
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	_, err := s.Execute("--config", configFile, "ignite", "upgrade", "--servo-version", "0.9.1")
+	s.Require().NoError(err)
+	s.Require().Empty(recorder.Invocations())
+	s.Require().Equal("opsani-ignite", s.kubernetes.Context)
+	patches := s.kubernetes.Patches()
+	s.Require().Len(patches, 1)
+	s.Require().Equal("servo", patches[0].GetName())
+	s.Require().Equal("default", patches[0].GetNamespace())
+	s.Require().JSONEq(`{"spec":{"template":{"spec":{"containers":[{"name":"main","image":"opsani/servo-k8s-prom-vegeta:0.9.1"}]}}}}`, string(patches[0].GetPatch()))
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "ignite-state.yaml"))
+	s.Require().NoError(err)
+	var state command.IgniteState
+	s.Require().NoError(yaml.Unmarshal(data, &state))
+	s.Require().Equal("0.9.1", state.ServoVersion)
+
+	// The pinned version is used by subsequent commands
+	s.SetCommand(command.NewRootCommand())
+	output, err := s.Execute("--config", configFile, "ignite", "preload", "--list")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "opsani/servo-k8s-prom-vegeta:0.9.1\n")
+}
+
+// progressEvents decodes the JSON progress events interleaved with command output
+func progressEvents(t *testing.T, output string) []command.ProgressEvent {
+	events := []command.ProgressEvent{}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "{\"event\":") {
+			var event command.ProgressEvent
+			require.NoError(t, json.Unmarshal([]byte(line), &event))
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteUpgradeWithJSONProgress() {
+	_, configFile := s.igniteConfigDir()
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	output, err := s.Execute("--config", configFile, "--progress-format", "json", "ignite", "upgrade", "--servo-version", "0.9.1")
+	s.Require().NoError(err)
+	events := progressEvents(s.T(), output)
+	s.Require().Len(events, 2)
+	s.Require().Equal(command.ProgressEventStarted, events[0].Event)
+	s.Require().Equal(command.ProgressEventSucceeded, events[1].Event)
+	s.Require().Equal(events[0].Task, events[1].Task)
+	s.Require().Contains(events[0].Task, "0.9.1")
+	s.Require().NotContains(events[0].Task, "\x1b")
+	s.Require().False(events[1].Time.Before(events[0].Time))
+	s.Require().Empty(events[1].Error)
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteUpgradeWithJSONProgressFailure() {
+	_, configFile := s.igniteConfigDir()
+	s.kubernetes.Errors["patch deployments.apps"] = errors.New(`deployments.apps "servo" not found`)
+
+	output, err := s.Execute("--config", configFile, "--progress-format", "json", "ignite", "upgrade")
+	s.Require().Error(err)
+	events := progressEvents(s.T(), output)
+	s.Require().Len(events, 2)
+	s.Require().Equal(command.ProgressEventFailed, events[1].Event)
+	s.Require().Equal(err.Error(), events[1].Error)
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteUpgradeWithoutJSONProgress() {
+	_, configFile := s.igniteConfigDir()
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	output, err := s.Execute("--config", configFile, "ignite", "upgrade")
+	s.Require().NoError(err)
+	s.Require().Empty(progressEvents(s.T(), output))
+}
+
+func (s *IgniteTestSuite) TestRunningWithInvalidProgressFormat() {
+	_, configFile := s.igniteConfigDir()
+	_, err := s.Execute("--config", configFile, "--progress-format", "xml", "ignite", "upgrade")
+	s.Require().EqualError(err, `invalid progress format "xml": must be "human" or "json"`)
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteStartWithServoVersion() {
+	_, configFile := s.igniteConfigDir()
+	recorder := test.NewExecRecorder()
+	recorder.Respond("minikube profile list", test.ExecResponse{Stdout: `{"valid": [{"Name": "opsani-ignite"}]}`})
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	_, err := s.Execute("--config", configFile, "ignite", "start", "--servo-version", "v1.0.0")
+	s.Require().NoError(err)
+	patches := s.kubernetes.Patches()
+	s.Require().NotEmpty(patches)
+	s.Require().Contains(string(patches[len(patches)-1].GetPatch()), `"image":"opsani/servo-k8s-prom-vegeta:v1.0.0"`)
+}
+
+func (s *IgniteTestSuite) TestRunningIgniteUpgradeInvalidVersion() {
+	_, configFile := s.igniteConfigDir()
+	_, err := s.Execute("--config", configFile, "ignite", "upgrade", "--servo-version", "newest")
+	s.Require().EqualError(err, `invalid servo version "newest": must be "latest" or a release version such as 0.9.1`)
+}
+
+func (s *IgniteTestSuite) TestRunningIgnitePreflight() {
+	_, configFile := s.igniteConfigDir()
+	s.kubernetes.SetAPIVersions("monitoring.coreos.com/v1", "rbac.authorization.k8s.io/v1", "authorization.k8s.io/v1")
+	s.kubernetes.Allow("create", "clusterroles.rbac.authorization.k8s.io")
+
+	output, err := s.Execute("--config", configFile, "ignite", "preflight")
+	s.Require().EqualError(err, "1 preflight checks failed: resolve the issues above or rerun with --skip-preflight")
+	s.Require().Contains(output, "RBAC: RBAC authorization is enabled")
+	s.Require().Contains(output, "Permissions: not permitted to create cluster roles and cluster role bindings")
+	s.Require().Contains(output, "Ask a cluster administrator to grant you the cluster-admin role or deploy with --scope namespace")
+	s.Require().Contains(output, "Metrics: the resource metrics API (metrics.k8s.io) is not available")
+}
+
+func (s *IgniteTestSuite) TestRunningIgnitePreloadForHostPlatform() {
+	_, configFile := s.igniteConfigDir()
+	command.SetHostPlatformFunc(func() string { return "linux/arm64" })
+	recorder := test.NewExecRecorder()
+	recorder.Respond("docker image inspect", test.ExecResponse{Stdout: "linux/arm64\n"})
+	// A single platform image is pulled regardless of the requested platform
+	recorder.Respond("docker image inspect --format {{.Os}}/{{.Architecture}} opsani/servo-k8s-prom-vegeta", test.ExecResponse{Stdout: "linux/amd64\n"})
+	// A multi-platform image without an arm64 manifest
+	recorder.Respond("docker pull --platform linux/arm64 quay.io/coreos/prometheus-operator", test.ExecResponse{
+		Stderr: "no matching manifest for linux/arm64 in the manifest list entries", ExitCode: 1,
+	})
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	output, err := s.Execute("--config", configFile, "ignite", "preload")
+	s.Require().NoError(err)
+	s.Require().Contains(recorder.Invocations(), []string{"docker", "pull", "--platform", "linux/arm64", "jimmidyson/configmap-reload:v0.3.0"})
+	s.Require().Contains(recorder.Invocations(), []string{"docker", "pull", "--platform", "linux/amd64", "quay.io/coreos/prometheus-operator:v0.38.1"})
+	s.Require().Contains(output, "image opsani/servo-k8s-prom-vegeta:latest (linux/amd64) is not published for linux/arm64 and will run emulated")
+	s.Require().Contains(output, "image quay.io/coreos/prometheus-operator:v0.38.1 (linux/amd64) is not published for linux/arm64 and will run emulated")
+	s.Require().NotContains(output, "configmap-reload:v0.3.0 (linux/amd64)")
+}
+
+func (s *IgniteTestSuite) TestRunningIgnitePreloadForExplicitPlatform() {
+	_, configFile := s.igniteConfigDir()
+	command.SetHostPlatformFunc(func() string { return "linux/arm64" })
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	output, err := s.Execute("--config", configFile, "ignite", "preload", "--platform", "linux/amd64")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"docker", "pull", "--platform", "linux/amd64", "jimmidyson/configmap-reload:v0.3.0"}, recorder.Invocations()[0])
+	s.Require().NotContains(output, "emulated")
+}
+
+func (s *IgniteTestSuite) TestRunningIgnitePreloadInvalidPlatform() {
+	_, configFile := s.igniteConfigDir()
+	_, err := s.Execute("--config", configFile, "ignite", "preload", "--platform", "arm64")
+	s.Require().EqualError(err, `invalid platform "arm64": must be of the form os/arch[/variant] (e.g. linux/arm64)`)
+}
+
+func TestSamePlatform(t *testing.T) {
+	require.True(t, command.SamePlatform("linux/arm/v7", "linux/arm"))
+	require.True(t, command.SamePlatform("linux/amd64", "linux/amd64"))
+	require.False(t, command.SamePlatform("linux/arm64", "linux/amd64"))
+}
+
+func (s *IgniteTestSuite) TestRunningIgnitePreloadWithPodman() {
+	_, configFile := s.igniteConfigDir()
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	_, err := s.Execute("--config", configFile, "ignite", "preload", "--container-runtime", "podman", "--loader", "kind")
+	s.Require().NoError(err)
+	invocations := recorder.Invocations()
+	s.Require().Equal([]string{"podman", "pull", "jimmidyson/configmap-reload:v0.3.0"}, invocations[0])
+	s.Require().Equal([]string{"podman", "save", "-o"}, invocations[1][:3])
+	s.Require().Equal("jimmidyson/configmap-reload:v0.3.0", invocations[1][4])
+	s.Require().Equal([]string{"kind", "load", "image-archive", invocations[1][3], "--name", "opsani-ignite"}, invocations[2])
+	_, err = os.Stat(invocations[1][3])
+	s.Require().True(os.IsNotExist(err), "image archive should be removed")
+}
+
+func (s *IgniteTestSuite) TestRunningIgnitePreloadWithRuntimeFromConfig() {
+	_, configFile := s.igniteConfigDir()
+	s.Require().NoError(ioutil.WriteFile(configFile, []byte(`profiles:
+- name: default
+  optimizer: example.com/app
+  token: "123456"
+ignite:
+  container_runtime: nerdctl
+`), 0644))
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	_, err := s.Execute("--config", configFile, "ignite", "preload")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"nerdctl", "pull", "jimmidyson/configmap-reload:v0.3.0"}, recorder.Invocations()[0])
+}
+
+func (s *IgniteTestSuite) TestRunningIgnitePreloadInvalidContainerRuntime() {
+	_, configFile := s.igniteConfigDir()
+	_, err := s.Execute("--config", configFile, "ignite", "preload", "--container-runtime", "lxc")
+	s.Require().EqualError(err, `invalid container runtime "lxc": must be one of docker, podman, nerdctl`)
+}
+
+func TestDetectContainerRuntime(t *testing.T) {
+	detect := func(env map[string]string, paths []string, binaries ...string) string {
+		return command.DetectContainerRuntime(command.ContainerRuntimeEnv{
+			Getenv: func(key string) string { return env[key] },
+			Exists: func(path string) bool {
+				for _, p := range paths {
+					if p == path {
+						return true
+					}
+				}
+				return false
+			},
+			LookPath: func(file string) (string, error) {
+				for _, b := range binaries {
+					if b == file {
+						return "/usr/local/bin/" + file, nil
+					}
+				}
+				return "", errors.New("not found")
+			},
+		})
+	}
+	home := map[string]string{"HOME": "/home/dev", "XDG_RUNTIME_DIR": "/run/user/1000"}
+	require.Equal(t, "docker", detect(home, nil))
+	require.Equal(t, "docker", detect(map[string]string{"DOCKER_HOST": "tcp://10.0.0.1:2376"}, nil, "podman"))
+	require.Equal(t, "podman", detect(home, []string{"/run/user/1000/podman/podman.sock"}, "docker", "podman"))
+	require.Equal(t, "docker", detect(home, []string{"/var/run/docker.sock", "/run/user/1000/podman/podman.sock"}, "docker", "podman"))
+	require.Equal(t, "nerdctl", detect(home, []string{"/home/dev/.rd/containerd.sock"}, "nerdctl", "docker"))
+	require.Equal(t, "podman", detect(home, nil, "podman"))
+}
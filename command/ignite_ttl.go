@@ -0,0 +1,179 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// KeyIgniteTTL is the flag for limiting the lifetime of the Ignite cluster
+const KeyIgniteTTL = "ttl"
+
+// IgniteState records the lifecycle of the Ignite cluster between invocations
+type IgniteState struct {
+	Profile      string     `json:"profile"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	ServoVersion string     `json:"servo_version,omitempty"`
+	ManifestsDir string     `json:"manifests_dir,omitempty"`
+}
+
+// Expired reports whether the cluster has outlived its TTL at the given time
+func (s IgniteState) Expired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
+}
+
+// AddIgniteTTLFlag registers the --ttl flag on the given command
+func AddIgniteTTLFlag(cmd *cobra.Command) {
+	cmd.Flags().Duration(KeyIgniteTTL, 0, "Delete the Ignite cluster after a duration (e.g. 4h)")
+}
+
+// statePath returns the path of a file recording state alongside the config file in use
+func (baseCmd *BaseCommand) statePath(filename string) string {
+	dir := baseCmd.DefaultConfigPath()
+	if configFile := baseCmd.viperCfg.ConfigFileUsed(); configFile != "" {
+		dir = filepath.Dir(configFile)
+	}
+	return filepath.Join(dir, filename)
+}
+
+// IgniteStatePath returns the path of the file recording the Ignite cluster state
+// The state is stored alongside the config file in use
+func (baseCmd *BaseCommand) IgniteStatePath() string {
+	return baseCmd.statePath("ignite-state.yaml")
+}
+
+// LoadIgniteState returns the recorded Ignite cluster state or nil if no cluster is recorded
+func (baseCmd *BaseCommand) LoadIgniteState() (*IgniteState, error) {
+	data, err := ioutil.ReadFile(baseCmd.IgniteStatePath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	state := &IgniteState{}
+	if err := yaml.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid ignite state %s: %w", baseCmd.IgniteStatePath(), err)
+	}
+	return state, nil
+}
+
+// SaveIgniteState records the Ignite cluster state, removing the record when state is nil
+func (baseCmd *BaseCommand) SaveIgniteState(state *IgniteState) error {
+	path := baseCmd.IgniteStatePath()
+	if state == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := yaml.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// recordIgniteTTL records the expiry of the Ignite cluster from the --ttl flag
+func (vitalCommand *vitalCommand) recordIgniteTTL(cmd *cobra.Command, now time.Time) error {
+	ttl, _ := cmd.Flags().GetDuration(KeyIgniteTTL)
+	if ttl <= 0 {
+		return nil
+	}
+	state, err := vitalCommand.LoadIgniteState()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = &IgniteState{Profile: igniteProfile}
+	}
+	expiresAt := now.Add(ttl)
+	state.ExpiresAt = &expiresAt
+	if err := vitalCommand.SaveIgniteState(state); err != nil {
+		return err
+	}
+	vitalCommand.Printf("\n%s  The Ignite cluster expires at %s.\n", vitalCommand.Glyph(glyphInfo), expiresAt.Format(time.RFC1123))
+	vitalCommand.Printf("   Schedule automatic teardown with a cron entry such as: */15 * * * * opsani ignite gc\n")
+	return nil
+}
+
+// deleteIgniteCluster deletes the Ignite minikube profile and clears the recorded state
+func (vitalCommand *vitalCommand) deleteIgniteCluster() error {
+	ctx, cancel := vitalCommand.ProvisionContext()
+	defer cancel()
+	cmd := commandContext(ctx, "minikube", "delete", "-p", igniteProfile)
+	cmd.Stdout = vitalCommand.OutOrStdout()
+	cmd.Stderr = vitalCommand.ErrOrStderr()
+	if err := runCommand(cmd); err != nil {
+		return err
+	}
+	return vitalCommand.SaveIgniteState(nil)
+}
+
+// CheckIgniteExpiryRunE prompts to delete an Ignite cluster that has outlived its TTL
+// Declining the prompt leaves the cluster running until the next invocation
+func (vitalCommand *vitalCommand) CheckIgniteExpiryRunE(cmd *cobra.Command, args []string) error {
+	switch cmd.Name() {
+	case "gc", "delete":
+		return nil
+	}
+	state, err := vitalCommand.LoadIgniteState()
+	if err != nil || state == nil || !state.Expired(time.Now()) {
+		return err
+	}
+
+	expired := time.Since(*state.ExpiresAt).Round(time.Minute)
+	teardown, err := vitalCommand.Confirm(fmt.Sprintf("Ignite cluster %q expired %s ago. Delete it now?", state.Profile, expired), true)
+	if err != nil {
+		return err
+	}
+	if !teardown {
+		return nil
+	}
+	return vitalCommand.deleteIgniteCluster()
+}
+
+// NewIgniteGCCommand returns a new `opsani ignite gc` command instance
+func NewIgniteGCCommand(vitalCommand *vitalCommand) *cobra.Command {
+	return &cobra.Command{
+		Use:   "gc",
+		Short: "Delete an expired Ignite cluster",
+		Long: `Deletes the Ignite cluster if it has outlived the TTL set with "opsani ignite start --ttl".
+
+The command is non-interactive and suitable for running periodically from cron or a launchd agent.`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: nil,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			state, err := vitalCommand.LoadIgniteState()
+			if err != nil {
+				return err
+			}
+			if state == nil || !state.Expired(time.Now()) {
+				return nil
+			}
+			vitalCommand.Printf("Deleting expired Ignite cluster %q...\n", state.Profile)
+			return vitalCommand.deleteIgniteCluster()
+		},
+	}
+}
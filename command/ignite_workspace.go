@@ -0,0 +1,61 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// KeyOutputDir is the flag for the directory that Ignite writes the applied manifests into
+const KeyOutputDir = "output-dir"
+
+// AddIgniteOutputDirFlag registers the --output-dir flag on the given command
+func AddIgniteOutputDirFlag(cmd *cobra.Command) {
+	cmd.Flags().String(KeyOutputDir, "", "Directory to write the Ignite manifests into (default is ~/.opsani/workspaces/<profile>/manifests)")
+	cmd.MarkFlagDirname(KeyOutputDir)
+}
+
+// IgniteWorkspacePath returns the path of the workspace holding the Ignite artifacts of a profile
+// Workspaces are stored alongside the config file in use
+func (baseCmd *BaseCommand) IgniteWorkspacePath(profileName string) string {
+	return filepath.Join(filepath.Dir(baseCmd.IgniteStatePath()), "workspaces", profileName)
+}
+
+// igniteOutputDir returns the directory to write the Ignite manifests into from the --output-dir flag,
+// defaulting to the workspace of the selected profile
+func (vitalCommand *vitalCommand) igniteOutputDir(cmd *cobra.Command) (string, error) {
+	if dir, _ := cmd.Flags().GetString(KeyOutputDir); dir != "" {
+		return filepath.Abs(dir)
+	}
+	return filepath.Join(vitalCommand.IgniteWorkspacePath(vitalCommand.profileName()), "manifests"), nil
+}
+
+// igniteManifestsDir returns the directory that the Ignite manifests were written into as recorded
+// in the Ignite state, defaulting to the workspace of the selected profile
+func (vitalCommand *vitalCommand) igniteManifestsDir() string {
+	if state, err := vitalCommand.LoadIgniteState(); err == nil && state != nil && state.ManifestsDir != "" {
+		return state.ManifestsDir
+	}
+	return filepath.Join(vitalCommand.IgniteWorkspacePath(vitalCommand.profileName()), "manifests")
+}
+
+func (vitalCommand *vitalCommand) profileName() string {
+	if vitalCommand.profile == nil {
+		return "default"
+	}
+	return vitalCommand.profile.Name
+}
@@ -26,36 +26,78 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const confirmedArg = "confirmed"
+// Flags of the init wizard
+const (
+	confirmedArg       = "confirmed"
+	initNameArg        = "name"
+	initAttachServoArg = "attach-servo"
+	initIgniteArg      = "ignite"
+)
 
 type initCommand struct {
 	*BaseCommand
-
-	confirmed bool
 }
 
-func (initCmd *initCommand) RunInitWithTokenCommand(_ *cobra.Command, args []string) error {
-	initToken := args[0]
-
-	initCmd.Printf("Initializing with token: %s...\n", initToken)
-
+// RunInitCommand walks through initializing the Opsani CLI config
+// Credentials are acquired from an init token or by prompting, then the profile is created with an
+// optional servo attached and the interactive demo is offered once the config has been written
+func (initCmd *initCommand) RunInitCommand(c *cobra.Command, args []string) error {
 	configFile := initCmd.viperCfg.ConfigFileUsed()
 	// NOTE: On first launch with no config file, Viper returns ""
 	// because no config file was resolved. There's probably a cleaner solution...
 	if configFile == "" {
 		configFile = initCmd.DefaultConfigFile()
 	}
+
+	// Handle reinitialization case
+	overwrite := false
+	if _, err := os.Stat(configFile); !os.IsNotExist(err) && !initCmd.AssumeYes() {
+		initCmd.Println("Using config from:", configFile)
+		initCmd.PrettyPrintYAMLObject(initCmd.GetAllSettings())
+
+		if overwrite, err = initCmd.Confirm(fmt.Sprintf("Existing config found. Overwrite %s?", configFile), false); err != nil {
+			return err
+		}
+		if !overwrite {
+			return terminal.InterruptErr
+		}
+	}
+
+	// Acquire credentials
 	var profile Profile
-	URL := fmt.Sprintf("http://localhost:5678/init/%s", initToken)
-	client := resty.New()
-	resp, err := client.R().
-		SetResult(&profile).
-		Get(URL)
+	var err error
+	if len(args) == 1 {
+		profile, err = initCmd.profileFromInitToken(args[0])
+	} else {
+		profile, err = initCmd.promptForCredentials(overwrite)
+	}
 	if err != nil {
 		return err
 	}
-	if !resp.IsSuccess() {
-		return fmt.Errorf("Failed initialization with token %q (%s)", initToken, resp.Body())
+	if name, _ := c.Flags().GetString(initNameArg); c.Flags().Changed(initNameArg) || profile.Name == "" {
+		profile.Name = name
+	}
+	// Profiles retrieved with an init token use the Opsani API unless a base URL is given
+	if profile.BaseURL == "" {
+		profile.BaseURL = initCmd.BaseURL()
+	}
+	if err := ValidateBaseURL(profile.BaseURL); err != nil {
+		return err
+	}
+	if err := initCmd.confirmOptimizer(c, profile); err != nil {
+		return err
+	}
+	initCmd.recordScopes(c, &profile)
+
+	// Optionally attach a servo to the new profile
+	attachServo, err := initCmd.offer(c, initAttachServoArg, fmt.Sprintf("Attach a servo to profile %q?", profile.Name))
+	if err != nil {
+		return err
+	}
+	if attachServo {
+		if profile.Servo, err = initCmd.promptForServo(false, ""); err != nil {
+			return err
+		}
 	}
 
 	// Confirm that the user wants to write this config
@@ -67,73 +109,78 @@ func (initCmd *initCommand) RunInitWithTokenCommand(_ *cobra.Command, args []str
 
 	initCmd.Printf("\nOpsani config initialized:\n")
 	initCmd.PrettyPrintYAMLObject(initCmd.GetAllSettings())
-	if !initCmd.confirmed {
-		prompt := &survey.Confirm{
-			Message: fmt.Sprintf("Write to %s?", configFile),
-		}
-		initCmd.AskOne(prompt, &initCmd.confirmed)
-	}
-	if initCmd.confirmed {
-		configDir := filepath.Dir(configFile)
-		if _, err := os.Stat(configDir); os.IsNotExist(err) {
-			err = os.Mkdir(configDir, 0755)
-			if err != nil {
-				return err
-			}
-		}
-		if err := initCmd.viperCfg.WriteConfigAs(configFile); err != nil {
-			return err
-		}
-		initCmd.Println("\nOpsani CLI initialized")
+	confirmed, err := initCmd.Confirm(fmt.Sprintf("Write to %s?", configFile), false)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return nil
+	}
+	if err := initCmd.writeConfig(configFile); err != nil {
+		return err
+	}
+	initCmd.Println("\nOpsani CLI initialized")
+
+	// Optionally continue into the demo
+	ignite, err := initCmd.offer(c, initIgniteArg, "Launch the interactive demo with `opsani ignite`?")
+	if err != nil {
+		return err
+	}
+	if ignite {
+		return initCmd.executeNewRootCommand([]string{"--config", configFile, "--" + KeyProfile, profile.Name, "ignite"})
 	}
 	initCmd.Println("\nBegin optimizing by working with an interactive demo via `opsani ignite`")
 	initCmd.Println("Or jump right in to connecting your app by running `opsani vital`")
 	return nil
 }
 
-// RunInitCommand initializes Opsani CLI config
-func (initCmd *initCommand) RunInitCommand(_ *cobra.Command, args []string) error {
-	// Handle reinitialization case
-	overwrite := false
-	configFile := initCmd.viperCfg.ConfigFileUsed()
-	// NOTE: On first launch with no config file, Viper returns ""
-	// because no config file was resolved. There's probably a cleaner solution...
-	if configFile == "" {
-		configFile = initCmd.DefaultConfigFile()
-	}
-	if _, err := os.Stat(configFile); !os.IsNotExist(err) && !initCmd.confirmed {
-		initCmd.Println("Using config from:", configFile)
-		initCmd.PrettyPrintYAMLObject(initCmd.GetAllSettings())
+// profileFromInitToken retrieves the profile settings associated with an init token
+func (initCmd *initCommand) profileFromInitToken(initToken string) (Profile, error) {
+	initCmd.Printf("Initializing with token: %s...\n", initToken)
 
-		prompt := &survey.Confirm{
-			Message: fmt.Sprintf("Existing config found. Overwrite %s?", configFile),
-		}
-		err := initCmd.AskOne(prompt, &overwrite)
-		if err != nil {
-			return err
-		}
-		if !overwrite {
-			return terminal.InterruptErr
-		}
+	var profile Profile
+	URL := fmt.Sprintf("http://localhost:5678/init/%s", initToken)
+	client := resty.New()
+	timeHTTPClient(client.GetClient())
+	resp, err := client.R().
+		SetResult(&profile).
+		Get(URL)
+	if err != nil {
+		return Profile{}, err
+	}
+	if !resp.IsSuccess() {
+		return Profile{}, fmt.Errorf("Failed initialization with token %q (%s)", initToken, resp.Body())
 	}
+	return profile, nil
+}
 
+// promptForCredentials asks for the optimizer and token not already given via flags, the environment, or config
+// All settings are asked for when overwriting an existing config. The base URL is asked for alongside the optimizer
+// unless given via flag or environment, defaulting to the Opsani API for users outside of single-tenant deployments.
+func (initCmd *initCommand) promptForCredentials(overwrite bool) (Profile, error) {
 	profile := Profile{
-		Name:      "default",
 		Optimizer: initCmd.Optimizer(),
 		Token:     initCmd.AccessToken(),
 		BaseURL:   initCmd.BaseURL(),
 	}
 	whiteBold := ansi.ColorCode("white+b")
+	promptForBaseURL := (overwrite || profile.Optimizer == "") && initCmd.baseURLFromFlagsOrEnv() == ""
 
 	if overwrite || profile.Optimizer == "" {
 		err := initCmd.AskOne(&survey.Input{
 			Message: "Opsani optimizer (e.g. domain.com/app):",
 			Default: profile.Optimizer,
-		}, &profile.Optimizer, survey.WithValidator(survey.Required))
+		}, &profile.Optimizer, survey.WithValidator(survey.ComposeValidators(survey.Required, optimizerValidator)))
 		if err != nil {
-			return err
+			return Profile{}, err
 		}
+		profile.Optimizer, _ = NormalizeOptimizer(profile.Optimizer)
 	} else {
+		optimizer, err := NormalizeOptimizer(profile.Optimizer)
+		if err != nil {
+			return Profile{}, err
+		}
+		profile.Optimizer = optimizer
 		initCmd.Printf("%si %sApp: %s%s%s%s\n", ansi.Blue, whiteBold, ansi.Reset, ansi.LightCyan, profile.Optimizer, ansi.Reset)
 	}
 
@@ -143,41 +190,59 @@ func (initCmd *initCommand) RunInitCommand(_ *cobra.Command, args []string) erro
 			Default: profile.Token,
 		}, &profile.Token, survey.WithValidator(survey.Required))
 		if err != nil {
-			return err
+			return Profile{}, err
 		}
 	} else {
-		initCmd.Printf("%si %sAPI Token: %s%s%s%s\n", ansi.Blue, whiteBold, ansi.Reset, ansi.LightCyan, profile.Token, ansi.Reset)
+		initCmd.Printf("%si %sAPI Token: %s%s%s%s\n", ansi.Blue, whiteBold, ansi.Reset, ansi.LightCyan, initCmd.Redact(profile.Token), ansi.Reset)
 	}
 
-	// Confirm that the user wants to write this config
-	if registry, err := NewProfileRegistry(initCmd.viperCfg); err != nil {
-		return err
-	} else {
-		registry.AddProfile(profile)
+	if promptForBaseURL {
+		err := initCmd.AskOne(&survey.Input{
+			Message: "Opsani API base URL:",
+			Default: profile.BaseURL,
+			Help:    "Change the base URL only for single-tenant or dedicated Opsani deployments",
+		}, &profile.BaseURL, survey.WithValidator(survey.ComposeValidators(survey.Required, baseURLValidator)))
+		if err != nil {
+			return Profile{}, err
+		}
+	} else if CustomBaseURL(profile.BaseURL) != "" {
+		initCmd.Printf("%si %sAPI: %s%s%s%s\n", ansi.Blue, whiteBold, ansi.Reset, ansi.LightCyan, profile.BaseURL, ansi.Reset)
 	}
+	return profile, nil
+}
 
-	initCmd.Printf("\nOpsani config initialized:\n")
-	initCmd.PrettyPrintYAMLObject(initCmd.GetAllSettings())
-	if !initCmd.confirmed {
-		prompt := &survey.Confirm{
-			Message: fmt.Sprintf("Write to %s?", configFile),
-		}
-		initCmd.AskOne(prompt, &initCmd.confirmed)
-	}
-	if initCmd.confirmed {
-		configDir := filepath.Dir(configFile)
-		if _, err := os.Stat(configDir); os.IsNotExist(err) {
-			err = os.Mkdir(configDir, 0755)
-			if err != nil {
-				return err
-			}
-		}
-		if err := initCmd.viperCfg.WriteConfigAs(configFile); err != nil {
+// baseURLValidator is a survey validator for base URL input
+func baseURLValidator(val interface{}) error {
+	if str, ok := val.(string); ok {
+		return ValidateBaseURL(str)
+	}
+	return nil
+}
+
+// offer returns true if an optional step of the wizard was requested by flag or accepted at a prompt
+// Optional steps are skipped without prompting when answering yes to all prompts or not running interactively
+func (initCmd *initCommand) offer(c *cobra.Command, flag string, message string) (bool, error) {
+	if accepted, _ := c.Flags().GetBool(flag); accepted {
+		return true, nil
+	}
+	if initCmd.AssumeYes() || !initCmd.Interactive() {
+		return false, nil
+	}
+	accepted := false
+	err := initCmd.AskOne(&survey.Confirm{Message: message}, &accepted)
+	return accepted, err
+}
+
+// writeConfig writes the config to the given file, creating its directory as necessary
+func (initCmd *initCommand) writeConfig(configFile string) error {
+	configDir := filepath.Dir(configFile)
+	if _, err := os.Stat(configDir); os.IsNotExist(err) {
+		err = os.Mkdir(configDir, 0755)
+		if err != nil {
 			return err
 		}
-		initCmd.Println("\nOpsani CLI initialized")
 	}
-	return nil
+	return initCmd.viperCfg.WriteConfigAs(configFile)
 }
 
 // NewInitCommand returns a new `opsani init` command instance
@@ -187,21 +252,24 @@ func NewInitCommand(baseCommand *BaseCommand) *cobra.Command {
 		Use:         "init [INIT_TOKEN]",
 		Annotations: map[string]string{"other": "true"},
 		Short:       "Initialize Opsani CLI configuration",
-		Long: `Initializes an Opsani config file and acquires the required settings:
-	
-    * 'optimizer':   Opsani app to control (OPSANI_OPTIMIZER).
-    * 'token': API token to authenticate with (OPSANI_TOKEN).
+		Long: `Initializes an Opsani config file by walking through the first run of the CLI:
+
+    * Credentials are taken from the INIT_TOKEN or prompted for:
+      'optimizer': Opsani app to control (OPSANI_OPTIMIZER).
+      'token': API token to authenticate with (OPSANI_TOKEN).
+    * A profile is created for the optimizer (named with --name).
+    * A servo can be attached to the profile (--attach-servo).
+    * The interactive demo can be launched once initialized (--ignite).
 	`,
 		Args:              cobra.MaximumNArgs(1),
 		PersistentPreRunE: initCmd.InitConfigRunE, // Skip loading the config file
-		RunE: func(c *cobra.Command, args []string) error {
-			if len(args) == 1 {
-				return initCmd.RunInitWithTokenCommand(c, args)
-			} else {
-				return initCmd.RunInitCommand(c, args)
-			}
-		},
-	}
-	cmd.Flags().BoolVar(&initCmd.confirmed, confirmedArg, false, "Write config without asking for confirmation")
+		RunE:              initCmd.RunInitCommand,
+	}
+	cmd.Flags().String(initNameArg, "default", "Name of the profile to create")
+	cmd.Flags().Bool(initAttachServoArg, false, "Attach a servo to the profile without asking")
+	cmd.Flags().Bool(initIgniteArg, false, "Launch the interactive demo once initialized without asking")
+	cmd.Flags().BoolVar(&baseCommand.assumeYes, confirmedArg, false, "Write config without asking for confirmation")
+	cmd.Flags().MarkDeprecated(confirmedArg, fmt.Sprintf("use --%s instead", KeyYes))
+	AddSkipVerifyFlag(cmd)
 	return cmd
 }
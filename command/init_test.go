@@ -17,6 +17,8 @@ package command_test
 import (
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 
@@ -86,11 +88,15 @@ func (s *InitTestSuite) TestTerminalConfirm() {
 func (s *InitTestSuite) TestInitWithExistingConfigDeclinedNoConfigFile() {
 	cfgName := "/tmp/this-will-never-exist.yaml"
 	os.Remove(cfgName)
-	_, err := s.ExecuteTestInteractively(test.Args("--config", cfgName, "init"), func(t *test.InteractiveTestContext) error {
+	_, err := s.ExecuteTestInteractively(test.Args("--config", cfgName, "init", "--skip-verify"), func(t *test.InteractiveTestContext) error {
 		t.ExpectMatch(expect.RegexpPattern("Opsani optimizer"))
 		t.SendLine("dev.opsani.com/amazing-app")
 		t.RequireMatch(expect.RegexpPattern("API Token"))
 		t.SendLine("123456")
+		t.RequireString("Opsani API base URL:")
+		t.SendLine("")
+		t.RequireString(`Attach a servo to profile "default"?`)
+		t.SendLine("N")
 		t.RequireMatch(expect.RegexpPattern(fmt.Sprintf("Write to %s?", cfgName)))
 		t.SendLine("N")
 		t.ExpectEOF()
@@ -109,7 +115,7 @@ func (s *InitTestSuite) TestInitWithExistingConfigDeclined() {
 		},
 	})
 
-	_, err := s.ExecuteTestInteractively(test.Args("--config", configFile.Name(), "init"), func(t *test.InteractiveTestContext) error {
+	_, err := s.ExecuteTestInteractively(test.Args("--config", configFile.Name(), "init", "--skip-verify"), func(t *test.InteractiveTestContext) error {
 		t.RequireStringf("Using config from: %s", configFile.Name())
 		t.RequireStringf("Existing config found. Overwrite %s?", configFile.Name())
 		t.SendLine("N")
@@ -134,7 +140,7 @@ func (s *InitTestSuite) TestInitWithExistingConfigAccepted() {
 		},
 	})
 
-	context, err := s.ExecuteTestInteractively(test.Args("--config", configFile.Name(), "init"), func(t *test.InteractiveTestContext) error {
+	context, err := s.ExecuteTestInteractively(test.Args("--config", configFile.Name(), "init", "--skip-verify"), func(t *test.InteractiveTestContext) error {
 		t.RequireStringf("Using config from: %s", configFile.Name())
 		t.RequireStringf("Existing config found. Overwrite %s?", configFile.Name())
 		t.SendLine("Y")
@@ -142,10 +148,17 @@ func (s *InitTestSuite) TestInitWithExistingConfigAccepted() {
 		t.SendLine("dev.opsani.com/amazing-app")
 		t.RequireMatch(expect.RegexpPattern("API Token"))
 		t.SendLine("123456")
+		t.RequireString("Opsani API base URL:")
+		t.SendLine("")
+		t.RequireString(`Attach a servo to profile "default"?`)
+		t.SendLine("N")
 		t.RequireMatch(expect.RegexpPattern(fmt.Sprintf("Write to %s?", configFile.Name())))
 
 		t.SendLine("Y")
 		t.RequireMatch(expect.RegexpPattern("Opsani CLI initialized"))
+		t.RequireString("Launch the interactive demo with `opsani ignite`?")
+		t.SendLine("N")
+		t.RequireString("Begin optimizing")
 		return nil
 	})
 	s.Require().NoError(err, context.OutputBuffer().String())
@@ -158,8 +171,152 @@ func (s *InitTestSuite) TestInitWithExistingConfigAccepted() {
 	yaml.Unmarshal(body, &config)
 	s.Require().Equal("dev.opsani.com/amazing-app", config.Profiles[1].Optimizer)
 	s.Require().Equal("123456", config.Profiles[1].Token)
+	s.Require().Equal(command.DefaultBaseURL, config.Profiles[1].BaseURL)
+}
+
+func (s *InitTestSuite) TestInitValidatesAndVerifiesOptimizer() {
+	paths := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	cfgName := "/tmp/opsani-init-verify.yaml"
+	os.Remove(cfgName)
+	defer os.Remove(cfgName)
+
+	_, err := s.ExecuteTestInteractively(test.Args("--config", cfgName, "--base-url", server.URL, "init"), func(t *test.InteractiveTestContext) error {
+		t.ExpectMatch(expect.RegexpPattern("Opsani optimizer"))
+		t.SendLine("amazing-app")
+		t.RequireString("missing the organization domain")
+		t.SendLine("https://dev.opsani.com/amazing-app/")
+		t.RequireMatch(expect.RegexpPattern("API Token"))
+		t.SendLine("123456")
+		t.RequireString("Verified optimizer dev.opsani.com/amazing-app")
+		t.RequireString(`Attach a servo to profile "default"?`)
+		t.SendLine("N")
+		t.RequireMatch(expect.RegexpPattern(fmt.Sprintf("Write to %s?", cfgName)))
+		t.SendLine("Y")
+		t.RequireMatch(expect.RegexpPattern("Opsani CLI initialized"))
+		t.RequireString("Launch the interactive demo with `opsani ignite`?")
+		t.SendLine("N")
+		t.RequireString("Begin optimizing")
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"/accounts/dev.opsani.com/applications/amazing-app/state", "/accounts/dev.opsani.com/applications/amazing-app/token"}, paths)
+
+	var config struct {
+		Profiles []command.Profile `yaml:"profiles"`
+	}
+	body, err := ioutil.ReadFile(cfgName)
+	s.Require().NoError(err)
+	s.Require().NoError(yaml.Unmarshal(body, &config))
+	s.Require().Equal("dev.opsani.com/amazing-app", config.Profiles[0].Optimizer)
+}
+
+func (s *InitTestSuite) TestInitPromptsForBaseURL() {
+	paths := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	cfgName := "/tmp/opsani-init-base-url.yaml"
+	os.Remove(cfgName)
+	defer os.Remove(cfgName)
+
+	_, err := s.ExecuteTestInteractively(test.Args("--config", cfgName, "init", "--yes"), func(t *test.InteractiveTestContext) error {
+		t.ExpectMatch(expect.RegexpPattern("Opsani optimizer"))
+		t.SendLine("dev.opsani.com/amazing-app")
+		t.RequireMatch(expect.RegexpPattern("API Token"))
+		t.SendLine("123456")
+		t.RequireString("Opsani API base URL:")
+		t.SendLine("api.cell-1.opsani.com")
+		t.RequireString("must be an http or https URL")
+		t.SendLine(server.URL)
+		t.RequireString("Verified optimizer dev.opsani.com/amazing-app")
+		t.RequireMatch(expect.RegexpPattern("Opsani CLI initialized"))
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Require().Contains(paths, "/accounts/dev.opsani.com/applications/amazing-app/state")
+
+	var config struct {
+		Profiles []command.Profile `yaml:"profiles"`
+	}
+	body, err := ioutil.ReadFile(cfgName)
+	s.Require().NoError(err)
+	s.Require().NoError(yaml.Unmarshal(body, &config))
+	s.Require().Equal(server.URL, config.Profiles[0].BaseURL)
+}
+
+func (s *InitTestSuite) TestInitRejectsInvalidBaseURL() {
+	_, err := s.Execute("--config", "/tmp/opsani-init-invalid.yaml", "--base-url", "cell-1", "--optimizer", "dev.opsani.com/amazing-app", "--token", "123456", "init", "--yes")
+	s.Require().EqualError(err, `invalid base URL "cell-1": must be an http or https URL such as https://api.opsani.com/`)
 }
 
 func (s *InitTestSuite) TestInitWithToken() {
 	s.T().Skip("Pending test for init with a token")
 }
+
+func (s *InitTestSuite) TestInitAttachesServo() {
+	cfgName := "/tmp/opsani-init-servo.yaml"
+	os.Remove(cfgName)
+	defer os.Remove(cfgName)
+
+	_, err := s.ExecuteTestInteractively(test.Args("--config", cfgName, "init", "--skip-verify", "--name", "staging", "--attach-servo"), func(t *test.InteractiveTestContext) error {
+		t.ExpectMatch(expect.RegexpPattern("Opsani optimizer"))
+		t.SendLine("dev.opsani.com/amazing-app")
+		t.RequireMatch(expect.RegexpPattern("API Token"))
+		t.SendLine("123456")
+		t.RequireString("Opsani API base URL:")
+		t.SendLine("")
+		t.RequireString("Select deployment:")
+		t.SendLine("")
+		t.RequireString("Namespace:")
+		t.SendLine("")
+		t.RequireString("Deployment:")
+		t.SendLine("")
+		t.RequireMatch(expect.RegexpPattern(fmt.Sprintf("Write to %s?", cfgName)))
+		t.SendLine("Y")
+		t.RequireMatch(expect.RegexpPattern("Opsani CLI initialized"))
+		t.RequireString("Launch the interactive demo with `opsani ignite`?")
+		t.SendLine("N")
+		t.RequireString("Begin optimizing")
+		return nil
+	})
+	s.Require().NoError(err)
+
+	var config struct {
+		Profiles []command.Profile `yaml:"profiles"`
+	}
+	body, err := ioutil.ReadFile(cfgName)
+	s.Require().NoError(err)
+	s.Require().NoError(yaml.Unmarshal(body, &config))
+	s.Require().Len(config.Profiles, 1)
+	s.Require().Equal("staging", config.Profiles[0].Name)
+	s.Require().Equal(command.Servo{Type: "kubernetes", Namespace: "opsani", Deployment: "servo"}, config.Profiles[0].Servo)
+}
+
+func (s *InitTestSuite) TestInitNonInteractiveSkipsOptionalSteps() {
+	cfgName := "/tmp/opsani-init-yes.yaml"
+	os.Remove(cfgName)
+	defer os.Remove(cfgName)
+
+	output, err := s.Execute("--config", cfgName, "--optimizer", "dev.opsani.com/amazing-app", "--token", "123456", "init", "--skip-verify", "--yes")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Opsani CLI initialized")
+	s.Require().Contains(output, "Begin optimizing by working with an interactive demo via `opsani ignite`")
+
+	var config struct {
+		Profiles []command.Profile `yaml:"profiles"`
+	}
+	body, err := ioutil.ReadFile(cfgName)
+	s.Require().NoError(err)
+	s.Require().NoError(yaml.Unmarshal(body, &config))
+	s.Require().Equal("default", config.Profiles[0].Name)
+	s.Require().Equal(command.Servo{}, config.Profiles[0].Servo)
+}
@@ -20,13 +20,12 @@ import (
 	"strings"
 
 	"github.com/go-resty/resty/v2"
-	"github.com/hokaccha/go-prettyjson"
 	"github.com/tidwall/sjson"
 )
 
 // PrettyPrintJSONObject prints the given object as pretty printed JSON
 func PrettyPrintJSONObject(obj interface{}) error {
-	s, err := prettyjson.Marshal(obj)
+	s, err := prettyJSONFormatter().Marshal(obj)
 	if err != nil {
 		return err
 	}
@@ -36,7 +35,7 @@ func PrettyPrintJSONObject(obj interface{}) error {
 
 // PrettyPrintJSONBytes prints the given byte array as pretty printed JSON
 func PrettyPrintJSONBytes(bytes []byte) error {
-	s, err := prettyjson.Format(bytes)
+	s, err := prettyJSONFormatter().Format(bytes)
 	if err != nil {
 		return err
 	}
@@ -61,7 +60,6 @@ func SetJSONKeyPathValuesFromStringsOnBytes(jsonPathDescriptors []string, bytes
 	var err error // declare err to avoid shadowing effects in the loop
 	for _, exp := range jsonPathDescriptors {
 		bytes, err = SetJSONKeyPathValuesFromStringOnBytes(exp, bytes)
-		PrettyPrintJSONBytes(bytes)
 		if err != nil {
 			return bytes, err
 		}
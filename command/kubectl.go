@@ -0,0 +1,45 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// KubectlRunner executes kubectl with the given arguments and returns its standard output
+type KubectlRunner func(ctx context.Context, args ...string) ([]byte, error)
+
+// kubectlRunner returns a runner executing kubectl against the given kubeconfig
+func kubectlRunner(kubeconfig string) KubectlRunner {
+	return func(ctx context.Context, args ...string) ([]byte, error) {
+		if kubeconfig != "" {
+			args = append([]string{"--kubeconfig", kubeconfig}, args...)
+		}
+		stderr := new(bytes.Buffer)
+		cmd := commandContext(ctx, "kubectl", args...)
+		cmd.Stderr = stderr
+		output, err := commandOutput(cmd)
+		if err != nil {
+			if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+				return output, fmt.Errorf("kubectl %s: %w: %s", strings.Join(args, " "), err, msg)
+			}
+			return output, fmt.Errorf("kubectl %s: %w", strings.Join(args, " "), err)
+		}
+		return output, nil
+	}
+}
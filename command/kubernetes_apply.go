@@ -0,0 +1,263 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// KubernetesFieldManager is the field manager that owns the fields set by the CLI with server-side apply
+const KubernetesFieldManager = "opsani-cli"
+
+// DefaultKubernetesPollInterval is the delay between checks while waiting on the cluster
+const DefaultKubernetesPollInterval = 2 * time.Second
+
+var (
+	customResourceDefinitionKind     = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+	customResourceDefinitionResource = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	podResource                      = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	deploymentResource               = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+)
+
+// KubernetesClient applies manifests to a cluster, queries its objects, and waits on them without kubectl
+type KubernetesClient struct {
+	client    dynamic.Interface
+	mapper    meta.RESTMapper
+	discovery discovery.DiscoveryInterface
+
+	// PollInterval is the delay between checks while waiting on discovery or for watches to be available
+	PollInterval time.Duration
+}
+
+// NewKubernetesClient returns a client using the given dynamic client and REST mapper
+// Mappers that can be reset, such as discovery based mappers, are reset to find newly established
+// custom resource definitions
+func NewKubernetesClient(client dynamic.Interface, mapper meta.RESTMapper) *KubernetesClient {
+	return &KubernetesClient{client: client, mapper: mapper, PollInterval: DefaultKubernetesPollInterval}
+}
+
+// NewKubernetesClientWithDiscovery returns a client that maps resources and reports API versions
+// using the given discovery client
+func NewKubernetesClientWithDiscovery(client dynamic.Interface, discoveryClient discovery.DiscoveryInterface) *KubernetesClient {
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+	kubernetes := NewKubernetesClient(client, mapper)
+	kubernetes.discovery = discoveryClient
+	return kubernetes
+}
+
+// NewKubernetesClientForContext returns a client for a context of a kubeconfig
+// An empty kubeconfig is located as kubectl does and an empty context selects the current context
+func NewKubernetesClientForContext(kubeconfig, context string) (*KubernetesClient, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		rules,
+		&clientcmd.ConfigOverrides{CurrentContext: context},
+	).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed loading kubeconfig: %w", err)
+	}
+	timeHTTPClientConfig(config)
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewKubernetesClientWithDiscovery(client, discoveryClient), nil
+}
+
+// KubernetesClientFactory creates a client for a context of a kubeconfig
+type KubernetesClientFactory func(kubeconfig, context string) (*KubernetesClient, error)
+
+var kubernetesClientFactory KubernetesClientFactory = NewKubernetesClientForContext
+
+// SetKubernetesClientFactory is a package helper for testing that replaces the clients used by commands
+// Passing nil restores the default of NewKubernetesClientForContext
+func SetKubernetesClientFactory(factory KubernetesClientFactory) {
+	if factory == nil {
+		factory = NewKubernetesClientForContext
+	}
+	kubernetesClientFactory = factory
+}
+
+// Apply creates or updates the objects of a YAML or JSON manifest with server-side apply
+// Applied custom resource definitions are waited on before the next object is applied and objects of the
+// kinds they define wait for the kinds to be served. Objects of other kinds unknown to the cluster fail
+// without waiting. A line is written to w for each object applied
+func (c *KubernetesClient) Apply(ctx context.Context, manifest []byte, w io.Writer) error {
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(manifest), 4096)
+	definedKinds := map[schema.GroupKind]bool{}
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("invalid manifest: %w", err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+
+		gvk := obj.GroupVersionKind()
+		mapping, err := c.waitForMapping(ctx, gvk, definedKinds[gvk.GroupKind()])
+		if err != nil {
+			return err
+		}
+		data, err := obj.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		resource := c.resourceInterface(mapping, obj.GetNamespace())
+		force := true
+		_, err = resource.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+			FieldManager: KubernetesFieldManager,
+			Force:        &force,
+		})
+		if err != nil {
+			return fmt.Errorf("failed applying %s %q: %w", gvk.Kind, obj.GetName(), err)
+		}
+		fmt.Fprintf(w, "%s/%s serverside-applied\n", mapping.Resource.GroupResource(), obj.GetName())
+
+		if gvk.GroupKind() == customResourceDefinitionKind {
+			group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+			kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+			definedKinds[schema.GroupKind{Group: group, Kind: kind}] = true
+			if err := c.waitForEstablished(ctx, mapping, obj.GetName()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// RestartDeployment triggers a rollout of a deployment by updating its pod template, as done by
+// `kubectl rollout restart`
+func (c *KubernetesClient) RestartDeployment(ctx context.Context, namespace, name string) error {
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":%q}}}}}`,
+		time.Now().Format(time.RFC3339))
+	_, err := c.client.Resource(deploymentResource).Namespace(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{
+		FieldManager: KubernetesFieldManager,
+	})
+	if err != nil {
+		return fmt.Errorf("failed restarting deployment %q: %w", name, err)
+	}
+	return nil
+}
+
+// SetDeploymentImage updates the image of a container of a deployment, as done by `kubectl set image`
+func (c *KubernetesClient) SetDeploymentImage(ctx context.Context, namespace, name, container, image string) error {
+	patch := fmt.Sprintf(`{"spec":{"template":{"spec":{"containers":[{"name":%q,"image":%q}]}}}}`, container, image)
+	_, err := c.client.Resource(deploymentResource).Namespace(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{
+		FieldManager: KubernetesFieldManager,
+	})
+	if err != nil {
+		return fmt.Errorf("failed setting image of deployment %q: %w", name, err)
+	}
+	return nil
+}
+
+func (c *KubernetesClient) resourceInterface(mapping *meta.RESTMapping, namespace string) dynamic.ResourceInterface {
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return c.client.Resource(mapping.Resource)
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	return c.client.Resource(mapping.Resource).Namespace(namespace)
+}
+
+// waitForMapping returns the resource serving a kind
+// Kinds of pending custom resource definitions are waited on until discovery finds them, while other
+// kinds are looked up again only once in case the cached discovery is stale
+func (c *KubernetesClient) waitForMapping(ctx context.Context, gvk schema.GroupVersionKind, pending bool) (*meta.RESTMapping, error) {
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		c.resetMapper()
+		mapping, err = c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if meta.IsNoMatchError(err) && pending {
+		err = c.poll(ctx, fmt.Sprintf("custom resource definition of %s", gvk.Kind), func() (bool, error) {
+			var err error
+			mapping, err = c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+			if meta.IsNoMatchError(err) {
+				c.resetMapper()
+				return false, nil
+			}
+			return err == nil, err
+		})
+	}
+	if meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("kind %s of %s is not served by the cluster: install its custom resource definition first", gvk.Kind, gvk.GroupVersion())
+	}
+	return mapping, err
+}
+
+// waitForEstablished waits until an applied custom resource definition is established and can be used
+func (c *KubernetesClient) waitForEstablished(ctx context.Context, mapping *meta.RESTMapping, name string) error {
+	err := c.WaitFor(ctx, mapping.Resource, "", name, CustomResourceDefinitionEstablished, nil)
+	c.resetMapper()
+	return err
+}
+
+// poll calls the condition until it is done or fails, giving up when the context is done
+func (c *KubernetesClient) poll(ctx context.Context, description string, condition func() (bool, error)) error {
+	for {
+		done, err := condition()
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed waiting for %s: %w", description, ctx.Err())
+		case <-time.After(c.PollInterval):
+		}
+	}
+}
+
+func (c *KubernetesClient) resetMapper() {
+	if resettable, ok := c.mapper.(interface{ Reset() }); ok {
+		resettable.Reset()
+	}
+}
+
+// hasTrueCondition reports whether the status of an object has a condition of the given type that is true
+func hasTrueCondition(obj *unstructured.Unstructured, conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == conditionType && condition["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const applyManifest = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: servo
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: servo
+rules: []
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: prometheuses.monitoring.coreos.com
+`
+
+// resettableRESTMapper is a REST mapper that learns a kind on reset, as discovery finds a newly established CRD
+type resettableRESTMapper struct {
+	*meta.DefaultRESTMapper
+	resets  int
+	pending []schema.GroupVersionKind
+
+	// learnAfter is the number of resets before the pending kinds are learned
+	learnAfter int
+}
+
+func (m *resettableRESTMapper) Reset() {
+	m.resets++
+	if m.resets < m.learnAfter {
+		return
+	}
+	for _, gvk := range m.pending {
+		m.Add(gvk, meta.RESTScopeNamespace)
+	}
+	m.pending = nil
+}
+
+func newTestRESTMapper() *resettableRESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}, meta.RESTScopeRoot)
+	return &resettableRESTMapper{DefaultRESTMapper: mapper}
+}
+
+func establishedCRD(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": name},
+		"status": map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Established", "status": "True"}},
+		},
+	}}
+}
+
+func TestKubernetesClientApply(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), establishedCRD("prometheuses.monitoring.coreos.com"))
+	patches := []k8stesting.PatchActionImpl{}
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchActionImpl)
+		patches = append(patches, patch)
+		obj := &unstructured.Unstructured{}
+		return true, obj, json.Unmarshal(patch.GetPatch(), &obj.Object)
+	})
+	mapper := newTestRESTMapper()
+
+	output := new(bytes.Buffer)
+	err := command.NewKubernetesClient(client, mapper).Apply(context.Background(), []byte(applyManifest), output)
+	require.NoError(t, err)
+	require.Equal(t, "serviceaccounts/servo serverside-applied\n"+
+		"clusterroles.rbac.authorization.k8s.io/servo serverside-applied\n"+
+		"customresourcedefinitions.apiextensions.k8s.io/prometheuses.monitoring.coreos.com serverside-applied\n", output.String())
+
+	require.Len(t, patches, 3)
+	require.Equal(t, types.ApplyPatchType, patches[0].GetPatchType())
+	require.Equal(t, "default", patches[0].GetNamespace())
+	require.Equal(t, "", patches[1].GetNamespace())
+	require.Equal(t, "servo", patches[1].GetName())
+
+	// The mapper is reset once the CRD is established so that its resources can be applied
+	require.Equal(t, 1, mapper.resets)
+}
+
+// customResourceDefinition returns a manifest of an established custom resource definition
+func customResourceDefinition(plural, group, kind string) string {
+	return fmt.Sprintf(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: %s.%s
+spec:
+  group: %s
+  names:
+    kind: %s
+    plural: %s
+`, plural, group, group, kind, plural)
+}
+
+func TestKubernetesClientApplyWaitsForCustomResourceDefinition(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), establishedCRD("prometheuses.monitoring.coreos.com"), establishedCRD("widgets.example.com"))
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &unstructured.Unstructured{}, nil
+	})
+	mapper := newTestRESTMapper()
+	prometheus := schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "Prometheus"}
+	mapper.pending = []schema.GroupVersionKind{prometheus}
+	mapper.learnAfter = 3
+
+	kubernetes := command.NewKubernetesClient(client, mapper)
+	kubernetes.PollInterval = time.Millisecond
+	manifest := customResourceDefinition("prometheuses", "monitoring.coreos.com", "Prometheus") +
+		"---\napiVersion: monitoring.coreos.com/v1\nkind: Prometheus\nmetadata:\n  name: prometheus\n"
+	output := new(bytes.Buffer)
+	require.NoError(t, kubernetes.Apply(context.Background(), []byte(manifest), output))
+	require.Equal(t, "customresourcedefinitions.apiextensions.k8s.io/prometheuses.monitoring.coreos.com serverside-applied\n"+
+		"prometheuses.monitoring.coreos.com/prometheus serverside-applied\n", output.String())
+	require.Equal(t, 3, mapper.resets)
+
+	// Kinds defined in the manifest that are never served time out
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	widget := customResourceDefinition("widgets", "example.com", "Widget") +
+		"---\napiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: widget\n"
+	err := kubernetes.Apply(ctx, []byte(widget), output)
+	require.EqualError(t, err, "failed waiting for custom resource definition of Widget: context deadline exceeded")
+}
+
+func TestKubernetesClientApplyUnknownKindFailsFast(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	mapper := newTestRESTMapper()
+	kubernetes := command.NewKubernetesClient(client, mapper)
+	kubernetes.PollInterval = time.Hour
+
+	widget := "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: widget\n"
+	err := kubernetes.Apply(context.Background(), []byte(widget), new(bytes.Buffer))
+	require.EqualError(t, err, "kind Widget of example.com/v1 is not served by the cluster: install its custom resource definition first")
+	require.Equal(t, 1, mapper.resets)
+	require.Empty(t, client.Actions())
+}
+
+func TestKubernetesClientRestartDeployment(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	var patch k8stesting.PatchActionImpl
+	client.PrependReactor("patch", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch = action.(k8stesting.PatchActionImpl)
+		return true, &unstructured.Unstructured{}, nil
+	})
+	require.NoError(t, command.NewKubernetesClient(client, newTestRESTMapper()).RestartDeployment(context.Background(), "default", "servo"))
+	require.Equal(t, "servo", patch.GetName())
+	require.Equal(t, types.StrategicMergePatchType, patch.GetPatchType())
+	require.Contains(t, string(patch.GetPatch()), `"kubectl.kubernetes.io/restartedAt"`)
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	namespaceResource               = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	serviceResource                 = schema.GroupVersionResource{Version: "v1", Resource: "services"}
+	statefulSetResource             = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}
+	daemonSetResource               = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}
+	rolloutResource                 = schema.GroupVersionResource{Group: "argoproj.io", Resource: "rollouts"}
+	podDisruptionBudgetResource     = schema.GroupVersionResource{Group: "policy", Resource: "poddisruptionbudgets"}
+	podSecurityPolicyResource       = schema.GroupVersionResource{Group: "policy", Version: "v1beta1", Resource: "podsecuritypolicies"}
+	horizontalPodAutoscalerResource = schema.GroupVersionResource{Group: "autoscaling", Resource: "horizontalpodautoscalers"}
+	podMetricsResource              = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+	selfSubjectAccessReviewResource = schema.GroupVersionResource{Group: "authorization.k8s.io", Version: "v1", Resource: "selfsubjectaccessreviews"}
+)
+
+// List returns the objects of a resource matching the label selector as a JSON list, as `kubectl get -o json` does
+// Objects in all namespaces are listed when the namespace is empty. Resources given without a version are
+// listed at the version preferred by the cluster
+func (c *KubernetesClient) List(ctx context.Context, resource schema.GroupVersionResource, namespace string, selector map[string]string) ([]byte, error) {
+	ri, err := c.namespacedResource(resource, namespace)
+	if err != nil {
+		return nil, err
+	}
+	list, err := ri.List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(selector).String()})
+	if err != nil {
+		return nil, err
+	}
+	return list.MarshalJSON()
+}
+
+// Get returns an object as JSON, as `kubectl get -o json` does
+func (c *KubernetesClient) Get(ctx context.Context, resource schema.GroupVersionResource, namespace, name string) ([]byte, error) {
+	ri, err := c.namespacedResource(resource, namespace)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := ri.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return obj.MarshalJSON()
+}
+
+// APIVersions returns the group versions served by the cluster, as listed by `kubectl api-versions`
+func (c *KubernetesClient) APIVersions() ([]string, error) {
+	if c.discovery == nil {
+		return nil, fmt.Errorf("API discovery is not available")
+	}
+	groups, err := c.discovery.ServerGroups()
+	if err != nil {
+		return nil, err
+	}
+	versions := []string{}
+	for _, group := range groups.Groups {
+		for _, version := range group.Versions {
+			versions = append(versions, version.GroupVersion)
+		}
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// CanI reports whether the current user is permitted to perform the verb on the resource, as `kubectl auth can-i` does
+// Cluster wide permission is checked when the namespace is empty
+func (c *KubernetesClient) CanI(ctx context.Context, verb string, resource schema.GroupResource, namespace string) (bool, error) {
+	review := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "authorization.k8s.io/v1",
+		"kind":       "SelfSubjectAccessReview",
+		"spec": map[string]interface{}{
+			"resourceAttributes": map[string]interface{}{
+				"verb":      verb,
+				"group":     resource.Group,
+				"resource":  resource.Resource,
+				"namespace": namespace,
+			},
+		},
+	}}
+	result, err := c.client.Resource(selfSubjectAccessReviewResource).Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed reviewing access to %s: %w", resource, err)
+	}
+	allowed, _, _ := unstructured.NestedBool(result.Object, "status", "allowed")
+	return allowed, nil
+}
+
+// namespacedResource returns the interface for a resource in the namespace, or in all namespaces when it is empty
+func (c *KubernetesClient) namespacedResource(resource schema.GroupVersionResource, namespace string) (dynamic.ResourceInterface, error) {
+	if resource.Version == "" {
+		var err error
+		if resource, err = c.mapper.ResourceFor(resource); err != nil {
+			return nil, err
+		}
+	}
+	if namespace == "" {
+		return c.client.Resource(resource), nil
+	}
+	return c.client.Resource(resource).Namespace(namespace), nil
+}
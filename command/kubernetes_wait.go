@@ -0,0 +1,197 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// KeyWaitTimeout is the flag that limits how long to wait for Kubernetes resources to become ready
+const KeyWaitTimeout = "wait-timeout"
+
+// DefaultWaitTimeout is the default duration to wait for Kubernetes resources to become ready
+const DefaultWaitTimeout = 5 * time.Minute
+
+// AddWaitTimeoutFlag registers the --wait-timeout flag on the given command
+func AddWaitTimeoutFlag(cmd *cobra.Command) {
+	cmd.Flags().Duration(KeyWaitTimeout, DefaultWaitTimeout, "Maximum duration to wait for Kubernetes resources to become ready")
+}
+
+// waitContext returns a context that expires after the wait timeout of the command
+func waitContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	timeout := DefaultWaitTimeout
+	if flag := cmd.Flags().Lookup(KeyWaitTimeout); flag != nil {
+		timeout, _ = cmd.Flags().GetDuration(KeyWaitTimeout)
+	}
+	return contextWithTimeout(timeout)
+}
+
+// ReadinessCondition reports whether an object is ready along with a short description of its state
+type ReadinessCondition func(obj *unstructured.Unstructured) (ready bool, status string)
+
+// ProgressFunc receives updates on the state of a long running operation
+type ProgressFunc func(status string)
+
+// PodReady is satisfied when a pod reports the Ready condition
+func PodReady(obj *unstructured.Unstructured) (bool, string) {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	if phase == "" {
+		phase = "Pending"
+	}
+	statuses, _, _ := unstructured.NestedSlice(obj.Object, "status", "containerStatuses")
+	ready := 0
+	for _, s := range statuses {
+		if status, ok := s.(map[string]interface{}); ok && status["ready"] == true {
+			ready++
+		}
+	}
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "containers")
+	return hasTrueCondition(obj, "Ready"), fmt.Sprintf("%s, %d/%d containers ready", phase, ready, len(containers))
+}
+
+// DeploymentAvailable is satisfied when a deployment has rolled out and reports the Available condition
+func DeploymentAvailable(obj *unstructured.Unstructured) (bool, string) {
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+	updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedReplicas")
+	available, _, _ := unstructured.NestedInt64(obj.Object, "status", "availableReplicas")
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	rolledOut := observed >= obj.GetGeneration() && updated >= replicas && available >= replicas
+	return rolledOut && hasTrueCondition(obj, "Available"), fmt.Sprintf("%d/%d replicas available", available, replicas)
+}
+
+// CustomResourceDefinitionEstablished is satisfied when a custom resource definition is established and can be used
+func CustomResourceDefinitionEstablished(obj *unstructured.Unstructured) (bool, string) {
+	if hasTrueCondition(obj, "Established") {
+		return true, "established"
+	}
+	return false, "not yet established"
+}
+
+// WaitFor watches an object until the condition is satisfied, reporting changes in its state to progress
+// Objects that do not exist yet are waited on until they are created. The wait ends when the context is done
+func (c *KubernetesClient) WaitFor(ctx context.Context, resource schema.GroupVersionResource, namespace, name string,
+	condition ReadinessCondition, progress ProgressFunc) error {
+	var ri dynamic.ResourceInterface = c.client.Resource(resource)
+	if namespace != "" {
+		ri = c.client.Resource(resource).Namespace(namespace)
+	}
+	description := fmt.Sprintf("%s/%s", resource.GroupResource(), name)
+	lastStatus := ""
+	report := func(status string) {
+		if progress != nil && status != lastStatus {
+			progress(status)
+		}
+		lastStatus = status
+	}
+	check := func(obj *unstructured.Unstructured) bool {
+		ready, status := condition(obj)
+		report(status)
+		return ready
+	}
+
+	for {
+		resourceVersion := ""
+		obj, err := ri.Get(ctx, name, metav1.GetOptions{})
+		if err == nil {
+			if check(obj) {
+				return nil
+			}
+			resourceVersion = obj.GetResourceVersion()
+		} else if apierrors.IsNotFound(err) {
+			report("waiting to be created")
+		} else if ctx.Err() == nil {
+			return fmt.Errorf("failed getting %s: %w", description, err)
+		}
+
+		watcher, err := ri.Watch(ctx, metav1.ListOptions{
+			FieldSelector:   fields.OneTermEqualSelector("metadata.name", name).String(),
+			ResourceVersion: resourceVersion,
+		})
+		if err != nil {
+			// Fall back to polling when the watch cannot be established
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("failed waiting for %s (%s): %w", description, lastStatus, ctx.Err())
+			case <-time.After(c.PollInterval):
+			}
+			continue
+		}
+		ready, err := c.watchUntil(ctx, watcher, name, check)
+		watcher.Stop()
+		if ready {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed waiting for %s (%s): %w", description, lastStatus, err)
+		}
+		// The watch expired and is resumed from the current state of the object
+	}
+}
+
+// watchUntil consumes watch events until the object satisfies the check, the watch closes, or the context is done
+func (c *KubernetesClient) watchUntil(ctx context.Context, watcher watch.Interface, name string, check func(*unstructured.Unstructured) bool) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return false, nil
+			}
+			obj, ok := event.Object.(*unstructured.Unstructured)
+			if !ok || obj.GetName() != name {
+				continue
+			}
+			switch event.Type {
+			case watch.Added, watch.Modified:
+				if check(obj) {
+					return true, nil
+				}
+			}
+		}
+	}
+}
+
+// WaitForPodReady waits until a pod reports the Ready condition
+func (c *KubernetesClient) WaitForPodReady(ctx context.Context, namespace, name string, progress ProgressFunc) error {
+	return c.WaitFor(ctx, podResource, namespace, name, PodReady, progress)
+}
+
+// WaitForDeploymentAvailable waits until a deployment has rolled out and is available
+func (c *KubernetesClient) WaitForDeploymentAvailable(ctx context.Context, namespace, name string, progress ProgressFunc) error {
+	return c.WaitFor(ctx, deploymentResource, namespace, name, DeploymentAvailable, progress)
+}
+
+// WaitForCustomResourceDefinition waits until a custom resource definition is established, such as one
+// installed by an operator, so that its resources can be applied
+func (c *KubernetesClient) WaitForCustomResourceDefinition(ctx context.Context, name string, progress ProgressFunc) error {
+	err := c.WaitFor(ctx, customResourceDefinitionResource, "", name, CustomResourceDefinitionEstablished, progress)
+	c.resetMapper()
+	return err
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testPod(phase string, ready bool) *unstructured.Unstructured {
+	readyStatus := "False"
+	if ready {
+		readyStatus = "True"
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "prometheus-prometheus-0", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"name": "prometheus"}},
+		},
+		"status": map[string]interface{}{
+			"phase":             phase,
+			"conditions":        []interface{}{map[string]interface{}{"type": "Ready", "status": readyStatus}},
+			"containerStatuses": []interface{}{map[string]interface{}{"name": "prometheus", "ready": ready}},
+		},
+	}}
+}
+
+func testDeployment(replicas, updated, available int64, availableCondition string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "servo", "namespace": "default", "generation": int64(2)},
+		"spec":       map[string]interface{}{"replicas": replicas},
+		"status": map[string]interface{}{
+			"observedGeneration": int64(2),
+			"updatedReplicas":    updated,
+			"availableReplicas":  available,
+			"conditions":         []interface{}{map[string]interface{}{"type": "Available", "status": availableCondition}},
+		},
+	}}
+}
+
+func TestPodReady(t *testing.T) {
+	ready, status := command.PodReady(testPod("Running", true))
+	require.True(t, ready)
+	require.Equal(t, "Running, 1/1 containers ready", status)
+
+	ready, status = command.PodReady(testPod("", false))
+	require.False(t, ready)
+	require.Equal(t, "Pending, 0/1 containers ready", status)
+}
+
+func TestDeploymentAvailable(t *testing.T) {
+	ready, status := command.DeploymentAvailable(testDeployment(2, 2, 2, "True"))
+	require.True(t, ready)
+	require.Equal(t, "2/2 replicas available", status)
+
+	// Replicas of the previous rollout are still available while the new ones start
+	ready, status = command.DeploymentAvailable(testDeployment(2, 1, 2, "True"))
+	require.False(t, ready)
+	require.Equal(t, "2/2 replicas available", status)
+
+	ready, status = command.DeploymentAvailable(testDeployment(1, 1, 0, "False"))
+	require.False(t, ready)
+	require.Equal(t, "0/1 replicas available", status)
+}
+
+func TestCustomResourceDefinitionEstablished(t *testing.T) {
+	ready, status := command.CustomResourceDefinitionEstablished(establishedCRD("prometheuses.monitoring.coreos.com"))
+	require.True(t, ready)
+	require.Equal(t, "established", status)
+
+	ready, status = command.CustomResourceDefinitionEstablished(&unstructured.Unstructured{Object: map[string]interface{}{}})
+	require.False(t, ready)
+	require.Equal(t, "not yet established", status)
+}
+
+func TestKubernetesClientWaitForPodReady(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), testPod("Pending", false))
+	watcher := watch.NewFake()
+	client.PrependWatchReactor("pods", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		return true, watcher, nil
+	})
+	go func() {
+		watcher.Modify(testPod("Running", false))
+		watcher.Modify(testPod("Running", false))
+		watcher.Modify(testPod("Running", true))
+	}()
+
+	statuses := []string{}
+	err := command.NewKubernetesClient(client, newTestRESTMapper()).WaitForPodReady(context.Background(), "default", "prometheus-prometheus-0", func(status string) {
+		statuses = append(statuses, status)
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Pending, 0/1 containers ready", "Running, 0/1 containers ready", "Running, 1/1 containers ready"}, statuses)
+}
+
+func TestKubernetesClientWaitForDeploymentToBeCreated(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	watcher := watch.NewFake()
+	client.PrependWatchReactor("deployments", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		return true, watcher, nil
+	})
+	go watcher.Add(testDeployment(1, 1, 1, "True"))
+
+	statuses := []string{}
+	err := command.NewKubernetesClient(client, newTestRESTMapper()).WaitForDeploymentAvailable(context.Background(), "default", "servo", func(status string) {
+		statuses = append(statuses, status)
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"waiting to be created", "1/1 replicas available"}, statuses)
+}
+
+func TestKubernetesClientWaitForTimesOut(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	kubernetes := command.NewKubernetesClient(client, newTestRESTMapper())
+	kubernetes.PollInterval = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := kubernetes.WaitForPodReady(ctx, "default", "missing", nil)
+	require.EqualError(t, err, "failed waiting for pods/missing (waiting to be created): context deadline exceeded")
+}
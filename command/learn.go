@@ -0,0 +1,364 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/markbates/pkger"
+	"github.com/spf13/cobra"
+)
+
+// KeyLearnURL is the configuration key for the docs endpoint that learn topics are refreshed from
+const KeyLearnURL = "learn.url"
+
+// Sources of learn topics
+const (
+	LearnSourceEmbedded = "embedded"
+	LearnSourceRemote   = "remote"
+)
+
+// learnTopicNamePattern restricts topic names to safe file names
+var learnTopicNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// reservedLearnTopicNames are the subcommands of `opsani learn` that would shadow topics of the same name
+var reservedLearnTopicNames = []string{"list", "refresh"}
+
+// validLearnTopicName reports whether the name is a safe file name that is not reserved for a subcommand
+func validLearnTopicName(name string) bool {
+	for _, reserved := range reservedLearnTopicNames {
+		if name == reserved {
+			return false
+		}
+	}
+	return learnTopicNamePattern.MatchString(name)
+}
+
+// LearnTopic is a markdown document displayed by `opsani learn`
+// The content is a text/template rendered with LearnTopicData
+type LearnTopic struct {
+	Name    string `json:"name"`
+	Title   string `json:"title"`
+	Source  string `json:"source"`
+	Content string `json:"-"`
+}
+
+// LearnTopicData is the template context for rendering learn topics
+type LearnTopicData struct {
+	// ServoConfigMap is the path of the servo ConfigMap manifest written by Ignite
+	ServoConfigMap string
+}
+
+// Render returns the markdown of the topic
+func (topic LearnTopic) Render(data LearnTopicData) (string, error) {
+	tmpl, err := template.New(topic.Name).Parse(topic.Content)
+	if err != nil {
+		return "", fmt.Errorf("invalid learn topic %q: %w", topic.Name, err)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid learn topic %q: %w", topic.Name, err)
+	}
+	return b.String(), nil
+}
+
+// newLearnTopic returns a topic titled by the first heading of its markdown
+func newLearnTopic(name, source, content string) LearnTopic {
+	topic := LearnTopic{Name: name, Title: name, Source: source, Content: content}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			topic.Title = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+			break
+		}
+	}
+	return topic
+}
+
+// LearnRegistry is the collection of learn topics available to the CLI
+type LearnRegistry struct {
+	topics map[string]LearnTopic
+}
+
+// LoadLearnRegistry returns the embedded topics overlaid with the topics cached by `opsani learn refresh`
+func LoadLearnRegistry(cacheDir string) (*LearnRegistry, error) {
+	registry := &LearnRegistry{topics: map[string]LearnTopic{}}
+	err := pkger.Walk("/docs/learn", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(info.Name(), ".md")
+		if info.IsDir() || filepath.Ext(info.Name()) != ".md" || !validLearnTopicName(name) {
+			return nil
+		}
+		data, err := readEmbeddedFile(embeddedPath(path))
+		if err != nil {
+			return err
+		}
+		registry.topics[name] = newLearnTopic(name, LearnSourceEmbedded, string(data))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(cacheDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".md")
+		if file.IsDir() || filepath.Ext(file.Name()) != ".md" || !validLearnTopicName(name) {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(cacheDir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		registry.topics[name] = newLearnTopic(name, LearnSourceRemote, string(data))
+	}
+	return registry, nil
+}
+
+// Topics returns the topics sorted by name
+func (r *LearnRegistry) Topics() []LearnTopic {
+	topics := []LearnTopic{}
+	for _, topic := range r.topics {
+		topics = append(topics, topic)
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].Name < topics[j].Name })
+	return topics
+}
+
+// Names returns the topic names sorted alphabetically
+func (r *LearnRegistry) Names() []string {
+	names := []string{}
+	for _, topic := range r.Topics() {
+		names = append(names, topic.Name)
+	}
+	return names
+}
+
+// Topic returns the topic with the given name
+func (r *LearnRegistry) Topic(name string) (LearnTopic, error) {
+	topic, ok := r.topics[name]
+	if !ok {
+		return topic, fmt.Errorf("unknown learn topic %q: must be one of %s", name, strings.Join(r.Names(), ", "))
+	}
+	return topic, nil
+}
+
+// RefreshLearnTopics downloads the topics listed in the index.json of the docs endpoint into the cache directory
+// The index is a JSON array of topic names, each served as markdown at <url>/<name>.md
+func RefreshLearnTopics(client *http.Client, docsURL string, cacheDir string) ([]string, error) {
+	docsURL = strings.TrimSuffix(docsURL, "/")
+	index, err := fetchLearnDocument(client, docsURL+"/index.json")
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	if err := json.Unmarshal(index, &names); err != nil {
+		return nil, fmt.Errorf("invalid learn index %s/index.json: %w", docsURL, err)
+	}
+	topics := map[string][]byte{}
+	for _, name := range names {
+		if !validLearnTopicName(name) {
+			return nil, fmt.Errorf("invalid learn topic name %q in %s/index.json", name, docsURL)
+		}
+		content, err := fetchLearnDocument(client, docsURL+"/"+name+".md")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := newLearnTopic(name, LearnSourceRemote, string(content)).Render(LearnTopicData{}); err != nil {
+			return nil, err
+		}
+		topics[name] = content
+	}
+
+	// Replace the cache only once every topic has been downloaded
+	if err := os.RemoveAll(cacheDir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, err
+	}
+	for name, content := range topics {
+		if err := ioutil.WriteFile(filepath.Join(cacheDir, name+".md"), content, 0644); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func fetchLearnDocument(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// LearnCacheDir returns the directory of the topics downloaded by `opsani learn refresh`
+func (baseCmd *BaseCommand) LearnCacheDir() string {
+	return baseCmd.statePath("learn")
+}
+
+// LearnRegistry returns the embedded and refreshed learn topics
+func (baseCmd *BaseCommand) LearnRegistry() (*LearnRegistry, error) {
+	return LoadLearnRegistry(baseCmd.LearnCacheDir())
+}
+
+// DisplayLearnTopic renders a learn topic as paged markdown
+func (vitalCommand *vitalCommand) DisplayLearnTopic(name string) error {
+	registry, err := vitalCommand.LearnRegistry()
+	if err != nil {
+		return err
+	}
+	topic, err := registry.Topic(name)
+	if err != nil {
+		return err
+	}
+	markdown, err := topic.Render(LearnTopicData{
+		ServoConfigMap: filepath.Join(vitalCommand.igniteManifestsDir(), "servo-configmap.yaml"),
+	})
+	if err != nil {
+		return err
+	}
+	return vitalCommand.DisplayMarkdown(markdown, true)
+}
+
+// LearnTopicRunE returns a cobra run function displaying a learn topic
+func (vitalCommand *vitalCommand) LearnTopicRunE(name string) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		return vitalCommand.DisplayLearnTopic(name)
+	}
+}
+
+// NewLearnCommand returns a new `opsani learn` command instance
+func NewLearnCommand(baseCmd *BaseCommand) *cobra.Command {
+	vitalCommand := vitalCommand{BaseCommand: baseCmd}
+	completeTopics := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if err := baseCmd.InitConfigRunE(cmd, args); err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		registry, err := baseCmd.LearnRegistry()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return registry.Names(), cobra.ShellCompDirectiveNoFileComp
+	}
+
+	learnCmd := &cobra.Command{
+		Use:   "learn [TOPIC]",
+		Short: "Learn about optimization with Opsani",
+		Long: `Displays a topic about optimization with Opsani, or lists the topics when none is given.
+
+Topics are embedded into the CLI and can be updated from a docs endpoint with
+` + "`opsani learn refresh`" + `. Refreshed topics take precedence over the embedded ones.`,
+		Example: `  opsani learn list
+  opsani learn loadgen
+  opsani learn refresh --url https://docs.example.com/cli/learn`,
+		Annotations:       map[string]string{"educational": "true"},
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeTopics,
+		PersistentPreRunE: baseCmd.InitConfigRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return listLearnTopics(baseCmd, cmd)
+			}
+			return vitalCommand.DisplayLearnTopic(args[0])
+		},
+	}
+	AddOutputFlag(learnCmd, TabularOutputFormats...)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List learn topics",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listLearnTopics(baseCmd, cmd)
+		},
+	}
+	AddOutputFlag(listCmd, TabularOutputFormats...)
+	learnCmd.AddCommand(listCmd)
+
+	refreshCmd := &cobra.Command{
+		Use:   "refresh",
+		Short: "Download the latest learn topics from the docs endpoint",
+		Long: `Downloads the topics listed in the index.json of the docs endpoint, a JSON array of
+topic names that are each served as markdown at <url>/<name>.md. The endpoint is
+given by --url or the learn.url setting.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			docsURL, _ := cmd.Flags().GetString("url")
+			if docsURL == "" {
+				docsURL = baseCmd.viperCfg.GetString(KeyLearnURL)
+			}
+			docsURL = strings.TrimSuffix(docsURL, "/")
+			if docsURL == "" {
+				return fmt.Errorf("no docs endpoint configured: pass --url or set %s in the config file", KeyLearnURL)
+			}
+			client := &http.Client{Timeout: 10 * time.Second}
+			names, err := RefreshLearnTopics(client, docsURL, baseCmd.LearnCacheDir())
+			if err != nil {
+				return fmt.Errorf("unable to refresh learn topics: %w", err)
+			}
+			baseCmd.Printf("Refreshed %d learn topics from %s: %s\n", len(names), docsURL, strings.Join(names, ", "))
+			return nil
+		},
+	}
+	refreshCmd.Flags().String("url", "", fmt.Sprintf("Docs endpoint serving learn topics (default is the %s setting)", KeyLearnURL))
+	learnCmd.AddCommand(refreshCmd)
+
+	return learnCmd
+}
+
+func listLearnTopics(baseCmd *BaseCommand, cmd *cobra.Command) error {
+	output, err := OutputFormat(cmd, TabularOutputFormats...)
+	if err != nil {
+		return err
+	}
+	registry, err := baseCmd.LearnRegistry()
+	if err != nil {
+		return err
+	}
+	table := Table{Headers: []string{"TOPIC", "TITLE", "SOURCE"}}
+	for _, topic := range registry.Topics() {
+		table.Rows = append(table.Rows, []string{topic.Name, topic.Title, topic.Source})
+	}
+	return baseCmd.RenderTable(output, table)
+}
+
+func init() {
+	pkger.Include("/docs/learn")
+}
@@ -0,0 +1,210 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+	"sigs.k8s.io/yaml"
+)
+
+type LearnTestSuite struct {
+	test.Suite
+}
+
+func TestLearnTestSuite(t *testing.T) {
+	suite.Run(t, new(LearnTestSuite))
+}
+
+func (s *LearnTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+}
+
+// learnConfigDir returns a temporary directory containing a config file for learn topics to be cached alongside
+func (s *LearnTestSuite) learnConfigDir() (dir string, configFile string) {
+	dir, err := ioutil.TempDir("", "learn")
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { os.RemoveAll(dir) })
+	configFile = filepath.Join(dir, "config.yaml")
+	data, err := yaml.Marshal(map[string][]map[string]string{
+		"profiles": {{"name": "default", "optimizer": "example.com/app", "token": "123456"}},
+	})
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(configFile, data, 0644))
+	return dir, configFile
+}
+
+func learnDocsServer(docs map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc, ok := docs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(doc))
+	}))
+}
+
+func (s *LearnTestSuite) TestListingEmbeddedTopics() {
+	_, configFile := s.learnConfigDir()
+	output, err := s.Execute("--config", configFile, "learn", "list")
+	s.Require().NoError(err)
+	s.Require().Regexp(`adjust\s+Opsani Ignite - Adjustments\s+embedded`, output)
+	s.Require().Regexp(`loadgen\s+Opsani Ignite - Load Generation\s+embedded`, output)
+	s.Require().Regexp(`measure\s+Opsani Ignite - Measurements\s+embedded`, output)
+}
+
+func (s *LearnTestSuite) TestLearnWithoutTopicListsTopics() {
+	_, configFile := s.learnConfigDir()
+	output, err := s.Execute("--config", configFile, "learn")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "TOPIC")
+	s.Require().Contains(output, "loadgen")
+}
+
+func (s *LearnTestSuite) TestDisplayingTopicRendersManifestsDir() {
+	dir, configFile := s.learnConfigDir()
+	data, err := yaml.Marshal(command.IgniteState{Profile: "opsani-ignite", ManifestsDir: "/srv/ignite"})
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "ignite-state.yaml"), data, 0644))
+
+	output, err := s.Execute("--config", configFile, "learn", "adjust")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "kubectl apply -f "+filepath.Join("/srv/ignite", "servo-configmap.yaml"))
+	s.Require().NotContains(output, "{{")
+}
+
+func (s *LearnTestSuite) TestDisplayingUnknownTopic() {
+	_, configFile := s.learnConfigDir()
+	_, err := s.Execute("--config", configFile, "learn", "autoscaling")
+	s.Require().EqualError(err, `unknown learn topic "autoscaling": must be one of adjust, loadgen, measure`)
+}
+
+func (s *LearnTestSuite) TestRefreshingTopicsFromDocsEndpoint() {
+	server := learnDocsServer(map[string]string{
+		"/learn/index.json":  `["loadgen", "slo"]`,
+		"/learn/loadgen.md":  "# Load Generation\n\nEdit {{ .ServoConfigMap }} to change the rate.\n",
+		"/learn/slo.md":      "# Service Level Objectives\n\nSLOs constrain the optimizer.\n",
+		"/learn/unlisted.md": "# Unlisted\n",
+	})
+	defer server.Close()
+	dir, configFile := s.learnConfigDir()
+
+	output, err := s.Execute("--config", configFile, "learn", "refresh", "--url", server.URL+"/learn/")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Refreshed 2 learn topics from "+server.URL+"/learn: loadgen, slo")
+	s.Require().FileExists(filepath.Join(dir, "learn", "slo.md"))
+
+	output, err = s.Execute("--config", configFile, "learn", "list")
+	s.Require().NoError(err)
+	s.Require().Regexp(`adjust\s+Opsani Ignite - Adjustments\s+embedded`, output)
+	s.Require().Regexp(`loadgen\s+Load Generation\s+remote`, output)
+	s.Require().Regexp(`slo\s+Service Level Objectives\s+remote`, output)
+	s.Require().NotContains(output, "unlisted")
+
+	output, err = s.Execute("--config", configFile, "learn", "slo")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "SLOs constrain the optimizer.")
+}
+
+func (s *LearnTestSuite) TestRefreshingTopicsWithURLFromConfig() {
+	server := learnDocsServer(map[string]string{
+		"/index.json": `["slo"]`,
+		"/slo.md":     "# Service Level Objectives\n",
+	})
+	defer server.Close()
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{{"name": "default", "optimizer": "example.com/app", "token": "123456"}},
+		"learn":    map[string]string{"url": server.URL},
+	})
+	cacheDir := filepath.Join(filepath.Dir(configFile.Name()), "learn")
+	defer os.RemoveAll(cacheDir)
+
+	output, err := s.Execute("--config", configFile.Name(), "learn", "refresh")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Refreshed 1 learn topics")
+	s.Require().FileExists(filepath.Join(cacheDir, "slo.md"))
+}
+
+func (s *LearnTestSuite) TestRefreshingTopicsRejectsUnsafeNames() {
+	server := learnDocsServer(map[string]string{
+		"/index.json": `["../config"]`,
+	})
+	defer server.Close()
+	dir, configFile := s.learnConfigDir()
+
+	_, err := s.Execute("--config", configFile, "learn", "refresh", "--url", server.URL)
+	s.Require().EqualError(err, `unable to refresh learn topics: invalid learn topic name "../config" in `+server.URL+`/index.json`)
+	s.Require().NoDirExists(filepath.Join(dir, "learn"))
+}
+
+func (s *LearnTestSuite) TestRefreshingTopicsRejectsReservedNames() {
+	server := learnDocsServer(map[string]string{
+		"/index.json": `["slo", "refresh"]`,
+		"/slo.md":     "# Service Level Objectives\n",
+		"/refresh.md": "# Refresh\n",
+	})
+	defer server.Close()
+	dir, configFile := s.learnConfigDir()
+
+	_, err := s.Execute("--config", configFile, "learn", "refresh", "--url", server.URL)
+	s.Require().EqualError(err, `unable to refresh learn topics: invalid learn topic name "refresh" in `+server.URL+`/index.json`)
+	s.Require().NoDirExists(filepath.Join(dir, "learn"))
+}
+
+func (s *LearnTestSuite) TestListingTopicsSkipsCachedReservedNames() {
+	dir, configFile := s.learnConfigDir()
+	s.Require().NoError(os.MkdirAll(filepath.Join(dir, "learn"), 0755))
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(dir, "learn", "list.md"), []byte("# Shadowed List\n"), 0644))
+
+	output, err := s.Execute("--config", configFile, "learn", "list")
+	s.Require().NoError(err)
+	s.Require().NotContains(output, "Shadowed List")
+	s.Require().Contains(output, "loadgen")
+}
+
+func (s *LearnTestSuite) TestRefreshingTopicsKeepsCacheOnFailure() {
+	server := learnDocsServer(map[string]string{
+		"/index.json": `["slo"]`,
+		"/slo.md":     "# Service Level Objectives\n",
+	})
+	dir, configFile := s.learnConfigDir()
+	_, err := s.Execute("--config", configFile, "learn", "refresh", "--url", server.URL)
+	s.Require().NoError(err)
+	server.Close()
+
+	broken := learnDocsServer(map[string]string{"/index.json": `["slo", "missing"]`, "/slo.md": "# SLO\n"})
+	defer broken.Close()
+	_, err = s.Execute("--config", configFile, "learn", "refresh", "--url", broken.URL)
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "404 Not Found")
+	content, err := ioutil.ReadFile(filepath.Join(dir, "learn", "slo.md"))
+	s.Require().NoError(err)
+	s.Require().Equal("# Service Level Objectives\n", string(content))
+}
+
+func (s *LearnTestSuite) TestRefreshingTopicsWithoutURL() {
+	_, configFile := s.learnConfigDir()
+	_, err := s.Execute("--config", configFile, "learn", "refresh")
+	s.Require().EqualError(err, "no docs endpoint configured: pass --url or set learn.url in the config file")
+}
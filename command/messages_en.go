@@ -0,0 +1,167 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+// messagesEn is the English message catalog and the fallback for all other locales
+var messagesEn = map[string]string{
+	"prompt.ready":                     "Ready to get started?",
+	"prompt.confirmed":                 "Let's do this thing.",
+	"vital.task.discovery.description": "discovering Kubernetes resources...",
+	"vital.task.discovery.success":     "discovered %s workloads across %s namespaces.",
+	"vital.task.discovery.failure":     "failed discovering Kubernetes resources",
+	"vital.prompt.namespace":           "Select namespace:",
+	"vital.prompt.workload":            "Select workload:",
+	"vital.prompt.container":           "Select container:",
+	"vital.prompt.service":             "Select service:",
+	"vital.target.detected":            "Detected application: %s (container %s, service %s)",
+	"vital.prompt.confirm_target":      "Optimize the detected application?",
+	"vital.prompt.datadog.site":        "Datadog site:",
+	"vital.prompt.datadog.api_key":     "Datadog API key:",
+	"vital.prompt.datadog.app_key":     "Datadog application key:",
+	"vital.prompt.newrelic.account_id": "New Relic account ID:",
+	"vital.prompt.newrelic.api_key":    "New Relic user API key:",
+	"vital.manifests.written":          "Servo manifests written to %s",
+	"vital.manifests.apply":            "Deploy the servo by running %s",
+	"vital.target.selected":            "Optimizing %s (container %s, service %s)",
+	"vital.guardrails.recommended":     "Recommended guardrails: cpu %s to %s in steps of %s, memory %s to %s in steps of %s",
+	"vital.prompt.confirm_guardrails":  "Use the recommended guardrails?",
+	"vital.task.safety.description":    "checking the application for availability risks...",
+	"vital.task.safety.success":        "safety checks complete.",
+	"vital.task.safety.failure":        "failed checking the application",
+	"vital.safety.failed":              "%d safety checks failed: resolve the issues above or rerun with --force",
+	"prompt.overwrite_servo":           "Existing servo attached to %q. Overwrite?",
+
+	"task.log":                           "full output recorded to %s",
+	"ignite.task.start.description":      "starting minikube...",
+	"ignite.task.start.success":          "minikube profile %s started.",
+	"ignite.task.start.failure":          "failed starting minikube",
+	"ignite.task.stop.description":       "stopping minikube...",
+	"ignite.task.stop.success":           "minikube profile %s stopped.",
+	"ignite.task.stop.failure":           "failed stopping minikube",
+	"ignite.task.delete.description":     "deleting minikube profile...",
+	"ignite.task.delete.success":         "minikube profile %s deleted.",
+	"ignite.task.delete.failure":         "failed deleting minikube profile",
+	"ignite.delete.detached":             "servo detached from profile %s.",
+	"ignite.delete.manifests":            "%d manifests removed from %s.",
+	"ignite.task.reset.description":      "resetting demo optimizer...",
+	"ignite.task.reset.success":          "demo optimizer reset.",
+	"ignite.task.reset.failure":          "failed resetting demo optimizer",
+	"ignite.task.docker.description":     "checking for Docker runtime...",
+	"ignite.task.docker.success":         "Docker %s found.",
+	"ignite.task.docker.failure":         "unable to find Docker",
+	"ignite.task.runtime.description":    "checking for %s container runtime...",
+	"ignite.task.runtime.success":        "%s %s found.",
+	"ignite.task.runtime.failure":        "unable to find %s",
+	"ignite.task.minikube.description":   "checking for minikube...",
+	"ignite.task.minikube.success":       "minikube %s found.",
+	"ignite.task.minikube.failure":       "unable to find minikube",
+	"ignite.prompt.recreate":             " There is an existing %q minikube profile. Do you want to recreate it?",
+	"ignite.task.recreate.description":   "deleting existing minikube profile...",
+	"ignite.task.recreate.failure":       "failed deletion of minikube profile",
+	"ignite.task.create.description":     "creating a new minikube profile...",
+	"ignite.task.create.success":         "minikube profile %s created.",
+	"ignite.task.create.failure":         "failed creation of minikube profile",
+	"ignite.task.engine.description":     "asking Opsani for an optimization engine...",
+	"ignite.task.engine.success":         "optimization engine acquired.",
+	"ignite.task.engine.failure":         "failed trying to acquire an optimization engine",
+	"preflight.task.description":         "probing the cluster for servo requirements...",
+	"preflight.task.success":             "cluster probe complete.",
+	"preflight.task.failure":             "failed probing the cluster",
+	"preflight.failed":                   "%d preflight checks failed: resolve the issues above or rerun with --skip-preflight",
+	"ignite.task.crd.description":        "waiting for Prometheus custom resource definition to propogate...",
+	"ignite.task.crd.success":            "Prometheus custom resource definition is now available.",
+	"ignite.task.crd.failure":            "failed waiting for Prometheus custom resource definition",
+	"ignite.task.manifest.description":   "applying manifest %s...",
+	"ignite.task.manifest.success":       "manifest %s applied.",
+	"ignite.task.manifest.failure":       "manifest application failed",
+	"ignite.task.preload.description":    "preloading image %s...",
+	"ignite.task.preload.success":        "image %s loaded.",
+	"ignite.task.preload.failure":        "failed preloading image %s",
+	"ignite.preload.emulated":            "image %s is not published for %s and will run emulated",
+	"ignite.task.upgrade.description":    "upgrading servo to %s...",
+	"ignite.task.upgrade.success":        "servo upgraded to %s.",
+	"ignite.task.upgrade.failure":        "failed upgrading servo",
+	"ignite.task.prometheus.description": "waiting for Prometheus pod...",
+	"ignite.task.prometheus.success":     "pod/prometheus-prometheus-0 is now running.",
+	"ignite.task.prometheus.failure":     "failed waiting for prometheus pod",
+	"ignite.task.optimizer.description":  "configuring optimizer for ignite...",
+	"ignite.task.optimizer.success":      "optimizer configured.",
+	"ignite.task.optimizer.failure":      "failed configuring optimizer for ignite",
+	"ignite.task.servo.description":      "restarting servo...",
+	"ignite.task.servo.success":          "deployments/servo is now available.",
+	"ignite.task.servo.failure":          "failed waiting for servo deployment",
+	"ignite.ignition":                    "We have ignition",
+	"ignite.summary.servo":               "Servo running in Kubernetes %s",
+	"ignite.summary.profile":             "Servo attached to opsani profile %s",
+	"ignite.summary.manifests":           "Manifests written to %s",
+	"ignite.summary.results":             "Optimization results will begin reporting in the console shortly.",
+
+	"ignite.intro": `# Opsani Ignite
+
+Ignite deploys a complete optimization experience onto your local workstation.
+
+[Docker](https://www.docker.com/), [Kubernetes](https://kubernetes.io/), and [minikube](https://minikube.sigs.k8s.io/docs/) will be configured to run
+a deployment of a simple web application, [Prometheus](https://prometheus.io/) for capturing metrics,
+and a servo connected to your Opsani account.
+
+Deployment will be done in a new minikube profile called **opsani-ignite** that is
+isolated from your existing work.
+
+Manifests generated during deployment are written to **%s**.`,
+
+	"vital.intro": `# Opsani Vital
+
+## Let's talk about your cloud costs
+
+It's the worst kept secret in tech. We're all spending way more on infrastructure than is necessary.
+
+But it's not our fault. Our applications have become too big and complicated to optimize.
+
+Until now.
+
+## Better living through machine learning...
+
+Opsani utilizes state of the art machine learning technology to continuously optimize your applications for *cost* and *performance*.
+
+## Getting started
+
+To start optimizing, a Servo must be deployed into your environment.
+
+A Servo is a lightweight container that lets Opsani know what is going on in your application and recommend optimizations.
+
+This app is designed to assist you in assembling and deploying a Servo through the miracle of automation and sensible defaults.
+
+The process looks like...
+
+- [x] Register for Vital
+- [x] Install Opsani
+- [x] Read this doc
+- [ ] Deploy the Servo
+- [ ] Start optimizing
+
+## Things to keep in mind
+
+All software run and deployed is Open Source. Opsani supports manual and assisted integrations if you like to do things the hard way.
+
+Over the next 20 minutes, we will gather details about your application, the deployment environment, and your optimization goals.
+
+The process will involve cloning Git repositories, connecting to your metrics & orchestration systems, and running Docker containers.
+
+As tasks are completed, artifacts will be generated and saved onto this workstation.
+
+Everything is logged, you can be pause and resume at any time, and important items will require confirmation.
+
+Once this is wrapped up, you can start optimizing immediately.`,
+}
@@ -0,0 +1,37 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+// messagesEs is the Spanish message catalog
+// Untranslated keys fall back to English
+var messagesEs = map[string]string{
+	"prompt.ready":           "¿Listo para comenzar?",
+	"prompt.confirmed":       "¡Manos a la obra!",
+	"prompt.overwrite_servo": "Ya hay un servo vinculado a %q. ¿Sobrescribirlo?",
+
+	"task.log": "salida completa registrada en %s",
+
+	"ignite.task.docker.description":   "buscando el entorno de ejecución de Docker...",
+	"ignite.task.docker.success":       "Docker %s encontrado.",
+	"ignite.task.docker.failure":       "no se encontró Docker",
+	"ignite.task.minikube.description": "buscando minikube...",
+	"ignite.task.minikube.success":     "minikube %s encontrado.",
+	"ignite.task.minikube.failure":     "no se encontró minikube",
+	"ignite.ignition":                  "Tenemos ignición",
+	"ignite.summary.servo":             "Servo ejecutándose en Kubernetes %s",
+	"ignite.summary.profile":           "Servo vinculado al perfil de opsani %s",
+	"ignite.summary.manifests":         "Manifiestos escritos en %s",
+	"ignite.summary.results":           "Los resultados de la optimización comenzarán a aparecer en la consola en breve.",
+}
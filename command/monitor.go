@@ -0,0 +1,475 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/opsani/cli/opsani"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// Monitor flag keys
+const (
+	KeyMonitorStaleAfter = "stale-after"
+	KeyMonitorWebhook    = "webhook"
+	KeyMonitorNoDesktop  = "no-desktop"
+	KeyMonitorForeground = "foreground"
+	KeyMonitorOnce       = "once"
+
+	DefaultMonitorInterval   = time.Minute
+	DefaultMonitorStaleAfter = 15 * time.Minute
+)
+
+// monitorWebhookEnv passes the webhook to the background monitor without exposing it in the process arguments
+const monitorWebhookEnv = "OPSANI_MONITOR_WEBHOOK"
+
+// MonitorState records the background monitor between invocations
+type MonitorState struct {
+	PID       int       `json:"pid"`
+	Profiles  []string  `json:"profiles"`
+	StartedAt time.Time `json:"started_at"`
+	LogFile   string    `json:"log_file"`
+}
+
+// MonitorStatePath returns the path of the file recording the background monitor
+func (baseCmd *BaseCommand) MonitorStatePath() string {
+	return baseCmd.statePath("monitor.yaml")
+}
+
+// MonitorLogPath returns the path of the file that the background monitor logs to
+func (baseCmd *BaseCommand) MonitorLogPath() string {
+	return baseCmd.statePath("monitor.log")
+}
+
+// MonitorLockPath returns the path of the file locked by the background monitor for as long as it runs
+func (baseCmd *BaseCommand) MonitorLockPath() string {
+	return baseCmd.statePath("monitor.lock")
+}
+
+// LoadMonitorState returns the recorded monitor state or nil if no monitor is recorded
+func (baseCmd *BaseCommand) LoadMonitorState() (*MonitorState, error) {
+	data, err := ioutil.ReadFile(baseCmd.MonitorStatePath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	state := &MonitorState{}
+	if err := yaml.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid monitor state %s: %w", baseCmd.MonitorStatePath(), err)
+	}
+	return state, nil
+}
+
+// SaveMonitorState records the monitor state, removing the record when state is nil
+func (baseCmd *BaseCommand) SaveMonitorState(state *MonitorState) error {
+	path := baseCmd.MonitorStatePath()
+	if state == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := yaml.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// runningMonitor returns the recorded monitor if its process is still running and holds the monitor lock
+// The lock keeps an unrelated process that reused the pid of an exited monitor from being mistaken for it
+func (baseCmd *BaseCommand) runningMonitor() (*MonitorState, error) {
+	state, err := baseCmd.LoadMonitorState()
+	if err != nil || state == nil || !processRunning(state.PID) || !monitorLocked(baseCmd.MonitorLockPath()) {
+		return nil, err
+	}
+	return state, nil
+}
+
+// MonitorAlert describes a change in the health of a monitored servo
+type MonitorAlert struct {
+	Profile   string
+	Optimizer string
+
+	// Err describes why the servo needs attention and is nil when the servo has recovered
+	Err error
+}
+
+// Text returns a human readable summary of the alert
+func (a MonitorAlert) Text() string {
+	if a.Err != nil {
+		return fmt.Sprintf("Servo of %s (profile %q) needs attention: %s", a.Optimizer, a.Profile, a.Err)
+	}
+	return fmt.Sprintf("Servo of %s (profile %q) is reporting again", a.Optimizer, a.Profile)
+}
+
+// lastServoReport returns the time that the servo last reported an adjustment or measurement
+// An error is returned only if neither could be retrieved
+func lastServoReport(client *opsani.Client) (*time.Time, error) {
+	var lastReport *time.Time
+	latest := func(startedAt time.Time, completedAt *time.Time) {
+		t := startedAt
+		if completedAt != nil {
+			t = *completedAt
+		}
+		if lastReport == nil || t.After(*lastReport) {
+			lastReport = &t
+		}
+	}
+	adjustments, adjustmentsErr := client.GetAdjustments(opsani.TimeRange{Limit: 1})
+	for _, adjustment := range adjustments {
+		latest(adjustment.StartedAt, adjustment.CompletedAt)
+	}
+	measurements, measurementsErr := client.GetMeasurements(opsani.TimeRange{Limit: 1})
+	for _, measurement := range measurements {
+		latest(measurement.StartedAt, measurement.CompletedAt)
+	}
+	if adjustmentsErr != nil && measurementsErr != nil {
+		return nil, adjustmentsErr
+	}
+	return lastReport, nil
+}
+
+// desktopNotify displays a notification with the desktop notification service of the OS
+func desktopNotify(title, message string) error {
+	ctx, cancel := contextWithTimeout(10 * time.Second)
+	defer cancel()
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = commandContext(ctx, "osascript", "-e", fmt.Sprintf("display notification %q with title %q", message, title))
+	case "windows":
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	default:
+		cmd = commandContext(ctx, "notify-send", title, message)
+	}
+	return runCommand(cmd)
+}
+
+// servoMonitor polls the servos of profiles and alerts when their health changes
+type servoMonitor struct {
+	*BaseCommand
+	profiles   []*Profile
+	staleAfter time.Duration
+	timeout    time.Duration
+	desktop    bool
+	webhook    *SlackNotifier
+
+	// healthy records the health of each profile at the last poll
+	healthy map[string]bool
+}
+
+// checkServo returns an error describing why the servo of the profile needs attention
+// Servos must be reachable without prompting for credentials because the monitor runs unattended
+func (m *servoMonitor) checkServo(profile *Profile, now time.Time) error {
+	if profile.Servo != (Servo{}) {
+		driver, err := servoDriverFactory(profile.Servo, m.timeout)
+		if err == nil {
+			err = driver.Ping()
+		}
+		if err != nil {
+			return fmt.Errorf("servo is unreachable: %w", err)
+		}
+	}
+
+	lastReport, err := lastServoReport(m.NewAPIClientForProfile(profile).SetTimeout(m.timeout))
+	if err != nil {
+		return fmt.Errorf("unable to retrieve servo reports: %w", err)
+	} else if lastReport == nil {
+		return fmt.Errorf("servo has not reported")
+	} else if age := now.Sub(*lastReport); age > m.staleAfter {
+		return fmt.Errorf("servo has not reported for %s", age.Round(time.Minute))
+	}
+	return nil
+}
+
+// poll checks each servo, alerting when one needs attention or recovers, and returns the number needing attention
+func (m *servoMonitor) poll(now time.Time) int {
+	unhealthy := 0
+	for _, profile := range m.profiles {
+		err := m.checkServo(profile, now)
+		if err != nil {
+			unhealthy++
+		}
+		healthy, seen := m.healthy[profile.Name]
+		m.healthy[profile.Name] = err == nil
+		if (seen && healthy == (err == nil)) || (!seen && err == nil) {
+			continue
+		}
+		m.alert(now, MonitorAlert{Profile: profile.Name, Optimizer: profile.Optimizer, Err: err})
+	}
+	return unhealthy
+}
+
+// alert logs the alert and delivers it to the desktop and webhook
+// Delivery failures are logged and never stop the monitor
+func (m *servoMonitor) alert(now time.Time, alert MonitorAlert) {
+	m.Printf("%s %s\n", now.Format(time.RFC3339), alert.Text())
+	if m.desktop {
+		if err := desktopNotify("Opsani", alert.Text()); err != nil {
+			m.PrintErrf("warning: failed displaying desktop notification: %s\n", err)
+		}
+	}
+	if m.webhook != nil {
+		if err := m.webhook.PostText(alert.Text()); err != nil {
+			m.PrintErrf("warning: failed posting to webhook: %s\n", err)
+		}
+	}
+}
+
+// run polls the servos at the interval until interrupted or terminated
+func (m *servoMonitor) run(interval time.Duration) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.poll(time.Now())
+		select {
+		case <-interrupt:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// NewMonitorCommand returns a new `opsani monitor` command instance
+func NewMonitorCommand(baseCmd *BaseCommand) *cobra.Command {
+	monitorCmd := &cobra.Command{
+		Use:   "monitor",
+		Short: "Monitor servo health in the background",
+		Long: `Runs a lightweight local daemon that polls the servos of profiles and alerts when a servo
+becomes unreachable or stops reporting to the optimizer, and again when it recovers.
+
+Alerts are displayed as desktop notifications (macOS and Linux) and posted to the Slack webhook
+configured by notifications.slack_webhook or given with --webhook.`,
+		Args: cobra.NoArgs,
+		PersistentPreRunE: ReduceRunEFuncs(
+			baseCmd.InitConfigRunE,
+			baseCmd.RequireConfigFileFlagToExistRunE,
+			baseCmd.RequireInitRunE,
+		),
+	}
+
+	startCmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start monitoring servos",
+		Long: `Starts a background process that checks the servos of the active profile, or of the profiles
+selected with --profiles or --all, at each interval.
+
+A servo needs attention when it cannot be reached with kubectl or SSH, or when the optimizer has not
+received an adjustment or measurement from it within the --stale-after duration. The background
+process logs to monitor.log alongside the config file.`,
+		Example: `  opsani monitor start --profiles production,staging --stale-after 30m
+  opsani monitor start --once --no-desktop`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profiles, err := baseCmd.SelectedProfiles(cmd)
+			if err != nil {
+				return err
+			} else if profiles == nil {
+				if baseCmd.profile == nil {
+					return fmt.Errorf("no profiles are configured")
+				}
+				profiles = []*Profile{baseCmd.profile}
+			}
+			interval, _ := cmd.Flags().GetDuration(KeyWatchInterval)
+			staleAfter, _ := cmd.Flags().GetDuration(KeyMonitorStaleAfter)
+			if interval <= 0 {
+				return fmt.Errorf("invalid interval %q: must be greater than zero", interval)
+			} else if staleAfter <= 0 {
+				return fmt.Errorf("invalid stale after duration %q: must be greater than zero", staleAfter)
+			}
+
+			foreground, _ := cmd.Flags().GetBool(KeyMonitorForeground)
+			once, _ := cmd.Flags().GetBool(KeyMonitorOnce)
+			if !foreground && !once {
+				return baseCmd.startMonitorDaemon(cmd, profiles)
+			}
+
+			monitor := &servoMonitor{
+				BaseCommand: baseCmd,
+				profiles:    profiles,
+				staleAfter:  staleAfter,
+				timeout:     baseCmd.Timeout(),
+				healthy:     map[string]bool{},
+			}
+			if monitor.timeout == 0 {
+				monitor.timeout = defaultConnectivityTimeout
+			}
+			noDesktop, _ := cmd.Flags().GetBool(KeyMonitorNoDesktop)
+			monitor.desktop = !noDesktop
+			webhook, _ := cmd.Flags().GetString(KeyMonitorWebhook)
+			if webhook == "" {
+				webhook = os.Getenv(monitorWebhookEnv)
+			}
+			if webhook == "" {
+				webhook = baseCmd.viperCfg.GetString(KeySlackWebhook)
+			}
+			if webhook != "" {
+				monitor.webhook = NewSlackNotifier(webhook)
+			}
+
+			if once {
+				if unhealthy := monitor.poll(time.Now()); unhealthy > 0 {
+					return fmt.Errorf("%d of %d servos need attention", unhealthy, len(profiles))
+				}
+				return nil
+			}
+			monitor.Printf("%s Monitoring %s every %s\n", time.Now().Format(time.RFC3339), strings.Join(profileNames(profiles), ", "), interval)
+			monitor.run(interval)
+			if state, err := baseCmd.LoadMonitorState(); err == nil && state != nil && state.PID == os.Getpid() {
+				return baseCmd.SaveMonitorState(nil)
+			}
+			return nil
+		},
+	}
+	startCmd.Flags().StringSlice(KeyProfiles, nil, "Monitor the servos of the named profiles (e.g. team-a,team-b)")
+	startCmd.Flags().Bool(KeyAllProfiles, false, "Monitor the servos of all profiles")
+	startCmd.Flags().Duration(KeyWatchInterval, DefaultMonitorInterval, "Polling interval")
+	startCmd.Flags().Duration(KeyMonitorStaleAfter, DefaultMonitorStaleAfter, "Alert when a servo has not reported for a duration")
+	startCmd.Flags().String(KeyMonitorWebhook, "", "Slack compatible webhook URL to post alerts to (default is notifications.slack_webhook)")
+	startCmd.Flags().Bool(KeyMonitorNoDesktop, false, "Disable desktop notifications")
+	startCmd.Flags().Bool(KeyMonitorForeground, false, "Run in the foreground until interrupted")
+	startCmd.Flags().Bool(KeyMonitorOnce, false, "Check the servos once in the foreground and exit")
+	monitorCmd.AddCommand(startCmd)
+
+	monitorCmd.AddCommand(&cobra.Command{
+		Use:   "stop",
+		Short: "Stop monitoring servos",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			state, err := baseCmd.runningMonitor()
+			if err != nil {
+				return err
+			} else if state == nil {
+				baseCmd.SaveMonitorState(nil)
+				return fmt.Errorf("monitor is not running")
+			}
+			if err := stopProcess(state.PID); err != nil {
+				return err
+			}
+			baseCmd.Printf("Stopped monitor (pid %d).\n", state.PID)
+			return baseCmd.SaveMonitorState(nil)
+		},
+	})
+
+	monitorCmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Display the status of the monitor",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			state, err := baseCmd.runningMonitor()
+			if err != nil {
+				return err
+			} else if state == nil {
+				baseCmd.Println("Monitor is not running.")
+				return nil
+			}
+			baseCmd.Printf("Monitor is running (pid %d) since %s.\n", state.PID, state.StartedAt.Format(time.RFC1123))
+			baseCmd.Printf("Profiles: %s\n", strings.Join(state.Profiles, ", "))
+			baseCmd.Printf("Log: %s\n", state.LogFile)
+			return nil
+		},
+	})
+
+	return monitorCmd
+}
+
+// profileNames returns the names of the profiles
+func profileNames(profiles []*Profile) []string {
+	names := []string{}
+	for _, profile := range profiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+// startMonitorDaemon starts a detached process running the monitor in the foreground and records it
+func (baseCmd *BaseCommand) startMonitorDaemon(cmd *cobra.Command, profiles []*Profile) error {
+	if state, err := baseCmd.runningMonitor(); err != nil {
+		return err
+	} else if state != nil {
+		return fmt.Errorf("monitor is already running (pid %d): stop it with `opsani monitor stop`", state.PID)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	args := []string{"monitor", "start", "--" + KeyMonitorForeground, "--" + KeyProfiles, strings.Join(profileNames(profiles), ",")}
+	if configFile := baseCmd.viperCfg.ConfigFileUsed(); configFile != "" {
+		args = append([]string{"--config", configFile}, args...)
+	}
+	for _, key := range []string{KeyWatchInterval, KeyMonitorStaleAfter, KeyMonitorNoDesktop} {
+		if flag := cmd.Flags().Lookup(key); flag.Changed {
+			args = append(args, fmt.Sprintf("--%s=%s", key, flag.Value))
+		}
+	}
+
+	lock, err := lockMonitor(baseCmd.MonitorLockPath())
+	if err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(baseCmd.MonitorLogPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		lock.Close()
+		return err
+	}
+	defer logFile.Close()
+	daemon := commandContext(context.Background(), executable, args...)
+	daemon.Stdout = logFile
+	daemon.Stderr = logFile
+	if lock != nil {
+		defer lock.Close()
+		daemon.ExtraFiles = []*os.File{lock}
+	}
+	if webhook := cmd.Flags().Lookup(KeyMonitorWebhook); webhook.Changed {
+		if daemon.Env == nil {
+			daemon.Env = os.Environ()
+		}
+		daemon.Env = append(daemon.Env, monitorWebhookEnv+"="+webhook.Value.String())
+	}
+	detachProcess(daemon)
+	if err := daemon.Start(); err != nil {
+		return err
+	}
+	state := &MonitorState{
+		PID:       daemon.Process.Pid,
+		Profiles:  profileNames(profiles),
+		StartedAt: time.Now(),
+		LogFile:   logFile.Name(),
+	}
+	daemon.Process.Release()
+	if err := baseCmd.SaveMonitorState(state); err != nil {
+		return err
+	}
+	baseCmd.Printf("Monitoring %s in the background (pid %d). Logs are written to %s.\n", strings.Join(state.Profiles, ", "), state.PID, state.LogFile)
+	return nil
+}
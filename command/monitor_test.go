@@ -0,0 +1,171 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+	"sigs.k8s.io/yaml"
+)
+
+type MonitorTestSuite struct {
+	test.Suite
+	driver   *test.FakeServoDriver
+	recorder *test.ExecRecorder
+	webhooks []string
+}
+
+func TestMonitorTestSuite(t *testing.T) {
+	suite.Run(t, new(MonitorTestSuite))
+}
+
+func (s *MonitorTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+	s.driver = test.NewFakeServoDriver()
+	command.SetServoDriverFactory(s.driver.Factory())
+	s.recorder = test.NewExecRecorder()
+	command.SetCommandContextFunc(s.recorder.CommandContext)
+	s.webhooks = nil
+}
+
+func (s *MonitorTestSuite) TearDownTest() {
+	command.SetServoDriverFactory(nil)
+	command.SetCommandContextFunc(nil)
+}
+
+// configFile writes a config with a profile whose servo last reported at the given time
+func (s *MonitorTestSuite) configFile(lastReport time.Time) string {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/adjustments") {
+			fmt.Fprintf(w, `{"adjustments": [{"id": "1", "started_at": %q}]}`, lastReport.Format(time.RFC3339))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{}`))
+	}))
+	s.T().Cleanup(api.Close)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		s.webhooks = append(s.webhooks, body["text"])
+	}))
+	s.T().Cleanup(webhook.Close)
+
+	dir, err := ioutil.TempDir("", "monitor")
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { os.RemoveAll(dir) })
+	data, err := yaml.Marshal(map[string]interface{}{
+		"profiles": []map[string]interface{}{
+			{
+				"name": "default", "optimizer": "example.com/app", "token": "123456", "base_url": api.URL,
+				"servo": map[string]string{"type": "kubernetes", "namespace": "opsani", "deployment": "servo"},
+			},
+		},
+		"notifications": map[string]string{"slack_webhook": webhook.URL},
+	})
+	s.Require().NoError(err)
+	configFile := filepath.Join(dir, "config.yaml")
+	s.Require().NoError(ioutil.WriteFile(configFile, data, 0644))
+	return configFile
+}
+
+func (s *MonitorTestSuite) TestRunningMonitorOnceHealthy() {
+	configFile := s.configFile(time.Now().Add(-time.Minute))
+	output, err := s.Execute("--config", configFile, "monitor", "start", "--once")
+	s.Require().NoError(err)
+	s.Require().Empty(output)
+	s.Require().Equal([]string{"Ping"}, s.driver.Calls())
+	s.Require().Empty(s.webhooks)
+	s.Require().Empty(s.recorder.Invocations())
+}
+
+func (s *MonitorTestSuite) TestRunningMonitorOnceStale() {
+	configFile := s.configFile(time.Now().Add(-2 * time.Hour))
+	output, err := s.Execute("--config", configFile, "monitor", "start", "--once", "--stale-after", "30m")
+	s.Require().EqualError(err, "1 of 1 servos need attention")
+	alert := `Servo of example.com/app (profile "default") needs attention: servo has not reported for 2h0m0s`
+	s.Require().Contains(output, alert)
+	s.Require().Equal([]string{alert}, s.webhooks)
+	s.Require().Len(s.recorder.Invocations(), 1)
+	s.Require().Contains(s.recorder.LastInvocation(), alert)
+}
+
+func (s *MonitorTestSuite) TestRunningMonitorOnceUnreachable() {
+	configFile := s.configFile(time.Now())
+	s.driver.Errors["Ping"] = errors.New("connection refused")
+	output, err := s.Execute("--config", configFile, "monitor", "start", "--once", "--no-desktop")
+	s.Require().EqualError(err, "1 of 1 servos need attention")
+	s.Require().Contains(output, "needs attention: servo is unreachable: connection refused")
+	s.Require().Len(s.webhooks, 1)
+	s.Require().Empty(s.recorder.Invocations())
+}
+
+func (s *MonitorTestSuite) TestRunningMonitorOnceWebhookFromEnvironment() {
+	configFile := s.configFile(time.Now().Add(-2 * time.Hour))
+	var received []string
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		received = append(received, body["text"])
+	}))
+	defer webhook.Close()
+	s.SetEnv("OPSANI_MONITOR_WEBHOOK", webhook.URL)
+	_, err := s.Execute("--config", configFile, "monitor", "start", "--once", "--no-desktop", "--stale-after", "30m")
+	s.Require().EqualError(err, "1 of 1 servos need attention")
+	s.Require().Len(received, 1)
+	s.Require().Empty(s.webhooks)
+}
+
+func (s *MonitorTestSuite) TestRunningMonitorStartDetaches() {
+	configFile := s.configFile(time.Now())
+	output, err := s.Execute("--config", configFile, "monitor", "start", "--stale-after", "30m", "--webhook", "https://hooks.example.com/secret")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Monitoring default in the background")
+
+	argv := strings.Join(s.recorder.LastInvocation()[1:], " ")
+	s.Require().Equal(fmt.Sprintf("--config %s monitor start --foreground --profiles default --stale-after=30m0s", configFile), argv)
+
+	data, err := ioutil.ReadFile(filepath.Join(filepath.Dir(configFile), "monitor.yaml"))
+	s.Require().NoError(err)
+	var state command.MonitorState
+	s.Require().NoError(yaml.Unmarshal(data, &state))
+	s.Require().Equal([]string{"default"}, state.Profiles)
+	s.Require().Equal(filepath.Join(filepath.Dir(configFile), "monitor.log"), state.LogFile)
+	s.Require().NotZero(state.PID)
+}
+
+func (s *MonitorTestSuite) TestRunningMonitorStopNotRunning() {
+	configFile := s.configFile(time.Now())
+	_, err := s.Execute("--config", configFile, "monitor", "stop")
+	s.Require().EqualError(err, "monitor is not running")
+
+	output, err := s.Execute("--config", configFile, "monitor", "status")
+	s.Require().NoError(err)
+	s.Require().Equal("Monitor is not running.\n", output)
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !windows
+
+package command_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"sigs.k8s.io/yaml"
+)
+
+// recordMonitor writes a monitor state recording the process as the background monitor
+func (s *MonitorTestSuite) recordMonitor(configFile string, pid int) {
+	data, err := yaml.Marshal(command.MonitorState{PID: pid, Profiles: []string{"default"}, StartedAt: time.Now()})
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(filepath.Dir(configFile), "monitor.yaml"), data, 0644))
+}
+
+func (s *MonitorTestSuite) TestRunningMonitorStopReusedPID() {
+	configFile := s.configFile(time.Now())
+	s.recordMonitor(configFile, os.Getpid())
+
+	output, err := s.Execute("--config", configFile, "monitor", "status")
+	s.Require().NoError(err)
+	s.Require().Equal("Monitor is not running.\n", output)
+
+	_, err = s.Execute("--config", configFile, "monitor", "stop")
+	s.Require().EqualError(err, "monitor is not running")
+	s.Require().NoFileExists(filepath.Join(filepath.Dir(configFile), "monitor.yaml"))
+}
+
+func (s *MonitorTestSuite) TestRunningMonitorStatusLocked() {
+	configFile := s.configFile(time.Now())
+	s.recordMonitor(configFile, os.Getpid())
+	lock, err := os.Create(filepath.Join(filepath.Dir(configFile), "monitor.lock"))
+	s.Require().NoError(err)
+	defer lock.Close()
+	s.Require().NoError(syscall.Flock(int(lock.Fd()), syscall.LOCK_EX))
+
+	output, err := s.Execute("--config", configFile, "monitor", "status")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Monitor is running (pid")
+
+	_, err = s.Execute("--config", configFile, "monitor", "start")
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "monitor is already running")
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build windows
+
+package command
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// detachedProcess is the Windows process creation flag for running without a console
+const detachedProcess = 0x00000008
+
+// detachProcess starts the command without a console so that it outlives the terminal
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: detachedProcess}
+}
+
+// processRunning reports whether a process with the pid exists
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
+
+// stopProcess terminates the process
+// Windows processes cannot be signalled so the monitor exits without cleaning up its state
+func stopProcess(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Kill()
+}
+
+// lockMonitor does nothing as files cannot be passed on to the background monitor on Windows
+func lockMonitor(path string) (*os.File, error) {
+	return nil, nil
+}
+
+// monitorLocked always reports the monitor lock as held as it is not taken on Windows
+func monitorLocked(path string) bool {
+	return true
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// KeySlackWebhook is the configuration key for the Slack incoming webhook notified when long tasks finish
+const KeySlackWebhook = "notifications.slack_webhook"
+
+// Notification describes the outcome of a long running task
+type Notification struct {
+	Task      string
+	Optimizer string
+	Duration  time.Duration
+	Err       error
+}
+
+// Text returns a human readable summary of the notification
+func (n Notification) Text() string {
+	target := ""
+	if n.Optimizer != "" {
+		target = fmt.Sprintf(" for %s", n.Optimizer)
+	}
+	duration := n.Duration.Round(time.Second)
+	if n.Err != nil {
+		return fmt.Sprintf(":x: `opsani %s`%s failed after %s: %s", n.Task, target, duration, n.Err)
+	}
+	return fmt.Sprintf(":white_check_mark: `opsani %s`%s finished in %s", n.Task, target, duration)
+}
+
+// Notifier delivers task notifications to an external service
+type Notifier interface {
+	Notify(notification Notification) error
+}
+
+// SlackNotifier posts notifications to a Slack incoming webhook
+type SlackNotifier struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+// NewSlackNotifier returns a new notifier posting to the given webhook URL
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{WebhookURL: webhookURL, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Notify posts the notification text to the webhook
+func (s *SlackNotifier) Notify(notification Notification) error {
+	return s.PostText(notification.Text())
+}
+
+// PostText posts a message to the webhook
+func (s *SlackNotifier) PostText(text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	resp, err := s.Client.Post(s.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("slack webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Notifier returns the configured notifier or nil if notifications are not configured
+func (baseCmd *BaseCommand) Notifier() Notifier {
+	if baseCmd.viperCfg == nil {
+		return nil
+	}
+	if webhook := baseCmd.viperCfg.GetString(KeySlackWebhook); webhook != "" {
+		return NewSlackNotifier(webhook)
+	}
+	return nil
+}
+
+// NotifyRunE wraps a Cobra run function so that the configured notifier is informed when it finishes or fails
+func (baseCmd *BaseCommand) NotifyRunE(runE RunEFunc) RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		started := time.Now()
+		err := runE(cmd, args)
+		baseCmd.notifyTaskFinished(cmd, started, err)
+		return err
+	}
+}
+
+// notifyTaskFinished delivers a notification for the command that started at the given time
+// Delivery failures are reported as warnings and never fail the task
+func (baseCmd *BaseCommand) notifyTaskFinished(cmd *cobra.Command, started time.Time, err error) {
+	notifier := baseCmd.Notifier()
+	if notifier == nil {
+		return
+	}
+	task := cmd.CommandPath()
+	if root := cmd.Root(); root != nil && len(task) > len(root.Name()) {
+		task = task[len(root.Name())+1:]
+	}
+	notification := Notification{
+		Task:      task,
+		Optimizer: baseCmd.Optimizer(),
+		Duration:  time.Since(started),
+		Err:       err,
+	}
+	if notifyErr := notifier.Notify(notification); notifyErr != nil {
+		baseCmd.PrintErrf("warning: failed sending notification: %s\n", notifyErr)
+	}
+}
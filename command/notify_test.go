@@ -0,0 +1,70 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationText(t *testing.T) {
+	notification := command.Notification{Task: "servo check", Optimizer: "example.com/app", Duration: 3*time.Minute + 2400*time.Millisecond}
+	require.Equal(t, ":white_check_mark: `opsani servo check` for example.com/app finished in 3m2s", notification.Text())
+	notification.Err = errors.New("exit status 1")
+	require.Equal(t, ":x: `opsani servo check` for example.com/app failed after 3m2s: exit status 1", notification.Text())
+}
+
+func TestNotifierDisabledByDefault(t *testing.T) {
+	baseCmd := command.NewRootCommand()
+	require.Nil(t, baseCmd.Notifier())
+}
+
+func TestNotifierFromConfig(t *testing.T) {
+	baseCmd := command.NewRootCommand()
+	baseCmd.Viper().Set(command.KeySlackWebhook, "https://hooks.slack.com/services/T0/B0/X")
+	notifier, ok := baseCmd.Notifier().(*command.SlackNotifier)
+	require.True(t, ok)
+	require.Equal(t, "https://hooks.slack.com/services/T0/B0/X", notifier.WebhookURL)
+}
+
+func TestSlackNotifierPostsText(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	err := command.NewSlackNotifier(server.URL).Notify(command.Notification{Task: "ignite", Duration: time.Minute})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"text": ":white_check_mark: `+"`opsani ignite`"+` finished in 1m0s"}`, body)
+}
+
+func TestSlackNotifierWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := command.NewSlackNotifier(server.URL).Notify(command.Notification{Task: "ignite"})
+	require.EqualError(t, err, "slack webhook returned 403 Forbidden")
+}
@@ -39,17 +39,27 @@ func NewOptimizerCommand(baseCmd *BaseCommand) *cobra.Command {
 	appStopCmd := NewOptimizerStopCommand(baseCmd)
 	appRestartCmd := NewOptimizerRestartCommand(baseCmd)
 	appStatusCmd := NewOptimizerStatusCommand(baseCmd)
+	appDescribeCmd := NewOptimizerDescribeCommand(baseCmd)
+	appSimulateCmd := NewOptimizerSimulateCommand(baseCmd)
 	appConfigCmd := NewOptimizerConfigCommand(baseCmd)
+	appAdjustmentsCmd := NewOptimizerAdjustmentsCommand(baseCmd)
+	appMeasurementsCmd := NewOptimizerMeasurementsCommand(baseCmd)
 
 	// Lifecycle
 	appCmd.AddCommand(appStartCmd)
 	appCmd.AddCommand(appStopCmd)
 	appCmd.AddCommand(appRestartCmd)
 	appCmd.AddCommand(appStatusCmd)
+	appCmd.AddCommand(appDescribeCmd)
+	appCmd.AddCommand(appSimulateCmd)
 
 	// Config
 	appCmd.AddCommand(appConfigCmd)
 
+	// Activity
+	appCmd.AddCommand(appAdjustmentsCmd)
+	appCmd.AddCommand(appMeasurementsCmd)
+
 	return appCmd
 }
 
@@ -63,7 +73,7 @@ func NewConsoleCommand(baseCmd *BaseCommand) *cobra.Command {
 		Args:        cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			org, appID := baseCmd.GetOptimizerComponents()
-			url := fmt.Sprintf("https://console.opsani.com/accounts/%s/applications/%s", org, appID)
+			url := fmt.Sprintf("%s/accounts/%s/applications/%s", baseCmd.ConsoleURL(), org, appID)
 			openURLInDefaultBrowser(url)
 			return nil
 		},
@@ -0,0 +1,190 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opsani/cli/opsani"
+	"github.com/spf13/cobra"
+)
+
+// NewOptimizerAdjustmentsCommand returns a new Opsani CLI `optimizer adjustments` command
+func NewOptimizerAdjustmentsCommand(baseCmd *BaseCommand) *cobra.Command {
+	adjustmentsCmd := &cobra.Command{
+		Use:   "adjustments",
+		Short: "Review adjustments made by the optimizer",
+		Args:  cobra.NoArgs,
+	}
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List recent adjustments",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			timeRange, err := timeRangeFromFlags(cmd, time.Now())
+			if err != nil {
+				return err
+			}
+			adjustments, err := baseCmd.NewAPIClient().GetAdjustments(timeRange)
+			if err != nil {
+				return err
+			}
+			output, _ := cmd.Flags().GetString("output")
+			if output == OutputJSON {
+				return baseCmd.PrettyPrintJSONObject(adjustments)
+			}
+
+			table := Table{Headers: []string{"ID", "STATUS", "STARTED", "DURATION", "SETTINGS"}}
+			for _, adjustment := range adjustments {
+				table.Rows = append(table.Rows, []string{
+					adjustment.ID,
+					adjustment.Status,
+					adjustment.StartedAt.Local().Format(time.RFC3339),
+					formatActivityDuration(adjustment.Duration()),
+					summarizeAdjustmentSettings(adjustment.Components),
+				})
+			}
+			return baseCmd.RenderTable(output, table)
+		},
+	}
+	AddTimeRangeFlags(listCmd)
+	adjustmentsCmd.AddCommand(listCmd)
+	adjustmentsCmd.AddCommand(NewOptimizerAdjustmentsForwardCommand(baseCmd))
+	return adjustmentsCmd
+}
+
+// NewOptimizerMeasurementsCommand returns a new Opsani CLI `optimizer measurements` command
+func NewOptimizerMeasurementsCommand(baseCmd *BaseCommand) *cobra.Command {
+	measurementsCmd := &cobra.Command{
+		Use:   "measurements",
+		Short: "Review measurements taken by the optimizer",
+		Args:  cobra.NoArgs,
+	}
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List recent measurements",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			timeRange, err := timeRangeFromFlags(cmd, time.Now())
+			if err != nil {
+				return err
+			}
+			measurements, err := baseCmd.NewAPIClient().GetMeasurements(timeRange)
+			if err != nil {
+				return err
+			}
+			output, _ := cmd.Flags().GetString("output")
+			if output == OutputJSON {
+				return baseCmd.PrettyPrintJSONObject(measurements)
+			}
+
+			table := Table{Headers: []string{"ID", "STATUS", "STARTED", "DURATION", "METRICS"}}
+			for _, measurement := range measurements {
+				table.Rows = append(table.Rows, []string{
+					measurement.ID,
+					measurement.Status,
+					measurement.StartedAt.Local().Format(time.RFC3339),
+					formatActivityDuration(measurement.Duration()),
+					summarizeMeasurementMetrics(measurement.Metrics),
+				})
+			}
+			return baseCmd.RenderTable(output, table)
+		},
+	}
+	AddTimeRangeFlags(listCmd)
+	measurementsCmd.AddCommand(listCmd)
+	return measurementsCmd
+}
+
+// activityOutputFormats are the output formats of optimizer activity listings
+var activityOutputFormats = []string{OutputTable, OutputJSON, OutputCSV, OutputMarkdown}
+
+// AddTimeRangeFlags adds flags for filtering optimizer activity by time
+func AddTimeRangeFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("since", 24*time.Hour, "Show activity newer than a relative duration (e.g. 30m, 6h)")
+	cmd.Flags().String("start", "", "Show activity after a time (RFC3339, overrides --since)")
+	cmd.Flags().String("end", "", "Show activity before a time (RFC3339)")
+	cmd.Flags().IntP("limit", "n", 25, "Maximum number of entries to show (0 for all)")
+	AddOutputFlag(cmd, activityOutputFormats...)
+}
+
+// timeRangeFromFlags returns the time range described by the time range flags relative to now
+func timeRangeFromFlags(cmd *cobra.Command, now time.Time) (opsani.TimeRange, error) {
+	timeRange := opsani.TimeRange{}
+	timeRange.Limit, _ = cmd.Flags().GetInt("limit")
+	if _, err := OutputFormat(cmd, activityOutputFormats...); err != nil {
+		return timeRange, err
+	}
+
+	if start, _ := cmd.Flags().GetString("start"); start != "" {
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return timeRange, fmt.Errorf("invalid start time %q: %w", start, err)
+		}
+		timeRange.Start = t
+	} else if since, _ := cmd.Flags().GetDuration("since"); since > 0 {
+		timeRange.Start = now.Add(-since)
+	}
+	if end, _ := cmd.Flags().GetString("end"); end != "" {
+		t, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return timeRange, fmt.Errorf("invalid end time %q: %w", end, err)
+		}
+		timeRange.End = t
+	}
+	if !timeRange.End.IsZero() && timeRange.End.Before(timeRange.Start) {
+		return timeRange, fmt.Errorf("end time %s is before start time %s",
+			timeRange.End.Format(time.RFC3339), timeRange.Start.Format(time.RFC3339))
+	}
+	return timeRange, nil
+}
+
+func formatActivityDuration(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(time.Second).String()
+}
+
+// summarizeAdjustmentSettings formats component settings as sorted component.setting=value pairs
+func summarizeAdjustmentSettings(components map[string]map[string]interface{}) string {
+	settings := []string{}
+	for component, values := range components {
+		for name, value := range values {
+			settings = append(settings, fmt.Sprintf("%s.%s=%v", component, name, value))
+		}
+	}
+	sort.Strings(settings)
+	return strings.Join(settings, " ")
+}
+
+// summarizeMeasurementMetrics formats metrics as sorted name=value pairs
+func summarizeMeasurementMetrics(metrics map[string]opsani.MeasurementMetric) string {
+	summaries := []string{}
+	for name, metric := range metrics {
+		summary := fmt.Sprintf("%s=%g", name, metric.Value)
+		if metric.Unit != "" {
+			summary += metric.Unit
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Strings(summaries)
+	return strings.Join(summaries, " ")
+}
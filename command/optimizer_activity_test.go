@@ -0,0 +1,124 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+)
+
+type OptimizerActivityTestSuite struct {
+	test.Suite
+}
+
+func TestOptimizerActivityTestSuite(t *testing.T) {
+	suite.Run(t, new(OptimizerActivityTestSuite))
+}
+
+func (s *OptimizerActivityTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+}
+
+// activityServer returns a test server responding with the body and recording the request query
+func (s *OptimizerActivityTestSuite) activityServer(body string, query *url.Values) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	s.SetEnv("OPSANI_BASE_URL", server.URL)
+	s.SetEnv("OPSANI_OPTIMIZER", "example.com/app")
+	s.SetEnv("OPSANI_TOKEN", "123456")
+	return server
+}
+
+func (s *OptimizerActivityTestSuite) TestRunningAdjustmentsListHelp() {
+	output, err := s.Execute("optimizer", "adjustments", "list", "--help")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "List recent adjustments")
+	s.Require().Contains(output, "--since")
+}
+
+func (s *OptimizerActivityTestSuite) TestRunningAdjustmentsList() {
+	var query url.Values
+	server := s.activityServer(`{"adjustments": [{"id": "adj-1", "status": "completed",
+		"started_at": "2020-06-01T10:00:00Z", "completed_at": "2020-06-01T10:02:30Z",
+		"components": {"web": {"replicas": 2, "cpu": 0.5}}}]}`, &query)
+	defer server.Close()
+
+	output, err := s.Execute("optimizer", "adjustments", "list", "--start", "2020-06-01T00:00:00Z", "--end", "2020-06-02T00:00:00Z", "-n", "5")
+	s.Require().NoError(err)
+	s.Require().Equal("2020-06-01T00:00:00Z", query.Get("start"))
+	s.Require().Equal("2020-06-02T00:00:00Z", query.Get("end"))
+	s.Require().Equal("5", query.Get("limit"))
+	s.Require().Contains(output, "adj-1")
+	s.Require().Contains(output, "2m30s")
+	s.Require().Contains(output, "web.cpu=0.5 web.replicas=2")
+}
+
+func (s *OptimizerActivityTestSuite) TestRunningAdjustmentsListSince() {
+	var query url.Values
+	server := s.activityServer(`{"adjustments": []}`, &query)
+	defer server.Close()
+
+	before := time.Now()
+	_, err := s.Execute("optimizer", "adjustments", "list", "--since", "6h")
+	s.Require().NoError(err)
+	start, err := time.Parse(time.RFC3339, query.Get("start"))
+	s.Require().NoError(err)
+	s.Require().WithinDuration(before.Add(-6*time.Hour), start, time.Minute)
+	s.Require().Equal("", query.Get("end"))
+}
+
+func (s *OptimizerActivityTestSuite) TestRunningAdjustmentsListInvalidRange() {
+	s.SetEnv("OPSANI_OPTIMIZER", "example.com/app")
+	s.SetEnv("OPSANI_TOKEN", "123456")
+	_, err := s.Execute("optimizer", "adjustments", "list", "--start", "2020-06-02T00:00:00Z", "--end", "2020-06-01T00:00:00Z")
+	s.Require().EqualError(err, "end time 2020-06-01T00:00:00Z is before start time 2020-06-02T00:00:00Z")
+	_, err = s.Execute("optimizer", "adjustments", "list", "--start", "yesterday")
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), `invalid start time "yesterday"`)
+}
+
+func (s *OptimizerActivityTestSuite) TestRunningMeasurementsList() {
+	var query url.Values
+	server := s.activityServer(`{"measurements": [{"id": "msr-1", "status": "running",
+		"started_at": "2020-06-01T10:00:00Z", "metrics": {"throughput": {"value": 125.5, "unit": "rpm"}, "cost": {"value": 3}}}]}`, &query)
+	defer server.Close()
+
+	output, err := s.Execute("optimizer", "measurements", "list")
+	s.Require().NoError(err)
+	s.Require().Equal("25", query.Get("limit"))
+	s.Require().Contains(output, "msr-1")
+	s.Require().Contains(output, "cost=3 throughput=125.5rpm")
+}
+
+func (s *OptimizerActivityTestSuite) TestRunningMeasurementsListJSON() {
+	var query url.Values
+	server := s.activityServer(`{"measurements": [{"id": "msr-1", "status": "completed",
+		"started_at": "2020-06-01T10:00:00Z", "metrics": {}}]}`, &query)
+	defer server.Close()
+
+	output, err := s.Execute("optimizer", "measurements", "list", "-o", "json")
+	s.Require().NoError(err)
+	s.Require().Contains(output, `"msr-1"`)
+}
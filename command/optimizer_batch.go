@@ -0,0 +1,148 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/opsani/cli/opsani"
+	"github.com/spf13/cobra"
+)
+
+// Profile selection flags for fanning lifecycle actions out over multiple optimizers
+const (
+	KeyProfiles    = "profiles"
+	KeyAllProfiles = "all"
+)
+
+// LifecycleAction describes an optimizer lifecycle action that can be applied to many profiles
+type LifecycleAction struct {
+	Verb    string // e.g. "stop"
+	Past    string // e.g. "stopped"
+	Perform func(client *opsani.Client) (*resty.Response, error)
+}
+
+// AddProfileSelectionFlags registers the --profiles and --all flags on the given command
+func (baseCmd *BaseCommand) AddProfileSelectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice(KeyProfiles, nil, "Apply to the optimizers of the named profiles (e.g. team-a,team-b)")
+	cmd.Flags().Bool(KeyAllProfiles, false, "Apply to the optimizers of all profiles")
+	baseCmd.AddDeprecatedForceFlag(cmd)
+}
+
+// SelectedProfiles returns the profiles chosen by --profiles or --all, or nil if neither flag was given
+func (baseCmd *BaseCommand) SelectedProfiles(cmd *cobra.Command) ([]*Profile, error) {
+	names, _ := cmd.Flags().GetStringSlice(KeyProfiles)
+	all, _ := cmd.Flags().GetBool(KeyAllProfiles)
+	if len(names) == 0 && !all {
+		return nil, nil
+	}
+	if len(names) > 0 && all {
+		return nil, fmt.Errorf("--%s cannot be combined with --%s", KeyProfiles, KeyAllProfiles)
+	}
+
+	registry, err := NewProfileRegistry(baseCmd.viperCfg)
+	if err != nil {
+		return nil, err
+	}
+	profiles := registry.Profiles()
+	if !all {
+		profiles = []*Profile{}
+		for _, name := range uniqueSortedStrings(names) {
+			profile := registry.ProfileNamed(name)
+			if profile == nil {
+				return nil, fmt.Errorf("no profile %q", name)
+			}
+			profiles = append(profiles, profile)
+		}
+	}
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("no profiles are configured")
+	}
+	for _, profile := range profiles {
+		profile.ApplyDefaults(registry.Defaults())
+	}
+	return profiles, nil
+}
+
+// NewAPIClientForProfile returns an Opsani API client configured for the optimizer of the profile
+func (baseCmd *BaseCommand) NewAPIClientForProfile(profile *Profile) *opsani.Client {
+	baseURL := profile.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return baseCmd.NewAPIClient().
+		SetBaseURL(baseURL).
+		SetApp(profile.Optimizer).
+		SetAuthToken(profile.Token)
+}
+
+// LifecycleRunE returns a run function that applies the action to the active optimizer or,
+// when --profiles or --all is given, to the optimizer of each selected profile
+func (baseCmd *BaseCommand) LifecycleRunE(action LifecycleAction) RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		profiles, err := baseCmd.SelectedProfiles(cmd)
+		if err != nil {
+			return err
+		}
+		if profiles == nil {
+			resp, err := action.Perform(baseCmd.NewAPIClient())
+			if err != nil {
+				return err
+			}
+			return PrettyPrintJSONResponse(resp)
+		}
+		return baseCmd.runLifecycleActionForProfiles(cmd, action, profiles)
+	}
+}
+
+// runLifecycleActionForProfiles applies the action to each profile, confirming each unless --yes is given,
+// and renders a summary of the results
+func (baseCmd *BaseCommand) runLifecycleActionForProfiles(cmd *cobra.Command, action LifecycleAction, profiles []*Profile) error {
+	results := [][]string{}
+	failures := 0
+	for _, profile := range profiles {
+		if scope := cmd.Annotations[annotationScope]; !profile.HasScope(scope) {
+			results = append(results, []string{profile.Name, profile.Optimizer, fmt.Sprintf("skipped: token lacks the %q scope", scope)})
+			continue
+		}
+		confirmed, err := baseCmd.Confirm(fmt.Sprintf("%s optimizer %s (profile %q)?", strings.Title(action.Verb), profile.Optimizer, profile.Name), false)
+		if err != nil {
+			return err
+		}
+
+		result := "skipped"
+		if confirmed {
+			if _, err := action.Perform(baseCmd.NewAPIClientForProfile(profile)); err != nil {
+				result = fmt.Sprintf("failed: %s", err)
+				failures++
+			} else {
+				result = action.Past
+			}
+		}
+		results = append(results, []string{profile.Name, profile.Optimizer, result})
+	}
+
+	table := baseCmd.newPlainTable()
+	table.SetHeader([]string{"PROFILE", "OPTIMIZER", "RESULT"})
+	table.AppendBulk(results)
+	table.Render()
+
+	if failures > 0 {
+		return fmt.Errorf("failed to %s %d of %d optimizers", action.Verb, failures, len(profiles))
+	}
+	return nil
+}
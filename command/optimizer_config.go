@@ -16,6 +16,7 @@ package command
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -26,6 +27,7 @@ import (
 	"github.com/opsani/cli/opsani"
 	"github.com/spf13/cobra"
 	"github.com/tidwall/gjson"
+	"sigs.k8s.io/yaml"
 )
 
 /**
@@ -54,24 +56,63 @@ func NewOptimizerConfigEditCommand(baseCmd *BaseCommand) *cobra.Command {
 	return &cobra.Command{
 		Use:   "edit [PATH=VALUE ...]",
 		Short: "Edit optimizer config",
-		Args:  ValidSetJSONKeyPathArgs,
+		Long: `Edit optimizer config in your editor and submit the changes.
+
+Configs rejected by the optimizer are re-opened in the editor annotated with the error.
+Saving an empty file cancels the edit.`,
+		Args: ValidSetJSONKeyPathArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Create temp file
-			tempFile, err := ioutil.TempFile(os.TempDir(), "*.json")
+			format, err := configEditFormatNamed(appConfig.Format)
 			if err != nil {
 				return err
 			}
-			filename := tempFile.Name()
 
-			// Download config to temp
+			// Download config unless it has been supplied as input
 			client := baseCmd.NewAPIClient()
-			resp, err := client.GetConfig()
+			var config []byte
+			if appConfig.InputFile != "" {
+				if appConfig.InputFile == "-" && (len(args) == 0 || appConfig.Interactive) {
+					return fmt.Errorf("cannot edit interactively when reading config from stdin")
+				}
+				if config, err = readConfigInput(cmd, appConfig.InputFile); err != nil {
+					return err
+				}
+			} else {
+				resp, err := client.GetConfig()
+				if err != nil {
+					return err
+				}
+				config = resp.Body()
+			}
+
+			// Apply any inline path edits
+			if len(args) > 0 {
+				config, err = SetJSONKeyPathValuesFromStringsOnBytes(args, config)
+				if err != nil {
+					return err
+				}
+			}
+
+			buffer, err := format.render(config)
 			if err != nil {
 				return err
 			}
-			if err = opsani.WritePrettyJSONBytesToFile(resp.Body(), filename); err != nil {
+
+			// Submit directly unless editing interactively
+			if len(args) > 0 && !appConfig.Interactive {
+				resp, err := client.SetConfigFromBody(config, appConfig.ApplyNow)
+				if err != nil {
+					return err
+				}
+				return PrettyPrintJSONResponse(resp)
+			}
+
+			// Create temp file
+			tempFile, err := ioutil.TempFile(os.TempDir(), "*."+format.extension)
+			if err != nil {
 				return err
 			}
+			filename := tempFile.Name()
 
 			// Defer removal of the temporary file in case any of the next steps fail.
 			defer os.Remove(filename)
@@ -80,43 +121,116 @@ func NewOptimizerConfigEditCommand(baseCmd *BaseCommand) *cobra.Command {
 				return err
 			}
 
-			// Apply any inline path edits
-			if len(args) > 0 {
-				config, err := ioutil.ReadFile(filename)
-				if err != nil {
+			// Edit until the config is accepted or the edit is cancelled
+			for {
+				if err = ioutil.WriteFile(filename, buffer, 0600); err != nil {
 					return err
 				}
-
-				config, err = SetJSONKeyPathValuesFromStringsOnBytes(args, config)
-				if err != nil {
+				if err = openFileInEditor(filename, appConfig.Editor); err != nil {
 					return err
 				}
-
-				if err = ioutil.WriteFile(filename, config, 0755); err != nil {
+				if buffer, err = ioutil.ReadFile(filename); err != nil {
 					return err
 				}
-			}
+				buffer = format.stripAnnotations(buffer)
+				if len(strings.TrimSpace(string(buffer))) == 0 {
+					return fmt.Errorf("edit cancelled: config is empty")
+				}
 
-			// Edit interactively if necessary
-			if len(args) == 0 || appConfig.Interactive {
-				if err = openFileInEditor(filename, appConfig.Editor); err != nil {
+				body, err := format.parse(buffer)
+				if err != nil {
+					buffer = format.annotate(buffer, err.Error())
+					continue
+				}
+
+				// Send it back
+				resp, err := client.SetConfigFromBody(body, appConfig.ApplyNow)
+				var apiErr *opsani.APIError
+				if errors.As(err, &apiErr) {
+					buffer = format.annotate(buffer, apiErrorDetails(apiErr))
+					continue
+				} else if err != nil {
 					return err
 				}
+				return PrettyPrintJSONResponse(resp)
 			}
+		},
+	}
+}
 
-			body, err := ioutil.ReadFile(filename)
-			if err != nil {
-				return err
-			}
+// configEditFormat describes how a config is presented for editing
+type configEditFormat struct {
+	extension string
+	comment   string
+	render    func(config []byte) ([]byte, error)
+	parse     func(buffer []byte) ([]byte, error)
+}
 
-			// Send it back
-			resp, err = client.SetConfigFromBody(body, appConfig.ApplyNow)
-			if err != nil {
-				return err
+var configEditFormats = map[string]configEditFormat{
+	"json": {
+		extension: "json",
+		comment:   "//",
+		render: func(config []byte) ([]byte, error) {
+			var obj interface{}
+			if err := json.Unmarshal(config, &obj); err != nil {
+				return nil, err
 			}
-			return PrettyPrintJSONResponse(resp)
+			return json.MarshalIndent(obj, "", "  ")
+		},
+		parse: func(buffer []byte) ([]byte, error) {
+			if err := json.Unmarshal(buffer, &map[string]interface{}{}); err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+			return buffer, nil
+		},
+	},
+	"yaml": {
+		extension: "yaml",
+		comment:   "#",
+		render:    yaml.JSONToYAML,
+		parse: func(buffer []byte) ([]byte, error) {
+			return configJSONFromBytes(buffer, "config")
 		},
+	},
+}
+
+func configEditFormatNamed(name string) (configEditFormat, error) {
+	format, ok := configEditFormats[name]
+	if !ok {
+		return format, fmt.Errorf("invalid format %q: must be %q or %q", name, "json", "yaml")
 	}
+	return format, nil
+}
+
+// annotationPrefix marks lines added by the CLI so they can be removed before submission
+func (f configEditFormat) annotationPrefix() string {
+	return f.comment + "| "
+}
+
+// annotate prepends an error annotation to the buffer, replacing any previous annotation
+// The remainder of the buffer, including any comments, is preserved
+func (f configEditFormat) annotate(buffer []byte, message string) []byte {
+	prefix := f.annotationPrefix()
+	annotation := new(strings.Builder)
+	fmt.Fprintf(annotation, "%sThe config was rejected and has been re-opened for editing.\n", prefix)
+	fmt.Fprintf(annotation, "%sSave an empty file to cancel the edit.\n", prefix)
+	fmt.Fprintf(annotation, "%s\n", strings.TrimSpace(prefix))
+	for _, line := range strings.Split(strings.TrimSpace(message), "\n") {
+		fmt.Fprintf(annotation, "%sError: %s\n", prefix, line)
+	}
+	return append([]byte(annotation.String()), f.stripAnnotations(buffer)...)
+}
+
+// stripAnnotations removes lines added by annotate
+func (f configEditFormat) stripAnnotations(buffer []byte) []byte {
+	prefix := strings.TrimSpace(f.annotationPrefix())
+	lines := []string{}
+	for _, line := range strings.SplitAfter(string(buffer), "\n") {
+		if !strings.HasPrefix(line, prefix) {
+			lines = append(lines, line)
+		}
+	}
+	return []byte(strings.Join(lines, ""))
 }
 
 // NewOptimizerConfigGetCommand returns a new Opsani CLI `app config get` action
@@ -172,18 +286,11 @@ func NewOptimizerConfigGetCommand(baseCmd *BaseCommand) *cobra.Command {
 	}
 }
 
-func bodyForConfigUpdateWithArgs(args []string) (interface{}, error) {
+// bodyForConfigUpdateWithArgs returns the config from the input file or first argument
+// A filename of "-" reads the config from stdin
+func bodyForConfigUpdateWithArgs(cmd *cobra.Command, args []string) (interface{}, error) {
 	if filename := appConfig.InputFile; filename != "" {
-		bytes, err := ioutil.ReadFile(filename)
-		if err != nil {
-			return nil, err
-		}
-
-		err = json.Unmarshal(bytes, &map[string]interface{}{})
-		if err != nil {
-			return nil, fmt.Errorf("file %v is not valid JSON: %w", filename, err)
-		}
-		return bytes, nil
+		return readConfigInput(cmd, filename)
 	} else {
 		if len(args) == 0 {
 			return nil, fmt.Errorf("cannot patch without a JSON config argument")
@@ -192,6 +299,43 @@ func bodyForConfigUpdateWithArgs(args []string) (interface{}, error) {
 	}
 }
 
+// readConfigInput reads a config document from the named file or from stdin when the filename is "-"
+// JSON and YAML documents are accepted and returned as JSON
+func readConfigInput(cmd *cobra.Command, filename string) ([]byte, error) {
+	var bytes []byte
+	var err error
+	if filename == "-" {
+		filename = "stdin"
+		bytes, err = ioutil.ReadAll(cmd.InOrStdin())
+	} else {
+		bytes, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return configJSONFromBytes(bytes, filename)
+}
+
+// configJSONFromBytes validates that the bytes contain a config object, converting YAML to JSON
+// Documents beginning with a brace are treated as JSON, everything else as YAML
+func configJSONFromBytes(bytes []byte, filename string) ([]byte, error) {
+	if trimmed := strings.TrimSpace(string(bytes)); !strings.HasPrefix(trimmed, "{") {
+		converted, err := yaml.YAMLToJSON(bytes)
+		if err != nil {
+			return nil, fmt.Errorf("file %v is not valid YAML: %w", filename, err)
+		}
+		if !strings.HasPrefix(string(converted), "{") {
+			return nil, fmt.Errorf("file %v does not contain a config object", filename)
+		}
+		return converted, nil
+	}
+
+	if err := json.Unmarshal(bytes, &map[string]interface{}{}); err != nil {
+		return nil, fmt.Errorf("file %v is not valid JSON: %w", filename, err)
+	}
+	return bytes, nil
+}
+
 // NewOptimizerConfigSetCommand returns a new Opsani CLI `app config set` action
 func NewOptimizerConfigSetCommand(baseCmd *BaseCommand) *cobra.Command {
 	return &cobra.Command{
@@ -200,7 +344,7 @@ func NewOptimizerConfigSetCommand(baseCmd *BaseCommand) *cobra.Command {
 		Args:  RangeOfValidJSONArgs(0, 1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			client := baseCmd.NewAPIClient()
-			body, err := bodyForConfigUpdateWithArgs(args)
+			body, err := bodyForConfigUpdateWithArgs(cmd, args)
 			if err != nil {
 				return err
 			}
@@ -223,7 +367,7 @@ func NewOptimizerConfigPatchCommand(baseCmd *BaseCommand) *cobra.Command {
 		Args:  RangeOfValidJSONArgs(0, 1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			client := baseCmd.NewAPIClient()
-			body, err := bodyForConfigUpdateWithArgs(args)
+			body, err := bodyForConfigUpdateWithArgs(cmd, args)
 			if err != nil {
 				return err
 			}
@@ -243,6 +387,7 @@ var appConfig = struct {
 	ApplyNow    bool
 	Editor      string
 	Interactive bool
+	Format      string
 }{}
 
 // NewOptimizerConfigCommand returns a new Opsani CLI `app config` action
@@ -256,11 +401,15 @@ func NewOptimizerConfigCommand(baseCmd *BaseCommand) *cobra.Command {
 	appConfigSetCmd := NewOptimizerConfigSetCommand(baseCmd)
 	appConfigPatchCmd := NewOptimizerConfigPatchCommand(baseCmd)
 	appConfigEditCmd := NewOptimizerConfigEditCommand(baseCmd)
+	appConfigBrowseCmd := NewOptimizerConfigBrowseCommand(baseCmd)
+	appConfigSetValuesCmd := NewOptimizerConfigSetValuesCommand(baseCmd)
 
 	appConfigCmd.AddCommand(appConfigGetCmd)
-	appConfigCmd.AddCommand(appConfigSetCmd)
-	appConfigCmd.AddCommand(appConfigPatchCmd)
-	appConfigCmd.AddCommand(appConfigEditCmd)
+	appConfigCmd.AddCommand(RequireScope(appConfigSetCmd, ScopeWrite))
+	appConfigCmd.AddCommand(RequireScope(appConfigPatchCmd, ScopeWrite))
+	appConfigCmd.AddCommand(RequireScope(appConfigEditCmd, ScopeWrite))
+	appConfigCmd.AddCommand(appConfigBrowseCmd)
+	appConfigCmd.AddCommand(RequireScope(appConfigSetValuesCmd, ScopeWrite))
 
 	// alias for app config get
 	appConfigCmd.Args = appConfigGetCmd.Args
@@ -272,16 +421,20 @@ func NewOptimizerConfigCommand(baseCmd *BaseCommand) *cobra.Command {
 
 	// app config set & patch flags
 	updateGlobs := []string{"*.json", "*.yaml", "*.yml"}
-	appConfigPatchCmd.Flags().StringVarP(&appConfig.InputFile, "file", "f", "", "File containing config to apply")
+	appConfigPatchCmd.Flags().StringVarP(&appConfig.InputFile, "file", "f", "", "File containing config to apply (\"-\" reads from stdin)")
 	appConfigPatchCmd.MarkFlagFilename("file", updateGlobs...)
 	appConfigPatchCmd.Flags().BoolVarP(&appConfig.ApplyNow, "apply", "a", true, "Apply the config changes immediately")
-	appConfigSetCmd.Flags().StringVarP(&appConfig.InputFile, "file", "f", "", "File containing config to apply")
+	appConfigSetCmd.Flags().StringVarP(&appConfig.InputFile, "file", "f", "", "File containing config to apply (\"-\" reads from stdin)")
 	appConfigSetCmd.MarkFlagFilename("file", updateGlobs...)
 	appConfigSetCmd.Flags().BoolVarP(&appConfig.ApplyNow, "apply", "a", true, "Apply the config changes immediately")
+	appConfigBrowseCmd.Flags().BoolVarP(&appConfig.ApplyNow, "apply", "a", true, "Apply the config changes immediately")
 
 	// app edit flags
 	appConfigEditCmd.Flags().StringVarP(&appConfig.Editor, "editor", "e", os.Getenv("EDITOR"), "Edit the config with the given editor (overrides $EDITOR)")
 	appConfigEditCmd.Flags().BoolVarP(&appConfig.Interactive, "interactive", "i", false, "Edit the config changes interactively")
+	appConfigEditCmd.Flags().StringVar(&appConfig.Format, "format", "json", "Format to edit the config in: {json|yaml}")
+	appConfigEditCmd.Flags().StringVarP(&appConfig.InputFile, "file", "f", "", "File containing config to edit instead of the current config (\"-\" reads from stdin)")
+	appConfigEditCmd.MarkFlagFilename("file", updateGlobs...)
 
 	return appConfigCmd
 }
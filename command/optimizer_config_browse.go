@@ -0,0 +1,286 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// NewOptimizerConfigBrowseCommand returns a new Opsani CLI `optimizer config browse` action
+func NewOptimizerConfigBrowseCommand(baseCmd *BaseCommand) *cobra.Command {
+	return &cobra.Command{
+		Use:   "browse",
+		Short: "Browse optimizer config interactively",
+		Long: `Browse the optimizer config as a tree, expanding objects and arrays to explore its structure.
+
+Selecting a value displays its path (usable with "opsani optimizer config get") and copies it
+to the clipboard on supported terminals. Values can be edited inline and the edits are
+submitted as a single patch when browsing is finished.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client := baseCmd.NewAPIClient()
+			resp, err := client.GetConfig()
+			if err != nil {
+				return err
+			}
+
+			browser := &configBrowser{BaseCommand: baseCmd, config: resp.Body(), patch: []byte("{}")}
+			if err := browser.Run(); err != nil {
+				return err
+			}
+			if !browser.edited {
+				return nil
+			}
+
+			baseCmd.Println("Patch:")
+			if err := baseCmd.PrettyPrintJSONBytes(browser.patch); err != nil {
+				return err
+			}
+			confirmed, err := baseCmd.Confirm("Apply patch?", false)
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				return nil
+			}
+			resp, err = client.PatchConfigFromBody(browser.patch, appConfig.ApplyNow)
+			if err != nil {
+				return err
+			}
+			return PrettyPrintJSONResponse(resp)
+		},
+	}
+}
+
+// GJSONPath joins path components into a gjson path, escaping special characters
+func GJSONPath(components ...string) string {
+	escaped := make([]string, len(components))
+	for i, component := range components {
+		var b strings.Builder
+		for _, r := range component {
+			switch r {
+			case '.', '*', '?', '|', '#', '@', '\\':
+				b.WriteRune('\\')
+			}
+			b.WriteRune(r)
+		}
+		escaped[i] = b.String()
+	}
+	return strings.Join(escaped, ".")
+}
+
+// SetConfigPatchValue sets the value at the path in the config and in the JSON merge patch of its edits
+// Values that parse as JSON are set verbatim and all other values are set as strings
+func SetConfigPatchValue(config, patch []byte, path string, value string) ([]byte, []byte, error) {
+	raw := value
+	if !json.Valid([]byte(value)) {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		raw = string(data)
+	}
+	return SetConfigPatchRawValue(config, patch, path, raw)
+}
+
+// SetConfigPatchRawValue sets the raw JSON value at the path in the config and in the JSON merge patch of its edits
+// Merge patches replace arrays whole, so paths through an array patch the entire edited top-level value instead
+func SetConfigPatchRawValue(config, patch []byte, path string, raw string) ([]byte, []byte, error) {
+	config, err := sjson.SetRawBytes(config, path, []byte(raw))
+	if err != nil {
+		return nil, nil, err
+	}
+	prefixes := gjsonPathPrefixes(path)
+	for _, prefix := range prefixes {
+		if gjson.GetBytes(config, prefix).IsArray() {
+			path, raw = prefixes[0], gjson.GetBytes(config, prefixes[0]).Raw
+			break
+		}
+	}
+	patch, err = sjson.SetRawBytes(patch, path, []byte(raw))
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, patch, nil
+}
+
+// gjsonPathPrefixes returns the paths of the ancestors of a gjson path, outermost first
+func gjsonPathPrefixes(path string) []string {
+	prefixes := []string{}
+	escaped := false
+	for i, r := range path {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '.':
+			prefixes = append(prefixes, path[:i])
+		}
+	}
+	return prefixes
+}
+
+// ConfigNodeEntry describes a child of a node in the config tree
+type ConfigNodeEntry struct {
+	Key   string
+	Value gjson.Result
+}
+
+// IsLeaf reports whether the entry is a scalar value rather than an object or array
+func (e ConfigNodeEntry) IsLeaf() bool {
+	return !e.Value.IsObject() && !e.Value.IsArray()
+}
+
+// Label returns a one-line description of the entry for display in the tree
+func (e ConfigNodeEntry) Label() string {
+	switch {
+	case e.Value.IsObject():
+		return fmt.Sprintf("%s {%d}", e.Key, len(e.Value.Map()))
+	case e.Value.IsArray():
+		return fmt.Sprintf("%s [%d]", e.Key, len(e.Value.Array()))
+	default:
+		raw := e.Value.Raw
+		if len(raw) > 60 {
+			raw = raw[:57] + "..."
+		}
+		return fmt.Sprintf("%s = %s", e.Key, raw)
+	}
+}
+
+// ConfigNodeEntries returns the children of an object or array node in document order
+func ConfigNodeEntries(node gjson.Result) []ConfigNodeEntry {
+	entries := []ConfigNodeEntry{}
+	index := 0
+	node.ForEach(func(key, value gjson.Result) bool {
+		k := key.String()
+		if node.IsArray() {
+			k = fmt.Sprint(index)
+		}
+		entries = append(entries, ConfigNodeEntry{Key: k, Value: value})
+		index++
+		return true
+	})
+	return entries
+}
+
+// configBrowser navigates the config tree interactively, accumulating edits into a patch
+type configBrowser struct {
+	*BaseCommand
+	config []byte
+	patch  []byte
+	path   []string
+	edited bool
+}
+
+const (
+	configBrowserUp   = ".."
+	configBrowserDone = "(done)"
+)
+
+// Run presents the tree until the user is done browsing
+func (b *configBrowser) Run() error {
+	for {
+		node := gjson.ParseBytes(b.config)
+		if len(b.path) > 0 {
+			node = gjson.GetBytes(b.config, GJSONPath(b.path...))
+		}
+		entries := ConfigNodeEntries(node)
+		options := []string{}
+		for _, entry := range entries {
+			options = append(options, entry.Label())
+		}
+		if len(b.path) > 0 {
+			options = append(options, configBrowserUp)
+		}
+		options = append(options, configBrowserDone)
+
+		location := "config"
+		if len(b.path) > 0 {
+			location = GJSONPath(b.path...)
+		}
+		selected := 0
+		if err := b.AskOne(&survey.Select{
+			Message:  location,
+			Options:  options,
+			PageSize: 15,
+		}, &selected); err != nil {
+			return err
+		}
+
+		switch {
+		case selected < len(entries):
+			entry := entries[selected]
+			if entry.IsLeaf() {
+				if err := b.selectLeaf(entry); err != nil {
+					return err
+				}
+			} else {
+				b.path = append(b.path, entry.Key)
+			}
+		case options[selected] == configBrowserUp:
+			b.path = b.path[:len(b.path)-1]
+		default:
+			return nil
+		}
+	}
+}
+
+// selectLeaf displays the path of a leaf value and offers to edit it
+func (b *configBrowser) selectLeaf(entry ConfigNodeEntry) error {
+	path := GJSONPath(append(append([]string{}, b.path...), entry.Key)...)
+	b.copyToClipboard(path)
+	b.Printf("%s %s\n", color.New(color.Bold).Sprint("Path:"), path)
+
+	edit := false
+	if err := b.AskOne(&survey.Confirm{Message: "Edit value?"}, &edit); err != nil {
+		return err
+	}
+	if !edit {
+		return nil
+	}
+
+	value := ""
+	if err := b.AskOne(&survey.Input{Message: path, Default: entry.Value.Raw}, &value); err != nil {
+		return err
+	}
+	if value == entry.Value.Raw {
+		return nil
+	}
+	config, patch, err := SetConfigPatchValue(b.config, b.patch, path, value)
+	if err != nil {
+		return err
+	}
+	b.config, b.patch, b.edited = config, patch, true
+	return nil
+}
+
+// copyToClipboard copies text to the system clipboard via the OSC 52 terminal escape sequence
+// Terminals without OSC 52 support ignore the sequence
+func (b *configBrowser) copyToClipboard(text string) {
+	if b.Accessible() {
+		return
+	}
+	fmt.Fprintf(b.stdio().Out, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+}
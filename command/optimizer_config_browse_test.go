@@ -0,0 +1,64 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestGJSONPathEscapesComponents(t *testing.T) {
+	require.Equal(t, "adjustment.replicas", command.GJSONPath("adjustment", "replicas"))
+	require.Equal(t, `components.web\.main.settings`, command.GJSONPath("components", "web.main", "settings"))
+	require.Equal(t, `metrics.p\*`, command.GJSONPath("metrics", "p*"))
+
+	config := []byte(`{"components": {"web.main": {"settings": {"cpu": 1}}}}`)
+	require.Equal(t, int64(1), gjson.GetBytes(config, command.GJSONPath("components", "web.main", "settings", "cpu")).Int())
+}
+
+func TestSetConfigPatchValue(t *testing.T) {
+	config, patch, err := command.SetConfigPatchValue([]byte(`{"adjustment": {"replicas": 2}}`), []byte("{}"), "adjustment.replicas", "3")
+	require.NoError(t, err)
+	config, patch, err = command.SetConfigPatchValue(config, patch, `components.web\.main.mode`, "fast")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"adjustment": {"replicas": 3}, "components": {"web.main": {"mode": "fast"}}}`, string(patch))
+	require.JSONEq(t, string(patch), string(config))
+}
+
+func TestSetConfigPatchValueInArrayKeepsSiblings(t *testing.T) {
+	config := []byte(`{"a": [{"b": 1, "c": 2}, {"b": 3, "c": 4}], "d": {"e": 5}}`)
+	config, patch, err := command.SetConfigPatchValue(config, []byte("{}"), "a.1.b", "9")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"a": [{"b": 1, "c": 2}, {"b": 9, "c": 4}]}`, string(patch))
+	_, patch, err = command.SetConfigPatchValue(config, patch, "d.e", "6")
+	require.NoError(t, err)
+	require.JSONEq(t, `{"a": [{"b": 1, "c": 2}, {"b": 9, "c": 4}], "d": {"e": 6}}`, string(patch))
+}
+
+func TestConfigNodeEntries(t *testing.T) {
+	node := gjson.Parse(`{"adjustment": {"replicas": 2}, "metrics": ["cpu", "mem"], "mode": "fast"}`)
+	labels := []string{}
+	for _, entry := range command.ConfigNodeEntries(node) {
+		labels = append(labels, entry.Label())
+	}
+	require.Equal(t, []string{"adjustment {1}", "metrics [2]", `mode = "fast"`}, labels)
+
+	entries := command.ConfigNodeEntries(node.Get("metrics"))
+	require.Equal(t, "1", entries[1].Key)
+	require.True(t, entries[1].IsLeaf())
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+)
+
+// NewOptimizerConfigSetValuesCommand returns a new Opsani CLI `optimizer config set-values` action
+func NewOptimizerConfigSetValuesCommand(baseCmd *BaseCommand) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set-values PATH=VALUE ...",
+		Short: "Set optimizer config values by path",
+		Long: `Set optimizer config values by path and submit the changes as a patch.
+
+Values are typed according to the existing value at the path: numbers and booleans must parse
+as such and strings are set verbatim. Values for new paths that are valid JSON are set as JSON
+and all others are set as strings.`,
+		Example: `  opsani optimizer config set-values adjustment.cpu.max=3.0 adjustment.mem.min=1Gi`,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := ValidSetJSONKeyPathArgs(cmd, args); err != nil {
+				return err
+			}
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			client := baseCmd.NewAPIClient()
+			resp, err := client.GetConfig()
+			if err != nil {
+				return err
+			}
+			patch, err := ConfigPatchForValues(resp.Body(), args)
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				return baseCmd.PrettyPrintJSONBytes(patch)
+			}
+
+			resp, err = client.PatchConfigFromBody(patch, appConfig.ApplyNow)
+			if err != nil {
+				return err
+			}
+			return PrettyPrintJSONResponse(resp)
+		},
+	}
+	cmd.Flags().BoolVarP(&appConfig.ApplyNow, "apply", "a", true, "Apply the config changes immediately")
+	cmd.Flags().Bool("dry-run", false, "Display the patch without submitting it")
+	return cmd
+}
+
+// ConfigPatchForValues returns a JSON merge patch setting each PATH=VALUE expression
+// Values are typed to match the existing value at the path in the config. Paths through an array
+// patch the entire edited top-level value as merge patches replace arrays whole
+func ConfigPatchForValues(config []byte, expressions []string) ([]byte, error) {
+	patch := []byte("{}")
+	for _, expression := range expressions {
+		components := strings.SplitN(expression, "=", 2)
+		path, value := components[0], components[1]
+
+		raw, err := typedConfigValue(gjson.GetBytes(config, path), path, value)
+		if err != nil {
+			return nil, err
+		}
+		if config, patch, err = SetConfigPatchRawValue(config, patch, path, raw); err != nil {
+			return nil, err
+		}
+	}
+	return patch, nil
+}
+
+// typedConfigValue returns the raw JSON for a value typed to match the existing value
+func typedConfigValue(existing gjson.Result, path string, value string) (string, error) {
+	parsed := gjson.Result{}
+	if gjson.Valid(value) {
+		parsed = gjson.Parse(value)
+	}
+	switch existing.Type {
+	case gjson.Number:
+		if parsed.Type != gjson.Number {
+			return "", fmt.Errorf("invalid value %q for %s: must be a number", value, path)
+		}
+		return value, nil
+	case gjson.True, gjson.False:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for %s: must be a boolean", value, path)
+		}
+		return strconv.FormatBool(b), nil
+	case gjson.String:
+		return jsonString(value)
+	default:
+		if parsed.Exists() {
+			return value, nil
+		} else if existing.Exists() {
+			return "", fmt.Errorf("invalid value %q for %s: must be valid JSON", value, path)
+		}
+		return jsonString(value)
+	}
+}
+
+func jsonString(value string) (string, error) {
+	data, err := json.Marshal(value)
+	return string(data), err
+}
@@ -0,0 +1,52 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+)
+
+var setValuesConfig = []byte(`{"cpu": {"max": 2, "unit": "cores"}, "mem": {"min": "512Mi"}, "enabled": true, "components": {}}`)
+
+func TestConfigPatchForValuesTypesFromExistingValues(t *testing.T) {
+	patch, err := command.ConfigPatchForValues(setValuesConfig, []string{"cpu.max=3.0", "mem.min=1Gi", "enabled=false", "cpu.unit=4"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"cpu": {"max": 3.0, "unit": "4"}, "mem": {"min": "1Gi"}, "enabled": false}`, string(patch))
+}
+
+func TestConfigPatchForValuesNewPaths(t *testing.T) {
+	patch, err := command.ConfigPatchForValues(setValuesConfig, []string{"replicas=3", "mode=fast", `tags=["a","b"]`})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"replicas": 3, "mode": "fast", "tags": ["a", "b"]}`, string(patch))
+}
+
+func TestConfigPatchForValuesInArraysKeepsSiblings(t *testing.T) {
+	config := []byte(`{"a": [{"b": 1, "c": 2}, {"b": 3, "c": 4}], "d": {"e": 5}}`)
+	patch, err := command.ConfigPatchForValues(config, []string{"a.1.b=9", "a.0.c=7", "d.e=6"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"a": [{"b": 1, "c": 7}, {"b": 9, "c": 4}], "d": {"e": 6}}`, string(patch))
+}
+
+func TestConfigPatchForValuesInvalidTypes(t *testing.T) {
+	_, err := command.ConfigPatchForValues(setValuesConfig, []string{"cpu.max=lots"})
+	require.EqualError(t, err, `invalid value "lots" for cpu.max: must be a number`)
+	_, err = command.ConfigPatchForValues(setValuesConfig, []string{"enabled=maybe"})
+	require.EqualError(t, err, `invalid value "maybe" for enabled: must be a boolean`)
+	_, err = command.ConfigPatchForValues(setValuesConfig, []string{"components=web"})
+	require.EqualError(t, err, `invalid value "web" for components: must be valid JSON`)
+}
@@ -15,6 +15,14 @@
 package command_test
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/opsani/cli/command"
@@ -57,3 +65,206 @@ func (s *AppConfigTestSuite) TestRunningAppConfigSetHelp() {
 	s.Require().NoError(err)
 	s.Require().Contains(output, "Set optimizer config")
 }
+
+func (s *AppConfigTestSuite) TestRunningAppConfigSetFromStdin() {
+	var body string
+	server := s.configServer(&body)
+	defer server.Close()
+
+	s.SetStdin(strings.NewReader(`{"adjustment": {"replicas": 2}}`))
+	_, err := s.Execute("optimizer", "config", "set", "-f", "-")
+	s.Require().NoError(err)
+	s.Require().JSONEq(`{"adjustment": {"replicas": 2}}`, body)
+}
+
+func (s *AppConfigTestSuite) TestRunningAppConfigPatchFromStdinInvalidJSON() {
+	s.SetEnv("OPSANI_OPTIMIZER", "example.com/app")
+	s.SetEnv("OPSANI_TOKEN", "123456")
+	s.SetStdin(strings.NewReader(`{"replicas": }`))
+	_, err := s.Execute("optimizer", "config", "patch", "-f", "-")
+	s.Require().EqualError(err, "file stdin is not valid JSON: invalid character '}' looking for beginning of value")
+}
+
+func (s *AppConfigTestSuite) TestRunningAppConfigPatchFromStdinNotAnObject() {
+	s.SetEnv("OPSANI_OPTIMIZER", "example.com/app")
+	s.SetEnv("OPSANI_TOKEN", "123456")
+	s.SetStdin(strings.NewReader("- replicas\n- cpu\n"))
+	_, err := s.Execute("optimizer", "config", "patch", "-f", "-")
+	s.Require().EqualError(err, "file stdin does not contain a config object")
+}
+
+// configServer returns a test server recording the body of config updates
+func (s *AppConfigTestSuite) configServer(body *string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		*body = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status": "ok"}`))
+	}))
+	s.SetEnv("OPSANI_BASE_URL", server.URL)
+	s.SetEnv("OPSANI_OPTIMIZER", "example.com/app")
+	s.SetEnv("OPSANI_TOKEN", "123456")
+	return server
+}
+
+func (s *AppConfigTestSuite) TestRunningAppConfigPatchFromStdinYAML() {
+	var body string
+	server := s.configServer(&body)
+	defer server.Close()
+
+	s.SetStdin(strings.NewReader("adjustment:\n  replicas: 2\n"))
+	_, err := s.Execute("optimizer", "config", "patch", "-f", "-")
+	s.Require().NoError(err)
+	s.Require().JSONEq(`{"adjustment": {"replicas": 2}}`, body)
+}
+
+func (s *AppConfigTestSuite) TestRunningAppConfigEditFromStdin() {
+	var body string
+	server := s.configServer(&body)
+	defer server.Close()
+
+	s.SetStdin(strings.NewReader("adjustment:\n  replicas: 2\n"))
+	_, err := s.Execute("optimizer", "config", "edit", "-f", "-", "adjustment.mode=fast")
+	s.Require().NoError(err)
+	s.Require().JSONEq(`{"adjustment": {"replicas": 2, "mode": "fast"}}`, body)
+}
+
+func (s *AppConfigTestSuite) TestRunningAppConfigEditFromStdinInteractively() {
+	s.SetEnv("OPSANI_OPTIMIZER", "example.com/app")
+	s.SetEnv("OPSANI_TOKEN", "123456")
+	s.SetStdin(strings.NewReader("{}"))
+	_, err := s.Execute("optimizer", "config", "edit", "-f", "-")
+	s.Require().EqualError(err, "cannot edit interactively when reading config from stdin")
+}
+
+// editorScript writes an executable shell script for use as $EDITOR
+// The script is invoked with the path of the file being edited as $1
+func (s *AppConfigTestSuite) editorScript(script string) string {
+	if runtime.GOOS == "windows" {
+		s.T().Skip("editor scripts require a POSIX shell")
+	}
+	dir, err := ioutil.TempDir("", "opsani-editor")
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "editor")
+	s.Require().NoError(ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script+"\nrm -f \"$1.bak\"\n"), 0755))
+	return path
+}
+
+// editServer returns a test server serving a config and recording the body of each update
+// Updates are rejected with the given messages in order before being accepted
+func (s *AppConfigTestSuite) editServer(bodies *[]string, rejections ...string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"adjustment": {"replicas": 2}}`))
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		*bodies = append(*bodies, string(data))
+		if len(*bodies) <= len(rejections) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"status": "bad-request", "message": %q}`, rejections[len(*bodies)-1])
+			return
+		}
+		w.Write([]byte(`{"status": "ok"}`))
+	}))
+	s.SetEnv("OPSANI_BASE_URL", server.URL)
+	s.SetEnv("OPSANI_OPTIMIZER", "example.com/app")
+	s.SetEnv("OPSANI_TOKEN", "123456")
+	return server
+}
+
+func (s *AppConfigTestSuite) TestRunningAppConfigEditYAML() {
+	var bodies []string
+	server := s.editServer(&bodies)
+	defer server.Close()
+
+	editor := s.editorScript(`sed -i.bak 's/replicas: 2/replicas: 3/' "$1"`)
+	_, err := s.Execute("optimizer", "config", "edit", "--format", "yaml", "--editor", editor)
+	s.Require().NoError(err)
+	s.Require().Len(bodies, 1)
+	s.Require().JSONEq(`{"adjustment": {"replicas": 3}}`, bodies[0])
+}
+
+func (s *AppConfigTestSuite) TestRunningAppConfigEditInvalidFormat() {
+	s.SetEnv("OPSANI_OPTIMIZER", "example.com/app")
+	s.SetEnv("OPSANI_TOKEN", "123456")
+	_, err := s.Execute("optimizer", "config", "edit", "--format", "toml")
+	s.Require().EqualError(err, `invalid format "toml": must be "json" or "yaml"`)
+}
+
+func (s *AppConfigTestSuite) TestRunningAppConfigEditEmptyBuffer() {
+	var bodies []string
+	server := s.editServer(&bodies)
+	defer server.Close()
+
+	editor := s.editorScript(`: > "$1"`)
+	_, err := s.Execute("optimizer", "config", "edit", "--editor", editor)
+	s.Require().EqualError(err, "edit cancelled: config is empty")
+	s.Require().Empty(bodies)
+}
+
+func (s *AppConfigTestSuite) TestRunningAppConfigEditRejectedReopensWithAnnotation() {
+	var bodies []string
+	server := s.editServer(&bodies, "replicas must be at least 3")
+	defer server.Close()
+
+	capture, err := ioutil.TempFile("", "opsani-editor-capture")
+	s.Require().NoError(err)
+	defer os.Remove(capture.Name())
+	editor := s.editorScript(fmt.Sprintf(`cat "$1" >> %s
+if ! grep -q '# my comment' "$1"; then
+  sed -i.bak 's/replicas: 2/replicas: 1 # my comment/' "$1"
+else
+  sed -i.bak 's/replicas: 1/replicas: 3/' "$1"
+fi
+`, capture.Name()))
+	_, err = s.Execute("optimizer", "config", "edit", "--format", "yaml", "--editor", editor)
+	s.Require().NoError(err)
+	s.Require().Len(bodies, 2)
+	s.Require().JSONEq(`{"adjustment": {"replicas": 1}}`, bodies[0])
+	s.Require().JSONEq(`{"adjustment": {"replicas": 3}}`, bodies[1])
+
+	edits, err := ioutil.ReadFile(capture.Name())
+	s.Require().NoError(err)
+	s.Require().Contains(string(edits), `#| The config was rejected and has been re-opened for editing.
+#| Save an empty file to cancel the edit.
+#|
+#| Error: replicas must be at least 3
+adjustment:
+  replicas: 1 # my comment
+`)
+}
+
+func (s *AppConfigTestSuite) TestRunningAppConfigBrowseHelp() {
+	output, err := s.Execute("optimizer", "config", "browse", "--help")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Browse the optimizer config as a tree")
+}
+
+func (s *AppConfigTestSuite) TestRunningAppConfigSetValues() {
+	var bodies []string
+	server := s.editServer(&bodies)
+	defer server.Close()
+
+	_, err := s.Execute("optimizer", "config", "set-values", "adjustment.replicas=3", "adjustment.mode=fast")
+	s.Require().NoError(err)
+	s.Require().Len(bodies, 1)
+	s.Require().JSONEq(`{"adjustment": {"replicas": 3, "mode": "fast"}}`, bodies[0])
+}
+
+func (s *AppConfigTestSuite) TestRunningAppConfigSetValuesDryRun() {
+	var bodies []string
+	server := s.editServer(&bodies)
+	defer server.Close()
+
+	_, err := s.Execute("optimizer", "config", "set-values", "--dry-run", "adjustment.replicas=3")
+	s.Require().NoError(err)
+	s.Require().Empty(bodies)
+}
+
+func (s *AppConfigTestSuite) TestRunningAppConfigSetValuesInvalidArgs() {
+	_, err := s.Execute("optimizer", "config", "set-values", "adjustment.replicas")
+	s.Require().EqualError(err, "argument 'adjustment.replicas' is not of the form [PATH]=[VALUE]")
+}
@@ -0,0 +1,220 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+	"sigs.k8s.io/yaml"
+)
+
+// OptimizationTarget identifies a deployment container optimized by the servo
+type OptimizationTarget struct {
+	Namespace  string
+	Deployment string
+	Container  string
+}
+
+// OptimizationTargetDescription summarizes the live state of an optimization target in the cluster
+type OptimizationTargetDescription struct {
+	OptimizationTarget
+	Image         string
+	Requests      map[string]string
+	Limits        map[string]string
+	Services      []string
+	Replicas      int64
+	ReadyReplicas int64
+}
+
+// ServoConfigTargets returns the optimization targets declared in the k8s application components of a servo config
+// Components are named "deployment" or "deployment/container" and reside in the configured namespace or the given default
+func ServoConfigTargets(config []byte, defaultNamespace string) ([]OptimizationTarget, error) {
+	data, err := yaml.YAMLToJSON(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing servo config: %w", err)
+	}
+	k8s := gjson.GetBytes(data, "k8s")
+	namespace := k8s.Get("namespace").String()
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	targets := []OptimizationTarget{}
+	k8s.Get("application.components").ForEach(func(key, _ gjson.Result) bool {
+		deployment, container := key.String(), ""
+		if i := strings.Index(deployment, "/"); i != -1 {
+			deployment, container = deployment[:i], deployment[i+1:]
+		}
+		targets = append(targets, OptimizationTarget{Namespace: namespace, Deployment: deployment, Container: container})
+		return true
+	})
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("servo config does not declare any k8s application components")
+	}
+	return targets, nil
+}
+
+// DescribeOptimizationTarget queries the cluster for the deployment, container, and services of a target
+func DescribeOptimizationTarget(ctx context.Context, kubectl KubectlRunner, target OptimizationTarget) (OptimizationTargetDescription, error) {
+	description := OptimizationTargetDescription{OptimizationTarget: target}
+	deployment, err := kubectl(ctx, "-n", target.Namespace, "get", "deployment", target.Deployment, "-o", "json")
+	if err != nil {
+		return description, err
+	}
+	result := gjson.ParseBytes(deployment)
+	description.Replicas = result.Get("spec.replicas").Int()
+	description.ReadyReplicas = result.Get("status.readyReplicas").Int()
+
+	containers := result.Get("spec.template.spec.containers").Array()
+	var container *gjson.Result
+	for i := range containers {
+		if target.Container == "" || containers[i].Get("name").String() == target.Container {
+			container = &containers[i]
+			break
+		}
+	}
+	if container == nil {
+		return description, fmt.Errorf("container %q not found in deployment %q", target.Container, target.Deployment)
+	}
+	description.Container = container.Get("name").String()
+	description.Image = container.Get("image").String()
+	description.Requests = resourceQuantities(container.Get("resources.requests"))
+	description.Limits = resourceQuantities(container.Get("resources.limits"))
+
+	services, err := kubectl(ctx, "-n", target.Namespace, "get", "services", "-o", "json")
+	if err != nil {
+		return description, err
+	}
+	podLabels := result.Get("spec.template.metadata.labels").Map()
+	for _, service := range gjson.GetBytes(services, "items").Array() {
+		selector := service.Get("spec.selector").Map()
+		if len(selector) == 0 {
+			continue
+		}
+		matches := true
+		for key, value := range selector {
+			if podLabels[key].String() != value.String() {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			description.Services = append(description.Services, service.Get("metadata.name").String())
+		}
+	}
+	return description, nil
+}
+
+func resourceQuantities(result gjson.Result) map[string]string {
+	quantities := map[string]string{}
+	result.ForEach(func(key, value gjson.Result) bool {
+		quantities[key.String()] = value.String()
+		return true
+	})
+	return quantities
+}
+
+// formatResourceQuantities formats resource quantities as sorted name=quantity pairs
+func formatResourceQuantities(quantities map[string]string) string {
+	if len(quantities) == 0 {
+		return "-"
+	}
+	pairs := []string{}
+	for name, quantity := range quantities {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, quantity))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// servoKubectlRunner returns a kubectl runner for the cluster hosting the targets of the servo
+func servoKubectlRunner(servo Servo) KubectlRunner {
+	runner := kubectlRunner(servo.Kubeconfig)
+	if servo.Context == "" {
+		return runner
+	}
+	return func(ctx context.Context, args ...string) ([]byte, error) {
+		return runner(ctx, append([]string{"--context", servo.Context}, args...)...)
+	}
+}
+
+// NewOptimizerDescribeCommand returns a new `opsani app describe` command instance
+func NewOptimizerDescribeCommand(baseCmd *BaseCommand) *cobra.Command {
+	servoCmd := servoCommand{BaseCommand: baseCmd}
+	return &cobra.Command{
+		Use:   "describe",
+		Short: "Describe the application being optimized",
+		Long: `Summarizes the optimization targets declared in the config of the attached servo
+by querying the cluster for the namespace, deployment, container image, resource
+requests and limits, services, and replica count of each target.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if baseCmd.profile == nil || baseCmd.profile.Servo == (Servo{}) {
+				return fmt.Errorf("no servo attached to the active profile: attach one with `opsani servo attach`")
+			}
+			servo := baseCmd.profile.Servo
+			driver, err := servoCmd.servoDriver()
+			if driver == nil {
+				return err
+			}
+			config, err := driver.ConfigData()
+			if err != nil {
+				return fmt.Errorf("failed reading servo config: %w", err)
+			}
+			defaultNamespace := servo.Namespace
+			if servo.Type != "kubernetes" || defaultNamespace == "" {
+				defaultNamespace = "default"
+			}
+			targets, err := ServoConfigTargets(config, defaultNamespace)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := baseCmd.ContextWithTimeout()
+			defer cancel()
+			kubectl := servoKubectlRunner(servo)
+			for i, target := range targets {
+				description, err := DescribeOptimizationTarget(ctx, kubectl, target)
+				if err != nil {
+					return err
+				}
+				if i > 0 {
+					baseCmd.Println()
+				}
+				services := "-"
+				if len(description.Services) > 0 {
+					services = strings.Join(description.Services, ", ")
+				}
+				table := baseCmd.newPlainTable()
+				table.AppendBulk([][]string{
+					{"Namespace:", description.Namespace},
+					{"Deployment:", description.Deployment},
+					{"Container:", description.Container},
+					{"Image:", description.Image},
+					{"Requests:", formatResourceQuantities(description.Requests)},
+					{"Limits:", formatResourceQuantities(description.Limits)},
+					{"Services:", services},
+					{"Replicas:", fmt.Sprintf("%d (%d ready)", description.Replicas, description.ReadyReplicas)},
+				})
+				table.Render()
+			}
+			return nil
+		},
+	}
+}
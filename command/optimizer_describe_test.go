@@ -0,0 +1,135 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const describeServoConfig = `
+k8s:
+  application:
+    components:
+      web/main:
+        settings:
+          cpu: {min: 0.1, max: 0.8}
+`
+
+const describeDeployment = `{
+	"spec": {
+		"replicas": 3,
+		"template": {
+			"metadata": {"labels": {"app": "web", "tier": "frontend"}},
+			"spec": {"containers": [
+				{"name": "envoy", "image": "envoyproxy/envoy:v1.14"},
+				{"name": "main", "image": "opsani/co-http:latest", "resources": {
+					"requests": {"cpu": "100m", "memory": "128Mi"},
+					"limits": {"memory": "256Mi"}
+				}}
+			]}
+		}
+	},
+	"status": {"readyReplicas": 2}
+}`
+
+const describeServices = `{"items": [
+	{"metadata": {"name": "web"}, "spec": {"selector": {"app": "web"}}},
+	{"metadata": {"name": "db"}, "spec": {"selector": {"app": "db"}}},
+	{"metadata": {"name": "external"}, "spec": {}}
+]}`
+
+func TestServoConfigTargets(t *testing.T) {
+	targets, err := command.ServoConfigTargets([]byte(describeServoConfig), "apps")
+	require.NoError(t, err)
+	require.Equal(t, []command.OptimizationTarget{{Namespace: "apps", Deployment: "web", Container: "main"}}, targets)
+
+	targets, err = command.ServoConfigTargets([]byte("k8s:\n  namespace: prod\n  application:\n    components:\n      api: {}\n"), "apps")
+	require.NoError(t, err)
+	require.Equal(t, []command.OptimizationTarget{{Namespace: "prod", Deployment: "api"}}, targets)
+
+	_, err = command.ServoConfigTargets([]byte("prom: {}\n"), "apps")
+	require.EqualError(t, err, "servo config does not declare any k8s application components")
+}
+
+func TestDescribeOptimizationTarget(t *testing.T) {
+	kubectl := cannedKubectl(map[string]string{
+		"-n apps get deployment web -o json": describeDeployment,
+		"-n apps get services -o json":       describeServices,
+	})
+	description, err := command.DescribeOptimizationTarget(context.Background(), kubectl,
+		command.OptimizationTarget{Namespace: "apps", Deployment: "web", Container: "main"})
+	require.NoError(t, err)
+	require.Equal(t, "opsani/co-http:latest", description.Image)
+	require.Equal(t, map[string]string{"cpu": "100m", "memory": "128Mi"}, description.Requests)
+	require.Equal(t, map[string]string{"memory": "256Mi"}, description.Limits)
+	require.Equal(t, []string{"web"}, description.Services)
+	require.EqualValues(t, 3, description.Replicas)
+	require.EqualValues(t, 2, description.ReadyReplicas)
+
+	_, err = command.DescribeOptimizationTarget(context.Background(), kubectl,
+		command.OptimizationTarget{Namespace: "apps", Deployment: "web", Container: "sidecar"})
+	require.EqualError(t, err, `container "sidecar" not found in deployment "web"`)
+}
+
+type OptimizerDescribeTestSuite struct {
+	test.Suite
+}
+
+func TestOptimizerDescribeTestSuite(t *testing.T) {
+	suite.Run(t, new(OptimizerDescribeTestSuite))
+}
+
+func (s *OptimizerDescribeTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+}
+
+func (s *OptimizerDescribeTestSuite) TearDownTest() {
+	command.SetServoDriverFactory(nil)
+	command.SetCommandContextFunc(nil)
+}
+
+func (s *OptimizerDescribeTestSuite) TestDescribe() {
+	driver := test.NewFakeServoDriver()
+	driver.ServoConfig = []byte(describeServoConfig)
+	command.SetServoDriverFactory(driver.Factory())
+	recorder := test.NewExecRecorder()
+	recorder.Respond("kubectl -n opsani get deployment web", test.ExecResponse{Stdout: describeDeployment})
+	recorder.Respond("kubectl -n opsani get services", test.ExecResponse{Stdout: describeServices})
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "app", "describe")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"ConfigData"}, driver.Calls())
+	s.Require().Regexp(`Namespace:\s+opsani`, output)
+	s.Require().Regexp(`Container:\s+main`, output)
+	s.Require().Regexp(`Image:\s+opsani/co-http:latest`, output)
+	s.Require().Regexp(`Requests:\s+cpu=100m, memory=128Mi`, output)
+	s.Require().Regexp(`Services:\s+web`, output)
+	s.Require().Regexp(`Replicas:\s+3 \(2 ready\)`, output)
+}
+
+func (s *OptimizerDescribeTestSuite) TestDescribeWithoutServo() {
+	configFile := test.TempConfigFileWithObj(map[string][]map[string]string{
+		"profiles": {{"name": "default", "optimizer": "example.com/app", "token": "123456"}},
+	})
+	_, err := s.Execute("--config", configFile.Name(), "app", "describe")
+	s.Require().EqualError(err, "no servo attached to the active profile: attach one with `opsani servo attach`")
+}
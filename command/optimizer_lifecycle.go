@@ -14,72 +14,150 @@
 
 package command
 
-import "github.com/spf13/cobra"
+import (
+	"fmt"
+	"time"
+
+	"github.com/opsani/cli/opsani"
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+)
+
+// KeyWaitFor is the flag for blocking until the optimizer reaches a lifecycle state
+const KeyWaitFor = "wait-for"
+
+// Optimizer lifecycle states
+const (
+	OptimizerStateRunning = "running"
+	OptimizerStateStopped = "stopped"
+)
+
+// DefaultWaitForTimeout limits waiting for a state when no timeout is configured
+const DefaultWaitForTimeout = 5 * time.Minute
 
 // NewOptimizerStartCommand returns an Opsani CLI command for starting the app
 func NewOptimizerStartCommand(baseCmd *BaseCommand) *cobra.Command {
-	return &cobra.Command{
+	startCmd := &cobra.Command{
 		Use:   "start",
 		Short: "Start the app",
 		Args:  cobra.NoArgs,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			client := baseCmd.NewAPIClient()
-			if resp, err := client.StartApp(); err == nil {
-				return PrettyPrintJSONResponse(resp)
-			} else {
-				return err
-			}
-		},
+		RunE: baseCmd.LifecycleRunE(LifecycleAction{
+			Verb:    "start",
+			Past:    "started",
+			Perform: (*opsani.Client).StartApp,
+		}),
 	}
+	baseCmd.AddProfileSelectionFlags(startCmd)
+	return RequireScope(startCmd, ScopeWrite)
 }
 
 // NewOptimizerStopCommand returns an Opsani CLI command for stopping the app
 func NewOptimizerStopCommand(baseCmd *BaseCommand) *cobra.Command {
-	return &cobra.Command{
+	stopCmd := &cobra.Command{
 		Use:   "stop",
 		Short: "Stop the app",
 		Args:  cobra.NoArgs,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			client := baseCmd.NewAPIClient()
-			resp, err := client.StopApp()
-			if err != nil {
-				return err
-			}
-			return PrettyPrintJSONResponse(resp)
-		},
+		RunE: baseCmd.LifecycleRunE(LifecycleAction{
+			Verb:    "stop",
+			Past:    "stopped",
+			Perform: (*opsani.Client).StopApp,
+		}),
 	}
+	baseCmd.AddProfileSelectionFlags(stopCmd)
+	return RequireScope(stopCmd, ScopeWrite)
 }
 
 // NewOptimizerRestartCommand returns an Opsani CLI command for restarting the app
 func NewOptimizerRestartCommand(baseCmd *BaseCommand) *cobra.Command {
-	return &cobra.Command{
+	restartCmd := &cobra.Command{
 		Use:   "restart",
 		Short: "Restart the app",
 		Args:  cobra.NoArgs,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			client := baseCmd.NewAPIClient()
-			resp, err := client.RestartApp()
-			if err != nil {
-				return err
-			}
-			return PrettyPrintJSONResponse(resp)
-		},
+		RunE: baseCmd.LifecycleRunE(LifecycleAction{
+			Verb:    "restart",
+			Past:    "restarted",
+			Perform: (*opsani.Client).RestartApp,
+		}),
 	}
+	baseCmd.AddProfileSelectionFlags(restartCmd)
+	return RequireScope(restartCmd, ScopeWrite)
 }
 
 // NewOptimizerStatusCommand returns an Opsani CLI command for retrieving status on the app
 func NewOptimizerStatusCommand(baseCmd *BaseCommand) *cobra.Command {
-	return &cobra.Command{
+	statusCmd := &cobra.Command{
 		Use:   "status",
 		Short: "Check app status",
-		Args:  cobra.NoArgs,
+		Long: `Check app status.
+
+With --wait-for the status is polled at the watch interval until the app reaches the
+given state, making it possible for deploy scripts to block on lifecycle transitions.
+Waiting is limited by --timeout (default 5m).`,
+		Example: `  opsani optimizer start && opsani optimizer status --wait-for running --timeout 5m`,
+		Args:    cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			client := baseCmd.NewAPIClient()
-			resp, err := client.GetAppStatus()
-			if err != nil {
-				return err
+			if state, _ := cmd.Flags().GetString(KeyWaitFor); state != "" {
+				return baseCmd.waitForOptimizerState(cmd, state)
 			}
-			return PrettyPrintJSONResponse(resp)
+			return baseCmd.WatchRunE(func(cmd *cobra.Command, args []string) error {
+				client := baseCmd.NewAPIClient()
+				resp, err := client.GetAppStatus()
+				if err != nil {
+					return err
+				}
+				return PrettyPrintJSONResponse(resp)
+			})(cmd, args)
 		},
 	}
+	AddWatchFlags(statusCmd)
+	statusCmd.Flags().String(KeyWaitFor, "", fmt.Sprintf("Wait for the app to reach a state: {%s|%s}", OptimizerStateRunning, OptimizerStateStopped))
+	return statusCmd
+}
+
+// waitForOptimizerState polls the app status until it reaches the state or the timeout elapses
+func (baseCmd *BaseCommand) waitForOptimizerState(cmd *cobra.Command, state string) error {
+	if state != OptimizerStateRunning && state != OptimizerStateStopped {
+		return fmt.Errorf("invalid state %q: must be %q or %q", state, OptimizerStateRunning, OptimizerStateStopped)
+	}
+	if watch, _ := cmd.Flags().GetBool(KeyWatch); watch {
+		return fmt.Errorf("--%s cannot be combined with --%s", KeyWaitFor, KeyWatch)
+	}
+	interval, _ := cmd.Flags().GetDuration(KeyWatchInterval)
+	if interval <= 0 {
+		return fmt.Errorf("invalid watch interval %q: must be greater than zero", interval)
+	}
+	timeout := baseCmd.Timeout()
+	if timeout <= 0 {
+		timeout = DefaultWaitForTimeout
+	}
+
+	client := baseCmd.NewAPIClient()
+	deadline := time.Now().Add(timeout)
+	lastState := ""
+	for {
+		resp, err := client.GetAppStatus()
+		if err != nil {
+			return err
+		}
+		current := optimizerState(resp.Body())
+		if current == state {
+			return PrettyPrintJSONResponse(resp)
+		}
+		if current != lastState {
+			baseCmd.PrintErrf("Waiting for optimizer %s to be %s (currently %s)...\n", baseCmd.Optimizer(), state, current)
+			lastState = current
+		}
+		if !time.Now().Add(interval).Before(deadline) {
+			return fmt.Errorf("timed out after %s waiting for optimizer to be %s (last state %q)", timeout, state, current)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// optimizerState returns the lifecycle state reported in an app status response
+func optimizerState(body []byte) string {
+	if state := gjson.GetBytes(body, "state"); state.Exists() {
+		return state.String()
+	}
+	return gjson.GetBytes(body, "status").String()
 }
@@ -15,6 +15,10 @@
 package command_test
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/opsani/cli/command"
@@ -62,3 +66,165 @@ func (s *AppLifecycleTestSuite) TestRunningAppRestartNoSuchProfile() {
 	_, err := s.Execute("app", "-p", "invalid", "restart")
 	s.Require().Error(err, `no profile "invalid"`)
 }
+
+func (s *AppLifecycleTestSuite) TestRunningAppStatusWatchHelp() {
+	output, err := s.Execute("app", "status", "--help")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Watch for changes, re-rendering output in place")
+	s.Require().Contains(output, "--interval")
+}
+
+// stateServer returns a test API server reporting each of the states in turn, repeating the last
+func (s *AppLifecycleTestSuite) stateServer(states ...string) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		state := states[len(states)-1]
+		if requests < len(states) {
+			state = states[requests]
+		}
+		requests++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"state": "` + state + `"}`))
+	}))
+	s.T().Cleanup(server.Close)
+	s.SetEnv("OPSANI_BASE_URL", server.URL)
+	s.SetEnv("OPSANI_OPTIMIZER", "example.com/app")
+	s.SetEnv("OPSANI_TOKEN", "123456")
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func (s *AppLifecycleTestSuite) TestRunningAppStatusWaitForRunning() {
+	_, requests := s.stateServer("stopped", "starting", "running")
+	output, err := s.Execute("optimizer", "status", "--wait-for", "running", "--interval", "10ms")
+	s.Require().NoError(err)
+	s.Require().Equal(3, requests())
+	s.Require().Contains(output, "Waiting for optimizer example.com/app to be running (currently stopped)...")
+	s.Require().Contains(output, "Waiting for optimizer example.com/app to be running (currently starting)...")
+}
+
+func (s *AppLifecycleTestSuite) TestRunningAppStatusWaitForAlreadyInState() {
+	_, requests := s.stateServer("stopped")
+	output, err := s.Execute("optimizer", "status", "--wait-for", "stopped")
+	s.Require().NoError(err)
+	s.Require().Equal(1, requests())
+	s.Require().NotContains(output, "Waiting for optimizer")
+}
+
+func (s *AppLifecycleTestSuite) TestRunningAppStatusWaitForTimeout() {
+	s.stateServer("stopped")
+	_, err := s.Execute("optimizer", "status", "--wait-for", "running", "--interval", "10ms", "--timeout", "50ms")
+	s.Require().EqualError(err, `timed out after 50ms waiting for optimizer to be running (last state "stopped")`)
+}
+
+func (s *AppLifecycleTestSuite) TestRunningAppStatusWaitForInvalidState() {
+	s.stateServer("stopped")
+	_, err := s.Execute("optimizer", "status", "--wait-for", "paused")
+	s.Require().EqualError(err, `invalid state "paused": must be "running" or "stopped"`)
+}
+
+func (s *AppLifecycleTestSuite) TestRunningAppStatusWaitForWithWatch() {
+	s.stateServer("stopped")
+	_, err := s.Execute("optimizer", "status", "--wait-for", "running", "--watch")
+	s.Require().EqualError(err, "--wait-for cannot be combined with --watch")
+}
+
+type lifecycleRequest struct {
+	Method string
+	Path   string
+	Token  string
+}
+
+// fleetConfigFile returns a config file with profiles for three optimizers served by a test API server
+// Requests for the optimizer named failing respond with an error
+func (s *AppLifecycleTestSuite) fleetConfigFile(failing string) (string, func() []lifecycleRequest) {
+	var mu sync.Mutex
+	requests := []lifecycleRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, lifecycleRequest{r.Method, r.URL.Path, r.Header.Get("Authorization")})
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if failing != "" && strings.Contains(r.URL.Path, "/applications/"+failing+"/") {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"status": "error", "message": "optimizer unavailable"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	s.T().Cleanup(server.Close)
+
+	profiles := []map[string]string{}
+	for _, name := range []string{"team-a", "team-b", "team-c"} {
+		profiles = append(profiles, map[string]string{
+			"name":      name,
+			"optimizer": "example.com/" + name,
+			"token":     name + "-token",
+			"base_url":  server.URL,
+		})
+	}
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{"profiles": profiles})
+	return configFile.Name(), func() []lifecycleRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func (s *AppLifecycleTestSuite) TestRunningAppStopSelectedProfiles() {
+	configFile, requests := s.fleetConfigFile("")
+	output, err := s.Execute("--config", configFile, "optimizer", "stop", "--profiles", "team-c,team-a", "--force")
+	s.Require().NoError(err)
+	s.Require().Equal([]lifecycleRequest{
+		{"PATCH", "/accounts/example.com/applications/team-a/state", "Bearer team-a-token"},
+		{"PATCH", "/accounts/example.com/applications/team-c/state", "Bearer team-c-token"},
+	}, requests())
+	s.Require().Regexp(`team-a\s+example.com/team-a\s+stopped`, output)
+	s.Require().Regexp(`team-c\s+example.com/team-c\s+stopped`, output)
+	s.Require().NotContains(output, "team-b")
+}
+
+func (s *AppLifecycleTestSuite) TestRunningAppStartAllProfilesConfirmed() {
+	configFile, requests := s.fleetConfigFile("")
+	context, err := s.ExecuteTestInteractively(test.Args("--config", configFile, "optimizer", "start", "--all"), func(t *test.InteractiveTestContext) error {
+		t.RequireString(`Start optimizer example.com/team-a (profile "team-a")?`)
+		t.SendLine("Y")
+		t.RequireString(`Start optimizer example.com/team-b (profile "team-b")?`)
+		t.SendLine("N")
+		t.RequireString(`Start optimizer example.com/team-c (profile "team-c")?`)
+		t.SendLine("Y")
+		t.ExpectEOF()
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Require().Len(requests(), 2)
+	s.Require().Regexp(`team-b\s+example.com/team-b\s+skipped`, context.OutputBuffer().String())
+}
+
+func (s *AppLifecycleTestSuite) TestRunningAppStopProfilesWithFailure() {
+	configFile, requests := s.fleetConfigFile("team-b")
+	output, err := s.Execute("--config", configFile, "optimizer", "stop", "--all", "--force")
+	s.Require().EqualError(err, "failed to stop 1 of 3 optimizers")
+	s.Require().Len(requests(), 3)
+	s.Require().Regexp(`team-b\s+example.com/team-b\s+failed: request failed: optimizer unavailable`, output)
+	s.Require().Regexp(`team-c\s+example.com/team-c\s+stopped`, output)
+}
+
+func (s *AppLifecycleTestSuite) TestRunningAppStopUnknownProfile() {
+	configFile, requests := s.fleetConfigFile("")
+	_, err := s.Execute("--config", configFile, "optimizer", "stop", "--profiles", "team-a,team-z", "--force")
+	s.Require().EqualError(err, `no profile "team-z"`)
+	s.Require().Empty(requests())
+}
+
+func (s *AppLifecycleTestSuite) TestRunningAppStopProfilesAndAll() {
+	configFile, _ := s.fleetConfigFile("")
+	_, err := s.Execute("--config", configFile, "optimizer", "stop", "--profiles", "team-a", "--all")
+	s.Require().EqualError(err, "--profiles cannot be combined with --all")
+}
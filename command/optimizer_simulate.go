@@ -0,0 +1,328 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/opsani/cli/opsani"
+	"github.com/spf13/cobra"
+)
+
+// ResourceSettings are the per replica CPU cores and GiB of memory and the replica count of a component
+type ResourceSettings struct {
+	CPU       float64 `json:"cpu"`
+	MemoryGiB float64 `json:"memory_gib"`
+	Replicas  int     `json:"replicas"`
+}
+
+// capacity returns the total CPU cores across all replicas
+func (r ResourceSettings) capacity() float64 {
+	return r.CPU * float64(r.Replicas)
+}
+
+// LatencyEnvelope is the expected range of a latency metric under simulated settings
+type LatencyEnvelope struct {
+	Metric   string  `json:"metric"`
+	Unit     string  `json:"unit,omitempty"`
+	Baseline float64 `json:"baseline"`
+	Best     float64 `json:"best"`
+	Worst    float64 `json:"worst"`
+}
+
+// Simulation is the estimated outcome of adjusting a component to simulated settings
+type Simulation struct {
+	Component     string            `json:"component"`
+	PriceBook     PriceBook         `json:"price_book"`
+	Baseline      ResourceSettings  `json:"baseline"`
+	Simulated     ResourceSettings  `json:"simulated"`
+	BaselineCost  float64           `json:"baseline_monthly_cost"`
+	SimulatedCost float64           `json:"simulated_monthly_cost"`
+	Latency       []LatencyEnvelope `json:"latency"`
+}
+
+// CostDelta returns the change in monthly cost
+func (s Simulation) CostDelta() float64 {
+	return s.SimulatedCost - s.BaselineCost
+}
+
+var latencyMetricPattern = regexp.MustCompile(`(?i)latency|response_time|duration`)
+
+// SimulateAdjustment estimates the cost and latency of moving a component from the baseline to the simulated settings
+// Latency is assumed to scale inversely with total CPU capacity at worst and to be unaffected at best
+func SimulateAdjustment(component string, baseline, simulated ResourceSettings, metrics map[string]opsani.MeasurementMetric, priceBook PriceBook) Simulation {
+	simulation := Simulation{
+		Component:     component,
+		PriceBook:     priceBook,
+		Baseline:      baseline,
+		Simulated:     simulated,
+		BaselineCost:  priceBook.MonthlyCost(baseline.CPU, baseline.MemoryGiB, baseline.Replicas),
+		SimulatedCost: priceBook.MonthlyCost(simulated.CPU, simulated.MemoryGiB, simulated.Replicas),
+		Latency:       []LatencyEnvelope{},
+	}
+	if baseline.capacity() <= 0 || simulated.capacity() <= 0 {
+		return simulation
+	}
+	ratio := simulated.capacity() / baseline.capacity()
+	for name, metric := range metrics {
+		if !latencyMetricPattern.MatchString(name) {
+			continue
+		}
+		scaled := metric.Value / ratio
+		simulation.Latency = append(simulation.Latency, LatencyEnvelope{
+			Metric:   name,
+			Unit:     metric.Unit,
+			Baseline: metric.Value,
+			Best:     math.Min(metric.Value, scaled),
+			Worst:    math.Max(metric.Value, scaled),
+		})
+	}
+	sort.Slice(simulation.Latency, func(i, j int) bool {
+		return simulation.Latency[i].Metric < simulation.Latency[j].Metric
+	})
+	return simulation
+}
+
+// ParseCPUQuantity parses a number of cores (e.g. 1.5) or millicores (e.g. 1500m)
+func ParseCPUQuantity(quantity string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSuffix(quantity, "m"), 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid CPU quantity %q: must be a positive number of cores or millicores", quantity)
+	}
+	if strings.HasSuffix(quantity, "m") {
+		value /= 1000
+	}
+	return value, nil
+}
+
+var memoryUnits = map[string]float64{
+	"":   1,
+	"Ki": 1.0 / (1 << 20),
+	"Mi": 1.0 / (1 << 10),
+	"Gi": 1,
+	"Ti": 1 << 10,
+	"K":  1e3 / (1 << 30),
+	"M":  1e6 / (1 << 30),
+	"G":  1e9 / (1 << 30),
+	"T":  1e12 / (1 << 30),
+}
+
+var memoryQuantityPattern = regexp.MustCompile(`^([0-9]*\.?[0-9]+)([KMGT]i?)?$`)
+
+// ParseMemoryQuantity parses a Kubernetes memory quantity (e.g. 2Gi, 512Mi) into GiB
+// Bare numbers are interpreted as GiB
+func ParseMemoryQuantity(quantity string) (float64, error) {
+	matches := memoryQuantityPattern.FindStringSubmatch(quantity)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid memory quantity %q: must be a number of GiB or a quantity such as 512Mi or 2Gi", quantity)
+	}
+	value, _ := strconv.ParseFloat(matches[1], 64)
+	if value <= 0 {
+		return 0, fmt.Errorf("invalid memory quantity %q: must be positive", quantity)
+	}
+	return value * memoryUnits[matches[2]], nil
+}
+
+// adjustmentSettings returns the resource settings of a component from the settings applied by an adjustment
+func adjustmentSettings(components map[string]map[string]interface{}, component string) (string, ResourceSettings, error) {
+	if component == "" {
+		if len(components) != 1 {
+			names := []string{}
+			for name := range components {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return "", ResourceSettings{}, fmt.Errorf("the optimizer adjusts %d components (%s): select one with --component", len(names), strings.Join(names, ", "))
+		}
+		for name := range components {
+			component = name
+		}
+	}
+	values, ok := components[component]
+	if !ok {
+		return "", ResourceSettings{}, fmt.Errorf("component %q has not been adjusted by the optimizer", component)
+	}
+	number := func(keys ...string) float64 {
+		for _, key := range keys {
+			switch v := values[key].(type) {
+			case float64:
+				return v
+			case int:
+				return float64(v)
+			case string:
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					return f
+				}
+			}
+		}
+		return 0
+	}
+	settings := ResourceSettings{
+		CPU:       number("cpu"),
+		MemoryGiB: number("mem", "memory"),
+		Replicas:  int(number("replicas")),
+	}
+	if settings.Replicas == 0 {
+		settings.Replicas = 1
+	}
+	return component, settings, nil
+}
+
+// latestCompletedAdjustment returns the most recently started adjustment that has completed
+func latestCompletedAdjustment(adjustments []opsani.Adjustment) *opsani.Adjustment {
+	var latest *opsani.Adjustment
+	for i, adjustment := range adjustments {
+		if adjustment.CompletedAt == nil {
+			continue
+		}
+		if latest == nil || adjustment.StartedAt.After(latest.StartedAt) {
+			latest = &adjustments[i]
+		}
+	}
+	return latest
+}
+
+// latestCompletedMeasurement returns the most recently started measurement that has completed
+func latestCompletedMeasurement(measurements []opsani.Measurement) *opsani.Measurement {
+	var latest *opsani.Measurement
+	for i, measurement := range measurements {
+		if measurement.CompletedAt == nil {
+			continue
+		}
+		if latest == nil || measurement.StartedAt.After(latest.StartedAt) {
+			latest = &measurements[i]
+		}
+	}
+	return latest
+}
+
+func formatSignedFloat(value float64) string {
+	return fmt.Sprintf("%+.2f", value)
+}
+
+// formatLatency formats a latency rounded to two decimal places
+func formatLatency(value float64, unit string) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64) + unit
+}
+
+// NewOptimizerSimulateCommand returns a new `opsani optimizer simulate` command instance
+func NewOptimizerSimulateCommand(baseCmd *BaseCommand) *cobra.Command {
+	simulateCmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Estimate the impact of adjusting application settings",
+		Long: `Estimates the monthly cost delta and expected latency envelope of running a component
+with the given settings before committing an override.
+
+The baseline is taken from the settings of the most recent completed adjustment and
+the metrics of the most recent completed measurement. Costs are priced from a cloud
+price book: one of the built-in on-demand books or a YAML file defining name, currency,
+cpu_hour, and memory_gib_hour. Latency metrics are assumed to scale inversely with the
+total CPU capacity at worst and to be unaffected at best.`,
+		Example: `  opsani optimizer simulate --cpu 1.5 --memory 2Gi --replicas 3
+  opsani optimizer simulate --cpu 500m --price-book gcp
+  opsani optimizer simulate --replicas 4 --price-book ./prices.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			priceBookName, _ := cmd.Flags().GetString("price-book")
+			priceBook, err := LoadPriceBook(priceBookName)
+			if err != nil {
+				return err
+			}
+
+			client := baseCmd.NewAPIClient()
+			adjustments, err := client.GetAdjustments(opsani.TimeRange{})
+			if err != nil {
+				return err
+			}
+			adjustment := latestCompletedAdjustment(adjustments)
+			if adjustment == nil {
+				return fmt.Errorf("no completed adjustments found to establish a baseline")
+			}
+			component, _ := cmd.Flags().GetString("component")
+			component, baseline, err := adjustmentSettings(adjustment.Components, component)
+			if err != nil {
+				return err
+			}
+
+			simulated := baseline
+			if cmd.Flags().Changed("cpu") {
+				cpu, _ := cmd.Flags().GetString("cpu")
+				if simulated.CPU, err = ParseCPUQuantity(cpu); err != nil {
+					return err
+				}
+			}
+			if cmd.Flags().Changed("memory") {
+				memory, _ := cmd.Flags().GetString("memory")
+				if simulated.MemoryGiB, err = ParseMemoryQuantity(memory); err != nil {
+					return err
+				}
+			}
+			if cmd.Flags().Changed("replicas") {
+				if simulated.Replicas, _ = cmd.Flags().GetInt("replicas"); simulated.Replicas < 1 {
+					return fmt.Errorf("invalid replicas %d: must be at least 1", simulated.Replicas)
+				}
+			}
+
+			metrics := map[string]opsani.MeasurementMetric{}
+			measurements, err := client.GetMeasurements(opsani.TimeRange{})
+			if err != nil {
+				return err
+			}
+			if measurement := latestCompletedMeasurement(measurements); measurement != nil {
+				metrics = measurement.Metrics
+			}
+
+			simulation := SimulateAdjustment(component, baseline, simulated, metrics, priceBook)
+			if output, _ := cmd.Flags().GetString("output"); output == "json" {
+				return baseCmd.PrettyPrintJSONObject(simulation)
+			}
+
+			baseCmd.Printf("Simulating %s priced by %s\n\n", component, priceBook.Name)
+			table := baseCmd.newPlainTable()
+			table.SetHeader([]string{"", "BASELINE", "SIMULATED", "DELTA"})
+			table.AppendBulk([][]string{
+				{"CPU", fmt.Sprintf("%g", baseline.CPU), fmt.Sprintf("%g", simulated.CPU), formatSignedFloat(simulated.CPU - baseline.CPU)},
+				{"Memory (GiB)", fmt.Sprintf("%g", baseline.MemoryGiB), fmt.Sprintf("%g", simulated.MemoryGiB), formatSignedFloat(simulated.MemoryGiB - baseline.MemoryGiB)},
+				{"Replicas", strconv.Itoa(baseline.Replicas), strconv.Itoa(simulated.Replicas), fmt.Sprintf("%+d", simulated.Replicas-baseline.Replicas)},
+				{"Monthly cost (" + priceBook.Currency + ")", fmt.Sprintf("%.2f", simulation.BaselineCost), fmt.Sprintf("%.2f", simulation.SimulatedCost), formatSignedFloat(simulation.CostDelta())},
+			})
+			for _, envelope := range simulation.Latency {
+				expected := formatLatency(envelope.Best, envelope.Unit)
+				if envelope.Worst != envelope.Best {
+					expected = fmt.Sprintf("%s - %s", formatLatency(envelope.Best, envelope.Unit), formatLatency(envelope.Worst, envelope.Unit))
+				}
+				table.Append([]string{envelope.Metric, formatLatency(envelope.Baseline, envelope.Unit), expected, ""})
+			}
+			table.Render()
+			if simulated.MemoryGiB < baseline.MemoryGiB {
+				baseCmd.Println()
+				baseCmd.Printf("%s  Reducing memory below the baseline may cause out of memory restarts\n", baseCmd.Glyph(glyphWarning))
+			}
+			return nil
+		},
+	}
+	simulateCmd.Flags().String("component", "", "Component to simulate (required when the optimizer adjusts several)")
+	simulateCmd.Flags().String("cpu", "", "CPU per replica in cores or millicores (e.g. 1.5, 500m)")
+	simulateCmd.Flags().String("memory", "", "Memory per replica (e.g. 2Gi, 512Mi)")
+	simulateCmd.Flags().Int("replicas", 0, "Number of replicas")
+	simulateCmd.Flags().String("price-book", "aws", fmt.Sprintf("Price book: {%s} or a path to a price book file", strings.Join(PriceBookNames(), "|")))
+	simulateCmd.Flags().StringP("output", "o", "table", "Output format: {table|json}")
+	return simulateCmd
+}
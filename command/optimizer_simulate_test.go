@@ -0,0 +1,155 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/opsani"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestParseMemoryQuantity(t *testing.T) {
+	for quantity, expected := range map[string]float64{"2Gi": 2, "512Mi": 0.5, "1.5": 1.5, "1Ti": 1024} {
+		value, err := command.ParseMemoryQuantity(quantity)
+		require.NoError(t, err, quantity)
+		require.Equal(t, expected, value, quantity)
+	}
+	_, err := command.ParseMemoryQuantity("lots")
+	require.EqualError(t, err, `invalid memory quantity "lots": must be a number of GiB or a quantity such as 512Mi or 2Gi`)
+}
+
+func TestParseCPUQuantity(t *testing.T) {
+	value, err := command.ParseCPUQuantity("1500m")
+	require.NoError(t, err)
+	require.Equal(t, 1.5, value)
+	value, err = command.ParseCPUQuantity("2")
+	require.NoError(t, err)
+	require.Equal(t, 2.0, value)
+	_, err = command.ParseCPUQuantity("-1")
+	require.Error(t, err)
+}
+
+func TestLoadPriceBook(t *testing.T) {
+	priceBook, err := command.LoadPriceBook("GCP")
+	require.NoError(t, err)
+	require.Equal(t, command.PriceBooks["gcp"], priceBook)
+
+	dir, err := ioutil.TempDir("", "opsani-price-book")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "prices.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("cpu_hour: 0.05\nmemory_gib_hour: 0.01\n"), 0644))
+	priceBook, err = command.LoadPriceBook(path)
+	require.NoError(t, err)
+	require.Equal(t, command.PriceBook{Name: path, Currency: "USD", CPUHour: 0.05, MemoryGiBHour: 0.01}, priceBook)
+
+	_, err = command.LoadPriceBook("oracle")
+	require.EqualError(t, err, `unknown price book "oracle": must be one of aws, azure, gcp or a path to a price book file`)
+}
+
+func TestSimulateAdjustment(t *testing.T) {
+	priceBook := command.PriceBook{CPUHour: 0.1, MemoryGiBHour: 0.01}
+	metrics := map[string]opsani.MeasurementMetric{
+		"latency_p99": {Value: 200, Unit: "ms"},
+		"throughput":  {Value: 100},
+	}
+	baseline := command.ResourceSettings{CPU: 1, MemoryGiB: 2, Replicas: 2}
+
+	simulation := command.SimulateAdjustment("web", baseline, command.ResourceSettings{CPU: 2, MemoryGiB: 2, Replicas: 2}, metrics, priceBook)
+	require.InDelta(t, 175.2, simulation.BaselineCost, 0.001)
+	require.InDelta(t, 321.2, simulation.SimulatedCost, 0.001)
+	require.InDelta(t, 146, simulation.CostDelta(), 0.001)
+	require.Equal(t, []command.LatencyEnvelope{{Metric: "latency_p99", Unit: "ms", Baseline: 200, Best: 100, Worst: 200}}, simulation.Latency)
+
+	simulation = command.SimulateAdjustment("web", baseline, command.ResourceSettings{CPU: 1, MemoryGiB: 2, Replicas: 1}, metrics, priceBook)
+	require.Equal(t, []command.LatencyEnvelope{{Metric: "latency_p99", Unit: "ms", Baseline: 200, Best: 200, Worst: 400}}, simulation.Latency)
+}
+
+type OptimizerSimulateTestSuite struct {
+	test.Suite
+}
+
+func TestOptimizerSimulateTestSuite(t *testing.T) {
+	suite.Run(t, new(OptimizerSimulateTestSuite))
+}
+
+func (s *OptimizerSimulateTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+}
+
+// activityServer returns a test server responding to the adjustments and measurements endpoints
+func (s *OptimizerSimulateTestSuite) activityServer(adjustments, measurements string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/adjustments") {
+			w.Write([]byte(adjustments))
+		} else {
+			w.Write([]byte(measurements))
+		}
+	}))
+	s.SetEnv("OPSANI_BASE_URL", server.URL)
+	s.SetEnv("OPSANI_OPTIMIZER", "example.com/app")
+	s.SetEnv("OPSANI_TOKEN", "123456")
+	return server
+}
+
+func (s *OptimizerSimulateTestSuite) TestSimulate() {
+	server := s.activityServer(`{"adjustments": [
+		{"id": "adj-1", "status": "completed", "started_at": "2020-06-01T10:00:00Z", "completed_at": "2020-06-01T10:02:00Z",
+			"components": {"web": {"cpu": 0.5, "mem": 1, "replicas": 2}}},
+		{"id": "adj-2", "status": "running", "started_at": "2020-06-01T11:00:00Z",
+			"components": {"web": {"cpu": 4, "mem": 8, "replicas": 8}}}
+	]}`, `{"measurements": [
+		{"id": "msr-1", "status": "completed", "started_at": "2020-06-01T10:05:00Z", "completed_at": "2020-06-01T10:10:00Z",
+			"metrics": {"latency_p50": {"value": 120, "unit": "ms"}}}
+	]}`)
+	defer server.Close()
+
+	output, err := s.Execute("optimizer", "simulate", "--cpu", "1500m", "--memory", "512Mi", "--replicas", "3", "--price-book", "azure")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Simulating web priced by Azure Container Instances")
+	s.Require().Regexp(`CPU\s+0.5\s+1.5\s+\+1.00`, output)
+	s.Require().Regexp(`Replicas\s+2\s+3\s+\+1`, output)
+	s.Require().Regexp(`latency_p50\s+120ms\s+26.67ms - 120ms`, output)
+	s.Require().Contains(output, "Reducing memory below the baseline")
+}
+
+func (s *OptimizerSimulateTestSuite) TestSimulateWithoutBaseline() {
+	server := s.activityServer(`{"adjustments": []}`, `{"measurements": []}`)
+	defer server.Close()
+
+	_, err := s.Execute("optimizer", "simulate", "--cpu", "1")
+	s.Require().EqualError(err, "no completed adjustments found to establish a baseline")
+}
+
+func (s *OptimizerSimulateTestSuite) TestSimulateAmbiguousComponent() {
+	server := s.activityServer(`{"adjustments": [{"id": "adj-1", "status": "completed",
+		"started_at": "2020-06-01T10:00:00Z", "completed_at": "2020-06-01T10:02:00Z",
+		"components": {"web": {"cpu": 1}, "api": {"cpu": 1}}}]}`, `{"measurements": []}`)
+	defer server.Close()
+
+	_, err := s.Execute("optimizer", "simulate", "--cpu", "1")
+	s.Require().EqualError(err, "the optimizer adjusts 2 components (api, web): select one with --component")
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/opsani/cli/opsani"
+	"github.com/spf13/cobra"
+)
+
+// KeySkipVerify is the flag for skipping verification of the optimizer with the API
+const KeySkipVerify = "skip-verify"
+
+// AddSkipVerifyFlag registers the --skip-verify flag on the given command
+func AddSkipVerifyFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool(KeySkipVerify, false, "Skip confirming that the optimizer exists with the Opsani API")
+}
+
+// NormalizeOptimizer returns the optimizer in canonical domain/app form
+func NormalizeOptimizer(optimizer string) (string, error) {
+	id, err := opsani.ParseOptimizerID(optimizer)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// optimizerValidator is a survey validator for optimizer input
+func optimizerValidator(val interface{}) error {
+	if str, ok := val.(string); ok {
+		_, err := opsani.ParseOptimizerID(str)
+		return err
+	}
+	return nil
+}
+
+// verifyOptimizer confirms that the API is reachable and the optimizer of the profile exists and is accessible with its token
+func (baseCmd *BaseCommand) verifyOptimizer(profile Profile) error {
+	baseURL := profile.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	client := opsani.NewClient().
+		SetBaseURL(baseURL).
+		SetApp(profile.Optimizer).
+		SetAuthToken(profile.Token).
+		SetDebug(baseCmd.DebugModeEnabled()).
+		SetRedactSecrets(!baseCmd.ShowSecrets()).
+		SetTimeout(baseCmd.Timeout())
+	_, err := client.GetAppStatus()
+	var apiErr *opsani.APIError
+	var notFound *opsani.NotFoundError
+	var unauthorized *opsani.UnauthorizedError
+	if err != nil && !errors.As(err, &apiErr) {
+		// The request never reached the API, typically due to a mistyped base URL
+		return fmt.Errorf("unable to reach the Opsani API at %s: %w", baseURL, err)
+	} else if errors.As(err, &notFound) {
+		return fmt.Errorf("optimizer %q was not found", profile.Optimizer)
+	} else if errors.As(err, &unauthorized) {
+		return fmt.Errorf("the API token is not authorized to access optimizer %q", profile.Optimizer)
+	} else if err != nil {
+		return fmt.Errorf("unable to verify optimizer %q: %w", profile.Optimizer, err)
+	}
+	return nil
+}
+
+// confirmOptimizer verifies the optimizer of the profile unless --skip-verify is given
+// When verification fails the user is asked whether to continue anyway
+func (baseCmd *BaseCommand) confirmOptimizer(cmd *cobra.Command, profile Profile) error {
+	if skip, _ := cmd.Flags().GetBool(KeySkipVerify); skip {
+		return nil
+	}
+	err := baseCmd.verifyOptimizer(profile)
+	if err == nil {
+		baseCmd.Printf("%s  Verified optimizer %s\n", baseCmd.ColorGlyph(glyphSuccess), profile.Optimizer)
+		return nil
+	}
+
+	baseCmd.Printf("%s  %s\n", baseCmd.ColorGlyph(glyphFailure), err)
+	proceed := false
+	if askErr := baseCmd.AskOne(&survey.Confirm{
+		Message: "Save the profile anyway?",
+	}, &proceed); askErr != nil {
+		return askErr
+	}
+	if !proceed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opsani/cli/opsani"
+	"github.com/spf13/cobra"
+)
+
+// NewOrgCommand returns a new `opsani org` command instance
+func NewOrgCommand(baseCmd *BaseCommand) *cobra.Command {
+	orgCmd := &cobra.Command{
+		Use:   "org",
+		Short: "Manage organizations",
+		Long: `Manage the organizations of accounts with access to more than one.
+
+The organization selected with ` + "`opsani org switch`" + ` is stored in the active profile and
+optimizers of other organizations are rejected while it is selected.`,
+		Args: cobra.NoArgs,
+		PersistentPreRunE: ReduceRunEFuncs(
+			baseCmd.InitConfigRunE,
+			baseCmd.RequireConfigFileFlagToExistRunE,
+			baseCmd.RequireInitRunE,
+		),
+	}
+
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List organizations and their teams",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := OutputFormat(cmd, TabularOutputFormats...)
+			if err != nil {
+				return err
+			}
+			organizations, err := baseCmd.NewAPIClient().GetOrganizations()
+			if err != nil {
+				return err
+			}
+			current := baseCmd.currentOrg()
+			table := Table{Headers: []string{"CURRENT", "DOMAIN", "NAME", "TEAMS"}}
+			for _, org := range organizations {
+				marker := ""
+				if org.Domain == current {
+					marker = "*"
+				}
+				teams := []string{}
+				for _, team := range org.Teams {
+					teams = append(teams, fmt.Sprintf("%s (%s)", team.Name, team.Role))
+				}
+				table.Rows = append(table.Rows, []string{marker, org.Domain, org.Name, strings.Join(teams, ", ")})
+			}
+			return baseCmd.RenderTable(output, table)
+		},
+	}
+	AddOutputFlag(listCmd, TabularOutputFormats...)
+	orgCmd.AddCommand(listCmd)
+
+	switchCmd := &cobra.Command{
+		Use:   "switch DOMAIN",
+		Short: "Select the organization of the active profile",
+		Long: `Select the organization of the active profile.
+
+The organization must be accessible with the API token of the profile. When the
+optimizer of the profile belongs to another organization, name the app to optimize
+in the new organization with --app.`,
+		Example: `  opsani org switch example.com
+  opsani org switch other.com --app web`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app, _ := cmd.Flags().GetString("app")
+			return baseCmd.switchOrg(strings.ToLower(args[0]), app)
+		},
+	}
+	switchCmd.Flags().String("app", "", "App to optimize in the organization (required if the optimizer belongs to another organization)")
+	orgCmd.AddCommand(switchCmd)
+	return orgCmd
+}
+
+// currentOrg returns the organization selected for the active profile, falling back to that of the optimizer
+func (baseCmd *BaseCommand) currentOrg() string {
+	if baseCmd.profile == nil {
+		return ""
+	}
+	if baseCmd.profile.Org != "" {
+		return baseCmd.profile.Org
+	}
+	return baseCmd.profile.Organization()
+}
+
+// switchOrg stores the organization in the active profile, pointing the optimizer at the app within it
+func (baseCmd *BaseCommand) switchOrg(domain string, app string) error {
+	organizations, err := baseCmd.NewAPIClient().GetOrganizations()
+	if err != nil {
+		return err
+	}
+	var org *opsani.Organization
+	domains := []string{}
+	for i := range organizations {
+		domains = append(domains, organizations[i].Domain)
+		if organizations[i].Domain == domain {
+			org = &organizations[i]
+		}
+	}
+	if org == nil {
+		return fmt.Errorf("organization %q is not accessible with the API token: available organizations are %s", domain, strings.Join(domains, ", "))
+	}
+
+	registry, err := NewProfileRegistry(baseCmd.viperCfg)
+	if err != nil {
+		return err
+	}
+	profile := registry.ProfileNamed(baseCmd.profile.Name)
+	if profile == nil {
+		return fmt.Errorf("no profile %q", baseCmd.profile.Name)
+	}
+	updated := *profile
+	updated.Org = domain
+	if app != "" {
+		if updated.Optimizer, err = NormalizeOptimizer(domain + "/" + app); err != nil {
+			return err
+		}
+	} else if updated.Organization() != domain {
+		return fmt.Errorf("optimizer %q belongs to organization %q: specify the app to optimize in %q with --app", updated.Optimizer, updated.Organization(), domain)
+	}
+
+	if err := registry.UpdateProfile(updated.Name, updated); err != nil {
+		return err
+	}
+	if err := baseCmd.BackupConfig(fmt.Sprintf("org switch %s", domain)); err != nil {
+		return err
+	}
+	if err := registry.Save(); err != nil {
+		return err
+	}
+	baseCmd.Printf("Switched profile %q to organization %s (optimizer %s)\n", updated.Name, domain, updated.Optimizer)
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v2"
+)
+
+type OrgTestSuite struct {
+	test.Suite
+	server *httptest.Server
+}
+
+func TestOrgTestSuite(t *testing.T) {
+	suite.Run(t, new(OrgTestSuite))
+}
+
+func (s *OrgTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Require().Equal("/accounts", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"organizations": [
+			{"domain": "example.com", "name": "Example", "teams": [{"name": "platform", "role": "admin"}]},
+			{"domain": "other.com", "name": "Other", "teams": [{"name": "web", "role": "member"}, {"name": "api", "role": "member"}]}
+		]}`))
+	}))
+}
+
+func (s *OrgTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *OrgTestSuite) configFile(org string) string {
+	return test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{"name": "default", "optimizer": "example.com/app", "token": "123456", "base_url": s.server.URL, "org": org},
+		},
+	}).Name()
+}
+
+// profiles returns the profiles saved in the config file
+func (s *OrgTestSuite) profiles(configFile string) []command.Profile {
+	data, err := ioutil.ReadFile(configFile)
+	s.Require().NoError(err)
+	config := struct {
+		Profiles []command.Profile `yaml:"profiles"`
+	}{}
+	s.Require().NoError(yaml.Unmarshal(data, &config))
+	return config.Profiles
+}
+
+func (s *OrgTestSuite) TestList() {
+	output, err := s.Execute("--config", s.configFile(""), "org", "list")
+	s.Require().NoError(err)
+	s.Require().Regexp(`\*\s+example.com\s+Example\s+platform \(admin\)`, output)
+	s.Require().Regexp(`\n\s+other.com\s+Other\s+web \(member\), api \(member\)`, output)
+}
+
+func (s *OrgTestSuite) TestSwitch() {
+	configFile := s.configFile("")
+	output, err := s.Execute("--config", configFile, "org", "switch", "other.com", "--app", "web")
+	s.Require().NoError(err)
+	s.Require().Equal("Switched profile \"default\" to organization other.com (optimizer other.com/web)\n", output)
+	profiles := s.profiles(configFile)
+	s.Require().Equal("other.com", profiles[0].Org)
+	s.Require().Equal("other.com/web", profiles[0].Optimizer)
+}
+
+func (s *OrgTestSuite) TestSwitchRequiresApp() {
+	_, err := s.Execute("--config", s.configFile(""), "org", "switch", "other.com")
+	s.Require().EqualError(err, `optimizer "example.com/app" belongs to organization "example.com": specify the app to optimize in "other.com" with --app`)
+}
+
+func (s *OrgTestSuite) TestSwitchInaccessibleOrg() {
+	_, err := s.Execute("--config", s.configFile(""), "org", "switch", "unknown.com")
+	s.Require().EqualError(err, `organization "unknown.com" is not accessible with the API token: available organizations are example.com, other.com`)
+}
+
+func (s *OrgTestSuite) TestOptimizerValidatedAgainstOrg() {
+	_, err := s.Execute("--config", s.configFile("example.com"), "--optimizer", "other.com/web", "org", "list")
+	s.Require().EqualError(err, `optimizer "other.com/web" does not belong to organization "example.com" selected for profile "default"`)
+}
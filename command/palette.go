@@ -0,0 +1,190 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/hokaccha/go-prettyjson"
+)
+
+// KeyPalette is the configuration key for selecting the color palette of terminal output
+const KeyPalette = "ui.palette"
+
+// Color palettes selectable with ui.palette
+const (
+	PaletteDefault    = "default"
+	PaletteColorBlind = "colorblind"
+)
+
+// PaletteColor is a combination of fatih/color attributes such as a foreground color and bold
+type PaletteColor []color.Attribute
+
+// Sprint returns the arguments formatted in the color
+func (c PaletteColor) Sprint(a ...interface{}) string {
+	return color.New(c...).Sprint(a...)
+}
+
+// New returns a fatih/color color for libraries that accept one
+func (c PaletteColor) New() *color.Color {
+	return color.New(c...)
+}
+
+// Foreground returns the color without text attributes such as bold
+func (c PaletteColor) Foreground() PaletteColor {
+	foreground := PaletteColor{}
+	for _, attr := range c {
+		if colorNames[attr] != "" || (attr >= color.FgHiBlack && attr <= color.FgHiWhite) {
+			foreground = append(foreground, attr)
+		}
+	}
+	return foreground
+}
+
+var colorNames = map[color.Attribute]string{
+	color.FgBlack: "black", color.FgRed: "red", color.FgGreen: "green", color.FgYellow: "yellow",
+	color.FgBlue: "blue", color.FgMagenta: "magenta", color.FgCyan: "cyan", color.FgWhite: "white",
+}
+
+// surveyStyle returns the color in the style syntax of survey icons (e.g. green+hb)
+func (c PaletteColor) surveyStyle() string {
+	name, attrs := "default", ""
+	for _, attr := range c {
+		if attr >= color.FgHiBlack && attr <= color.FgHiWhite {
+			name, attrs = colorNames[attr-color.FgHiBlack+color.FgBlack], "h"+attrs
+		} else if colorNames[attr] != "" {
+			name = colorNames[attr]
+		} else if attr == color.Bold {
+			attrs += "b"
+		}
+	}
+	if attrs == "" {
+		return name
+	}
+	return name + "+" + attrs
+}
+
+// spinnerColors returns the color as arguments of spinner.Color (e.g. bold, fgHiBlue)
+func (c PaletteColor) spinnerColors() []string {
+	colors := []string{}
+	for _, attr := range c {
+		if attr >= color.FgHiBlack && attr <= color.FgHiWhite {
+			name := colorNames[attr-color.FgHiBlack+color.FgBlack]
+			colors = append(colors, "fgHi"+strings.ToUpper(name[:1])+name[1:])
+		} else if colorNames[attr] != "" {
+			colors = append(colors, colorNames[attr])
+		} else if attr == color.Bold {
+			colors = append(colors, "bold")
+		}
+	}
+	return colors
+}
+
+// Palette assigns colors to the roles of terminal output
+// Status colors decorate glyphs, task messages, table cells, and prompt icons; data colors highlight YAML and JSON
+type Palette struct {
+	Success PaletteColor
+	Failure PaletteColor
+	Info    PaletteColor
+	Warning PaletteColor
+
+	Key     PaletteColor
+	String  PaletteColor
+	Literal PaletteColor
+	Alias   PaletteColor
+}
+
+// Palettes are the color palettes selectable with ui.palette
+// The colorblind palette avoids distinguishing status by red and green, using the blue and orange pairing of Okabe-Ito
+var Palettes = map[string]Palette{
+	PaletteDefault: {
+		Success: PaletteColor{color.FgHiGreen, color.Bold},
+		Failure: PaletteColor{color.FgHiRed, color.Bold},
+		Info:    PaletteColor{color.FgHiBlue, color.Bold},
+		Warning: PaletteColor{color.FgYellow, color.Bold},
+		Key:     PaletteColor{color.FgHiCyan},
+		String:  PaletteColor{color.FgHiGreen},
+		Literal: PaletteColor{color.FgHiMagenta},
+		Alias:   PaletteColor{color.FgHiYellow},
+	},
+	PaletteColorBlind: {
+		Success: PaletteColor{color.FgHiBlue, color.Bold},
+		Failure: PaletteColor{color.FgHiYellow, color.Bold},
+		Info:    PaletteColor{color.FgHiCyan, color.Bold},
+		Warning: PaletteColor{color.FgHiMagenta, color.Bold},
+		Key:     PaletteColor{color.FgHiBlue},
+		String:  PaletteColor{color.FgHiCyan},
+		Literal: PaletteColor{color.FgHiYellow},
+		Alias:   PaletteColor{color.FgHiMagenta},
+	},
+}
+
+// PaletteNames returns the names of the palettes in alphabetical order
+func PaletteNames() []string {
+	names := []string{}
+	for name := range Palettes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// activePalette is the palette of the running command, configured alongside the color settings of output libraries
+var activePalette = Palettes[PaletteDefault]
+
+// Palette returns the color palette selected in the config
+func (baseCmd *BaseCommand) Palette() (Palette, error) {
+	name := PaletteDefault
+	if baseCmd.viperCfg != nil && baseCmd.viperCfg.GetString(KeyPalette) != "" {
+		name = baseCmd.viperCfg.GetString(KeyPalette)
+	}
+	palette, ok := Palettes[name]
+	if !ok {
+		return palette, fmt.Errorf("invalid palette %q: must be one of %s", name, strings.Join(PaletteNames(), ", "))
+	}
+	return palette, nil
+}
+
+// glyphColor returns the color of a status glyph
+func (p Palette) glyphColor(glyph string) PaletteColor {
+	switch glyph {
+	case glyphSuccess:
+		return p.Success
+	case glyphFailure:
+		return p.Failure
+	case glyphWarning:
+		return p.Warning
+	}
+	return p.Info
+}
+
+// ColorGlyph returns the given status glyph, or its plain text equivalent in accessible mode, in the color of its status
+func (baseCmd *BaseCommand) ColorGlyph(glyph string) string {
+	return activePalette.glyphColor(glyph).Sprint(baseCmd.Glyph(glyph))
+}
+
+// prettyJSONFormatter returns a formatter of JSON highlighted with the palette
+func prettyJSONFormatter() *prettyjson.Formatter {
+	formatter := prettyjson.NewFormatter()
+	formatter.KeyColor = activePalette.Key.New()
+	formatter.StringColor = activePalette.String.New()
+	formatter.BoolColor = activePalette.Literal.New()
+	formatter.NumberColor = activePalette.Literal.New()
+	formatter.NullColor = color.New(color.Faint)
+	return formatter
+}
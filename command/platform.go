@@ -0,0 +1,82 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// KeyPlatform is the flag for selecting the platform of pulled images
+const KeyPlatform = "platform"
+
+// DefaultImagePlatform is the platform that all Opsani images are published for
+const DefaultImagePlatform = "linux/amd64"
+
+var platformPattern = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/[a-z0-9]+)?$`)
+
+// ValidatePlatform returns an error if the platform is not of the form os/arch[/variant]
+func ValidatePlatform(platform string) error {
+	if !platformPattern.MatchString(platform) {
+		return fmt.Errorf("invalid platform %q: must be of the form os/arch[/variant] (e.g. linux/arm64)", platform)
+	}
+	return nil
+}
+
+// SamePlatform reports whether the platforms have the same OS and architecture, ignoring any variant
+func SamePlatform(a, b string) bool {
+	osArch := func(platform string) string {
+		components := strings.SplitN(platform, "/", 3)
+		if len(components) < 2 {
+			return platform
+		}
+		return components[0] + "/" + components[1]
+	}
+	return osArch(a) == osArch(b)
+}
+
+// HostPlatformFunc returns the platform of Linux containers native to the host machine
+type HostPlatformFunc func() string
+
+var hostPlatform HostPlatformFunc = detectHostPlatform
+
+// SetHostPlatformFunc sets the function used to determine the platform of the host machine
+// A nil function restores the default detection
+func SetHostPlatformFunc(f HostPlatformFunc) {
+	if f == nil {
+		f = detectHostPlatform
+	}
+	hostPlatform = f
+}
+
+// detectHostPlatform returns the Linux platform matching the host architecture
+// Binaries translated by Rosetta on Apple Silicon report amd64 so the translation state is checked
+func detectHostPlatform() string {
+	arch := runtime.GOARCH
+	if runtime.GOOS == "darwin" && arch == "amd64" {
+		output, err := exec.CommandContext(context.Background(), "sysctl", "-n", "sysctl.proc_translated").Output()
+		if err == nil && strings.TrimSpace(string(output)) == "1" {
+			arch = "arm64"
+		}
+	}
+	if arch == "arm" {
+		return "linux/arm/v7"
+	}
+	return "linux/" + arch
+}
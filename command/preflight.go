@@ -0,0 +1,249 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// KeySkipPreflight is the flag for bypassing the cluster capability probe before applying manifests
+const KeySkipPreflight = "skip-preflight"
+
+// PreflightStatus is the outcome of a preflight check
+type PreflightStatus string
+
+// Preflight check outcomes
+const (
+	PreflightPass PreflightStatus = "pass"
+	PreflightWarn PreflightStatus = "warn"
+	PreflightFail PreflightStatus = "fail"
+)
+
+// PreflightCheck describes whether the cluster has a capability required to deploy the servo
+type PreflightCheck struct {
+	Name        string          `json:"name"`
+	Status      PreflightStatus `json:"status"`
+	Detail      string          `json:"detail"`
+	Remediation string          `json:"remediation,omitempty"`
+}
+
+// PreflightReport is the result of probing a cluster before deploying the servo
+type PreflightReport struct {
+	Checks []PreflightCheck `json:"checks"`
+}
+
+// Failures returns the number of failed checks
+func (r PreflightReport) Failures() int {
+	failures := 0
+	for _, check := range r.Checks {
+		if check.Status == PreflightFail {
+			failures++
+		}
+	}
+	return failures
+}
+
+func (r *PreflightReport) add(name string, status PreflightStatus, detail, remediation string) {
+	r.Checks = append(r.Checks, PreflightCheck{Name: name, Status: status, Detail: detail, Remediation: remediation})
+}
+
+// PreflightOptions describes the deployment the cluster is probed for
+type PreflightOptions struct {
+	// Scope of the servo RBAC permissions: cluster or namespace
+	Scope string
+
+	// Namespace the servo is deployed into
+	Namespace string
+
+	// PrometheusOperator indicates that the Prometheus Operator CRDs are required
+	PrometheusOperator bool
+}
+
+// RunPreflight probes the cluster for the capabilities needed to apply the servo manifests
+// Probing continues past failed checks so that every issue is reported at once
+func RunPreflight(ctx context.Context, kubernetes *KubernetesClient, options PreflightOptions) PreflightReport {
+	report := PreflightReport{}
+	versions, err := kubernetes.APIVersions()
+	if err != nil {
+		report.add("Cluster access", PreflightFail, err.Error(),
+			"Verify that the current context of your kubeconfig points at a running cluster and that your credentials are valid")
+		return report
+	}
+	apiVersions := map[string]bool{}
+	for _, version := range versions {
+		apiVersions[version] = true
+	}
+	canI := func(verb string, resource schema.GroupResource, namespace string) bool {
+		allowed, err := kubernetes.CanI(ctx, verb, resource, namespace)
+		return err == nil && allowed
+	}
+
+	// RBAC
+	if apiVersions["rbac.authorization.k8s.io/v1"] {
+		report.add("RBAC", PreflightPass, "RBAC authorization is enabled", "")
+	} else {
+		report.add("RBAC", PreflightFail, "the rbac.authorization.k8s.io/v1 API is not available",
+			"Enable RBAC authorization on the API server (--authorization-mode=RBAC)")
+	}
+
+	// Servo permissions
+	if options.Scope == RBACScopeNamespace {
+		if canI("create", rbacResource("roles"), options.Namespace) && canI("create", rbacResource("rolebindings"), options.Namespace) {
+			report.add("Permissions", PreflightPass, fmt.Sprintf("can create roles in namespace %q", options.Namespace), "")
+		} else {
+			report.add("Permissions", PreflightFail, fmt.Sprintf("not permitted to create roles and role bindings in namespace %q", options.Namespace),
+				fmt.Sprintf("Ask a cluster administrator to grant you the admin role in namespace %q", options.Namespace))
+		}
+	} else {
+		if canI("create", rbacResource("clusterroles"), "") && canI("create", rbacResource("clusterrolebindings"), "") {
+			report.add("Permissions", PreflightPass, "can create cluster roles", "")
+		} else {
+			report.add("Permissions", PreflightFail, "not permitted to create cluster roles and cluster role bindings",
+				"Ask a cluster administrator to grant you the cluster-admin role or deploy with --scope namespace")
+		}
+	}
+
+	// Metrics
+	if apiVersions["metrics.k8s.io/v1beta1"] {
+		report.add("Metrics", PreflightPass, "the resource metrics API is available", "")
+	} else {
+		report.add("Metrics", PreflightWarn, "the resource metrics API (metrics.k8s.io) is not available",
+			"Install metrics-server (on minikube run `minikube addons enable metrics-server`)")
+	}
+
+	// Prometheus Operator
+	if options.PrometheusOperator {
+		if apiVersions["monitoring.coreos.com/v1"] {
+			report.add("Prometheus Operator", PreflightPass, "the Prometheus Operator CRDs are installed", "")
+		} else if canI("create", customResourceDefinitionResource.GroupResource(), "") {
+			report.add("Prometheus Operator", PreflightPass, "the Prometheus Operator CRDs will be installed", "")
+		} else {
+			report.add("Prometheus Operator", PreflightFail, "the Prometheus Operator CRDs are not installed and you are not permitted to install them",
+				"Ask a cluster administrator to install the Prometheus Operator CRDs (monitoring.coreos.com)")
+		}
+	}
+
+	// Pod security
+	checkPodSecurity(ctx, kubernetes, options.Namespace, apiVersions, &report)
+	return report
+}
+
+// checkPodSecurity reports admission constraints that may reject the servo pods
+func checkPodSecurity(ctx context.Context, kubernetes *KubernetesClient, namespace string, apiVersions map[string]bool, report *PreflightReport) {
+	if output, err := kubernetes.Get(ctx, namespaceResource, "", namespace); err == nil {
+		level := gjson.GetBytes(output, `metadata.labels.pod-security\.kubernetes\.io/enforce`).String()
+		if level == "restricted" {
+			report.add("Pod security", PreflightWarn, fmt.Sprintf("namespace %q enforces the restricted Pod Security Standard", namespace),
+				fmt.Sprintf("Relax enforcement with `kubectl label namespace %s pod-security.kubernetes.io/enforce=baseline --overwrite`", namespace))
+			return
+		}
+	}
+	if apiVersions["policy/v1beta1"] {
+		if output, err := kubernetes.List(ctx, podSecurityPolicyResource, "", nil); err == nil && len(gjson.GetBytes(output, "items").Array()) > 0 {
+			report.add("Pod security", PreflightWarn, "PodSecurityPolicies are enforced by the cluster",
+				fmt.Sprintf("Grant the servo service account in namespace %q use of a policy that admits its pods", namespace))
+			return
+		}
+	}
+	report.add("Pod security", PreflightPass, "no pod security constraints detected", "")
+}
+
+// rbacResource returns a resource of the RBAC authorization API group
+func rbacResource(resource string) schema.GroupResource {
+	return schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: resource}
+}
+
+// RunPreflight probes the cluster, renders the report, and returns an error if any check failed
+func (vitalCommand *vitalCommand) RunPreflight(kubernetes *KubernetesClient, options PreflightOptions) error {
+	var report PreflightReport
+	err := vitalCommand.RunTaskWithSpinner(Task{
+		Description: vitalCommand.T("preflight.task.description"),
+		Success:     vitalCommand.T("preflight.task.success"),
+		Failure:     vitalCommand.T("preflight.task.failure"),
+		Run: func() error {
+			ctx, cancel := vitalCommand.ContextWithTimeout()
+			defer cancel()
+			report = RunPreflight(ctx, kubernetes, options)
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+	vitalCommand.renderPreflightReport(report)
+	if failures := report.Failures(); failures > 0 {
+		return fmt.Errorf(vitalCommand.T("preflight.failed", failures))
+	}
+	return nil
+}
+
+func (vitalCommand *vitalCommand) renderPreflightReport(report PreflightReport) {
+	bold := color.New(color.Bold).SprintFunc()
+	out := vitalCommand.OutOrStdout()
+	fmt.Fprintln(out)
+	for _, check := range report.Checks {
+		message := fmt.Sprintf("%s: %s", bold(check.Name), check.Detail)
+		switch check.Status {
+		case PreflightPass:
+			fmt.Fprint(out, vitalCommand.successMessage(message))
+		case PreflightWarn:
+			fmt.Fprint(out, vitalCommand.warningMessage(message))
+		default:
+			fmt.Fprint(out, vitalCommand.failureMessage(message))
+		}
+		if check.Remediation != "" {
+			fmt.Fprintf(out, "   %s\n", check.Remediation)
+		}
+	}
+	fmt.Fprintln(out)
+}
+
+// NewIgnitePreflightCommand returns a new `opsani ignite preflight` command instance
+func NewIgnitePreflightCommand(vitalCommand *vitalCommand) *cobra.Command {
+	preflightCmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Check that the cluster can run the servo",
+		Long: `Probes the current kubeconfig context for the capabilities required to deploy the servo:
+RBAC authorization, permission to create the servo roles, the resource metrics API,
+the Prometheus Operator CRDs, and pod security constraints.
+
+Each failed check is reported with remediation steps. Preflight checks run automatically
+before ignite applies manifests unless --skip-preflight is given.`,
+		Args:              cobra.NoArgs,
+		PersistentPreRunE: nil,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options := PreflightOptions{PrometheusOperator: true}
+			options.Scope, _ = cmd.Flags().GetString("scope")
+			options.Namespace, _ = cmd.Flags().GetString("namespace")
+			if err := validateRBACScope(options.Scope); err != nil {
+				return err
+			}
+			kubernetes, err := kubernetesClientFactory("", "")
+			if err != nil {
+				return err
+			}
+			return vitalCommand.RunPreflight(kubernetes, options)
+		},
+	}
+	preflightCmd.Flags().String("scope", RBACScopeCluster, "Scope of servo permissions: {cluster|namespace}")
+	preflightCmd.Flags().String("namespace", "default", "Namespace the servo is deployed into")
+	return preflightCmd
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// cannedKubectl returns canned output keyed by the space joined arguments, failing for unknown arguments
+func cannedKubectl(responses map[string]string) command.KubectlRunner {
+	return func(ctx context.Context, args ...string) ([]byte, error) {
+		if output, ok := responses[strings.Join(args, " ")]; ok {
+			return []byte(output), nil
+		}
+		return nil, errors.New("exit status 1")
+	}
+}
+
+func preflightStatuses(report command.PreflightReport) map[string]command.PreflightStatus {
+	statuses := map[string]command.PreflightStatus{}
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	return statuses
+}
+
+func TestPreflightCapableCluster(t *testing.T) {
+	kubernetes := test.NewFakeKubernetes()
+	kubernetes.SetAPIVersions("v1", "apps/v1", "metrics.k8s.io/v1beta1", "monitoring.coreos.com/v1", "rbac.authorization.k8s.io/v1", "authorization.k8s.io/v1")
+	kubernetes.Allow("create", "clusterroles.rbac.authorization.k8s.io")
+	kubernetes.Allow("create", "clusterrolebindings.rbac.authorization.k8s.io")
+	kubernetes.Objects["namespaces/default"] = `{"metadata": {"name": "default"}}`
+	report := command.RunPreflight(context.Background(), kubernetes.Client(), command.PreflightOptions{
+		Scope: command.RBACScopeCluster, Namespace: "default", PrometheusOperator: true,
+	})
+	require.Equal(t, 0, report.Failures())
+	require.Equal(t, map[string]command.PreflightStatus{
+		"RBAC":                command.PreflightPass,
+		"Permissions":         command.PreflightPass,
+		"Metrics":             command.PreflightPass,
+		"Prometheus Operator": command.PreflightPass,
+		"Pod security":        command.PreflightPass,
+	}, preflightStatuses(report))
+}
+
+func TestPreflightRestrictedCluster(t *testing.T) {
+	kubernetes := test.NewFakeKubernetes()
+	kubernetes.SetAPIVersions("v1", "apps/v1", "policy/v1beta1", "rbac.authorization.k8s.io/v1", "authorization.k8s.io/v1")
+	kubernetes.Allow("create", "roles.rbac.authorization.k8s.io")
+	kubernetes.Objects["namespaces/apps"] = `{"metadata": {"name": "apps", "labels": {"pod-security.kubernetes.io/enforce": "restricted"}}}`
+	report := command.RunPreflight(context.Background(), kubernetes.Client(), command.PreflightOptions{
+		Scope: command.RBACScopeNamespace, Namespace: "apps", PrometheusOperator: true,
+	})
+	require.Equal(t, 2, report.Failures())
+	require.Equal(t, map[string]command.PreflightStatus{
+		"RBAC":                command.PreflightPass,
+		"Permissions":         command.PreflightFail,
+		"Metrics":             command.PreflightWarn,
+		"Prometheus Operator": command.PreflightFail,
+		"Pod security":        command.PreflightWarn,
+	}, preflightStatuses(report))
+	for _, check := range report.Checks {
+		if check.Status != command.PreflightPass {
+			require.NotEmpty(t, check.Remediation, check.Name)
+		}
+	}
+}
+
+func TestPreflightReviewsAccessInNamespace(t *testing.T) {
+	kubernetes := test.NewFakeKubernetes()
+	command.RunPreflight(context.Background(), kubernetes.Client(), command.PreflightOptions{
+		Scope: command.RBACScopeNamespace, Namespace: "apps",
+	})
+	reviews := []string{}
+	for _, action := range kubernetes.Actions() {
+		if create, ok := action.(k8stesting.CreateAction); ok {
+			attributes, _, _ := unstructured.NestedStringMap(create.GetObject().(*unstructured.Unstructured).Object, "spec", "resourceAttributes")
+			reviews = append(reviews, strings.Join([]string{attributes["verb"], attributes["group"], attributes["resource"], attributes["namespace"]}, " "))
+		}
+	}
+	require.Equal(t, []string{"create rbac.authorization.k8s.io roles apps"}, reviews)
+}
+
+func TestPreflightPodSecurityPolicies(t *testing.T) {
+	kubernetes := test.NewFakeKubernetes()
+	kubernetes.SetAPIVersions("policy/v1beta1", "rbac.authorization.k8s.io/v1", "authorization.k8s.io/v1")
+	kubernetes.Allow("create", "clusterroles.rbac.authorization.k8s.io")
+	kubernetes.Allow("create", "clusterrolebindings.rbac.authorization.k8s.io")
+	kubernetes.Lists["podsecuritypolicies.policy"] = `{"items": [{"metadata": {"name": "restricted"}}]}`
+	report := command.RunPreflight(context.Background(), kubernetes.Client(), command.PreflightOptions{Scope: command.RBACScopeCluster, Namespace: "default"})
+	require.Equal(t, command.PreflightWarn, preflightStatuses(report)["Pod security"])
+	require.NotContains(t, preflightStatuses(report), "Prometheus Operator")
+}
+
+func TestPreflightUnreachableCluster(t *testing.T) {
+	kubernetes := test.NewFakeKubernetes()
+	kubernetes.DiscoveryError = errors.New("connection refused")
+	report := command.RunPreflight(context.Background(), kubernetes.Client(), command.PreflightOptions{Scope: command.RBACScopeCluster})
+	require.Equal(t, []command.PreflightCheck{{
+		Name:        "Cluster access",
+		Status:      command.PreflightFail,
+		Detail:      "connection refused",
+		Remediation: "Verify that the current context of your kubeconfig points at a running cluster and that your credentials are valid",
+	}}, report.Checks)
+}
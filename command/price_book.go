@@ -0,0 +1,83 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// HoursPerMonth is the number of hours used to convert hourly prices into monthly costs
+const HoursPerMonth = 730
+
+// PriceBook describes the hourly price of compute resources
+type PriceBook struct {
+	Name          string  `json:"name"`
+	Currency      string  `json:"currency"`
+	CPUHour       float64 `json:"cpu_hour"`
+	MemoryGiBHour float64 `json:"memory_gib_hour"`
+}
+
+// PriceBooks are the built-in on-demand price books, keyed by cloud provider
+// Prices are approximate list prices in USD for serverless container capacity in a US region
+var PriceBooks = map[string]PriceBook{
+	"aws":   {Name: "AWS Fargate on-demand (us-east-1)", Currency: "USD", CPUHour: 0.04048, MemoryGiBHour: 0.004445},
+	"gcp":   {Name: "GCP Compute Engine N2 custom on-demand (us-central1)", Currency: "USD", CPUHour: 0.031611, MemoryGiBHour: 0.004237},
+	"azure": {Name: "Azure Container Instances on-demand (East US)", Currency: "USD", CPUHour: 0.0486, MemoryGiBHour: 0.00533},
+}
+
+// PriceBookNames returns the sorted names of the built-in price books
+func PriceBookNames() []string {
+	names := []string{}
+	for name := range PriceBooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadPriceBook returns the built-in price book with the given name or loads one from a YAML or JSON file
+func LoadPriceBook(nameOrPath string) (PriceBook, error) {
+	if priceBook, ok := PriceBooks[strings.ToLower(nameOrPath)]; ok {
+		return priceBook, nil
+	}
+	data, err := ioutil.ReadFile(nameOrPath)
+	if err != nil {
+		return PriceBook{}, fmt.Errorf("unknown price book %q: must be one of %s or a path to a price book file", nameOrPath, strings.Join(PriceBookNames(), ", "))
+	}
+	priceBook := PriceBook{}
+	if err := yaml.Unmarshal(data, &priceBook); err != nil {
+		return PriceBook{}, fmt.Errorf("failed parsing price book %q: %w", nameOrPath, err)
+	}
+	if priceBook.CPUHour <= 0 || priceBook.MemoryGiBHour <= 0 {
+		return PriceBook{}, fmt.Errorf("invalid price book %q: cpu_hour and memory_gib_hour must be positive", nameOrPath)
+	}
+	if priceBook.Name == "" {
+		priceBook.Name = nameOrPath
+	}
+	if priceBook.Currency == "" {
+		priceBook.Currency = "USD"
+	}
+	return priceBook, nil
+}
+
+// MonthlyCost returns the monthly price of running the replicas with the given CPU cores and GiB of memory each
+func (p PriceBook) MonthlyCost(cpu, memoryGiB float64, replicas int) float64 {
+	return float64(replicas) * (cpu*p.CPUHour + memoryGiB*p.MemoryGiBHour) * HoursPerMonth
+}
@@ -18,7 +18,6 @@ import (
 	"fmt"
 
 	"github.com/AlecAivazis/survey/v2"
-	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
@@ -26,7 +25,6 @@ import (
 type profileCommand struct {
 	*BaseCommand
 	verbose bool
-	force   bool
 }
 
 // NewProfileCommand returns a new instance of the profile command
@@ -56,6 +54,7 @@ func NewProfileCommand(baseCmd *BaseCommand) *cobra.Command {
 		RunE:        profileCommand.RunProfileList,
 	}
 	listCmd.Flags().BoolVarP(&profileCommand.verbose, "verbose", "v", false, "Display verbose output")
+	AddOutputFlag(listCmd, TabularOutputFormats...)
 	profileCmd.AddCommand(listCmd)
 	addCmd := &cobra.Command{
 		Use:                   "add [OPTIONS] [NAME]",
@@ -66,8 +65,32 @@ func NewProfileCommand(baseCmd *BaseCommand) *cobra.Command {
 		RunE:                  profileCommand.RunAddProfile,
 		DisableFlagsInUseLine: true,
 	}
+	AddSkipVerifyFlag(addCmd)
 	profileCmd.AddCommand(addCmd)
 
+	updateCmd := &cobra.Command{
+		Use:                   "update [OPTIONS] NAME",
+		Long:                  "Update the optimizer, token, or base URL of an existing profile",
+		Annotations:           map[string]string{"registry": "true"},
+		Short:                 "Update a profile",
+		Args:                  cobra.ExactArgs(1),
+		RunE:                  profileCommand.RunUpdateProfile,
+		DisableFlagsInUseLine: true,
+	}
+	profileCmd.AddCommand(updateCmd)
+
+	renameCmd := &cobra.Command{
+		Use:                   "rename NAME NEW_NAME",
+		Long:                  "Rename an existing profile, preserving its settings and attached servo",
+		Annotations:           map[string]string{"registry": "true"},
+		Aliases:               []string{"mv"},
+		Short:                 "Rename a profile",
+		Args:                  cobra.ExactArgs(2),
+		RunE:                  profileCommand.RunRenameProfile,
+		DisableFlagsInUseLine: true,
+	}
+	profileCmd.AddCommand(renameCmd)
+
 	removeCmd := &cobra.Command{
 		Use:                   "remove [OPTIONS] [NAME]",
 		Long:                  "Remove a profile from the configuration",
@@ -78,9 +101,11 @@ func NewProfileCommand(baseCmd *BaseCommand) *cobra.Command {
 		RunE:                  profileCommand.RunRemoveProfile,
 		DisableFlagsInUseLine: true,
 	}
-	removeCmd.Flags().BoolVarP(&profileCommand.force, "force", "f", false, "Don't prompt for confirmation")
+	baseCmd.AddDeprecatedForceFlag(removeCmd)
 	profileCmd.AddCommand(removeCmd)
 
+	profileCmd.AddCommand(NewProfileTestCommand(&profileCommand))
+
 	return profileCmd
 }
 
@@ -90,6 +115,13 @@ func (profileCmd *profileCommand) RunAddProfile(c *cobra.Command, args []string)
 		Token:     profileCmd.tokenFromFlagsOrEnv(),
 		BaseURL:   profileCmd.BaseURL(),
 	}
+	if profile.Optimizer != "" {
+		optimizer, err := NormalizeOptimizer(profile.Optimizer)
+		if err != nil {
+			return err
+		}
+		profile.Optimizer = optimizer
+	}
 	if len(args) > 0 {
 		profile.Name = args[0]
 	}
@@ -106,10 +138,11 @@ func (profileCmd *profileCommand) RunAddProfile(c *cobra.Command, args []string)
 	if profile.Optimizer == "" {
 		err := profileCmd.AskOne(&survey.Input{
 			Message: "Opsani optimizer (e.g. domain.com/app)?",
-		}, &profile.Optimizer, survey.WithValidator(survey.Required))
+		}, &profile.Optimizer, survey.WithValidator(survey.ComposeValidators(survey.Required, optimizerValidator)))
 		if err != nil {
 			return err
 		}
+		profile.Optimizer, _ = NormalizeOptimizer(profile.Optimizer)
 	}
 
 	if profile.Token == "" {
@@ -124,6 +157,13 @@ func (profileCmd *profileCommand) RunAddProfile(c *cobra.Command, args []string)
 	if registry, err := NewProfileRegistry(profileCmd.viperCfg); err != nil {
 		return err
 	} else {
+		if registry.ProfileNamed(profile.Name) != nil {
+			return fmt.Errorf("profile %q already exists", profile.Name)
+		}
+		if err := profileCmd.confirmOptimizer(c, profile); err != nil {
+			return err
+		}
+		profileCmd.recordScopes(c, &profile)
 		registry.AddProfile(profile)
 		err = registry.Save()
 		if err != nil {
@@ -150,6 +190,67 @@ func (profileCmd *profileCommand) RunAddProfile(c *cobra.Command, args []string)
 	return nil
 }
 
+func (profileCmd *profileCommand) RunUpdateProfile(c *cobra.Command, args []string) error {
+	registry, err := NewProfileRegistry(profileCmd.viperCfg)
+	if err != nil {
+		return err
+	}
+	name := args[0]
+	profile := registry.ProfileNamed(name)
+	if profile == nil {
+		return fmt.Errorf("Unable to find profile %q", name)
+	}
+
+	// Only apply values explicitly given on the command line
+	updated := *profile
+	changed := false
+	for key, field := range map[string]*string{
+		KeyOptimizer: &updated.Optimizer,
+		KeyToken:     &updated.Token,
+		KeyBaseURL:   &updated.BaseURL,
+	} {
+		if c.Flags().Changed(key) {
+			*field, _ = c.Flags().GetString(key)
+			changed = true
+		}
+	}
+	if !changed {
+		return fmt.Errorf("nothing to update: specify one or more of --%s, --%s, or --%s", KeyOptimizer, KeyToken, KeyBaseURL)
+	}
+	if updated.Optimizer, err = NormalizeOptimizer(updated.Optimizer); err != nil {
+		return err
+	}
+	if err := updated.ValidateOrg(); err != nil {
+		return err
+	}
+	if updated.Token != profile.Token || updated.BaseURL != profile.BaseURL {
+		// Scopes recorded for the previous token no longer apply
+		updated.Scopes = nil
+	}
+
+	if err := registry.UpdateProfile(name, updated); err != nil {
+		return err
+	}
+	if err := profileCmd.BackupConfig(fmt.Sprintf("profile update %s", name)); err != nil {
+		return err
+	}
+	return registry.Save()
+}
+
+func (profileCmd *profileCommand) RunRenameProfile(_ *cobra.Command, args []string) error {
+	registry, err := NewProfileRegistry(profileCmd.viperCfg)
+	if err != nil {
+		return err
+	}
+	if err := registry.RenameProfile(args[0], args[1]); err != nil {
+		return err
+	}
+	if err := profileCmd.BackupConfig(fmt.Sprintf("profile rename %s %s", args[0], args[1])); err != nil {
+		return err
+	}
+	return registry.Save()
+}
+
 func (profileCmd *profileCommand) RunRemoveProfile(_ *cobra.Command, args []string) error {
 	registry, err := NewProfileRegistry(profileCmd.viperCfg)
 	if err != nil {
@@ -161,15 +262,15 @@ func (profileCmd *profileCommand) RunRemoveProfile(_ *cobra.Command, args []stri
 		return fmt.Errorf("Unable to find profile %q", name)
 	}
 
-	confirmed := profileCmd.force
-	if !confirmed {
-		prompt := &survey.Confirm{
-			Message: fmt.Sprintf("Remove profile %q?", profile.Name),
-		}
-		profileCmd.AskOne(prompt, &confirmed)
+	confirmed, err := profileCmd.Confirm(fmt.Sprintf("Remove profile %q?", profile.Name), false)
+	if err != nil {
+		return err
 	}
 
 	if confirmed {
+		if err := profileCmd.BackupConfig(fmt.Sprintf("profile remove %s", profile.Name)); err != nil {
+			return err
+		}
 		registry.RemoveProfile(*profile)
 		return registry.Save()
 	}
@@ -177,52 +278,27 @@ func (profileCmd *profileCommand) RunRemoveProfile(_ *cobra.Command, args []stri
 	return nil
 }
 
-func (profileCmd *profileCommand) RunProfileList(_ *cobra.Command, args []string) error {
-	table := tablewriter.NewWriter(profileCmd.OutOrStdout())
-	table.SetAutoWrapText(false)
-	table.SetAutoFormatHeaders(true)
-	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
-	table.SetAlignment(tablewriter.ALIGN_LEFT)
-	table.SetCenterSeparator("")
-	table.SetColumnSeparator("")
-	table.SetRowSeparator("")
-	table.SetHeaderLine(false)
-	table.SetBorder(false)
-	table.SetTablePadding("\t") // pad with tabs
-	table.SetNoWhiteSpace(true)
-
-	data := [][]string{}
+func (profileCmd *profileCommand) RunProfileList(c *cobra.Command, args []string) error {
+	output, err := OutputFormat(c, TabularOutputFormats...)
+	if err != nil {
+		return err
+	}
 	registry, err := NewProfileRegistry(profileCmd.viperCfg)
 	if err != nil {
 		return err
 	}
-	profiles := registry.Profiles()
 
-	if profileCmd.verbose {
-		headers := []string{"NAME", "OPTIMIZER", "TOKEN", "SERVO"}
-		for _, profile := range profiles {
-			row := []string{
-				profile.Name,
-				profile.Optimizer,
-				profile.Token,
-				profile.Servo.Description(),
-			}
-			data = append(data, row)
-		}
-		table.SetHeader(headers)
-	} else {
-		for _, profile := range profiles {
-			row := []string{
-				profile.Name,
-				profile.Optimizer,
-				profile.Token,
-				profile.Servo.Description(),
-			}
-			data = append(data, row)
-		}
+	table := Table{
+		Headers:     []string{"NAME", "OPTIMIZER", "TOKEN", "SERVO"},
+		HideHeaders: !profileCmd.verbose,
 	}
-
-	table.AppendBulk(data)
-	table.Render()
-	return nil
+	for _, profile := range registry.Profiles() {
+		table.Rows = append(table.Rows, []string{
+			profile.Name,
+			profile.Optimizer,
+			profileCmd.Redact(profile.Token),
+			profile.Servo.Description(),
+		})
+	}
+	return profileCmd.RenderTable(output, table)
 }
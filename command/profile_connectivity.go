@@ -0,0 +1,162 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultConnectivityTimeout bounds each connectivity check when no timeout is configured
+const defaultConnectivityTimeout = 30 * time.Second
+
+// ConnectivityCheck is the outcome of checking that a profile can reach one of its endpoints
+type ConnectivityCheck struct {
+	Name    string
+	Detail  string
+	Latency time.Duration
+	Err     error
+
+	// Skipped is set when the profile has nothing to check
+	Skipped bool
+}
+
+// Status returns a short description of the outcome
+func (check ConnectivityCheck) Status() string {
+	if check.Skipped {
+		return "skipped"
+	} else if check.Err != nil {
+		return "failed"
+	}
+	return "ok"
+}
+
+// runConnectivityCheck times the check function
+func runConnectivityCheck(name, detail string, check func() error) ConnectivityCheck {
+	started := time.Now()
+	err := check()
+	return ConnectivityCheck{Name: name, Detail: detail, Latency: time.Since(started), Err: err}
+}
+
+// NewProfileTestCommand returns a new `opsani profile test` command instance
+func NewProfileTestCommand(profileCommand *profileCommand) *cobra.Command {
+	testCmd := &cobra.Command{
+		Use:   "test [NAME]",
+		Short: "Test connectivity of a profile",
+		Long: `Verifies that the Opsani API is reachable and accepts the token of the profile, and that the
+servo attached to the profile can be reached: the kubectl context can read the servo deployment
+or an SSH session can be opened on the Docker Compose host.
+
+Each check is reported with its latency. The active profile is tested unless a profile is named.
+Checks are limited to 30s unless a timeout is configured.`,
+		Example: `  opsani profile test
+  opsani profile test staging -o json`,
+		Annotations: map[string]string{"registry": "true"},
+		Args:        cobra.MaximumNArgs(1),
+		RunE:        profileCommand.RunTestProfile,
+	}
+	AddOutputFlag(testCmd, TabularOutputFormats...)
+	return testCmd
+}
+
+// profileToTest returns the named profile with defaults applied or the active profile
+func (profileCmd *profileCommand) profileToTest(args []string) (*Profile, error) {
+	if len(args) == 0 {
+		profile, err := profileCmd.LoadProfile()
+		if err == nil && profile == nil {
+			err = fmt.Errorf("no profiles are configured")
+		}
+		return profile, err
+	}
+
+	registry, err := NewProfileRegistry(profileCmd.viperCfg)
+	if err != nil {
+		return nil, err
+	}
+	profile := registry.ProfileNamed(args[0])
+	if profile == nil {
+		return nil, fmt.Errorf("Unable to find profile %q", args[0])
+	}
+	profile.ApplyDefaults(registry.Defaults())
+	// Timeouts configured on the profile apply to its checks
+	profileCmd.profile = profile
+	return profile, nil
+}
+
+// testProfile checks connectivity to the API and servo of the profile
+func (profileCmd *profileCommand) testProfile(profile Profile) []ConnectivityCheck {
+	checks := []ConnectivityCheck{
+		runConnectivityCheck("API", profile.Optimizer, func() error {
+			return profileCmd.verifyOptimizer(profile)
+		}),
+	}
+
+	if profile.Servo == (Servo{}) {
+		return append(checks, ConnectivityCheck{Name: "Servo", Detail: "no servo attached", Skipped: true})
+	}
+	checks = append(checks, runConnectivityCheck("Servo", profile.Servo.Description(), func() error {
+		driver, err := servoDriverFactory(profile.Servo, profileCmd.Timeout())
+		if err != nil {
+			return err
+		}
+		if d, ok := driver.(interface{ SetPrompter(SSHPrompter) }); ok {
+			d.SetPrompter(profileCmd.BaseCommand)
+		}
+		return driver.Ping()
+	}))
+	return checks
+}
+
+func (profileCmd *profileCommand) RunTestProfile(c *cobra.Command, args []string) error {
+	output, err := OutputFormat(c, TabularOutputFormats...)
+	if err != nil {
+		return err
+	}
+	profile, err := profileCmd.profileToTest(args)
+	if err != nil {
+		return err
+	}
+	if profileCmd.Timeout() == 0 {
+		// The profile under test is the active profile of the command so its timeout bounds every check
+		profile.Timeout = defaultConnectivityTimeout.String()
+	}
+
+	checks := profileCmd.testProfile(*profile)
+	table := Table{Headers: []string{"CHECK", "STATUS", "LATENCY", "DETAIL"}}
+	failures := 0
+	for _, check := range checks {
+		status, latency, detail := check.Status(), "-", check.Detail
+		if !check.Skipped {
+			latency = check.Latency.Round(time.Millisecond).String()
+		}
+		if check.Err != nil {
+			failures++
+			detail = check.Err.Error()
+		}
+		if output == OutputTable {
+			status = profileCmd.ColorGlyph(map[string]string{"ok": glyphSuccess, "failed": glyphFailure, "skipped": glyphInfo}[status]) + " " + status
+		}
+		table.Rows = append(table.Rows, []string{check.Name, status, latency, detail})
+	}
+	if err := profileCmd.RenderTable(output, table); err != nil {
+		return err
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d of %d connectivity checks failed for profile %q", failures, len(checks), profile.Name)
+	}
+	return nil
+}
@@ -36,6 +36,14 @@ type Servo struct {
 	// Kubernetes
 	Namespace  string `yaml:"namespace,omitempty" mapstructure:"namespace,omitempty"`
 	Deployment string `yaml:"deployment,omitempty" mapstructure:"deployment,omitempty"`
+	Kubeconfig string `yaml:"kubeconfig,omitempty" mapstructure:"kubeconfig,omitempty"`
+	Context    string `yaml:"context,omitempty" mapstructure:"context,omitempty"`
+
+	// ServoDeployment names the custom resource of servos managed by the servo operator
+	ServoDeployment string `yaml:"servo_deployment,omitempty" mapstructure:"servo_deployment,omitempty"`
+
+	// PrometheusURL is the Prometheus endpoint the servo gathers metrics from
+	PrometheusURL string `yaml:"prometheus_url,omitempty" mapstructure:"prometheus_url,omitempty"`
 }
 
 // Description returns a textual description of the servo
@@ -108,6 +116,50 @@ type Profile struct {
 	Token     string `yaml:"token" mapstructure:"token" json:"token"`
 	BaseURL   string `yaml:"base_url,omitempty" mapstructure:"base_url,omitempty" json:"base_url,omitempty"`
 	Servo     Servo  `yaml:"servo,omitempty" mapstructure:"servo,omitempty" json:"servo,omitempty"`
+	Timeout   string `yaml:"timeout,omitempty" mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// Org is the organization selected with `opsani org switch`, which the optimizer must belong to when set
+	Org string `yaml:"org,omitempty" mapstructure:"org,omitempty" json:"org,omitempty"`
+
+	// Scopes are the permission scopes of the token, fetched when the profile is created
+	Scopes []string `yaml:"scopes,omitempty" mapstructure:"scopes,omitempty" json:"scopes,omitempty"`
+
+	// LegacyApp is the optimizer of profiles written before "app" was renamed to "optimizer"
+	// It is read for compatibility and dropped when the config is next saved
+	LegacyApp string `yaml:"-" mapstructure:"app,omitempty" json:"-"`
+}
+
+// ProfileDefaults describes settings inherited by all profiles unless overridden
+type ProfileDefaults struct {
+	BaseURL    string `yaml:"base_url,omitempty" mapstructure:"base_url,omitempty" json:"base_url,omitempty"`
+	Bastion    string `yaml:"bastion,omitempty" mapstructure:"bastion,omitempty" json:"bastion,omitempty"`
+	Kubeconfig string `yaml:"kubeconfig,omitempty" mapstructure:"kubeconfig,omitempty" json:"kubeconfig,omitempty"`
+	Namespace  string `yaml:"namespace,omitempty" mapstructure:"namespace,omitempty" json:"namespace,omitempty"`
+	Timeout    string `yaml:"timeout,omitempty" mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// ApplyDefaults fills in any unset values on the profile from the given defaults
+// Servo settings are only inherited when applicable to the servo type
+func (p *Profile) ApplyDefaults(defaults ProfileDefaults) {
+	if p.BaseURL == "" {
+		p.BaseURL = defaults.BaseURL
+	}
+	if p.Timeout == "" {
+		p.Timeout = defaults.Timeout
+	}
+	switch p.Servo.Type {
+	case "docker-compose":
+		if p.Servo.Bastion == "" {
+			p.Servo.Bastion = defaults.Bastion
+		}
+	case "kubernetes":
+		if p.Servo.Namespace == "" {
+			p.Servo.Namespace = defaults.Namespace
+		}
+		if p.Servo.Kubeconfig == "" {
+			p.Servo.Kubeconfig = defaults.Kubeconfig
+		}
+	}
 }
 
 // Organization returns the domain of the organization that owns the app
@@ -115,6 +167,14 @@ func (p Profile) Organization() string {
 	return filepath.Dir(p.Optimizer)
 }
 
+// ValidateOrg returns an error if the optimizer does not belong to the selected organization
+func (p Profile) ValidateOrg() error {
+	if p.Org == "" || p.Optimizer == "" || p.Organization() == p.Org {
+		return nil
+	}
+	return fmt.Errorf("optimizer %q does not belong to organization %q selected for profile %q", p.Optimizer, p.Org, p.Name)
+}
+
 // AppName returns the name of the app
 func (p Profile) AppName() string {
 	return filepath.Base(p.Optimizer)
@@ -129,6 +189,7 @@ func (p Profile) IsActive() bool {
 type ProfileRegistry struct {
 	viper    *viper.Viper
 	profiles []*Profile
+	defaults ProfileDefaults
 }
 
 // NewProfileRegistry returns a new registry of configured app profiles
@@ -138,13 +199,29 @@ func NewProfileRegistry(viper *viper.Viper) (*ProfileRegistry, error) {
 	if err != nil {
 		return nil, err
 	}
+	for _, profile := range profiles {
+		if profile.Optimizer == "" {
+			profile.Optimizer = profile.LegacyApp
+		}
+	}
+	defaults := ProfileDefaults{}
+	err = viper.UnmarshalKey("defaults", &defaults)
+	if err != nil {
+		return nil, err
+	}
 
 	return &ProfileRegistry{
 		viper:    viper,
 		profiles: profiles,
+		defaults: defaults,
 	}, nil
 }
 
+// Defaults returns the settings inherited by all profiles unless overridden
+func (pr *ProfileRegistry) Defaults() ProfileDefaults {
+	return pr.defaults
+}
+
 // Profiles returns the Profiles in the configuration
 func (pr *ProfileRegistry) Profiles() []*Profile {
 	return pr.profiles
@@ -178,6 +255,34 @@ func (pr *ProfileRegistry) AddProfile(profile Profile) error {
 	return nil
 }
 
+// UpdateProfile replaces the Profile with the given name in the config
+func (pr *ProfileRegistry) UpdateProfile(name string, profile Profile) error {
+	s, index := pr.lookupProfile(name)
+	if s == nil {
+		return fmt.Errorf("no such profile %q", name)
+	}
+	if profile.Name != name && pr.ProfileNamed(profile.Name) != nil {
+		return fmt.Errorf("profile %q already exists", profile.Name)
+	}
+	pr.profiles[index] = &profile
+	pr.viper.Set("profiles", pr.profiles)
+	return nil
+}
+
+// RenameProfile changes the name of an existing Profile in the config
+func (pr *ProfileRegistry) RenameProfile(name string, newName string) error {
+	profile := pr.ProfileNamed(name)
+	if profile == nil {
+		return fmt.Errorf("no such profile %q", name)
+	}
+	if newName == "" {
+		return fmt.Errorf("profile name cannot be blank")
+	}
+	renamed := *profile
+	renamed.Name = newName
+	return pr.UpdateProfile(name, renamed)
+}
+
 // RemoveProfileNamed removes a Profile from the config with the given name
 func (pr *ProfileRegistry) RemoveProfileNamed(name string) error {
 	s, index := pr.lookupProfile(name)
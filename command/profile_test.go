@@ -15,11 +15,16 @@
 package command_test
 
 import (
+	"encoding/json"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/opsani/cli/command"
 	"github.com/opsani/cli/test"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/yaml.v2"
 )
@@ -72,7 +77,7 @@ func (s *ProfileTestSuite) TestRunningAddNoInput() {
 			},
 		},
 	})
-	args := test.Args("--config", configFile.Name(), "profile", "add")
+	args := test.Args("--config", configFile.Name(), "profile", "add", "--skip-verify")
 	_, err := s.ExecuteTestInteractively(args, func(t *test.InteractiveTestContext) error {
 		t.RequireString("Profile name?")
 		t.SendLine("opsani-dev")
@@ -232,6 +237,23 @@ func (s *ProfileTestSuite) TestRunningProfileList() {
 	configFile := test.TempConfigFileWithObj(config)
 	output, err := s.Execute("--config", configFile.Name(), "profile", "list")
 	s.Require().NoError(err)
+	s.Require().Contains(output, "default	example.com/app	********")
+	s.Require().NotContains(output, "123456")
+}
+
+func (s *ProfileTestSuite) TestRunningProfileListShowSecrets() {
+	config := map[string]interface{}{
+		"profiles": []map[string]string{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+			},
+		},
+	}
+	configFile := test.TempConfigFileWithObj(config)
+	output, err := s.Execute("--config", configFile.Name(), "--show-secrets", "profile", "list")
+	s.Require().NoError(err)
 	s.Require().Contains(output, "default	example.com/app	123456")
 }
 
@@ -249,6 +271,284 @@ func (s *ProfileTestSuite) TestRunningProfileListVerbose() {
 	configFile := test.TempConfigFileWithObj(config)
 	output, err := s.Execute("--config", configFile.Name(), "profile", "list", "-v")
 	s.Require().NoError(err)
-	s.Require().Contains(output, "NAME   	OPTIMIZER      	TOKEN 	SERVO")
-	s.Require().Contains(output, "default	example.com/app	123456")
+	s.Require().Contains(output, "NAME   	OPTIMIZER      	TOKEN   	SERVO")
+	s.Require().Contains(output, "default	example.com/app	********")
+}
+
+func (s *ProfileTestSuite) TestRunningProfileUpdate() {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]interface{}{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+				"servo": map[string]string{
+					"type":       "kubernetes",
+					"namespace":  "opsani",
+					"deployment": "servo",
+				},
+			},
+		},
+	})
+	_, err := s.Execute("--config", configFile.Name(), "profile", "update", "default", "--optimizer", "example.com/other-app", "--token", "abcdef")
+	s.Require().NoError(err)
+
+	var config = map[string][]command.Profile{}
+	body, _ := ioutil.ReadFile(configFile.Name())
+	yaml.Unmarshal(body, &config)
+	s.Require().Equal("example.com/other-app", config["profiles"][0].Optimizer)
+	s.Require().Equal("abcdef", config["profiles"][0].Token)
+	s.Require().Equal("kubernetes", config["profiles"][0].Servo.Type)
+}
+
+func (s *ProfileTestSuite) TestRunningProfileUpdateNothingChanged() {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+			},
+		},
+	})
+	_, err := s.Execute("--config", configFile.Name(), "profile", "update", "default")
+	s.Require().EqualError(err, "nothing to update: specify one or more of --optimizer, --token, or --base-url")
+}
+
+func (s *ProfileTestSuite) TestRunningProfileUpdateInvalidOptimizer() {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+			},
+		},
+	})
+	_, err := s.Execute("--config", configFile.Name(), "profile", "update", "default", "--optimizer", "other-app")
+	s.Require().EqualError(err, `invalid optimizer "other-app": missing the organization domain (e.g. example.com/other-app)`)
+}
+
+// registry loads the profile registry from the config file
+func (s *ProfileTestSuite) registry(configFile string) *command.ProfileRegistry {
+	v := viper.New()
+	v.SetConfigFile(configFile)
+	s.Require().NoError(v.ReadInConfig())
+	registry, err := command.NewProfileRegistry(v)
+	s.Require().NoError(err)
+	return registry
+}
+
+// optimizerServer returns a test API server responding to optimizer state requests with the status code
+func (s *ProfileTestSuite) optimizerServer(statusCode int) (*httptest.Server, *[]string) {
+	paths := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		w.Write([]byte(`{}`))
+	}))
+	s.T().Cleanup(server.Close)
+	return server, &paths
+}
+
+func (s *ProfileTestSuite) TestRunningAddVerifiesNormalizedOptimizer() {
+	server, paths := s.optimizerServer(http.StatusOK)
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{})
+	args := test.Args("--config", configFile.Name(), "--base-url", server.URL, "profile", "add", "staging",
+		"--optimizer", "https://Example.com/app/", "--token", "123456")
+	_, err := s.ExecuteTestInteractively(args, func(t *test.InteractiveTestContext) error {
+		t.RequireString("Verified optimizer example.com/app")
+		t.RequireString("Attach servo to new profile?")
+		t.SendLine("N")
+		t.ExpectEOF()
+		return nil
+	})
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"/accounts/example.com/applications/app/state", "/accounts/example.com/applications/app/token"}, *paths)
+
+	registry := s.registry(configFile.Name())
+	s.Require().Equal("example.com/app", registry.ProfileNamed("staging").Optimizer)
+}
+
+func (s *ProfileTestSuite) TestRunningAddUnknownOptimizerDeclined() {
+	server, _ := s.optimizerServer(http.StatusNotFound)
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{})
+	args := test.Args("--config", configFile.Name(), "--base-url", server.URL, "profile", "add", "staging",
+		"--optimizer", "example.com/ap", "--token", "123456")
+	_, err := s.ExecuteTestInteractively(args, func(t *test.InteractiveTestContext) error {
+		t.RequireString(`optimizer "example.com/ap" was not found`)
+		t.RequireString("Save the profile anyway?")
+		t.SendLine("N")
+		t.ExpectEOF()
+		return nil
+	})
+	s.Require().EqualError(err, `optimizer "example.com/ap" was not found`)
+
+	registry := s.registry(configFile.Name())
+	s.Require().Nil(registry.ProfileNamed("staging"))
+}
+
+func (s *ProfileTestSuite) TestRunningAddUnauthorizedOptimizerAccepted() {
+	server, _ := s.optimizerServer(http.StatusUnauthorized)
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{})
+	args := test.Args("--config", configFile.Name(), "--base-url", server.URL, "profile", "add", "staging",
+		"--optimizer", "example.com/app", "--token", "123456")
+	_, err := s.ExecuteTestInteractively(args, func(t *test.InteractiveTestContext) error {
+		t.RequireString(`the API token is not authorized to access optimizer "example.com/app"`)
+		t.RequireString("Save the profile anyway?")
+		t.SendLine("Y")
+		t.RequireString("Attach servo to new profile?")
+		t.SendLine("N")
+		t.ExpectEOF()
+		return nil
+	})
+	s.Require().NoError(err)
+
+	registry := s.registry(configFile.Name())
+	s.Require().NotNil(registry.ProfileNamed("staging"))
+}
+
+func (s *ProfileTestSuite) TestRunningProfileRename() {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+			},
+			{
+				"name":      "staging",
+				"optimizer": "example.com/staging",
+				"token":     "123456",
+			},
+		},
+	})
+	_, err := s.Execute("--config", configFile.Name(), "profile", "rename", "staging", "qa")
+	s.Require().NoError(err)
+
+	var config = map[string][]command.Profile{}
+	body, _ := ioutil.ReadFile(configFile.Name())
+	yaml.Unmarshal(body, &config)
+	s.Require().Equal("qa", config["profiles"][1].Name)
+	s.Require().Equal("example.com/staging", config["profiles"][1].Optimizer)
+}
+
+func (s *ProfileTestSuite) TestRunningProfileRenameExisting() {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+			},
+			{
+				"name":      "staging",
+				"optimizer": "example.com/staging",
+				"token":     "123456",
+			},
+		},
+	})
+	_, err := s.Execute("--config", configFile.Name(), "profile", "rename", "staging", "default")
+	s.Require().EqualError(err, `profile "default" already exists`)
+}
+
+func (s *ProfileTestSuite) TestProfileApplyDefaults() {
+	profile := command.Profile{
+		Name:  "team-a",
+		Servo: command.Servo{Type: "kubernetes", Deployment: "servo"},
+	}
+	profile.ApplyDefaults(command.ProfileDefaults{
+		BaseURL:    "https://opsani.example.com/",
+		Bastion:    "user@bastion.example.com",
+		Kubeconfig: "/etc/kube/config",
+		Namespace:  "optimization",
+	})
+	s.Require().Equal("https://opsani.example.com/", profile.BaseURL)
+	s.Require().Equal("optimization", profile.Servo.Namespace)
+	s.Require().Equal("/etc/kube/config", profile.Servo.Kubeconfig)
+	s.Require().Empty(profile.Servo.Bastion)
+}
+
+func (s *ProfileTestSuite) TestProfileApplyDefaultsDoesNotOverride() {
+	profile := command.Profile{
+		Name:    "team-b",
+		BaseURL: "https://api.opsani.com/",
+		Servo:   command.Servo{Type: "docker-compose", Bastion: "me@jump.example.com"},
+	}
+	profile.ApplyDefaults(command.ProfileDefaults{
+		BaseURL: "https://opsani.example.com/",
+		Bastion: "user@bastion.example.com",
+	})
+	s.Require().Equal("https://api.opsani.com/", profile.BaseURL)
+	s.Require().Equal("me@jump.example.com", profile.Servo.Bastion)
+}
+
+func (s *ProfileTestSuite) TestRegistryLoadsDefaults() {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"defaults": map[string]string{
+			"base_url":  "https://opsani.example.com/",
+			"namespace": "optimization",
+		},
+		"profiles": []map[string]string{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+			},
+		},
+	})
+	v := viper.New()
+	v.SetConfigFile(configFile.Name())
+	s.Require().NoError(v.ReadInConfig())
+	registry, err := command.NewProfileRegistry(v)
+	s.Require().NoError(err)
+	s.Require().Equal("https://opsani.example.com/", registry.Defaults().BaseURL)
+	s.Require().Equal("optimization", registry.Defaults().Namespace)
+}
+
+func (s *ProfileTestSuite) TestRunningProfileTest() {
+	server, paths := s.optimizerServer(http.StatusOK)
+	driver := test.NewFakeServoDriver()
+	command.SetServoDriverFactory(driver.Factory())
+	s.T().Cleanup(func() { command.SetServoDriverFactory(nil) })
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]interface{}{
+			{"name": "default", "optimizer": "example.com/app", "token": "123456"},
+			{
+				"name": "staging", "optimizer": "example.com/staging", "token": "123456", "base_url": server.URL,
+				"servo": map[string]string{"type": "kubernetes", "namespace": "opsani", "deployment": "servo"},
+			},
+		},
+	})
+
+	output, err := s.Execute("--config", configFile.Name(), "profile", "test", "staging", "-o", "json")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"/accounts/example.com/applications/staging/state"}, *paths)
+	s.Require().Equal([]string{"Ping"}, driver.Calls())
+	s.Require().Equal("opsani", driver.Servo.Namespace)
+	s.Require().Equal(30*time.Second, driver.Timeout)
+
+	var checks []map[string]string
+	s.Require().NoError(json.Unmarshal([]byte(output), &checks))
+	s.Require().Len(checks, 2)
+	s.Require().Equal("API", checks[0]["check"])
+	s.Require().Equal("ok", checks[0]["status"])
+	s.Require().Equal("Servo", checks[1]["check"])
+	s.Require().Equal("ok", checks[1]["status"])
+	s.Require().NotEmpty(checks[1]["latency"])
+}
+
+func (s *ProfileTestSuite) TestRunningProfileTestFailure() {
+	server, _ := s.optimizerServer(http.StatusUnauthorized)
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]interface{}{
+			{"name": "default", "optimizer": "example.com/app", "token": "123456"},
+		},
+	})
+
+	output, err := s.Execute("--config", configFile.Name(), "--base-url", server.URL, "profile", "test")
+	s.Require().EqualError(err, `1 of 2 connectivity checks failed for profile "default"`)
+	s.Require().Regexp(`API\s+✗ failed\s+\S+\s+the API token is not authorized to access optimizer "example.com/app"`, output)
+	s.Require().Regexp(`Servo\s+ℹ skipped\s+-\s+no servo attached`, output)
 }
@@ -0,0 +1,123 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// KeyProgressFormat is the flag for selecting how task progress is reported
+const KeyProgressFormat = "progress-format"
+
+// Progress formats
+const (
+	ProgressFormatHuman = "human"
+	ProgressFormatJSON  = "json"
+)
+
+// Progress event types
+const (
+	ProgressEventStarted   = "started"
+	ProgressEventProgress  = "progress"
+	ProgressEventSucceeded = "succeeded"
+	ProgressEventFailed    = "failed"
+)
+
+// ProgressEvent is a machine-readable record of a task changing state
+// Events are written to stderr as newline-delimited JSON when --progress-format=json
+type ProgressEvent struct {
+	Event      string    `json:"event"`
+	Task       string    `json:"task"`
+	Time       time.Time `json:"time"`
+	DurationMS int64     `json:"duration_ms,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	LogFile    string    `json:"log_file,omitempty"`
+}
+
+// ProgressFormat returns the format that task progress is reported in
+func (baseCmd *BaseCommand) ProgressFormat() string {
+	if baseCmd.progressFormat == "" {
+		return ProgressFormatHuman
+	}
+	return baseCmd.progressFormat
+}
+
+// validateProgressFormat checks the value of the --progress-format flag
+func (baseCmd *BaseCommand) validateProgressFormat() error {
+	switch baseCmd.ProgressFormat() {
+	case ProgressFormatHuman, ProgressFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid progress format %q: must be %q or %q", baseCmd.progressFormat, ProgressFormatHuman, ProgressFormatJSON)
+	}
+}
+
+// taskProgress reports the progress of a single task
+type taskProgress struct {
+	baseCmd *BaseCommand
+	task    string
+	started time.Time
+}
+
+// startTaskProgress emits a started event for the task described
+func (baseCmd *BaseCommand) startTaskProgress(description string) *taskProgress {
+	p := &taskProgress{baseCmd: baseCmd, task: plainText(description), started: time.Now()}
+	p.emit(ProgressEvent{Event: ProgressEventStarted, Time: p.started})
+	return p
+}
+
+// update emits a progress event describing the current state of the task
+func (p *taskProgress) update(status string) {
+	p.emit(ProgressEvent{Event: ProgressEventProgress, Time: time.Now(), Message: plainText(status)})
+}
+
+// finish emits a succeeded or failed event for the task
+func (p *taskProgress) finish(message string, err error, logFile string) {
+	now := time.Now()
+	event := ProgressEvent{
+		Event:      ProgressEventSucceeded,
+		Time:       now,
+		DurationMS: now.Sub(p.started).Milliseconds(),
+		Message:    plainText(message),
+	}
+	if err != nil {
+		event.Event = ProgressEventFailed
+		event.Error = err.Error()
+		event.LogFile = logFile
+	}
+	p.emit(event)
+}
+
+func (p *taskProgress) emit(event ProgressEvent) {
+	if p.baseCmd.ProgressFormat() != ProgressFormatJSON {
+		return
+	}
+	event.Task = p.task
+	event.Time = event.Time.UTC()
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(p.baseCmd.ErrOrStderr(), string(data))
+}
+
+// plainText strips terminal escape sequences and surrounding whitespace from a message
+func plainText(message string) string {
+	return strings.TrimSpace(ansiEscapePattern.ReplaceAllString(message, ""))
+}
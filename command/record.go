@@ -0,0 +1,160 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/spf13/cobra"
+	sshterminal "golang.org/x/crypto/ssh/terminal"
+)
+
+// KeyRecord is the flag for recording an interactive session
+const KeyRecord = "record"
+
+// SessionRecorder records terminal output as an asciinema v2 cast
+// See https://github.com/asciinema/asciinema/blob/develop/doc/asciicast-v2.md
+type SessionRecorder struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+}
+
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// NewSessionRecorder writes the cast header to w and returns a recorder for the session
+func NewSessionRecorder(w io.Writer, width, height int, start time.Time) (*SessionRecorder, error) {
+	header := castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: start.Unix(),
+		Env: map[string]string{
+			"SHELL": os.Getenv("SHELL"),
+			"TERM":  os.Getenv("TERM"),
+		},
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+		return nil, err
+	}
+	return &SessionRecorder{w: w, start: start}, nil
+}
+
+// RecordOutput appends an output event for data written to the terminal at the given time
+func (r *SessionRecorder) RecordOutput(at time.Time, data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event, err := json.Marshal([]interface{}{at.Sub(r.start).Seconds(), "o", string(data)})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(r.w, "%s\n", event)
+	return err
+}
+
+// recordingWriter tees terminal output into a session recording
+// The file descriptor is preserved so that Survey can continue to configure the terminal
+type recordingWriter struct {
+	io.Writer
+	fd       uintptr
+	recorder *SessionRecorder
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	if n > 0 {
+		w.recorder.RecordOutput(time.Now(), p[:n])
+	}
+	return n, err
+}
+
+func (w *recordingWriter) Fd() uintptr {
+	return w.fd
+}
+
+// recordingStdio returns stdio with output teed into the active session recording
+func (cmd *BaseCommand) recordingStdio(stdio terminal.Stdio) terminal.Stdio {
+	if cmd.recorder == nil {
+		return stdio
+	}
+	stdio.Out = &recordingWriter{Writer: stdio.Out, fd: stdio.Out.Fd(), recorder: cmd.recorder}
+	return stdio
+}
+
+// recordingOutput returns w with output teed into the active session recording
+func (cmd *BaseCommand) recordingOutput(w io.Writer) io.Writer {
+	if cmd.recorder == nil {
+		return w
+	}
+	return &recordingWriter{Writer: w, fd: os.Stdout.Fd(), recorder: cmd.recorder}
+}
+
+// AddRecordFlag adds the flag for recording the session of an interactive command
+func AddRecordFlag(cobraCmd *cobra.Command) {
+	cobraCmd.PersistentFlags().String(KeyRecord, "", "Record the terminal session to an asciinema cast file (e.g. session.cast)")
+	cobraCmd.MarkPersistentFlagFilename(KeyRecord, "*.cast")
+}
+
+// StartRecordingRunE begins recording the session when the record flag is set
+func (cmd *BaseCommand) StartRecordingRunE(c *cobra.Command, args []string) error {
+	filename, _ := c.Flags().GetString(KeyRecord)
+	if filename == "" {
+		return nil
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	width, height, err := sshterminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width, height = 80, 24
+	}
+	recorder, err := NewSessionRecorder(file, width, height, time.Now())
+	if err != nil {
+		file.Close()
+		return err
+	}
+	cmd.recorder = recorder
+	cmd.recordingFile = file
+	return nil
+}
+
+// StopRecordingRunE finishes the session recording
+// Events are written as they occur so recordings of failed sessions remain usable
+func (cmd *BaseCommand) StopRecordingRunE(c *cobra.Command, args []string) error {
+	if cmd.recordingFile == nil {
+		return nil
+	}
+	err := cmd.recordingFile.Close()
+	cmd.recorder = nil
+	cmd.recordingFile = nil
+	return err
+}
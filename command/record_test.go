@@ -0,0 +1,48 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionRecorderWritesAsciicast(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	start := time.Unix(1590000000, 0)
+	recorder, err := command.NewSessionRecorder(buffer, 120, 40, start)
+	require.NoError(t, err)
+	require.NoError(t, recorder.RecordOutput(start.Add(1500*time.Millisecond), []byte("\x1b[1mhello\x1b[0m\r\n")))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var header map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &header))
+	require.EqualValues(t, 2, header["version"])
+	require.EqualValues(t, 120, header["width"])
+	require.EqualValues(t, 40, header["height"])
+	require.EqualValues(t, 1590000000, header["timestamp"])
+
+	var event []interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	require.Equal(t, []interface{}{1.5, "o", "\x1b[1mhello\x1b[0m\r\n"}, event)
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// KeyShowSecrets is the flag that disables redaction of secrets in output
+const KeyShowSecrets = "show-secrets"
+
+const redactionMask = "********"
+
+// minRedactedSecretLength is the shortest secret replaced wherever it occurs in output
+// Shorter values are too likely to collide with unrelated numbers and identifiers
+const minRedactedSecretLength = 16
+
+// RedactSecret masks a secret value for display, revealing only the last four
+// characters of sufficiently long values to aid in identification
+func RedactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 12 {
+		return redactionMask
+	}
+	return redactionMask + secret[len(secret)-4:]
+}
+
+// RedactSecretsInBytes replaces all occurrences of the given secrets within a byte array
+// Secrets shorter than 16 characters or containing whitespace are left in place
+func RedactSecretsInBytes(data []byte, secrets ...string) []byte {
+	for _, secret := range secrets {
+		if len(secret) >= minRedactedSecretLength && !strings.ContainsAny(secret, " \t\r\n") {
+			data = bytes.ReplaceAll(data, []byte(secret), []byte(RedactSecret(secret)))
+		}
+	}
+	return data
+}
+
+var secretKeyValuePattern = regexp.MustCompile(`(?im)^(\s*-?\s*["']?[\w-]*(?:token|password|secret|api_key)[\w-]*["']?\s*[:=]\s*).+$`)
+
+// RedactSecretKeyValuesInBytes masks the values of secret-like keys (tokens, passwords, API keys)
+// in line oriented formats such as YAML, INI, or environment files
+func RedactSecretKeyValuesInBytes(data []byte) []byte {
+	return secretKeyValuePattern.ReplaceAll(data, []byte("${1}"+redactionMask))
+}
+
+var secretKeyPattern = regexp.MustCompile(`(?i)token|password|secret|api_key`)
+
+// redactSecretKeys masks the string values of secret-like keys within decoded YAML or JSON
+func redactSecretKeys(obj interface{}, redact func(string) string) interface{} {
+	switch value := obj.(type) {
+	case map[string]interface{}:
+		redacted := map[string]interface{}{}
+		for k, v := range value {
+			if secret, ok := v.(string); ok && secretKeyPattern.MatchString(k) {
+				redacted[k] = redact(secret)
+			} else {
+				redacted[k] = redactSecretKeys(v, redact)
+			}
+		}
+		return redacted
+	case map[interface{}]interface{}:
+		redacted := map[interface{}]interface{}{}
+		for k, v := range value {
+			if secret, ok := v.(string); ok && secretKeyPattern.MatchString(fmt.Sprint(k)) {
+				redacted[k] = redact(secret)
+			} else {
+				redacted[k] = redactSecretKeys(v, redact)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, v := range value {
+			redacted[i] = redactSecretKeys(v, redact)
+		}
+		return redacted
+	}
+	return obj
+}
+
+// ShowSecrets indicates if secrets will be displayed verbatim in output
+func (baseCmd *BaseCommand) ShowSecrets() bool {
+	return baseCmd.showSecrets
+}
+
+// Redact returns the secret masked for display unless secrets are being shown
+func (baseCmd *BaseCommand) Redact(secret string) string {
+	if baseCmd.showSecrets {
+		return secret
+	}
+	return RedactSecret(secret)
+}
+
+// RedactBytes masks all known secrets within the byte array unless secrets are being shown
+func (baseCmd *BaseCommand) RedactBytes(data []byte) []byte {
+	if baseCmd.showSecrets {
+		return data
+	}
+	return RedactSecretsInBytes(data, baseCmd.knownSecrets()...)
+}
+
+// RedactSecretKeys masks the values of secret-like keys within a decoded object unless secrets are being shown
+func (baseCmd *BaseCommand) RedactSecretKeys(obj interface{}) interface{} {
+	return redactSecretKeys(obj, baseCmd.Redact)
+}
+
+// RedactString masks all known secrets within the string unless secrets are being shown
+func (baseCmd *BaseCommand) RedactString(str string) string {
+	return string(baseCmd.RedactBytes([]byte(str)))
+}
+
+// knownSecrets returns all tokens in effect from flags, the environment, and the configured profiles
+func (baseCmd *BaseCommand) knownSecrets() []string {
+	secrets := []string{}
+	if token := strings.TrimSpace(baseCmd.AccessToken()); token != "" {
+		secrets = append(secrets, token)
+	}
+	if baseCmd.viperCfg != nil {
+		if registry, err := NewProfileRegistry(baseCmd.viperCfg); err == nil {
+			for _, profile := range registry.Profiles() {
+				if profile.Token != "" {
+					secrets = append(secrets, profile.Token)
+				}
+			}
+		}
+	}
+	return secrets
+}
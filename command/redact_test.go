@@ -0,0 +1,49 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactSecretShortValue(t *testing.T) {
+	require.Equal(t, "********", command.RedactSecret("123456"))
+}
+
+func TestRedactSecretLongValue(t *testing.T) {
+	require.Equal(t, "********wxyz", command.RedactSecret("abcdefghijklmnopqrstuvwxyz"))
+}
+
+func TestRedactSecretEmpty(t *testing.T) {
+	require.Equal(t, "", command.RedactSecret(""))
+}
+
+func TestRedactSecretsInBytes(t *testing.T) {
+	output := command.RedactSecretsInBytes([]byte(`{"token": "abcdefghijklmnopqrstuvwxyz", "other": "abc"}`), "abcdefghijklmnopqrstuvwxyz")
+	require.Equal(t, `{"token": "********wxyz", "other": "abc"}`, string(output))
+}
+
+func TestRedactSecretsInBytesSkipsShortSecrets(t *testing.T) {
+	output := command.RedactSecretsInBytes([]byte(`{"id": 1234567, "duration": 123456}`), "123456")
+	require.Equal(t, `{"id": 1234567, "duration": 123456}`, string(output))
+}
+
+func TestRedactSecretKeyValuesInBytes(t *testing.T) {
+	output := command.RedactSecretKeyValuesInBytes([]byte("datadog:\n  api_key: abc123\n  site: us\n"))
+	require.Equal(t, "datadog:\n  api_key: ********\n  site: us\n", string(output))
+}
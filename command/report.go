@@ -0,0 +1,173 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opsani/cli/opsani"
+	"github.com/spf13/cobra"
+)
+
+// AppSavings is the savings report row of an optimizer
+type AppSavings struct {
+	Profile   string `json:"profile,omitempty"`
+	Optimizer string `json:"optimizer"`
+	opsani.Savings
+}
+
+// savingsOutputFormats are the output formats of savings reports
+var savingsOutputFormats = []string{OutputTable, OutputJSON, OutputCSV, OutputMarkdown}
+
+// ParseRelativeDuration parses a duration that may be expressed in days (e.g. 30d) or weeks (e.g. 2w)
+// in addition to the units supported by time.ParseDuration
+func ParseRelativeDuration(value string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(value, suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(value, suffix), 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
+
+// NewReportCommand returns a new `opsani report` command instance
+func NewReportCommand(baseCmd *BaseCommand) *cobra.Command {
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Report on optimization results",
+		Args:  cobra.NoArgs,
+
+		// All commands require an initialized client
+		PersistentPreRunE: baseCmd.InitConfigRunE,
+	}
+	reportCmd.AddCommand(NewReportSavingsCommand(baseCmd))
+	return reportCmd
+}
+
+// NewReportSavingsCommand returns a new `opsani report savings` command instance
+func NewReportSavingsCommand(baseCmd *BaseCommand) *cobra.Command {
+	savingsCmd := &cobra.Command{
+		Use:   "savings",
+		Short: "Report realized and projected savings",
+		Long: `Reports the cost savings realized by optimization over a period of time and the
+projected annual savings of the current optimized configuration.
+
+Savings are reported for the optimizer of the active profile or, with --profiles
+or --all, for the optimizer of each selected profile.`,
+		Example: `  opsani report savings --since 30d
+  opsani report savings --all --output csv > savings.csv`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := OutputFormat(cmd, savingsOutputFormats...)
+			if err != nil {
+				return err
+			}
+			since, _ := cmd.Flags().GetString("since")
+			duration, err := ParseRelativeDuration(since)
+			if err != nil {
+				return err
+			}
+			timeRange := opsani.TimeRange{Start: time.Now().Add(-duration)}
+
+			profiles, err := baseCmd.SelectedProfiles(cmd)
+			if err != nil {
+				return err
+			}
+			reports := []AppSavings{}
+			if profiles == nil {
+				savings, err := baseCmd.NewAPIClient().GetSavings(timeRange)
+				if err != nil {
+					return err
+				}
+				report := AppSavings{Optimizer: baseCmd.Optimizer(), Savings: *savings}
+				if baseCmd.profile != nil {
+					report.Profile = baseCmd.profile.Name
+				}
+				reports = append(reports, report)
+			}
+			for _, profile := range profiles {
+				savings, err := baseCmd.NewAPIClientForProfile(profile).GetSavings(timeRange)
+				if err != nil {
+					return fmt.Errorf("failed retrieving savings of optimizer %s: %w", profile.Optimizer, err)
+				}
+				reports = append(reports, AppSavings{Profile: profile.Name, Optimizer: profile.Optimizer, Savings: *savings})
+			}
+
+			if output == OutputJSON {
+				return baseCmd.PrettyPrintJSONObject(reports)
+			}
+
+			// Exported formats carry plain numbers for spreadsheets while the table is formatted for reading
+			table := Table{}
+			if output == OutputTable {
+				table.Headers = []string{"PROFILE", "OPTIMIZER", "BASELINE", "OPTIMIZED", "REALIZED", "PROJECTED (ANNUAL)"}
+				for _, report := range reports {
+					table.Rows = append(table.Rows, []string{
+						report.Profile,
+						report.Optimizer,
+						formatCurrency(report.BaselineCost, report.Currency),
+						formatCurrency(report.OptimizedCost, report.Currency),
+						fmt.Sprintf("%s (%.1f%%)", formatCurrency(report.RealizedSavings, report.Currency), report.RealizedPercent()),
+						formatCurrency(report.ProjectedSavings, report.Currency),
+					})
+				}
+			} else {
+				table.Headers = []string{"profile", "optimizer", "currency", "baseline_cost", "optimized_cost", "realized_savings", "realized_percent", "projected_savings"}
+				for _, report := range reports {
+					table.Rows = append(table.Rows, []string{
+						report.Profile,
+						report.Optimizer,
+						report.Currency,
+						formatMoney(report.BaselineCost),
+						formatMoney(report.OptimizedCost),
+						formatMoney(report.RealizedSavings),
+						formatMoney(report.RealizedPercent()),
+						formatMoney(report.ProjectedSavings),
+					})
+				}
+			}
+			return baseCmd.RenderTable(output, table)
+		},
+	}
+	savingsCmd.Flags().String("since", "30d", "Report savings realized over a relative duration (e.g. 30d, 2w, 12h)")
+	savingsCmd.Flags().StringSlice(KeyProfiles, nil, "Report on the optimizers of the named profiles (e.g. team-a,team-b)")
+	savingsCmd.Flags().Bool(KeyAllProfiles, false, "Report on the optimizers of all profiles")
+	AddOutputFlag(savingsCmd, savingsOutputFormats...)
+	return savingsCmd
+}
+
+// formatMoney formats an amount with two decimal places
+func formatMoney(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// formatCurrency formats an amount with two decimal places followed by the currency code
+func formatCurrency(amount float64, currency string) string {
+	if currency == "" {
+		return formatMoney(amount)
+	}
+	return formatMoney(amount) + " " + currency
+}
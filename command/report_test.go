@@ -0,0 +1,141 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestParseRelativeDuration(t *testing.T) {
+	for value, expected := range map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+	} {
+		d, err := command.ParseRelativeDuration(value)
+		require.NoError(t, err, value)
+		require.Equal(t, expected, d, value)
+	}
+	_, err := command.ParseRelativeDuration("last month")
+	require.EqualError(t, err, `invalid duration "last month"`)
+}
+
+type ReportTestSuite struct {
+	test.Suite
+}
+
+func TestReportTestSuite(t *testing.T) {
+	suite.Run(t, new(ReportTestSuite))
+}
+
+func (s *ReportTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+}
+
+// savingsServer returns a test server responding with savings proportional to the app name length
+// and recording the start time queried for each app
+func (s *ReportTestSuite) savingsServer() (*httptest.Server, func() map[string]string) {
+	var mu sync.Mutex
+	starts := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app := strings.Split(r.URL.Path, "/")[4]
+		mu.Lock()
+		starts[app] = r.URL.Query().Get("start")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"currency":          "USD",
+			"baseline_cost":     1000 * len(app),
+			"optimized_cost":    800 * len(app),
+			"realized_savings":  200 * len(app),
+			"projected_savings": 2400 * len(app),
+		})
+	}))
+	s.T().Cleanup(server.Close)
+	return server, func() map[string]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return starts
+	}
+}
+
+func (s *ReportTestSuite) TestReportSavings() {
+	server, starts := s.savingsServer()
+	s.SetEnv("OPSANI_BASE_URL", server.URL)
+	s.SetEnv("OPSANI_OPTIMIZER", "example.com/app")
+	s.SetEnv("OPSANI_TOKEN", "123456")
+
+	before := time.Now()
+	output, err := s.Execute("report", "savings", "--since", "30d")
+	s.Require().NoError(err)
+	s.Require().Regexp(`example.com/app\s+3000.00 USD\s+2400.00 USD\s+600.00 USD \(20.0%\)\s+7200.00 USD`, output)
+	start, err := time.Parse(time.RFC3339, starts()["app"])
+	s.Require().NoError(err)
+	s.Require().WithinDuration(before.Add(-30*24*time.Hour), start, time.Minute)
+}
+
+func (s *ReportTestSuite) TestReportSavingsAllProfilesCSV() {
+	server, _ := s.savingsServer()
+	profiles := []map[string]string{}
+	for _, name := range []string{"team-a", "web"} {
+		profiles = append(profiles, map[string]string{
+			"name":      name,
+			"optimizer": "example.com/" + name,
+			"token":     name + "-token",
+			"base_url":  server.URL,
+		})
+	}
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{"profiles": profiles})
+
+	output, err := s.Execute("--config", configFile.Name(), "report", "savings", "--all", "-o", "csv")
+	s.Require().NoError(err)
+	s.Require().Equal(`profile,optimizer,currency,baseline_cost,optimized_cost,realized_savings,realized_percent,projected_savings
+team-a,example.com/team-a,USD,6000.00,4800.00,1200.00,20.00,14400.00
+web,example.com/web,USD,3000.00,2400.00,600.00,20.00,7200.00
+`, output)
+}
+
+func (s *ReportTestSuite) TestReportSavingsJSON() {
+	server, _ := s.savingsServer()
+	s.SetEnv("OPSANI_BASE_URL", server.URL)
+	s.SetEnv("OPSANI_OPTIMIZER", "example.com/app")
+	s.SetEnv("OPSANI_TOKEN", "123456")
+
+	output, err := s.Execute("report", "savings", "-o", "json")
+	s.Require().NoError(err)
+	s.Require().Contains(output, `"optimizer": "example.com/app"`)
+	s.Require().Contains(output, `"projected_savings": 7200`)
+}
+
+func (s *ReportTestSuite) TestReportSavingsInvalidFlags() {
+	s.SetEnv("OPSANI_OPTIMIZER", "example.com/app")
+	s.SetEnv("OPSANI_TOKEN", "123456")
+	_, err := s.Execute("report", "savings", "-o", "xml")
+	s.Require().EqualError(err, `invalid output format "xml": must be one of table, json, csv, markdown`)
+	s.SetCommand(command.NewRootCommand())
+	_, err = s.Execute("report", "savings", "--since", "forever")
+	s.Require().EqualError(err, `invalid duration "forever"`)
+}
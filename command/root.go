@@ -28,10 +28,8 @@ import (
 	"text/template"
 	"time"
 
-	"github.com/AlecAivazis/survey/v2/core"
 	"github.com/AlecAivazis/survey/v2/terminal"
 	"github.com/briandowns/spinner"
-	"github.com/docker/docker/pkg/term"
 	"github.com/fatih/color"
 	"github.com/mitchellh/go-homedir"
 	"github.com/opsani/cli/opsani"
@@ -52,6 +50,8 @@ const (
 	KeyEnvPrefix      = "OPSANI"
 
 	DefaultBaseURL = "https://api.opsani.com/"
+
+	defaultConsoleURL = "https://console.opsani.com"
 )
 
 var (
@@ -113,25 +113,38 @@ We'd love to hear your feedback at <https://github.com/opsani/cli>`,
 	cobraCmd.SetVersionTemplate(versionOutput)
 
 	// Bind our global configuration parameters
-	cobraCmd.PersistentFlags().String(KeyBaseURL, "", "Base URL for accessing the Opsani API")
-	cobraCmd.PersistentFlags().MarkHidden(KeyBaseURL)
+	cobraCmd.PersistentFlags().String(KeyBaseURL, "", "Base URL of the Opsani API for single-tenant deployments (overrides config file and OPSANI_BASE_URL)")
 	cobraCmd.PersistentFlags().String(KeyOptimizer, "", "Optimizer to manage (overrides config file and OPSANI_OPTIMIZER)")
 	cobraCmd.PersistentFlags().String(KeyToken, "", "Token for API authentication (overrides config file and OPSANI_TOKEN)")
+	addDeprecatedAppFlag(cobraCmd)
+	cobraCmd.PersistentFlags().Duration(KeyTimeout, 0, "Maximum duration of API requests and external commands (overrides config file and OPSANI_TIMEOUT)")
 
 	// Not stored in Viper
 	cobraCmd.PersistentFlags().BoolVarP(&rootCmd.debugModeEnabled, KeyDebugMode, "D", false, "Enable debug mode")
 	cobraCmd.PersistentFlags().BoolVar(&rootCmd.requestTracingEnabled, KeyRequestTracing, false, "Enable request tracing")
+	cobraCmd.PersistentFlags().BoolVar(&rootCmd.showSecrets, KeyShowSecrets, false, "Display tokens and other secrets without redaction")
+	cobraCmd.PersistentFlags().BoolVarP(&rootCmd.assumeYes, KeyYes, "y", false, "Answer yes to all confirmation prompts")
+	cobraCmd.PersistentFlags().StringVar(&rootCmd.progressFormat, KeyProgressFormat, ProgressFormatHuman, fmt.Sprintf("Format of task progress events written to stderr: {%s|%s}", ProgressFormatHuman, ProgressFormatJSON))
 
 	// Respect NO_COLOR from env to be a good sport
 	// https://no-color.org/
 	_, disableColors := os.LookupEnv("NO_COLOR")
 	cobraCmd.PersistentFlags().BoolVar(&rootCmd.disableColors, "no-colors", disableColors, "Disable colorized output")
+	cobraCmd.PersistentFlags().Int(KeyWidth, 0, "Width of output in columns for wrapping tables and text (default is the terminal width)")
 
 	configFileUsage := fmt.Sprintf("Location of config file (default \"%s\")", rootCmd.DefaultConfigFile())
 	cobraCmd.PersistentFlags().StringVar(&rootCmd.configFile, "config", "", configFileUsage)
 	cobraCmd.MarkPersistentFlagFilename("config", "*.yaml", "*.yml")
 	cobraCmd.PersistentFlags().StringP(KeyProfile, "p", os.Getenv("OPSANI_PROFILE"), "Profile to use (sets optimizer, token, and servo)")
 	cobraCmd.Flags().Bool("version", false, "Display version and exit")
+	// Developer flags for diagnosing slow commands
+	cobraCmd.PersistentFlags().Bool(KeyTiming, false, "Print the duration of config loading, API calls, and external processes to stderr")
+	cobraCmd.PersistentFlags().String(KeyProfileCPU, "", "Write a pprof CPU profile to `FILE`")
+	cobraCmd.PersistentFlags().String(KeyProfileHeap, "", "Write a pprof heap profile to `FILE` on exit")
+	cobraCmd.PersistentFlags().MarkHidden(KeyTiming)
+	cobraCmd.PersistentFlags().MarkHidden(KeyProfileCPU)
+	cobraCmd.PersistentFlags().MarkHidden(KeyProfileHeap)
+
 	cobraCmd.PersistentFlags().Bool("help", false, "Display help and exit")
 	cobraCmd.PersistentFlags().MarkHidden("help")
 	cobraCmd.PersistentFlags().MarkShorthandDeprecated("help", "please use --help")
@@ -145,12 +158,24 @@ We'd love to hear your feedback at <https://github.com/opsani/cli>`,
 	cobraCmd.AddCommand(NewOptimizerCommand(rootCmd))
 	cobraCmd.AddCommand(NewServoCommand(rootCmd))
 	cobraCmd.AddCommand(NewProfileCommand(rootCmd))
+	cobraCmd.AddCommand(NewOrgCommand(rootCmd))
+	cobraCmd.AddCommand(NewReportCommand(rootCmd))
+	cobraCmd.AddCommand(NewDiscoverCommand(rootCmd))
+	cobraCmd.AddCommand(NewCheckCommand(rootCmd))
+	cobraCmd.AddCommand(NewMonitorCommand(rootCmd))
 
 	cobraCmd.AddCommand(NewConsoleCommand(rootCmd))
 	cobraCmd.AddCommand(NewConfigCommand(rootCmd))
+	cobraCmd.AddCommand(NewAliasCommand(rootCmd))
+	cobraCmd.AddCommand(NewHistoryCommand(rootCmd))
 	cobraCmd.AddCommand(NewCompletionCommand(rootCmd))
+	cobraCmd.AddCommand(NewGenerateCommand(rootCmd))
+	cobraCmd.AddCommand(NewYAMLCommand(rootCmd))
 
 	cobraCmd.AddCommand(NewIgniteCommand(rootCmd))
+	cobraCmd.AddCommand(NewLearnCommand(rootCmd))
+	cobraCmd.AddCommand(NewVitalCommand(rootCmd))
+	cobraCmd.AddCommand(NewDocsCommand(rootCmd))
 
 	// Usage and help layout
 	cobra.AddTemplateFunc("hasSubCommands", hasSubCommands)
@@ -178,11 +203,15 @@ We'd love to hear your feedback at <https://github.com/opsani/cli>`,
 		if err == pflag.ErrHelp {
 			return err
 		}
-		return &FlagError{Err: err}
+		return &FlagError{Err: flagSuggestionsError(cmd, err)}
 	})
 
+	// Suggest subcommands for typos at every level of the command tree
+	enableSubCommandSuggestions(cobraCmd)
+
 	// Load configuration before execution of every action
 	cobraCmd.PersistentPreRunE = ReduceRunEFuncs(rootCmd.InitConfigRunE, rootCmd.RequireConfigFileFlagToExistRunE)
+	cobraCmd.PersistentPostRunE = rootCmd.FinishProfilingRunE
 
 	return rootCmd
 }
@@ -221,13 +250,38 @@ func Execute() (cmd *cobra.Command, err error) {
 	rootCmd := NewRootCommand()
 	cobraCmd := rootCmd.rootCobraCommand
 
+	// Expand user-defined aliases before Cobra resolves the command
+	args, err := rootCmd.ExpandAliases(os.Args[1:])
+	if err != nil {
+		cobraCmd.PrintErrf("%s: %s\n", cobraCmd.Name(), err)
+		return cobraCmd, err
+	}
+	cobraCmd.SetArgs(args)
+
+	defer sshConnections.Close()
+	startedAt := time.Now()
 	executedCmd, err := rootCmd.rootCobraCommand.ExecuteC()
+	rootCmd.RecordCommand(executedCmd, os.Args[1:], startedAt, err)
+	// Report timings and profiles of commands that failed or replace the post-run function
+	if profilingErr := rootCmd.finishProfiling(); profilingErr != nil {
+		executedCmd.PrintErrf("%s: %s\n", executedCmd.Name(), profilingErr)
+	}
 	if err != nil {
 		// Exit silently if the user bailed with control-c
 		if errors.Is(err, terminal.InterruptErr) {
 			return executedCmd, err
 		}
 
+		// Render API errors as a readable panel rather than one long line
+		var apiError *opsani.APIError
+		if errors.As(err, &apiError) {
+			executedCmd.PrintErrf("%s:\n%s", executedCmd.Name(), RenderAPIError(apiError, terminalWidth(), rootCmd.debugModeEnabled))
+			if remediation := APIErrorRemediation(err, rootCmd.profile); remediation != "" {
+				executedCmd.PrintErrln(remediation)
+			}
+			return cobraCmd, err
+		}
+
 		executedCmd.PrintErrf("%s: %s\n", executedCmd.Name(), err)
 
 		// Display usage for invalid command and flag errors
@@ -271,7 +325,19 @@ func ReduceRunEFuncs(runFuncs ...RunEFunc) RunEFunc {
 
 // InitConfigRunE initializes client configuration and aborts execution if an error is encountered
 func (baseCmd *BaseCommand) InitConfigRunE(cmd *cobra.Command, args []string) error {
-	return baseCmd.initConfig()
+	if err := baseCmd.startProfiling(); err != nil {
+		return err
+	}
+	if err := baseCmd.validateProgressFormat(); err != nil {
+		return err
+	}
+	finishConfigLoad := timings.Track(TimingPhaseConfig, "config")
+	err := baseCmd.initConfig()
+	finishConfigLoad()
+	if err != nil {
+		return err
+	}
+	return baseCmd.requireScopeRunE(cmd, args)
 }
 
 // RequireConfigFileFlagToExistRunE aborts command execution with an error if the config file specified via a flag does not exist
@@ -315,10 +381,13 @@ func (baseCmd *BaseCommand) initConfig() error {
 
 	// Load the configuration
 	if err := baseCmd.viperCfg.ReadInConfig(); err == nil {
+		registry, _ := NewProfileRegistry(baseCmd.viperCfg)
+		baseCmd.warnDeprecatedSettings(registry)
 		if _, err = baseCmd.LoadProfile(); err != nil {
 			return err
 		}
 	} else {
+		baseCmd.warnDeprecatedSettings(nil)
 		// Ignore config file not found or error
 		var perr *os.PathError
 		if !errors.As(err, &viper.ConfigFileNotFoundError{}) &&
@@ -327,32 +396,36 @@ func (baseCmd *BaseCommand) initConfig() error {
 		}
 	}
 
-	core.DisableColor = baseCmd.disableColors
-
-	return nil
+	// Output capabilities depend on accessible mode, which is set in the config file
+	return baseCmd.detectCapabilities()
 }
 
 func (vitalCommand *vitalCommand) newSpinner() *spinner.Spinner {
-	s := spinner.New(spinner.CharSets[14], 150*time.Millisecond)
+	charSet := spinner.CharSets[14]
+	if vitalCommand.ASCIIOnly() {
+		charSet = spinner.CharSets[9]
+	}
+	s := spinner.New(charSet, 150*time.Millisecond)
 	s.Writer = vitalCommand.OutOrStdout()
-	s.Color("bold", "blue")
+	s.Color(activePalette.Info.spinnerColors()...)
 	s.HideCursor = true
 	return s
 }
 
 func (vitalCommand *vitalCommand) infoMessage(message string) string {
-	c := color.New(color.FgHiBlue, color.Bold).SprintFunc()
-	return fmt.Sprintf("%s  %s\n", c("ℹ"), message)
+	return fmt.Sprintf("%s  %s\n", vitalCommand.ColorGlyph(glyphInfo), message)
 }
 
 func (vitalCommand *vitalCommand) successMessage(message string) string {
-	c := color.New(color.FgGreen, color.Bold).SprintFunc()
-	return fmt.Sprintf("%s  %s\n", c("\u2713"), message)
+	return fmt.Sprintf("%s  %s\n", vitalCommand.ColorGlyph(glyphSuccess), message)
 }
 
 func (vitalCommand *vitalCommand) failureMessage(message string) string {
-	c := color.New(color.Bold, color.FgHiRed).SprintFunc()
-	return fmt.Sprintf("%s  %s\n", c("\u2717"), message)
+	return fmt.Sprintf("%s  %s\n", vitalCommand.ColorGlyph(glyphFailure), message)
+}
+
+func (vitalCommand *vitalCommand) warningMessage(message string) string {
+	return fmt.Sprintf("%s  %s\n", vitalCommand.ColorGlyph(glyphWarning), message)
 }
 
 // Task describes a long-running task that may succeed or fail
@@ -363,16 +436,35 @@ type Task struct {
 	Run         func() error
 	RunW        func(w io.Writer) error
 	RunV        func() (interface{}, error)
+	// RunP reports updates on the state of the task, shown alongside its description
+	RunP func(progress ProgressFunc) error
 }
 
 // RunTaskWithSpinnerStatus displays an animated spinner around the execution of the given func
+// The spinner is replaced by a static description of the task when the output cannot be animated
 func (vitalCommand *vitalCommand) RunTaskWithSpinner(task Task) (err error) {
+	progress := vitalCommand.startTaskProgress(task.Description)
 	s := vitalCommand.newSpinner()
-	s.Suffix = "  " + task.Description
-	s.Start()
+	if !vitalCommand.Capabilities().Animation {
+		fmt.Fprint(s.Writer, vitalCommand.infoMessage(task.Description))
+	} else {
+		s.Suffix = "  " + task.Description
+		s.Start()
+	}
 	var templateVars interface{}
 	if task.RunV != nil {
 		templateVars, err = task.RunV()
+	} else if task.RunP != nil {
+		err = task.RunP(func(status string) {
+			progress.update(status)
+			if !vitalCommand.Capabilities().Animation {
+				fmt.Fprint(s.Writer, vitalCommand.infoMessage(fmt.Sprintf("%s %s", task.Description, status)))
+				return
+			}
+			s.Lock()
+			s.Suffix = fmt.Sprintf("  %s %s", task.Description, color.New(color.Faint).Sprint(status))
+			s.Unlock()
+		})
 	} else if task.RunW != nil {
 		err = task.RunW(s.Writer)
 	} else {
@@ -385,28 +477,49 @@ func (vitalCommand *vitalCommand) RunTaskWithSpinner(task Task) (err error) {
 		successMessage := new(bytes.Buffer)
 		err = tmpl.Execute(successMessage, templateVars)
 		if err != nil {
+			progress.finish("", err, "")
 			return err
 		}
+		progress.finish(successMessage.String(), nil, "")
 		fmt.Fprintf(s.Writer, vitalCommand.successMessage(string(successMessage.Bytes())))
 	} else {
+		progress.finish(task.Failure, err, "")
 		fmt.Fprintf(s.Writer, vitalCommand.failureMessage(fmt.Sprintf("%s: %s", task.Failure, err)))
 	}
 	return err
 }
 
 // RunTask displays runs a task
+// Output written by RunW is streamed beneath the task and recorded to a log file
 func (vitalCommand *vitalCommand) RunTask(task Task) (err error) {
 	w := vitalCommand.OutOrStdout()
+	progress := vitalCommand.startTaskProgress(task.Description)
 	fmt.Fprintf(w, vitalCommand.infoMessage(task.Description))
+	var logFile *os.File
 	if task.RunW != nil {
-		err = task.RunW(w)
+		var stream *OutputStream
+		stream, logFile = vitalCommand.newTaskOutputStream()
+		err = task.RunW(stream)
+		stream.Close(err == nil)
+		if logFile != nil {
+			logFile.Close()
+		}
 	} else {
 		err = task.Run()
 	}
 	if err == nil {
+		progress.finish(task.Success, nil, "")
 		fmt.Fprintf(w, vitalCommand.successMessage(task.Success))
 	} else {
+		logFileName := ""
+		if logFile != nil {
+			logFileName = logFile.Name()
+		}
+		progress.finish(task.Failure, err, logFileName)
 		fmt.Fprintf(w, vitalCommand.failureMessage(task.Failure))
+		if logFile != nil {
+			fmt.Fprintf(w, vitalCommand.infoMessage(vitalCommand.T("task.log", logFile.Name())))
+		}
 	}
 	return err
 }
@@ -417,10 +530,13 @@ func (baseCmd *BaseCommand) NewAPIClient() *opsani.Client {
 		SetBaseURL(baseCmd.BaseURL()).
 		SetApp(baseCmd.Optimizer()).
 		SetAuthToken(baseCmd.AccessToken()).
-		SetDebug(baseCmd.DebugModeEnabled())
+		SetDebug(baseCmd.DebugModeEnabled()).
+		SetRedactSecrets(!baseCmd.ShowSecrets()).
+		SetTimeout(baseCmd.Timeout())
 	if baseCmd.RequestTracingEnabled() {
 		c.EnableTrace()
 	}
+	timeHTTPClient(c.GetRestyClient().GetClient())
 
 	// Set the output directory to pwd by default
 	if dir, err := os.Getwd(); err == nil {
@@ -464,7 +580,7 @@ func (baseCmd *BaseCommand) DefaultConfigType() string {
 
 // GetBaseURL returns the Opsani API base URL
 func (baseCmd *BaseCommand) GetBaseURL() string {
-	return baseCmd.viperCfg.GetString(KeyBaseURL)
+	return baseCmd.BaseURL()
 }
 
 // GetAppComponents returns the organization name and app ID as separate path components
@@ -532,8 +648,7 @@ func hasManagementSubCommands(cmd *cobra.Command) bool {
 func operationSubCommands(cmd *cobra.Command) []*cobra.Command {
 	cmds := []*cobra.Command{}
 	for _, sub := range cmd.Commands() {
-		// if isOtherCommand(sub) {
-		if len(sub.Annotations) > 0 {
+		if isGroupedCommand(sub) {
 			continue
 		}
 		if sub.IsAvailableCommand() && !sub.HasSubCommands() {
@@ -544,11 +659,7 @@ func operationSubCommands(cmd *cobra.Command) []*cobra.Command {
 }
 
 func wrappedFlagUsages(cmd *cobra.Command) string {
-	width := 80
-	if ws, err := term.GetWinsize(0); err == nil {
-		width = int(ws.Width)
-	}
-	return cmd.Flags().FlagUsagesWrapped(width - 1)
+	return cmd.Flags().FlagUsagesWrapped(terminalWidth() - 1)
 }
 
 func managementSubCommands(cmd *cobra.Command) []*cobra.Command {
@@ -557,7 +668,7 @@ func managementSubCommands(cmd *cobra.Command) []*cobra.Command {
 		if isOtherCommand(sub) {
 			continue
 		}
-		if sub.IsAvailableCommand() && sub.HasSubCommands() && len(sub.Annotations) == 0 {
+		if sub.IsAvailableCommand() && sub.HasSubCommands() && !isGroupedCommand(sub) {
 			cmds = append(cmds, sub)
 		}
 	}
@@ -596,6 +707,11 @@ func isEducationalCommand(cmd *cobra.Command) bool {
 	return cmd.Annotations["educational"] == "true"
 }
 
+// isGroupedCommand returns true if the command is listed in a help section of its own
+func isGroupedCommand(cmd *cobra.Command) bool {
+	return isEducationalCommand(cmd) || isOtherCommand(cmd) || isRegistryCommand(cmd)
+}
+
 func isOtherCommand(cmd *cobra.Command) bool {
 	return cmd.Annotations["other"] == "true"
 }
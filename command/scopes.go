@@ -0,0 +1,101 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/opsani/cli/opsani"
+	"github.com/spf13/cobra"
+)
+
+// API token permission scopes
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// annotationScope is the command annotation naming the token scope the command requires
+const annotationScope = "scope"
+
+// RequireScope annotates the command as requiring the token scope
+// Commands fail fast when the scopes recorded for the profile do not include it.
+func RequireScope(cmd *cobra.Command, scope string) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[annotationScope] = scope
+	return cmd
+}
+
+// HasScope returns true if the token of the profile has the scope
+// Profiles recorded before scopes were fetched, or whose scopes could not be fetched, are assumed to have every scope.
+func (p Profile) HasScope(scope string) bool {
+	return len(p.Scopes) == 0 || scope == "" || stringSliceContains(p.Scopes, scope)
+}
+
+// missingScopeError explains that the token of the profile lacks the scope required by the command
+func missingScopeError(cmd *cobra.Command, profile *Profile, scope string) error {
+	return fmt.Errorf("%q requires an API token with the %q scope but the token of profile %q only grants %v: use a token with %q access or run `opsani profile update %s --token TOKEN`",
+		cmd.CommandPath(), scope, profile.Name, profile.Scopes, scope, profile.Name)
+}
+
+// requireScopeRunE fails if the token of the active profile lacks the scope the command requires
+// Commands applied to other profiles with --profiles or --all check the scopes of each profile instead.
+func (baseCmd *BaseCommand) requireScopeRunE(cmd *cobra.Command, args []string) error {
+	scope := cmd.Annotations[annotationScope]
+	if scope == "" || baseCmd.profile == nil {
+		return nil
+	}
+	if profiles, _ := cmd.Flags().GetStringSlice(KeyProfiles); len(profiles) > 0 {
+		return nil
+	}
+	if all, _ := cmd.Flags().GetBool(KeyAllProfiles); all {
+		return nil
+	}
+	if !baseCmd.profile.HasScope(scope) {
+		return missingScopeError(cmd, baseCmd.profile, scope)
+	}
+	return nil
+}
+
+// fetchScopes returns the permission scopes of the token of the profile
+// Scopes are not recorded if they cannot be fetched so that commands are not gated on stale information.
+func (baseCmd *BaseCommand) fetchScopes(profile Profile) []string {
+	baseURL := profile.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	info, err := opsani.NewClient().
+		SetBaseURL(baseURL).
+		SetApp(profile.Optimizer).
+		SetAuthToken(profile.Token).
+		SetDebug(baseCmd.DebugModeEnabled()).
+		SetRedactSecrets(!baseCmd.ShowSecrets()).
+		SetTimeout(baseCmd.Timeout()).
+		GetTokenInfo()
+	if err != nil || len(info.Scopes) == 0 {
+		return nil
+	}
+	return info.Scopes
+}
+
+// recordScopes records the permission scopes of the token in the profile unless verification is skipped
+func (baseCmd *BaseCommand) recordScopes(cmd *cobra.Command, profile *Profile) {
+	if skip, _ := cmd.Flags().GetBool(KeySkipVerify); skip {
+		return
+	}
+	profile.Scopes = baseCmd.fetchScopes(*profile)
+}
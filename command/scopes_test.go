@@ -0,0 +1,123 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+)
+
+type ScopesTestSuite struct {
+	test.Suite
+	paths []string
+}
+
+func TestScopesTestSuite(t *testing.T) {
+	suite.Run(t, new(ScopesTestSuite))
+}
+
+func (s *ScopesTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+	s.paths = nil
+}
+
+func (s *ScopesTestSuite) server() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.paths = append(s.paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"state": "running"}`))
+	}))
+}
+
+func (s *ScopesTestSuite) configFile(baseURL string) string {
+	return test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]interface{}{
+			{"name": "viewer", "optimizer": "example.com/a", "token": "123456", "base_url": baseURL, "scopes": []string{"read"}},
+			{"name": "admin", "optimizer": "example.com/b", "token": "123456", "base_url": baseURL, "scopes": []string{"read", "write"}},
+		},
+	}).Name()
+}
+
+func (s *ScopesTestSuite) TestReadOnlyTokenFailsFast() {
+	server := s.server()
+	defer server.Close()
+
+	_, err := s.Execute("--config", s.configFile(server.URL), "optimizer", "config", "set", `{"cpu": 1}`)
+	s.Require().EqualError(err, `"opsani optimizer config set" requires an API token with the "write" scope but the token of profile "viewer" only grants [read]: use a token with "write" access or run `+"`opsani profile update viewer --token TOKEN`")
+	s.Require().Empty(s.paths)
+}
+
+func (s *ScopesTestSuite) TestReadOnlyTokenCanRead() {
+	server := s.server()
+	defer server.Close()
+
+	_, err := s.Execute("--config", s.configFile(server.URL), "optimizer", "status")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"/accounts/example.com/applications/a/state"}, s.paths)
+}
+
+func (s *ScopesTestSuite) TestWriteTokenCanWrite() {
+	server := s.server()
+	defer server.Close()
+
+	_, err := s.Execute("--config", s.configFile(server.URL), "--profile", "admin", "optimizer", "start")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"/accounts/example.com/applications/b/state"}, s.paths)
+}
+
+func (s *ScopesTestSuite) TestAllProfilesSkipsReadOnlyTokens() {
+	server := s.server()
+	defer server.Close()
+
+	output, err := s.Execute("--config", s.configFile(server.URL), "--yes", "optimizer", "stop", "--all")
+	s.Require().NoError(err)
+	s.Require().Regexp(`viewer\s+example.com/a\s+skipped: token lacks the "write" scope`, output)
+	s.Require().Regexp(`admin\s+example.com/b\s+stopped`, output)
+	s.Require().Equal([]string{"/accounts/example.com/applications/b/state"}, s.paths)
+}
+
+func (s *ScopesTestSuite) TestProfileUpdateTokenClearsScopes() {
+	server := s.server()
+	defer server.Close()
+
+	configFile := s.configFile(server.URL)
+	_, err := s.Execute("--config", configFile, "profile", "update", "viewer", "--token", "abcdef")
+	s.Require().NoError(err)
+	_, err = s.Execute("--config", configFile, "optimizer", "config", "set", `{"cpu": 1}`)
+	s.Require().NoError(err)
+}
+
+func (s *ScopesTestSuite) TestTokenOverrideIgnoresRecordedScopes() {
+	server := s.server()
+	defer server.Close()
+
+	configFile := s.configFile(server.URL)
+	_, err := s.Execute("--config", configFile, "--token", "abcdef", "optimizer", "config", "set", `{"cpu": 1}`)
+	s.Require().NoError(err)
+
+	s.SetEnv("OPSANI_TOKEN", "abcdef")
+	_, err = s.Execute("--config", configFile, "optimizer", "config", "set", `{"cpu": 1}`)
+	s.Require().NoError(err)
+	s.UnsetEnv("OPSANI_TOKEN")
+
+	_, err = s.Execute("--config", configFile, "--token", "123456", "optimizer", "config", "set", `{"cpu": 1}`)
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), `requires an API token with the "write" scope`)
+}
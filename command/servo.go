@@ -18,17 +18,16 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"net"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
 	"github.com/mitchellh/go-homedir"
-	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/ssh"
-	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/crypto/ssh/knownhosts"
 	"golang.org/x/crypto/ssh/terminal"
 )
@@ -46,11 +45,12 @@ func ArgsS(args string) []string {
 // NOTE: Binding vars instead of using flags because the call stack is messy atm
 type servoCommand struct {
 	*BaseCommand
-	force      bool
 	verbose    bool
 	follow     bool
 	timestamps bool
 	lines      string
+	logLevel   string
+	connectors []string
 }
 
 // NewServoCommand returns a new instance of the servo command
@@ -60,7 +60,12 @@ func NewServoCommand(baseCmd *BaseCommand) *cobra.Command {
 	servoCmd := &cobra.Command{
 		Use:   "servo",
 		Short: "Manage servos",
-		Args:  cobra.NoArgs,
+		Long: `Manage servos
+
+Servo operations are bounded by --timeout (or OPSANI_TIMEOUT) with the exception of
+interactive shells and followed logs. Connections to docker-compose servos are given up
+after 30s (or the timeout, if shorter) and interrupting the CLI stops the remote command.`,
+		Args: cobra.NoArgs,
 		PersistentPreRunE: ReduceRunEFuncs(
 			baseCmd.InitConfigRunE,
 			baseCmd.RequireConfigFileFlagToExistRunE,
@@ -78,10 +83,16 @@ func NewServoCommand(baseCmd *BaseCommand) *cobra.Command {
 		RunE:        servoCommand.RunServoList,
 	}
 	listCmd.Flags().BoolVarP(&servoCommand.verbose, "verbose", "v", false, "Display verbose output")
+	AddOutputFlag(listCmd, TabularOutputFormats...)
 	servoCmd.AddCommand(listCmd)
 	attachCmd := &cobra.Command{
-		Use:                   "attach [OPTIONS]",
-		Long:                  "Attach servo to the active profile",
+		Use: "attach [OPTIONS]",
+		Long: `Attach servo to the active profile
+
+With --from-context the servo is inferred from a kubeconfig context without prompting:
+the namespace defaults to that of the context and the servo deployment is detected by
+the label selector.`,
+		Example:               "  opsani servo attach --from-context my-cluster",
 		Annotations:           map[string]string{"registry": "true"},
 		Short:                 "Attach servo to active profile",
 		Args:                  cobra.NoArgs,
@@ -90,6 +101,9 @@ func NewServoCommand(baseCmd *BaseCommand) *cobra.Command {
 	}
 	attachCmd.Flags().BoolP("bastion", "b", false, "Use a bastion host for access")
 	attachCmd.Flags().String("bastion-host", "", "Specify the bastion host (format is user@host[:port])")
+	attachCmd.Flags().String("from-context", "", "Infer a Kubernetes servo from a kubeconfig context")
+	attachCmd.Flags().StringP("namespace", "n", "", "Namespace of the servo deployment (defaults to the namespace of the context)")
+	attachCmd.Flags().String("selector", DefaultServoSelector, "Label selector identifying the servo deployment")
 	servoCmd.AddCommand(attachCmd)
 
 	detachCmd := &cobra.Command{
@@ -102,16 +116,18 @@ func NewServoCommand(baseCmd *BaseCommand) *cobra.Command {
 		RunE:                  servoCommand.RunDetachServo,
 		DisableFlagsInUseLine: true,
 	}
-	detachCmd.Flags().BoolVarP(&servoCommand.force, "force", "f", false, "Don't prompt for confirmation")
+	baseCmd.AddDeprecatedForceFlag(detachCmd)
 	servoCmd.AddCommand(detachCmd)
 
 	// Servo Lifecycle
-	servoCmd.AddCommand(&cobra.Command{
+	statusCmd := &cobra.Command{
 		Use:   "status",
 		Short: "Check servo status",
 		Args:  cobra.NoArgs,
-		RunE:  servoCommand.RunServoStatus,
-	})
+		RunE:  baseCmd.WatchRunE(servoCommand.RunServoStatus),
+	}
+	AddWatchFlags(statusCmd)
+	servoCmd.AddCommand(statusCmd)
 	servoCmd.AddCommand(&cobra.Command{
 		Use:   "start",
 		Short: "Start the servo",
@@ -130,14 +146,12 @@ func NewServoCommand(baseCmd *BaseCommand) *cobra.Command {
 		Args:  cobra.NoArgs,
 		RunE:  servoCommand.RunServoRestart,
 	})
+	servoCmd.AddCommand(NewServoPauseCommand(baseCmd))
+	servoCmd.AddCommand(NewServoResumeCommand(baseCmd))
 
 	// Servo Access
-	servoCmd.AddCommand(&cobra.Command{
-		Use:   "config",
-		Short: "View servo config file",
-		Args:  cobra.NoArgs,
-		RunE:  servoCommand.RunServoConfig,
-	})
+	servoCmd.AddCommand(NewServoConfigCommand(&servoCommand))
+	servoCmd.AddCommand(NewServoConnectorsCommand(&servoCommand))
 	logsCmd := &cobra.Command{
 		Use:   "logs",
 		Short: "View servo logs",
@@ -148,6 +162,8 @@ func NewServoCommand(baseCmd *BaseCommand) *cobra.Command {
 	logsCmd.Flags().BoolVarP(&servoCommand.follow, "follow", "f", false, "Follow log output")
 	logsCmd.Flags().BoolVarP(&servoCommand.timestamps, "timestamps", "t", false, "Show timestamps")
 	logsCmd.Flags().StringVarP(&servoCommand.lines, "lines", "l", "25", `Number of lines to show from the end of the logs (or "all").`)
+	logsCmd.Flags().StringVar(&servoCommand.logLevel, "level", "", fmt.Sprintf("Show only lines at or above the level: {%s}", strings.Join(ServoLogLevels, "|")))
+	logsCmd.Flags().StringSliceVar(&servoCommand.connectors, "connector", nil, "Show only lines from the connector (repeatable)")
 
 	servoCmd.AddCommand(logsCmd)
 	servoCmd.AddCommand(&cobra.Command{
@@ -156,6 +172,9 @@ func NewServoCommand(baseCmd *BaseCommand) *cobra.Command {
 		Args:  cobra.NoArgs,
 		RunE:  servoCommand.RunServoShell,
 	})
+	servoCmd.AddCommand(NewServoCheckCommand(&servoCommand))
+	servoCmd.AddCommand(NewServoDiscoverCommand(&servoCommand))
+	servoCmd.AddCommand(NewServoReportCommand(&servoCommand))
 
 	return servoCmd
 }
@@ -166,104 +185,138 @@ func (servoCmd *servoCommand) RunAttachServo(c *cobra.Command, args []string) er
 	}
 
 	if servoCmd.profile.Servo != (Servo{}) {
-		prompt := &survey.Confirm{
-			Message: fmt.Sprintf("Existing servo attached to %q. Overwrite?", servoCmd.profile.Name),
+		confirmed, err := servoCmd.Confirm(fmt.Sprintf("Existing servo attached to %q. Overwrite?", servoCmd.profile.Name), false)
+		if err != nil {
+			return err
 		}
-		var confirmed bool
-		servoCmd.AskOne(prompt, &confirmed)
-		if confirmed == false {
+		if !confirmed {
 			return nil
 		}
 	}
 
+	if kubeContext, _ := c.Flags().GetString("from-context"); kubeContext != "" {
+		namespace, _ := c.Flags().GetString("namespace")
+		selector, _ := c.Flags().GetString("selector")
+		servo, err := servoCmd.ServoFromContext(kubeContext, namespace, selector)
+		if err != nil {
+			return err
+		}
+		if err := servoCmd.saveServo(servo); err != nil {
+			return err
+		}
+		servoCmd.Printf("Attached servo %s from context %q to profile %q\n", servo.Description(), kubeContext, servoCmd.profile.Name)
+		if servo.ServoDeployment != "" {
+			servoCmd.Printf("The servo is managed by the servo operator: start and stop scale ServoDeployment %q\n", servo.ServoDeployment)
+		}
+		return nil
+	}
+
+	bastion, _ := c.Flags().GetBool("bastion")
+	bastionHost, _ := c.Flags().GetString("bastion-host")
+	servo, err := servoCmd.promptForServo(bastion, bastionHost)
+	if err != nil {
+		return err
+	}
+
+	return servoCmd.saveServo(servo)
+}
+
+// saveServo attaches the servo to the active profile and saves the config
+func (servoCmd *servoCommand) saveServo(servo Servo) error {
+	registry, err := NewProfileRegistry(servoCmd.viperCfg)
+	if err != nil {
+		return err
+	}
+	profile := registry.ProfileNamed(servoCmd.profile.Name)
+	profile.Servo = servo
+	return registry.Save()
+}
+
+// promptForServo asks for the deployment details of a servo
+// Bastion hosts are only prompted for docker-compose servos when requested and not already given
+func (baseCmd *BaseCommand) promptForServo(bastion bool, bastionHost string) (Servo, error) {
 	servo := Servo{}
+	namespace := "opsani"
+	if registry, err := NewProfileRegistry(baseCmd.viperCfg); err == nil && registry.Defaults().Namespace != "" {
+		namespace = registry.Defaults().Namespace
+	}
 
 	if servo.Type == "" {
-		err := servoCmd.AskOne(&survey.Select{
+		err := baseCmd.AskOne(&survey.Select{
 			Message: "Select deployment:",
 			Options: []string{"kubernetes", "docker-compose"},
 			Default: "kubernetes",
 		}, &servo.Type, survey.WithValidator(survey.Required))
 		if err != nil {
-			return err
+			return Servo{}, err
 		}
 	}
 
 	if servo.Type == "kubernetes" {
 		if servo.User == "" {
-			err := servoCmd.AskOne(&survey.Input{
+			err := baseCmd.AskOne(&survey.Input{
 				Message: "Namespace:",
-				Default: "opsani",
+				Default: namespace,
 			}, &servo.Namespace, survey.WithValidator(survey.Required))
 			if err != nil {
-				return err
+				return Servo{}, err
 			}
 		}
 
 		if servo.Host == "" {
-			err := servoCmd.AskOne(&survey.Input{
+			err := baseCmd.AskOne(&survey.Input{
 				Message: "Deployment:",
 				Default: "servo",
 			}, &servo.Deployment, survey.WithValidator(survey.Required))
 			if err != nil {
-				return err
+				return Servo{}, err
 			}
 		}
 	}
 
 	if servo.Type == "docker-compose" {
 		if servo.User == "" {
-			err := servoCmd.AskOne(&survey.Input{
+			err := baseCmd.AskOne(&survey.Input{
 				Message: "User?",
 			}, &servo.User, survey.WithValidator(survey.Required))
 			if err != nil {
-				return err
+				return Servo{}, err
 			}
 		}
 
 		if servo.Host == "" {
-			err := servoCmd.AskOne(&survey.Input{
+			err := baseCmd.AskOne(&survey.Input{
 				Message: "Host?",
 			}, &servo.Host, survey.WithValidator(survey.Required))
 			if err != nil {
-				return err
+				return Servo{}, err
 			}
 		}
 
 		if servo.Path == "" {
-			err := servoCmd.AskOne(&survey.Input{
+			err := baseCmd.AskOne(&survey.Input{
 				Message: "Path? (optional)",
 			}, &servo.Path)
 			if err != nil {
-				return err
+				return Servo{}, err
 			}
 		}
 
 		// Handle bastion hosts
-		if flagSet, _ := c.Flags().GetBool("bastion"); flagSet {
-			servo.Bastion, _ = c.Flags().GetString("bastion-host")
+		if bastion {
+			servo.Bastion = bastionHost
 			if servo.Bastion == "" {
-				err := servoCmd.AskOne(&survey.Input{
+				err := baseCmd.AskOne(&survey.Input{
 					Message: "Bastion host? (format is user@host[:port])",
 				}, &servo.Bastion)
 				if err != nil {
-					return err
+					return Servo{}, err
 				}
 			}
 		}
 	}
 
-	registry, err := NewProfileRegistry(servoCmd.viperCfg)
-	if err != nil {
-		return err
-	}
-	profile := registry.ProfileNamed(servoCmd.profile.Name)
-	profile.Servo = servo
-	if err := registry.Save(); err != nil {
-		return err
-	}
-
-	return nil
+	return servo, nil
 }
 
 func (servoCmd *servoCommand) RunDetachServo(_ *cobra.Command, args []string) error {
@@ -273,12 +326,9 @@ func (servoCmd *servoCommand) RunDetachServo(_ *cobra.Command, args []string) er
 		return fmt.Errorf("no servo is attached")
 	}
 
-	confirmed := servoCmd.force
-	if !confirmed {
-		prompt := &survey.Confirm{
-			Message: fmt.Sprintf("Detach servo from profile %q?", servoCmd.profile.Name),
-		}
-		servoCmd.AskOne(prompt, &confirmed)
+	confirmed, err := servoCmd.Confirm(fmt.Sprintf("Detach servo from profile %q?", servoCmd.profile.Name), false)
+	if err != nil {
+		return err
 	}
 
 	if confirmed {
@@ -288,6 +338,9 @@ func (servoCmd *servoCommand) RunDetachServo(_ *cobra.Command, args []string) er
 		}
 		profile := registry.ProfileNamed(servoCmd.profile.Name)
 		profile.Servo = Servo{}
+		if err := servoCmd.BackupConfig(fmt.Sprintf("servo detach from profile %s", profile.Name)); err != nil {
+			return err
+		}
 		if err := registry.Save(); err != nil {
 			return err
 		}
@@ -297,28 +350,26 @@ func (servoCmd *servoCommand) RunDetachServo(_ *cobra.Command, args []string) er
 	return nil
 }
 
-func (servoCmd *servoCommand) RunServoList(_ *cobra.Command, args []string) error {
-	table := tablewriter.NewWriter(servoCmd.OutOrStdout())
-	table.SetAutoWrapText(false)
-	table.SetAutoFormatHeaders(true)
-	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
-	table.SetAlignment(tablewriter.ALIGN_LEFT)
-	table.SetCenterSeparator("")
-	table.SetColumnSeparator("")
-	table.SetRowSeparator("")
-	table.SetHeaderLine(false)
-	table.SetBorder(false)
-	table.SetTablePadding("\t") // pad with tabs
-	table.SetNoWhiteSpace(true)
-
-	data := [][]string{}
+func (servoCmd *servoCommand) RunServoList(c *cobra.Command, args []string) error {
+	output, err := OutputFormat(c, TabularOutputFormats...)
+	if err != nil {
+		return err
+	}
 	registry, err := NewProfileRegistry(servoCmd.viperCfg)
 	if err != nil {
 		return nil
 	}
 
+	bastions := false
+	for _, profile := range registry.Profiles() {
+		if profile.Servo.Bastion != "" {
+			bastions = true
+		}
+	}
+
+	table := Table{}
 	if servoCmd.verbose {
-		headers := []string{"NAME", "TYPE", "NAMESPACE", "DEPLOYMENT", "USER", "HOST", "PATH"}
+		table.Headers = []string{"NAME", "TYPE", "NAMESPACE", "DEPLOYMENT", "USER", "HOST", "PATH"}
 		for _, profile := range registry.Profiles() {
 			row := []string{
 				profile.Name,
@@ -329,16 +380,14 @@ func (servoCmd *servoCommand) RunServoList(_ *cobra.Command, args []string) erro
 				profile.Servo.DisplayHost(),
 				profile.Servo.DisplayPath(),
 			}
-			if profile.Servo.Bastion != "" {
+			if bastions {
 				row = append(row, profile.Servo.Bastion)
-				if len(headers) == 4 {
-					headers = append(headers, "BASTION")
-				}
 			}
-			data = append(data, row)
+			table.Rows = append(table.Rows, row)
 		}
-		table.SetHeader(headers)
 	} else {
+		table.Headers = []string{"NAME", "TYPE", "SERVO"}
+		table.HideHeaders = true
 		for _, profile := range registry.Profiles() {
 			row := []string{
 				profile.Name,
@@ -347,20 +396,25 @@ func (servoCmd *servoCommand) RunServoList(_ *cobra.Command, args []string) erro
 			}
 			if profile.Servo.Bastion != "" {
 				row = append(row, fmt.Sprintf("(via %s)", profile.Servo.Bastion))
+			} else if bastions && output != OutputTable {
+				row = append(row, "")
 			}
-			data = append(data, row)
+			table.Rows = append(table.Rows, row)
 		}
 	}
+	if bastions {
+		table.Headers = append(table.Headers, "BASTION")
+	}
 
-	table.AppendBulk(data)
-	table.Render()
-	return nil
+	return servoCmd.RenderTable(output, table)
 }
 
-type servoLogsArgs struct {
+type ServoLogsArgs struct {
 	Follow     bool
 	Timestamps bool
 	Lines      string
+	Level      string
+	Connectors []string
 }
 
 // ServoDriver defines a standard interface for interacting with servo deployments
@@ -369,19 +423,27 @@ type ServoDriver interface {
 	Start() error
 	Stop() error
 	Restart() error
-	Logs(args servoLogsArgs) error
+	Logs(args ServoLogsArgs) error
 	Config() error
+	ConfigData() ([]byte, error)
+	SetConfigData(data []byte) error
 	Shell() error
+	Report(args ServoReportArgs) ([]ReportArtifact, error)
+	Check(args ServoCheckArgs) error
+	Ping() error
 }
 
 // DockerComposeServoDriver supports interaction with servos deployed via Docker Compose
 type DockerComposeServoDriver struct {
-	servo Servo
+	servo    Servo
+	timeout  time.Duration
+	prompter SSHPrompter
 }
 
 // Status outputs the servo status
 func (c *DockerComposeServoDriver) Status() error {
-	ctx := context.Background()
+	ctx, cancel := contextWithTimeout(c.timeout)
+	defer cancel()
 	return c.runInSSHSession(ctx, func(ctx context.Context, session *ssh.Session) error {
 		return c.runDockerComposeOverSSH("ps", nil, session)
 	})
@@ -389,7 +451,8 @@ func (c *DockerComposeServoDriver) Status() error {
 
 // Start starts the servo
 func (c *DockerComposeServoDriver) Start() error {
-	ctx := context.Background()
+	ctx, cancel := contextWithTimeout(c.timeout)
+	defer cancel()
 	return c.runInSSHSession(ctx, func(ctx context.Context, session *ssh.Session) error {
 		return c.runDockerComposeOverSSH("up -d", nil, session)
 	})
@@ -397,7 +460,8 @@ func (c *DockerComposeServoDriver) Start() error {
 
 // Stop stops the servo
 func (c *DockerComposeServoDriver) Stop() error {
-	ctx := context.Background()
+	ctx, cancel := contextWithTimeout(c.timeout)
+	defer cancel()
 	return c.runInSSHSession(ctx, func(ctx context.Context, session *ssh.Session) error {
 		return c.runDockerComposeOverSSH("down", nil, session)
 	})
@@ -405,18 +469,22 @@ func (c *DockerComposeServoDriver) Stop() error {
 
 // Restart restrarts the servo
 func (c *DockerComposeServoDriver) Restart() error {
-	ctx := context.Background()
+	ctx, cancel := contextWithTimeout(c.timeout)
+	defer cancel()
 	return c.runInSSHSession(ctx, func(ctx context.Context, session *ssh.Session) error {
 		return c.runDockerComposeOverSSH("down && docker-compse up -d", nil, session)
 	})
 }
 
 // Logs outputs the servo logs
-func (c *DockerComposeServoDriver) Logs(logsArgs servoLogsArgs) error {
-	ctx := context.Background()
+func (c *DockerComposeServoDriver) Logs(logsArgs ServoLogsArgs) error {
+	ctx, cancel := c.logsContext(logsArgs)
+	defer cancel()
 	return c.runInSSHSession(ctx, func(ctx context.Context, session *ssh.Session) error {
 		// TODO: Needs to be passed in
-		session.Stdout = os.Stdout
+		stdout, flush := servoLogOutput(os.Stdout, logsArgs)
+		defer flush()
+		session.Stdout = stdout
 		session.Stderr = os.Stderr
 
 		args := []string{}
@@ -437,7 +505,20 @@ func (c *DockerComposeServoDriver) Logs(logsArgs servoLogsArgs) error {
 
 // Config returns the servo config file
 func (c *DockerComposeServoDriver) Config() error {
-	ctx := context.Background()
+	data, err := c.ConfigData()
+
+	// We got the config, let's pretty print it
+	if err == nil {
+		prettyYAML, _ := PrettyPrintYAMLToString(data, true, true)
+		_, err = os.Stdout.Write([]byte(prettyYAML + "\n"))
+	}
+	return err
+}
+
+// ConfigData returns the raw contents of the servo config file
+func (c *DockerComposeServoDriver) ConfigData() ([]byte, error) {
+	ctx, cancel := contextWithTimeout(c.timeout)
+	defer cancel()
 	outputBuffer := new(bytes.Buffer)
 	err := c.runInSSHSession(ctx, func(ctx context.Context, session *ssh.Session) error {
 		session.Stdout = outputBuffer
@@ -450,21 +531,25 @@ func (c *DockerComposeServoDriver) Config() error {
 		sshCmd = append(sshCmd, "cat", "config.yaml")
 		return session.Run(strings.Join(sshCmd, " "))
 	})
-
-	// We got the config, let's pretty print it
-	if err == nil {
-		prettyYAML, _ := PrettyPrintYAMLToString(outputBuffer.Bytes(), true, true)
-		_, err = os.Stdout.Write([]byte(prettyYAML + "\n"))
-	}
-	return err
+	return outputBuffer.Bytes(), err
 }
 
 // Shell establishes an interactive shell with the servo
+// Interactive shells are not subject to the timeout
 func (c *DockerComposeServoDriver) Shell() error {
 	ctx := context.Background()
 	return c.runInSSHSession(ctx, c.runShellOnSSHSession)
 }
 
+// logsContext returns a context for retrieving logs
+// Followed logs stream until interrupted and are not subject to the timeout
+func (c *DockerComposeServoDriver) logsContext(logsArgs ServoLogsArgs) (context.Context, context.CancelFunc) {
+	if logsArgs.Follow {
+		return context.WithCancel(context.Background())
+	}
+	return contextWithTimeout(c.timeout)
+}
+
 func (c *DockerComposeServoDriver) runDockerComposeOverSSH(cmd string, args []string, session *ssh.Session) error {
 	session.Stdout = os.Stdout
 	session.Stderr = os.Stderr
@@ -511,6 +596,14 @@ func (c *DockerComposeServoDriver) runShellOnSSHSession(ctx context.Context, ses
 		return fmt.Errorf("session shell: %s", err)
 	}
 
+	// Propagate terminal resizes to the remote pty
+	stopResize := NotifyTerminalResize(func() {
+		if w, h, err := terminal.GetSize(fd); err == nil {
+			session.WindowChange(h, w)
+		}
+	})
+	defer stopResize()
+
 	if err := session.Wait(); err != nil {
 		if e, ok := err.(*ssh.ExitError); ok {
 			switch e.ExitStatus() {
@@ -528,47 +621,81 @@ func (c *DockerComposeServoDriver) runShellOnSSHSession(ctx context.Context, ses
 
 // KubernetesServoDriver supports interaction with servos deployed via Kubernetes
 type KubernetesServoDriver struct {
-	servo Servo
+	servo   Servo
+	timeout time.Duration
 }
 
-// Status outputs the servo status
+// kubectlArgs prepends the servo kubeconfig and context, when set, to the given kubectl arguments
+func (c *KubernetesServoDriver) kubectlArgs(args ...string) []string {
+	if c.servo.Context != "" {
+		args = append([]string{"--context", c.servo.Context}, args...)
+	}
+	if c.servo.Kubeconfig != "" {
+		args = append([]string{"--kubeconfig", c.servo.Kubeconfig}, args...)
+	}
+	return args
+}
+
+// kubectl returns a command for running kubectl against the servo deployment
+// The command is killed if the context is done before it completes
+func (c *KubernetesServoDriver) kubectl(ctx context.Context, args ...string) *exec.Cmd {
+	return commandContext(ctx, "kubectl", c.kubectlArgs(args...)...)
+}
+
+// Status outputs the servo status and a failure summary of containers in CrashLoopBackOff
 func (c *KubernetesServoDriver) Status() error {
+	ctx, cancel := contextWithTimeout(c.timeout)
+	defer cancel()
 	argsS := fmt.Sprintf("-n %v describe deployments/%v", c.servo.Namespace, c.servo.Deployment)
-	cmd := exec.Command("kubectl", ArgsS(argsS)...)
+	cmd := c.kubectl(ctx, ArgsS(argsS)...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	if err := runCommand(cmd); err != nil {
+		return err
+	}
+	c.writeCrashLoops(os.Stdout)
+	return nil
 }
 
 // Start starts the servo
 func (c *KubernetesServoDriver) Start() error {
-	argsS := fmt.Sprintf("-n %v scale --replicas=1 deployments/%v", c.servo.Namespace, c.servo.Deployment)
-	cmd := exec.Command("kubectl", ArgsS(argsS)...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return c.scale(1)
 }
 
 // Stop stops the servo
 func (c *KubernetesServoDriver) Stop() error {
-	argsS := fmt.Sprintf("-n %v scale --replicas=0 deployments/%v", c.servo.Namespace, c.servo.Deployment)
-	cmd := exec.Command("kubectl", ArgsS(argsS)...)
+	return c.scale(0)
+}
+
+// scale sets the number of servo replicas
+// Operator managed servos are scaled via their ServoDeployment so that the operator does not revert the change
+func (c *KubernetesServoDriver) scale(replicas int) error {
+	ctx, cancel := contextWithTimeout(c.timeout)
+	defer cancel()
+	args := ArgsS(fmt.Sprintf("-n %v scale --replicas=%d deployments/%v", c.servo.Namespace, replicas, c.servo.Deployment))
+	if c.servo.ServoDeployment != "" {
+		resourceArg := fmt.Sprintf("%s/%s", ServoDeploymentResource, c.servo.ServoDeployment)
+		args = Args("-n", c.servo.Namespace, "patch", resourceArg, "--type", "merge", "-p", fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+	}
+	cmd := c.kubectl(ctx, args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return runCommand(cmd)
 }
 
 // Restart restarts the servo
 func (c *KubernetesServoDriver) Restart() error {
+	ctx, cancel := contextWithTimeout(c.timeout)
+	defer cancel()
 	argsS := fmt.Sprintf("-n %v rollout restart deployment/%v", c.servo.Namespace, c.servo.Deployment)
-	cmd := exec.Command("kubectl", ArgsS(argsS)...)
+	cmd := c.kubectl(ctx, ArgsS(argsS)...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return runCommand(cmd)
 }
 
 // Logs outputs the servo logs
-func (c *KubernetesServoDriver) Logs(logsArgs servoLogsArgs) error {
+func (c *KubernetesServoDriver) Logs(logsArgs ServoLogsArgs) error {
 	deploymentArg := fmt.Sprintf("deployments/%v", c.servo.Deployment)
 	args := Args("-n", c.servo.Namespace, "logs", deploymentArg)
 
@@ -582,48 +709,140 @@ func (c *KubernetesServoDriver) Logs(logsArgs servoLogsArgs) error {
 		args = append(args, "--timestamps")
 	}
 
-	cmd := exec.Command("kubectl", args...)
-	cmd.Stdout = os.Stdout
+	ctx, cancel := c.logsContext(logsArgs)
+	defer cancel()
+	stdout, flush := servoLogOutput(os.Stdout, logsArgs)
+	cmd := c.kubectl(ctx, args...)
+	cmd.Stdout = stdout
 	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	err := runCommand(cmd)
+	if flushErr := flush(); err == nil {
+		err = flushErr
+	}
+	return err
 }
 
 // Config outputs the servo config
 func (c *KubernetesServoDriver) Config() error {
+	data, err := c.ConfigData()
+	if err != nil {
+		return nil
+	}
+
+	prettyYAML, _ := PrettyPrintYAMLToString(data, true, true)
+	_, err = os.Stdout.Write([]byte(prettyYAML + "\n"))
+	return err
+}
+
+// ConfigData returns the raw contents of the servo config file
+func (c *KubernetesServoDriver) ConfigData() ([]byte, error) {
+	ctx, cancel := contextWithTimeout(c.timeout)
+	defer cancel()
 	outputBuffer := new(bytes.Buffer)
 	argsS := fmt.Sprintf("-n %v exec deployment/%v -- cat /servo/config.yaml", c.servo.Namespace, c.servo.Deployment)
-	cmd := exec.Command("kubectl", ArgsS(argsS)...)
+	cmd := c.kubectl(ctx, ArgsS(argsS)...)
 	cmd.Stdout = outputBuffer
 	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return nil
-	}
+	err := runCommand(cmd)
+	return outputBuffer.Bytes(), err
+}
 
-	prettyYAML, _ := PrettyPrintYAMLToString(outputBuffer.Bytes(), true, true)
-	_, err := os.Stdout.Write([]byte(prettyYAML + "\n"))
-	return err
+// logsContext returns a context for retrieving logs
+// Followed logs stream until interrupted and are not subject to the timeout
+func (c *KubernetesServoDriver) logsContext(logsArgs ServoLogsArgs) (context.Context, context.CancelFunc) {
+	if logsArgs.Follow {
+		return context.WithCancel(context.Background())
+	}
+	return contextWithTimeout(c.timeout)
 }
 
 // NewServoDriver creates and returns an appropriate commander for a given servo
-func NewServoDriver(servo Servo) (ServoDriver, error) {
+// Operations other than interactive sessions are aborted if they exceed the timeout
+func NewServoDriver(servo Servo, timeout time.Duration) (ServoDriver, error) {
 	if servo.Type == "docker-compose" {
-		return &DockerComposeServoDriver{servo: servo}, nil
+		return &DockerComposeServoDriver{servo: servo, timeout: timeout}, nil
 	} else if servo.Type == "kubernetes" {
-		return &KubernetesServoDriver{servo: servo}, nil
+		return &KubernetesServoDriver{servo: servo, timeout: timeout}, nil
 	}
 	return nil, fmt.Errorf("no driver for servo type: %q", servo.Type)
 }
 
+// SetPrompter enables prompting for SSH credentials when agent authentication fails
+func (c *DockerComposeServoDriver) SetPrompter(prompter SSHPrompter) {
+	c.prompter = prompter
+}
+
+// servoDriver returns a driver for the servo of the active profile
+// Drivers that authenticate interactively prompt via the command stdio
+func (servoCmd *servoCommand) servoDriver() (ServoDriver, error) {
+	driver, err := servoDriverFactory(servoCmd.profile.Servo, servoCmd.Timeout())
+	if d, ok := driver.(interface{ SetPrompter(SSHPrompter) }); ok {
+		d.SetPrompter(servoCmd.BaseCommand)
+	}
+	return driver, err
+}
+
+// ServoDriverFactory creates a driver for interacting with a servo
+type ServoDriverFactory func(servo Servo, timeout time.Duration) (ServoDriver, error)
+
+var servoDriverFactory ServoDriverFactory = NewServoDriver
+
+// SetServoDriverFactory is a package helper for testing that replaces the drivers used by servo commands
+// Passing nil restores the default of NewServoDriver
+func SetServoDriverFactory(factory ServoDriverFactory) {
+	if factory == nil {
+		factory = NewServoDriver
+	}
+	servoDriverFactory = factory
+}
+
 func (servoCmd *servoCommand) RunServoStatus(_ *cobra.Command, args []string) error {
-	driver, err := NewServoDriver(servoCmd.profile.Servo)
+	driver, err := servoCmd.servoDriver()
 	if driver == nil {
 		return err
 	}
-	return driver.Status()
+	if err := driver.Status(); err != nil {
+		return err
+	}
+	servoCmd.printServoFreshness()
+	return nil
+}
+
+// printServoFreshness summarizes the servo activity observed by the optimizer
+// A servo that is running but has not reported within DefaultMonitorStaleAfter is flagged
+func (servoCmd *servoCommand) printServoFreshness() {
+	if servoCmd.Optimizer() == "" || servoCmd.AccessToken() == "" {
+		return
+	}
+	status, err := servoCmd.NewAPIClient().GetServoStatus()
+	if err != nil {
+		servoCmd.PrintErrf("warning: unable to retrieve the servo status from the optimizer: %s\n", err)
+		return
+	}
+
+	bold := color.New(color.Bold).SprintFunc()
+	line := func(glyph string, label string, format string, args ...interface{}) {
+		fmt.Fprintf(servoCmd.OutOrStdout(), "%s  %-21s %s\n", servoCmd.ColorGlyph(glyph), bold(label), fmt.Sprintf(format, args...))
+	}
+
+	servoCmd.Printf("\nOptimizer %s:\n", servoCmd.Optimizer())
+	if status.LastReportAt == nil {
+		line(glyphFailure, "Last report:", "never: check the optimizer and token of the servo with `opsani check link`")
+	} else if age := time.Since(*status.LastReportAt).Round(time.Second); age > DefaultMonitorStaleAfter {
+		line(glyphFailure, "Last report:", "%s ago: the servo is not reporting, check its logs with `opsani servo logs`", age)
+	} else {
+		line(glyphSuccess, "Last report:", "%s ago", age)
+	}
+	phase := status.Phase
+	if phase == "" {
+		phase = "unknown"
+	}
+	line(glyphInfo, "Phase:", "%s", phase)
+	line(glyphInfo, "Pending adjustments:", "%d", len(status.PendingAdjustments))
 }
 
 func (servoCmd *servoCommand) RunServoStart(_ *cobra.Command, args []string) error {
-	driver, err := NewServoDriver(servoCmd.profile.Servo)
+	driver, err := servoCmd.servoDriver()
 	if driver == nil {
 		return err
 	}
@@ -631,7 +850,7 @@ func (servoCmd *servoCommand) RunServoStart(_ *cobra.Command, args []string) err
 }
 
 func (servoCmd *servoCommand) RunServoStop(_ *cobra.Command, args []string) error {
-	driver, err := NewServoDriver(servoCmd.profile.Servo)
+	driver, err := servoCmd.servoDriver()
 	if driver == nil {
 		return err
 	}
@@ -639,7 +858,7 @@ func (servoCmd *servoCommand) RunServoStop(_ *cobra.Command, args []string) erro
 }
 
 func (servoCmd *servoCommand) RunServoRestart(_ *cobra.Command, args []string) error {
-	driver, err := NewServoDriver(servoCmd.profile.Servo)
+	driver, err := servoCmd.servoDriver()
 	if driver == nil {
 		return err
 	}
@@ -647,7 +866,7 @@ func (servoCmd *servoCommand) RunServoRestart(_ *cobra.Command, args []string) e
 }
 
 func (servoCmd *servoCommand) RunServoConfig(_ *cobra.Command, args []string) error {
-	driver, err := NewServoDriver(servoCmd.profile.Servo)
+	driver, err := servoCmd.servoDriver()
 	if driver == nil {
 		return err
 	}
@@ -655,20 +874,27 @@ func (servoCmd *servoCommand) RunServoConfig(_ *cobra.Command, args []string) er
 }
 
 func (servoCmd *servoCommand) RunServoLogs(_ *cobra.Command, args []string) error {
-	driver, err := NewServoDriver(servoCmd.profile.Servo)
+	if err := ValidateServoLogLevel(servoCmd.logLevel); err != nil {
+		return err
+	}
+	driver, err := servoCmd.servoDriver()
 	if driver == nil {
 		return err
 	}
-	logsArgs := servoLogsArgs{
+	logsArgs := ServoLogsArgs{
 		Follow:     servoCmd.follow,
 		Timestamps: servoCmd.timestamps,
 		Lines:      servoCmd.lines,
+		Level:      servoCmd.logLevel,
+	}
+	if len(servoCmd.connectors) > 0 {
+		logsArgs.Connectors = servoCmd.connectors
 	}
 	return driver.Logs(logsArgs)
 }
 
 func (servoCmd *servoCommand) RunServoShell(_ *cobra.Command, args []string) error {
-	driver, err := NewServoDriver(servoCmd.profile.Servo)
+	driver, err := servoCmd.servoDriver()
 	if driver == nil {
 		return err
 	}
@@ -679,83 +905,136 @@ func (servoCmd *servoCommand) RunServoShell(_ *cobra.Command, args []string) err
 /// SSH Primitives
 ///
 
-func sshAgent() ssh.AuthMethod {
-	if sshAgent, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK")); err == nil {
-		return ssh.PublicKeysCallback(agent.NewClient(sshAgent).Signers)
+// ServoShellCommand returns a shell command line running the arguments in the servo path on the servo host
+// The path and every argument are quoted so that they reach the remote shell verbatim
+func ServoShellCommand(path string, args ...string) string {
+	command := shellQuoteArgs(args)
+	if path != "" {
+		command = "cd " + shellQuoteArgs([]string{path}) + " && " + command
 	}
-	return nil
+	return command
 }
 
-// TODO: convert to standalone func
-func (c *DockerComposeServoDriver) runInSSHSession(ctx context.Context, runIt func(context.Context, *ssh.Session) error) error {
+// sshPoolKey identifies the connection to the servo host, including the bastion it is reached through
+func (c *DockerComposeServoDriver) sshPoolKey() string {
+	key := c.servo.User + "@" + c.servo.HostAndPort()
+	if c.servo.Bastion != "" {
+		key += " via " + c.servo.Bastion
+	}
+	return key
+}
+
+// dialSSH establishes a new connection to the servo host
+// Dialing and the handshakes are bounded by the dial timeout and the context, excluding time spent prompting for credentials
+func (c *DockerComposeServoDriver) dialSSH(ctx context.Context) (*ssh.Client, []*ssh.Client, error) {
 	// SSH client config
 	knownHosts, err := homedir.Expand("~/.ssh/known_hosts") // TODO: Windows support
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	hostKeyCallback, err := knownhosts.New(knownHosts)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
+	timeout := sshDialTimeout(c.timeout)
+	deadline := NewSSHDeadline(ctx, timeout)
+	defer deadline.Stop()
+	ctx = deadline
+	prompter := deadline.Prompter(c.prompter)
 	config := &ssh.ClientConfig{
-		User: c.servo.User,
-		Auth: []ssh.AuthMethod{
-			sshAgent(),
-		},
+		User:            c.servo.User,
+		Auth:            SSHAuthMethods(c.servo.User, c.servo.Host, prompter),
 		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
 	}
 
 	// Support bastion hosts via redialing
-	var sshClient *ssh.Client
 	if c.servo.Bastion != "" {
 		user, host := c.servo.BastionComponents()
 		bastionConfig := &ssh.ClientConfig{
-			User: user,
-			Auth: []ssh.AuthMethod{
-				sshAgent(),
-			},
+			User:            user,
+			Auth:            SSHAuthMethods(user, host, prompter),
 			HostKeyCallback: hostKeyCallback,
+			Timeout:         config.Timeout,
 		}
 
 		// Dial the bastion host
-		bastionClient, err := ssh.Dial("tcp", host, bastionConfig)
+		bastionClient, err := DialSSH(ctx, host, bastionConfig)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 
 		// Establish a new connection thrrough the bastion
 		conn, err := bastionClient.Dial("tcp", c.servo.HostAndPort())
 		if err != nil {
-			return err
+			bastionClient.Close()
+			return nil, nil, err
 		}
 
 		// Build a new SSH connection on top of the bastion connection
-		ncc, chans, reqs, err := ssh.NewClientConn(conn, c.servo.HostAndPort(), config)
+		sshClient, err := newSSHClientContext(ctx, conn, c.servo.HostAndPort(), config)
 		if err != nil {
-			return err
+			bastionClient.Close()
+			return nil, nil, err
 		}
+		return sshClient, []*ssh.Client{bastionClient}, nil
+	}
 
-		// Now connection a client on top of it
-		sshClient = ssh.NewClient(ncc, chans, reqs)
-	} else {
-		sshClient, err = ssh.Dial("tcp", c.servo.HostAndPort(), config)
-		if err != nil {
-			return err
-		}
+	sshClient, err := DialSSH(ctx, c.servo.HostAndPort(), config)
+	if err != nil {
+		return nil, nil, err
 	}
-	defer sshClient.Close()
+	return sshClient, nil, nil
+}
 
-	// Create sesssion
+// newSSHSession opens a session on the pooled connection to the servo host
+// A connection that has been dropped since it was last used is redialed once
+func (c *DockerComposeServoDriver) newSSHSession(ctx context.Context) (*ssh.Session, error) {
+	key := c.sshPoolKey()
+	dial := func() (*ssh.Client, []*ssh.Client, error) {
+		return c.dialSSH(ctx)
+	}
+	sshClient, err := sshConnections.Client(key, dial)
+	if err != nil {
+		return nil, err
+	}
 	session, err := sshClient.NewSession()
 	if err != nil {
-		return err
+		sshConnections.Evict(key)
+		if sshClient, err = sshConnections.Client(key, dial); err != nil {
+			return nil, err
+		}
+		session, err = sshClient.NewSession()
+	}
+	return session, err
+}
+
+// runInSSHSession runs a function on a new session with the servo host
+// When the context is done or the CLI is interrupted the remote command is sent SIGINT and the session is closed
+func (c *DockerComposeServoDriver) runInSSHSession(ctx context.Context, runIt func(context.Context, *ssh.Session) error) error {
+	ctx, cancel := contextWithInterrupt(ctx)
+	defer cancel()
+
+	session, err := c.newSSHSession(ctx)
+	if err != nil {
+		return sshSessionError(ctx, c.timeout, err)
 	}
 	defer session.Close()
 
+	// Closing the session interrupts the command while leaving the connection open for reuse
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
-		<-ctx.Done()
-		sshClient.Close()
+		select {
+		case <-ctx.Done():
+			session.Signal(ssh.SIGINT)
+			session.Close()
+		case <-done:
+		}
 	}()
 
-	return runIt(ctx, session)
+	if err := runIt(ctx, session); err != nil {
+		return sshSessionError(ctx, c.timeout, err)
+	}
+	return nil
 }
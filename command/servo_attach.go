@@ -0,0 +1,97 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// DefaultServoSelector is the label selector used to detect servo deployments in a kubeconfig context
+const DefaultServoSelector = "app.kubernetes.io/name=servo"
+
+// servoDeploymentsJSONPath lists deployments with the name of the ServoDeployment owning each
+var servoDeploymentsJSONPath = fmt.Sprintf(`jsonpath={range .items[*]}{.metadata.name}{"\t"}{.metadata.ownerReferences[?(@.kind==%q)].name}{"\n"}{end}`, ServoDeploymentKind)
+
+// kubectlOutput runs kubectl and returns its standard output
+func (servoCmd *servoCommand) kubectlOutput(args ...string) ([]byte, error) {
+	ctx, cancel := servoCmd.ContextWithTimeout()
+	defer cancel()
+	stderr := new(bytes.Buffer)
+	cmd := commandContext(ctx, "kubectl", args...)
+	cmd.Stderr = stderr
+	output, err := commandOutput(cmd)
+	if err != nil {
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			return nil, fmt.Errorf("kubectl %s: %w: %s", strings.Join(args, " "), err, msg)
+		}
+		return nil, fmt.Errorf("kubectl %s: %w", strings.Join(args, " "), err)
+	}
+	return output, nil
+}
+
+// ServoFromContext infers a Kubernetes servo from a kubeconfig context
+// The namespace defaults to that of the context and the deployment is detected via the selector
+func (servoCmd *servoCommand) ServoFromContext(kubeContext, namespace, selector string) (Servo, error) {
+	output, err := servoCmd.kubectlOutput("config", "view", "-o", "json")
+	if err != nil {
+		return Servo{}, err
+	}
+	result := gjson.GetBytes(output, fmt.Sprintf("contexts.#(name==%q)", kubeContext))
+	if !result.Exists() {
+		return Servo{}, fmt.Errorf("kubeconfig context %q not found", kubeContext)
+	}
+	if namespace == "" {
+		namespace = result.Get("context.namespace").String()
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	// Each line names a deployment followed by the ServoDeployment owning it if managed by the servo operator
+	output, err = servoCmd.kubectlOutput("--context", kubeContext, "-n", namespace,
+		"get", "deployments", "-l", selector, "-o", servoDeploymentsJSONPath)
+	if err != nil {
+		return Servo{}, err
+	}
+	deployments := []string{}
+	owners := map[string]string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			deployments = append(deployments, fields[0])
+			if len(fields) > 1 {
+				owners[fields[0]] = fields[1]
+			}
+		}
+	}
+	switch len(deployments) {
+	case 0:
+		return Servo{}, fmt.Errorf("no servo deployment labeled %q found in namespace %q of context %q", selector, namespace, kubeContext)
+	case 1:
+		return Servo{
+			Type:            "kubernetes",
+			Context:         kubeContext,
+			Namespace:       namespace,
+			Deployment:      deployments[0],
+			ServoDeployment: owners[deployments[0]],
+		}, nil
+	default:
+		return Servo{}, fmt.Errorf("found %d servo deployments labeled %q in namespace %q of context %q (%s): narrow the match with --selector",
+			len(deployments), selector, namespace, kubeContext, strings.Join(deployments, ", "))
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+type ServoCheckArgs struct {
+	Connectors  []string
+	Wait        bool
+	Progressive bool
+	Verbose     bool
+}
+
+// NewServoCheckCommand returns a new `opsani servo check` command instance
+func NewServoCheckCommand(servoCommand *servoCommand) *cobra.Command {
+	checkCmd := &cobra.Command{
+		Use:   "check [CONNECTOR ...]",
+		Short: "Check servo connector health",
+		Long: `Runs the servo check subsystem inside the deployed servo and streams the results.
+
+Checks are run against all connectors unless specific connectors are named.`,
+		RunE: servoCommand.RunServoCheck,
+	}
+	checkCmd.Flags().BoolP("wait", "w", false, "Wait for checks to pass (not subject to --timeout)")
+	checkCmd.Flags().Bool("progressive", false, "Run checks progressively, halting at the first failure")
+	checkCmd.Flags().BoolP("verbose", "v", false, "Display verbose output")
+	return checkCmd
+}
+
+func (servoCmd *servoCommand) RunServoCheck(c *cobra.Command, args []string) error {
+	driver, err := servoCmd.servoDriver()
+	if driver == nil {
+		return err
+	}
+
+	checkArgs := ServoCheckArgs{Connectors: args}
+	checkArgs.Wait, _ = c.Flags().GetBool("wait")
+	checkArgs.Progressive, _ = c.Flags().GetBool("progressive")
+	checkArgs.Verbose, _ = c.Flags().GetBool("verbose")
+
+	// Waiting for checks to pass can take minutes so notify when finished
+	started := time.Now()
+	err = driver.Check(checkArgs)
+	if checkArgs.Wait {
+		servoCmd.notifyTaskFinished(c, started, err)
+	}
+	return err
+}
+
+// command returns the servo CLI invocation for the check arguments
+func (checkArgs ServoCheckArgs) command() []string {
+	cmd := []string{"servo", "check"}
+	if checkArgs.Wait {
+		cmd = append(cmd, "--wait")
+	}
+	if checkArgs.Progressive {
+		cmd = append(cmd, "--progressive")
+	}
+	if checkArgs.Verbose {
+		cmd = append(cmd, "--verbose")
+	}
+	return append(cmd, checkArgs.Connectors...)
+}
+
+// checkContext returns a context for running checks
+// Waiting for checks to pass is open ended and not subject to the timeout
+func checkContext(checkArgs ServoCheckArgs, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if checkArgs.Wait {
+		return context.WithCancel(context.Background())
+	}
+	return contextWithTimeout(timeout)
+}
+
+// Check runs the servo checks inside the servo container
+func (c *KubernetesServoDriver) Check(checkArgs ServoCheckArgs) error {
+	ctx, cancel := checkContext(checkArgs, c.timeout)
+	defer cancel()
+	deploymentArg := fmt.Sprintf("deployment/%v", c.servo.Deployment)
+	args := append(Args("-n", c.servo.Namespace, "exec", deploymentArg, "--"), checkArgs.command()...)
+	cmd := c.kubectl(ctx, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return runCommand(cmd)
+}
+
+// Check runs the servo checks inside the servo container
+func (c *DockerComposeServoDriver) Check(checkArgs ServoCheckArgs) error {
+	ctx, cancel := checkContext(checkArgs, c.timeout)
+	defer cancel()
+	return c.runInSSHSession(ctx, func(ctx context.Context, session *ssh.Session) error {
+		session.Stdout = os.Stdout
+		session.Stderr = os.Stderr
+
+		args := append([]string{"docker-compose", "exec", "-T", "servo"}, checkArgs.command()...)
+		return session.Run(ServoShellCommand(c.servo.Path, args...))
+	})
+}
@@ -0,0 +1,304 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+	"golang.org/x/crypto/ssh"
+	"sigs.k8s.io/yaml"
+)
+
+// servoConfigPath is the path of the config file within Kubernetes servo containers
+const servoConfigPath = "/servo/config.yaml"
+
+// NewServoConfigCommand returns a new `opsani servo config` command instance
+func NewServoConfigCommand(servoCmd *servoCommand) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "View servo config file",
+		Args:  cobra.NoArgs,
+		RunE:  servoCmd.RunServoConfig,
+	}
+
+	editCmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Edit servo config file",
+		Long: `Edit the servo config file in your editor, write it back to the servo, and restart the servo.
+
+Kubernetes servos are updated by patching the ConfigMap mounted at ` + servoConfigPath + ` and
+Docker Compose servos by replacing config.yaml over SSH. Invalid YAML is re-opened in the
+editor annotated with the error. Configs are also checked against the servo config schema so that
+unknown connectors and out of range guardrails are caught before the servo restarts. Saving an
+empty file cancels the edit.`,
+		Args: cobra.NoArgs,
+		RunE: servoCmd.RunServoConfigEdit,
+	}
+	editCmd.Flags().StringP("editor", "e", os.Getenv("EDITOR"), "Edit the config with the given editor (overrides $EDITOR)")
+	editCmd.Flags().Bool("no-restart", false, "Write the config without restarting the servo")
+	addServoSchemaFlags(editCmd)
+	configCmd.AddCommand(editCmd)
+
+	validateCmd := &cobra.Command{
+		Use:   "validate [FILE]",
+		Short: "Validate servo config file",
+		Long: `Validate a servo config file against the servo config schema.
+
+The config of the active servo is validated unless a file is given. Unknown connectors,
+misspelled guardrail keys, and out of range values are reported without contacting the servo.`,
+		Args: cobra.MaximumNArgs(1),
+		// Files can be validated before the client is initialized
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := servoCmd.InitConfigRunE(cmd, args); err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				return nil
+			}
+			return ReduceRunEFuncs(servoCmd.RequireConfigFileFlagToExistRunE, servoCmd.RequireInitRunE)(cmd, args)
+		},
+		RunE: servoCmd.RunServoConfigValidate,
+	}
+	addServoSchemaFlags(validateCmd)
+	configCmd.AddCommand(validateCmd)
+
+	return configCmd
+}
+
+// RunServoConfigEdit edits the servo config until it is valid YAML, then writes it back and restarts the servo
+func (servoCmd *servoCommand) RunServoConfigEdit(c *cobra.Command, _ []string) error {
+	driver, err := servoCmd.servoDriver()
+	if driver == nil {
+		return err
+	}
+	config, err := driver.ConfigData()
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := ioutil.TempFile(os.TempDir(), "servo-*.yaml")
+	if err != nil {
+		return err
+	}
+	filename := tempFile.Name()
+	defer os.Remove(filename)
+	if err = tempFile.Close(); err != nil {
+		return err
+	}
+
+	schema, err := servoSchemaFromFlags(c)
+	if err != nil {
+		return err
+	}
+
+	// Edit until the config is valid or the edit is cancelled
+	editor, _ := c.Flags().GetString("editor")
+	format := configEditFormats["yaml"]
+	buffer := config
+	for {
+		if err = ioutil.WriteFile(filename, buffer, 0600); err != nil {
+			return err
+		}
+		if err = openFileInEditor(filename, editor); err != nil {
+			return err
+		}
+		if buffer, err = ioutil.ReadFile(filename); err != nil {
+			return err
+		}
+		buffer = format.stripAnnotations(buffer)
+		if len(strings.TrimSpace(string(buffer))) == 0 {
+			return fmt.Errorf("edit cancelled: config is empty")
+		}
+		if err = ValidateServoConfig(buffer, schema); err != nil {
+			buffer = format.annotate(buffer, err.Error())
+			continue
+		}
+		break
+	}
+
+	if bytes.Equal(bytes.TrimSpace(buffer), bytes.TrimSpace(config)) {
+		servoCmd.Println("Servo config unchanged")
+		return nil
+	}
+	return servoCmd.applyServoConfig(c, driver, buffer)
+}
+
+// applyServoConfig writes the config to the servo and restarts it unless --no-restart is set
+func (servoCmd *servoCommand) applyServoConfig(c *cobra.Command, driver ServoDriver, config []byte) error {
+	if err := driver.SetConfigData(config); err != nil {
+		return err
+	}
+	servoCmd.Println("Servo config updated")
+
+	if noRestart, _ := c.Flags().GetBool("no-restart"); noRestart {
+		servoCmd.Println("Restart the servo with `opsani servo restart` to apply the config")
+		return nil
+	}
+	if err := driver.Restart(); err != nil {
+		return err
+	}
+	servoCmd.Println("Servo restarted")
+	return nil
+}
+
+// RunServoConfigValidate validates a servo config file or the config of the active servo
+func (servoCmd *servoCommand) RunServoConfigValidate(c *cobra.Command, args []string) error {
+	schema, err := servoSchemaFromFlags(c)
+	if err != nil {
+		return err
+	}
+
+	var config []byte
+	if len(args) > 0 {
+		if config, err = ioutil.ReadFile(args[0]); err != nil {
+			return err
+		}
+	} else {
+		driver, err := servoCmd.servoDriver()
+		if driver == nil {
+			return err
+		}
+		if config, err = driver.ConfigData(); err != nil {
+			return err
+		}
+	}
+
+	if err := ValidateServoConfig(config, schema); err != nil {
+		return err
+	}
+	servoCmd.Println("Servo config is valid")
+	return nil
+}
+
+// addServoSchemaFlags registers the flags for selecting the schema servo configs are validated against
+func addServoSchemaFlags(cmd *cobra.Command) {
+	cmd.Flags().String("schema", "", "Validate against the JSON schema in `FILE`, such as the output of `servo schema`")
+	cmd.Flags().Bool("skip-schema", false, "Only check that the config is valid YAML")
+}
+
+// servoSchemaFromFlags returns the schema selected by the --schema and --skip-schema flags
+// A nil schema selects the embedded schema and an empty schema disables schema validation
+func servoSchemaFromFlags(c *cobra.Command) ([]byte, error) {
+	if skip, _ := c.Flags().GetBool("skip-schema"); skip {
+		return []byte{}, nil
+	}
+	path, _ := c.Flags().GetString("schema")
+	if path == "" {
+		return nil, nil
+	}
+	return ioutil.ReadFile(path)
+}
+
+// ValidateServoConfig returns an error if the config is not a YAML mapping or does not conform to the schema
+// The embedded servo config schema is used when schema is nil and schema validation is skipped when it is empty
+func ValidateServoConfig(config []byte, schema []byte) error {
+	var obj interface{}
+	if err := yaml.Unmarshal(config, &obj); err != nil {
+		return fmt.Errorf("invalid YAML: %w", err)
+	}
+	if _, ok := obj.(map[string]interface{}); !ok {
+		return fmt.Errorf("invalid servo config: must be a mapping of connector names to settings")
+	}
+	if schema != nil && len(schema) == 0 {
+		return nil
+	}
+	return ValidateServoConfigSchema(obj, schema)
+}
+
+// SetConfigData patches the ConfigMap that supplies the servo config file
+// Config files mounted with subPath are not refreshed in running pods, so the servo must be restarted to pick up changes
+func (c *KubernetesServoDriver) SetConfigData(data []byte) error {
+	ctx, cancel := contextWithTimeout(c.timeout)
+	defer cancel()
+	output := new(bytes.Buffer)
+	cmd := c.kubectl(ctx, "-n", c.servo.Namespace, "get", "deployment/"+c.servo.Deployment, "-o", "json")
+	cmd.Stdout = output
+	cmd.Stderr = os.Stderr
+	if err := runCommand(cmd); err != nil {
+		return err
+	}
+	name, key, err := ServoConfigMapRef(output.Bytes())
+	if err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{"data": map[string]string{key: string(data)}})
+	if err != nil {
+		return err
+	}
+	cmd = c.kubectl(ctx, "-n", c.servo.Namespace, "patch", "configmap", name, "--type", "merge", "-p", string(patch))
+	cmd.Stdout = ioutil.Discard
+	cmd.Stderr = os.Stderr
+	return runCommand(cmd)
+}
+
+// ServoConfigMapRef returns the name of the ConfigMap and the key within it that is mounted as the servo config file
+func ServoConfigMapRef(deployment []byte) (string, string, error) {
+	podSpec := gjson.GetBytes(deployment, "spec.template.spec")
+	for _, container := range podSpec.Get("containers").Array() {
+		for _, mount := range container.Get("volumeMounts").Array() {
+			mountPath := mount.Get("mountPath").String()
+			key := mount.Get("subPath").String()
+			if mountPath == path.Dir(servoConfigPath) {
+				key = path.Base(servoConfigPath)
+			} else if mountPath != servoConfigPath {
+				continue
+			}
+
+			for _, volume := range podSpec.Get("volumes").Array() {
+				if volume.Get("name").String() != mount.Get("name").String() || !volume.Get("configMap").Exists() {
+					continue
+				}
+				// Keys projected to other paths are mapped by items
+				for _, item := range volume.Get("configMap.items").Array() {
+					if item.Get("path").String() == key {
+						key = item.Get("key").String()
+					}
+				}
+				if key == "" {
+					key = path.Base(servoConfigPath)
+				}
+				return volume.Get("configMap.name").String(), key, nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("no ConfigMap is mounted at %s in the servo deployment", servoConfigPath)
+}
+
+// SetConfigData replaces the servo config file over SSH
+func (c *DockerComposeServoDriver) SetConfigData(data []byte) error {
+	ctx, cancel := contextWithTimeout(c.timeout)
+	defer cancel()
+	return c.runInSSHSession(ctx, func(ctx context.Context, session *ssh.Session) error {
+		session.Stdin = bytes.NewReader(data)
+		session.Stderr = os.Stderr
+
+		args := []string{}
+		if dir := c.servo.Path; dir != "" {
+			args = append(args, "cd", dir+"&&")
+		}
+		args = append(args, "cat", ">", "config.yaml")
+		return session.Run(strings.Join(args, " "))
+	})
+}
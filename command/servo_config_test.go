@@ -0,0 +1,241 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ServoConfigTestSuite struct {
+	test.Suite
+}
+
+func TestServoConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(ServoConfigTestSuite))
+}
+
+func (s *ServoConfigTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+}
+
+func (s *ServoConfigTestSuite) TearDownTest() {
+	command.SetServoDriverFactory(nil)
+	command.SetCommandContextFunc(nil)
+}
+
+// editorScript writes an executable shell script for use as $EDITOR
+func (s *ServoConfigTestSuite) editorScript(script string) string {
+	if runtime.GOOS == "windows" {
+		s.T().Skip("editor scripts require a POSIX shell")
+	}
+	dir, err := ioutil.TempDir("", "opsani-editor")
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "editor")
+	s.Require().NoError(ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script+"\nrm -f \"$1.bak\"\n"), 0755))
+	return path
+}
+
+const servoConfigYAML = "vegeta:\n  rate: 50/1s\n"
+
+const servoDeploymentJSON = `{"spec": {"template": {"spec": {
+	"containers": [{"name": "servo", "volumeMounts": [
+		{"name": "servo-token-volume", "mountPath": "/servo/opsani.token", "subPath": "opsani.token"},
+		{"name": "servo-config-volume", "mountPath": "/servo/config.yaml", "subPath": "config.yaml"}
+	]}],
+	"volumes": [
+		{"name": "servo-token-volume", "secret": {"secretName": "servo-token"}},
+		{"name": "servo-config-volume", "configMap": {"name": "servo-config"}}
+	]
+}}}}`
+
+func (s *ServoConfigTestSuite) TestEditKubernetesServoConfig() {
+	recorder := test.NewExecRecorder()
+	recorder.Respond("kubectl -n opsani exec", test.ExecResponse{Stdout: servoConfigYAML})
+	recorder.Respond("kubectl -n opsani get deployment/servo", test.ExecResponse{Stdout: servoDeploymentJSON})
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	editor := s.editorScript(`sed -i.bak 's#50/1s#100/1s#' "$1"`)
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "config", "edit", "--editor", editor)
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Servo config updated\nServo restarted\n")
+	s.Require().Equal([][]string{
+		{"kubectl", "-n", "opsani", "exec", "deployment/servo", "--", "cat", "/servo/config.yaml"},
+		{"kubectl", "-n", "opsani", "get", "deployment/servo", "-o", "json"},
+		{"kubectl", "-n", "opsani", "patch", "configmap", "servo-config", "--type", "merge", "-p", `{"data":{"config.yaml":"vegeta:\n  rate: 100/1s\n"}}`},
+		{"kubectl", "-n", "opsani", "rollout", "restart", "deployment/servo"},
+	}, recorder.Invocations())
+}
+
+func (s *ServoConfigTestSuite) TestEditServoConfigNoRestart() {
+	driver := test.NewFakeServoDriver()
+	driver.ServoConfig = []byte(servoConfigYAML)
+	command.SetServoDriverFactory(driver.Factory())
+
+	editor := s.editorScript(`sed -i.bak 's#50/1s#100/1s#' "$1"`)
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "config", "edit", "--editor", editor, "--no-restart")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Restart the servo with `opsani servo restart` to apply the config")
+	s.Require().Equal([]string{"ConfigData", "SetConfigData"}, driver.Calls())
+	s.Require().Equal("vegeta:\n  rate: 100/1s\n", string(driver.ServoConfig))
+}
+
+func (s *ServoConfigTestSuite) TestEditServoConfigReopensInvalidYAML() {
+	driver := test.NewFakeServoDriver()
+	driver.ServoConfig = []byte(servoConfigYAML)
+	command.SetServoDriverFactory(driver.Factory())
+
+	// Break the config on the first edit and fix it once annotated with the error
+	editor := s.editorScript(`if grep -q "Error: invalid YAML" "$1"; then
+  sed -i.bak 's#rate: \[#rate: 100/1s#' "$1"
+else
+  sed -i.bak 's#rate: 50/1s#rate: [#' "$1"
+fi`)
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "config", "edit", "--editor", editor)
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"ConfigData", "SetConfigData", "Restart"}, driver.Calls())
+	s.Require().Equal("vegeta:\n  rate: 100/1s\n", string(driver.ServoConfig))
+}
+
+func (s *ServoConfigTestSuite) TestEditServoConfigReopensSchemaErrors() {
+	driver := test.NewFakeServoDriver()
+	driver.ServoConfig = []byte(servoConfigYAML)
+	command.SetServoDriverFactory(driver.Factory())
+
+	// Misspell the connector on the first edit and correct it once annotated with the error
+	editor := s.editorScript(`if grep -q 'Error: vegata: unknown connector (did you mean "vegeta"?)' "$1"; then
+  sed -i.bak 's#^vegata:#vegeta:#; s#50/1s#100/1s#' "$1"
+else
+  sed -i.bak 's#^vegeta:#vegata:#' "$1"
+fi`)
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "config", "edit", "--editor", editor, "--no-restart")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"ConfigData", "SetConfigData"}, driver.Calls())
+	s.Require().Equal("vegeta:\n  rate: 100/1s\n", string(driver.ServoConfig))
+}
+
+func (s *ServoConfigTestSuite) TestEditServoConfigUnchanged() {
+	driver := test.NewFakeServoDriver()
+	driver.ServoConfig = []byte(servoConfigYAML)
+	command.SetServoDriverFactory(driver.Factory())
+
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "config", "edit", "--editor", s.editorScript("true"))
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Servo config unchanged")
+	s.Require().Equal([]string{"ConfigData"}, driver.Calls())
+}
+
+func TestServoConfigMapRef(t *testing.T) {
+	name, key, err := command.ServoConfigMapRef([]byte(servoDeploymentJSON))
+	require.NoError(t, err)
+	require.Equal(t, "servo-config", name)
+	require.Equal(t, "config.yaml", key)
+
+	name, key, err = command.ServoConfigMapRef([]byte(`{"spec": {"template": {"spec": {
+		"containers": [{"volumeMounts": [{"name": "config", "mountPath": "/servo"}]}],
+		"volumes": [{"name": "config", "configMap": {"name": "opsani-servo", "items": [{"key": "servo.yaml", "path": "config.yaml"}]}}]
+	}}}}`))
+	require.NoError(t, err)
+	require.Equal(t, "opsani-servo", name)
+	require.Equal(t, "servo.yaml", key)
+
+	_, _, err = command.ServoConfigMapRef([]byte(`{"spec": {"template": {"spec": {"containers": [{}]}}}}`))
+	require.EqualError(t, err, "no ConfigMap is mounted at /servo/config.yaml in the servo deployment")
+}
+
+func TestValidateServoConfig(t *testing.T) {
+	require.NoError(t, command.ValidateServoConfig([]byte(servoConfigYAML), nil))
+	require.Error(t, command.ValidateServoConfig([]byte("vegeta: ["), nil))
+	require.EqualError(t, command.ValidateServoConfig([]byte("- vegeta"), nil), "invalid servo config: must be a mapping of connector names to settings")
+}
+
+func TestValidateServoConfigSchema(t *testing.T) {
+	demo, err := ioutil.ReadFile(filepath.Join("..", "demo", "config.yaml"))
+	require.NoError(t, err)
+	require.NoError(t, command.ValidateServoConfig(demo, nil))
+
+	err = command.ValidateServoConfig([]byte(`vegata:
+  rate: 50/1s
+opsani_dev:
+  namespace: default
+  deployment: web
+  container: main
+  service: web
+  cpu:
+    min: 250m
+    max: 4
+    stp: 125m
+  memory:
+    min: 256 MiB
+    max: -1
+kubernetes:
+  deployments:
+  - name: web
+    replicas:
+      min: 0
+      max: 2.5
+    containers:
+    - cpu:
+        min: lots
+`), nil)
+	require.EqualError(t, err, `invalid servo config:
+kubernetes.deployments[0].containers[0]: missing required key "name"
+kubernetes.deployments[0].containers[0].cpu.min: "lots" is not a valid value
+kubernetes.deployments[0].replicas.max: expected integer, got number
+opsani_dev.cpu.stp: unknown key (did you mean "step"?)
+opsani_dev.memory.max: must be at least 0
+vegata: unknown connector (did you mean "vegeta"?)`)
+
+	// Schemas exported by the servo take precedence and skipping disables the check
+	schema := []byte(`{"type": "object", "properties": {"custom": {"type": "object"}}, "additionalProperties": false}`)
+	require.NoError(t, command.ValidateServoConfig([]byte("custom: {}"), schema))
+	require.EqualError(t, command.ValidateServoConfig([]byte("vegeta: {}"), schema), "invalid servo config:\nvegeta: unknown connector")
+	require.NoError(t, command.ValidateServoConfig([]byte("custom: {}"), []byte{}))
+}
+
+func (s *ServoConfigTestSuite) TestValidateServoConfigFile() {
+	dir, err := ioutil.TempDir("", "servo-config")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	s.Require().NoError(ioutil.WriteFile(path, []byte("vegeta:\n  rate: fast\n"), 0644))
+
+	_, err = s.Execute("servo", "config", "validate", path)
+	s.Require().EqualError(err, "invalid servo config:\nvegeta.rate: \"fast\" is not a valid value")
+
+	output, err := s.Execute("servo", "config", "validate", path, "--skip-schema")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Servo config is valid")
+}
+
+func (s *ServoConfigTestSuite) TestValidateActiveServoConfig() {
+	driver := test.NewFakeServoDriver()
+	driver.ServoConfig = []byte(servoConfigYAML)
+	command.SetServoDriverFactory(driver.Factory())
+
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "config", "validate")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Servo config is valid")
+	s.Require().Equal([]string{"ConfigData"}, driver.Calls())
+}
@@ -0,0 +1,325 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// servoConnectorsKey is the servo config key that selects the active connectors
+const servoConnectorsKey = "connectors"
+
+// servoSettingsKeys are top-level servo config keys that hold settings rather than connectors
+var servoSettingsKeys = []string{servoConnectorsKey, "optimizer"}
+
+// ServoConnector describes a connector configured in a servo config file
+type ServoConnector struct {
+	Name    string
+	Enabled bool
+}
+
+// NewServoConnectorsCommand returns a new `opsani servo connectors` command instance
+func NewServoConnectorsCommand(servoCmd *servoCommand) *cobra.Command {
+	connectorsCmd := &cobra.Command{
+		Use:   "connectors",
+		Short: "Manage servo connectors",
+		Long: `Manage the connectors run by the servo.
+
+Connectors are enabled and disabled through the connectors list of the servo config. The
+settings of disabled connectors are kept so that they can be enabled again later.`,
+		Args: cobra.NoArgs,
+	}
+
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List servo connectors",
+		Args:    cobra.NoArgs,
+		RunE:    servoCmd.RunServoConnectorsList,
+	}
+	AddOutputFlag(listCmd, TabularOutputFormats...)
+	connectorsCmd.AddCommand(listCmd)
+
+	enableCmd := &cobra.Command{
+		Use:   "enable NAME...",
+		Short: "Enable servo connectors",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			return servoCmd.RunServoConnectorsToggle(c, args, true)
+		},
+	}
+	enableCmd.Flags().Bool("no-restart", false, "Write the config without restarting the servo")
+	connectorsCmd.AddCommand(enableCmd)
+
+	disableCmd := &cobra.Command{
+		Use:   "disable NAME...",
+		Short: "Disable servo connectors",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			return servoCmd.RunServoConnectorsToggle(c, args, false)
+		},
+	}
+	disableCmd.Flags().Bool("no-restart", false, "Write the config without restarting the servo")
+	connectorsCmd.AddCommand(disableCmd)
+
+	return connectorsCmd
+}
+
+// RunServoConnectorsList lists the connectors of the servo config and whether they are enabled
+func (servoCmd *servoCommand) RunServoConnectorsList(c *cobra.Command, _ []string) error {
+	output, err := OutputFormat(c, TabularOutputFormats...)
+	if err != nil {
+		return err
+	}
+	driver, err := servoCmd.servoDriver()
+	if driver == nil {
+		return err
+	}
+	config, err := driver.ConfigData()
+	if err != nil {
+		return err
+	}
+	connectors, err := ServoConnectors(config)
+	if err != nil {
+		return err
+	}
+
+	table := Table{Headers: []string{"NAME", "STATUS"}}
+	for _, connector := range connectors {
+		status := "disabled"
+		if connector.Enabled {
+			status = "enabled"
+		}
+		table.Rows = append(table.Rows, []string{connector.Name, status})
+	}
+	return servoCmd.RenderTable(output, table)
+}
+
+// RunServoConnectorsToggle enables or disables connectors and applies the updated config
+func (servoCmd *servoCommand) RunServoConnectorsToggle(c *cobra.Command, names []string, enabled bool) error {
+	driver, err := servoCmd.servoDriver()
+	if driver == nil {
+		return err
+	}
+	config, err := driver.ConfigData()
+	if err != nil {
+		return err
+	}
+	updated, err := SetServoConnectorsEnabled(config, names, enabled)
+	if err != nil {
+		return err
+	}
+	if string(updated) == string(config) {
+		servoCmd.Println("Servo connectors unchanged")
+		return nil
+	}
+	return servoCmd.applyServoConfig(c, driver, updated)
+}
+
+// ServoConnectors returns the connectors configured in a servo config in the order they appear
+// All configured connectors are enabled unless the config selects them with a connectors list
+func ServoConnectors(config []byte) ([]ServoConnector, error) {
+	doc, err := parseServoConfigDocument(config)
+	if err != nil {
+		return nil, err
+	}
+	configured := configuredServoConnectors(doc)
+	active, selected := activeServoConnectors(doc)
+	connectors := []ServoConnector{}
+	for _, name := range configured {
+		connectors = append(connectors, ServoConnector{Name: name, Enabled: !selected || containsString(active, name)})
+	}
+	// Connectors may be enabled without settings of their own
+	for _, name := range active {
+		if !containsString(configured, name) {
+			connectors = append(connectors, ServoConnector{Name: name, Enabled: true})
+		}
+	}
+	return connectors, nil
+}
+
+// SetServoConnectorsEnabled returns the config with the named connectors enabled or disabled
+// The config is returned unchanged when the connectors are already in the requested state
+func SetServoConnectorsEnabled(config []byte, names []string, enabled bool) ([]byte, error) {
+	doc, err := parseServoConfigDocument(config)
+	if err != nil {
+		return nil, err
+	}
+	configured := configuredServoConnectors(doc)
+	active, selected := activeServoConnectors(doc)
+	if !selected {
+		active = append([]string{}, configured...)
+	}
+	known := append(append([]string{}, configured...), active...)
+
+	changed := false
+	for _, name := range names {
+		if !containsString(known, name) {
+			if enabled {
+				return nil, fmt.Errorf("connector %q is not configured: add a %q section to the servo config with `opsani servo config edit`", name, name)
+			}
+			message := fmt.Sprintf("unknown connector %q", name)
+			if suggestions := suggestionsFor(name, known); len(suggestions) > 0 {
+				message += fmt.Sprintf(" (did you mean %q?)", suggestions[0])
+			}
+			return nil, errors.New(message)
+		}
+		if enabled && !containsString(active, name) {
+			active = append(active, name)
+			changed = true
+		} else if !enabled && containsString(active, name) {
+			active = removeString(active, name)
+			changed = true
+		}
+	}
+	if !changed {
+		return config, nil
+	}
+	if len(active) == 0 {
+		return nil, fmt.Errorf("at least one connector must remain enabled")
+	}
+
+	// Drop the connectors list once it selects every configured connector again
+	if stringSlicesHaveSameElements(active, configured) {
+		doc = deleteMapSliceKey(doc, servoConnectorsKey)
+	} else {
+		doc = setMapSliceValue(doc, servoConnectorsKey, servoConnectorsValue(doc, active))
+	}
+	return yaml.Marshal(doc)
+}
+
+// parseServoConfigDocument parses a servo config preserving the order of its keys
+func parseServoConfigDocument(config []byte) (yaml.MapSlice, error) {
+	doc := yaml.MapSlice{}
+	if err := yaml.Unmarshal(config, &doc); err != nil {
+		return nil, fmt.Errorf("invalid servo config: %w", err)
+	}
+	return doc, nil
+}
+
+// configuredServoConnectors returns the names of the connector sections of a servo config
+func configuredServoConnectors(doc yaml.MapSlice) []string {
+	names := []string{}
+	for _, item := range doc {
+		name := fmt.Sprint(item.Key)
+		if !containsString(servoSettingsKeys, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// activeServoConnectors returns the connectors selected by the connectors key and whether the key is present
+// The key may be a list of connector names or a mapping of connector names to connector types
+func activeServoConnectors(doc yaml.MapSlice) ([]string, bool) {
+	for _, item := range doc {
+		if fmt.Sprint(item.Key) != servoConnectorsKey {
+			continue
+		}
+		names := []string{}
+		switch value := item.Value.(type) {
+		case []interface{}:
+			for _, name := range value {
+				names = append(names, fmt.Sprint(name))
+			}
+		case yaml.MapSlice:
+			for _, entry := range value {
+				names = append(names, fmt.Sprint(entry.Key))
+			}
+		}
+		return names, true
+	}
+	return nil, false
+}
+
+// servoConnectorsValue returns the value of the connectors key selecting the active connectors
+// Mappings of connector names to types are preserved, with newly enabled connectors named after their type
+func servoConnectorsValue(doc yaml.MapSlice, active []string) interface{} {
+	for _, item := range doc {
+		mapping, ok := item.Value.(yaml.MapSlice)
+		if fmt.Sprint(item.Key) != servoConnectorsKey || !ok {
+			continue
+		}
+		value := yaml.MapSlice{}
+		for _, name := range active {
+			connectorType := interface{}(name)
+			for _, entry := range mapping {
+				if fmt.Sprint(entry.Key) == name {
+					connectorType = entry.Value
+				}
+			}
+			value = append(value, yaml.MapItem{Key: name, Value: connectorType})
+		}
+		return value
+	}
+	return active
+}
+
+func setMapSliceValue(doc yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i, item := range doc {
+		if fmt.Sprint(item.Key) == key {
+			doc[i].Value = value
+			return doc
+		}
+	}
+	return append(doc, yaml.MapItem{Key: key, Value: value})
+}
+
+func deleteMapSliceKey(doc yaml.MapSlice, key string) yaml.MapSlice {
+	result := yaml.MapSlice{}
+	for _, item := range doc {
+		if fmt.Sprint(item.Key) != key {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func removeString(values []string, value string) []string {
+	result := []string{}
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func stringSlicesHaveSameElements(a, b []string) bool {
+	for _, v := range a {
+		if !containsString(b, v) {
+			return false
+		}
+	}
+	for _, v := range b {
+		if !containsString(a, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const connectorsServoConfigYAML = `kubernetes:
+  namespace: default
+prometheus:
+  base_url: http://prometheus:9090/
+vegeta:
+  rate: 50/1s
+`
+
+type ServoConnectorsTestSuite struct {
+	test.Suite
+	driver *test.FakeServoDriver
+}
+
+func TestServoConnectorsTestSuite(t *testing.T) {
+	suite.Run(t, new(ServoConnectorsTestSuite))
+}
+
+func (s *ServoConnectorsTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+	s.driver = test.NewFakeServoDriver()
+	s.driver.ServoConfig = []byte(connectorsServoConfigYAML)
+	command.SetServoDriverFactory(s.driver.Factory())
+}
+
+func (s *ServoConnectorsTestSuite) TearDownTest() {
+	command.SetServoDriverFactory(nil)
+}
+
+func (s *ServoConnectorsTestSuite) TestList() {
+	s.driver.ServoConfig = []byte(connectorsServoConfigYAML + "connectors:\n- kubernetes\n- prometheus\n")
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "connectors", "list", "-o", "csv")
+	s.Require().NoError(err)
+	s.Require().Equal("NAME,STATUS\nkubernetes,enabled\nprometheus,enabled\nvegeta,disabled\n", output)
+}
+
+func (s *ServoConnectorsTestSuite) TestDisable() {
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "connectors", "disable", "vegeta")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Servo config updated\nServo restarted\n")
+	s.Require().Equal([]string{"ConfigData", "SetConfigData", "Restart"}, s.driver.Calls())
+	s.Require().Equal(connectorsServoConfigYAML+"connectors:\n- kubernetes\n- prometheus\n", string(s.driver.ServoConfig))
+}
+
+func (s *ServoConnectorsTestSuite) TestEnableNoRestart() {
+	s.driver.ServoConfig = []byte(connectorsServoConfigYAML + "connectors:\n- kubernetes\n")
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "connectors", "enable", "vegeta", "--no-restart")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"ConfigData", "SetConfigData"}, s.driver.Calls())
+	s.Require().Equal(connectorsServoConfigYAML+"connectors:\n- kubernetes\n- vegeta\n", string(s.driver.ServoConfig))
+}
+
+func (s *ServoConnectorsTestSuite) TestEnableUnchanged() {
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "connectors", "enable", "vegeta")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Servo connectors unchanged")
+	s.Require().Equal([]string{"ConfigData"}, s.driver.Calls())
+}
+
+func (s *ServoConnectorsTestSuite) TestEnableUnconfigured() {
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "connectors", "enable", "datadog")
+	s.Require().EqualError(err, "connector \"datadog\" is not configured: add a \"datadog\" section to the servo config with `opsani servo config edit`")
+	s.Require().Equal([]string{"ConfigData"}, s.driver.Calls())
+}
+
+func TestSetServoConnectorsEnabled(t *testing.T) {
+	config := []byte(connectorsServoConfigYAML + "connectors:\n- kubernetes\n- prometheus\n")
+
+	// Enabling every configured connector drops the connectors list
+	updated, err := command.SetServoConnectorsEnabled(config, []string{"vegeta"}, true)
+	require.NoError(t, err)
+	require.Equal(t, connectorsServoConfigYAML, string(updated))
+
+	_, err = command.SetServoConnectorsEnabled(config, []string{"vegta"}, false)
+	require.EqualError(t, err, `unknown connector "vegta" (did you mean "vegeta"?)`)
+
+	_, err = command.SetServoConnectorsEnabled(config, []string{"kubernetes", "prometheus"}, false)
+	require.EqualError(t, err, "at least one connector must remain enabled")
+
+	// Mappings of connector names to types are preserved
+	mapped := []byte(connectorsServoConfigYAML + "connectors:\n  kubernetes: kubernetes\n  load: vegeta\n")
+	updated, err = command.SetServoConnectorsEnabled(mapped, []string{"prometheus"}, true)
+	require.NoError(t, err)
+	require.Equal(t, connectorsServoConfigYAML+"connectors:\n  kubernetes: kubernetes\n  load: vegeta\n  prometheus: prometheus\n", string(updated))
+
+	connectors, err := command.ServoConnectors(mapped)
+	require.NoError(t, err)
+	require.Equal(t, []command.ServoConnector{
+		{Name: "kubernetes", Enabled: true},
+		{Name: "prometheus", Enabled: false},
+		{Name: "vegeta", Enabled: false},
+		{Name: "load", Enabled: true},
+	}, connectors)
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Lines of previous container logs captured when diagnosing a crash loop
+// Capture starts at the minimum and doubles until a cause is identified or the logs are exhausted
+const (
+	minCrashLoopLogLines = 50
+	maxCrashLoopLogLines = 1600
+)
+
+// crashLoopSummaryLines is the number of log lines included in a crash loop summary
+const crashLoopSummaryLines = 10
+
+// CrashLoop describes a servo container restarting in CrashLoopBackOff
+type CrashLoop struct {
+	Pod       string
+	Container string
+	Restarts  int64
+	ExitCode  int64
+	// Reason and Message describe the last termination of the container
+	Reason  string
+	Message string
+	// Logs is the output of the previous instance of the container
+	Logs  string
+	Cause string
+}
+
+// crashLoopCauses maps patterns in termination messages and logs to likely causes of a crash loop
+var crashLoopCauses = []struct {
+	pattern *regexp.Regexp
+	cause   string
+}{
+	{regexp.MustCompile(`(?i)\b401\b|unauthorized|invalid (api )?token|authentication failed`),
+		"bad token: the Opsani API rejected the servo token, compare it with the profile using `opsani check link`"},
+	{regexp.MustCompile(`(?i)prometheus.*(connection refused|no such host|timed? ?out|unreachable|connecterror)|(connection refused|no such host|timed? ?out).*prometheus`),
+		"unreachable Prometheus: the servo cannot connect to the Prometheus endpoint in its config"},
+	{regexp.MustCompile(`(?i)\bforbidden\b|cannot (get|list|watch|patch|update|create) resource|\b403\b`),
+		"RBAC denied: the servo service account lacks permissions on the optimized resources"},
+	{regexp.MustCompile(`(?i)\bOOMKilled\b`),
+		"out of memory: raise the memory limit of the servo deployment"},
+}
+
+// DiagnoseCrashLoop returns the most likely cause of a crash loop from the termination reason, message, and logs
+// An empty string is returned when no known cause matches
+func DiagnoseCrashLoop(reason, message, logs string) string {
+	for _, text := range []string{reason, message, logs} {
+		for _, candidate := range crashLoopCauses {
+			if candidate.pattern.MatchString(text) {
+				return candidate.cause
+			}
+		}
+	}
+	return ""
+}
+
+// crashLoopsFromJSON returns the containers in CrashLoopBackOff in a kubectl pod list
+func crashLoopsFromJSON(data []byte) []CrashLoop {
+	loops := []CrashLoop{}
+	for _, pod := range gjson.GetBytes(data, "items").Array() {
+		for _, status := range pod.Get("status.containerStatuses").Array() {
+			if status.Get("state.waiting.reason").String() != "CrashLoopBackOff" {
+				continue
+			}
+			terminated := status.Get("lastState.terminated")
+			loops = append(loops, CrashLoop{
+				Pod:       pod.Get("metadata.name").String(),
+				Container: status.Get("name").String(),
+				Restarts:  status.Get("restartCount").Int(),
+				ExitCode:  terminated.Get("exitCode").Int(),
+				Reason:    terminated.Get("reason").String(),
+				Message:   strings.TrimSpace(terminated.Get("message").String()),
+			})
+		}
+	}
+	return loops
+}
+
+// Summary renders a concise description of the failure
+func (loop CrashLoop) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Container %s of pod %s is in CrashLoopBackOff (%d restarts, last exit code %d", loop.Container, loop.Pod, loop.Restarts, loop.ExitCode)
+	if loop.Reason != "" {
+		fmt.Fprintf(&b, ": %s", loop.Reason)
+	}
+	b.WriteString(")\n")
+	if loop.Message != "" {
+		fmt.Fprintf(&b, "  Termination message: %s\n", loop.Message)
+	}
+	cause := loop.Cause
+	if cause == "" {
+		cause = "unknown, review the logs of the previous container with `kubectl logs --previous`"
+	}
+	fmt.Fprintf(&b, "  Likely cause: %s\n", cause)
+	if lines := strings.Split(strings.TrimRight(loop.Logs, "\n"), "\n"); loop.Logs != "" {
+		if len(lines) > crashLoopSummaryLines {
+			lines = lines[len(lines)-crashLoopSummaryLines:]
+		}
+		b.WriteString("  Last log lines:\n")
+		for _, line := range lines {
+			fmt.Fprintf(&b, "    %s\n", line)
+		}
+	}
+	return b.String()
+}
+
+// crashLoops returns the servo containers in CrashLoopBackOff
+func (c *KubernetesServoDriver) crashLoops(ctx context.Context) ([]CrashLoop, error) {
+	output, err := c.kubectlOutput(ctx, "-n", c.servo.Namespace, "get", "deployment", c.servo.Deployment, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	labels := gjson.GetBytes(output, "spec.selector.matchLabels").Map()
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	selector := []string{}
+	for key, value := range labels {
+		selector = append(selector, key+"="+value.String())
+	}
+	sort.Strings(selector)
+
+	output, err = c.kubectlOutput(ctx, "-n", c.servo.Namespace, "get", "pods", "-l", strings.Join(selector, ","), "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return crashLoopsFromJSON(output), nil
+}
+
+// diagnoseCrashLoop captures the logs of the previous container and identifies the likely cause of the crash loop
+// The captured history doubles until a cause is found or the previous container has no more output
+func (c *KubernetesServoDriver) diagnoseCrashLoop(ctx context.Context, loop *CrashLoop) {
+	loop.Cause = DiagnoseCrashLoop(loop.Reason, loop.Message, "")
+	for lines := minCrashLoopLogLines; lines <= maxCrashLoopLogLines; lines *= 2 {
+		output, err := c.kubectlOutput(ctx, "-n", c.servo.Namespace, "logs", loop.Pod, "-c", loop.Container, "--previous", "--tail="+strconv.Itoa(lines))
+		if err != nil {
+			return
+		}
+		loop.Logs = string(output)
+		if loop.Cause == "" {
+			loop.Cause = DiagnoseCrashLoop("", "", loop.Logs)
+		}
+		if loop.Cause != "" || bytes.Count(output, []byte("\n")) < lines {
+			return
+		}
+	}
+}
+
+// kubectlOutput runs kubectl against the servo cluster and returns its stdout
+func (c *KubernetesServoDriver) kubectlOutput(ctx context.Context, args ...string) ([]byte, error) {
+	return commandOutput(c.kubectl(ctx, args...))
+}
+
+// writeCrashLoops outputs a failure summary for each servo container in CrashLoopBackOff
+func (c *KubernetesServoDriver) writeCrashLoops(w io.Writer) {
+	ctx, cancel := contextWithTimeout(c.timeout)
+	defer cancel()
+	loops, err := c.crashLoops(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: unable to check the servo pods for crash loops: %s\n", err)
+		return
+	}
+	for _, loop := range loops {
+		c.diagnoseCrashLoop(ctx, &loop)
+		fmt.Fprintf(w, "\n%s", loop.Summary())
+	}
+}
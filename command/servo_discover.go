@@ -0,0 +1,179 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+)
+
+// annotationServoOptimizer names the optimizer that a servo deployment reports to
+const annotationServoOptimizer = "servo.opsani.com/optimizer"
+
+// DiscoveredServo is a servo deployment found in a cluster
+type DiscoveredServo struct {
+	Servo     Servo
+	Optimizer string
+}
+
+// discoveredServosFromJSON returns the servos described by a kubectl deployment list
+// The optimizer is read from the servo annotation or, for servos deployed without it, the OPSANI_OPTIMIZER variable
+func discoveredServosFromJSON(data []byte, kubeContext string) []DiscoveredServo {
+	servos := []DiscoveredServo{}
+	for _, item := range gjson.GetBytes(data, "items").Array() {
+		optimizer := item.Get("metadata.annotations").Get(strings.ReplaceAll(annotationServoOptimizer, ".", `\.`)).String()
+		for _, container := range item.Get("spec.template.spec.containers").Array() {
+			if optimizer == "" {
+				optimizer = container.Get(`env.#(name=="OPSANI_OPTIMIZER").value`).String()
+			}
+		}
+		if normalized, err := NormalizeOptimizer(optimizer); err == nil {
+			optimizer = normalized
+		}
+		servos = append(servos, DiscoveredServo{
+			Servo: Servo{
+				Type:            "kubernetes",
+				Context:         kubeContext,
+				Namespace:       item.Get("metadata.namespace").String(),
+				Deployment:      item.Get("metadata.name").String(),
+				ServoDeployment: item.Get(fmt.Sprintf(`metadata.ownerReferences.#(kind==%q).name`, ServoDeploymentKind)).String(),
+			},
+			Optimizer: optimizer,
+		})
+	}
+	return servos
+}
+
+// DiscoverServos lists the servo deployments in the namespaces accessible with the kubeconfig context
+// Servos are listed in the namespace of the context when the context cannot list deployments cluster-wide
+func (servoCmd *servoCommand) DiscoverServos(kubeContext, selector string) ([]DiscoveredServo, error) {
+	if kubeContext == "" {
+		output, err := servoCmd.kubectlOutput("config", "current-context")
+		if err != nil {
+			return nil, err
+		}
+		kubeContext = strings.TrimSpace(string(output))
+	}
+
+	args := []string{"--context", kubeContext, "get", "deployments", "-l", selector, "-o", "json"}
+	output, err := servoCmd.kubectlOutput(append(args, "--all-namespaces")...)
+	if err != nil {
+		servoCmd.PrintErrf("warning: unable to list deployments in all namespaces, searching the namespace of context %q: %s\n", kubeContext, err)
+		if output, err = servoCmd.kubectlOutput(args...); err != nil {
+			return nil, err
+		}
+	}
+	return discoveredServosFromJSON(output, kubeContext), nil
+}
+
+// NewServoDiscoverCommand returns a new `opsani servo discover` command instance
+func NewServoDiscoverCommand(servoCommand *servoCommand) *cobra.Command {
+	discoverCmd := &cobra.Command{
+		Use:   "discover",
+		Short: "Discover servos in a cluster and attach them to profiles",
+		Long: `Scans the namespaces accessible with a kubeconfig context for servo deployments, matches the
+optimizer each servo reports to (the servo.opsani.com/optimizer annotation or the OPSANI_OPTIMIZER
+variable of the servo) against the profiles in the config, and offers to attach each servo to the
+profile of its optimizer.
+
+The current context is scanned unless another is given with --context.`,
+		Example: `  opsani servo discover
+  opsani servo discover --context production --yes`,
+		Annotations: map[string]string{"registry": "true"},
+		Args:        cobra.NoArgs,
+		RunE:        servoCommand.RunServoDiscover,
+	}
+	discoverCmd.Flags().String("context", "", "Kubeconfig context of the cluster to scan (defaults to the current context)")
+	discoverCmd.Flags().String("selector", DefaultServoSelector, "Label selector identifying servo deployments")
+	return discoverCmd
+}
+
+func (servoCmd *servoCommand) RunServoDiscover(c *cobra.Command, args []string) error {
+	kubeContext, _ := c.Flags().GetString("context")
+	selector, _ := c.Flags().GetString("selector")
+	servos, err := servoCmd.DiscoverServos(kubeContext, selector)
+	if err != nil {
+		return err
+	}
+	if len(servos) == 0 {
+		servoCmd.Printf("No servo deployments labeled %q found.\n", selector)
+		return nil
+	}
+
+	registry, err := NewProfileRegistry(servoCmd.viperCfg)
+	if err != nil {
+		return err
+	}
+	profileFor := func(optimizer string) *Profile {
+		for _, profile := range registry.Profiles() {
+			if optimizer != "" && profile.Optimizer == optimizer {
+				return profile
+			}
+		}
+		return nil
+	}
+
+	table := Table{Headers: []string{"SERVO", "OPTIMIZER", "PROFILE"}}
+	for _, discovered := range servos {
+		optimizer, profileName := discovered.Optimizer, "-"
+		if optimizer == "" {
+			optimizer = "unknown"
+		}
+		if profile := profileFor(discovered.Optimizer); profile != nil {
+			profileName = profile.Name
+			if profile.Servo == discovered.Servo {
+				profileName += " (attached)"
+			}
+		}
+		table.Rows = append(table.Rows, []string{discovered.Servo.Description(), optimizer, profileName})
+	}
+	if err := servoCmd.RenderTable(OutputTable, table); err != nil {
+		return err
+	}
+
+	attached := 0
+	for _, discovered := range servos {
+		profile := profileFor(discovered.Optimizer)
+		if profile == nil || profile.Servo == discovered.Servo {
+			continue
+		}
+		message := fmt.Sprintf("Attach servo %s to profile %q?", discovered.Servo.Description(), profile.Name)
+		if profile.Servo != (Servo{}) {
+			message = fmt.Sprintf("Replace servo %s of profile %q with %s?", profile.Servo.Description(), profile.Name, discovered.Servo.Description())
+		}
+		confirmed, err := servoCmd.Confirm(message, true)
+		if err != nil {
+			return err
+		}
+		if confirmed {
+			profile.Servo = discovered.Servo
+			attached++
+		}
+	}
+	if attached == 0 {
+		return nil
+	}
+	if err := servoCmd.BackupConfig("servo discover"); err != nil {
+		return err
+	}
+	if err := registry.Save(); err != nil {
+		return err
+	}
+	servoCmd.Printf("Attached %d discovered servos.\n", attached)
+	return nil
+}
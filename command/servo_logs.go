@@ -0,0 +1,196 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// ServoLogLevels are the servo log levels in increasing order of severity
+var ServoLogLevels = []string{"trace", "debug", "info", "success", "warning", "error", "critical"}
+
+// servoLogLevelColor returns the palette color of a servo log level
+func servoLogLevelColor(level string) (PaletteColor, bool) {
+	switch level {
+	case "trace", "debug":
+		return PaletteColor{color.Faint}, true
+	case "info":
+		return activePalette.Info.Foreground(), true
+	case "success":
+		return activePalette.Success.Foreground(), true
+	case "warning":
+		return activePalette.Warning.Foreground(), true
+	case "error":
+		return activePalette.Failure.Foreground(), true
+	case "critical":
+		return activePalette.Failure, true
+	}
+	return nil, false
+}
+
+// servoLogLinePattern matches the level and source of servo log lines of the form:
+//
+//	2020-07-23 17:56:11.123 | INFO     | servo.connectors.prometheus:measure:123 - message
+//
+// Prefixes added by kubectl timestamps and docker-compose service names are skipped
+var servoLogLinePattern = regexp.MustCompile(`\|\s*(?i:(TRACE|DEBUG|INFO|SUCCESS|WARNING|ERROR|CRITICAL))\s*\|\s*(\S+)\s+-\s`)
+
+// servoLogConnectorPattern matches a connector named by a bracketed prefix of the message
+var servoLogConnectorPattern = regexp.MustCompile(`^\[([^\]]+)\]`)
+
+// servoLogLevelIndex returns the severity of the level or -1 if it is not a servo log level
+func servoLogLevelIndex(level string) int {
+	level = strings.ToLower(level)
+	for i, l := range ServoLogLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// ValidateServoLogLevel returns an error if the level is not empty and not a servo log level
+func ValidateServoLogLevel(level string) error {
+	if level != "" && servoLogLevelIndex(level) < 0 {
+		return fmt.Errorf("invalid log level %q: must be one of %s", level, strings.Join(ServoLogLevels, ", "))
+	}
+	return nil
+}
+
+// servoLogConnector returns the connector that emitted the log line
+// Connectors are identified by their module (servo.connectors.NAME) or a [NAME] prefix on the message
+func servoLogConnector(source, message string) string {
+	if match := servoLogConnectorPattern.FindStringSubmatch(message); match != nil {
+		return match[1]
+	}
+	components := strings.Split(strings.SplitN(source, ":", 2)[0], ".")
+	for i, component := range components {
+		if component == "connectors" && i+1 < len(components) {
+			return components[i+1]
+		}
+	}
+	return ""
+}
+
+// ServoLogWriter is an io.Writer that filters and colorizes servo log lines by level and connector
+// Lines that are not structured, such as tracebacks, take on the level and connector of the preceding line
+type ServoLogWriter struct {
+	out        io.Writer
+	minLevel   int
+	connectors []string
+	colorize   bool
+
+	mu        sync.Mutex
+	partial   []byte
+	level     string
+	connector string
+}
+
+// NewServoLogWriter returns a writer that writes lines at or above the level from any of the connectors to out
+// An empty level or connector list disables the corresponding filter
+func NewServoLogWriter(out io.Writer, level string, connectors []string, colorize bool) *ServoLogWriter {
+	minLevel := servoLogLevelIndex(level)
+	if minLevel < 0 {
+		minLevel = 0
+	}
+	return &ServoLogWriter{
+		out:        out,
+		minLevel:   minLevel,
+		connectors: connectors,
+		colorize:   colorize,
+	}
+}
+
+// Write filters and writes any complete lines
+func (w *ServoLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.partial[:i+1])
+		w.partial = w.partial[i+1:]
+		if err := w.writeLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes any incomplete trailing line
+func (w *ServoLogWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.partial) == 0 {
+		return nil
+	}
+	line := string(w.partial)
+	w.partial = nil
+	return w.writeLine(line)
+}
+
+func (w *ServoLogWriter) writeLine(line string) error {
+	match := servoLogLinePattern.FindStringSubmatchIndex(line)
+	if match != nil {
+		w.level = strings.ToLower(line[match[2]:match[3]])
+		w.connector = servoLogConnector(line[match[4]:match[5]], strings.TrimSpace(line[match[1]:]))
+	}
+	if !w.included() {
+		return nil
+	}
+	if match != nil && w.colorize {
+		if c, ok := servoLogLevelColor(w.level); ok {
+			line = line[:match[2]] + c.Sprint(line[match[2]:match[3]]) + line[match[3]:]
+		}
+	}
+	_, err := io.WriteString(w.out, line)
+	return err
+}
+
+// included reports whether the current line passes the level and connector filters
+func (w *ServoLogWriter) included() bool {
+	if w.minLevel > 0 && servoLogLevelIndex(w.level) < w.minLevel {
+		return false
+	}
+	if len(w.connectors) == 0 {
+		return true
+	}
+	for _, connector := range w.connectors {
+		if strings.EqualFold(connector, w.connector) {
+			return true
+		}
+	}
+	return false
+}
+
+// servoLogOutput returns the writer for servo logs written to out and a func flushing it
+// Output is passed through unmodified unless filtering or colorization applies
+func servoLogOutput(out io.Writer, logsArgs ServoLogsArgs) (io.Writer, func() error) {
+	if logsArgs.Level == "" && len(logsArgs.Connectors) == 0 && color.NoColor {
+		return out, func() error { return nil }
+	}
+	w := NewServoLogWriter(out, logsArgs.Level, logsArgs.Connectors, !color.NoColor)
+	return w, w.Flush
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+)
+
+const servoLogs = `2020-07-23 17:56:11.123 | INFO     | servo.runner:run:52 - Servo started
+2020-07-23 17:56:12.456 | DEBUG    | servo.connectors.prometheus:measure:123 - Querying Prometheus
+2020-07-23 17:56:13.789 | WARNING  | servo.connectors.kubernetes:adjust:88 - Deployment is not ready
+2020-07-23 17:56:14.012 | ERROR    | servo.connectors.prometheus:measure:140 - Query failed
+Traceback (most recent call last):
+  File "prometheus.py", line 140, in measure
+2020-07-23 17:56:15.345 | INFO     | servo.runner:run:60 - [vegeta] Load test complete
+`
+
+func filterServoLogs(t *testing.T, input, level string, connectors []string, colorize bool) string {
+	var buf bytes.Buffer
+	w := command.NewServoLogWriter(&buf, level, connectors, colorize)
+	// Write in pieces to exercise line buffering
+	_, err := w.Write([]byte(input[:30]))
+	require.NoError(t, err)
+	_, err = w.Write([]byte(input[30:]))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+	return buf.String()
+}
+
+func TestServoLogWriterPassesThroughUnfiltered(t *testing.T) {
+	require.Equal(t, servoLogs, filterServoLogs(t, servoLogs, "", nil, false))
+}
+
+func TestServoLogWriterFiltersByLevel(t *testing.T) {
+	require.Equal(t, `2020-07-23 17:56:13.789 | WARNING  | servo.connectors.kubernetes:adjust:88 - Deployment is not ready
+2020-07-23 17:56:14.012 | ERROR    | servo.connectors.prometheus:measure:140 - Query failed
+Traceback (most recent call last):
+  File "prometheus.py", line 140, in measure
+`, filterServoLogs(t, servoLogs, "warning", nil, false))
+}
+
+func TestServoLogWriterFiltersByConnector(t *testing.T) {
+	require.Equal(t, `2020-07-23 17:56:12.456 | DEBUG    | servo.connectors.prometheus:measure:123 - Querying Prometheus
+2020-07-23 17:56:14.012 | ERROR    | servo.connectors.prometheus:measure:140 - Query failed
+Traceback (most recent call last):
+  File "prometheus.py", line 140, in measure
+2020-07-23 17:56:15.345 | INFO     | servo.runner:run:60 - [vegeta] Load test complete
+`, filterServoLogs(t, servoLogs, "", []string{"prometheus", "Vegeta"}, false))
+}
+
+func TestServoLogWriterSkipsDriverPrefixes(t *testing.T) {
+	input := "servo_1  | 2020-07-23 17:56:14.012 | ERROR    | servo.connectors.prometheus:measure:140 - Query failed\n" +
+		"servo_1  | 2020-07-23 17:56:15.345 | INFO     | servo.runner:run:60 - Servo started\n"
+	require.Equal(t, "servo_1  | 2020-07-23 17:56:14.012 | ERROR    | servo.connectors.prometheus:measure:140 - Query failed\n",
+		filterServoLogs(t, input, "error", nil, false))
+}
+
+func TestServoLogWriterColorizesLevels(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	output := filterServoLogs(t, "2020-07-23 17:56:14.012 | ERROR    | servo.runner:run:60 - Failed\n", "", nil, true)
+	require.Equal(t, "2020-07-23 17:56:14.012 | "+command.Palettes[command.PaletteDefault].Failure.Foreground().Sprint("ERROR")+"    | servo.runner:run:60 - Failed\n", output)
+}
+
+func TestValidateServoLogLevel(t *testing.T) {
+	require.NoError(t, command.ValidateServoLogLevel(""))
+	require.NoError(t, command.ValidateServoLogLevel("WARNING"))
+	require.EqualError(t, command.ValidateServoLogLevel("verbose"), `invalid log level "verbose": must be one of trace, debug, info, success, warning, error, critical`)
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"github.com/go-resty/resty/v2"
+	"github.com/opsani/cli/opsani"
+	"github.com/spf13/cobra"
+)
+
+// NewServoPauseCommand returns a new `opsani servo pause` command instance
+func NewServoPauseCommand(baseCmd *BaseCommand) *cobra.Command {
+	pauseCmd := &cobra.Command{
+		Use:   "pause",
+		Short: "Pause optimization",
+		Long: `Pause the measurement and adjustment cycles of the optimizer while leaving the servo running.
+
+Unlike stopping the servo, the servo stays connected and optimization picks up where it left off
+on ` + "`opsani servo resume`" + `. Pause for maintenance windows, incidents, or deploy freezes.`,
+		Example: `  opsani servo pause --reason "database maintenance"
+  opsani servo pause --profiles team-a,team-b`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reason, _ := cmd.Flags().GetString("reason")
+			return baseCmd.LifecycleRunE(LifecycleAction{
+				Verb: "pause",
+				Past: "paused",
+				Perform: func(client *opsani.Client) (*resty.Response, error) {
+					return client.PauseApp(reason)
+				},
+			})(cmd, args)
+		},
+	}
+	pauseCmd.Flags().String("reason", "", "Reason for pausing, recorded by the optimizer")
+	baseCmd.AddProfileSelectionFlags(pauseCmd)
+	return RequireScope(pauseCmd, ScopeWrite)
+}
+
+// NewServoResumeCommand returns a new `opsani servo resume` command instance
+func NewServoResumeCommand(baseCmd *BaseCommand) *cobra.Command {
+	resumeCmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Resume paused optimization",
+		Args:  cobra.NoArgs,
+		RunE: baseCmd.LifecycleRunE(LifecycleAction{
+			Verb:    "resume",
+			Past:    "resumed",
+			Perform: (*opsani.Client).ResumeApp,
+		}),
+	}
+	baseCmd.AddProfileSelectionFlags(resumeCmd)
+	return RequireScope(resumeCmd, ScopeWrite)
+}
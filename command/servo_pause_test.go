@@ -0,0 +1,87 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+)
+
+type ServoPauseTestSuite struct {
+	test.Suite
+	paths  []string
+	bodies []map[string]interface{}
+}
+
+func TestServoPauseTestSuite(t *testing.T) {
+	suite.Run(t, new(ServoPauseTestSuite))
+}
+
+func (s *ServoPauseTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+	s.paths, s.bodies = nil, nil
+}
+
+// overrideServer records the path and body of each request and returns a canned state
+func (s *ServoPauseTestSuite) overrideServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&body)
+		s.paths = append(s.paths, r.URL.Path)
+		s.bodies = append(s.bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"state": "running", "paused": true}`))
+	}))
+}
+
+func (s *ServoPauseTestSuite) configFile(baseURL string) string {
+	return test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{"name": "team-a", "optimizer": "example.com/a", "token": "123456", "base_url": baseURL},
+			{"name": "team-b", "optimizer": "example.com/b", "token": "123456", "base_url": baseURL},
+		},
+	}).Name()
+}
+
+func (s *ServoPauseTestSuite) TestPause() {
+	server := s.overrideServer()
+	defer server.Close()
+
+	_, err := s.Execute("--config", s.configFile(server.URL), "servo", "pause", "--reason", "database maintenance")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"/accounts/example.com/applications/a/state/override"}, s.paths)
+	s.Require().Equal(map[string]interface{}{"paused": true, "reason": "database maintenance"}, s.bodies[0])
+}
+
+func (s *ServoPauseTestSuite) TestResumeProfiles() {
+	server := s.overrideServer()
+	defer server.Close()
+
+	output, err := s.Execute("--config", s.configFile(server.URL), "--yes", "servo", "resume", "--all")
+	s.Require().NoError(err)
+	s.Require().Regexp(`team-a\s+example.com/a\s+resumed`, output)
+	s.Require().Regexp(`team-b\s+example.com/b\s+resumed`, output)
+	s.Require().Equal([]string{
+		"/accounts/example.com/applications/a/state/override",
+		"/accounts/example.com/applications/b/state/override",
+	}, s.paths)
+	s.Require().Equal(map[string]interface{}{"paused": false}, s.bodies[1])
+}
@@ -0,0 +1,47 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Ping confirms that the kubectl context can reach the cluster and read the servo deployment
+func (c *KubernetesServoDriver) Ping() error {
+	ctx, cancel := contextWithTimeout(c.timeout)
+	defer cancel()
+	deploymentArg := fmt.Sprintf("deployment/%v", c.servo.Deployment)
+	cmd := c.kubectl(ctx, "-n", c.servo.Namespace, "get", deploymentArg, "-o", "name")
+	if output, err := commandCombinedOutput(cmd); err != nil {
+		if msg := bytes.TrimSpace(output); len(msg) > 0 {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// Ping confirms that an SSH session can be opened on the servo host
+func (c *DockerComposeServoDriver) Ping() error {
+	ctx, cancel := contextWithTimeout(c.timeout)
+	defer cancel()
+	return c.runInSSHSession(ctx, func(ctx context.Context, session *ssh.Session) error {
+		return session.Run("true")
+	})
+}
@@ -0,0 +1,211 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v2"
+)
+
+// ReportArtifact is a named file collected for inclusion in a servo report bundle
+type ReportArtifact struct {
+	Name string
+	Data []byte
+}
+
+type ServoReportArgs struct {
+	Lines string
+}
+
+// NewServoReportCommand returns a new `opsani servo report` command instance
+func NewServoReportCommand(servoCommand *servoCommand) *cobra.Command {
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Generate a servo support bundle",
+		Long: `Gathers servo logs, configuration, deployment details, events, and CLI environment
+info into a timestamped tar.gz archive suitable for sharing with Opsani support.
+
+Access tokens are redacted from all collected artifacts.`,
+		Args: cobra.NoArgs,
+		RunE: servoCommand.RunServoReport,
+	}
+	reportCmd.Flags().StringP("lines", "l", "500", `Number of log lines to collect (or "all")`)
+	reportCmd.Flags().StringP("output", "o", "", "Write the bundle to the given file (default \"servo-report-TIMESTAMP.tar.gz\")")
+	reportCmd.MarkFlagFilename("output", "*.tar.gz", "*.tgz")
+	return reportCmd
+}
+
+func (servoCmd *servoCommand) RunServoReport(c *cobra.Command, args []string) error {
+	lines, _ := c.Flags().GetString("lines")
+	if n, err := strconv.Atoi(lines); lines != "all" && (err != nil || n < 0) {
+		return fmt.Errorf("invalid value %q for --lines: must be a non-negative number or \"all\"", lines)
+	}
+	driver, err := servoCmd.servoDriver()
+	if driver == nil {
+		return err
+	}
+
+	filename, _ := c.Flags().GetString("output")
+	now := time.Now()
+	if filename == "" {
+		filename = fmt.Sprintf("servo-report-%s.tar.gz", now.Format("20060102-150405"))
+	}
+
+	artifacts, err := driver.Report(ServoReportArgs{Lines: lines})
+	if err != nil {
+		return err
+	}
+	artifacts = append(artifacts, ReportArtifact{Name: "environment.yaml", Data: servoCmd.reportEnvironment()})
+
+	// Support bundles are always redacted, regardless of --show-secrets
+	secrets := servoCmd.knownSecrets()
+	for i := range artifacts {
+		artifacts[i].Data = RedactSecretsInBytes(RedactSecretKeyValuesInBytes(artifacts[i].Data), secrets...)
+	}
+
+	if err := writeReportArchive(filename, now, artifacts); err != nil {
+		return err
+	}
+	servoCmd.Printf("Servo report written to %s\n", filename)
+	return nil
+}
+
+// reportEnvironment describes the CLI and profile in effect for inclusion in the report
+func (servoCmd *servoCommand) reportEnvironment() []byte {
+	env := map[string]interface{}{
+		"cli": map[string]string{
+			"version":    Version,
+			"commit":     Commit,
+			"build_date": BuildDate,
+			"os":         runtime.GOOS,
+			"arch":       runtime.GOARCH,
+			"go":         runtime.Version(),
+		},
+		"profile": map[string]interface{}{
+			"name":      servoCmd.profile.Name,
+			"optimizer": servoCmd.Optimizer(),
+			"base_url":  servoCmd.BaseURL(),
+			"servo":     servoCmd.profile.Servo,
+		},
+		"generated_at": time.Now().Format(time.RFC3339),
+	}
+	data, err := yaml.Marshal(env)
+	if err != nil {
+		return []byte(err.Error())
+	}
+	return data
+}
+
+func writeReportArchive(filename string, modTime time.Time, artifacts []ReportArtifact) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	dir := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(filename), ".tar.gz"), ".tgz")
+	for _, artifact := range artifacts {
+		header := &tar.Header{
+			Name:    dir + "/" + artifact.Name,
+			Mode:    0644,
+			Size:    int64(len(artifact.Data)),
+			ModTime: modTime,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(artifact.Data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// runForReport executes a command and captures its combined output
+// Failures are recorded in the artifact rather than aborting collection
+func runForReport(timeout time.Duration, name string, args ...string) []byte {
+	ctx, cancel := contextWithTimeout(timeout)
+	defer cancel()
+	outputBuffer := new(bytes.Buffer)
+	cmd := commandContext(ctx, name, args...)
+	cmd.Stdout = outputBuffer
+	cmd.Stderr = outputBuffer
+	if err := runCommand(cmd); err != nil {
+		fmt.Fprintf(outputBuffer, "\nerror: %s\n", err)
+	}
+	return outputBuffer.Bytes()
+}
+
+// Report collects support artifacts from the servo deployment
+func (c *KubernetesServoDriver) Report(args ServoReportArgs) ([]ReportArtifact, error) {
+	deploymentArg := fmt.Sprintf("deployments/%v", c.servo.Deployment)
+	tail := args.Lines
+	if tail == "all" {
+		tail = "-1"
+	}
+	return []ReportArtifact{
+		{Name: "logs.txt", Data: runForReport(c.timeout, "kubectl", c.kubectlArgs("-n", c.servo.Namespace, "logs", deploymentArg, "--tail="+tail)...)},
+		{Name: "config.yaml", Data: runForReport(c.timeout, "kubectl", c.kubectlArgs("-n", c.servo.Namespace, "exec", deploymentArg, "--", "cat", "/servo/config.yaml")...)},
+		{Name: "describe.txt", Data: runForReport(c.timeout, "kubectl", c.kubectlArgs("-n", c.servo.Namespace, "describe", deploymentArg)...)},
+		{Name: "events.txt", Data: runForReport(c.timeout, "kubectl", c.kubectlArgs("-n", c.servo.Namespace, "get", "events", "--sort-by=.lastTimestamp")...)},
+	}, nil
+}
+
+// Report collects support artifacts from the servo deployment
+func (c *DockerComposeServoDriver) Report(args ServoReportArgs) ([]ReportArtifact, error) {
+	commands := []struct {
+		name string
+		args []string
+	}{
+		{"logs.txt", []string{"docker-compose", "logs", "--no-color", "--tail", args.Lines}},
+		{"config.yaml", []string{"cat", "config.yaml"}},
+		{"describe.txt", []string{"docker-compose", "ps"}},
+		{"events.txt", []string{"docker", "events", "--since", "1h", "--until", "0s"}},
+	}
+	artifacts := []ReportArtifact{}
+	for _, command := range commands {
+		outputBuffer := new(bytes.Buffer)
+		ctx, cancel := contextWithTimeout(c.timeout)
+		err := c.runInSSHSession(ctx, func(ctx context.Context, session *ssh.Session) error {
+			session.Stdout = outputBuffer
+			session.Stderr = outputBuffer
+			return session.Run(ServoShellCommand(c.servo.Path, command.args...))
+		})
+		cancel()
+		if err != nil {
+			fmt.Fprintf(outputBuffer, "\nerror: %s\n", err)
+		}
+		artifacts = append(artifacts, ReportArtifact{Name: command.name, Data: outputBuffer.Bytes()})
+	}
+	return artifacts, nil
+}
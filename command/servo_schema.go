@@ -0,0 +1,369 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// servoConfigSchema is the JSON schema of servo config files
+// Connector names and guardrail ranges are strict so that typos are caught before the servo sees them,
+// while the remaining connector settings are only type checked to tolerate newer servo releases
+const servoConfigSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "Servo config",
+  "type": "object",
+  "properties": {
+    "opsani_dev": {"$ref": "#/definitions/OpsaniDev"},
+    "kubernetes": {"$ref": "#/definitions/Kubernetes"},
+    "prometheus": {"$ref": "#/definitions/Prometheus"},
+    "vegeta": {"$ref": "#/definitions/Vegeta"},
+    "datadog": {"$ref": "#/definitions/Metrics"},
+    "newrelic": {"$ref": "#/definitions/Metrics"},
+    "connectors": {"type": ["array", "object"], "items": {"type": "string"}},
+    "optimizer": {"type": "object"},
+    "k8s": {"type": "object"},
+    "prom": {"type": "object"},
+    "statestore": {"type": "object"}
+  },
+  "additionalProperties": false,
+  "definitions": {
+    "CPUValue": {
+      "type": ["number", "string"],
+      "minimum": 0,
+      "pattern": "^[0-9]+(\\.[0-9]+)?m?$"
+    },
+    "MemoryValue": {
+      "type": ["integer", "string"],
+      "minimum": 0,
+      "pattern": "^[0-9]+(\\.[0-9]+)? ?([KMGTPE]i?B?)?$"
+    },
+    "CPU": {
+      "type": "object",
+      "properties": {
+        "min": {"$ref": "#/definitions/CPUValue"},
+        "max": {"$ref": "#/definitions/CPUValue"},
+        "step": {"$ref": "#/definitions/CPUValue"}
+      },
+      "additionalProperties": false
+    },
+    "Memory": {
+      "type": "object",
+      "properties": {
+        "min": {"$ref": "#/definitions/MemoryValue"},
+        "max": {"$ref": "#/definitions/MemoryValue"},
+        "step": {"$ref": "#/definitions/MemoryValue"}
+      },
+      "additionalProperties": false
+    },
+    "Replicas": {
+      "type": "object",
+      "properties": {
+        "min": {"type": "integer", "minimum": 0},
+        "max": {"type": "integer", "minimum": 1},
+        "step": {"type": "integer", "minimum": 1}
+      },
+      "additionalProperties": false
+    },
+    "Container": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "alias": {"type": "string"},
+        "cpu": {"$ref": "#/definitions/CPU"},
+        "memory": {"$ref": "#/definitions/Memory"}
+      },
+      "required": ["name"]
+    },
+    "Deployment": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "namespace": {"type": "string"},
+        "replicas": {"$ref": "#/definitions/Replicas"},
+        "containers": {"type": "array", "items": {"$ref": "#/definitions/Container"}}
+      },
+      "required": ["name", "containers"]
+    },
+    "Kubernetes": {
+      "type": "object",
+      "properties": {
+        "namespace": {"type": "string"},
+        "timeout": {"type": "string"},
+        "settlement": {"type": "string"},
+        "deployments": {"type": "array", "items": {"$ref": "#/definitions/Deployment"}},
+        "rollouts": {"type": "array", "items": {"$ref": "#/definitions/Deployment"}}
+      }
+    },
+    "OpsaniDev": {
+      "type": "object",
+      "properties": {
+        "namespace": {"type": "string"},
+        "deployment": {"type": "string"},
+        "rollout": {"type": "string"},
+        "container": {"type": "string"},
+        "service": {"type": "string"},
+        "port": {"type": ["integer", "string"]},
+        "cpu": {"$ref": "#/definitions/CPU"},
+        "memory": {"$ref": "#/definitions/Memory"}
+      },
+      "required": ["namespace", "container", "service"]
+    },
+    "Metric": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "query": {"type": "string"},
+        "unit": {"type": "string"}
+      },
+      "required": ["name", "query"]
+    },
+    "Metrics": {
+      "type": "object",
+      "properties": {
+        "metrics": {"type": "array", "items": {"$ref": "#/definitions/Metric"}}
+      }
+    },
+    "Prometheus": {
+      "type": "object",
+      "properties": {
+        "base_url": {"type": "string", "pattern": "^https?://"},
+        "streaming_interval": {"type": "string"},
+        "metrics": {"type": "array", "items": {"$ref": "#/definitions/Metric"}}
+      }
+    },
+    "Vegeta": {
+      "type": "object",
+      "properties": {
+        "rate": {"type": ["integer", "string"], "minimum": 0, "pattern": "^[0-9]+(/[0-9]*(ns|us|ms|s|m|h))?$"},
+        "duration": {"type": "string"},
+        "format": {"enum": ["http", "json"]},
+        "target": {"type": "string"},
+        "targets": {"type": "string"},
+        "workers": {"type": "integer", "minimum": 1},
+        "max_workers": {"type": "integer", "minimum": 1}
+      }
+    }
+  }
+}`
+
+// ServoConfigError describes the problems found when validating a servo config against its schema
+type ServoConfigError struct {
+	Problems []string
+}
+
+func (e *ServoConfigError) Error() string {
+	return "invalid servo config:\n" + strings.Join(e.Problems, "\n")
+}
+
+// ValidateServoConfigSchema validates the config against a JSON schema
+// The embedded servo config schema is used when schema is nil
+func ValidateServoConfigSchema(config interface{}, schema []byte) error {
+	if schema == nil {
+		schema = []byte(servoConfigSchema)
+	}
+	var root map[string]interface{}
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return fmt.Errorf("invalid servo config schema: %w", err)
+	}
+	validator := schemaValidator{root: root}
+	validator.validate(root, config, "")
+	if len(validator.problems) > 0 {
+		return &ServoConfigError{Problems: validator.problems}
+	}
+	return nil
+}
+
+// schemaValidator checks values against the subset of JSON schema used by servo config schemas
+type schemaValidator struct {
+	root     map[string]interface{}
+	problems []string
+}
+
+func (v *schemaValidator) addProblem(path string, format string, a ...interface{}) {
+	if path == "" {
+		path = "config"
+	}
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, a...))
+}
+
+// resolve follows local references of the form #/definitions/Name
+func (v *schemaValidator) resolve(schema map[string]interface{}) map[string]interface{} {
+	for depth := 0; depth < 32; depth++ {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		var target interface{} = v.root
+		for _, component := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			parent, ok := target.(map[string]interface{})
+			if !ok {
+				return map[string]interface{}{}
+			}
+			target = parent[component]
+		}
+		if schema, ok = target.(map[string]interface{}); !ok {
+			return map[string]interface{}{}
+		}
+	}
+	return schema
+}
+
+func (v *schemaValidator) validate(schema map[string]interface{}, value interface{}, path string) {
+	schema = v.resolve(schema)
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		actual := jsonTypeOf(value)
+		matched := false
+		for _, t := range types {
+			if t == actual || (t == "number" && actual == "integer") {
+				matched = true
+			}
+		}
+		if !matched {
+			v.addProblem(path, "expected %s, got %s", strings.Join(types, " or "), actual)
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		quoted := []string{}
+		for _, e := range enum {
+			if e == value {
+				found = true
+			}
+			quoted = append(quoted, fmt.Sprintf("%q", fmt.Sprint(e)))
+		}
+		if !found {
+			v.addProblem(path, "must be one of %s", strings.Join(quoted, ", "))
+		}
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(schema, value, path)
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range value {
+				v.validate(items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case float64:
+		if minimum, ok := schema["minimum"].(float64); ok && value < minimum {
+			v.addProblem(path, "must be at least %v", minimum)
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && value > maximum {
+			v.addProblem(path, "must be at most %v", maximum)
+		}
+	case string:
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(value) {
+				v.addProblem(path, "%q is not a valid value", value)
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) validateObject(schema map[string]interface{}, object map[string]interface{}, path string) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if _, ok := object[fmt.Sprint(name)]; !ok {
+				v.addProblem(path, "missing required key %q", name)
+			}
+		}
+	}
+
+	names := []string{}
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	keys := []string{}
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		if property, ok := properties[key].(map[string]interface{}); ok {
+			v.validate(property, object[key], keyPath)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				kind := "key"
+				if path == "" {
+					kind = "connector"
+				}
+				problem := fmt.Sprintf("unknown %s", kind)
+				if suggestions := suggestionsFor(key, names); len(suggestions) > 0 {
+					problem += fmt.Sprintf(" (did you mean %q?)", suggestions[0])
+				}
+				v.addProblem(keyPath, "%s", problem)
+			}
+		case map[string]interface{}:
+			v.validate(additional, object[key], keyPath)
+		}
+	}
+}
+
+// schemaTypes returns the types allowed by the type keyword of a schema
+func schemaTypes(t interface{}) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := []string{}
+		for _, e := range t {
+			types = append(types, fmt.Sprint(e))
+		}
+		return types
+	}
+	return nil
+}
+
+// jsonTypeOf returns the JSON schema type name of a decoded value
+func jsonTypeOf(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
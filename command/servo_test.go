@@ -15,11 +15,19 @@
 package command_test
 
 import (
+	"errors"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/opsani/cli/command"
 	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/yaml.v2"
 )
@@ -36,6 +44,38 @@ func (s *ServoTestSuite) SetupTest() {
 	s.SetCommand(command.NewRootCommand())
 }
 
+func (s *ServoTestSuite) TearDownTest() {
+	command.SetServoDriverFactory(nil)
+	command.SetCommandContextFunc(nil)
+}
+
+// TestHelperProcess simulates external commands executed via test.ExecRecorder
+func TestHelperProcess(t *testing.T) {
+	test.RunHelperProcess()
+}
+
+func servoConfigFile(servo map[string]string) string {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]interface{}{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+				"servo":     servo,
+			},
+		},
+	})
+	return configFile.Name()
+}
+
+func kubernetesServoConfigFile() string {
+	return servoConfigFile(map[string]string{
+		"type":       "kubernetes",
+		"namespace":  "opsani",
+		"deployment": "servo",
+	})
+}
+
 func (s *ServoTestSuite) TestRunningServo() {
 	output, err := s.Execute("servo")
 	s.Require().NoError(err)
@@ -360,3 +400,447 @@ func (s *ServoTestSuite) TestRunningServoListVerbose() {
 	s.Require().Contains(output, "NAME   	TYPE          	NAMESPACE	DEPLOYMENT	USER        	HOST          	PATH   ")
 	s.Require().Contains(output, "default	docker-compose	         	          	blakewatters	dev.opsani.com	/servo	")
 }
+
+func (s *ServoTestSuite) TestRunningServoStatusWatchHelp() {
+	output, err := s.Execute("servo", "status", "--help")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Watch for changes, re-rendering output in place")
+}
+
+func (s *ServoTestSuite) TestRunningServoReportHelp() {
+	output, err := s.Execute("servo", "report", "--help")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Gathers servo logs, configuration, deployment details")
+}
+
+func (s *ServoTestSuite) TestRunningServoReportInvalidServo() {
+	configFile := test.TempConfigFileWithObj(map[string][]map[string]string{
+		"profiles": {
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+			},
+		},
+	})
+	_, err := s.Execute("--config", configFile.Name(), "servo", "report")
+	s.Require().EqualError(err, "no driver for servo type: \"\"")
+}
+
+func (s *ServoTestSuite) TestRunningServoReportInvalidLines() {
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "report", "--lines", "-5")
+	s.Require().EqualError(err, `invalid value "-5" for --lines: must be a non-negative number or "all"`)
+	_, err = s.Execute("--config", kubernetesServoConfigFile(), "servo", "report", "--lines", "many")
+	s.Require().EqualError(err, `invalid value "many" for --lines: must be a non-negative number or "all"`)
+}
+
+func (s *ServoTestSuite) TestRunningKubernetesServoReportAllLines() {
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+	dir, err := ioutil.TempDir("", "servo-report")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+	_, err = s.Execute("--config", kubernetesServoConfigFile(), "servo", "report", "--lines", "all", "--output", filepath.Join(dir, "report.tar.gz"))
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"kubectl", "-n", "opsani", "logs", "deployments/servo", "--tail=-1"}, recorder.Invocations()[0])
+}
+
+func (s *ServoTestSuite) TestRunningServoCheckHelp() {
+	output, err := s.Execute("servo", "check", "--help")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Runs the servo check subsystem inside the deployed servo")
+	s.Require().Contains(output, "--progressive")
+}
+
+func (s *ServoTestSuite) TestRunningServoCheckInvalidServo() {
+	configFile := test.TempConfigFileWithObj(map[string][]map[string]string{
+		"profiles": {
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+			},
+		},
+	})
+	_, err := s.Execute("--config", configFile.Name(), "servo", "check", "--wait")
+	s.Require().EqualError(err, "no driver for servo type: \"\"")
+}
+
+func (s *ServoTestSuite) TestRunningServoStartWithFakeDriver() {
+	driver := test.NewFakeServoDriver()
+	command.SetServoDriverFactory(driver.Factory())
+	configFile := servoConfigFile(map[string]string{"type": "docker-compose", "host": "dev.opsani.com"})
+	_, err := s.Execute("--config", configFile, "--timeout", "5s", "servo", "start")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"Start"}, driver.Calls())
+	s.Require().Equal("dev.opsani.com", driver.Servo.Host)
+	s.Require().Equal(5*time.Second, driver.Timeout)
+}
+
+func (s *ServoTestSuite) TestRunningServoLogsWithFakeDriver() {
+	driver := test.NewFakeServoDriver()
+	command.SetServoDriverFactory(driver.Factory())
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "logs", "-f", "-l", "100")
+	s.Require().NoError(err)
+	s.Require().Equal(command.ServoLogsArgs{Follow: true, Lines: "100"}, driver.LastArgs())
+}
+
+func (s *ServoTestSuite) TestRunningServoStopWithFakeDriverFailure() {
+	driver := test.NewFakeServoDriver()
+	driver.Errors["Stop"] = errors.New("servo is unreachable")
+	command.SetServoDriverFactory(driver.Factory())
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "stop")
+	s.Require().EqualError(err, "servo is unreachable")
+}
+
+func (s *ServoTestSuite) TestRunningKubernetesServoLifecycle() {
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+	configFile := kubernetesServoConfigFile()
+	for _, name := range []string{"start", "stop", "restart"} {
+		_, err := s.Execute("--config", configFile, "servo", name)
+		s.Require().NoError(err)
+	}
+	s.Require().Equal([][]string{
+		{"kubectl", "-n", "opsani", "scale", "--replicas=1", "deployments/servo"},
+		{"kubectl", "-n", "opsani", "scale", "--replicas=0", "deployments/servo"},
+		{"kubectl", "-n", "opsani", "rollout", "restart", "deployment/servo"},
+	}, recorder.Invocations())
+}
+
+func (s *ServoTestSuite) TestRunningKubernetesServoLogs() {
+	recorder := test.NewExecRecorder()
+	command.SetCommandContextFunc(recorder.CommandContext)
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "logs", "--timestamps")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"kubectl", "-n", "opsani", "logs", "deployments/servo", "--tail=25", "--timestamps"}, recorder.LastInvocation())
+}
+
+func (s *ServoTestSuite) TestRunningKubernetesServoStartFailure() {
+	recorder := test.NewExecRecorder()
+	recorder.Respond("kubectl -n opsani scale", test.ExecResponse{Stderr: "deployments.apps \"servo\" not found", ExitCode: 1})
+	command.SetCommandContextFunc(recorder.CommandContext)
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "start")
+	s.Require().EqualError(err, "exit status 1")
+}
+
+func (s *ServoTestSuite) TestRunningKubernetesServoStatusTimeout() {
+	recorder := test.NewExecRecorder()
+	recorder.Respond("kubectl", test.ExecResponse{Delay: 10 * time.Second})
+	command.SetCommandContextFunc(recorder.CommandContext)
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "--timeout", "100ms", "servo", "status")
+	s.Require().EqualError(err, "signal: killed")
+}
+
+// servoStatusConfigFile returns a config whose optimizer API responds to servo status requests with the body
+func (s *ServoTestSuite) servoStatusConfigFile(body string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/accounts/example.com/applications/app/servo" {
+			w.WriteHeader(http.StatusNotFound)
+			body = `{}`
+		}
+		w.Write([]byte(body))
+	}))
+	s.T().Cleanup(server.Close)
+	return test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]interface{}{
+			{
+				"name": "default", "optimizer": "example.com/app", "token": "123456", "base_url": server.URL,
+				"servo": map[string]string{"type": "kubernetes", "namespace": "opsani", "deployment": "servo"},
+			},
+		},
+	}).Name()
+}
+
+func (s *ServoTestSuite) TestRunningServoStatusReportsFreshness() {
+	driver := test.NewFakeServoDriver()
+	command.SetServoDriverFactory(driver.Factory())
+	lastReport := time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339)
+	configFile := s.servoStatusConfigFile(`{"last_report_at": "` + lastReport + `", "phase": "measuring",
+		"pending_adjustments": [{"id": "adj-1", "status": "pending", "started_at": "` + lastReport + `"}]}`)
+	output, err := s.Execute("--config", configFile, "servo", "status")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"Status"}, driver.Calls())
+	s.Require().Contains(output, "Optimizer example.com/app:")
+	s.Require().Regexp(`Last report:\s+2m[0-9]+s ago\n`, output)
+	s.Require().Regexp(`Phase:\s+measuring`, output)
+	s.Require().Regexp(`Pending adjustments:\s+1`, output)
+}
+
+func (s *ServoTestSuite) TestRunningServoStatusFlagsStaleServo() {
+	driver := test.NewFakeServoDriver()
+	command.SetServoDriverFactory(driver.Factory())
+	lastReport := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	output, err := s.Execute("--config", s.servoStatusConfigFile(`{"last_report_at": "`+lastReport+`", "phase": "adjusting"}`), "servo", "status")
+	s.Require().NoError(err)
+	s.Require().Regexp(`Last report:\s+1h0m[0-9]+s ago: the servo is not reporting`, output)
+
+	output, err = s.Execute("--config", s.servoStatusConfigFile(`{}`), "servo", "status")
+	s.Require().NoError(err)
+	s.Require().Regexp("Last report:\\s+never: check the optimizer and token of the servo with `opsani check link`", output)
+	s.Require().Regexp(`Phase:\s+unknown`, output)
+}
+
+func (s *ServoTestSuite) TestRunningServoCheckWaitNotifiesSlack() {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	driver := test.NewFakeServoDriver()
+	driver.Errors["Check"] = errors.New("checks failed")
+	command.SetServoDriverFactory(driver.Factory())
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"notifications": map[string]string{"slack_webhook": server.URL},
+		"profiles": []map[string]interface{}{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+				"servo":     map[string]string{"type": "kubernetes", "namespace": "opsani", "deployment": "servo"},
+			},
+		},
+	})
+	_, err := s.Execute("--config", configFile.Name(), "servo", "check", "--wait")
+	s.Require().EqualError(err, "checks failed")
+	s.Require().Contains(body, "`opsani servo check` for example.com/app failed after")
+	s.Require().Contains(body, "checks failed")
+
+	body = ""
+	s.SetCommand(command.NewRootCommand())
+	_, err = s.Execute("--config", configFile.Name(), "servo", "check")
+	s.Require().Error(err)
+	s.Require().Empty(body)
+}
+
+const kubeconfigView = `{"contexts": [
+	{"name": "my-cluster", "context": {"cluster": "my-cluster", "namespace": "apps"}},
+	{"name": "bare", "context": {"cluster": "bare"}}
+]}`
+
+func (s *ServoTestSuite) TestRunningServoAttachFromContext() {
+	recorder := test.NewExecRecorder()
+	recorder.Respond("kubectl config view", test.ExecResponse{Stdout: kubeconfigView})
+	recorder.Respond("kubectl --context my-cluster", test.ExecResponse{Stdout: "servo"})
+	command.SetCommandContextFunc(recorder.CommandContext)
+	configFile := servoConfigFile(nil)
+
+	output, err := s.Execute("--config", configFile, "servo", "attach", "--from-context", "my-cluster")
+	s.Require().NoError(err)
+	s.Require().Contains(output, `Attached servo namespaces/apps/deployments/servo from context "my-cluster" to profile "default"`)
+	s.Require().Equal([]string{
+		"kubectl", "--context", "my-cluster", "-n", "apps", "get", "deployments",
+		"-l", "app.kubernetes.io/name=servo", "-o",
+		`jsonpath={range .items[*]}{.metadata.name}{"\t"}{.metadata.ownerReferences[?(@.kind=="ServoDeployment")].name}{"\n"}{end}`,
+	}, recorder.LastInvocation())
+
+	var config = map[string]interface{}{}
+	body, _ := ioutil.ReadFile(configFile)
+	s.Require().NoError(yaml.Unmarshal(body, &config))
+	s.Require().Equal(map[interface{}]interface{}{
+		"type":       "kubernetes",
+		"namespace":  "apps",
+		"deployment": "servo",
+		"context":    "my-cluster",
+	}, config["profiles"].([]interface{})[0].(map[interface{}]interface{})["servo"])
+
+	// The attached context is used when driving the servo
+	s.SetCommand(command.NewRootCommand())
+	_, err = s.Execute("--config", configFile, "servo", "start")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"kubectl", "--context", "my-cluster", "-n", "apps", "scale", "--replicas=1", "deployments/servo"}, recorder.LastInvocation())
+}
+
+func (s *ServoTestSuite) TestRunningServoAttachFromContextOperatorManaged() {
+	recorder := test.NewExecRecorder()
+	recorder.Respond("kubectl config view", test.ExecResponse{Stdout: kubeconfigView})
+	recorder.Respond("kubectl --context my-cluster", test.ExecResponse{Stdout: "web-servo\tweb\n"})
+	command.SetCommandContextFunc(recorder.CommandContext)
+	configFile := servoConfigFile(nil)
+
+	output, err := s.Execute("--config", configFile, "servo", "attach", "--from-context", "my-cluster")
+	s.Require().NoError(err)
+	s.Require().Contains(output, `start and stop scale ServoDeployment "web"`)
+
+	var config = map[string]interface{}{}
+	body, _ := ioutil.ReadFile(configFile)
+	s.Require().NoError(yaml.Unmarshal(body, &config))
+	servo := config["profiles"].([]interface{})[0].(map[interface{}]interface{})["servo"].(map[interface{}]interface{})
+	s.Require().Equal("web-servo", servo["deployment"])
+	s.Require().Equal("web", servo["servo_deployment"])
+
+	// Operator managed servos are stopped via the ServoDeployment
+	s.SetCommand(command.NewRootCommand())
+	_, err = s.Execute("--config", configFile, "servo", "stop")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{
+		"kubectl", "--context", "my-cluster", "-n", "apps", "patch", "servodeployments.servo.opsani.com/web",
+		"--type", "merge", "-p", `{"spec":{"replicas":0}}`,
+	}, recorder.LastInvocation())
+}
+
+const discoveredServos = `{"items": [
+	{"metadata": {"name": "servo", "namespace": "apps", "annotations": {"servo.opsani.com/optimizer": "example.com/app"}}},
+	{"metadata": {"name": "legacy-servo", "namespace": "legacy"}, "spec": {"template": {"spec": {"containers": [
+		{"name": "servo", "env": [{"name": "OPSANI_OPTIMIZER", "value": "example.com/legacy"}]}
+	]}}}}
+]}`
+
+func (s *ServoTestSuite) TestRunningServoDiscover() {
+	recorder := test.NewExecRecorder()
+	recorder.Respond("kubectl config current-context", test.ExecResponse{Stdout: "my-cluster\n"})
+	recorder.Respond("kubectl --context my-cluster get deployments", test.ExecResponse{Stdout: discoveredServos})
+	command.SetCommandContextFunc(recorder.CommandContext)
+	configFile := servoConfigFile(nil)
+
+	output, err := s.Execute("--config", configFile, "--yes", "servo", "discover")
+	s.Require().NoError(err)
+	s.Require().Regexp(`namespaces/apps/deployments/servo\s+example.com/app\s+default`, output)
+	s.Require().Regexp(`namespaces/legacy/deployments/legacy-servo\s+example.com/legacy\s+-`, output)
+	s.Require().Contains(output, "Attached 1 discovered servos.")
+	s.Require().Equal([]string{
+		"kubectl", "--context", "my-cluster", "get", "deployments", "-l", "app.kubernetes.io/name=servo", "-o", "json", "--all-namespaces",
+	}, recorder.LastInvocation())
+
+	var config = map[string]interface{}{}
+	body, _ := ioutil.ReadFile(configFile)
+	s.Require().NoError(yaml.Unmarshal(body, &config))
+	s.Require().Equal(map[interface{}]interface{}{
+		"type":       "kubernetes",
+		"namespace":  "apps",
+		"deployment": "servo",
+		"context":    "my-cluster",
+	}, config["profiles"].([]interface{})[0].(map[interface{}]interface{})["servo"])
+
+	// Servos that are already attached are not offered again
+	s.SetCommand(command.NewRootCommand())
+	output, err = s.Execute("--config", configFile, "servo", "discover", "--context", "my-cluster")
+	s.Require().NoError(err)
+	s.Require().Regexp(`namespaces/apps/deployments/servo\s+example.com/app\s+default \(attached\)`, output)
+	s.Require().NotContains(output, "Attached")
+}
+
+func (s *ServoTestSuite) TestRunningServoDiscoverNamespaceFallback() {
+	recorder := test.NewExecRecorder()
+	recorder.Respond("kubectl --context my-cluster get deployments", test.ExecResponse{Stdout: `{"items": []}`})
+	recorder.Respond("kubectl --context my-cluster get deployments -l app.kubernetes.io/name=servo -o json --all-namespaces",
+		test.ExecResponse{Stderr: "forbidden", ExitCode: 1})
+	command.SetCommandContextFunc(recorder.CommandContext)
+
+	output, err := s.Execute("--config", servoConfigFile(nil), "servo", "discover", "--context", "my-cluster")
+	s.Require().NoError(err)
+	s.Require().Contains(output, `warning: unable to list deployments in all namespaces, searching the namespace of context "my-cluster"`)
+	s.Require().Contains(output, `No servo deployments labeled "app.kubernetes.io/name=servo" found.`)
+}
+
+func (s *ServoTestSuite) TestRunningServoAttachFromContextDefaultNamespace() {
+	recorder := test.NewExecRecorder()
+	recorder.Respond("kubectl config view", test.ExecResponse{Stdout: kubeconfigView})
+	recorder.Respond("kubectl --context bare", test.ExecResponse{Stdout: ""})
+	command.SetCommandContextFunc(recorder.CommandContext)
+	_, err := s.Execute("--config", servoConfigFile(nil), "servo", "attach", "--from-context", "bare")
+	s.Require().EqualError(err, `no servo deployment labeled "app.kubernetes.io/name=servo" found in namespace "default" of context "bare"`)
+}
+
+func (s *ServoTestSuite) TestRunningServoAttachFromContextAmbiguous() {
+	recorder := test.NewExecRecorder()
+	recorder.Respond("kubectl config view", test.ExecResponse{Stdout: kubeconfigView})
+	recorder.Respond("kubectl --context my-cluster", test.ExecResponse{Stdout: "servo\t\nservo-canary\t\n"})
+	command.SetCommandContextFunc(recorder.CommandContext)
+	_, err := s.Execute("--config", servoConfigFile(nil), "servo", "attach", "--from-context", "my-cluster", "-n", "other")
+	s.Require().EqualError(err, `found 2 servo deployments labeled "app.kubernetes.io/name=servo" in namespace "other" of context "my-cluster" (servo, servo-canary): narrow the match with --selector`)
+}
+
+func (s *ServoTestSuite) TestRunningServoAttachFromUnknownContext() {
+	recorder := test.NewExecRecorder()
+	recorder.Respond("kubectl config view", test.ExecResponse{Stdout: kubeconfigView})
+	command.SetCommandContextFunc(recorder.CommandContext)
+	_, err := s.Execute("--config", servoConfigFile(nil), "servo", "attach", "--from-context", "missing")
+	s.Require().EqualError(err, `kubeconfig context "missing" not found`)
+}
+
+func (s *ServoTestSuite) TestRunningServoLogsWithFilters() {
+	driver := test.NewFakeServoDriver()
+	command.SetServoDriverFactory(driver.Factory())
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "logs", "--level", "warning", "--connector", "prometheus")
+	s.Require().NoError(err)
+	s.Require().Equal(command.ServoLogsArgs{Lines: "25", Level: "warning", Connectors: []string{"prometheus"}}, driver.LastArgs())
+}
+
+func (s *ServoTestSuite) TestRunningServoLogsWithInvalidLevel() {
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "servo", "logs", "--level", "verbose")
+	s.Require().EqualError(err, `invalid log level "verbose": must be one of trace, debug, info, success, warning, error, critical`)
+}
+
+func TestDiagnoseCrashLoop(t *testing.T) {
+	for _, tc := range []struct {
+		reason, message, logs string
+		cause                 string
+	}{
+		{"Error", "", "opsani api error: 401 Unauthorized", "bad token"},
+		{"Error", "", "failed to query prometheus at http://prometheus:9090: connection refused", "unreachable Prometheus"},
+		{"Error", `deployments.apps "web" is forbidden: User "system:serviceaccount:opsani:servo" cannot patch resource "deployments"`, "", "RBAC denied"},
+		{"OOMKilled", "", "", "out of memory"},
+		{"Error", "", "panic: something unexpected", ""},
+	} {
+		cause := command.DiagnoseCrashLoop(tc.reason, tc.message, tc.logs)
+		if tc.cause == "" {
+			require.Empty(t, cause)
+		} else {
+			require.True(t, strings.HasPrefix(cause, tc.cause), cause)
+		}
+	}
+}
+
+func TestCrashLoopSummary(t *testing.T) {
+	loop := command.CrashLoop{
+		Pod: "servo-7d9f", Container: "servo", Restarts: 7, ExitCode: 1, Reason: "Error",
+		Message: "invalid token", Logs: "starting servo\nopsani api error: 401 Unauthorized\n",
+		Cause: command.DiagnoseCrashLoop("Error", "invalid token", ""),
+	}
+	summary := loop.Summary()
+	require.True(t, strings.HasPrefix(summary, "Container servo of pod servo-7d9f is in CrashLoopBackOff (7 restarts, last exit code 1: Error)\n"))
+	require.Contains(t, summary, "  Termination message: invalid token\n")
+	require.Contains(t, summary, "  Likely cause: bad token:")
+	require.Contains(t, summary, "  Last log lines:\n    starting servo\n    opsani api error: 401 Unauthorized\n")
+}
+
+func TestKubernetesServoStatusCapturesCrashLoopLogs(t *testing.T) {
+	recorder := test.NewExecRecorder()
+	recorder.Respond("kubectl -n opsani get deployment servo", test.ExecResponse{Stdout: `{"spec": {"selector": {"matchLabels": {"app.kubernetes.io/name": "servo"}}}}`})
+	recorder.Respond("kubectl -n opsani get pods", test.ExecResponse{Stdout: `{"items": [{"metadata": {"name": "servo-7d9f"}, "status": {"containerStatuses": [
+		{"name": "servo", "restartCount": 4, "state": {"waiting": {"reason": "CrashLoopBackOff"}}, "lastState": {"terminated": {"exitCode": 1, "reason": "Error"}}}
+	]}}]}`})
+	recorder.Respond("kubectl -n opsani logs servo-7d9f -c servo --previous", test.ExecResponse{Stdout: strings.Repeat("waiting for optimizer\n", 120)})
+	command.SetCommandContextFunc(recorder.CommandContext)
+	defer command.SetCommandContextFunc(nil)
+
+	driver, err := command.NewServoDriver(command.Servo{Type: "kubernetes", Namespace: "opsani", Deployment: "servo"}, 10*time.Second)
+	require.NoError(t, err)
+	require.NoError(t, driver.Status())
+
+	logs := [][]string{}
+	for _, invocation := range recorder.Invocations() {
+		if len(invocation) > 3 && invocation[3] == "logs" {
+			logs = append(logs, invocation)
+		}
+	}
+	require.Equal(t, [][]string{
+		{"kubectl", "-n", "opsani", "logs", "servo-7d9f", "-c", "servo", "--previous", "--tail=50"},
+		{"kubectl", "-n", "opsani", "logs", "servo-7d9f", "-c", "servo", "--previous", "--tail=100"},
+		{"kubectl", "-n", "opsani", "logs", "servo-7d9f", "-c", "servo", "--previous", "--tail=200"},
+	}, logs)
+	require.Contains(t, recorder.Invocations(), []string{"kubectl", "-n", "opsani", "get", "pods", "-l", "app.kubernetes.io/name=servo", "-o", "json"})
+}
+
+func TestServoShellCommand(t *testing.T) {
+	require.Equal(t, "docker-compose ps", command.ServoShellCommand("", "docker-compose", "ps"))
+	require.Equal(t,
+		`cd '/srv/my servo' && docker-compose exec -T servo servo check '--selector=a;rm -rf ~'`,
+		command.ServoShellCommand("/srv/my servo", "docker-compose", "exec", "-T", "servo", "servo", "check", "--selector=a;rm -rf ~"),
+	)
+	require.Equal(t, `cd '/srv/it'\''s' && cat config.yaml`, command.ServoShellCommand("/srv/it's", "cat", "config.yaml"))
+}
@@ -17,22 +17,21 @@
 package command
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"os/exec"
-	"os/signal"
-	"syscall"
 
 	"github.com/creack/pty"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
 // Shell establishes an interactive shell with the servo
+// Interactive shells are not subject to the timeout
 func (c *KubernetesServoDriver) Shell() error {
 	argsS := fmt.Sprintf("-n %v exec -it deployment/%v -- /bin/bash", c.servo.Namespace, c.servo.Deployment)
-	cmd := exec.Command("kubectl", ArgsS(argsS)...)
+	cmd := c.kubectl(context.Background(), ArgsS(argsS)...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
@@ -45,16 +44,14 @@ func (c *KubernetesServoDriver) Shell() error {
 	defer func() { _ = ptmx.Close() }() // Best effort.
 
 	// Handle pty size.
-	ch := make(chan os.Signal, 1)
-	signal.Notify(ch, syscall.SIGWINCH)
-	go func() {
-		for range ch {
-			if err := pty.InheritSize(os.Stdin, ptmx); err != nil {
-				log.Printf("error resizing pty: %s", err)
-			}
+	resize := func() {
+		if err := pty.InheritSize(os.Stdin, ptmx); err != nil {
+			log.Printf("error resizing pty: %s", err)
 		}
-	}()
-	ch <- syscall.SIGWINCH // Initial resize.
+	}
+	stopResize := NotifyTerminalResize(resize)
+	defer stopResize()
+	resize() // Initial resize.
 
 	// Set stdin in raw mode.
 	oldState, err := terminal.MakeRaw(int(os.Stdin.Fd()))
@@ -0,0 +1,254 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// DefaultSSHDialTimeout bounds establishing SSH connections when no timeout is configured
+const DefaultSSHDialTimeout = 30 * time.Second
+
+// SSHPasswordAttempts is the number of times a password is prompted for before authentication fails
+const SSHPasswordAttempts = 3
+
+// SSHPrompter prompts for credentials when agent authentication is unavailable or rejected
+type SSHPrompter interface {
+	AskOne(p survey.Prompt, response interface{}, opts ...survey.AskOpt) error
+}
+
+// sshAgent returns public key authentication via the SSH agent or nil when no agent is running
+func sshAgent() ssh.AuthMethod {
+	if sshAgent, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK")); err == nil {
+		return ssh.PublicKeysCallback(agent.NewClient(sshAgent).Signers)
+	}
+	return nil
+}
+
+// SSHAuthMethods returns the methods for authenticating as the user on the host
+// Keys held by the SSH agent are tried first, falling back to keyboard-interactive and password
+// prompts when a prompter is given
+func SSHAuthMethods(user, host string, prompter SSHPrompter) []ssh.AuthMethod {
+	methods := []ssh.AuthMethod{}
+	if agentAuth := sshAgent(); agentAuth != nil {
+		methods = append(methods, agentAuth)
+	}
+	if prompter == nil {
+		return methods
+	}
+
+	keyboardInteractive := ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		for i, question := range questions {
+			message := strings.TrimSpace(question)
+			if i == 0 && strings.TrimSpace(instruction) != "" {
+				message = strings.TrimSpace(instruction) + "\n" + message
+			}
+			var prompt survey.Prompt = &survey.Password{Message: message}
+			if echos[i] {
+				prompt = &survey.Input{Message: message}
+			}
+			if err := prompter.AskOne(prompt, &answers[i]); err != nil {
+				return nil, err
+			}
+		}
+		return answers, nil
+	})
+	password := ssh.PasswordCallback(func() (string, error) {
+		var password string
+		err := prompter.AskOne(&survey.Password{Message: fmt.Sprintf("Password for %s@%s:", user, host)}, &password)
+		return password, err
+	})
+	return append(methods,
+		ssh.RetryableAuthMethod(keyboardInteractive, SSHPasswordAttempts),
+		ssh.RetryableAuthMethod(password, SSHPasswordAttempts),
+	)
+}
+
+// sshDialTimeout returns the timeout for establishing SSH connections
+func sshDialTimeout(timeout time.Duration) time.Duration {
+	if timeout > 0 && timeout < DefaultSSHDialTimeout {
+		return timeout
+	}
+	return DefaultSSHDialTimeout
+}
+
+// SSHDeadline is a context bounding the establishment of SSH connections
+// Time spent prompting for credentials does not count towards the timeout so that users are not
+// disconnected while typing a password
+type SSHDeadline struct {
+	context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	timer     *time.Timer
+	remaining time.Duration
+	started   time.Time
+	prompts   int
+	expired   bool
+}
+
+// NewSSHDeadline returns a deadline that is done once the timeout has elapsed outside of prompts or the parent is done
+func NewSSHDeadline(parent context.Context, timeout time.Duration) *SSHDeadline {
+	ctx, cancel := context.WithCancel(parent)
+	d := &SSHDeadline{Context: ctx, cancel: cancel, remaining: timeout, started: time.Now()}
+	d.timer = time.AfterFunc(timeout, d.expire)
+	return d
+}
+
+// Err returns context.DeadlineExceeded once the timeout has elapsed
+func (d *SSHDeadline) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.expired {
+		return context.DeadlineExceeded
+	}
+	return d.Context.Err()
+}
+
+// Stop releases the resources of the deadline
+func (d *SSHDeadline) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.timer.Stop()
+	d.cancel()
+}
+
+// Prompter returns a prompter that pauses the deadline while prompting, or nil when the prompter is nil
+func (d *SSHDeadline) Prompter(prompter SSHPrompter) SSHPrompter {
+	if prompter == nil {
+		return nil
+	}
+	return &sshDeadlinePrompter{prompter: prompter, deadline: d}
+}
+
+func (d *SSHDeadline) expire() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.prompts == 0 {
+		d.expired = true
+		d.cancel()
+	}
+}
+
+func (d *SSHDeadline) pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prompts++
+	if d.prompts == 1 && d.timer.Stop() {
+		d.remaining -= time.Since(d.started)
+	}
+}
+
+func (d *SSHDeadline) resume() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prompts--
+	if d.prompts == 0 && !d.expired {
+		d.started = time.Now()
+		d.timer = time.AfterFunc(d.remaining, d.expire)
+	}
+}
+
+// sshDeadlinePrompter pauses a deadline for the duration of each prompt
+type sshDeadlinePrompter struct {
+	prompter SSHPrompter
+	deadline *SSHDeadline
+}
+
+func (p *sshDeadlinePrompter) AskOne(prompt survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+	p.deadline.pause()
+	defer p.deadline.resume()
+	return p.prompter.AskOne(prompt, response, opts...)
+}
+
+// DialSSH connects to the SSH server at addr over TCP
+// The dial and handshake are abandoned when the context is done
+func DialSSH(ctx context.Context, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	dialer := net.Dialer{Timeout: config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return newSSHClientContext(ctx, conn, addr, config)
+}
+
+// newSSHClientContext performs the SSH handshake on the connection, closing it if the context is done first
+func newSSHClientContext(ctx context.Context, conn net.Conn, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	type result struct {
+		client *ssh.Client
+		err    error
+	}
+	handshake := make(chan result, 1)
+	go func() {
+		ncc, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+		if err != nil {
+			handshake <- result{err: err}
+			return
+		}
+		handshake <- result{client: ssh.NewClient(ncc, chans, reqs)}
+	}()
+
+	select {
+	case r := <-handshake:
+		return r.client, r.err
+	case <-ctx.Done():
+		conn.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// contextWithInterrupt returns a context that is also cancelled when the process is interrupted
+func contextWithInterrupt(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(interrupt)
+	}()
+	return ctx, cancel
+}
+
+// sshSessionError reports a timed out or interrupted session in place of the error it caused
+func sshSessionError(ctx context.Context, timeout time.Duration, err error) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return fmt.Errorf("ssh session timed out after %s", timeout)
+	case context.Canceled:
+		return terminal.InterruptErr
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("ssh connection timed out after %s", sshDialTimeout(timeout))
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultSSHKeepAliveInterval is the interval between keep-alive pings on pooled SSH connections
+const DefaultSSHKeepAliveInterval = 15 * time.Second
+
+// SSHDialFunc establishes a new SSH connection
+// Any additional clients the connection depends on, such as a bastion, are returned for closing alongside it
+type SSHDialFunc func() (client *ssh.Client, dependents []*ssh.Client, err error)
+
+// SSHConnectionPool shares SSH connections across the sessions of a CLI invocation
+// Each connection multiplexes sessions and is pinged periodically so that dropped connections are evicted
+type SSHConnectionPool struct {
+	keepAlive   time.Duration
+	mu          sync.Mutex
+	connections map[string]*pooledSSHConnection
+}
+
+type pooledSSHConnection struct {
+	client     *ssh.Client
+	dependents []*ssh.Client
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// close closes the connection and the clients it depends on, innermost first
+func (c *pooledSSHConnection) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.client.Close()
+		for i := len(c.dependents) - 1; i >= 0; i-- {
+			c.dependents[i].Close()
+		}
+	})
+}
+
+// NewSSHConnectionPool returns an empty pool that pings connections at the keep-alive interval
+// A non-positive interval disables keep-alive pings
+func NewSSHConnectionPool(keepAlive time.Duration) *SSHConnectionPool {
+	return &SSHConnectionPool{
+		keepAlive:   keepAlive,
+		connections: map[string]*pooledSSHConnection{},
+	}
+}
+
+// Client returns the pooled connection for the key, dialing a new connection when none is open
+func (p *SSHConnectionPool) Client(key string, dial SSHDialFunc) (*ssh.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if conn, ok := p.connections[key]; ok {
+		select {
+		case <-conn.done:
+			delete(p.connections, key)
+		default:
+			return conn.client, nil
+		}
+	}
+
+	client, dependents, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	conn := &pooledSSHConnection{client: client, dependents: dependents, done: make(chan struct{})}
+	p.connections[key] = conn
+	go func() {
+		// Evict the connection as soon as it is dropped by the remote end
+		client.Wait()
+		p.evict(key, conn)
+	}()
+	if p.keepAlive > 0 {
+		go p.keepConnectionAlive(key, conn)
+	}
+	return client, nil
+}
+
+// Evict closes and removes the pooled connection for the key so that the next use redials
+func (p *SSHConnectionPool) Evict(key string) {
+	p.mu.Lock()
+	conn, ok := p.connections[key]
+	p.mu.Unlock()
+	if ok {
+		p.evict(key, conn)
+	}
+}
+
+// Close closes all pooled connections
+func (p *SSHConnectionPool) Close() {
+	p.mu.Lock()
+	connections := p.connections
+	p.connections = map[string]*pooledSSHConnection{}
+	p.mu.Unlock()
+	for _, conn := range connections {
+		conn.close()
+	}
+}
+
+func (p *SSHConnectionPool) evict(key string, conn *pooledSSHConnection) {
+	p.mu.Lock()
+	if p.connections[key] == conn {
+		delete(p.connections, key)
+	}
+	p.mu.Unlock()
+	conn.close()
+}
+
+// keepConnectionAlive pings the server until the connection is closed, evicting it when a ping fails
+func (p *SSHConnectionPool) keepConnectionAlive(key string, conn *pooledSSHConnection) {
+	ticker := time.NewTicker(p.keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-conn.done:
+			return
+		case <-ticker.C:
+			if _, _, err := conn.client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				p.evict(key, conn)
+				return
+			}
+		}
+	}
+}
+
+// sshConnections is shared by the servo drivers of an invocation and closed when it completes
+var sshConnections = NewSSHConnectionPool(DefaultSSHKeepAliveInterval)
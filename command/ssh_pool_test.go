@@ -0,0 +1,162 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshTestServer accepts SSH connections and sessions without authentication
+type sshTestServer struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+	mu       sync.Mutex
+	conns    []*ssh.ServerConn
+}
+
+func newSSHTestServer(t *testing.T) *sshTestServer {
+	return newSSHTestServerWithConfig(t, &ssh.ServerConfig{NoClientAuth: true})
+}
+
+func newSSHTestServerWithConfig(t *testing.T, config *ssh.ServerConfig) *sshTestServer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+	config.AddHostKey(signer)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &sshTestServer{listener: listener, config: config}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *sshTestServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			serverConn, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, serverConn)
+			s.mu.Unlock()
+			go ssh.DiscardRequests(reqs)
+			for newChannel := range chans {
+				channel, requests, err := newChannel.Accept()
+				if err != nil {
+					continue
+				}
+				go ssh.DiscardRequests(requests)
+				defer channel.Close()
+			}
+		}()
+	}
+}
+
+// connections returns the number of connections accepted
+func (s *sshTestServer) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// drop closes all open connections from the server end
+func (s *sshTestServer) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *sshTestServer) dial() (*ssh.Client, []*ssh.Client, error) {
+	client, err := ssh.Dial("tcp", s.listener.Addr().String(), &ssh.ClientConfig{
+		User:            "servo",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	return client, nil, err
+}
+
+func TestSSHConnectionPoolSharesConnections(t *testing.T) {
+	server := newSSHTestServer(t)
+	pool := command.NewSSHConnectionPool(0)
+	defer pool.Close()
+
+	for i := 0; i < 3; i++ {
+		client, err := pool.Client("servo@host", server.dial)
+		require.NoError(t, err)
+		session, err := client.NewSession()
+		require.NoError(t, err)
+		session.Close()
+	}
+	require.Equal(t, 1, server.connections())
+}
+
+func TestSSHConnectionPoolRedialsDroppedConnections(t *testing.T) {
+	server := newSSHTestServer(t)
+	pool := command.NewSSHConnectionPool(0)
+	defer pool.Close()
+
+	client, err := pool.Client("servo@host", server.dial)
+	require.NoError(t, err)
+	server.drop()
+	client.Wait()
+
+	require.Eventually(t, func() bool {
+		next, err := pool.Client("servo@host", server.dial)
+		return err == nil && next != client
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, 2, server.connections())
+}
+
+func TestSSHConnectionPoolKeepAlive(t *testing.T) {
+	server := newSSHTestServer(t)
+	pool := command.NewSSHConnectionPool(10 * time.Millisecond)
+	defer pool.Close()
+
+	client, err := pool.Client("servo@host", server.dial)
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	next, err := pool.Client("servo@host", server.dial)
+	require.NoError(t, err)
+	require.Same(t, client, next)
+	require.Equal(t, 1, server.connections())
+}
+
+func TestSSHConnectionPoolClose(t *testing.T) {
+	server := newSSHTestServer(t)
+	pool := command.NewSSHConnectionPool(0)
+
+	client, err := pool.Client("servo@host", server.dial)
+	require.NoError(t, err)
+	pool.Close()
+	_, err = client.NewSession()
+	require.Error(t, err)
+}
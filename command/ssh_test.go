@@ -0,0 +1,208 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestDialSSH(t *testing.T) {
+	server := newSSHTestServer(t)
+	client, err := command.DialSSH(context.Background(), server.listener.Addr().String(), &ssh.ClientConfig{
+		User:            "servo",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         time.Second,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	require.Equal(t, 1, server.connections())
+}
+
+func TestDialSSHAbandonsStalledHandshake(t *testing.T) {
+	// Accept connections without ever speaking SSH
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = command.DialSSH(ctx, listener.Addr().String(), &ssh.ClientConfig{
+		User:            "servo",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         time.Second,
+	})
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+// scriptedPrompter answers prompts in order, recording their messages
+type scriptedPrompter struct {
+	answers  []string
+	messages []string
+}
+
+func (p *scriptedPrompter) AskOne(prompt survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+	switch prompt := prompt.(type) {
+	case *survey.Password:
+		p.messages = append(p.messages, "password: "+prompt.Message)
+	case *survey.Input:
+		p.messages = append(p.messages, "input: "+prompt.Message)
+	}
+	if len(p.answers) == 0 {
+		return errors.New("no more answers")
+	}
+	*(response.(*string)), p.answers = p.answers[0], p.answers[1:]
+	return nil
+}
+
+func dialWithPrompter(t *testing.T, server *sshTestServer, prompter command.SSHPrompter) (*ssh.Client, error) {
+	sock, ok := os.LookupEnv("SSH_AUTH_SOCK")
+	os.Unsetenv("SSH_AUTH_SOCK")
+	if ok {
+		defer os.Setenv("SSH_AUTH_SOCK", sock)
+	}
+	return command.DialSSH(context.Background(), server.listener.Addr().String(), &ssh.ClientConfig{
+		User:            "servo",
+		Auth:            command.SSHAuthMethods("servo", "example.com", prompter),
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         time.Second,
+	})
+}
+
+func TestSSHPasswordAuthFallback(t *testing.T) {
+	server := newSSHTestServerWithConfig(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, errors.New("wrong password")
+		},
+	})
+	prompter := &scriptedPrompter{answers: []string{"wrong", "secret"}}
+	client, err := dialWithPrompter(t, server, prompter)
+	require.NoError(t, err)
+	client.Close()
+	require.Equal(t, []string{
+		"password: Password for servo@example.com:",
+		"password: Password for servo@example.com:",
+	}, prompter.messages)
+}
+
+func TestSSHKeyboardInteractiveAuthFallback(t *testing.T) {
+	server := newSSHTestServerWithConfig(t, &ssh.ServerConfig{
+		KeyboardInteractiveCallback: func(conn ssh.ConnMetadata, challenge ssh.KeyboardInteractiveChallenge) (*ssh.Permissions, error) {
+			answers, err := challenge("", "Two-factor login", []string{"Password: ", "Code: "}, []bool{false, true})
+			if err != nil {
+				return nil, err
+			}
+			if answers[0] == "secret" && answers[1] == "123456" {
+				return nil, nil
+			}
+			return nil, errors.New("access denied")
+		},
+	})
+	prompter := &scriptedPrompter{answers: []string{"secret", "123456"}}
+	client, err := dialWithPrompter(t, server, prompter)
+	require.NoError(t, err)
+	client.Close()
+	require.Equal(t, []string{
+		"password: Two-factor login\nPassword:",
+		"input: Code:",
+	}, prompter.messages)
+}
+
+// slowPrompter answers every prompt with a password after a delay
+type slowPrompter struct {
+	delay    time.Duration
+	password string
+}
+
+func (p *slowPrompter) AskOne(prompt survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+	time.Sleep(p.delay)
+	*(response.(*string)) = p.password
+	return nil
+}
+
+func TestSSHDeadlinePausesWhilePrompting(t *testing.T) {
+	server := newSSHTestServerWithConfig(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, errors.New("wrong password")
+		},
+	})
+	sock, ok := os.LookupEnv("SSH_AUTH_SOCK")
+	os.Unsetenv("SSH_AUTH_SOCK")
+	if ok {
+		defer os.Setenv("SSH_AUTH_SOCK", sock)
+	}
+
+	deadline := command.NewSSHDeadline(context.Background(), 200*time.Millisecond)
+	defer deadline.Stop()
+	prompter := deadline.Prompter(&slowPrompter{delay: 500 * time.Millisecond, password: "secret"})
+	client, err := command.DialSSH(deadline, server.listener.Addr().String(), &ssh.ClientConfig{
+		User:            "servo",
+		Auth:            command.SSHAuthMethods("servo", "example.com", prompter),
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         time.Second,
+	})
+	require.NoError(t, err)
+	client.Close()
+	require.NoError(t, deadline.Err())
+}
+
+func TestSSHDeadlineExpires(t *testing.T) {
+	deadline := command.NewSSHDeadline(context.Background(), 50*time.Millisecond)
+	defer deadline.Stop()
+	select {
+	case <-deadline.Done():
+	case <-time.After(time.Second):
+		t.Fatal("deadline did not expire")
+	}
+	require.Equal(t, context.DeadlineExceeded, deadline.Err())
+	require.Nil(t, deadline.Prompter(nil))
+}
+
+func TestSSHAuthWithoutPrompter(t *testing.T) {
+	server := newSSHTestServerWithConfig(t, &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			return nil, nil
+		},
+	})
+	_, err := dialWithPrompter(t, server, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to authenticate")
+}
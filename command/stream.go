@@ -0,0 +1,190 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// DefaultStreamLines is the number of trailing output lines displayed beneath a running task
+const DefaultStreamLines = 8
+
+// KeyTaskLogLimit is the config key for the number of task output logs retained
+const KeyTaskLogLimit = "history.task_logs"
+
+// DefaultTaskLogLimit is the number of task output logs retained when no limit is configured
+const DefaultTaskLogLimit = 50
+
+// OutputStream is an io.Writer that renders subprocess output as an indented log group
+// beneath the current task while recording the complete output to a log
+//
+// On a terminal the group is a scrolling region displaying the most recent lines that is
+// collapsed once the task succeeds. Elsewhere every line is written with the prefix.
+type OutputStream struct {
+	out         io.Writer
+	log         io.Writer
+	prefix      string
+	maxLines    int
+	interactive bool
+
+	mu       sync.Mutex
+	partial  []byte
+	lines    []string
+	rendered int
+}
+
+// NewOutputStream returns a stream writing prefixed output to out and recording it to log
+// Scrolling and collapsing are enabled when interactive is true
+func NewOutputStream(out io.Writer, log io.Writer, interactive bool) *OutputStream {
+	if log == nil {
+		log = ioutil.Discard
+	}
+	return &OutputStream{
+		out:         out,
+		log:         log,
+		prefix:      color.New(color.Faint).Sprint("  " + outputGlyph(glyphGutter) + " "),
+		maxLines:    DefaultStreamLines,
+		interactive: interactive,
+	}
+}
+
+// Write records the output and renders any complete lines
+func (s *OutputStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.log.Write(p); err != nil {
+		return 0, err
+	}
+	s.partial = append(s.partial, p...)
+	for {
+		i := bytes.IndexAny(s.partial, "\r\n")
+		if i < 0 {
+			break
+		}
+		line := string(s.partial[:i])
+		s.partial = s.partial[i+1:]
+		s.appendLine(line)
+	}
+	return len(p), nil
+}
+
+// Close flushes buffered output and finishes the log group
+// Successful groups are collapsed on a terminal while failures leave the output visible
+func (s *OutputStream) Close(success bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.partial) > 0 {
+		s.appendLine(string(s.partial))
+		s.partial = nil
+	}
+	if s.interactive && success {
+		s.erase()
+	}
+}
+
+var ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+func (s *OutputStream) appendLine(line string) {
+	line = strings.TrimRight(ansiEscapePattern.ReplaceAllString(line, ""), " \t")
+	if line == "" {
+		return
+	}
+	if !s.interactive {
+		fmt.Fprintf(s.out, "%s%s\n", s.prefix, line)
+		return
+	}
+	s.lines = append(s.lines, line)
+	if len(s.lines) > s.maxLines {
+		s.lines = s.lines[len(s.lines)-s.maxLines:]
+	}
+	s.erase()
+	for _, l := range s.lines {
+		fmt.Fprintf(s.out, "%s%s\n", s.prefix, l)
+	}
+	s.rendered = len(s.lines)
+}
+
+// erase moves the cursor to the top of the rendered region and clears to the end of the screen
+func (s *OutputStream) erase() {
+	if s.rendered > 0 {
+		fmt.Fprintf(s.out, "\x1b[%dA\x1b[J", s.rendered)
+		s.rendered = 0
+	}
+}
+
+// TaskLogsPath returns the directory that task output logs are recorded into
+func (baseCmd *BaseCommand) TaskLogsPath() string {
+	return filepath.Join(baseCmd.DefaultConfigPath(), "logs")
+}
+
+// taskLogLimit returns the number of task output logs to retain
+func (baseCmd *BaseCommand) taskLogLimit() int {
+	if baseCmd.viperCfg.IsSet(KeyTaskLogLimit) {
+		return baseCmd.viperCfg.GetInt(KeyTaskLogLimit)
+	}
+	return DefaultTaskLogLimit
+}
+
+// CreateTaskLog creates a timestamped log file for recording the output of a task
+// The oldest logs are removed once the task log limit is reached
+func (baseCmd *BaseCommand) CreateTaskLog(now time.Time) (*os.File, error) {
+	limit := baseCmd.taskLogLimit()
+	if limit <= 0 {
+		return nil, fmt.Errorf("task logs are disabled")
+	}
+	dir := baseCmd.TaskLogsPath()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	filename := filepath.Join(dir, fmt.Sprintf("task-%s.log", now.Format("20060102-150405.000")))
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, _ := filepath.Glob(filepath.Join(dir, "task-*.log"))
+	sort.Strings(logs)
+	for len(logs) > limit {
+		if logs[0] != filename {
+			os.Remove(logs[0])
+		}
+		logs = logs[1:]
+	}
+	return file, nil
+}
+
+// newTaskOutputStream returns a stream for displaying task output and the log file recording it
+// Output is displayed without recording if the log file cannot be created
+func (vitalCommand *vitalCommand) newTaskOutputStream() (*OutputStream, *os.File) {
+	w := vitalCommand.OutOrStdout()
+	interactive := vitalCommand.Capabilities().Animation
+	logFile, err := vitalCommand.CreateTaskLog(time.Now())
+	if err != nil {
+		return NewOutputStream(w, nil, interactive), nil
+	}
+	return NewOutputStream(w, logFile, interactive), logFile
+}
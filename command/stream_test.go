@@ -0,0 +1,91 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputStreamPrefixesLines(t *testing.T) {
+	out, log := new(bytes.Buffer), new(bytes.Buffer)
+	stream := command.NewOutputStream(out, log, false)
+	fmt.Fprint(stream, "Starting control plane\nPulling images")
+	fmt.Fprint(stream, "...\n\x1b[32mDone!\x1b[0m")
+	stream.Close(true)
+	require.Equal(t, "  │ Starting control plane\n  │ Pulling images...\n  │ Done!\n", out.String())
+	require.Equal(t, "Starting control plane\nPulling images...\n\x1b[32mDone!\x1b[0m", log.String())
+}
+
+func TestOutputStreamScrollsAndCollapses(t *testing.T) {
+	out := new(bytes.Buffer)
+	stream := command.NewOutputStream(out, nil, true)
+	for i := 1; i <= command.DefaultStreamLines+2; i++ {
+		fmt.Fprintf(stream, "line %d\n", i)
+	}
+	rendered := out.String()
+	last := rendered[strings.LastIndex(rendered, "\x1b[J")+len("\x1b[J"):]
+	require.Equal(t, command.DefaultStreamLines, strings.Count(last, "\n"))
+	require.True(t, strings.HasPrefix(last, "  │ line 3\n"))
+
+	stream.Close(true)
+	require.True(t, strings.HasSuffix(out.String(), fmt.Sprintf("\x1b[%dA\x1b[J", command.DefaultStreamLines)))
+}
+
+func TestOutputStreamFailureLeavesOutput(t *testing.T) {
+	out := new(bytes.Buffer)
+	stream := command.NewOutputStream(out, nil, true)
+	fmt.Fprint(stream, "error: connection refused\n")
+	stream.Close(false)
+	require.True(t, strings.HasSuffix(out.String(), "  │ error: connection refused\n"))
+}
+
+func TestCreateTaskLogKeepsNewestLogs(t *testing.T) {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{{"name": "default", "optimizer": "example.com/app", "token": "123456"}},
+		"history":  map[string]int{"task_logs": 3},
+	})
+	defer os.Remove(configFile.Name())
+	rootCmd := command.NewRootCommand()
+	_, err := test.NewCommandExecutor(rootCmd.RootCobraCommand()).Execute("--config", configFile.Name(), "profile", "list")
+	require.NoError(t, err)
+
+	dir := rootCmd.TaskLogsPath()
+	require.NoError(t, os.RemoveAll(dir))
+	defer os.RemoveAll(dir)
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		file, err := rootCmd.CreateTaskLog(start.Add(time.Duration(i) * time.Minute))
+		require.NoError(t, err)
+		file.Close()
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	require.Equal(t, []string{"task-20200601-120200.000.log", "task-20200601-120300.000.log", "task-20200601-120400.000.log"}, names)
+}
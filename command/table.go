@@ -0,0 +1,253 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// Output formats supported by commands that render tabular data
+const (
+	OutputTable    = "table"
+	OutputJSON     = "json"
+	OutputYAML     = "yaml"
+	OutputCSV      = "csv"
+	OutputMarkdown = "markdown"
+)
+
+// TabularOutputFormats are the output formats supported by every table
+var TabularOutputFormats = []string{OutputTable, OutputJSON, OutputYAML, OutputCSV, OutputMarkdown}
+
+// Table is tabular data that can be rendered in any of the tabular output formats
+type Table struct {
+	Headers []string
+	Rows    [][]string
+
+	// HideHeaders omits the header row from plain table output
+	HideHeaders bool
+}
+
+// AddOutputFlag registers the --output flag for choosing between the given formats
+// The first format is the default
+func AddOutputFlag(cmd *cobra.Command, formats ...string) {
+	cmd.Flags().StringP("output", "o", formats[0], fmt.Sprintf("Output format: {%s}", strings.Join(formats, "|")))
+}
+
+// OutputFormat returns the value of the --output flag, validated against the given formats
+func OutputFormat(cmd *cobra.Command, formats ...string) (string, error) {
+	output, _ := cmd.Flags().GetString("output")
+	for _, format := range formats {
+		if output == format {
+			return output, nil
+		}
+	}
+	return "", fmt.Errorf("invalid output format %q: must be one of %s", output, strings.Join(formats, ", "))
+}
+
+// RenderTable writes the table to the command output in the given format
+// JSON and YAML render each row as an object keyed by the snake cased column headers
+func (baseCmd *BaseCommand) RenderTable(format string, table Table) error {
+	switch format {
+	case OutputJSON:
+		return baseCmd.PrettyPrintJSONObject(table.objects())
+	case OutputYAML:
+		return baseCmd.PrettyPrintYAMLObject(table.objects())
+	case OutputCSV:
+		return writeCSVTable(baseCmd.OutOrStdout(), table)
+	case OutputMarkdown:
+		return writeMarkdownTable(baseCmd.OutOrStdout(), table)
+	case OutputTable:
+		writer := baseCmd.newPlainTable()
+		if !table.HideHeaders {
+			writer.SetHeader(table.Headers)
+		}
+		writer.AppendBulk(table.Rows)
+		writer.Render()
+		return nil
+	}
+	return fmt.Errorf("invalid output format %q: must be one of %s", format, strings.Join(TabularOutputFormats, ", "))
+}
+
+var nonIdentifierPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// objects returns the rows keyed by the snake cased column headers
+func (t Table) objects() []map[string]string {
+	keys := make([]string, len(t.Headers))
+	for i, header := range t.Headers {
+		keys[i] = strings.Trim(nonIdentifierPattern.ReplaceAllString(strings.ToLower(header), "_"), "_")
+	}
+	objects := []map[string]string{}
+	for _, row := range t.Rows {
+		object := map[string]string{}
+		for i, value := range row {
+			if i < len(keys) {
+				object[keys[i]] = value
+			}
+		}
+		objects = append(objects, object)
+	}
+	return objects
+}
+
+func writeCSVTable(w io.Writer, table Table) error {
+	writer := csv.NewWriter(w)
+	writer.Write(table.Headers)
+	writer.WriteAll(table.Rows)
+	return writer.Error()
+}
+
+func writeMarkdownTable(w io.Writer, table Table) error {
+	row := func(values []string) string {
+		cells := make([]string, len(table.Headers))
+		for i := range cells {
+			if i < len(values) {
+				cells[i] = strings.ReplaceAll(strings.ReplaceAll(values[i], "|", `\|`), "\n", " ")
+			}
+		}
+		return "| " + strings.Join(cells, " | ") + " |\n"
+	}
+	separators := make([]string, len(table.Headers))
+	for i := range separators {
+		separators[i] = "---"
+	}
+	var b strings.Builder
+	b.WriteString(row(table.Headers))
+	b.WriteString("|" + strings.Join(separators, "|") + "|\n")
+	for _, values := range table.Rows {
+		b.WriteString(row(values))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// minPlainTableColumnWidth is the narrowest that columns of plain tables are wrapped to
+const minPlainTableColumnWidth = 12
+
+// plainTableTabWidth is the tab stop width assumed when measuring tab padded tables
+const plainTableTabWidth = 8
+
+// plainTable is a borderless, tab padded table that wraps long cells to fit the output width
+// Rows are buffered until rendering because tablewriter measures cells as they are added
+type plainTable struct {
+	*tablewriter.Table
+	width   int
+	headers []string
+	rows    [][]string
+}
+
+// newPlainTable returns a borderless, tab padded table in the style of `opsani profile list`
+func (baseCmd *BaseCommand) newPlainTable() *plainTable {
+	return newPlainTable(baseCmd.OutOrStdout(), baseCmd.Capabilities().Width)
+}
+
+// newPlainTable returns a plain table written to w that is wrapped to width columns, or never wrapped if width is zero
+func newPlainTable(w io.Writer, width int) *plainTable {
+	table := tablewriter.NewWriter(w)
+	table.SetAutoWrapText(false)
+	table.SetAutoFormatHeaders(true)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("")
+	table.SetColumnSeparator("")
+	table.SetRowSeparator("")
+	table.SetHeaderLine(false)
+	table.SetBorder(false)
+	table.SetTablePadding("\t") // pad with tabs
+	table.SetNoWhiteSpace(true)
+	return &plainTable{Table: table, width: width}
+}
+
+// SetHeader sets the header row
+func (t *plainTable) SetHeader(keys []string) {
+	t.headers = keys
+}
+
+// Append adds a row
+func (t *plainTable) Append(row []string) {
+	t.rows = append(t.rows, row)
+}
+
+// AppendBulk adds rows
+func (t *plainTable) AppendBulk(rows [][]string) {
+	t.rows = append(t.rows, rows...)
+}
+
+// Render writes the table, wrapping cells at word boundaries if the table is wider than the output
+func (t *plainTable) Render() {
+	if limit := plainTableColumnLimit(append([][]string{t.headers}, t.rows...), t.width); limit > 0 {
+		t.SetAutoWrapText(true)
+		t.SetColWidth(limit)
+	}
+	if t.headers != nil {
+		t.Table.SetHeader(t.headers)
+	}
+	t.Table.AppendBulk(t.rows)
+	t.Table.Render()
+}
+
+// plainTableColumnLimit returns the widest column width that fits the rows within width columns
+// Zero is returned if the rows fit without wrapping or width is zero
+func plainTableColumnLimit(rows [][]string, width int) int {
+	natural := []int{}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(natural) {
+				natural = append(natural, 0)
+			}
+			for _, line := range strings.Split(cell, "\n") {
+				if w := tablewriter.DisplayWidth(line); w > natural[i] {
+					natural[i] = w
+				}
+			}
+		}
+	}
+	if width <= 0 || plainTableWidth(natural, 0) <= width {
+		return 0
+	}
+	widest := 0
+	for _, w := range natural {
+		if w > widest {
+			widest = w
+		}
+	}
+	for limit := widest - 1; limit > minPlainTableColumnWidth; limit-- {
+		if plainTableWidth(natural, limit) <= width {
+			return limit
+		}
+	}
+	return minPlainTableColumnWidth
+}
+
+// plainTableWidth returns the rendered width of tab padded columns, each capped at limit unless limit is zero
+func plainTableWidth(columns []int, limit int) int {
+	position := 0
+	for i, w := range columns {
+		if limit > 0 && w > limit {
+			w = limit
+		}
+		position += w
+		if i < len(columns)-1 {
+			position = (position/plainTableTabWidth + 1) * plainTableTabWidth
+		}
+	}
+	return position
+}
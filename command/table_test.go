@@ -0,0 +1,157 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+)
+
+type TableOutputTestSuite struct {
+	test.Suite
+}
+
+func TestTableOutputTestSuite(t *testing.T) {
+	suite.Run(t, new(TableOutputTestSuite))
+}
+
+func (s *TableOutputTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+}
+
+func (s *TableOutputTestSuite) profilesConfigFile() string {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]interface{}{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+				"servo": map[string]string{
+					"type":       "kubernetes",
+					"namespace":  "opsani",
+					"deployment": "servo",
+				},
+			},
+			{
+				"name":      "staging",
+				"optimizer": "example.com/a|b",
+				"token":     "abcdef",
+			},
+		},
+	})
+	return configFile.Name()
+}
+
+func (s *TableOutputTestSuite) TestProfileListCSV() {
+	output, err := s.Execute("--config", s.profilesConfigFile(), "profile", "list", "-o", "csv")
+	s.Require().NoError(err)
+	s.Require().Equal(`NAME,OPTIMIZER,TOKEN,SERVO
+default,example.com/app,********,namespaces/opsani/deployments/servo
+staging,example.com/a|b,********,
+`, output)
+}
+
+func (s *TableOutputTestSuite) TestProfileListMarkdown() {
+	output, err := s.Execute("--config", s.profilesConfigFile(), "profile", "list", "--output", "markdown")
+	s.Require().NoError(err)
+	s.Require().Equal(`| NAME | OPTIMIZER | TOKEN | SERVO |
+|---|---|---|---|
+| default | example.com/app | ******** | namespaces/opsani/deployments/servo |
+| staging | example.com/a\|b | ******** |  |
+`, output)
+}
+
+func (s *TableOutputTestSuite) TestProfileListJSON() {
+	output, err := s.Execute("--config", s.profilesConfigFile(), "profile", "list", "-o", "json")
+	s.Require().NoError(err)
+	s.Require().Contains(output, `"optimizer": "example.com/app"`)
+	s.Require().Contains(output, `"servo": "namespaces/opsani/deployments/servo"`)
+	s.Require().NotContains(output, "123456")
+}
+
+func (s *TableOutputTestSuite) TestServoListYAML() {
+	output, err := s.Execute("--config", s.profilesConfigFile(), "--no-colors", "servo", "list", "-v", "-o", "yaml")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "deployment: servo")
+	s.Require().Contains(output, "namespace: opsani")
+}
+
+func (s *TableOutputTestSuite) TestServoListMarkdown() {
+	output, err := s.Execute("--config", s.profilesConfigFile(), "servo", "list", "-o", "markdown")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "| NAME | TYPE | SERVO |\n")
+	s.Require().Contains(output, "| default | kubernetes | namespaces/opsani/deployments/servo |\n")
+}
+
+func (s *TableOutputTestSuite) TestInvalidOutputFormat() {
+	_, err := s.Execute("--config", s.profilesConfigFile(), "profile", "list", "-o", "xml")
+	s.Require().EqualError(err, `invalid output format "xml": must be one of table, json, yaml, csv, markdown`)
+}
+
+// expandedLineWidths returns the display width of each line with tabs expanded to 8 column stops
+func expandedLineWidths(output string) []int {
+	widths := []int{}
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		width := 0
+		for _, r := range line {
+			if r == '\t' {
+				width = (width/8 + 1) * 8
+			} else {
+				width++
+			}
+		}
+		widths = append(widths, width)
+	}
+	return widths
+}
+
+func (s *TableOutputTestSuite) aliasesConfigFile() string {
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"aliases": map[string]string{
+			"tail":   "servo logs --follow --timestamps --lines 100 --level warning --connector kubernetes",
+			"status": "optimizer status",
+		},
+	})
+	return configFile.Name()
+}
+
+func (s *TableOutputTestSuite) TestTableFitsWidth() {
+	output, err := s.Execute("--config", s.aliasesConfigFile(), "--width", "48", "alias", "list")
+	s.Require().NoError(err)
+	widths := expandedLineWidths(output)
+	s.Require().Greater(len(widths), 3, "long cells are wrapped onto additional lines")
+	for _, width := range widths {
+		s.Require().LessOrEqual(width, 48)
+	}
+	for _, word := range strings.Fields("servo logs --follow --timestamps --lines 100 --level warning --connector kubernetes") {
+		s.Require().Contains(output, word)
+	}
+}
+
+func (s *TableOutputTestSuite) TestTableIsNotWrappedWithoutWidth() {
+	output, err := s.Execute("--config", s.aliasesConfigFile(), "alias", "list")
+	s.Require().NoError(err)
+	s.Require().Len(expandedLineWidths(output), 3)
+	s.Require().Contains(output, "servo logs --follow --timestamps --lines 100 --level warning --connector kubernetes")
+}
+
+func (s *TableOutputTestSuite) TestInvalidWidth() {
+	_, err := s.Execute("--config", s.aliasesConfigFile(), "--width", "-1", "alias", "list")
+	s.Require().EqualError(err, "invalid width -1: must be a positive number of columns")
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !windows
+
+package command_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyTerminalResize(t *testing.T) {
+	resized := make(chan struct{}, 1)
+	stop := command.NotifyTerminalResize(func() {
+		select {
+		case resized <- struct{}{}:
+		default:
+		}
+	})
+	defer stop()
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGWINCH))
+	select {
+	case <-resized:
+	case <-time.After(5 * time.Second):
+		t.Fatal("resize callback was not invoked")
+	}
+}
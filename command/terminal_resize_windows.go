@@ -0,0 +1,51 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build windows
+
+package command
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// terminalResizePollInterval is the interval between checks of the console size
+const terminalResizePollInterval = 250 * time.Millisecond
+
+// NotifyTerminalResize calls onResize each time the terminal window is resized until stop is called
+// Windows has no resize signal so the console size is polled
+func NotifyTerminalResize(onResize func()) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		fd := int(os.Stdout.Fd())
+		w, h, _ := terminal.GetSize(fd)
+		ticker := time.NewTicker(terminalResizePollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if nw, nh, err := terminal.GetSize(fd); err == nil && (nw != w || nh != h) {
+					w, h = nw, nh
+					onResize()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
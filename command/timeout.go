@@ -0,0 +1,77 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// KeyTimeout is the flag for limiting the duration of external process and API operations
+const KeyTimeout = "timeout"
+
+// KeyProvisionTimeout is the flag for limiting the duration of provisioning the Ignite cluster
+// Provisioning is exempt from --timeout, which is sized for API requests rather than starting clusters and pulling images
+const KeyProvisionTimeout = "provision-timeout"
+
+// DefaultProvisionTimeout is the default duration of provisioning steps such as starting the cluster and pulling images
+const DefaultProvisionTimeout = 15 * time.Minute
+
+// Timeout returns the maximum duration of external process and API operations
+// The timeout is determined by flag, the OPSANI_TIMEOUT environment variable, or the active profile
+// A zero duration indicates that operations will not time out
+func (baseCmd *BaseCommand) Timeout() time.Duration {
+	if flag := baseCmd.PersistentFlags().Lookup(KeyTimeout); flag != nil && flag.Changed {
+		timeout, _ := baseCmd.PersistentFlags().GetDuration(KeyTimeout)
+		return timeout
+	}
+	if value, ok := os.LookupEnv("OPSANI_TIMEOUT"); ok {
+		if timeout, err := time.ParseDuration(value); err == nil {
+			return timeout
+		}
+	}
+	if baseCmd.profile != nil && baseCmd.profile.Timeout != "" {
+		if timeout, err := time.ParseDuration(baseCmd.profile.Timeout); err == nil {
+			return timeout
+		}
+	}
+	return 0
+}
+
+// ContextWithTimeout returns a context that is cancelled when the configured timeout elapses
+func (baseCmd *BaseCommand) ContextWithTimeout() (context.Context, context.CancelFunc) {
+	return contextWithTimeout(baseCmd.Timeout())
+}
+
+// contextWithTimeout returns a context that expires after the timeout or a cancellable context for a zero timeout
+func contextWithTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// AddProvisionTimeoutFlag registers the --provision-timeout flag on the given command and its subcommands
+func AddProvisionTimeoutFlag(cmd *cobra.Command, timeout *time.Duration) {
+	cmd.PersistentFlags().DurationVar(timeout, KeyProvisionTimeout, DefaultProvisionTimeout, "Maximum duration of provisioning steps such as starting the cluster, pulling images, and applying manifests")
+}
+
+// ProvisionContext returns a context that is cancelled when the provisioning timeout elapses
+func (vitalCommand *vitalCommand) ProvisionContext() (context.Context, context.CancelFunc) {
+	return contextWithTimeout(vitalCommand.provisionTimeout)
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/suite"
+)
+
+type TimeoutTestSuite struct {
+	test.Suite
+	rootCmd *command.BaseCommand
+}
+
+func TestTimeoutTestSuite(t *testing.T) {
+	suite.Run(t, new(TimeoutTestSuite))
+}
+
+func (s *TimeoutTestSuite) SetupTest() {
+	os.Unsetenv("OPSANI_TIMEOUT")
+	s.rootCmd = command.NewRootCommand()
+	s.SetCommand(s.rootCmd)
+}
+
+func (s *TimeoutTestSuite) configFile(timeout string) *os.File {
+	return test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{
+				"name":      "default",
+				"optimizer": "example.com/app",
+				"token":     "123456",
+				"timeout":   timeout,
+			},
+		},
+	})
+}
+
+func (s *TimeoutTestSuite) TestTimeoutDefaultsToNone() {
+	s.Require().Equal(time.Duration(0), s.rootCmd.Timeout())
+}
+
+func (s *TimeoutTestSuite) TestTimeoutFromProfile() {
+	_, err := s.Execute("--config", s.configFile("45s").Name(), "config")
+	s.Require().NoError(err)
+	s.Require().Equal(45*time.Second, s.rootCmd.Timeout())
+}
+
+func (s *TimeoutTestSuite) TestTimeoutFromEnvOverridesProfile() {
+	os.Setenv("OPSANI_TIMEOUT", "1m")
+	defer os.Unsetenv("OPSANI_TIMEOUT")
+	_, err := s.Execute("--config", s.configFile("45s").Name(), "config")
+	s.Require().NoError(err)
+	s.Require().Equal(time.Minute, s.rootCmd.Timeout())
+}
+
+func (s *TimeoutTestSuite) TestTimeoutFromFlagOverridesProfile() {
+	_, err := s.Execute("--config", s.configFile("45s").Name(), "--timeout", "10s", "config")
+	s.Require().NoError(err)
+	s.Require().Equal(10*time.Second, s.rootCmd.Timeout())
+}
+
+func (s *TimeoutTestSuite) TestInvalidProfileTimeout() {
+	_, err := s.Execute("--config", s.configFile("soon").Name(), "config")
+	s.Require().EqualError(err, `invalid timeout "soon" for profile "default": time: invalid duration "soon"`)
+}
@@ -0,0 +1,268 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/rest"
+)
+
+// Developer flags for diagnosing slow commands
+const (
+	KeyTiming      = "timing"
+	KeyProfileCPU  = "profile-cpu"
+	KeyProfileHeap = "profile-heap"
+)
+
+// Phases of command execution reported by --timing
+const (
+	TimingPhaseConfig    = "config load"
+	TimingPhaseAPI       = "API calls"
+	TimingPhaseProcesses = "external processes"
+)
+
+var timingPhases = []string{TimingPhaseConfig, TimingPhaseAPI, TimingPhaseProcesses}
+
+// maxTimingSpanName is the length at which span names are truncated in the timing report
+const maxTimingSpanName = 72
+
+// Timings records the duration of the phases of a command execution
+// A nil Timings is valid and records nothing
+type Timings struct {
+	startedAt time.Time
+
+	mu    sync.Mutex
+	spans []timingSpan
+}
+
+type timingSpan struct {
+	phase    string
+	name     string
+	duration time.Duration
+}
+
+// timings records the current execution when --timing is given
+// It is package level so that external commands created outside of a command can be timed
+var timings *Timings
+
+// NewTimings returns a new Timings measuring the total duration from now
+func NewTimings() *Timings {
+	return &Timings{startedAt: time.Now()}
+}
+
+// Record adds a span of the given duration to a phase
+func (t *Timings) Record(phase, name string, duration time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = append(t.spans, timingSpan{phase: phase, name: name, duration: duration})
+}
+
+// Track starts a span of a phase, returning a function that records it when called
+func (t *Timings) Track(phase, name string) func() {
+	if t == nil {
+		return func() {}
+	}
+	startedAt := time.Now()
+	return func() {
+		t.Record(phase, name, time.Since(startedAt))
+	}
+}
+
+// Report writes a summary of the duration of each phase and its spans
+func (t *Timings) Report(w io.Writer) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "Timing:")
+	for _, phase := range timingPhases {
+		var total time.Duration
+		spans := []timingSpan{}
+		for _, span := range t.spans {
+			if span.phase == phase {
+				total += span.duration
+				spans = append(spans, span)
+			}
+		}
+		if phase == TimingPhaseConfig || len(spans) == 0 {
+			fmt.Fprintf(tw, "  %s\t%s\n", phase, formatTimingDuration(total))
+			continue
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t(%d)\n", phase, formatTimingDuration(total), len(spans))
+		for _, span := range spans {
+			fmt.Fprintf(tw, "    %s\t%s\n", truncateTimingSpanName(span.name), formatTimingDuration(span.duration))
+		}
+	}
+	fmt.Fprintf(tw, "  total\t%s\n", formatTimingDuration(time.Since(t.startedAt)))
+	tw.Flush()
+}
+
+func formatTimingDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+func truncateTimingSpanName(name string) string {
+	if len(name) > maxTimingSpanName {
+		return name[:maxTimingSpanName-3] + "..."
+	}
+	return name
+}
+
+// trackProcess starts timing an external command, returning a function to call once it has exited
+func trackProcess(cmd *exec.Cmd) func() {
+	if timings == nil {
+		return func() {}
+	}
+	return timings.Track(TimingPhaseProcesses, strings.Join(cmd.Args, " "))
+}
+
+// runCommand runs an external command, timing it when --timing is given
+// Commands that are not installed fail with install instructions
+func runCommand(cmd *exec.Cmd) error {
+	defer trackProcess(cmd)()
+	return missingToolError(cmd.Run())
+}
+
+// commandOutput runs an external command and returns its standard output, timing it when --timing is given
+func commandOutput(cmd *exec.Cmd) ([]byte, error) {
+	defer trackProcess(cmd)()
+	output, err := cmd.Output()
+	return output, missingToolError(err)
+}
+
+// commandCombinedOutput runs an external command and returns its combined standard output and
+// standard error, timing it when --timing is given
+func commandCombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	defer trackProcess(cmd)()
+	output, err := cmd.CombinedOutput()
+	return output, missingToolError(err)
+}
+
+// timedTransport records the duration of API requests
+type timedTransport struct {
+	timings   *Timings
+	transport http.RoundTripper
+}
+
+func (t *timedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	defer t.timings.Track(TimingPhaseAPI, req.Method+" "+req.URL.Path)()
+	return t.transport.RoundTrip(req)
+}
+
+// timeHTTPClient records the duration of requests made by an HTTP client when --timing is given
+func timeHTTPClient(hc *http.Client) {
+	if timings == nil {
+		return
+	}
+	transport := hc.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	hc.Transport = &timedTransport{timings: timings, transport: transport}
+}
+
+// timeHTTPClientConfig records the duration of requests made by Kubernetes clients when --timing is given
+func timeHTTPClientConfig(config *rest.Config) {
+	if timings == nil {
+		return
+	}
+	t := timings
+	config.WrapTransport = func(transport http.RoundTripper) http.RoundTripper {
+		return &timedTransport{timings: t, transport: transport}
+	}
+}
+
+// startProfiling begins recording timings and profiles requested by the developer flags
+func (baseCmd *BaseCommand) startProfiling() error {
+	if baseCmd.profiling {
+		return nil
+	}
+	baseCmd.profiling = true
+
+	if enabled, _ := baseCmd.PersistentFlags().GetBool(KeyTiming); enabled {
+		timings = NewTimings()
+	}
+	if path, _ := baseCmd.PersistentFlags().GetString(KeyProfileCPU); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed creating CPU profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return fmt.Errorf("failed starting CPU profile: %w", err)
+		}
+		baseCmd.cpuProfile = f
+	}
+	return nil
+}
+
+// finishProfiling writes the timings and profiles requested by the developer flags
+// It is safe to call more than once, doing nothing after the first call
+func (baseCmd *BaseCommand) finishProfiling() error {
+	if !baseCmd.profiling {
+		return nil
+	}
+	baseCmd.profiling = false
+
+	if baseCmd.cpuProfile != nil {
+		pprof.StopCPUProfile()
+		if err := baseCmd.cpuProfile.Close(); err != nil {
+			return err
+		}
+		baseCmd.cpuProfile = nil
+	}
+	if path, _ := baseCmd.PersistentFlags().GetString(KeyProfileHeap); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed creating heap profile: %w", err)
+		}
+		defer f.Close()
+		runtime.GC() // Report up-to-date allocation statistics
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			return fmt.Errorf("failed writing heap profile: %w", err)
+		}
+	}
+	if timings != nil {
+		timings.Report(baseCmd.ErrOrStderr())
+		timings = nil
+	}
+	return nil
+}
+
+// FinishProfilingRunE writes the timings and profiles requested by the developer flags after a command runs
+func (baseCmd *BaseCommand) FinishProfilingRunE(cmd *cobra.Command, args []string) error {
+	return baseCmd.finishProfiling()
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestTimingsReport(t *testing.T) {
+	timings := command.NewTimings()
+	timings.Record(command.TimingPhaseConfig, "config", 2*time.Millisecond)
+	timings.Record(command.TimingPhaseProcesses, "kubectl get pods", 1500*time.Millisecond)
+	timings.Record(command.TimingPhaseProcesses, "kubectl get deployments", 500*time.Millisecond)
+
+	buf := new(bytes.Buffer)
+	timings.Report(buf)
+	require.Contains(t, buf.String(), "Timing:\n  config load                2ms\n  API calls                  0s\n")
+	require.Contains(t, buf.String(), "  external processes         2s  (2)\n    kubectl get pods         1.5s\n    kubectl get deployments  500ms\n")
+	require.Regexp(t, "  total +[0-9.]+[µm]?s\n$", buf.String())
+
+	// A nil Timings records nothing
+	var disabled *command.Timings
+	disabled.Track(command.TimingPhaseAPI, "GET /")()
+	disabled.Report(buf)
+}
+
+type TimingTestSuite struct {
+	test.Suite
+}
+
+func TestTimingTestSuite(t *testing.T) {
+	suite.Run(t, new(TimingTestSuite))
+}
+
+func (s *TimingTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+}
+
+func (s *TimingTestSuite) TestTimingIsHidden() {
+	output, err := s.Execute("--help")
+	s.Require().NoError(err)
+	s.Require().NotContains(output, "--timing")
+	s.Require().NotContains(output, "--profile-cpu")
+}
+
+func (s *TimingTestSuite) TestTimingReportsAPICalls() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"adjustment": {}}`))
+	}))
+	defer server.Close()
+
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "--base-url", server.URL, "--timing", "optimizer", "config", "get")
+	s.Require().NoError(err)
+	s.Require().Contains(output, "Timing:\n  config load")
+	s.Require().Regexp(`API calls +[0-9.]+[µm]?s +\(1\)\n    GET /accounts/example.com/applications/app/config`, output)
+}
+
+func (s *TimingTestSuite) TestWithoutTiming() {
+	output, err := s.Execute("--config", kubernetesServoConfigFile(), "config")
+	s.Require().NoError(err)
+	s.Require().NotContains(output, "Timing:")
+}
+
+func (s *TimingTestSuite) TestProfiles() {
+	dir, err := ioutil.TempDir("", "profiles")
+	s.Require().NoError(err)
+	defer os.RemoveAll(dir)
+	cpuProfile := filepath.Join(dir, "cpu.pprof")
+	heapProfile := filepath.Join(dir, "heap.pprof")
+
+	_, err = s.Execute("--config", kubernetesServoConfigFile(), "--profile-cpu", cpuProfile, "--profile-heap", heapProfile, "config")
+	s.Require().NoError(err)
+	for _, path := range []string{cpuProfile, heapProfile} {
+		info, err := os.Stat(path)
+		s.Require().NoError(err)
+		s.Require().NotZero(info.Size())
+	}
+}
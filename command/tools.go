@@ -0,0 +1,137 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// toolInstallation describes how to install an external tool run by the CLI
+type toolInstallation struct {
+	// Commands install the tool with the package manager of each platform
+	Commands map[string]string
+	URL      string
+}
+
+var toolInstallations = map[string]toolInstallation{
+	"docker": {
+		Commands: map[string]string{
+			"darwin":  "brew install --cask docker",
+			"windows": "choco install docker-desktop",
+			"linux":   "sudo apt-get install docker.io",
+		},
+		URL: "https://docs.docker.com/get-docker/",
+	},
+	"podman": {
+		Commands: map[string]string{
+			"darwin":  "brew install podman",
+			"windows": "choco install podman-cli",
+			"linux":   "sudo apt-get install podman",
+		},
+		URL: "https://podman.io/getting-started/installation",
+	},
+	"nerdctl": {
+		URL: "https://github.com/containerd/nerdctl#install",
+	},
+	"kubectl": {
+		Commands: map[string]string{
+			"darwin":  "brew install kubectl",
+			"windows": "choco install kubernetes-cli",
+			"linux":   "sudo snap install kubectl --classic",
+		},
+		URL: "https://kubernetes.io/docs/tasks/tools/",
+	},
+	"minikube": {
+		Commands: map[string]string{
+			"darwin":  "brew install minikube",
+			"windows": "choco install minikube",
+		},
+		URL: "https://minikube.sigs.k8s.io/docs/start/",
+	},
+	"kind": {
+		Commands: map[string]string{
+			"darwin":  "brew install kind",
+			"windows": "choco install kind",
+		},
+		URL: "https://kind.sigs.k8s.io/docs/user/quick-start/#installation",
+	},
+	"kubeseal": {
+		Commands: map[string]string{
+			"darwin": "brew install kubeseal",
+		},
+		URL: "https://github.com/bitnami-labs/sealed-secrets#kubeseal",
+	},
+}
+
+// MissingToolError is returned when an external tool required by a command is not installed
+type MissingToolError struct {
+	Tool string
+	// Instructions describe how to install the tool on the current platform
+	Instructions string
+}
+
+func (err *MissingToolError) Error() string {
+	message := fmt.Sprintf("%s is required but was not found on your PATH", err.Tool)
+	if err.Instructions != "" {
+		message += "\n\n" + err.Instructions
+	}
+	return message
+}
+
+// ToolInstallInstructions returns instructions for installing an external tool on a platform
+// An empty string is returned for tools without known instructions
+func ToolInstallInstructions(tool, goos string) string {
+	installation, ok := toolInstallations[tool]
+	if !ok {
+		return ""
+	}
+	lines := []string{}
+	if command, ok := installation.Commands[goos]; ok {
+		lines = append(lines, fmt.Sprintf("Install %s with:\n  %s", tool, command))
+		lines = append(lines, fmt.Sprintf("or see %s for other options", installation.URL))
+	} else {
+		lines = append(lines, fmt.Sprintf("See %s for installing %s", installation.URL, tool))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// NewMissingToolError returns an error describing how to install a missing tool on this platform
+func NewMissingToolError(tool string) *MissingToolError {
+	return &MissingToolError{Tool: tool, Instructions: ToolInstallInstructions(tool, runtime.GOOS)}
+}
+
+// RequireTool returns the path of an external tool or an error with install instructions when it is missing
+func RequireTool(tool string) (string, error) {
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return "", NewMissingToolError(tool)
+	}
+	return path, nil
+}
+
+// missingToolError translates an error from running an external command that is not installed into
+// an error with install instructions, returning other errors unchanged
+func missingToolError(err error) error {
+	var execErr *exec.Error
+	if errors.As(err, &execErr) && errors.Is(execErr.Err, exec.ErrNotFound) {
+		return NewMissingToolError(filepath.Base(execErr.Name))
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestToolInstallInstructions(t *testing.T) {
+	require.Equal(t, "Install kubectl with:\n  brew install kubectl\nor see https://kubernetes.io/docs/tasks/tools/ for other options",
+		command.ToolInstallInstructions("kubectl", "darwin"))
+	require.Equal(t, "Install kubectl with:\n  choco install kubernetes-cli\nor see https://kubernetes.io/docs/tasks/tools/ for other options",
+		command.ToolInstallInstructions("kubectl", "windows"))
+	require.Equal(t, "Install docker with:\n  sudo apt-get install docker.io\nor see https://docs.docker.com/get-docker/ for other options",
+		command.ToolInstallInstructions("docker", "linux"))
+	require.Equal(t, "See https://minikube.sigs.k8s.io/docs/start/ for installing minikube",
+		command.ToolInstallInstructions("minikube", "linux"))
+	require.Empty(t, command.ToolInstallInstructions("unknown", "linux"))
+}
+
+func TestMissingToolError(t *testing.T) {
+	err := &command.MissingToolError{Tool: "kind", Instructions: "Install kind with:\n  brew install kind"}
+	require.EqualError(t, err, "kind is required but was not found on your PATH\n\nInstall kind with:\n  brew install kind")
+	require.EqualError(t, &command.MissingToolError{Tool: "helm"}, "helm is required but was not found on your PATH")
+}
+
+func TestRequireTool(t *testing.T) {
+	_, err := command.RequireTool("opsani-missing-tool")
+	var missingToolError *command.MissingToolError
+	require.True(t, errors.As(err, &missingToolError))
+	require.Equal(t, "opsani-missing-tool", missingToolError.Tool)
+}
+
+type ToolsTestSuite struct {
+	test.Suite
+	path string
+}
+
+func TestToolsTestSuite(t *testing.T) {
+	suite.Run(t, new(ToolsTestSuite))
+}
+
+func (s *ToolsTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+
+	// Run with a PATH that contains no tools
+	dir, err := ioutil.TempDir("", "path")
+	s.Require().NoError(err)
+	s.path = dir
+	s.SetEnv("PATH", dir)
+}
+
+func (s *ToolsTestSuite) TearDownTest() {
+	os.RemoveAll(s.path)
+}
+
+func (s *ToolsTestSuite) TestMissingToolIsReportedWhenRun() {
+	_, err := s.Execute("--config", kubernetesServoConfigFile(), "generate", "secret", "--sealed-secrets")
+	var missingToolError *command.MissingToolError
+	s.Require().True(errors.As(err, &missingToolError))
+	s.Require().Equal("kubeseal", missingToolError.Tool)
+	s.Require().Contains(err.Error(), "kubeseal is required but was not found on your PATH")
+}
@@ -0,0 +1,285 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// VitalTarget identifies the application selected for optimization
+type VitalTarget struct {
+	Namespace string
+	Kind      string
+	Workload  string
+	Container string
+	Service   string
+}
+
+// String returns a description of the target workload such as "deployment apps/web"
+func (t VitalTarget) String() string {
+	return fmt.Sprintf("%s %s/%s", strings.ToLower(t.Kind), t.Namespace, t.Workload)
+}
+
+// RunVitalDiscovery discovers the resources in the cluster and walks the user through selecting an optimization target
+func (vitalCommand *vitalCommand) RunVitalDiscovery(cobraCmd *cobra.Command, args []string) error {
+	scope, _ := cobraCmd.Flags().GetString("scope")
+	servoNamespace, _ := cobraCmd.Flags().GetString("servo-namespace")
+	metricsProvider, _ := cobraCmd.Flags().GetString("metrics-provider")
+	if err := validateRBACScope(scope); err != nil {
+		return err
+	}
+	if err := validateMetricsProvider(metricsProvider); err != nil {
+		return err
+	}
+	var answers *VitalAnswers
+	if answersFile, _ := cobraCmd.Flags().GetString("answers"); answersFile != "" {
+		var err error
+		if answers, err = LoadVitalAnswers(answersFile); err != nil {
+			return err
+		}
+		if answers.Metrics.Provider == "" {
+			answers.Metrics.Provider = metricsProvider
+		}
+		if err := answers.Guardrails.Validate(); err != nil {
+			return err
+		}
+	}
+	outputDir, _ := cobraCmd.Flags().GetString("output-dir")
+	if outputDir == "" {
+		var err error
+		if outputDir, err = AvailableOutputDir(DefaultManifestsDir); err != nil {
+			return err
+		}
+	}
+
+	kubeconfig := ""
+	if vitalCommand.profile != nil {
+		kubeconfig = vitalCommand.profile.Servo.Kubeconfig
+	}
+	kubernetes, err := kubernetesClientFactory(kubeconfig, "")
+	if err != nil {
+		return err
+	}
+	discovery := NewKubernetesDiscovery(kubernetes, vitalCommand.Timeout())
+
+	bold := color.New(color.Bold).SprintFunc()
+	var resources *KubernetesResources
+	err = vitalCommand.RunTaskWithSpinner(Task{
+		Description: vitalCommand.T("vital.task.discovery.description"),
+		Success:     vitalCommand.T("vital.task.discovery.success", bold("{{ len .Workloads }}"), bold("{{ len .Namespaces }}")),
+		Failure:     vitalCommand.T("vital.task.discovery.failure"),
+		RunV: func() (interface{}, error) {
+			var err error
+			resources, err = discovery.Discover()
+			return resources, err
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var target *VitalTarget
+	if answers != nil {
+		target, err = answers.Target(resources)
+	} else {
+		target, err = vitalCommand.confirmDetectedTarget(DetectTargets(resources))
+		if err == nil && target == nil {
+			target, err = vitalCommand.selectTarget(resources)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(vitalCommand.OutOrStdout(), "\n%s", vitalCommand.infoMessage(vitalCommand.T("vital.target.selected",
+		bold(target.String()), bold(target.Container), bold(target.Service))))
+	force, _ := cobraCmd.Flags().GetBool(KeyForce)
+	if err := vitalCommand.RunSafetyChecks(kubernetes, resources, *target, force); err != nil {
+		return err
+	}
+
+	var metrics MetricsSource
+	guardrails := Guardrails{}
+	if answers != nil {
+		metrics, err = answers.MetricsSource()
+		guardrails = answers.Guardrails
+	} else {
+		if guardrails, err = vitalCommand.recommendGuardrails(discovery, resources, *target); err != nil {
+			return err
+		}
+		metrics, err = vitalCommand.askMetricsSource(metricsProvider)
+	}
+	if err != nil {
+		return err
+	}
+	manifests, err := GenerateServoManifests(*target, *vitalCommand.profile, ServoManifestOptions{
+		Scope:          scope,
+		ServoNamespace: servoNamespace,
+		Metrics:        metrics,
+		Guardrails:     guardrails,
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeManifests(outputDir, manifests); err != nil {
+		return err
+	}
+	fmt.Fprint(vitalCommand.OutOrStdout(), vitalCommand.successMessage(vitalCommand.T("vital.manifests.written", bold(outputDir))))
+	fmt.Fprint(vitalCommand.OutOrStdout(), vitalCommand.infoMessage(vitalCommand.T("vital.manifests.apply", bold("kubectl apply -f "+outputDir))))
+	return nil
+}
+
+// confirmDetectedTarget presents the highest ranked candidate for confirmation
+// A nil target is returned if nothing was detected or the user chooses to select a target manually
+func (vitalCommand *vitalCommand) confirmDetectedTarget(candidates []TargetCandidate) (*VitalTarget, error) {
+	if len(candidates) == 0 || candidates[0].Target.Service == "" {
+		return nil, nil
+	}
+	target := candidates[0].Target
+	bold := color.New(color.Bold).SprintFunc()
+	fmt.Fprintf(vitalCommand.OutOrStdout(), "\n%s", vitalCommand.infoMessage(vitalCommand.T("vital.target.detected",
+		bold(target.String()), bold(target.Container), bold(target.Service))))
+	confirmed := true
+	if err := vitalCommand.AskOne(&survey.Confirm{
+		Message: vitalCommand.T("vital.prompt.confirm_target"),
+		Default: true,
+	}, &confirmed); err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, nil
+	}
+	return &target, nil
+}
+
+// askMetricsSource prompts for the credentials and settings needed to query the metrics provider
+func (vitalCommand *vitalCommand) askMetricsSource(provider string) (MetricsSource, error) {
+	source := MetricsSource{Provider: provider}
+	var questions []*survey.Question
+	switch provider {
+	case MetricsProviderDatadog:
+		questions = []*survey.Question{
+			{
+				Name:   "site",
+				Prompt: &survey.Select{Message: vitalCommand.T("vital.prompt.datadog.site"), Options: []string{"datadoghq.com", "datadoghq.eu", "us3.datadoghq.com", "ddog-gov.com"}},
+			},
+			{
+				Name:     "apikey",
+				Prompt:   &survey.Password{Message: vitalCommand.T("vital.prompt.datadog.api_key")},
+				Validate: survey.Required,
+			},
+			{
+				Name:     "appkey",
+				Prompt:   &survey.Password{Message: vitalCommand.T("vital.prompt.datadog.app_key")},
+				Validate: survey.Required,
+			},
+		}
+	case MetricsProviderNewRelic:
+		questions = []*survey.Question{
+			{
+				Name:     "accountid",
+				Prompt:   &survey.Input{Message: vitalCommand.T("vital.prompt.newrelic.account_id")},
+				Validate: survey.Required,
+			},
+			{
+				Name:     "apikey",
+				Prompt:   &survey.Password{Message: vitalCommand.T("vital.prompt.newrelic.api_key")},
+				Validate: survey.Required,
+			},
+		}
+	default:
+		return source, nil
+	}
+	err := vitalCommand.Ask(questions, &source)
+	return source, err
+}
+
+// selectTarget prompts for the namespace, deployment, container, and service to optimize from the discovered resources
+func (vitalCommand *vitalCommand) selectTarget(resources *KubernetesResources) (*VitalTarget, error) {
+	target := &VitalTarget{}
+	if len(resources.Namespaces) == 0 {
+		return nil, fmt.Errorf("no application namespaces found")
+	}
+	namespaceSelect := &survey.Select{
+		Message: vitalCommand.T("vital.prompt.namespace"),
+		Options: resources.Namespaces,
+	}
+	if candidates := DetectTargets(resources); len(candidates) > 0 {
+		namespaceSelect.Default = candidates[0].Target.Namespace
+	}
+	if err := vitalCommand.AskOne(namespaceSelect, &target.Namespace); err != nil {
+		return nil, err
+	}
+
+	// Present workloads in ranked order
+	workloads := resources.WorkloadsInNamespace(target.Namespace)
+	candidates := DetectTargets(&KubernetesResources{Workloads: workloads, Services: resources.Services})
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no workloads found in namespace %q", target.Namespace)
+	}
+	workloadNames := []string{}
+	for _, candidate := range candidates {
+		workloadNames = append(workloadNames, fmt.Sprintf("%s/%s", strings.ToLower(candidate.Target.Kind), candidate.Target.Workload))
+	}
+	var index int
+	if err := vitalCommand.AskOne(&survey.Select{
+		Message: vitalCommand.T("vital.prompt.workload"),
+		Options: workloadNames,
+	}, &index); err != nil {
+		return nil, err
+	}
+	var workload KubernetesWorkload
+	for _, w := range workloads {
+		if w.Kind == candidates[index].Target.Kind && w.Name == candidates[index].Target.Workload {
+			workload = w
+		}
+	}
+	target.Kind = workload.Kind
+	target.Workload = workload.Name
+	detectedService := candidates[index].Target.Service
+
+	if len(workload.Containers) == 1 {
+		target.Container = workload.Containers[0]
+	} else if err := vitalCommand.AskOne(&survey.Select{
+		Message: vitalCommand.T("vital.prompt.container"),
+		Options: workload.Containers,
+	}, &target.Container); err != nil {
+		return nil, err
+	}
+
+	serviceNames := []string{}
+	for _, service := range resources.ServicesInNamespace(target.Namespace) {
+		serviceNames = append(serviceNames, service.Name)
+	}
+	if len(serviceNames) == 0 {
+		return nil, fmt.Errorf("no services found in namespace %q", target.Namespace)
+	}
+	serviceSelect := &survey.Select{
+		Message: vitalCommand.T("vital.prompt.service"),
+		Options: serviceNames,
+	}
+	if detectedService != "" {
+		serviceSelect.Default = detectedService
+	}
+	if err := vitalCommand.AskOne(serviceSelect, &target.Service); err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
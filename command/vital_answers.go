@@ -0,0 +1,137 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ResourceRange bounds the values the optimizer may assign to a resource and the increment between them
+type ResourceRange struct {
+	Min  string `json:"min,omitempty"`
+	Max  string `json:"max,omitempty"`
+	Step string `json:"step,omitempty"`
+}
+
+// Guardrails bound the CPU and memory settings the optimizer may apply to the target
+type Guardrails struct {
+	CPU    ResourceRange `json:"cpu,omitempty"`
+	Memory ResourceRange `json:"memory,omitempty"`
+}
+
+// Validate checks that the bounds are valid quantities and that minimums do not exceed maximums
+func (g Guardrails) Validate() error {
+	if err := validateResourceRange("cpu", g.CPU, ParseCPUQuantity); err != nil {
+		return err
+	}
+	return validateResourceRange("memory", g.Memory, ParseMemoryQuantity)
+}
+
+func validateResourceRange(name string, r ResourceRange, parse func(string) (float64, error)) error {
+	var min, max float64
+	var err error
+	if r.Min != "" {
+		if min, err = parse(r.Min); err != nil {
+			return fmt.Errorf("invalid %s guardrail: %w", name, err)
+		}
+	}
+	if r.Max != "" {
+		if max, err = parse(r.Max); err != nil {
+			return fmt.Errorf("invalid %s guardrail: %w", name, err)
+		}
+	}
+	if r.Step != "" {
+		if _, err := parse(r.Step); err != nil {
+			return fmt.Errorf("invalid %s guardrail: %w", name, err)
+		}
+	}
+	if r.Min != "" && r.Max != "" && min > max {
+		return fmt.Errorf("invalid %s guardrail: min %s is greater than max %s", name, r.Min, r.Max)
+	}
+	return nil
+}
+
+// VitalAnswers pre-supplies the answers to the prompts of `opsani vital` for unattended runs
+type VitalAnswers struct {
+	Namespace  string        `json:"namespace"`
+	Workload   string        `json:"workload"`
+	Container  string        `json:"container,omitempty"`
+	Service    string        `json:"service,omitempty"`
+	Metrics    MetricsSource `json:"metrics,omitempty"`
+	Guardrails Guardrails    `json:"guardrails,omitempty"`
+}
+
+// LoadVitalAnswers reads answers from a YAML or JSON file
+// Unknown keys are rejected so that typos do not silently fall back to defaults
+func LoadVitalAnswers(path string) (*VitalAnswers, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	answers := &VitalAnswers{}
+	if err := yaml.UnmarshalStrict(data, answers); err != nil {
+		return nil, fmt.Errorf("failed parsing answers file %q: %w", path, err)
+	}
+	if answers.Namespace == "" || answers.Workload == "" {
+		return nil, fmt.Errorf("invalid answers file %q: namespace and workload are required", path)
+	}
+	return answers, nil
+}
+
+// Target validates the answers against the discovered resources and returns the selected target
+// The container and service default to those detected for the workload.
+func (answers *VitalAnswers) Target(resources *KubernetesResources) (*VitalTarget, error) {
+	if !stringSliceContains(resources.Namespaces, answers.Namespace) {
+		return nil, fmt.Errorf("namespace %q not found", answers.Namespace)
+	}
+	target, err := SelectTarget(resources, TargetSelector{
+		Namespace: answers.Namespace,
+		Workload:  answers.Workload,
+		Container: answers.Container,
+		Service:   answers.Service,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if target.Service == "" {
+		return nil, fmt.Errorf("no service selects %s: specify one with the service answer", target)
+	}
+	return target, nil
+}
+
+// MetricsSource validates and returns the metrics answers for the provider
+func (answers *VitalAnswers) MetricsSource() (MetricsSource, error) {
+	source := answers.Metrics
+	if err := validateMetricsProvider(source.Provider); err != nil {
+		return source, err
+	}
+	switch source.Provider {
+	case MetricsProviderDatadog:
+		if source.Site == "" {
+			source.Site = "datadoghq.com"
+		}
+		if source.APIKey == "" || source.AppKey == "" {
+			return source, fmt.Errorf("metrics api_key and app_key answers are required for %q", source.Provider)
+		}
+	case MetricsProviderNewRelic:
+		if source.AccountID == "" || source.APIKey == "" {
+			return source, fmt.Errorf("metrics account_id and api_key answers are required for %q", source.Provider)
+		}
+	}
+	return source, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestGuardrailsValidate(t *testing.T) {
+	require.NoError(t, command.Guardrails{}.Validate())
+	require.NoError(t, command.Guardrails{CPU: command.ResourceRange{Min: "250m", Max: "2"}, Memory: command.ResourceRange{Max: "4Gi"}}.Validate())
+	require.EqualError(t, command.Guardrails{CPU: command.ResourceRange{Min: "2", Max: "500m"}}.Validate(),
+		"invalid cpu guardrail: min 2 is greater than max 500m")
+	require.EqualError(t, command.Guardrails{Memory: command.ResourceRange{Min: "lots"}}.Validate(),
+		`invalid memory guardrail: invalid memory quantity "lots": must be a number of GiB or a quantity such as 512Mi or 2Gi`)
+}
+
+func TestGenerateServoManifestsGuardrails(t *testing.T) {
+	target := command.VitalTarget{Namespace: "apps", Kind: command.WorkloadDeployment, Workload: "web", Container: "main", Service: "web"}
+	manifests, err := command.GenerateServoManifests(target, manifestProfile, command.ServoManifestOptions{
+		Guardrails: command.Guardrails{CPU: command.ResourceRange{Min: "250m", Max: "2"}, Memory: command.ResourceRange{Max: "4Gi"}},
+	})
+	require.NoError(t, err)
+	require.Contains(t, manifestNamed(t, manifests, "servo-configmap.yaml"), `      service: web
+      cpu:
+        min: "250m"
+        max: "2"
+      memory:
+        max: "4Gi"
+`)
+}
+
+type VitalAnswersTestSuite struct {
+	test.Suite
+	kubernetes *test.FakeKubernetes
+	dir        string
+}
+
+func TestVitalAnswersTestSuite(t *testing.T) {
+	suite.Run(t, new(VitalAnswersTestSuite))
+}
+
+func (s *VitalAnswersTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+	s.kubernetes = newDiscoveryKubernetes()
+	command.SetKubernetesClientFactory(s.kubernetes.Factory())
+
+	dir, err := ioutil.TempDir("", "opsani-vital-answers")
+	s.Require().NoError(err)
+	s.dir = dir
+}
+
+func (s *VitalAnswersTestSuite) TearDownTest() {
+	command.SetKubernetesClientFactory(nil)
+	os.RemoveAll(s.dir)
+}
+
+// vital runs `opsani vital` with the answers, writing manifests into the temporary directory
+func (s *VitalAnswersTestSuite) vital(answers string) (string, error) {
+	answersFile := filepath.Join(s.dir, "answers.yaml")
+	s.Require().NoError(ioutil.WriteFile(answersFile, []byte(answers), 0600))
+	configFile := test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{"name": "default", "optimizer": "example.com/app", "token": "123456"},
+		},
+	}).Name()
+	return s.Execute("--config", configFile, "vital", "--answers", answersFile, "--output-dir", filepath.Join(s.dir, "manifests"))
+}
+
+func (s *VitalAnswersTestSuite) TestAnswers() {
+	_, err := s.vital(`namespace: apps
+workload: web
+guardrails:
+  cpu:
+    max: "2"
+  memory:
+    min: 256Mi
+`)
+	s.Require().NoError(err)
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, "manifests", "servo-configmap.yaml"))
+	s.Require().NoError(err)
+	s.Require().Contains(string(data), "deployment: web\n      container: main\n      service: web\n      cpu:\n        max: \"2\"\n      memory:\n        min: \"256Mi\"\n")
+}
+
+func (s *VitalAnswersTestSuite) TestAnswersDatadog() {
+	_, err := s.vital(`namespace: apps
+workload: web
+metrics:
+  provider: datadog
+  api_key: dd-api
+  app_key: dd-app
+`)
+	s.Require().NoError(err)
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, "manifests", "servo-configmap.yaml"))
+	s.Require().NoError(err)
+	s.Require().Contains(string(data), "site: datadoghq.com")
+}
+
+func (s *VitalAnswersTestSuite) TestAnswersValidatedAgainstCluster() {
+	_, err := s.vital("namespace: staging\nworkload: web\n")
+	s.Require().EqualError(err, `namespace "staging" not found`)
+	s.SetCommand(command.NewRootCommand())
+	_, err = s.vital("namespace: apps\nworkload: web\ncontainer: sidecar\n")
+	s.Require().EqualError(err, `container "sidecar" not found in deployment apps/web`)
+	s.SetCommand(command.NewRootCommand())
+	_, err = s.vital("namespace: apps\nworkload: db\n")
+	s.Require().EqualError(err, "no service selects statefulset apps/db: specify one with the service answer")
+}
+
+func (s *VitalAnswersTestSuite) TestAnswersBlockedBySafetyChecks() {
+	s.kubernetes.Lists["horizontalpodautoscalers.autoscaling"] = `{"items": [{"metadata": {"namespace": "apps", "name": "web"}, "spec": {
+		"scaleTargetRef": {"kind": "Deployment", "name": "web"}, "targetCPUUtilizationPercentage": 80
+	}}]}`
+	output, err := s.vital("namespace: apps\nworkload: web\n")
+	s.Require().EqualError(err, "1 safety checks failed: resolve the issues above or rerun with --force")
+	s.Require().Contains(output, "HorizontalPodAutoscaler web scales on cpu utilization")
+	s.Require().NoFileExists(filepath.Join(s.dir, "manifests", "servo-configmap.yaml"))
+}
+
+func (s *VitalAnswersTestSuite) TestAnswersInvalid() {
+	_, err := s.vital("namespace: apps\n")
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), "namespace and workload are required")
+	s.SetCommand(command.NewRootCommand())
+	_, err = s.vital("namespace: apps\nworkload: web\nservise: web\n")
+	s.Require().Error(err)
+	s.Require().Contains(err.Error(), `unknown field "servise"`)
+	s.SetCommand(command.NewRootCommand())
+	_, err = s.vital("namespace: apps\nworkload: web\nmetrics:\n  provider: newrelic\n")
+	s.Require().EqualError(err, `metrics account_id and api_key answers are required for "newrelic"`)
+}
+
+func TestRecommendGuardrails(t *testing.T) {
+	guardrails := command.RecommendGuardrails(command.ContainerResources{CPURequest: "500m", MemoryRequest: "512Mi", MemoryLimit: "1Gi"}, command.ContainerUsage{})
+	require.Equal(t, command.Guardrails{
+		CPU:    command.ResourceRange{Min: "250m", Max: "1", Step: "125m"},
+		Memory: command.ResourceRange{Min: "256Mi", Max: "1Gi", Step: "128Mi"},
+	}, guardrails)
+	require.NoError(t, guardrails.Validate())
+}
+
+func TestRecommendGuardrailsFromUsage(t *testing.T) {
+	// Memory minimums stay clear of peak usage and maximums leave room to double it
+	guardrails := command.RecommendGuardrails(command.ContainerResources{CPULimit: "2"}, command.ContainerUsage{CPU: 1.2, Memory: 0.75})
+	require.Equal(t, command.Guardrails{
+		CPU:    command.ResourceRange{Min: "1", Max: "4", Step: "125m"},
+		Memory: command.ResourceRange{Min: "1Gi", Max: "1536Mi", Step: "128Mi"},
+	}, guardrails)
+}
+
+func TestRecommendGuardrailsWithoutResources(t *testing.T) {
+	guardrails := command.RecommendGuardrails(command.ContainerResources{}, command.ContainerUsage{})
+	require.Equal(t, command.Guardrails{
+		CPU:    command.ResourceRange{Min: "250m", Max: "1", Step: "125m"},
+		Memory: command.ResourceRange{Min: "256Mi", Max: "1Gi", Step: "128Mi"},
+	}, guardrails)
+}
+
+func TestContainerUsage(t *testing.T) {
+	kubernetes := test.NewFakeKubernetes()
+	kubernetes.Lists["pods.metrics.k8s.io"] = `{"items": [
+		{"metadata": {"name": "web-1", "namespace": "apps", "labels": {"app": "web", "tier": "frontend"}}, "containers": [
+			{"name": "main", "usage": {"cpu": "120m", "memory": "300Mi"}},
+			{"name": "envoy", "usage": {"cpu": "5000000n", "memory": "20Mi"}}
+		]},
+		{"metadata": {"name": "web-2", "namespace": "apps", "labels": {"app": "web", "tier": "frontend"}}, "containers": [
+			{"name": "main", "usage": {"cpu": "1", "memory": "262144Ki"}}
+		]},
+		{"metadata": {"name": "api-1", "namespace": "apps", "labels": {"app": "api"}}, "containers": [
+			{"name": "main", "usage": {"cpu": "3", "memory": "1Gi"}}
+		]}
+	]}`
+	discovery := command.NewKubernetesDiscovery(kubernetes.Client(), 0)
+	usage, err := discovery.ContainerUsage("apps", map[string]string{"tier": "frontend", "app": "web"})
+	require.NoError(t, err)
+	list := kubernetes.Actions()[0].(k8stesting.ListAction)
+	require.Equal(t, "apps", list.GetNamespace())
+	require.Equal(t, "app=web,tier=frontend", list.GetListRestrictions().Labels.String())
+	require.Equal(t, 1.0, usage["main"].CPU)
+	require.InDelta(t, 300.0/1024, usage["main"].Memory, 0.0001)
+	require.Equal(t, 0.005, usage["envoy"].CPU)
+}
@@ -0,0 +1,232 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// BulkServo is a servo generated for one deployment when bootstrapping optimization of a whole namespace
+type BulkServo struct {
+	Target    VitalTarget
+	Optimizer string
+	Name      string
+	Dir       string
+	Status    string
+}
+
+// Statuses of servos generated in bulk
+const (
+	bulkStatusWritten   = "written"
+	bulkStatusApplied   = "applied"
+	bulkStatusNoService = "skipped: no service selects the deployment"
+)
+
+// BulkOptimizerID returns the optimizer for the workload within the organization of the profile optimizer
+// An optimizer of "example.com/app" yields "example.com/web" for the "web" workload.
+func BulkOptimizerID(profileOptimizer string, workload string) (string, error) {
+	org, _ := splitOptimizer(profileOptimizer)
+	if org == "" {
+		return "", fmt.Errorf("cannot derive optimizers from %q: expected an optimizer of the form \"organization/app\"", profileOptimizer)
+	}
+	return org + "/" + workload, nil
+}
+
+// splitOptimizer splits an optimizer into its organization and app name
+func splitOptimizer(optimizer string) (org string, app string) {
+	i := strings.Index(optimizer, "/")
+	if i <= 0 || i == len(optimizer)-1 {
+		return "", ""
+	}
+	return optimizer[:i], optimizer[i+1:]
+}
+
+// BulkServos plans a servo for each deployment in the namespace, ordered by deployment name
+// Deployments not selected by a service cannot be optimized by the opsani_dev connector and are skipped.
+// Servos deployed into a shared servo namespace are named after the namespace of their deployment too,
+// keeping servos of same-named deployments in different namespaces apart.
+func BulkServos(resources *KubernetesResources, namespace string, servoNamespace string, profileOptimizer string, outputDir string) ([]BulkServo, error) {
+	servos := []BulkServo{}
+	for _, candidate := range DetectTargets(resources) {
+		target := candidate.Target
+		if target.Namespace != namespace || target.Kind != WorkloadDeployment {
+			continue
+		}
+		optimizer, err := BulkOptimizerID(profileOptimizer, target.Workload)
+		if err != nil {
+			return nil, err
+		}
+		name := "servo-" + target.Workload
+		if servoNamespace != "" && servoNamespace != namespace {
+			name = "servo-" + namespace + "-" + target.Workload
+		}
+		if len(name) > 63 {
+			name = strings.TrimRight(name[:63], "-.")
+		}
+		servo := BulkServo{Target: target, Optimizer: optimizer, Name: name, Dir: filepath.Join(outputDir, target.Workload)}
+		if target.Service == "" {
+			servo.Status = bulkStatusNoService
+		}
+		servos = append(servos, servo)
+	}
+	if len(servos) == 0 {
+		return nil, fmt.Errorf("no deployments found in namespace %q", namespace)
+	}
+	sort.Slice(servos, func(i, j int) bool { return servos[i].Target.Workload < servos[j].Target.Workload })
+	return servos, nil
+}
+
+// RunVitalAllDeployments generates a servo for every deployment in a namespace and optionally applies them
+func (vitalCommand *vitalCommand) RunVitalAllDeployments(cobraCmd *cobra.Command, args []string) error {
+	namespace, _ := cobraCmd.Flags().GetString("namespace")
+	if namespace == "" {
+		return fmt.Errorf("--all-deployments requires --namespace")
+	}
+	scope, _ := cobraCmd.Flags().GetString("scope")
+	servoNamespace, _ := cobraCmd.Flags().GetString("servo-namespace")
+	metricsProvider, _ := cobraCmd.Flags().GetString("metrics-provider")
+	apply, _ := cobraCmd.Flags().GetBool("apply")
+	force, _ := cobraCmd.Flags().GetBool(KeyForce)
+	if err := validateRBACScope(scope); err != nil {
+		return err
+	}
+	if metricsProvider != MetricsProviderPrometheus {
+		return fmt.Errorf("--all-deployments only supports the %q metrics provider", MetricsProviderPrometheus)
+	}
+	outputDir, _ := cobraCmd.Flags().GetString("output-dir")
+	if outputDir == "" {
+		var err error
+		if outputDir, err = AvailableOutputDir(DefaultManifestsDir); err != nil {
+			return err
+		}
+	}
+
+	kubeconfig := vitalCommand.profile.Servo.Kubeconfig
+	kubernetes, err := kubernetesClientFactory(kubeconfig, "")
+	if err != nil {
+		return err
+	}
+	resources, err := NewKubernetesDiscovery(kubernetes, vitalCommand.Timeout()).Discover()
+	if err != nil {
+		return err
+	}
+	servos, err := BulkServos(resources, namespace, servoNamespace, vitalCommand.profile.Optimizer, outputDir)
+	if err != nil {
+		return err
+	}
+	for i, servo := range servos {
+		if servo.Status != "" {
+			continue
+		}
+		ctx, cancel := vitalCommand.ContextWithTimeout()
+		report := RunSafetyChecks(ctx, kubernetes, targetWorkload(resources, servo.Target), servo.Target.Container)
+		cancel()
+		if report.Failures() > 0 && !force {
+			servos[i].Status = fmt.Sprintf("skipped: failed safety checks (%s)", strings.ToLower(strings.Join(failedChecks(report), ", ")))
+			continue
+		}
+		profile := *vitalCommand.profile
+		profile.Optimizer = servo.Optimizer
+		manifests, err := GenerateServoManifests(servo.Target, profile, ServoManifestOptions{
+			Scope:          scope,
+			ServoNamespace: servoNamespace,
+			ServoName:      servo.Name,
+		})
+		if err != nil {
+			return err
+		}
+		if err := writeManifests(servo.Dir, manifests); err != nil {
+			return err
+		}
+		servos[i].Status = bulkStatusWritten
+	}
+
+	failures, written, applied := 0, 0, false
+	for _, servo := range servos {
+		if servo.Status == bulkStatusWritten {
+			written++
+		}
+	}
+	if apply && written > 0 {
+		confirmed, err := vitalCommand.Confirm(fmt.Sprintf("Apply servos for %d deployments in namespace %q?", written, namespace), true)
+		if err != nil {
+			return err
+		}
+		if confirmed {
+			applied = true
+			for i, servo := range servos {
+				if servo.Status != bulkStatusWritten {
+					continue
+				}
+				ctx, cancel := vitalCommand.ContextWithTimeout()
+				err := applyManifestsDir(ctx, kubernetes, servo.Dir)
+				cancel()
+				if err != nil {
+					servos[i].Status = fmt.Sprintf("failed: %s", err)
+					failures++
+					continue
+				}
+				servos[i].Status = bulkStatusApplied
+			}
+		}
+	}
+
+	table := Table{Headers: []string{"DEPLOYMENT", "OPTIMIZER", "SERVO", "MANIFESTS", "STATUS"}}
+	for _, servo := range servos {
+		dir := servo.Dir
+		if strings.HasPrefix(servo.Status, "skipped") {
+			dir = "-"
+		}
+		table.Rows = append(table.Rows, []string{servo.Target.Workload, servo.Optimizer, servo.Name, dir, servo.Status})
+	}
+	if err := vitalCommand.RenderTable(OutputTable, table); err != nil {
+		return err
+	}
+	if failures > 0 {
+		return fmt.Errorf("failed applying %d servos", failures)
+	}
+	if !applied && written > 0 {
+		vitalCommand.Printf("\nDeploy the servos by running `kubectl apply -R -f %s`\n", outputDir)
+	}
+	return nil
+}
+
+// applyManifestsDir applies the manifests written to a directory in name order
+func applyManifestsDir(ctx context.Context, kubernetes *KubernetesClient, dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		manifest, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+		if err := kubernetes.Apply(ctx, manifest, ioutil.Discard); err != nil {
+			return fmt.Errorf("%s: %w", file.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestBulkOptimizerID(t *testing.T) {
+	optimizer, err := command.BulkOptimizerID("example.com/app", "web")
+	require.NoError(t, err)
+	require.Equal(t, "example.com/web", optimizer)
+	_, err = command.BulkOptimizerID("app", "web")
+	require.EqualError(t, err, `cannot derive optimizers from "app": expected an optimizer of the form "organization/app"`)
+}
+
+func TestBulkServos(t *testing.T) {
+	resources := &command.KubernetesResources{
+		Namespaces: []string{"apps", "other"},
+		Workloads: []command.KubernetesWorkload{
+			{Kind: command.WorkloadDeployment, Namespace: "apps", Name: "worker", Selector: map[string]string{"app": "worker"}, PodLabels: map[string]string{"app": "worker"}, Containers: []string{"main"}},
+			{Kind: command.WorkloadDeployment, Namespace: "apps", Name: "web", Selector: map[string]string{"app": "web"}, PodLabels: map[string]string{"app": "web"}, Containers: []string{"main"}},
+			{Kind: command.WorkloadStatefulSet, Namespace: "apps", Name: "db", Selector: map[string]string{"app": "db"}, PodLabels: map[string]string{"app": "db"}, Containers: []string{"postgres"}},
+			{Kind: command.WorkloadDeployment, Namespace: "other", Name: "api", Selector: map[string]string{"app": "api"}, PodLabels: map[string]string{"app": "api"}, Containers: []string{"main"}},
+		},
+		Services: []command.KubernetesService{
+			{Namespace: "apps", Name: "web", Selector: map[string]string{"app": "web"}},
+			{Namespace: "apps", Name: "db", Selector: map[string]string{"app": "db"}},
+		},
+	}
+	servos, err := command.BulkServos(resources, "apps", "", "example.com/app", "manifests")
+	require.NoError(t, err)
+	require.Len(t, servos, 2)
+	require.Equal(t, "web", servos[0].Target.Workload)
+	require.Equal(t, "example.com/web", servos[0].Optimizer)
+	require.Equal(t, "servo-web", servos[0].Name)
+	require.Equal(t, filepath.Join("manifests", "web"), servos[0].Dir)
+	require.Equal(t, "", servos[0].Status)
+	require.Equal(t, "worker", servos[1].Target.Workload)
+	require.Equal(t, "skipped: no service selects the deployment", servos[1].Status)
+
+	servos, err = command.BulkServos(resources, "apps", "opsani", "example.com/app", "manifests")
+	require.NoError(t, err)
+	require.Equal(t, "servo-apps-web", servos[0].Name)
+
+	_, err = command.BulkServos(resources, "empty", "", "example.com/app", "manifests")
+	require.EqualError(t, err, `no deployments found in namespace "empty"`)
+}
+
+type VitalBulkTestSuite struct {
+	test.Suite
+	kubernetes *test.FakeKubernetes
+	dir        string
+}
+
+func TestVitalBulkTestSuite(t *testing.T) {
+	suite.Run(t, new(VitalBulkTestSuite))
+}
+
+func (s *VitalBulkTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+	s.kubernetes = newDiscoveryKubernetes()
+	command.SetKubernetesClientFactory(s.kubernetes.Factory())
+
+	dir, err := ioutil.TempDir("", "opsani-vital-bulk")
+	s.Require().NoError(err)
+	s.dir = dir
+}
+
+func (s *VitalBulkTestSuite) TearDownTest() {
+	command.SetKubernetesClientFactory(nil)
+	os.RemoveAll(s.dir)
+}
+
+func (s *VitalBulkTestSuite) configFile() string {
+	return test.TempConfigFileWithObj(map[string]interface{}{
+		"profiles": []map[string]string{
+			{"name": "default", "optimizer": "example.com/app", "token": "123456"},
+		},
+	}).Name()
+}
+
+func (s *VitalBulkTestSuite) TestAllDeploymentsRequiresNamespace() {
+	_, err := s.Execute("--config", s.configFile(), "vital", "--all-deployments")
+	s.Require().EqualError(err, "--all-deployments requires --namespace")
+}
+
+func (s *VitalBulkTestSuite) TestAllDeploymentsWritesManifests() {
+	outputDir := filepath.Join(s.dir, "manifests")
+	output, err := s.Execute("--config", s.configFile(), "vital", "--all-deployments", "-n", "apps", "--output-dir", outputDir)
+	s.Require().NoError(err)
+	s.Require().Regexp(`web\s+example.com/web\s+servo-web\s+\S+/web\s+written`, output)
+	s.Require().Contains(output, "kubectl apply -R -f "+outputDir)
+
+	data, err := ioutil.ReadFile(filepath.Join(outputDir, "web", "servo-deployment.yaml"))
+	s.Require().NoError(err)
+	s.Require().Contains(string(data), "name: servo-web\n")
+	s.Require().Contains(string(data), "value: example.com/web\n")
+	s.Require().Empty(s.kubernetes.Patches())
+}
+
+func (s *VitalBulkTestSuite) TestAllDeploymentsApply() {
+	outputDir := filepath.Join(s.dir, "manifests")
+	output, err := s.Execute("--config", s.configFile(), "--yes", "vital", "--all-deployments", "-n", "apps", "--output-dir", outputDir, "--apply")
+	s.Require().NoError(err)
+	s.Require().Regexp(`web\s+example.com/web\s+servo-web\s+\S+/web\s+applied`, output)
+	applied := []string{}
+	for _, patch := range s.kubernetes.Patches() {
+		s.Require().Equal(types.ApplyPatchType, patch.GetPatchType())
+		applied = append(applied, patch.GetResource().Resource+"/"+patch.GetName())
+	}
+	s.Require().Contains(applied, "configmaps/servo-web-config")
+	s.Require().Contains(applied, "deployments/servo-web")
+	s.Require().Contains(applied, "clusterroles/opsani-apps-servo-web")
+	s.Require().Contains(applied, "clusterrolebindings/opsani-apps-servo-web")
+}
+
+func (s *VitalBulkTestSuite) TestAllDeploymentsSharedServoNamespace() {
+	outputDir := filepath.Join(s.dir, "manifests")
+	output, err := s.Execute("--config", s.configFile(), "vital", "--all-deployments", "-n", "apps", "--servo-namespace", "opsani", "--output-dir", outputDir)
+	s.Require().NoError(err)
+	s.Require().Regexp(`web\s+example.com/web\s+servo-apps-web\s+\S+/web\s+written`, output)
+
+	data, err := ioutil.ReadFile(filepath.Join(outputDir, "web", "servo-rbac.yaml"))
+	s.Require().NoError(err)
+	s.Require().Contains(string(data), "kind: ClusterRoleBinding\nmetadata:\n  name: opsani-opsani-servo-apps-web\n")
+}
+
+func (s *VitalBulkTestSuite) TestAllDeploymentsSkipsUnsafeDeployments() {
+	s.kubernetes.Lists["horizontalpodautoscalers.autoscaling"] = `{"items": [{"metadata": {"namespace": "apps", "name": "web"}, "spec": {
+		"scaleTargetRef": {"kind": "Deployment", "name": "web"}, "targetCPUUtilizationPercentage": 80
+	}}]}`
+	outputDir := filepath.Join(s.dir, "manifests")
+	output, err := s.Execute("--config", s.configFile(), "vital", "--all-deployments", "-n", "apps", "--output-dir", outputDir)
+	s.Require().NoError(err)
+	s.Require().Regexp(`web\s+example.com/web\s+servo-web\s+-\s+skipped: failed safety checks \(autoscaling\)`, output)
+	s.Require().NoDirExists(filepath.Join(outputDir, "web"))
+
+	s.SetCommand(command.NewRootCommand())
+	output, err = s.Execute("--config", s.configFile(), "vital", "--all-deployments", "-n", "apps", "--output-dir", outputDir, "--force")
+	s.Require().NoError(err)
+	s.Require().Regexp(`web\s+example.com/web\s+servo-web\s+\S+/web\s+written`, output)
+}
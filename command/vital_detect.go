@@ -0,0 +1,96 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"sort"
+)
+
+// TargetCandidate is a workload ranked by its likelihood of being the application to optimize
+type TargetCandidate struct {
+	Target VitalTarget
+	Score  int
+}
+
+// Scoring weights for target detection heuristics
+const (
+	scoreSelectedByService = 10
+	scoreSingleContainer   = 5
+	scoreAppLabel          = 2
+)
+
+// servicesSelecting returns the services whose selector matches the pod template labels of the workload
+func servicesSelecting(workload KubernetesWorkload, services []KubernetesService) []KubernetesService {
+	matches := []KubernetesService{}
+	for _, service := range services {
+		if service.Namespace != workload.Namespace || len(service.Selector) == 0 {
+			continue
+		}
+		selected := true
+		for key, value := range service.Selector {
+			if workload.PodLabels[key] != value {
+				selected = false
+				break
+			}
+		}
+		if selected {
+			matches = append(matches, service)
+		}
+	}
+	return matches
+}
+
+// DetectTargets ranks the discovered workloads as optimization targets
+// Workloads selected by a Service, running a single container, and carrying conventional app labels
+// rank highest. System namespaces are excluded during discovery.
+func DetectTargets(resources *KubernetesResources) []TargetCandidate {
+	candidates := []TargetCandidate{}
+	for _, workload := range resources.Workloads {
+		if len(workload.Containers) == 0 {
+			continue
+		}
+		candidate := TargetCandidate{
+			Target: VitalTarget{
+				Namespace: workload.Namespace,
+				Kind:      workload.Kind,
+				Workload:  workload.Name,
+				Container: workload.Containers[0],
+			},
+		}
+		if services := servicesSelecting(workload, resources.Services); len(services) > 0 {
+			candidate.Target.Service = services[0].Name
+			candidate.Score += scoreSelectedByService
+		}
+		if len(workload.Containers) == 1 {
+			candidate.Score += scoreSingleContainer
+		}
+		if _, ok := workload.Labels["app"]; ok {
+			candidate.Score += scoreAppLabel
+		} else if _, ok := workload.Labels["app.kubernetes.io/name"]; ok {
+			candidate.Score += scoreAppLabel
+		}
+		candidates = append(candidates, candidate)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		if candidates[i].Target.Namespace != candidates[j].Target.Namespace {
+			return candidates[i].Target.Namespace < candidates[j].Target.Namespace
+		}
+		return candidates[i].Target.Workload < candidates[j].Target.Workload
+	})
+	return candidates
+}
@@ -0,0 +1,68 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectTargetsPrefersServicedSingleContainerWorkloads(t *testing.T) {
+	resources := &command.KubernetesResources{
+		Workloads: []command.KubernetesWorkload{
+			{Kind: command.WorkloadDeployment, Namespace: "apps", Name: "worker", Containers: []string{"worker"}},
+			{Kind: command.WorkloadRollout, Namespace: "apps", Name: "api", Selector: map[string]string{"app": "api"}, PodLabels: map[string]string{"app": "api"}, Containers: []string{"api", "envoy"}},
+			{Kind: command.WorkloadStatefulSet, Namespace: "apps", Name: "web", Labels: map[string]string{"app": "web"}, Selector: map[string]string{"app": "web"}, PodLabels: map[string]string{"app": "web"}, Containers: []string{"web"}},
+		},
+		Services: []command.KubernetesService{
+			{Namespace: "apps", Name: "web-svc", Selector: map[string]string{"app": "web"}},
+			{Namespace: "apps", Name: "api-svc", Selector: map[string]string{"app": "api"}},
+			{Namespace: "other", Name: "worker-svc", Selector: map[string]string{"app": "worker"}},
+		},
+	}
+	candidates := command.DetectTargets(resources)
+	require.Len(t, candidates, 3)
+	require.Equal(t, command.VitalTarget{Namespace: "apps", Kind: "StatefulSet", Workload: "web", Container: "web", Service: "web-svc"}, candidates[0].Target)
+	require.Equal(t, command.VitalTarget{Namespace: "apps", Kind: "Rollout", Workload: "api", Container: "api", Service: "api-svc"}, candidates[1].Target)
+	require.Equal(t, command.VitalTarget{Namespace: "apps", Kind: "Deployment", Workload: "worker", Container: "worker"}, candidates[2].Target)
+}
+
+func TestDetectTargetsSkipsWorkloadsWithoutContainers(t *testing.T) {
+	resources := &command.KubernetesResources{
+		Workloads: []command.KubernetesWorkload{{Kind: command.WorkloadDeployment, Namespace: "apps", Name: "empty"}},
+	}
+	require.Empty(t, command.DetectTargets(resources))
+}
+
+func TestDetectTargetsMatchesServicesOnPodTemplateLabels(t *testing.T) {
+	resources := &command.KubernetesResources{
+		Workloads: []command.KubernetesWorkload{
+			// The service selects a label only set on the pod template
+			{Kind: command.WorkloadDeployment, Namespace: "apps", Name: "web", Selector: map[string]string{"app": "web"}, PodLabels: map[string]string{"app": "web", "tier": "frontend"}, Containers: []string{"web"}},
+			// The labels of the workload itself never reach its pods
+			{Kind: command.WorkloadDeployment, Namespace: "apps", Name: "api", Labels: map[string]string{"tier": "backend"}, Selector: map[string]string{"app": "api"}, PodLabels: map[string]string{"app": "api"}, Containers: []string{"api"}},
+		},
+		Services: []command.KubernetesService{
+			{Namespace: "apps", Name: "frontend", Selector: map[string]string{"tier": "frontend"}},
+			{Namespace: "apps", Name: "backend", Selector: map[string]string{"tier": "backend"}},
+		},
+	}
+	candidates := command.DetectTargets(resources)
+	require.Len(t, candidates, 2)
+	require.Equal(t, command.VitalTarget{Namespace: "apps", Kind: "Deployment", Workload: "web", Container: "web", Service: "frontend"}, candidates[0].Target)
+	require.Equal(t, command.VitalTarget{Namespace: "apps", Kind: "Deployment", Workload: "api", Container: "api"}, candidates[1].Target)
+}
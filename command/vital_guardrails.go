@@ -0,0 +1,172 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/tidwall/gjson"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ContainerResources are the resource requests and limits of a container as Kubernetes quantities
+type ContainerResources struct {
+	CPURequest    string
+	CPULimit      string
+	MemoryRequest string
+	MemoryLimit   string
+}
+
+// ContainerUsage is the peak resource usage observed across the pods of a workload
+type ContainerUsage struct {
+	// CPU is in cores and Memory is in GiB
+	CPU    float64
+	Memory float64
+}
+
+// Increments and baselines used when recommending guardrails
+const (
+	cpuGuardrailStep    = 0.125
+	memoryGuardrailStep = 0.125 // 128Mi
+
+	// defaultCPUBaseline and defaultMemoryBaseline are assumed when a container has no requests, limits, or usage
+	defaultCPUBaseline    = 0.5
+	defaultMemoryBaseline = 0.5
+)
+
+// ContainerUsage returns the peak usage of each container across the pods matching the selector
+// Usage is reported by metrics-server via the resource metrics API and is unavailable on clusters without it.
+func (d *KubernetesDiscovery) ContainerUsage(namespace string, selector map[string]string) (map[string]ContainerUsage, error) {
+	ctx, cancel := contextWithTimeout(d.timeout)
+	defer cancel()
+	output, err := d.kubernetes.List(ctx, podMetricsResource, namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := map[string]ContainerUsage{}
+	for _, pod := range gjson.GetBytes(output, "items").Array() {
+		for _, container := range pod.Get("containers").Array() {
+			cpu, err := resource.ParseQuantity(container.Get("usage.cpu").String())
+			if err != nil {
+				continue
+			}
+			memory, err := resource.ParseQuantity(container.Get("usage.memory").String())
+			if err != nil {
+				continue
+			}
+			name := container.Get("name").String()
+			peak := usage[name]
+			peak.CPU = math.Max(peak.CPU, float64(cpu.MilliValue())/1000)
+			peak.Memory = math.Max(peak.Memory, float64(memory.Value())/(1<<30))
+			usage[name] = peak
+		}
+	}
+	return usage, nil
+}
+
+// RecommendGuardrails proposes guardrails around the current resources of a container
+// The range spans half to double the baseline (the request, falling back to the limit and then to the
+// peak usage) and is widened to include the limit and twice the peak usage. Memory minimums stay clear
+// of the peak usage to avoid out of memory kills.
+func RecommendGuardrails(resources ContainerResources, usage ContainerUsage) Guardrails {
+	cpuRequest, _ := ParseCPUQuantity(resources.CPURequest)
+	cpuLimit, _ := ParseCPUQuantity(resources.CPULimit)
+	memoryRequest, _ := ParseMemoryQuantity(resources.MemoryRequest)
+	memoryLimit, _ := ParseMemoryQuantity(resources.MemoryLimit)
+
+	cpuMin, cpuMax := recommendRange(cpuRequest, cpuLimit, usage.CPU, defaultCPUBaseline, 0, cpuGuardrailStep)
+	memoryMin, memoryMax := recommendRange(memoryRequest, memoryLimit, usage.Memory, defaultMemoryBaseline, usage.Memory*1.25, memoryGuardrailStep)
+	return Guardrails{
+		CPU: ResourceRange{
+			Min:  formatCPUQuantity(cpuMin),
+			Max:  formatCPUQuantity(cpuMax),
+			Step: formatCPUQuantity(cpuGuardrailStep),
+		},
+		Memory: ResourceRange{
+			Min:  formatMemoryQuantity(memoryMin),
+			Max:  formatMemoryQuantity(memoryMax),
+			Step: formatMemoryQuantity(memoryGuardrailStep),
+		},
+	}
+}
+
+// recommendRange returns a minimum and maximum that are multiples of the step
+func recommendRange(request, limit, peak, defaultBaseline, floor, step float64) (float64, float64) {
+	baseline := request
+	if baseline == 0 {
+		baseline = limit
+	}
+	if baseline == 0 {
+		baseline = peak
+	}
+	if baseline == 0 {
+		baseline = defaultBaseline
+	}
+	min := math.Max(baseline/2, floor)
+	max := math.Max(math.Max(baseline*2, limit), peak*2)
+	min = math.Max(step, math.Floor(min/step)*step)
+	if min < floor {
+		min = math.Ceil(floor/step) * step
+	}
+	max = math.Max(min+step, math.Ceil(max/step)*step)
+	return min, max
+}
+
+// formatCPUQuantity formats cores as whole cores or millicores
+func formatCPUQuantity(cores float64) string {
+	millicores := int64(math.Round(cores * 1000))
+	if millicores%1000 == 0 {
+		return fmt.Sprintf("%d", millicores/1000)
+	}
+	return fmt.Sprintf("%dm", millicores)
+}
+
+// formatMemoryQuantity formats GiB as whole GiB or MiB
+func formatMemoryQuantity(gib float64) string {
+	mib := int64(math.Round(gib * 1024))
+	if mib%1024 == 0 {
+		return fmt.Sprintf("%dGi", mib/1024)
+	}
+	return fmt.Sprintf("%dMi", mib)
+}
+
+// recommendGuardrails presents guardrails recommended from the current resources and usage of the target for confirmation
+// No guardrails are returned if the user declines, leaving the servo defaults in place
+func (vitalCommand *vitalCommand) recommendGuardrails(discovery *KubernetesDiscovery, resources *KubernetesResources, target VitalTarget) (Guardrails, error) {
+	workload := targetWorkload(resources, target)
+	usage := ContainerUsage{}
+	if containerUsage, err := discovery.ContainerUsage(target.Namespace, workload.Selector); err == nil {
+		usage = containerUsage[target.Container]
+	}
+	guardrails := RecommendGuardrails(workload.Resources[target.Container], usage)
+
+	fmt.Fprintf(vitalCommand.OutOrStdout(), "\n%s", vitalCommand.infoMessage(vitalCommand.T("vital.guardrails.recommended",
+		guardrails.CPU.Min, guardrails.CPU.Max, guardrails.CPU.Step,
+		guardrails.Memory.Min, guardrails.Memory.Max, guardrails.Memory.Step)))
+	confirmed := true
+	if err := vitalCommand.AskOne(&survey.Confirm{
+		Message: vitalCommand.T("vital.prompt.confirm_guardrails"),
+		Default: true,
+	}, &confirmed); err != nil {
+		return Guardrails{}, err
+	}
+	if !confirmed {
+		return Guardrails{}, nil
+	}
+	return guardrails, nil
+}
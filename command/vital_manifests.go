@@ -0,0 +1,452 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// workloadKind describes how the servo interacts with a kind of workload
+type workloadKind struct {
+	// APIGroup and Resource identify the workload for RBAC rules and kubectl
+	APIGroup string
+	Resource string
+
+	// ConfigKey is the opsani_dev connector setting that names the workload
+	ConfigKey string
+}
+
+var workloadKinds = map[string]workloadKind{
+	WorkloadDeployment:  {APIGroup: "apps", Resource: "deployments", ConfigKey: "deployment"},
+	WorkloadStatefulSet: {APIGroup: "apps", Resource: "statefulsets", ConfigKey: "statefulset"},
+	WorkloadDaemonSet:   {APIGroup: "apps", Resource: "daemonsets", ConfigKey: "daemonset"},
+	WorkloadRollout:     {APIGroup: "argoproj.io", Resource: "rollouts", ConfigKey: "rollout"},
+}
+
+// Manifest is a named Kubernetes manifest generated for deploying a servo
+type Manifest struct {
+	Name string
+	Data []byte
+}
+
+// RBAC scopes for generated servo permissions
+const (
+	RBACScopeCluster   = "cluster"
+	RBACScopeNamespace = "namespace"
+)
+
+// ServoManifestOptions customizes the generated servo manifests
+type ServoManifestOptions struct {
+	// Scope determines if the servo is granted cluster-wide permissions or is restricted to the target namespace
+	Scope string
+
+	// ServoNamespace is the namespace the servo is deployed into (defaults to the target namespace)
+	ServoNamespace string
+
+	// ServoName names the servo deployment and its supporting resources (defaults to "servo")
+	ServoName string
+
+	// Metrics configures the source of application metrics (defaults to Prometheus)
+	Metrics MetricsSource
+
+	// Guardrails bound the resources the optimizer may assign to the target
+	Guardrails Guardrails
+}
+
+// Metrics providers supported by generated servo configurations
+const (
+	MetricsProviderPrometheus = "prometheus"
+	MetricsProviderDatadog    = "datadog"
+	MetricsProviderNewRelic   = "newrelic"
+)
+
+// MetricsSource describes where the servo retrieves application metrics from
+// Prometheus metrics are gathered by the opsani_dev connector and require no further configuration
+type MetricsSource struct {
+	Provider string `json:"provider,omitempty"`
+
+	// Site is the Datadog site to query (e.g. datadoghq.com or datadoghq.eu)
+	Site string `json:"site,omitempty"`
+
+	// AccountID is the New Relic account to query
+	AccountID string `json:"account_id,omitempty"`
+
+	// APIKey authenticates with the provider and AppKey is the Datadog application key
+	// Keys are stored in the servo Secret rather than the ConfigMap
+	APIKey string `json:"api_key,omitempty"`
+	AppKey string `json:"app_key,omitempty"`
+}
+
+func validateMetricsProvider(provider string) error {
+	switch provider {
+	case MetricsProviderPrometheus, MetricsProviderDatadog, MetricsProviderNewRelic:
+		return nil
+	}
+	return fmt.Errorf("invalid metrics provider %q: must be %q, %q, or %q",
+		provider, MetricsProviderPrometheus, MetricsProviderDatadog, MetricsProviderNewRelic)
+}
+
+// servoManifestContext is the data made available to the servo manifest templates
+type servoManifestContext struct {
+	Target       VitalTarget
+	WorkloadKind workloadKind
+	Profile      Profile
+	Options      ServoManifestOptions
+	// BaseURL is the API base URL of single-tenant deployments and empty for the Opsani API
+	BaseURL string
+}
+
+// Validate checks that the options are consistent with the target
+// Namespace scoped permissions only extend to the target namespace so the servo must be deployed alongside it
+func (options ServoManifestOptions) Validate(target VitalTarget) error {
+	if err := validateRBACScope(options.Scope); err != nil {
+		return err
+	}
+	if err := validateMetricsProvider(options.Metrics.Provider); err != nil {
+		return err
+	}
+	if err := options.Guardrails.Validate(); err != nil {
+		return err
+	}
+	if options.Scope == RBACScopeNamespace && options.ServoNamespace != target.Namespace {
+		return fmt.Errorf("namespace scoped servo must be deployed in the target namespace %q (got %q)", target.Namespace, options.ServoNamespace)
+	}
+	return nil
+}
+
+func validateRBACScope(scope string) error {
+	if scope != RBACScopeCluster && scope != RBACScopeNamespace {
+		return fmt.Errorf("invalid scope %q: must be %q or %q", scope, RBACScopeCluster, RBACScopeNamespace)
+	}
+	return nil
+}
+
+// GenerateServoManifests renders the manifests for deploying a servo that optimizes the target with the given profile
+func GenerateServoManifests(target VitalTarget, profile Profile, options ServoManifestOptions) ([]Manifest, error) {
+	kind, ok := workloadKinds[target.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unsupported workload kind %q", target.Kind)
+	}
+	if options.Scope == "" {
+		options.Scope = RBACScopeCluster
+	}
+	if options.ServoNamespace == "" {
+		options.ServoNamespace = target.Namespace
+	}
+	if options.Metrics.Provider == "" {
+		options.Metrics.Provider = MetricsProviderPrometheus
+	}
+	if options.ServoName == "" {
+		options.ServoName = "servo"
+	}
+	if err := options.Validate(target); err != nil {
+		return nil, err
+	}
+	ctx := servoManifestContext{
+		Target:       target,
+		WorkloadKind: kind,
+		Profile:      profile,
+		Options:      options,
+		BaseURL:      CustomBaseURL(profile.BaseURL),
+	}
+
+	manifests := []Manifest{}
+	for _, tmpl := range servoManifestTemplates {
+		t, err := template.New(tmpl.Name).Funcs(template.FuncMap{
+			"base64encode": func(v string) string {
+				return base64.StdEncoding.EncodeToString([]byte(v))
+			},
+		}).Parse(tmpl.Text)
+		if err != nil {
+			return nil, err
+		}
+		buffer := new(bytes.Buffer)
+		if err := t.Execute(buffer, ctx); err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, Manifest{Name: tmpl.Name, Data: buffer.Bytes()})
+	}
+	return manifests, nil
+}
+
+// DefaultManifestsDir is the directory servo manifests are written to when no output directory is given
+const DefaultManifestsDir = "manifests"
+
+// AvailableOutputDir returns the directory if it is missing or empty, otherwise the first missing or empty
+// of dir-2, dir-3, and so on so that the output of earlier runs is not overwritten
+func AvailableOutputDir(dir string) (string, error) {
+	candidate := dir
+	for n := 2; ; n++ {
+		entries, err := ioutil.ReadDir(candidate)
+		if os.IsNotExist(err) || (err == nil && len(entries) == 0) {
+			return candidate, nil
+		} else if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s-%d", dir, n)
+	}
+}
+
+// writeManifests writes the manifests into the given directory, creating it if necessary
+// Manifests that are not valid YAML are rejected before anything is written for them
+func writeManifests(dir string, manifests []Manifest) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, manifest := range manifests {
+		if err := ValidateYAMLDocuments(manifest.Data); err != nil {
+			return fmt.Errorf("invalid manifest %s: %w", manifest.Name, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, manifest.Name), manifest.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var servoManifestTemplates = []struct {
+	Name string
+	Text string
+}{
+	{"servo-configmap.yaml", `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Options.ServoName }}-config
+  namespace: {{ .Options.ServoNamespace }}
+data:
+  servo.yaml: |
+    opsani_dev:
+      namespace: {{ .Target.Namespace }}
+      {{ .WorkloadKind.ConfigKey }}: {{ .Target.Workload }}
+      container: {{ .Target.Container }}
+      service: {{ .Target.Service }}
+{{- with .Options.Guardrails.CPU }}{{ if or .Min .Max .Step }}
+      cpu:
+{{- if .Min }}
+        min: {{ printf "%q" .Min }}
+{{- end }}
+{{- if .Max }}
+        max: {{ printf "%q" .Max }}
+{{- end }}
+{{- if .Step }}
+        step: {{ printf "%q" .Step }}
+{{- end }}
+{{- end }}{{ end }}
+{{- with .Options.Guardrails.Memory }}{{ if or .Min .Max .Step }}
+      memory:
+{{- if .Min }}
+        min: {{ printf "%q" .Min }}
+{{- end }}
+{{- if .Max }}
+        max: {{ printf "%q" .Max }}
+{{- end }}
+{{- if .Step }}
+        step: {{ printf "%q" .Step }}
+{{- end }}
+{{- end }}{{ end }}
+{{- with .Options.Metrics }}
+{{- if eq .Provider "datadog" }}
+    datadog:
+      site: {{ .Site }}
+      metrics:
+      - name: throughput
+        query: sum:trace.http.request.hits{service:{{ $.Target.Service }},kube_namespace:{{ $.Target.Namespace }}}.as_rate()
+        unit: rps
+      - name: error_rate
+        query: sum:trace.http.request.errors{service:{{ $.Target.Service }},kube_namespace:{{ $.Target.Namespace }}}.as_rate() / sum:trace.http.request.hits{service:{{ $.Target.Service }},kube_namespace:{{ $.Target.Namespace }}}.as_rate()
+        unit: percent
+      - name: latency_p90
+        query: p90:trace.http.request{service:{{ $.Target.Service }},kube_namespace:{{ $.Target.Namespace }}}
+        unit: seconds
+{{- else if eq .Provider "newrelic" }}
+    newrelic:
+      account_id: {{ .AccountID }}
+      metrics:
+      - name: throughput
+        query: SELECT rate(count(*), 1 second) FROM Transaction WHERE appName = '{{ $.Target.Service }}'
+        unit: rps
+      - name: error_rate
+        query: SELECT percentage(count(*), WHERE error IS true) FROM Transaction WHERE appName = '{{ $.Target.Service }}'
+        unit: percent
+      - name: latency_p90
+        query: SELECT percentile(duration, 90) FROM Transaction WHERE appName = '{{ $.Target.Service }}'
+        unit: seconds
+{{- end }}
+{{- end }}
+`},
+	{"servo-rbac.yaml", `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: {{ .Options.ServoName }}
+  namespace: {{ .Options.ServoNamespace }}
+---
+apiVersion: rbac.authorization.k8s.io/v1
+{{- if eq .Options.Scope "namespace" }}
+kind: Role
+metadata:
+  name: opsani-{{ .Options.ServoName }}
+  namespace: {{ .Target.Namespace }}
+{{- else }}
+kind: ClusterRole
+metadata:
+  name: opsani-{{ .Options.ServoNamespace }}-{{ .Options.ServoName }}
+{{- end }}
+rules:
+- apiGroups: ["{{ .WorkloadKind.APIGroup }}"]
+  resources: ["{{ .WorkloadKind.Resource }}"]
+  verbs: ["get", "list", "watch", "update", "patch"]
+- apiGroups: [""]
+  resources: ["pods", "pods/log", "pods/status"]
+  verbs: ["create", "delete", "get", "list", "watch"]
+- apiGroups: [""]
+  resources: ["services", "configmaps"]
+  verbs: ["get", "list", "watch", "update", "patch"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+{{- if eq .Options.Scope "namespace" }}
+kind: RoleBinding
+metadata:
+  name: opsani-{{ .Options.ServoName }}
+  namespace: {{ .Target.Namespace }}
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: opsani-{{ .Options.ServoName }}
+{{- else }}
+kind: ClusterRoleBinding
+metadata:
+  name: opsani-{{ .Options.ServoNamespace }}-{{ .Options.ServoName }}
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: opsani-{{ .Options.ServoNamespace }}-{{ .Options.ServoName }}
+{{- end }}
+subjects:
+- kind: ServiceAccount
+  name: {{ .Options.ServoName }}
+  namespace: {{ .Options.ServoNamespace }}
+`},
+	{"servo-secret.yaml", `apiVersion: v1
+kind: Secret
+metadata:
+  name: {{ .Options.ServoName }}-token
+  namespace: {{ .Options.ServoNamespace }}
+type: Opaque
+data:
+  token: {{ base64encode .Profile.Token }}
+{{- with .Options.Metrics }}
+{{- if eq .Provider "datadog" }}
+  datadog_api_key: {{ base64encode .APIKey }}
+  datadog_app_key: {{ base64encode .AppKey }}
+{{- else if eq .Provider "newrelic" }}
+  newrelic_api_key: {{ base64encode .APIKey }}
+{{- end }}
+{{- end }}
+`},
+	{"servo-deployment.yaml", `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Options.ServoName }}
+  namespace: {{ .Options.ServoNamespace }}
+  labels:
+    app.kubernetes.io/name: {{ .Options.ServoName }}
+    app.kubernetes.io/component: core
+  annotations:
+    servo.opsani.com/optimizer: {{ .Profile.Optimizer }}
+spec:
+  replicas: 1
+  revisionHistoryLimit: 2
+  strategy:
+    type: Recreate
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: {{ .Options.ServoName }}
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: {{ .Options.ServoName }}
+        app.kubernetes.io/component: core
+    spec:
+      serviceAccountName: {{ .Options.ServoName }}
+      containers:
+      - name: servo
+        image: opsani/servox:latest
+        args:
+        - run
+        env:
+        - name: OPSANI_OPTIMIZER
+          value: {{ .Profile.Optimizer }}
+{{- with .BaseURL }}
+        - name: OPSANI_BASE_URL
+          value: {{ . }}
+{{- end }}
+        - name: OPSANI_TOKEN_FILE
+          value: /servo/opsani.token
+        - name: SERVO_CONFIG_FILE
+          value: /servo/servo.yaml
+{{- with .Options.Metrics }}
+{{- if eq .Provider "datadog" }}
+        - name: DATADOG_API_KEY
+          valueFrom:
+            secretKeyRef:
+              name: {{ $.Options.ServoName }}-token
+              key: datadog_api_key
+        - name: DATADOG_APP_KEY
+          valueFrom:
+            secretKeyRef:
+              name: {{ $.Options.ServoName }}-token
+              key: datadog_app_key
+{{- else if eq .Provider "newrelic" }}
+        - name: NEW_RELIC_API_KEY
+          valueFrom:
+            secretKeyRef:
+              name: {{ $.Options.ServoName }}-token
+              key: newrelic_api_key
+{{- end }}
+{{- end }}
+        volumeMounts:
+        - name: servo-token-volume
+          mountPath: /servo/opsani.token
+          subPath: opsani.token
+          readOnly: true
+        - name: servo-config-volume
+          mountPath: /servo/servo.yaml
+          subPath: servo.yaml
+          readOnly: true
+        resources:
+          limits:
+            cpu: 250m
+            memory: 256Mi
+      volumes:
+      - name: servo-token-volume
+        secret:
+          secretName: {{ .Options.ServoName }}-token
+          items:
+          - key: token
+            path: opsani.token
+      - name: servo-config-volume
+        configMap:
+          name: {{ .Options.ServoName }}-config
+          items:
+          - key: servo.yaml
+            path: servo.yaml
+`},
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+)
+
+func manifestNamed(t *testing.T, manifests []command.Manifest, name string) string {
+	for _, manifest := range manifests {
+		if manifest.Name == name {
+			return string(manifest.Data)
+		}
+	}
+	t.Fatalf("no manifest named %q", name)
+	return ""
+}
+
+var manifestProfile = command.Profile{Name: "default", Optimizer: "example.com/app", Token: "123456"}
+
+func TestGenerateServoManifestsForDeployment(t *testing.T) {
+	target := command.VitalTarget{Namespace: "apps", Kind: command.WorkloadDeployment, Workload: "web", Container: "main", Service: "web"}
+	manifests, err := command.GenerateServoManifests(target, manifestProfile, command.ServoManifestOptions{})
+	require.NoError(t, err)
+	require.Len(t, manifests, 4)
+	for _, manifest := range manifests {
+		test.RequireMatchesGolden(t, "manifests/deployment/"+manifest.Name, string(manifest.Data))
+	}
+}
+
+func TestGenerateServoManifestsForWorkloadKinds(t *testing.T) {
+	cases := []struct {
+		kind      string
+		configKey string
+		rule      string
+	}{
+		{command.WorkloadStatefulSet, "statefulset: db", `- apiGroups: ["apps"]
+  resources: ["statefulsets"]`},
+		{command.WorkloadDaemonSet, "daemonset: db", `- apiGroups: ["apps"]
+  resources: ["daemonsets"]`},
+		{command.WorkloadRollout, "rollout: db", `- apiGroups: ["argoproj.io"]
+  resources: ["rollouts"]`},
+	}
+	for _, c := range cases {
+		t.Run(c.kind, func(t *testing.T) {
+			target := command.VitalTarget{Namespace: "apps", Kind: c.kind, Workload: "db", Container: "main", Service: "db"}
+			manifests, err := command.GenerateServoManifests(target, manifestProfile, command.ServoManifestOptions{})
+			require.NoError(t, err)
+			require.Contains(t, manifestNamed(t, manifests, "servo-configmap.yaml"), c.configKey)
+			require.Contains(t, manifestNamed(t, manifests, "servo-rbac.yaml"), c.rule)
+		})
+	}
+}
+
+func TestGenerateServoManifestsClusterScopedNamesIncludeNamespace(t *testing.T) {
+	for _, namespace := range []string{"apps", "shop"} {
+		target := command.VitalTarget{Namespace: namespace, Kind: command.WorkloadDeployment, Workload: "web", Container: "main", Service: "web"}
+		manifests, err := command.GenerateServoManifests(target, manifestProfile, command.ServoManifestOptions{})
+		require.NoError(t, err)
+		rbac := manifestNamed(t, manifests, "servo-rbac.yaml")
+		name := "opsani-" + namespace + "-servo"
+		require.Contains(t, rbac, "kind: ClusterRoleBinding\nmetadata:\n  name: "+name+"\nroleRef:\n  apiGroup: rbac.authorization.k8s.io\n  kind: ClusterRole\n  name: "+name+"\n")
+	}
+}
+
+func TestGenerateServoManifestsUnsupportedKind(t *testing.T) {
+	target := command.VitalTarget{Namespace: "apps", Kind: "CronJob", Workload: "backup"}
+	_, err := command.GenerateServoManifests(target, manifestProfile, command.ServoManifestOptions{})
+	require.EqualError(t, err, `unsupported workload kind "CronJob"`)
+}
+
+func TestGenerateServoManifestsNamespaceScope(t *testing.T) {
+	target := command.VitalTarget{Namespace: "apps", Kind: command.WorkloadDeployment, Workload: "web", Container: "main", Service: "web"}
+	manifests, err := command.GenerateServoManifests(target, manifestProfile, command.ServoManifestOptions{Scope: command.RBACScopeNamespace})
+	require.NoError(t, err)
+	rbac := manifestNamed(t, manifests, "servo-rbac.yaml")
+	require.Contains(t, rbac, "kind: Role\nmetadata:\n  name: opsani-servo\n  namespace: apps\n")
+	require.Contains(t, rbac, "kind: RoleBinding\nmetadata:\n  name: opsani-servo\n  namespace: apps\n")
+	require.NotContains(t, rbac, "ClusterRole")
+}
+
+func TestGenerateServoManifestsClusterScopeByDefault(t *testing.T) {
+	target := command.VitalTarget{Namespace: "apps", Kind: command.WorkloadDeployment, Workload: "web", Container: "main", Service: "web"}
+	manifests, err := command.GenerateServoManifests(target, manifestProfile, command.ServoManifestOptions{ServoNamespace: "opsani"})
+	require.NoError(t, err)
+	rbac := manifestNamed(t, manifests, "servo-rbac.yaml")
+	require.Contains(t, rbac, "kind: ClusterRole\nmetadata:\n  name: opsani-opsani-servo\n")
+	require.Contains(t, rbac, "kind: ClusterRoleBinding\nmetadata:\n  name: opsani-opsani-servo\n")
+	require.Contains(t, manifestNamed(t, manifests, "servo-deployment.yaml"), "namespace: opsani\n")
+	require.Contains(t, manifestNamed(t, manifests, "servo-configmap.yaml"), "      namespace: apps\n")
+}
+
+func TestGenerateServoManifestsNamespaceScopeRequiresTargetNamespace(t *testing.T) {
+	target := command.VitalTarget{Namespace: "apps", Kind: command.WorkloadDeployment, Workload: "web", Container: "main", Service: "web"}
+	_, err := command.GenerateServoManifests(target, manifestProfile, command.ServoManifestOptions{Scope: command.RBACScopeNamespace, ServoNamespace: "opsani"})
+	require.EqualError(t, err, `namespace scoped servo must be deployed in the target namespace "apps" (got "opsani")`)
+}
+
+func TestGenerateServoManifestsInvalidScope(t *testing.T) {
+	target := command.VitalTarget{Namespace: "apps", Kind: command.WorkloadDeployment, Workload: "web", Container: "main", Service: "web"}
+	_, err := command.GenerateServoManifests(target, manifestProfile, command.ServoManifestOptions{Scope: "galaxy"})
+	require.EqualError(t, err, `invalid scope "galaxy": must be "cluster" or "namespace"`)
+}
+
+func TestGenerateServoManifestsPrometheusByDefault(t *testing.T) {
+	target := command.VitalTarget{Namespace: "apps", Kind: command.WorkloadDeployment, Workload: "web", Container: "main", Service: "web"}
+	manifests, err := command.GenerateServoManifests(target, manifestProfile, command.ServoManifestOptions{})
+	require.NoError(t, err)
+	require.NotContains(t, manifestNamed(t, manifests, "servo-configmap.yaml"), "datadog:")
+	require.NotContains(t, manifestNamed(t, manifests, "servo-configmap.yaml"), "newrelic:")
+	require.NotContains(t, manifestNamed(t, manifests, "servo-secret.yaml"), "api_key")
+}
+
+func TestGenerateServoManifestsBaseURL(t *testing.T) {
+	target := command.VitalTarget{Namespace: "apps", Kind: command.WorkloadDeployment, Workload: "web", Container: "main", Service: "web"}
+	profile := manifestProfile
+	profile.BaseURL = command.DefaultBaseURL
+	manifests, err := command.GenerateServoManifests(target, profile, command.ServoManifestOptions{})
+	require.NoError(t, err)
+	require.NotContains(t, manifestNamed(t, manifests, "servo-deployment.yaml"), "OPSANI_BASE_URL")
+
+	profile.BaseURL = "https://api.cell-1.opsani.com/"
+	manifests, err = command.GenerateServoManifests(target, profile, command.ServoManifestOptions{})
+	require.NoError(t, err)
+	require.Contains(t, manifestNamed(t, manifests, "servo-deployment.yaml"), `        - name: OPSANI_OPTIMIZER
+          value: example.com/app
+        - name: OPSANI_BASE_URL
+          value: https://api.cell-1.opsani.com/
+`)
+}
+
+func TestGenerateServoManifestsDatadog(t *testing.T) {
+	target := command.VitalTarget{Namespace: "apps", Kind: command.WorkloadDeployment, Workload: "web", Container: "main", Service: "web"}
+	manifests, err := command.GenerateServoManifests(target, manifestProfile, command.ServoManifestOptions{
+		Metrics: command.MetricsSource{Provider: command.MetricsProviderDatadog, Site: "datadoghq.eu", APIKey: "dd-api-secret", AppKey: "dd-app-secret"},
+	})
+	require.NoError(t, err)
+	configMap := manifestNamed(t, manifests, "servo-configmap.yaml")
+	test.RequireMatchesGolden(t, "manifests/datadog/servo-configmap.yaml", configMap)
+	require.NotContains(t, configMap, "dd-api-secret")
+	secret := manifestNamed(t, manifests, "servo-secret.yaml")
+	require.Contains(t, secret, "datadog_api_key: ZGQtYXBpLXNlY3JldA==")
+	require.Contains(t, secret, "datadog_app_key: ZGQtYXBwLXNlY3JldA==")
+	require.Contains(t, manifestNamed(t, manifests, "servo-deployment.yaml"), "name: DATADOG_APP_KEY")
+}
+
+func TestGenerateServoManifestsNewRelic(t *testing.T) {
+	target := command.VitalTarget{Namespace: "apps", Kind: command.WorkloadDeployment, Workload: "web", Container: "main", Service: "web"}
+	manifests, err := command.GenerateServoManifests(target, manifestProfile, command.ServoManifestOptions{
+		Metrics: command.MetricsSource{Provider: command.MetricsProviderNewRelic, AccountID: "42", APIKey: "api"},
+	})
+	require.NoError(t, err)
+	configMap := manifestNamed(t, manifests, "servo-configmap.yaml")
+	test.RequireMatchesGolden(t, "manifests/newrelic/servo-configmap.yaml", configMap)
+	require.Contains(t, manifestNamed(t, manifests, "servo-secret.yaml"), "newrelic_api_key: YXBp")
+	require.Contains(t, manifestNamed(t, manifests, "servo-deployment.yaml"), "name: NEW_RELIC_API_KEY")
+}
+
+func TestGenerateServoManifestsInvalidMetricsProvider(t *testing.T) {
+	target := command.VitalTarget{Namespace: "apps", Kind: command.WorkloadDeployment, Workload: "web", Container: "main", Service: "web"}
+	_, err := command.GenerateServoManifests(target, manifestProfile, command.ServoManifestOptions{Metrics: command.MetricsSource{Provider: "graphite"}})
+	require.EqualError(t, err, `invalid metrics provider "graphite": must be "prometheus", "datadog", or "newrelic"`)
+}
+
+func TestAvailableOutputDir(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "opsani-manifests")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	dir := filepath.Join(tmpDir, "manifests")
+
+	available, err := command.AvailableOutputDir(dir)
+	require.NoError(t, err)
+	require.Equal(t, dir, available)
+
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	available, err = command.AvailableOutputDir(dir)
+	require.NoError(t, err)
+	require.Equal(t, dir, available, "empty directories are reused")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "servo-deployment.yaml"), []byte("kind: Deployment"), 0644))
+	require.NoError(t, os.MkdirAll(dir+"-2", 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir+"-2", "servo-deployment.yaml"), []byte("kind: Deployment"), 0644))
+	available, err = command.AvailableOutputDir(dir)
+	require.NoError(t, err)
+	require.Equal(t, dir+"-3", available)
+}
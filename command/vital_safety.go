@@ -0,0 +1,184 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// KeyForce is the flag for generating a servo despite failed safety checks
+const KeyForce = "force"
+
+// RunSafetyChecks verifies that adjusting the workload will not put the availability of the application at risk
+// Every adjustment rolls out new pods, so single replicas, missing readiness probes, and autoscalers reacting to
+// the adjusted resources can cause downtime. Problems are reported as failures and cautions as warnings.
+func RunSafetyChecks(ctx context.Context, kubernetes *KubernetesClient, workload KubernetesWorkload, container string) PreflightReport {
+	report := PreflightReport{}
+	kind := strings.ToLower(workload.Kind)
+
+	// Replicas
+	if workload.Kind != WorkloadDaemonSet {
+		if workload.Replicas > 1 {
+			report.add("Replicas", PreflightPass, fmt.Sprintf("%d replicas remain available during adjustments", workload.Replicas), "")
+		} else {
+			report.add("Replicas", PreflightFail, fmt.Sprintf("%s %s is not replicated and is unavailable while adjustments roll out", kind, workload.Name),
+				fmt.Sprintf("Scale to at least 2 replicas with `kubectl scale %s %s -n %s --replicas=2`", kind, workload.Name, workload.Namespace))
+		}
+	}
+
+	// Readiness probe
+	if stringSliceContains(workload.ReadinessProbes, container) {
+		report.add("Readiness probe", PreflightPass, fmt.Sprintf("container %s defines a readiness probe", container), "")
+	} else {
+		report.add("Readiness probe", PreflightFail, fmt.Sprintf("container %s has no readiness probe, so traffic may reach adjusted pods before they are ready", container),
+			fmt.Sprintf("Add a readinessProbe to container %s of %s %s", container, kind, workload.Name))
+	}
+
+	// Pod disruption budget
+	if output, err := kubernetes.List(ctx, podDisruptionBudgetResource, workload.Namespace, nil); err != nil || !gjson.ValidBytes(output) {
+		report.add("Disruption budget", PreflightWarn, "unable to list PodDisruptionBudgets", "")
+	} else {
+		budget := ""
+		for _, item := range gjson.GetBytes(output, "items").Array() {
+			if selectsWorkload(item.Get("spec.selector.matchLabels"), workload) {
+				budget = item.Get("metadata.name").String()
+				break
+			}
+		}
+		if budget != "" {
+			report.add("Disruption budget", PreflightPass, fmt.Sprintf("PodDisruptionBudget %s protects the pods", budget), "")
+		} else {
+			report.add("Disruption budget", PreflightWarn, fmt.Sprintf("no PodDisruptionBudget protects the pods of %s %s", kind, workload.Name),
+				fmt.Sprintf("Create one with `kubectl create poddisruptionbudget %s -n %s --selector %s --min-available 1`", workload.Name, workload.Namespace, labelSelector(workload.Selector)))
+		}
+	}
+
+	// Horizontal pod autoscaler
+	if output, err := kubernetes.List(ctx, horizontalPodAutoscalerResource, workload.Namespace, nil); err != nil || !gjson.ValidBytes(output) {
+		report.add("Autoscaling", PreflightWarn, "unable to list HorizontalPodAutoscalers", "")
+	} else {
+		checkAutoscalers(gjson.GetBytes(output, "items").Array(), workload, &report)
+	}
+	return report
+}
+
+// checkAutoscalers reports autoscalers that conflict with adjustments of the workload
+// Autoscaling on resource utilization is relative to the requests being adjusted, so each adjustment shifts the scaling threshold.
+func checkAutoscalers(autoscalers []gjson.Result, workload KubernetesWorkload, report *PreflightReport) {
+	for _, hpa := range autoscalers {
+		if hpa.Get("spec.scaleTargetRef.kind").String() != workload.Kind || hpa.Get("spec.scaleTargetRef.name").String() != workload.Name {
+			continue
+		}
+		name := hpa.Get("metadata.name").String()
+		if resources := hpaResourceMetrics(hpa); len(resources) > 0 {
+			report.add("Autoscaling", PreflightFail, fmt.Sprintf("HorizontalPodAutoscaler %s scales on %s utilization, which shifts as the optimizer adjusts requests", name, strings.Join(resources, " and ")),
+				fmt.Sprintf("Scale on application metrics instead or remove the autoscaler with `kubectl delete hpa %s -n %s`", name, workload.Namespace))
+		} else {
+			report.add("Autoscaling", PreflightWarn, fmt.Sprintf("HorizontalPodAutoscaler %s overrides the replica count set by the optimizer", name), "")
+		}
+		return
+	}
+	report.add("Autoscaling", PreflightPass, "no HorizontalPodAutoscaler targets the workload", "")
+}
+
+// hpaResourceMetrics returns the resources whose utilization drives the HorizontalPodAutoscaler
+// Both the autoscaling/v1 CPU target and autoscaling/v2 resource metrics are supported.
+func hpaResourceMetrics(hpa gjson.Result) []string {
+	resources := []string{}
+	if hpa.Get("spec.targetCPUUtilizationPercentage").Exists() {
+		resources = append(resources, "cpu")
+	}
+	for _, metric := range hpa.Get("spec.metrics").Array() {
+		if metric.Get("type").String() == "Resource" {
+			resources = append(resources, metric.Get("resource.name").String())
+		}
+	}
+	return resources
+}
+
+// failedChecks returns the names of the failed checks in the report
+func failedChecks(report PreflightReport) []string {
+	names := []string{}
+	for _, check := range report.Checks {
+		if check.Status == PreflightFail {
+			names = append(names, check.Name)
+		}
+	}
+	return names
+}
+
+// targetWorkload returns the discovered workload of the target
+func targetWorkload(resources *KubernetesResources, target VitalTarget) KubernetesWorkload {
+	for _, workload := range resources.WorkloadsInNamespace(target.Namespace) {
+		if workload.Kind == target.Kind && workload.Name == target.Workload {
+			return workload
+		}
+	}
+	return KubernetesWorkload{Kind: target.Kind, Namespace: target.Namespace, Name: target.Workload}
+}
+
+// selectsWorkload returns true if the match labels select the pod template labels of the workload
+func selectsWorkload(matchLabels gjson.Result, workload KubernetesWorkload) bool {
+	labels := matchLabels.Map()
+	if len(labels) == 0 {
+		return false
+	}
+	for key, value := range labels {
+		if workload.PodLabels[key] != value.String() {
+			return false
+		}
+	}
+	return true
+}
+
+// labelSelector formats labels as a kubectl selector such as "app=web,tier=frontend"
+func labelSelector(labels map[string]string) string {
+	selector := []string{}
+	for key, value := range labels {
+		selector = append(selector, key+"="+value)
+	}
+	sort.Strings(selector)
+	return strings.Join(selector, ",")
+}
+
+// RunSafetyChecks checks the target workload, renders the report, and returns an error if any check failed unless forced
+func (vitalCommand *vitalCommand) RunSafetyChecks(kubernetes *KubernetesClient, resources *KubernetesResources, target VitalTarget, force bool) error {
+	workload := targetWorkload(resources, target)
+	var report PreflightReport
+	err := vitalCommand.RunTaskWithSpinner(Task{
+		Description: vitalCommand.T("vital.task.safety.description"),
+		Success:     vitalCommand.T("vital.task.safety.success"),
+		Failure:     vitalCommand.T("vital.task.safety.failure"),
+		Run: func() error {
+			ctx, cancel := vitalCommand.ContextWithTimeout()
+			defer cancel()
+			report = RunSafetyChecks(ctx, kubernetes, workload, target.Container)
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+	vitalCommand.renderPreflightReport(report)
+	if failures := report.Failures(); failures > 0 && !force {
+		return fmt.Errorf(vitalCommand.T("vital.safety.failed", failures))
+	}
+	return nil
+}
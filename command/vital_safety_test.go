@@ -0,0 +1,115 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+)
+
+// newKubernetesWithLists returns a fake cluster listing the given JSON object lists keyed by resource
+func newKubernetesWithLists(lists map[string]string) *test.FakeKubernetes {
+	kubernetes := test.NewFakeKubernetes()
+	for resource, list := range lists {
+		kubernetes.Lists[resource] = list
+	}
+	return kubernetes
+}
+
+var safetyWorkload = command.KubernetesWorkload{
+	Kind:            command.WorkloadDeployment,
+	Namespace:       "apps",
+	Name:            "web",
+	Labels:          map[string]string{"app": "web"},
+	Selector:        map[string]string{"app": "web"},
+	PodLabels:       map[string]string{"app": "web"},
+	Containers:      []string{"main"},
+	Replicas:        3,
+	ReadinessProbes: []string{"main"},
+}
+
+func TestSafetyChecksSafeWorkload(t *testing.T) {
+	kubernetes := newKubernetesWithLists(map[string]string{
+		"poddisruptionbudgets.policy":          `{"items": [{"metadata": {"namespace": "apps", "name": "web-pdb"}, "spec": {"selector": {"matchLabels": {"app": "web"}}}}]}`,
+		"horizontalpodautoscalers.autoscaling": `{"items": [{"metadata": {"namespace": "apps", "name": "api"}, "spec": {"scaleTargetRef": {"kind": "Deployment", "name": "api"}, "targetCPUUtilizationPercentage": 80}}]}`,
+	})
+	report := command.RunSafetyChecks(context.Background(), kubernetes.Client(), safetyWorkload, "main")
+	require.Equal(t, 0, report.Failures())
+	require.Equal(t, map[string]command.PreflightStatus{
+		"Replicas":          command.PreflightPass,
+		"Readiness probe":   command.PreflightPass,
+		"Disruption budget": command.PreflightPass,
+		"Autoscaling":       command.PreflightPass,
+	}, preflightStatuses(report))
+}
+
+func TestSafetyChecksMatchDisruptionBudgetsOnPodTemplateLabels(t *testing.T) {
+	kubernetes := newKubernetesWithLists(map[string]string{
+		"poddisruptionbudgets.policy": `{"items": [
+			{"metadata": {"namespace": "apps", "name": "frontend-pdb"}, "spec": {"selector": {"matchLabels": {"tier": "frontend"}}}},
+			{"metadata": {"namespace": "apps", "name": "team-pdb"}, "spec": {"selector": {"matchLabels": {"team": "web"}}}}
+		]}`,
+	})
+	workload := safetyWorkload
+	workload.Labels = map[string]string{"team": "web"}
+	workload.PodLabels = map[string]string{"app": "web", "tier": "frontend"}
+	report := command.RunSafetyChecks(context.Background(), kubernetes.Client(), workload, "main")
+	require.Equal(t, command.PreflightPass, preflightStatuses(report)["Disruption budget"])
+	require.Contains(t, report.Checks[2].Detail, "frontend-pdb")
+	require.NotContains(t, report.Checks[2].Detail, "team-pdb")
+}
+
+func TestSafetyChecksRiskyWorkload(t *testing.T) {
+	kubernetes := newKubernetesWithLists(map[string]string{
+		"poddisruptionbudgets.policy": `{"items": [{"metadata": {"namespace": "apps", "name": "db-pdb"}, "spec": {"selector": {"matchLabels": {"app": "db"}}}}]}`,
+		"horizontalpodautoscalers.autoscaling": `{"items": [{"metadata": {"namespace": "apps", "name": "web"}, "spec": {
+			"scaleTargetRef": {"kind": "Deployment", "name": "web"},
+			"metrics": [{"type": "Resource", "resource": {"name": "memory"}}]
+		}}]}`,
+	})
+	workload := safetyWorkload
+	workload.Replicas = 1
+	workload.ReadinessProbes = nil
+	report := command.RunSafetyChecks(context.Background(), kubernetes.Client(), workload, "main")
+	require.Equal(t, 3, report.Failures())
+	require.Equal(t, map[string]command.PreflightStatus{
+		"Replicas":          command.PreflightFail,
+		"Readiness probe":   command.PreflightFail,
+		"Disruption budget": command.PreflightWarn,
+		"Autoscaling":       command.PreflightFail,
+	}, preflightStatuses(report))
+	require.Equal(t, "HorizontalPodAutoscaler web scales on memory utilization, which shifts as the optimizer adjusts requests", report.Checks[3].Detail)
+	require.Equal(t, "Create one with `kubectl create poddisruptionbudget web -n apps --selector app=web --min-available 1`", report.Checks[2].Remediation)
+}
+
+func TestSafetyChecksDaemonSet(t *testing.T) {
+	kubernetes := newKubernetesWithLists(map[string]string{
+		"horizontalpodautoscalers.autoscaling": `{"items": [{"metadata": {"namespace": "apps", "name": "agent"}, "spec": {
+			"scaleTargetRef": {"kind": "DaemonSet", "name": "agent"},
+			"metrics": [{"type": "External", "external": {"metric": {"name": "queue_depth"}}}]
+		}}]}`,
+	})
+	workload := command.KubernetesWorkload{Kind: command.WorkloadDaemonSet, Namespace: "apps", Name: "agent", ReadinessProbes: []string{"agent"}}
+	report := command.RunSafetyChecks(context.Background(), kubernetes.Client(), workload, "agent")
+	require.Equal(t, map[string]command.PreflightStatus{
+		"Readiness probe":   command.PreflightPass,
+		"Disruption budget": command.PreflightWarn,
+		"Autoscaling":       command.PreflightWarn,
+	}, preflightStatuses(report))
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Watch flag keys
+const (
+	KeyWatch         = "watch"
+	KeyWatchInterval = "interval"
+
+	DefaultWatchInterval = 2 * time.Second
+)
+
+// clearScreen moves the cursor home and erases the display
+const clearScreen = "\x1b[H\x1b[2J"
+
+// AddWatchFlags registers the --watch and --interval flags on the given command
+func AddWatchFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP(KeyWatch, "w", false, "Watch for changes, re-rendering output in place")
+	cmd.Flags().Duration(KeyWatchInterval, DefaultWatchInterval, "Refresh interval when watching")
+}
+
+// WatchRunE wraps a Cobra run function so that it is executed repeatedly when the --watch flag is set
+// Output is re-rendered in place until the user interrupts execution with control-c
+// In accessible mode output is appended rather than redrawn
+func (baseCmd *BaseCommand) WatchRunE(runE RunEFunc) RunEFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		watch, _ := cmd.Flags().GetBool(KeyWatch)
+		if !watch {
+			return runE(cmd, args)
+		}
+		interval, _ := cmd.Flags().GetDuration(KeyWatchInterval)
+		if interval <= 0 {
+			return fmt.Errorf("invalid watch interval %q: must be greater than zero", interval)
+		}
+
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			out := baseCmd.OutOrStdout()
+			if !baseCmd.Capabilities().Animation {
+				fmt.Fprintln(out)
+			} else {
+				fmt.Fprint(out, clearScreen)
+			}
+			fmt.Fprintf(out, "Every %s: %s\t%s\n\n", interval, cmd.CommandPath(), time.Now().Format(time.RFC1123))
+			if err := runE(cmd, args); err != nil {
+				baseCmd.PrintErrf("%s: %s\n", cmd.Name(), err)
+			}
+
+			select {
+			case <-interrupt:
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+)
+
+func (s *ServoTestSuite) TestRunningServoStatusWatchRejectsInvalidInterval() {
+	driver := test.NewFakeServoDriver()
+	command.SetServoDriverFactory(driver.Factory())
+	for _, interval := range []string{"0s", "-1s"} {
+		_, err := s.Execute("--config", s.servoStatusConfigFile(`{}`), "servo", "status", "--watch", "--interval", interval)
+		s.Require().Error(err)
+		s.Require().Contains(err.Error(), "must be greater than zero")
+	}
+	s.Require().Empty(driver.Calls())
+}
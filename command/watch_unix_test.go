@@ -0,0 +1,60 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !windows
+
+package command_test
+
+import (
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+)
+
+// interruptAfterCalls interrupts the process once the fake driver has been invoked the given number of times
+func (s *ServoTestSuite) interruptAfterCalls(driver *test.FakeServoDriver, calls int) {
+	go func() {
+		deadline := time.Now().Add(10 * time.Second)
+		for len(driver.Calls()) < calls && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+	}()
+}
+
+func (s *ServoTestSuite) TestRunningServoStatusWatchRerendersOnTick() {
+	driver := test.NewFakeServoDriver()
+	command.SetServoDriverFactory(driver.Factory())
+	s.interruptAfterCalls(driver, 3)
+	output, err := s.Execute("--config", s.servoStatusConfigFile(`{}`), "servo", "status", "--watch", "--interval", "10ms")
+	s.Require().NoError(err)
+	s.Require().GreaterOrEqual(len(driver.Calls()), 3)
+	for _, call := range driver.Calls() {
+		s.Require().Equal("Status", call)
+	}
+	s.Require().GreaterOrEqual(strings.Count(output, "Every 10ms: opsani servo status"), 3)
+}
+
+func (s *ServoTestSuite) TestRunningServoStatusWatchExitsOnInterrupt() {
+	driver := test.NewFakeServoDriver()
+	command.SetServoDriverFactory(driver.Factory())
+	s.interruptAfterCalls(driver, 1)
+	output, err := s.Execute("--config", s.servoStatusConfigFile(`{}`), "servo", "status", "--watch", "--interval", "1h")
+	s.Require().NoError(err)
+	s.Require().Equal([]string{"Status"}, driver.Calls())
+	s.Require().Equal(1, strings.Count(output, "Every 1h0m0s: opsani servo status"))
+}
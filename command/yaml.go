@@ -0,0 +1,70 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+)
+
+// NewYAMLCommand returns a new Opsani CLI yaml command instance
+func NewYAMLCommand(baseCmd *BaseCommand) *cobra.Command {
+	yamlCmd := &cobra.Command{
+		Use:         "yaml",
+		Short:       "Work with YAML manifests",
+		Annotations: map[string]string{"other": "true"},
+		Args:        cobra.NoArgs,
+	}
+
+	fmtCmd := &cobra.Command{
+		Use:   "fmt FILE",
+		Short: "Validate and pretty print a YAML file",
+		Long: `Validates each document of a YAML file, such as the servo manifests generated by the CLI, and
+pretty prints the documents with the separators between them.
+
+Pass - as the file to read from stdin.`,
+		Example: `  opsani yaml fmt manifests/servo-deployment.yaml
+  kubectl get deployment web -o yaml | opsani yaml fmt -`,
+		Args:              cobra.ExactArgs(1),
+		PersistentPreRunE: nil,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var data []byte
+			var err error
+			if args[0] == "-" {
+				data, err = ioutil.ReadAll(cmd.InOrStdin())
+			} else {
+				data, err = ioutil.ReadFile(args[0])
+			}
+			if err != nil {
+				return err
+			}
+			if err := ValidateYAMLDocuments(data); err != nil {
+				return fmt.Errorf("%s: %w", args[0], err)
+			}
+			if check, _ := cmd.Flags().GetBool("check"); check {
+				return nil
+			}
+			lineNumbers, _ := cmd.Flags().GetBool("line-numbers")
+			return baseCmd.PrettyPrintYAML(data, lineNumbers)
+		},
+	}
+	fmtCmd.Flags().BoolP("line-numbers", "n", false, "Prefix each line with its line number")
+	fmtCmd.Flags().Bool("check", false, "Validate the documents without printing them")
+	yamlCmd.AddCommand(fmtCmd)
+
+	return yamlCmd
+}
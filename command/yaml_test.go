@@ -0,0 +1,106 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/opsani/cli/command"
+	"github.com/opsani/cli/test"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const multiDocumentYAML = `---
+apiVersion: v1
+kind: ServiceAccount
+---
+# The servo role
+kind: Role
+rules: [get, list]
+...
+---
+kind: Deployment
+`
+
+func TestSplitYAMLDocuments(t *testing.T) {
+	documents := command.SplitYAMLDocuments([]byte(multiDocumentYAML))
+	require.Len(t, documents, 3)
+	require.Equal(t, "apiVersion: v1\nkind: ServiceAccount\n", string(documents[0]))
+	require.Equal(t, "# The servo role\nkind: Role\nrules: [get, list]\n", string(documents[1]))
+	require.Equal(t, "kind: Deployment\n", string(documents[2]))
+
+	require.Len(t, command.SplitYAMLDocuments([]byte("kind: Pod\n")), 1)
+	require.Empty(t, command.SplitYAMLDocuments([]byte("\n")))
+}
+
+func TestValidateYAMLDocuments(t *testing.T) {
+	require.NoError(t, command.ValidateYAMLDocuments([]byte(multiDocumentYAML)))
+
+	err := command.ValidateYAMLDocuments([]byte("kind: Pod\n---\nkind: Role\n  rules: [\n"))
+	var documentError *command.YAMLDocumentError
+	require.True(t, errors.As(err, &documentError))
+	require.Equal(t, 2, documentError.Index)
+	require.Equal(t, 3, documentError.Line)
+	require.Contains(t, err.Error(), "invalid YAML document 2 (starting on line 3)")
+}
+
+func TestPrettyPrintYAMLToStringMultipleDocuments(t *testing.T) {
+	output, err := command.PrettyPrintYAMLToString([]byte(multiDocumentYAML), false, false)
+	require.NoError(t, err)
+	require.Equal(t, "---\napiVersion: v1\nkind: ServiceAccount\n---\n# The servo role\nkind: Role\nrules: [get, list]\n---\nkind: Deployment", output)
+
+	// Line numbers match the lines of the original stream
+	output, err = command.PrettyPrintYAMLToString([]byte("kind: Pod\n---\nkind: Role\n"), false, true)
+	require.NoError(t, err)
+	require.Equal(t, " 1 | kind: Pod\n 2 | ---\n 3 | kind: Role", output)
+}
+
+type YAMLTestSuite struct {
+	test.Suite
+}
+
+func TestYAMLTestSuite(t *testing.T) {
+	suite.Run(t, new(YAMLTestSuite))
+}
+
+func (s *YAMLTestSuite) SetupTest() {
+	s.SetCommand(command.NewRootCommand())
+}
+
+func (s *YAMLTestSuite) yamlFile(body string) string {
+	file, err := ioutil.TempFile("", "*.yaml")
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { os.Remove(file.Name()) })
+	_, err = file.WriteString(body)
+	s.Require().NoError(err)
+	s.Require().NoError(file.Close())
+	return file.Name()
+}
+
+func (s *YAMLTestSuite) TestRunningYAMLFmt() {
+	output, err := s.Execute("yaml", "fmt", s.yamlFile(multiDocumentYAML))
+	s.Require().NoError(err)
+	s.Require().Equal("---\napiVersion: v1\nkind: ServiceAccount\n---\n# The servo role\nkind: Role\nrules: [get, list]\n---\nkind: Deployment\n", output)
+}
+
+func (s *YAMLTestSuite) TestRunningYAMLFmtInvalid() {
+	file := s.yamlFile("kind: Pod\n---\nkind: [\n")
+	_, err := s.Execute("yaml", "fmt", "--check", file)
+	s.Require().EqualError(err, file+": invalid YAML document 2 (starting on line 3): yaml: line 1: did not find expected node content")
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/goccy/go-yaml/lexer"
+	"github.com/goccy/go-yaml/printer"
+	"gopkg.in/yaml.v2"
+)
+
+const escape = "\x1b"
+
+func format(attr color.Attribute) string {
+	return fmt.Sprintf("%s[%dm", escape, attr)
+}
+
+// yamlDocument is a document within a multi-document YAML stream
+type yamlDocument struct {
+	// Separator is the "---" line that starts the document, if any
+	Separator string
+	// Line is the line number of the first line of content within the stream
+	Line    int
+	Content string
+}
+
+// isBlank reports whether the document has no content other than whitespace
+func (d yamlDocument) isBlank() bool {
+	return strings.TrimSpace(d.Content) == ""
+}
+
+// splitYAMLStream splits a YAML stream into documents at "---" separators and "..." end markers
+// Blank documents that are not introduced by a separator are dropped
+func splitYAMLStream(data []byte) []yamlDocument {
+	documents := []yamlDocument{}
+	current := yamlDocument{Line: 1}
+	lines := []string{}
+	finish := func() {
+		current.Content = strings.Join(lines, "\n")
+		if current.Separator != "" || !current.isBlank() {
+			documents = append(documents, current)
+		}
+		lines = []string{}
+	}
+
+	for i, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		switch {
+		case isYAMLMarker(line, "---"):
+			finish()
+			current = yamlDocument{Separator: strings.TrimRight(line, " \t\r"), Line: i + 2}
+		case isYAMLMarker(line, "..."):
+			finish()
+			current = yamlDocument{Line: i + 2}
+		default:
+			lines = append(lines, line)
+		}
+	}
+	finish()
+	return documents
+}
+
+func isYAMLMarker(line string, marker string) bool {
+	line = strings.TrimRight(line, "\r")
+	return line == marker || strings.HasPrefix(line, marker+" ") || strings.HasPrefix(line, marker+"\t")
+}
+
+// SplitYAMLDocuments splits a multi-document YAML stream into its non-blank documents
+func SplitYAMLDocuments(data []byte) [][]byte {
+	documents := [][]byte{}
+	for _, document := range splitYAMLStream(data) {
+		if !document.isBlank() {
+			documents = append(documents, []byte(document.Content+"\n"))
+		}
+	}
+	return documents
+}
+
+// YAMLDocumentError describes a document of a YAML stream that could not be parsed
+type YAMLDocumentError struct {
+	// Index is the position of the document within the stream, starting at 1
+	Index int
+	// Line is the line number within the stream that the document starts on
+	Line int
+	Err  error
+}
+
+func (e *YAMLDocumentError) Error() string {
+	return fmt.Sprintf("invalid YAML document %d (starting on line %d): %s", e.Index, e.Line, e.Err)
+}
+
+func (e *YAMLDocumentError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateYAMLDocuments parses each document of a YAML stream, returning an error describing the
+// first document that is invalid
+func ValidateYAMLDocuments(data []byte) error {
+	for i, document := range splitYAMLStream(data) {
+		var obj interface{}
+		if err := yaml.Unmarshal([]byte(document.Content), &obj); err != nil {
+			return &YAMLDocumentError{Index: i + 1, Line: document.Line, Err: err}
+		}
+	}
+	return nil
+}
+
+// yamlPrinter returns a printer of YAML tokens, colorized with the CLI palette if requested
+func yamlPrinter(colorize bool, lineNumbers bool, lineOffset int) printer.Printer {
+	var p printer.Printer
+	p.LineNumber = lineNumbers
+	p.LineNumberFormat = func(num int) string {
+		return fmt.Sprintf("%2d | ", num+lineOffset)
+	}
+	if !colorize {
+		return p
+	}
+
+	property := func(c PaletteColor) printer.PrintFunc {
+		prefix := ""
+		for _, attr := range c {
+			prefix += format(attr)
+		}
+		return func() *printer.Property {
+			return &printer.Property{
+				Prefix: prefix,
+				Suffix: format(color.Reset),
+			}
+		}
+	}
+	p.LineNumberFormat = func(num int) string {
+		fn := color.New(color.Bold, color.FgHiWhite).SprintFunc()
+		return fn(fmt.Sprintf("%2d | ", num+lineOffset))
+	}
+	p.Bool = property(activePalette.Literal)
+	p.Number = property(activePalette.Literal)
+	p.MapKey = property(activePalette.Key)
+	p.Anchor = property(activePalette.Alias)
+	p.Alias = property(activePalette.Alias)
+	p.String = property(activePalette.String)
+	return p
+}
+
+// prettyPrintYAMLDocuments formats each document of a YAML stream, preserving the separators between them
+func prettyPrintYAMLDocuments(data []byte, colorize bool, lineNumbers bool) string {
+	texts := []string{}
+	for _, document := range splitYAMLStream(data) {
+		if document.Separator != "" {
+			separator := document.Separator
+			if colorize {
+				separator = format(color.Faint) + separator + format(color.Reset)
+			}
+			if lineNumbers {
+				separator = yamlPrinter(colorize, true, 0).LineNumberFormat(document.Line-1) + separator
+			}
+			texts = append(texts, separator)
+		}
+		if document.isBlank() {
+			continue
+		}
+		p := yamlPrinter(colorize, lineNumbers, document.Line-1)
+		texts = append(texts, p.PrintTokens(lexer.Tokenize(document.Content)))
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opsani
+
+import (
+	"strconv"
+	"time"
+)
+
+// TimeRange filters optimizer activity to a window of time
+// Zero values are omitted from the request and leave the range open ended
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+	Limit int
+}
+
+func (r TimeRange) queryParams() map[string]string {
+	params := map[string]string{}
+	if !r.Start.IsZero() {
+		params["start"] = r.Start.UTC().Format(time.RFC3339)
+	}
+	if !r.End.IsZero() {
+		params["end"] = r.End.UTC().Format(time.RFC3339)
+	}
+	if r.Limit > 0 {
+		params["limit"] = strconv.Itoa(r.Limit)
+	}
+	return params
+}
+
+// Adjustment describes a change to application settings applied by the optimizer
+type Adjustment struct {
+	ID          string                            `json:"id"`
+	Status      string                            `json:"status"`
+	StartedAt   time.Time                         `json:"started_at"`
+	CompletedAt *time.Time                        `json:"completed_at,omitempty"`
+	Components  map[string]map[string]interface{} `json:"components"`
+}
+
+// Duration returns the time taken to apply the adjustment or zero if it has not completed
+func (a Adjustment) Duration() time.Duration {
+	if a.CompletedAt == nil {
+		return 0
+	}
+	return a.CompletedAt.Sub(a.StartedAt)
+}
+
+// AdjustmentList is the response body of the adjustments endpoint
+type AdjustmentList struct {
+	Adjustments []Adjustment `json:"adjustments"`
+}
+
+// MeasurementMetric is the summarized value of a metric over a measurement
+type MeasurementMetric struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
+// Measurement describes the metrics gathered by the optimizer for a configuration
+type Measurement struct {
+	ID          string                       `json:"id"`
+	Status      string                       `json:"status"`
+	StartedAt   time.Time                    `json:"started_at"`
+	CompletedAt *time.Time                   `json:"completed_at,omitempty"`
+	Metrics     map[string]MeasurementMetric `json:"metrics"`
+}
+
+// Duration returns the time taken by the measurement or zero if it has not completed
+func (m Measurement) Duration() time.Duration {
+	if m.CompletedAt == nil {
+		return 0
+	}
+	return m.CompletedAt.Sub(m.StartedAt)
+}
+
+// MeasurementList is the response body of the measurements endpoint
+type MeasurementList struct {
+	Measurements []Measurement `json:"measurements"`
+}
+
+/**
+Activity
+*/
+
+// GetAdjustments retrieves the adjustments made by the optimizer within the time range
+func (c *Client) GetAdjustments(timeRange TimeRange) ([]Adjustment, error) {
+	result := &AdjustmentList{}
+	_, err := c.newRequest().
+		SetQueryParams(timeRange.queryParams()).
+		SetResult(result).
+		Get(c.appResourceURLPath("adjustments"))
+	if err != nil {
+		return nil, err
+	}
+	return result.Adjustments, nil
+}
+
+// GetMeasurements retrieves the measurements taken by the optimizer within the time range
+func (c *Client) GetMeasurements(timeRange TimeRange) ([]Measurement, error) {
+	result := &MeasurementList{}
+	_, err := c.newRequest().
+		SetQueryParams(timeRange.queryParams()).
+		SetResult(result).
+		Get(c.appResourceURLPath("measurements"))
+	if err != nil {
+		return nil, err
+	}
+	return result.Measurements, nil
+}
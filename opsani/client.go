@@ -24,6 +24,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"encoding/json"
 
@@ -32,10 +33,11 @@ import (
 
 // APIError represents an error returned by the Opsani API
 type APIError struct {
-	Status    string `json:"status"`
-	Message   string `json:"message"`
-	Traceback string `json:"traceback"`
-	Version   string `json:"version"`
+	Status    string       `json:"status"`
+	Message   string       `json:"message"`
+	Traceback string       `json:"traceback"`
+	Version   string       `json:"version"`
+	Errors    []FieldError `json:"errors,omitempty"`
 }
 
 // Error returns an error representation of the API error
@@ -45,9 +47,10 @@ func (err APIError) Error() string {
 
 // Client provides a high level interface to the Opsani API
 type Client struct {
-	restyClient *resty.Client
-	appDomain   string
-	appName     string
+	restyClient   *resty.Client
+	appDomain     string
+	appName       string
+	redactSecrets bool
 }
 
 // NewClient creates a new Opsani API client.
@@ -63,11 +66,7 @@ func NewClient() *Client {
 	// Return errors for 4xx and 5xx responses
 	rc.OnAfterResponse(func(c *resty.Client, resp *resty.Response) error {
 		if resp.IsError() {
-			apiError := resp.Error().(*APIError)
-			if apiError != nil && *apiError != (APIError{}) {
-				return apiError
-			}
-			return fmt.Errorf("request failed (%q): %s", resp.Status(), resp.Body())
+			return newAPIError(resp)
 		}
 
 		return nil
@@ -91,9 +90,18 @@ func createClientWithHTTPClient(hc *http.Client) *Client {
 }
 
 func createClientWithRestyClient(rc *resty.Client) *Client {
-	return &Client{
+	c := &Client{
 		restyClient: rc,
 	}
+
+	// Mask the auth token in debug request logs
+	rc.OnRequestLog(func(rl *resty.RequestLog) error {
+		if c.redactSecrets && rl.Header.Get("Authorization") != "" {
+			rl.Header.Set("Authorization", "Bearer [REDACTED]")
+		}
+		return nil
+	})
+	return c
 }
 
 // GetRestyClient returns the current `resty.Client` used by the opsani client.
@@ -202,6 +210,24 @@ func (c *Client) RestartApp() (*resty.Response, error) {
 		Put(c.appConfigURLPath())
 }
 
+func (c *Client) overrideURLPath() string {
+	return c.appResourceURLPath("state/override")
+}
+
+// PauseApp suspends the measurement and adjustment cycles of an Opsani app while leaving the servo running
+func (c *Client) PauseApp(reason string) (*resty.Response, error) {
+	return c.newRequest().
+		SetBody(map[string]interface{}{"paused": true, "reason": reason}).
+		Put(c.overrideURLPath())
+}
+
+// ResumeApp resumes the measurement and adjustment cycles of a paused Opsani app
+func (c *Client) ResumeApp() (*resty.Response, error) {
+	return c.newRequest().
+		SetBody(map[string]interface{}{"paused": false}).
+		Put(c.overrideURLPath())
+}
+
 // GetAppStatus retrieves the status of the Opsani app from the API
 func (c *Client) GetAppStatus() (*resty.Response, error) {
 	return c.newRequest().
@@ -212,6 +238,23 @@ func (c *Client) GetAppStatus() (*resty.Response, error) {
 Authentication actions
 */
 
+// TokenInfo describes the permissions granted to an API token
+type TokenInfo struct {
+	Scopes []string `json:"scopes"`
+}
+
+// GetTokenInfo retrieves the permission scopes of the API token for the app
+func (c *Client) GetTokenInfo() (*TokenInfo, error) {
+	result := &TokenInfo{}
+	_, err := c.newRequest().
+		SetResult(result).
+		Get(c.appResourceURLPath("token"))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // IsAuthenticated returns true if an authentication token has been set.
 func (c *Client) IsAuthenticated() bool {
 	return c.restyClient.Token == ""
@@ -240,6 +283,19 @@ func (c *Client) SetDebug(enabled bool) *Client {
 	return c
 }
 
+// SetTimeout sets the timeout for requests made by the client
+// A zero timeout disables the timeout
+func (c *Client) SetTimeout(timeout time.Duration) *Client {
+	c.restyClient.SetTimeout(timeout)
+	return c
+}
+
+// SetRedactSecrets controls whether or not the auth token is masked in debug request logs
+func (c *Client) SetRedactSecrets(enabled bool) *Client {
+	c.redactSecrets = enabled
+	return c
+}
+
 // EnableTrace enables tracing information for all requests
 func (c *Client) EnableTrace() *Client {
 	c.restyClient.EnableTrace()
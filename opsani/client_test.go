@@ -15,9 +15,12 @@
 package opsani_test
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"encoding/json"
 
@@ -98,3 +101,206 @@ func (s *ClientTestSuite) TestStartAppAlreadyStartedError() {
 	s.Require().Empty(result)
 	s.Require().Equal(&responseObj, err)
 }
+
+func (s *ClientTestSuite) TestPauseAndResumeApp() {
+	var method, path string
+	var body map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		body = map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Add("content-type", "application/json")
+		w.Write([]byte(`{"status": "ok"}`))
+	}))
+	defer ts.Close()
+
+	client := opsani.NewClient()
+	client.SetBaseURL(ts.URL)
+	client.SetApp("example.com/app")
+	_, err := client.PauseApp("maintenance")
+	s.Require().NoError(err)
+	s.Require().Equal(http.MethodPut, method)
+	s.Require().Equal("/accounts/example.com/applications/app/state/override", path)
+	s.Require().Equal(map[string]interface{}{"paused": true, "reason": "maintenance"}, body)
+
+	_, err = client.ResumeApp()
+	s.Require().NoError(err)
+	s.Require().Equal("/accounts/example.com/applications/app/state/override", path)
+	s.Require().Equal(map[string]interface{}{"paused": false}, body)
+}
+
+func (s *ClientTestSuite) TestErrorResponsesAreTyped() {
+	var statusCode int
+	var body string
+	contentType := "application/json"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("content-type", contentType)
+		w.WriteHeader(statusCode)
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	client := opsani.NewClient()
+	client.SetBaseURL(ts.URL)
+	client.SetApp("example.com/app")
+
+	statusCode, body = http.StatusNotFound, `{"status": "404 Not Found", "message": "no such application"}`
+	_, err := client.GetAppStatus()
+	var notFound *opsani.NotFoundError
+	s.Require().True(errors.As(err, &notFound))
+	s.Require().Equal("request failed: no such application (404 Not Found)", err.Error())
+
+	statusCode, body = http.StatusForbidden, `{"message": "read-only token"}`
+	_, err = client.StartApp()
+	var unauthorized *opsani.UnauthorizedError
+	s.Require().True(errors.As(err, &unauthorized))
+	s.Require().True(unauthorized.Forbidden)
+	s.Require().Equal("403 Forbidden", unauthorized.Status)
+
+	statusCode, body = http.StatusUnprocessableEntity, `{"message": "invalid config", "errors": [{"loc": ["adjustment", "cpu", "max"], "msg": "must be a number"}, {"loc": ["measurement", "metrics", 0], "msg": "unknown metric"}]}`
+	_, err = client.PatchConfigFromBody(map[string]interface{}{}, false)
+	var validation *opsani.ValidationError
+	s.Require().True(errors.As(err, &validation))
+	s.Require().Len(validation.Fields(), 2)
+	s.Require().Equal("adjustment.cpu.max: must be a number", validation.Fields()[0].String())
+	s.Require().Equal("measurement.metrics.0", validation.Fields()[1].Path())
+
+	statusCode, body, contentType = http.StatusBadGateway, `upstream unavailable`, "text/plain"
+	_, err = client.GetAppStatus()
+	var apiErr *opsani.APIError
+	s.Require().True(errors.As(err, &apiErr))
+	s.Require().Equal("request failed: upstream unavailable (502 Bad Gateway)", err.Error())
+	s.Require().False(errors.As(err, &validation))
+}
+
+func (s *ClientTestSuite) TestGetOrganizations() {
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Add("content-type", "application/json")
+		w.Write([]byte(`{"organizations": [{"domain": "example.com", "name": "Example", "teams": [{"name": "platform", "role": "admin"}]}]}`))
+	}))
+	defer ts.Close()
+
+	client := opsani.NewClient()
+	client.SetBaseURL(ts.URL)
+	organizations, err := client.GetOrganizations()
+	s.Require().NoError(err)
+	s.Require().Equal("/accounts", path)
+	s.Require().Equal([]opsani.Organization{
+		{Domain: "example.com", Name: "Example", Teams: []opsani.Team{{Name: "platform", Role: "admin"}}},
+	}, organizations)
+}
+
+func (s *ClientTestSuite) TestGetTokenInfo() {
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Add("content-type", "application/json")
+		w.Write([]byte(`{"scopes": ["read"]}`))
+	}))
+	defer ts.Close()
+
+	client := opsani.NewClient()
+	client.SetBaseURL(ts.URL)
+	client.SetApp("example.com/app")
+	info, err := client.GetTokenInfo()
+	s.Require().NoError(err)
+	s.Require().Equal("/accounts/example.com/applications/app/token", path)
+	s.Require().Equal([]string{"read"}, info.Scopes)
+}
+
+func (s *ClientTestSuite) TestGetAdjustments() {
+	var query url.Values
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.Path, r.URL.Query()
+		w.Header().Add("content-type", "application/json")
+		w.Write([]byte(`{"adjustments": [{"id": "adj-1", "status": "completed", "started_at": "2020-06-01T10:00:00Z",
+			"completed_at": "2020-06-01T10:02:30Z", "components": {"web": {"cpu": 0.5, "replicas": 2}}}]}`))
+	}))
+	defer ts.Close()
+
+	client := opsani.NewClient()
+	client.SetBaseURL(ts.URL)
+	client.SetApp("example.com/app")
+	start := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	adjustments, err := client.GetAdjustments(opsani.TimeRange{Start: start, Limit: 10})
+	s.Require().NoError(err)
+	s.Require().Equal("/accounts/example.com/applications/app/adjustments", path)
+	s.Require().Equal("2020-06-01T00:00:00Z", query.Get("start"))
+	s.Require().Equal("", query.Get("end"))
+	s.Require().Equal("10", query.Get("limit"))
+	s.Require().Len(adjustments, 1)
+	s.Require().Equal("adj-1", adjustments[0].ID)
+	s.Require().Equal(150*time.Second, adjustments[0].Duration())
+	s.Require().Equal(0.5, adjustments[0].Components["web"]["cpu"])
+}
+
+func (s *ClientTestSuite) TestGetServoStatus() {
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Add("content-type", "application/json")
+		w.Write([]byte(`{"last_report_at": "2020-06-01T10:00:00Z", "phase": "measuring",
+			"pending_adjustments": [{"id": "adj-2", "status": "pending", "started_at": "2020-06-01T10:01:00Z"}]}`))
+	}))
+	defer ts.Close()
+
+	client := opsani.NewClient()
+	client.SetBaseURL(ts.URL)
+	client.SetApp("example.com/app")
+	status, err := client.GetServoStatus()
+	s.Require().NoError(err)
+	s.Require().Equal("/accounts/example.com/applications/app/servo", path)
+	s.Require().Equal(time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC), *status.LastReportAt)
+	s.Require().Equal("measuring", status.Phase)
+	s.Require().Len(status.PendingAdjustments, 1)
+	s.Require().Equal("adj-2", status.PendingAdjustments[0].ID)
+}
+
+func (s *ClientTestSuite) TestGetMeasurements() {
+	var query url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Add("content-type", "application/json")
+		w.Write([]byte(`{"measurements": [{"id": "msr-1", "status": "running", "started_at": "2020-06-01T10:00:00Z",
+			"metrics": {"throughput": {"value": 125.5, "unit": "rpm"}}}]}`))
+	}))
+	defer ts.Close()
+
+	client := opsani.NewClient()
+	client.SetBaseURL(ts.URL)
+	client.SetApp("example.com/app")
+	end := time.Date(2020, 6, 2, 0, 0, 0, 0, time.UTC)
+	measurements, err := client.GetMeasurements(opsani.TimeRange{End: end})
+	s.Require().NoError(err)
+	s.Require().Equal("", query.Get("start"))
+	s.Require().Equal("2020-06-02T00:00:00Z", query.Get("end"))
+	s.Require().Len(measurements, 1)
+	s.Require().Equal(time.Duration(0), measurements[0].Duration())
+	s.Require().Equal(opsani.MeasurementMetric{Value: 125.5, Unit: "rpm"}, measurements[0].Metrics["throughput"])
+}
+
+func (s *ClientTestSuite) TestGetSavings() {
+	var query url.Values
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.Path, r.URL.Query()
+		w.Header().Add("content-type", "application/json")
+		w.Write([]byte(`{"currency": "USD", "baseline_cost": 1000, "optimized_cost": 750,
+			"realized_savings": 250, "projected_savings": 3000}`))
+	}))
+	defer ts.Close()
+
+	client := opsani.NewClient()
+	client.SetBaseURL(ts.URL)
+	client.SetApp("example.com/app")
+	start := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	savings, err := client.GetSavings(opsani.TimeRange{Start: start})
+	s.Require().NoError(err)
+	s.Require().Equal("/accounts/example.com/applications/app/savings", path)
+	s.Require().Equal("2020-06-01T00:00:00Z", query.Get("start"))
+	s.Require().Equal(opsani.Savings{Currency: "USD", BaselineCost: 1000, OptimizedCost: 750, RealizedSavings: 250, ProjectedSavings: 3000}, *savings)
+	s.Require().Equal(25.0, savings.RealizedPercent())
+}
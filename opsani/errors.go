@@ -0,0 +1,122 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opsani
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// FieldError describes a value of a request body that failed validation
+type FieldError struct {
+	// Location is the path to the value as a sequence of keys and indexes
+	Location []interface{} `json:"loc"`
+	Message  string        `json:"msg"`
+}
+
+// Path returns the location of the value as a dotted path such as "adjustment.cpu.max"
+func (f FieldError) Path() string {
+	keys := []string{}
+	for _, key := range f.Location {
+		switch k := key.(type) {
+		case float64:
+			keys = append(keys, fmt.Sprintf("%d", int(k)))
+		default:
+			keys = append(keys, fmt.Sprintf("%v", k))
+		}
+	}
+	return strings.Join(keys, ".")
+}
+
+// String returns a description of the field error
+func (f FieldError) String() string {
+	if path := f.Path(); path != "" {
+		return fmt.Sprintf("%s: %s", path, f.Message)
+	}
+	return f.Message
+}
+
+// NotFoundError is returned when the requested resource does not exist
+type NotFoundError struct {
+	*APIError
+}
+
+// Unwrap returns the underlying API error
+func (err *NotFoundError) Unwrap() error {
+	return err.APIError
+}
+
+// UnauthorizedError is returned when the API token is missing, invalid, or lacks access to the resource
+type UnauthorizedError struct {
+	*APIError
+	// Forbidden is true if the token is valid but not permitted to perform the request
+	Forbidden bool
+}
+
+// Unwrap returns the underlying API error
+func (err *UnauthorizedError) Unwrap() error {
+	return err.APIError
+}
+
+// ValidationError is returned when the request body is rejected by the API
+type ValidationError struct {
+	*APIError
+}
+
+// Unwrap returns the underlying API error
+func (err *ValidationError) Unwrap() error {
+	return err.APIError
+}
+
+// Fields returns the values that failed validation
+func (err *ValidationError) Fields() []FieldError {
+	return err.Errors
+}
+
+// isEmpty returns true if no error details were decoded from the response
+func (err *APIError) isEmpty() bool {
+	return err.Status == "" && err.Message == "" && err.Traceback == "" && err.Version == "" && len(err.Errors) == 0
+}
+
+// newAPIError maps an error response to a typed error by status code
+// Responses without a JSON error body are described by their status and raw body.
+func newAPIError(resp *resty.Response) error {
+	apiError, _ := resp.Error().(*APIError)
+	if apiError == nil || apiError.isEmpty() {
+		apiError = &APIError{Message: strings.TrimSpace(string(resp.Body()))}
+	}
+	if apiError.Status == "" {
+		apiError.Status = resp.Status()
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusNotFound:
+		return &NotFoundError{apiError}
+	case http.StatusUnauthorized:
+		return &UnauthorizedError{APIError: apiError}
+	case http.StatusForbidden:
+		return &UnauthorizedError{APIError: apiError, Forbidden: true}
+	case http.StatusUnprocessableEntity:
+		return &ValidationError{apiError}
+	case http.StatusBadRequest:
+		if len(apiError.Errors) > 0 {
+			return &ValidationError{apiError}
+		}
+	}
+	return apiError
+}
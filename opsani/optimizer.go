@@ -0,0 +1,68 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opsani
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	urlSchemePattern     = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+	consoleURLPattern    = regexp.MustCompile(`accounts/([^/?#]+)/applications/([^/?#]+)`)
+	optimizerDomainRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)+$`)
+	optimizerAppRegex    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+)
+
+// OptimizerID identifies an optimizer by the domain of the owning organization and the name of the app
+type OptimizerID struct {
+	Domain string
+	App    string
+}
+
+// String returns the optimizer in domain/app form
+func (id OptimizerID) String() string {
+	return id.Domain + "/" + id.App
+}
+
+// ParseOptimizerID parses and normalizes an optimizer of the form domain.com/app
+// URL schemes and trailing slashes are stripped and console URLs are reduced to the optimizer they reference
+func ParseOptimizerID(optimizer string) (OptimizerID, error) {
+	value := strings.TrimSpace(optimizer)
+	if matches := consoleURLPattern.FindStringSubmatch(value); matches != nil {
+		value = matches[1] + "/" + matches[2]
+	}
+	value = urlSchemePattern.ReplaceAllString(value, "")
+	value = strings.Trim(value, "/")
+	if value == "" {
+		return OptimizerID{}, fmt.Errorf("invalid optimizer %q: must be of the form domain.com/app", optimizer)
+	}
+
+	components := strings.Split(value, "/")
+	if len(components) == 1 {
+		return OptimizerID{}, fmt.Errorf("invalid optimizer %q: missing the organization domain (e.g. example.com/%s)", optimizer, value)
+	} else if len(components) != 2 {
+		return OptimizerID{}, fmt.Errorf("invalid optimizer %q: must be of the form domain.com/app", optimizer)
+	}
+	id := OptimizerID{Domain: strings.ToLower(components[0]), App: components[1]}
+	if !optimizerDomainRegex.MatchString(id.Domain) {
+		return OptimizerID{}, fmt.Errorf("invalid optimizer %q: %q is not a valid organization domain", optimizer, components[0])
+	}
+	if !optimizerAppRegex.MatchString(id.App) {
+		return OptimizerID{}, fmt.Errorf("invalid optimizer %q: %q is not a valid app name", optimizer, id.App)
+	}
+	return id, nil
+}
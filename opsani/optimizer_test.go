@@ -0,0 +1,58 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opsani_test
+
+import (
+	"testing"
+
+	"github.com/opsani/cli/opsani"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOptimizerIDNormalizes(t *testing.T) {
+	cases := map[string]string{
+		"example.com/app":            "example.com/app",
+		" Example.COM/app/ ":         "example.com/app",
+		"https://example.com/app":    "example.com/app",
+		"dev.opsani.com/amazing-app": "dev.opsani.com/amazing-app",
+		"https://console.opsani.com/accounts/example.com/applications/app":    "example.com/app",
+		"https://console.opsani.com/accounts/example.com/applications/app/#x": "example.com/app",
+	}
+	for input, expected := range cases {
+		id, err := opsani.ParseOptimizerID(input)
+		require.NoError(t, err, input)
+		require.Equal(t, expected, id.String(), input)
+	}
+}
+
+func TestParseOptimizerIDComponents(t *testing.T) {
+	id, err := opsani.ParseOptimizerID("example.com/app")
+	require.NoError(t, err)
+	require.Equal(t, opsani.OptimizerID{Domain: "example.com", App: "app"}, id)
+}
+
+func TestParseOptimizerIDInvalid(t *testing.T) {
+	cases := map[string]string{
+		"":                    `invalid optimizer "": must be of the form domain.com/app`,
+		"app":                 `invalid optimizer "app": missing the organization domain (e.g. example.com/app)`,
+		"example.com/app/foo": `invalid optimizer "example.com/app/foo": must be of the form domain.com/app`,
+		"example/app":         `invalid optimizer "example/app": "example" is not a valid organization domain`,
+		"example.com/-app":    `invalid optimizer "example.com/-app": "-app" is not a valid app name`,
+	}
+	for input, expected := range cases {
+		_, err := opsani.ParseOptimizerID(input)
+		require.EqualError(t, err, expected, input)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opsani
+
+// Organization is an account owning optimizers, identified by its domain
+type Organization struct {
+	Domain string `json:"domain"`
+	Name   string `json:"name"`
+	Teams  []Team `json:"teams"`
+}
+
+// Team is a group of users within an organization
+type Team struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// OrganizationList is a list of organizations
+type OrganizationList struct {
+	Organizations []Organization `json:"organizations"`
+}
+
+/**
+Organizations
+*/
+
+// GetOrganizations retrieves the organizations the API token has access to
+func (c *Client) GetOrganizations() ([]Organization, error) {
+	result := &OrganizationList{}
+	_, err := c.newRequest().
+		SetResult(result).
+		Get("/accounts")
+	if err != nil {
+		return nil, err
+	}
+	return result.Organizations, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opsani
+
+// Savings summarizes the cost impact of optimization over a time range
+type Savings struct {
+	Currency         string  `json:"currency"`
+	BaselineCost     float64 `json:"baseline_cost"`
+	OptimizedCost    float64 `json:"optimized_cost"`
+	RealizedSavings  float64 `json:"realized_savings"`
+	ProjectedSavings float64 `json:"projected_savings"`
+}
+
+// RealizedPercent returns the realized savings as a percentage of the baseline cost
+func (s Savings) RealizedPercent() float64 {
+	if s.BaselineCost == 0 {
+		return 0
+	}
+	return s.RealizedSavings / s.BaselineCost * 100
+}
+
+// GetSavings retrieves the realized savings within the time range and the projected annual savings
+func (c *Client) GetSavings(timeRange TimeRange) (*Savings, error) {
+	result := &Savings{}
+	_, err := c.newRequest().
+		SetQueryParams(timeRange.queryParams()).
+		SetResult(result).
+		Get(c.appResourceURLPath("savings"))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
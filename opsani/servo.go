@@ -0,0 +1,37 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opsani
+
+import "time"
+
+// ServoStatus describes the servo activity observed by the optimizer
+type ServoStatus struct {
+	// LastReportAt is the time of the last message from the servo or nil if it has never reported
+	LastReportAt       *time.Time   `json:"last_report_at,omitempty"`
+	Phase              string       `json:"phase"`
+	PendingAdjustments []Adjustment `json:"pending_adjustments"`
+}
+
+// GetServoStatus retrieves the last report, optimization phase, and pending adjustments of the servo
+func (c *Client) GetServoStatus() (*ServoStatus, error) {
+	result := &ServoStatus{}
+	_, err := c.newRequest().
+		SetResult(result).
+		Get(c.appResourceURLPath("servo"))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
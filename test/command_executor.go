@@ -16,6 +16,8 @@ package test
 
 import (
 	"bytes"
+	"io"
+	"os"
 
 	"github.com/prometheus/common/log"
 	"github.com/spf13/cobra"
@@ -31,6 +33,47 @@ func NewCommandExecutor(rootCmd *cobra.Command) *CommandExecutor {
 // CommandExecutor provides an interface for executing Cobra commands in tests
 type CommandExecutor struct {
 	rootCmd *cobra.Command
+	stdin   io.Reader
+	env     map[string]string
+}
+
+// SetStdin sets the input read by the next execution (e.g. for `-f -` arguments)
+// The reader is consumed by a single execution and cleared afterwards
+func (ce *CommandExecutor) SetStdin(stdin io.Reader) {
+	ce.stdin = stdin
+}
+
+// SetEnv sets an environment variable for the duration of each execution
+// Previous values are restored once the execution completes
+func (ce *CommandExecutor) SetEnv(key, value string) {
+	if ce.env == nil {
+		ce.env = map[string]string{}
+	}
+	ce.env[key] = value
+}
+
+// UnsetEnv removes an environment variable previously set via SetEnv
+func (ce *CommandExecutor) UnsetEnv(key string) {
+	delete(ce.env, key)
+}
+
+// applyEnv sets the configured environment and returns a func that restores the previous environment
+func (ce *CommandExecutor) applyEnv() func() {
+	restores := []func(){}
+	for key, value := range ce.env {
+		key := key
+		if previous, ok := os.LookupEnv(key); ok {
+			restores = append(restores, func() { os.Setenv(key, previous) })
+		} else {
+			restores = append(restores, func() { os.Unsetenv(key) })
+		}
+		os.Setenv(key, value)
+	}
+	return func() {
+		for _, restore := range restores {
+			restore()
+		}
+	}
 }
 
 // Execute runs a Cobra command with the given arguments and returns the output captured
@@ -51,6 +94,14 @@ func (ce *CommandExecutor) ExecuteC(args ...string) (c *cobra.Command, output st
 	ce.rootCmd.SetOut(buf)
 	ce.rootCmd.SetErr(buf)
 	ce.rootCmd.SetArgs(args)
+	if ce.stdin != nil {
+		ce.rootCmd.SetIn(ce.stdin)
+		defer func() {
+			ce.stdin = nil
+			ce.rootCmd.SetIn(nil)
+		}()
+	}
+	defer ce.applyEnv()()
 
 	c, err = ce.rootCmd.ExecuteC()
 	return c, buf.String(), err
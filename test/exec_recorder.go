@@ -0,0 +1,134 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables used to communicate simulated behavior to the helper process
+const (
+	helperProcessEnv = "OPSANI_TEST_HELPER_PROCESS"
+	helperStdoutEnv  = "OPSANI_TEST_HELPER_STDOUT"
+	helperStderrEnv  = "OPSANI_TEST_HELPER_STDERR"
+	helperExitEnv    = "OPSANI_TEST_HELPER_EXIT_CODE"
+	helperDelayEnv   = "OPSANI_TEST_HELPER_DELAY"
+)
+
+// ExecResponse describes the simulated result of an external command
+type ExecResponse struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+
+	// Delay postpones completion to simulate slow commands and time-outs
+	Delay time.Duration
+}
+
+// ExecRecorder intercepts the execution of external commands, recording the argv of each
+// invocation and simulating its result deterministically via a helper process
+//
+// The test binary must route the helper process by declaring:
+//
+//	func TestHelperProcess(t *testing.T) { test.RunHelperProcess() }
+type ExecRecorder struct {
+	mu          sync.Mutex
+	invocations [][]string
+	responses   []execResponseRule
+}
+
+type execResponseRule struct {
+	prefix   string
+	response ExecResponse
+}
+
+// NewExecRecorder returns a new recorder that succeeds with no output for all commands
+func NewExecRecorder() *ExecRecorder {
+	return &ExecRecorder{}
+}
+
+// Respond configures the response for invocations whose space joined argv begins with the prefix
+// The most recently configured matching response is used
+func (r *ExecRecorder) Respond(prefix string, response ExecResponse) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses = append(r.responses, execResponseRule{prefix: prefix, response: response})
+}
+
+// Invocations returns the argv of each command executed in order
+func (r *ExecRecorder) Invocations() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]string{}, r.invocations...)
+}
+
+// LastInvocation returns the argv of the most recently executed command
+func (r *ExecRecorder) LastInvocation() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.invocations) == 0 {
+		return nil
+	}
+	return r.invocations[len(r.invocations)-1]
+}
+
+// CommandContext records the invocation and returns a command that runs the helper process
+// Use with command.SetCommandContextFunc
+func (r *ExecRecorder) CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	argv := append([]string{name}, args...)
+	r.mu.Lock()
+	r.invocations = append(r.invocations, argv)
+	response := ExecResponse{}
+	joined := strings.Join(argv, " ")
+	for i := len(r.responses) - 1; i >= 0; i-- {
+		if strings.HasPrefix(joined, r.responses[i].prefix) {
+			response = r.responses[i].response
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	cmd := exec.CommandContext(ctx, os.Args[0], append([]string{"-test.run=TestHelperProcess", "--"}, argv...)...)
+	cmd.Env = append(os.Environ(),
+		helperProcessEnv+"=1",
+		helperStdoutEnv+"="+response.Stdout,
+		helperStderrEnv+"="+response.Stderr,
+		helperExitEnv+"="+strconv.Itoa(response.ExitCode),
+		helperDelayEnv+"="+response.Delay.String(),
+	)
+	return cmd
+}
+
+// RunHelperProcess simulates an external command when the test binary is run as a helper process
+// It returns immediately when the binary is not running as a helper
+func RunHelperProcess() {
+	if os.Getenv(helperProcessEnv) != "1" {
+		return
+	}
+	if delay, err := time.ParseDuration(os.Getenv(helperDelayEnv)); err == nil {
+		time.Sleep(delay)
+	}
+	fmt.Fprint(os.Stdout, os.Getenv(helperStdoutEnv))
+	fmt.Fprint(os.Stderr, os.Getenv(helperStderrEnv))
+	exitCode, _ := strconv.Atoi(os.Getenv(helperExitEnv))
+	os.Exit(exitCode)
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// UpdateGoldenFiles rewrites golden files with the actual output instead of comparing
+// Run `go test ./... -update` after intentional output changes and review the diff
+var UpdateGoldenFiles = flag.Bool("update", false, "update golden files with actual output")
+
+// GoldenFileDir is the directory golden files are read from, relative to the package under test
+const GoldenFileDir = "testdata"
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+// NormalizeOutput strips ANSI escape codes, normalizes line endings, and trims trailing
+// whitespace from each line so that golden files are stable across terminals and platforms
+func NormalizeOutput(output string) string {
+	output = ansiPattern.ReplaceAllString(output, "")
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GoldenFilePath returns the path to the golden file with the given name
+func GoldenFilePath(name string) string {
+	return filepath.Join(GoldenFileDir, name+".golden")
+}
+
+// RequireMatchesGolden fails the test unless the normalized output matches the named golden file
+// The golden file is written instead when the -update flag is given
+func RequireMatchesGolden(t testing.TB, name string, output string) {
+	t.Helper()
+	actual := NormalizeOutput(output)
+	path := GoldenFilePath(name)
+	if *UpdateGoldenFiles {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed creating golden file directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(actual), 0644); err != nil {
+			t.Fatalf("failed writing golden file: %s", err)
+		}
+		return
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed reading golden file (run with -update to create it): %s", err)
+	}
+	expected := NormalizeOutput(string(data))
+	if expected != actual {
+		diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(expected),
+			B:        difflib.SplitLines(actual),
+			FromFile: path,
+			ToFile:   "actual",
+			Context:  3,
+		})
+		t.Fatalf("output does not match golden file %s (run with -update to accept changes):\n%s", path, diff)
+	}
+}
+
+// RequireOutputMatchesGolden fails the test unless the output matches the named golden file
+func (h *Suite) RequireOutputMatchesGolden(name string, output string) {
+	RequireMatchesGolden(h.T(), name, output)
+}
@@ -0,0 +1,276 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opsani/cli/command"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/discovery"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// kubernetesAPIResources are the resources served by each group version of the fake cluster
+var kubernetesAPIResources = map[string][]metav1.APIResource{
+	"v1": {
+		{Name: "namespaces", Kind: "Namespace"},
+		{Name: "pods", Kind: "Pod", Namespaced: true},
+		{Name: "services", Kind: "Service", Namespaced: true},
+		{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+		{Name: "secrets", Kind: "Secret", Namespaced: true},
+		{Name: "serviceaccounts", Kind: "ServiceAccount", Namespaced: true},
+	},
+	"apps/v1": {
+		{Name: "deployments", Kind: "Deployment", Namespaced: true},
+		{Name: "statefulsets", Kind: "StatefulSet", Namespaced: true},
+		{Name: "daemonsets", Kind: "DaemonSet", Namespaced: true},
+	},
+	"autoscaling/v1": {
+		{Name: "horizontalpodautoscalers", Kind: "HorizontalPodAutoscaler", Namespaced: true},
+	},
+	"policy/v1beta1": {
+		{Name: "poddisruptionbudgets", Kind: "PodDisruptionBudget", Namespaced: true},
+		{Name: "podsecuritypolicies", Kind: "PodSecurityPolicy"},
+	},
+	"rbac.authorization.k8s.io/v1": {
+		{Name: "roles", Kind: "Role", Namespaced: true},
+		{Name: "rolebindings", Kind: "RoleBinding", Namespaced: true},
+		{Name: "clusterroles", Kind: "ClusterRole"},
+		{Name: "clusterrolebindings", Kind: "ClusterRoleBinding"},
+	},
+	"authorization.k8s.io/v1": {
+		{Name: "selfsubjectaccessreviews", Kind: "SelfSubjectAccessReview"},
+	},
+	"apiextensions.k8s.io/v1": {
+		{Name: "customresourcedefinitions", Kind: "CustomResourceDefinition"},
+	},
+	"metrics.k8s.io/v1beta1": {
+		{Name: "pods", Kind: "PodMetrics", Namespaced: true},
+	},
+	"monitoring.coreos.com/v1": {
+		{Name: "prometheuses", Kind: "Prometheus", Namespaced: true},
+	},
+	"argoproj.io/v1alpha1": {
+		{Name: "rollouts", Kind: "Rollout", Namespaced: true},
+	},
+}
+
+// DefaultKubernetesAPIVersions are the group versions served by a fake cluster unless set otherwise
+var DefaultKubernetesAPIVersions = []string{
+	"v1",
+	"apps/v1",
+	"autoscaling/v1",
+	"policy/v1beta1",
+	"rbac.authorization.k8s.io/v1",
+	"authorization.k8s.io/v1",
+	"apiextensions.k8s.io/v1",
+}
+
+// FakeKubernetes is a test double for the cluster queried by command.KubernetesClient
+// Resources are identified by their group resource, such as "pods" or "deployments.apps"
+type FakeKubernetes struct {
+	// Lists maps a resource to the JSON object list returned when it is listed
+	// Items are filtered by the namespace and label selector of the request
+	Lists map[string]string
+
+	// Objects maps a resource and name (e.g. "deployments.apps/web") to the JSON object returned by Get
+	Objects map[string]string
+
+	// Errors maps a verb and resource (e.g. "list services") to the error it returns
+	Errors map[string]error
+
+	// DiscoveryError is returned by API discovery to simulate an unreachable cluster
+	DiscoveryError error
+
+	// Kubeconfig and Context are those the most recent client was created for by Factory
+	Kubeconfig string
+	Context    string
+
+	mu          sync.Mutex
+	apiVersions []string
+	allowed     map[string]bool
+	actions     []k8stesting.Action
+	reactors    []fakeKubernetesReactor
+}
+
+type fakeKubernetesReactor struct {
+	verb, resource string
+	reaction       k8stesting.ReactionFunc
+}
+
+// NewFakeKubernetes returns a new fake cluster serving the default API versions
+func NewFakeKubernetes() *FakeKubernetes {
+	return &FakeKubernetes{
+		Lists:       map[string]string{},
+		Objects:     map[string]string{},
+		Errors:      map[string]error{},
+		apiVersions: DefaultKubernetesAPIVersions,
+		allowed:     map[string]bool{},
+	}
+}
+
+// SetAPIVersions replaces the group versions served by the fake cluster
+func (k *FakeKubernetes) SetAPIVersions(versions ...string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.apiVersions = versions
+}
+
+// Allow permits the verb on a resource (e.g. "create", "clusterroles.rbac.authorization.k8s.io") in access reviews
+func (k *FakeKubernetes) Allow(verb, resource string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.allowed[verb+" "+resource] = true
+}
+
+// PrependReactor adds a reaction that takes precedence over the canned responses of clients created afterwards
+func (k *FakeKubernetes) PrependReactor(verb, resource string, reaction k8stesting.ReactionFunc) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.reactors = append(k.reactors, fakeKubernetesReactor{verb, resource, reaction})
+}
+
+// Actions returns the requests made to the fake cluster in order
+func (k *FakeKubernetes) Actions() []k8stesting.Action {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return append([]k8stesting.Action{}, k.actions...)
+}
+
+// Patches returns the patch requests made to the fake cluster in order
+func (k *FakeKubernetes) Patches() []k8stesting.PatchAction {
+	patches := []k8stesting.PatchAction{}
+	for _, action := range k.Actions() {
+		if patch, ok := action.(k8stesting.PatchAction); ok {
+			patches = append(patches, patch)
+		}
+	}
+	return patches
+}
+
+// Factory returns a client factory creating clients of the fake cluster and recording the kubeconfig and context
+// Use with command.SetKubernetesClientFactory
+func (k *FakeKubernetes) Factory() command.KubernetesClientFactory {
+	return func(kubeconfig, context string) (*command.KubernetesClient, error) {
+		k.mu.Lock()
+		k.Kubeconfig, k.Context = kubeconfig, context
+		k.mu.Unlock()
+		return k.Client(), nil
+	}
+}
+
+// Client returns a new client of the fake cluster
+func (k *FakeKubernetes) Client() *command.KubernetesClient {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	fakeDiscovery := &discoveryfake.FakeDiscovery{Fake: &k8stesting.Fake{}}
+	for _, version := range k.apiVersions {
+		fakeDiscovery.Resources = append(fakeDiscovery.Resources, &metav1.APIResourceList{
+			GroupVersion: version,
+			APIResources: kubernetesAPIResources[version],
+		})
+	}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	client.PrependReactor("*", "*", k.react)
+	for i := len(k.reactors) - 1; i >= 0; i-- {
+		client.PrependReactor(k.reactors[i].verb, k.reactors[i].resource, k.reactors[i].reaction)
+	}
+	client.PrependReactor("*", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		k.actions = append(k.actions, action)
+		return false, nil, nil
+	})
+	var discoveryClient discovery.DiscoveryInterface = fakeDiscovery
+	if k.DiscoveryError != nil {
+		discoveryClient = &unreachableDiscovery{FakeDiscovery: fakeDiscovery, err: k.DiscoveryError}
+	}
+	kubernetes := command.NewKubernetesClientWithDiscovery(client, discoveryClient)
+	kubernetes.PollInterval = time.Millisecond
+	return kubernetes
+}
+
+// react answers requests from the canned lists, objects, errors, and permissions
+func (k *FakeKubernetes) react(action k8stesting.Action) (bool, runtime.Object, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	resource := action.GetResource().GroupResource()
+	if err := k.Errors[action.GetVerb()+" "+resource.String()]; err != nil {
+		return true, nil, err
+	}
+	switch action := action.(type) {
+	case k8stesting.PatchAction:
+		return true, &unstructured.Unstructured{Object: map[string]interface{}{}}, nil
+	case k8stesting.ListAction:
+		list := &unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "v1", "kind": "List"}}
+		if data, ok := k.Lists[resource.String()]; ok {
+			content := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(data), &content); err != nil {
+				return true, nil, err
+			}
+			items, _, _ := unstructured.NestedSlice(content, "items")
+			for _, item := range items {
+				obj := unstructured.Unstructured{Object: item.(map[string]interface{})}
+				if action.GetNamespace() == "" || obj.GetNamespace() == action.GetNamespace() {
+					list.Items = append(list.Items, obj)
+				}
+			}
+		}
+		return true, list, nil
+	case k8stesting.GetAction:
+		data, ok := k.Objects[resource.String()+"/"+action.GetName()]
+		if !ok {
+			return true, nil, apierrors.NewNotFound(resource, action.GetName())
+		}
+		obj := &unstructured.Unstructured{}
+		return true, obj, json.Unmarshal([]byte(data), &obj.Object)
+	case k8stesting.CreateAction:
+		obj, ok := action.GetObject().(*unstructured.Unstructured)
+		if !ok || resource.Resource != "selfsubjectaccessreviews" {
+			return false, nil, nil
+		}
+		attributes, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "resourceAttributes")
+		reviewed := schema.GroupResource{Group: attributes["group"], Resource: attributes["resource"]}
+		allowed := k.allowed[fmt.Sprintf("%s %s", attributes["verb"], reviewed)]
+		review := obj.DeepCopy()
+		unstructured.SetNestedField(review.Object, allowed, "status", "allowed")
+		return true, review, nil
+	}
+	return false, nil, nil
+}
+
+// unreachableDiscovery fails API discovery as a cluster that cannot be reached does
+type unreachableDiscovery struct {
+	*discoveryfake.FakeDiscovery
+	err error
+}
+
+func (d *unreachableDiscovery) ServerGroups() (*metav1.APIGroupList, error) {
+	return nil, d.err
+}
+
+func (d *unreachableDiscovery) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	return nil, nil, d.err
+}
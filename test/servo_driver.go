@@ -0,0 +1,156 @@
+// Copyright 2020 Opsani
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"sync"
+	"time"
+
+	"github.com/opsani/cli/command"
+)
+
+// FakeServoDriver is a test double for command.ServoDriver that records the operations invoked
+// Errors can be configured per operation to simulate failures
+type FakeServoDriver struct {
+	Servo   command.Servo
+	Timeout time.Duration
+
+	// Errors maps an operation name (e.g. "Start") to the error it returns
+	Errors map[string]error
+
+	// Artifacts are returned by Report
+	Artifacts []command.ReportArtifact
+
+	// ServoConfig is returned by ConfigData
+	ServoConfig []byte
+
+	mu    sync.Mutex
+	calls []string
+	args  []interface{}
+}
+
+// NewFakeServoDriver returns a new fake servo driver
+func NewFakeServoDriver() *FakeServoDriver {
+	return &FakeServoDriver{Errors: map[string]error{}}
+}
+
+// Factory returns a servo driver factory that returns the fake driver for any servo
+// Use with command.SetServoDriverFactory
+func (d *FakeServoDriver) Factory() command.ServoDriverFactory {
+	return func(servo command.Servo, timeout time.Duration) (command.ServoDriver, error) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.Servo = servo
+		d.Timeout = timeout
+		return d, nil
+	}
+}
+
+// Calls returns the names of the operations invoked in order
+func (d *FakeServoDriver) Calls() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string{}, d.calls...)
+}
+
+// LastArgs returns the arguments of the most recent operation invoked with arguments
+func (d *FakeServoDriver) LastArgs() interface{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.args) == 0 {
+		return nil
+	}
+	return d.args[len(d.args)-1]
+}
+
+func (d *FakeServoDriver) record(name string, args interface{}) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls = append(d.calls, name)
+	if args != nil {
+		d.args = append(d.args, args)
+	}
+	return d.Errors[name]
+}
+
+// Status records the invocation
+func (d *FakeServoDriver) Status() error {
+	return d.record("Status", nil)
+}
+
+// Start records the invocation
+func (d *FakeServoDriver) Start() error {
+	return d.record("Start", nil)
+}
+
+// Stop records the invocation
+func (d *FakeServoDriver) Stop() error {
+	return d.record("Stop", nil)
+}
+
+// Restart records the invocation
+func (d *FakeServoDriver) Restart() error {
+	return d.record("Restart", nil)
+}
+
+// Logs records the invocation
+func (d *FakeServoDriver) Logs(args command.ServoLogsArgs) error {
+	return d.record("Logs", args)
+}
+
+// Config records the invocation
+func (d *FakeServoDriver) Config() error {
+	return d.record("Config", nil)
+}
+
+// ConfigData records the invocation and returns the configured servo config
+func (d *FakeServoDriver) ConfigData() ([]byte, error) {
+	if err := d.record("ConfigData", nil); err != nil {
+		return nil, err
+	}
+	return d.ServoConfig, nil
+}
+
+// SetConfigData records the invocation and replaces the servo config
+func (d *FakeServoDriver) SetConfigData(data []byte) error {
+	if err := d.record("SetConfigData", string(data)); err != nil {
+		return err
+	}
+	d.ServoConfig = data
+	return nil
+}
+
+// Shell records the invocation
+func (d *FakeServoDriver) Shell() error {
+	return d.record("Shell", nil)
+}
+
+// Report records the invocation and returns the configured artifacts
+func (d *FakeServoDriver) Report(args command.ServoReportArgs) ([]command.ReportArtifact, error) {
+	if err := d.record("Report", args); err != nil {
+		return nil, err
+	}
+	return d.Artifacts, nil
+}
+
+// Check records the invocation
+func (d *FakeServoDriver) Check(args command.ServoCheckArgs) error {
+	return d.record("Check", args)
+}
+
+// Ping records the invocation
+func (d *FakeServoDriver) Ping() error {
+	return d.record("Ping", nil)
+}
@@ -15,6 +15,7 @@
 package test
 
 import (
+	"io"
 	"strings"
 	"time"
 
@@ -115,6 +116,21 @@ func (h *Suite) AddDefaultArgs(args ...string) {
 	h.args = append(h.args, args...)
 }
 
+// SetStdin sets the input read by the next command execution
+func (h *Suite) SetStdin(stdin io.Reader) {
+	h.ce.SetStdin(stdin)
+}
+
+// SetEnv sets an environment variable for the duration of each command execution
+func (h *Suite) SetEnv(key, value string) {
+	h.ce.SetEnv(key, value)
+}
+
+// UnsetEnv removes an environment variable previously set via SetEnv
+func (h *Suite) UnsetEnv(key string) {
+	h.ce.UnsetEnv(key)
+}
+
 ///
 /// Command Execution Functions
 ///